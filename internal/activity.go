@@ -0,0 +1,515 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// An agent starting a new session cannot tell what happened since its last
+// one. With `orchestra serve --activity`, serve watches the workspace and
+// appends what it sees to .projects/activity.jsonl, one event per line:
+// sessions starting and ending, HEAD moving (commits, checkouts, resets),
+// and uncommitted files changing. Test runs are recorded by `orchestra
+// activity hook`, which an agent's PostToolUse hook calls, or by `orchestra
+// activity record test` from scripts. The tools plugin reads the log to
+// answer "what changed since my last session"; `orchestra activity` prints
+// it.
+
+// Activity event types.
+const (
+	activitySessionStart = "session-start"
+	activitySessionEnd   = "session-end"
+	activityHead         = "head"  // HEAD moved
+	activityFiles        = "files" // uncommitted files changed
+	activityTest         = "test"  // a test command ran
+)
+
+// activityInterval is how often serve looks for activity.
+const activityInterval = 5 * time.Second
+
+// activityMaxFiles caps the files listed in one event.
+const activityMaxFiles = 50
+
+// activityMaxAge is how long compact keeps activity events.
+const activityMaxAge = 90 * 24 * time.Hour
+
+// activityEvent is one line of .projects/activity.jsonl.
+type activityEvent struct {
+	Time     time.Time `json:"time"`
+	Type     string    `json:"type"`
+	Summary  string    `json:"summary"`           // one line for people and agents
+	Session  int       `json:"session,omitempty"` // serve's pid, for session events
+	Branch   string    `json:"branch,omitempty"`
+	Worktree string    `json:"worktree,omitempty"` // linked worktree's name; "" for the main checkout
+	Head     string    `json:"head,omitempty"`     // commit HEAD points at
+	From     string    `json:"from,omitempty"`     // head: where HEAD was before
+	Commits  []string  `json:"commits,omitempty"`  // head: "<short> <subject>", newest first
+	Files    []string  `json:"files,omitempty"`    // relative to the workspace
+	More     int       `json:"more,omitempty"`     // files left out of Files
+	Command  string    `json:"command,omitempty"`  // test
+	Exit     *int      `json:"exit_code,omitempty"`
+}
+
+func activityPath(workspace string) string {
+	return filepath.Join(workspace, ".projects", "activity.jsonl")
+}
+
+// appendActivity appends events to the workspace's activity log.
+func appendActivity(workspace string, events ...activityEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	var b bytes.Buffer
+	for _, e := range events {
+		e.Time = e.Time.UTC().Truncate(time.Second)
+		line, _ := json.Marshal(e)
+		b.Write(append(line, '\n'))
+	}
+	lf, err := os.OpenFile(activityPath(workspace), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := lf.Write(b.Bytes()); err != nil {
+		lf.Close()
+		return err
+	}
+	return lf.Close()
+}
+
+// loadActivity reads the activity log in time order.
+func loadActivity(workspace string) []activityEvent {
+	lf, err := os.Open(activityPath(workspace))
+	if err != nil {
+		return nil
+	}
+	defer lf.Close()
+
+	var out []activityEvent
+	sc := bufio.NewScanner(lf)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		var e activityEvent
+		if json.Unmarshal(sc.Bytes(), &e) == nil && e.Type != "" {
+			out = append(out, e)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Time.Before(out[j].Time) })
+	return out
+}
+
+// --- watcher ---
+
+// activityWatcher tracks what it last saw of the workspace's git state.
+type activityWatcher struct {
+	workspace string
+	session   int
+	git       bool
+	worktree  string // name of the linked worktree, whose events are told apart in a shared log
+	branch    string
+	head      string
+	files     map[string]string // path -> status, size, and mtime
+}
+
+// watchActivity records activity for workspace every interval until stop
+// closes, bracketed by session events, then closes done.
+func watchActivity(workspace string, session int, interval time.Duration, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	if _, err := os.Stat(filepath.Join(workspace, ".projects")); err != nil {
+		return
+	}
+	w := &activityWatcher{workspace: workspace, session: session, worktree: detectWorktree(workspace).name()}
+	w.record(w.start(time.Now())...)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			events := w.poll(time.Now())
+			events = append(events, activityEvent{
+				Time:    time.Now(),
+				Type:    activitySessionEnd,
+				Summary: "session ended",
+				Session: w.session,
+				Branch:  w.branch,
+				Head:    w.head,
+			})
+			w.record(events...)
+			return
+		case <-ticker.C:
+			w.record(w.poll(time.Now())...)
+		}
+	}
+}
+
+// record appends events, marked with the worktree they happened in.
+func (w *activityWatcher) record(events ...activityEvent) {
+	for i := range events {
+		events[i].Worktree = w.worktree
+	}
+	appendActivity(w.workspace, events...)
+}
+
+// start reads the git state and returns the session-start event, preceded
+// by a head event when HEAD moved since the last recorded event.
+func (w *activityWatcher) start(now time.Time) []activityEvent {
+	w.git = gitOutput(w.workspace, "rev-parse", "--git-dir") != ""
+	var events []activityEvent
+	if w.git {
+		w.branch, w.head = w.gitHead()
+		w.files = w.gitFiles()
+		// Test events carry no git state; the last event that does, from
+		// this worktree when worktrees share .projects/, says where the
+		// previous session left HEAD.
+		var last *activityEvent
+		for _, e := range loadActivity(w.workspace) {
+			if e.Type != activityTest && e.Worktree == w.worktree {
+				last = &e
+			}
+		}
+		if last != nil && (last.Head != w.head || last.Branch != w.branch) {
+			events = append(events, w.headEvent(now, last.Head, last.Branch, w.branch, w.head))
+		}
+	}
+
+	start := activityEvent{Time: now, Type: activitySessionStart, Session: w.session, Branch: w.branch, Head: w.head}
+	start.Summary = "session started"
+	if w.worktree != "" {
+		start.Summary += " in worktree " + w.worktree
+	}
+	if w.head != "" {
+		start.Summary += fmt.Sprintf(" on %s at %s", orDash(w.branch), shortCommit(w.head))
+	}
+	dirty := sortedKeys(w.files)
+	if len(dirty) > 0 {
+		start.Summary += fmt.Sprintf(", %d uncommitted file(s)", len(dirty))
+		start.Files, start.More = capFiles(dirty)
+	}
+	return append(events, start)
+}
+
+// poll returns events for what changed since the last poll.
+func (w *activityWatcher) poll(now time.Time) []activityEvent {
+	if !w.git {
+		return nil
+	}
+	var events []activityEvent
+	branch, head := w.gitHead()
+	if head != w.head || branch != w.branch {
+		events = append(events, w.headEvent(now, w.head, w.branch, branch, head))
+		w.branch, w.head = branch, head
+	}
+
+	files := w.gitFiles()
+	var changed []string
+	for path, sig := range files {
+		if w.files[path] != sig {
+			changed = append(changed, path)
+		}
+	}
+	w.files = files
+	if len(changed) > 0 {
+		sortNames(changed)
+		e := activityEvent{Time: now, Type: activityFiles, Branch: w.branch, Head: w.head}
+		e.Files, e.More = capFiles(changed)
+		e.Summary = fmt.Sprintf("%d file(s) changed: %s", len(changed), strings.Join(e.Files[:min(3, len(e.Files))], ", "))
+		if len(changed) > 3 {
+			e.Summary += ", …"
+		}
+		events = append(events, e)
+	}
+	return events
+}
+
+// headEvent describes HEAD moving from from (on fromBranch) to head on
+// branch. from is "" when the branch had no commits.
+func (w *activityWatcher) headEvent(now time.Time, from, fromBranch, branch, head string) activityEvent {
+	e := activityEvent{Time: now, Type: activityHead, Branch: branch, Head: head, From: from}
+	revs := head
+	if from != "" {
+		revs = from + ".." + head
+	}
+	if head != "" {
+		if log := gitOutput(w.workspace, "log", "--format=%h %s", "-n", "20", revs); log != "" {
+			e.Commits = strings.Split(log, "\n")
+		}
+	}
+	switch {
+	case branch != fromBranch:
+		e.Summary = fmt.Sprintf("switched to %s at %s", orDash(branch), shortCommit(head))
+	case len(e.Commits) == 1:
+		e.Summary = fmt.Sprintf("new commit on %s: %s", orDash(branch), e.Commits[0])
+	case len(e.Commits) > 1:
+		e.Summary = fmt.Sprintf("%d new commits on %s, latest: %s", len(e.Commits), orDash(branch), e.Commits[0])
+	default:
+		e.Summary = fmt.Sprintf("%s moved from %s to %s", orDash(branch), shortCommit(from), shortCommit(head))
+	}
+	return e
+}
+
+// gitHead returns the current branch ("" when detached) and commit.
+func (w *activityWatcher) gitHead() (branch, head string) {
+	return gitHead(w.workspace)
+}
+
+// gitFiles returns the uncommitted files outside .projects/, each with a
+// signature that changes when the file does.
+func (w *activityWatcher) gitFiles() map[string]string {
+	files := map[string]string{}
+	out := gitOutput(w.workspace, "status", "--porcelain", "-z")
+	entries := strings.Split(out, "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		status, path := entry[:2], entry[3:]
+		if status[0] == 'R' || status[0] == 'C' {
+			i++ // the original path follows
+		}
+		if strings.HasPrefix(path, ".projects/") || strings.HasPrefix(path, ".orchestra-mcp.") {
+			continue
+		}
+		sig := status
+		if info, err := os.Stat(filepath.Join(w.workspace, filepath.FromSlash(path))); err == nil {
+			sig += fmt.Sprintf(" %d %d", info.Size(), info.ModTime().UnixNano())
+		}
+		files[path] = sig
+	}
+	return files
+}
+
+// gitOutput runs git in dir and returns its trimmed output, or "" on error.
+func gitOutput(dir string, args ...string) string {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimRight(string(out), "\n\x00")
+}
+
+func shortCommit(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return orDash(sha)
+}
+
+func capFiles(files []string) ([]string, int) {
+	if len(files) > activityMaxFiles {
+		return files[:activityMaxFiles], len(files) - activityMaxFiles
+	}
+	return files, 0
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sortNames(keys)
+	return keys
+}
+
+// trimActivity drops events older than cutoff and returns how many.
+func trimActivity(workspace string, cutoff time.Time) (int, error) {
+	events := loadActivity(workspace)
+	kept := events[:0]
+	for _, e := range events {
+		if !e.Time.Before(cutoff) {
+			kept = append(kept, e)
+		}
+	}
+	dropped := len(events) - len(kept)
+	if dropped == 0 {
+		return 0, nil
+	}
+	var b bytes.Buffer
+	for _, e := range kept {
+		line, _ := json.Marshal(e)
+		b.Write(append(line, '\n'))
+	}
+	return dropped, writeFileAtomic(activityPath(workspace), b.Bytes(), 0644)
+}
+
+// --- commands ---
+
+// RunActivity handles `orchestra activity` -- prints the activity log,
+// by default since the last serve session ended.
+func RunActivity(args []string) {
+	fs := newFlagSet("activity")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	since := fs.String("since", "last-session", "Window start: last-session, a duration like 2d or 12h, or a date (YYYY-MM-DD)")
+	types := fs.String("type", "", "Comma-separated event types to show (session-start, session-end, head, files, test)")
+	porcelain := fs.Bool("porcelain", false, "Stable tab-separated output for scripts")
+	parseFlags(fs, args)
+
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	events := loadActivity(absWorkspace)
+
+	var cutoff time.Time
+	if *since == "last-session" {
+		cutoff = lastSessionEnd(absWorkspace, events)
+	} else if cutoff, err = parseSince(*since, time.Now()); err != nil {
+		fatal("--since: %v", err)
+	}
+	want := map[string]bool{}
+	for _, t := range strings.Split(*types, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			want[t] = true
+		}
+	}
+
+	var shown []activityEvent
+	for _, e := range events {
+		if e.Time.After(cutoff) && (len(want) == 0 || want[e.Type]) {
+			shown = append(shown, e)
+		}
+	}
+	if len(shown) == 0 {
+		if !*porcelain {
+			if len(events) == 0 {
+				fmt.Fprintf(os.Stderr, "No activity recorded. Start serve with --activity to record it.\n")
+			} else {
+				fmt.Fprintf(os.Stderr, "No activity since %s.\n", cutoff.Local().Format("2006-01-02 15:04"))
+			}
+		}
+		return
+	}
+
+	// Porcelain: time (RFC 3339), type, summary.
+	if *porcelain {
+		for _, e := range shown {
+			fmt.Fprintf(os.Stdout, "%s\t%s\t%s\n", e.Time.Format(time.RFC3339), e.Type, e.Summary)
+		}
+		return
+	}
+	if !cutoff.IsZero() {
+		fmt.Fprintf(os.Stderr, "Activity since %s:\n\n", cutoff.Local().Format("2006-01-02 15:04"))
+	}
+	tw := newTable(os.Stdout)
+	for _, e := range shown {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", e.Time.Local().Format("2006-01-02 15:04"), e.Type, e.Summary)
+	}
+	tw.Flush()
+}
+
+// lastSessionEnd returns when the last serve session that is no longer
+// running ended, or the zero time when none has.
+func lastSessionEnd(workspace string, events []activityEvent) time.Time {
+	running := map[int]bool{}
+	for _, rec := range liveServeRecords() {
+		if rec.Workspace == workspace {
+			running[rec.PID] = true
+		}
+	}
+	for i := len(events) - 1; i >= 0; i-- {
+		if e := events[i]; e.Type == activitySessionEnd && !running[e.Session] {
+			return e.Time
+		}
+	}
+	return time.Time{}
+}
+
+// runActivityRecord handles `orchestra activity record test` -- appends a
+// test run to the log, for scripts and git hooks.
+func runActivityRecord(args []string) {
+	fs := newFlagSet("activity record")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	command := fs.String("command", "", "The test command that ran")
+	exitCode := fs.Int("exit-code", -1, "Its exit status (-1 when unknown)")
+	parseFlags(fs, args)
+
+	if fs.NArg() != 1 || fs.Arg(0) != activityTest {
+		fatal("usage: orchestra activity record test --command=CMD [--exit-code=N]")
+	}
+	if *command == "" {
+		fatal("--command is required")
+	}
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	var exit *int
+	if *exitCode >= 0 {
+		exit = exitCode
+	}
+	if err := appendActivity(absWorkspace, testEvent(*command, exit)); err != nil {
+		fatal("record activity: %v", err)
+	}
+}
+
+// testCommand matches the test runners `activity hook` records.
+var testCommand = regexp.MustCompile(`(^|[\s;&|(])(go test|(npm|pnpm|yarn|bun)( run)? test|npx (jest|vitest)|jest|vitest|pytest|python3? -m (pytest|unittest)|cargo (test|nextest)|make (test|check)|rspec|bundle exec (rspec|rake test)|mvn( -\S+)* (test|verify)|gradlew? test|\./gradlew test|dotnet test|mix test|phpunit|ctest)(\s|$)`)
+
+// runActivityHook handles `orchestra activity hook` -- reads an agent's
+// PostToolUse hook payload from stdin and records shell commands that run
+// tests. It never fails, so a broken log cannot block the agent.
+func runActivityHook(args []string) {
+	fs := newFlagSet("activity hook")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	parseFlags(fs, args)
+
+	data, _ := io.ReadAll(io.LimitReader(os.Stdin, 1<<20))
+	var payload struct {
+		ToolName  string `json:"tool_name"`
+		ToolInput struct {
+			Command string `json:"command"`
+		} `json:"tool_input"`
+		ToolResponse map[string]any `json:"tool_response"`
+		Cwd          string         `json:"cwd"`
+	}
+	if json.Unmarshal(data, &payload) != nil || payload.ToolInput.Command == "" || !testCommand.MatchString(payload.ToolInput.Command) {
+		return
+	}
+	// Hooks run without a --workspace; the payload says where the agent is.
+	dir := *workspace
+	if dir == "." && payload.Cwd != "" {
+		dir = payload.Cwd
+	}
+	absWorkspace, err := resolveWorkspace(dir)
+	if err != nil {
+		return
+	}
+	if _, err := os.Stat(filepath.Join(absWorkspace, ".projects")); err != nil {
+		return
+	}
+	var exit *int
+	for _, key := range []string{"exit_code", "exitCode", "returnCode"} {
+		if v, ok := payload.ToolResponse[key].(float64); ok {
+			code := int(v)
+			exit = &code
+			break
+		}
+	}
+	if err := appendActivity(absWorkspace, testEvent(payload.ToolInput.Command, exit)); err != nil {
+		fmt.Fprintf(os.Stderr, "orchestra: record activity: %v\n", err)
+	}
+}
+
+func testEvent(command string, exit *int) activityEvent {
+	e := activityEvent{Time: time.Now(), Type: activityTest, Command: command, Exit: exit}
+	switch {
+	case exit == nil:
+		e.Summary = "ran " + command
+	case *exit == 0:
+		e.Summary = "tests passed: " + command
+	default:
+		e.Summary = fmt.Sprintf("tests failed (exit %d): %s", *exit, command)
+	}
+	return e
+}
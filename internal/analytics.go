@@ -0,0 +1,203 @@
+package internal
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// analyticsOptions are the flags shared by every analytics subcommand.
+type analyticsOptions struct {
+	workspace string
+	project   string
+	since     time.Time
+	csv       bool
+}
+
+// parseAnalyticsFlags registers and parses the shared analytics flags, then
+// brings the transition log up to date.
+func parseAnalyticsFlags(path string, args []string) (*analyticsOptions, []transition) {
+	fs := newFlagSet(path)
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	project := fs.String("project", "", "Limit to one project")
+	since := fs.String("since", "30d", "Window start: a duration like 30d, 2w, 12h, or a date (YYYY-MM-DD)")
+	asCSV := fs.Bool("csv", false, "Write CSV to stdout")
+	parseFlags(fs, args)
+
+	start, err := parseSince(*since, time.Now())
+	if err != nil {
+		fatal("--since: %v", err)
+	}
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	checkWorkspaceSchema(absWorkspace, false)
+
+	if _, err := recordTransitions(absWorkspace); err != nil {
+		printStatus(tagWarn, "could not update history: %v", err)
+	}
+
+	var log []transition
+	for _, t := range loadTransitions(absWorkspace) {
+		if *project == "" || t.Project == *project {
+			log = append(log, t)
+		}
+	}
+	return &analyticsOptions{workspace: absWorkspace, project: *project, since: start, csv: *asCSV}, log
+}
+
+// parseSince accepts Go durations plus d (days) and w (weeks) suffixes, or
+// an absolute date.
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			v, err := strconv.Atoi(n)
+			if err != nil || v < 0 {
+				return time.Time{}, fmt.Errorf("invalid window %q", s)
+			}
+			return now.Add(-time.Duration(v) * unit), nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid window %q", s)
+	}
+	return now.Add(-d), nil
+}
+
+// writeAnalytics prints rows as CSV or an aligned table.
+func writeAnalytics(opts *analyticsOptions, header []string, rows [][]string) {
+	if opts.csv {
+		w := csv.NewWriter(os.Stdout)
+		w.Write(header)
+		w.WriteAll(rows)
+		return
+	}
+	tw := newTable(os.Stdout)
+	fmt.Fprintf(tw, "  %s\n", strings.ToUpper(strings.Join(header, "\t")))
+	for _, r := range rows {
+		fmt.Fprintf(tw, "  %s\n", strings.Join(r, "\t"))
+	}
+	tw.Flush()
+}
+
+// --- cycle-time ---
+
+// runAnalyticsCycleTime reports, for every feature finished in the window,
+// the time from first entering in-progress to reaching done.
+func runAnalyticsCycleTime(args []string) {
+	opts, log := parseAnalyticsFlags("analytics cycle-time", args)
+
+	started := map[string]time.Time{}
+	var rows [][]string
+	var cycles []time.Duration
+	for _, t := range log {
+		key := featureKey(t.Project, t.Feature)
+		if t.To == "in-progress" {
+			if _, ok := started[key]; !ok {
+				started[key] = t.Time
+			}
+		}
+		if t.To != "done" || t.Time.Before(opts.since) {
+			continue
+		}
+		start, ok := started[key]
+		if !ok {
+			continue // never seen in progress; created as done or history began later
+		}
+		d := t.Time.Sub(start)
+		cycles = append(cycles, d)
+		rows = append(rows, []string{t.Feature, start.Local().Format(time.DateOnly), t.Time.Local().Format(time.DateOnly), fmt.Sprintf("%.1f", d.Hours()/24)})
+	}
+
+	writeAnalytics(opts, []string{"feature", "started", "done", "days"}, rows)
+	if !opts.csv {
+		if len(cycles) == 0 {
+			fmt.Fprintf(os.Stderr, "No features completed since %s.\n", opts.since.Format(time.DateOnly))
+			return
+		}
+		sort.Slice(cycles, func(i, j int) bool { return cycles[i] < cycles[j] })
+		fmt.Fprintf(os.Stdout, "\n%d features: median %.1f days, 85th percentile %.1f days\n",
+			len(cycles), percentile(cycles, 50).Hours()/24, percentile(cycles, 85).Hours()/24)
+	}
+}
+
+// percentile returns the p-th percentile of sorted durations (nearest rank).
+func percentile(sorted []time.Duration, p int) time.Duration {
+	i := (p*len(sorted)+99)/100 - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+// --- burndown ---
+
+// runAnalyticsBurndown reports, for each day in the window, how many known
+// features were not yet done at the end of that day.
+func runAnalyticsBurndown(args []string) {
+	opts, log := parseAnalyticsFlags("analytics burndown", args)
+
+	status := map[string]string{}
+	var rows [][]string
+	i := 0
+	today := startOfDay(time.Now())
+	for day := startOfDay(opts.since); !day.After(today); day = day.AddDate(0, 0, 1) {
+		end := day.AddDate(0, 0, 1)
+		done := 0
+		for ; i < len(log) && log[i].Time.Before(end); i++ {
+			status[featureKey(log[i].Project, log[i].Feature)] = log[i].To
+		}
+		for _, s := range status {
+			if s == "done" {
+				done++
+			}
+		}
+		rows = append(rows, []string{day.Format(time.DateOnly), strconv.Itoa(len(status) - done), strconv.Itoa(done), strconv.Itoa(len(status))})
+	}
+	writeAnalytics(opts, []string{"date", "remaining", "done", "total"}, rows)
+}
+
+// --- throughput ---
+
+// runAnalyticsThroughput reports features reaching done per week.
+func runAnalyticsThroughput(args []string) {
+	opts, log := parseAnalyticsFlags("analytics throughput", args)
+
+	counts := map[time.Time]int{}
+	for _, t := range log {
+		if t.To == "done" && !t.Time.Before(opts.since) {
+			counts[startOfWeek(t.Time.Local())]++
+		}
+	}
+
+	var rows [][]string
+	total := 0
+	for week := startOfWeek(opts.since); !week.After(time.Now()); week = week.AddDate(0, 0, 7) {
+		rows = append(rows, []string{week.Format(time.DateOnly), strconv.Itoa(counts[week])})
+		total += counts[week]
+	}
+	writeAnalytics(opts, []string{"week", "done"}, rows)
+	if !opts.csv && len(rows) > 0 {
+		fmt.Fprintf(os.Stdout, "\n%d features done, %.1f per week\n", total, float64(total)/float64(len(rows)))
+	}
+}
+
+func startOfDay(t time.Time) time.Time {
+	t = t.Local()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// startOfWeek returns the Monday starting t's week.
+func startOfWeek(t time.Time) time.Time {
+	d := startOfDay(t)
+	return d.AddDate(0, 0, -((int(d.Weekday()) + 6) % 7))
+}
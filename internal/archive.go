@@ -0,0 +1,389 @@
+package internal
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Finished features are moved out of the store the storage plugin and every
+// reader scans, into compressed archives:
+//
+//	.projects/.archive/<project>/<time>.tar.gz   one per archive run
+//
+// Each holds the archived feature files as they were, decrypted; in an
+// encrypted workspace the archive itself is encrypted like the feature
+// files. Archived features keep their IDs, which are never given out
+// again, and count as done for the dependencies of features still active.
+
+// defaultArchiveAge is how long a feature must have been done before
+// `orchestra archive` moves it.
+const defaultArchiveAge = "90d"
+
+func featureArchiveDir(workspace string) string {
+	return filepath.Join(workspace, ".projects", ".archive")
+}
+
+// featureArchivePaths returns the archives of project, or of every project
+// when project is empty, oldest first.
+func featureArchivePaths(workspace, project string) []string {
+	pattern := filepath.Join(featureArchiveDir(workspace), "*", "*.tar.gz")
+	if project != "" {
+		pattern = filepath.Join(featureArchiveDir(workspace), project, "*.tar.gz")
+	}
+	paths, _ := filepath.Glob(pattern)
+	sort.Slice(paths, func(i, j int) bool { return filepath.Base(paths[i]) < filepath.Base(paths[j]) })
+	return paths
+}
+
+// archivedAt is the time an archive was written, from its name.
+func archivedAt(path string) time.Time {
+	t, _ := time.Parse("20060102T150405Z", strings.TrimSuffix(filepath.Base(path), ".tar.gz"))
+	return t
+}
+
+// loadArchivedFeatures reads the archived features of project, or of every
+// project when project is empty. A feature's path is the archive it is in.
+func loadArchivedFeatures(workspace, project string) ([]*feature, error) {
+	var features []*feature
+	for _, path := range featureArchivePaths(workspace, project) {
+		fs, err := readFeatureArchive(path)
+		if err != nil {
+			return features, fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+		features = append(features, fs...)
+	}
+	return features, nil
+}
+
+// readFeatureArchive reads the features in one archive.
+func readFeatureArchive(path string) ([]*feature, error) {
+	data, err := readProjectFile(path)
+	if err != nil {
+		return nil, err
+	}
+	files, err := untarFeatures(data)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		if strings.HasSuffix(name, ".md") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	project := filepath.Base(filepath.Dir(path))
+	var features []*feature
+	for _, name := range names {
+		f, err := parseFeature(files[name], name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		f.Project, f.path = project, path
+		features = append(features, f)
+	}
+	return features, nil
+}
+
+// writeFeatureArchive writes files (name -> contents, decrypted) to a new
+// archive of project and returns its path.
+func writeFeatureArchive(workspace, project string, files map[string][]byte, now time.Time) (string, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, name := range names {
+		h := &tar.Header{Name: name, Mode: 0644, Size: int64(len(files[name])), ModTime: now, Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(h); err != nil {
+			return "", err
+		}
+		if _, err := tw.Write(files[name]); err != nil {
+			return "", err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return "", err
+	}
+	if err := gz.Close(); err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(featureArchiveDir(workspace), project)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, now.UTC().Format("20060102T150405Z")+".tar.gz")
+	for i := 2; ; i++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			break
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s-%d.tar.gz", now.UTC().Format("20060102T150405Z"), i))
+	}
+	return path, writeProjectFile(path, buf.Bytes())
+}
+
+// doneSince is when f last changed, which for a done feature is about when
+// it was finished: its updated_at, or its file's modification time.
+func doneSince(f *feature) time.Time {
+	if t, err := time.Parse(time.RFC3339, f.UpdatedAt); err == nil {
+		return t
+	}
+	if info, err := os.Stat(f.path); err == nil {
+		return info.ModTime()
+	}
+	return time.Time{}
+}
+
+// archivable returns the features done since before cutoff, leaving out
+// any that is the parent of a feature that stays, so an epic is archived
+// with its whole tree or not at all.
+func archivable(features []*feature, cutoff time.Time) []*feature {
+	keep := map[string]bool{}
+	for _, f := range features {
+		if f.Status != "done" || doneSince(f).After(cutoff) {
+			keep[strings.ToUpper(f.ID)] = true
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, f := range features {
+			parent := strings.ToUpper(f.Parent)
+			if keep[strings.ToUpper(f.ID)] && parent != "" && !keep[parent] {
+				keep[parent] = true
+				changed = true
+			}
+		}
+	}
+	var out []*feature
+	for _, f := range features {
+		if !keep[strings.ToUpper(f.ID)] {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// --- commands ---
+
+// RunArchive handles `orchestra archive` -- moves features done for longer
+// than --done-older-than into compressed archives, or with --restore puts
+// archived features back.
+func RunArchive(args []string) {
+	fs := newFlagSet("archive")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	olderThan := fs.String("done-older-than", defaultArchiveAge, "Archive features done for longer than this (e.g. 30d, 12w, 2160h)")
+	project := fs.String("project", "", "Only archive features of this project")
+	dryRun := fs.Bool("dry-run", false, "List what would be archived without moving anything")
+	restore := fs.String("restore", "", "Comma-separated archived feature IDs to move back into the store")
+	parseFlags(fs, args)
+
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	checkWorkspaceSchema(absWorkspace, true)
+
+	if *restore != "" {
+		restoreArchivedFeatures(absWorkspace, splitCSV(*restore), *dryRun)
+		return
+	}
+
+	now := time.Now()
+	cutoff, err := parseSince(*olderThan, now)
+	if err != nil {
+		fatal("--done-older-than: %v", err)
+	}
+	// Record any last moves first, so the history sees them before the
+	// files go.
+	if _, err := recordTransitions(absWorkspace); err != nil {
+		printStatus(tagWarn, "could not update history: %v", err)
+	}
+
+	byProject := map[string][]*feature{}
+	var projects []string
+	for _, f := range archivable(loadFeatures(absWorkspace, ""), cutoff) {
+		if *project != "" && f.Project != *project {
+			continue
+		}
+		if byProject[f.Project] == nil {
+			projects = append(projects, f.Project)
+		}
+		byProject[f.Project] = append(byProject[f.Project], f)
+	}
+	if len(projects) == 0 {
+		fmt.Fprintf(os.Stderr, "Nothing to archive: no feature has been done for longer than %s\n", *olderThan)
+		return
+	}
+	sortNames(projects)
+
+	total := 0
+	for _, p := range projects {
+		features := byProject[p]
+		if *dryRun {
+			for _, f := range features {
+				fmt.Fprintf(os.Stdout, "%s\t%s\tdone %s ago\t%s\n", p, f.ID, formatAge(now.Sub(doneSince(f))), f.Title)
+			}
+			total += len(features)
+			continue
+		}
+
+		files := map[string][]byte{}
+		for _, f := range features {
+			data, err := readProjectFile(f.path)
+			if err != nil {
+				fatal("read %s: %v", f.path, err)
+			}
+			files[filepath.Base(f.path)] = data
+		}
+		path, err := writeFeatureArchive(absWorkspace, p, files, now)
+		if err != nil {
+			fatal("write archive for %s: %v", p, err)
+		}
+		// The archive is written; only now do the files go.
+		for _, f := range features {
+			if err := os.Remove(f.path); err != nil {
+				printStatus(tagWarn, "%s is archived but could not be removed: %v", f.ID, err)
+			}
+		}
+		rel, _ := filepath.Rel(absWorkspace, path)
+		printStatus(tagOK, "%s: archived %d feature(s) to %s", p, len(features), filepath.ToSlash(rel))
+		total += len(features)
+	}
+
+	if *dryRun {
+		fmt.Fprintf(os.Stderr, "Dry run: %d feature(s) would be archived\n", total)
+		return
+	}
+	if err := writeProgressDoc(absWorkspace); err != nil {
+		printStatus(tagWarn, "PROGRESS.md: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "Archived %d feature(s). List them with 'orchestra features list --archived'.\n", total)
+}
+
+// restoreArchivedFeatures moves the archived features ids back into the
+// store and rewrites their archives without them.
+func restoreArchivedFeatures(workspace string, ids []string, dryRun bool) {
+	want := map[string]bool{}
+	for _, id := range ids {
+		want[strings.ToUpper(id)] = true
+	}
+
+	restored := 0
+	for _, path := range featureArchivePaths(workspace, "") {
+		features, err := readFeatureArchive(path)
+		if err != nil {
+			fatal("%s: %v", path, err)
+		}
+		var hit bool
+		for _, f := range features {
+			hit = hit || want[strings.ToUpper(f.ID)]
+		}
+		if !hit {
+			continue
+		}
+
+		data, err := readProjectFile(path)
+		if err != nil {
+			fatal("%s: %v", path, err)
+		}
+		files, err := untarFeatures(data)
+		if err != nil {
+			fatal("%s: %v", path, err)
+		}
+		project := filepath.Base(filepath.Dir(path))
+		for name, content := range files {
+			id := strings.TrimSuffix(name, ".md")
+			if !want[strings.ToUpper(id)] {
+				continue
+			}
+			dest := filepath.Join(featuresDir(workspace, project), name)
+			if _, err := os.Stat(dest); err == nil {
+				fatal("%s already exists; remove it or leave %s archived", dest, id)
+			}
+			if dryRun {
+				fmt.Fprintf(os.Stdout, "%s\t%s\n", project, id)
+			} else {
+				if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+					fatal("%v", err)
+				}
+				if err := writeProjectFile(dest, content); err != nil {
+					fatal("restore %s: %v", id, err)
+				}
+				printStatus(tagOK, "restored %s to %s", id, project)
+			}
+			delete(files, name)
+			delete(want, strings.ToUpper(id))
+			restored++
+		}
+		if dryRun {
+			continue
+		}
+
+		// Rewrite the archive without the restored features.
+		if len(files) == 0 {
+			if err = os.Remove(path); err == nil {
+				os.Remove(filepath.Dir(path)) // only if it is empty now
+			}
+		} else {
+			var newPath string
+			if newPath, err = writeFeatureArchive(workspace, project, files, archivedAt(path)); err == nil && newPath != path {
+				err = os.Rename(newPath, path)
+			}
+		}
+		if err != nil {
+			fatal("rewrite %s: %v", path, err)
+		}
+	}
+
+	for id := range want {
+		printStatus(tagFail, "%s is not archived", id)
+	}
+	if restored > 0 && !dryRun {
+		if err := writeProgressDoc(workspace); err != nil {
+			printStatus(tagWarn, "PROGRESS.md: %v", err)
+		}
+	}
+	if len(want) > 0 {
+		os.Exit(1)
+	}
+}
+
+// untarFeatures returns the files (name -> contents) in decrypted archive
+// data.
+func untarFeatures(data []byte) (map[string][]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	files := map[string][]byte{}
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		if files[h.Name], err = io.ReadAll(tr); err != nil {
+			return nil, err
+		}
+	}
+}
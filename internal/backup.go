@@ -0,0 +1,507 @@
+package internal
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Workspace backups. `orchestra backup` writes the workspace's .projects/
+// and .orchestra.yaml to a tar.gz in ~/.orchestra/backups/<workspace-hash>/,
+// named by the time it was taken. Files that are rebuilt on demand (the
+// feature index) or only describe running processes are left out. In an
+// encrypted workspace the feature files are backed up encrypted. `backup
+// schedule` installs a launchd agent (macOS) or systemd user timer (Linux)
+// that runs `orchestra backup --keep=N` for the workspace.
+
+// backupTimeFormat names backups; it sorts in time order.
+const backupTimeFormat = "20060102-150405"
+
+// backupPreRestore marks the backup restore takes of the state it replaces.
+const backupPreRestore = "pre-restore"
+
+// backupMeta is meta.json in a workspace's backup directory.
+type backupMeta struct {
+	Workspace string          `json:"workspace"`
+	Schedule  *backupSchedule `json:"schedule,omitempty"`
+}
+
+// backupSchedule records what `backup schedule` installed.
+type backupSchedule struct {
+	Interval  string   `json:"interval"` // hourly, daily, or weekly
+	Keep      int      `json:"keep"`
+	Files     []string `json:"files"` // the launchd plist or systemd units
+	CreatedAt string   `json:"created_at"`
+}
+
+// backupInfo is one backup archive.
+type backupInfo struct {
+	ID      string // file name without .tar.gz
+	Path    string
+	Created time.Time
+	Size    int64
+}
+
+func backupsRoot() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".orchestra", "backups")
+}
+
+// workspaceHash identifies a workspace in file and unit names.
+func workspaceHash(workspace string) string {
+	sum := sha256.Sum256([]byte(workspace))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+func backupDir(workspace string) string {
+	return filepath.Join(backupsRoot(), workspaceHash(workspace))
+}
+
+func loadBackupMeta(workspace string) backupMeta {
+	meta := backupMeta{Workspace: workspace}
+	if data, err := os.ReadFile(filepath.Join(backupDir(workspace), "meta.json")); err == nil {
+		json.Unmarshal(data, &meta)
+	}
+	return meta
+}
+
+func saveBackupMeta(workspace string, meta backupMeta) error {
+	if err := os.MkdirAll(backupDir(workspace), 0700); err != nil {
+		return err
+	}
+	data, _ := json.MarshalIndent(meta, "", "  ")
+	return writeFileAtomic(filepath.Join(backupDir(workspace), "meta.json"), append(data, '\n'), 0600)
+}
+
+// listBackups returns the workspace's backups, newest first.
+func listBackups(workspace string) []backupInfo {
+	paths, _ := filepath.Glob(filepath.Join(backupDir(workspace), "*.tar.gz"))
+	var out []backupInfo
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		id := strings.TrimSuffix(filepath.Base(path), ".tar.gz")
+		created, err := time.ParseInLocation(backupTimeFormat, id[:min(len(id), len(backupTimeFormat))], time.UTC)
+		if err != nil {
+			continue
+		}
+		out = append(out, backupInfo{ID: id, Path: path, Created: created, Size: info.Size()})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID > out[j].ID })
+	return out
+}
+
+// findBackup returns the backup named id, or the newest for "latest".
+func findBackup(workspace, id string) (backupInfo, error) {
+	backups := listBackups(workspace)
+	if len(backups) == 0 {
+		return backupInfo{}, fmt.Errorf("no backups of %s in %s", workspace, backupDir(workspace))
+	}
+	if id == "latest" {
+		return backups[0], nil
+	}
+	for _, b := range backups {
+		if b.ID == id || b.ID == strings.TrimSuffix(id, ".tar.gz") {
+			return b, nil
+		}
+	}
+	return backupInfo{}, fmt.Errorf("no backup %q; run 'orchestra backup list'", id)
+}
+
+// backupSkipped reports whether a file under .projects/ is left out of
+// backups: the feature index, serve's daemon state (per worktree), and
+// temp files.
+func backupSkipped(rel string) bool {
+	name := filepath.Base(rel)
+	switch {
+	case rel == filepath.Join(".projects", ".index.json"):
+		return true
+	case filepath.Dir(rel) == ".projects" && strings.HasPrefix(name, ".serve.") && strings.HasSuffix(name, ".json"):
+		return true
+	}
+	return strings.HasPrefix(name, ".") && strings.Contains(name, ".tmp-")
+}
+
+// createBackup archives the workspace's .projects/ and .orchestra.yaml.
+// suffix, if any, is appended to the backup's ID.
+func createBackup(workspace, suffix string) (backupInfo, int, error) {
+	dir := backupDir(workspace)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return backupInfo{}, 0, err
+	}
+	meta := loadBackupMeta(workspace)
+	meta.Workspace = workspace
+	if err := saveBackupMeta(workspace, meta); err != nil {
+		return backupInfo{}, 0, err
+	}
+
+	now := time.Now().UTC()
+	id := now.Format(backupTimeFormat)
+	if suffix != "" {
+		id += "-" + suffix
+	}
+	base := id
+	for n := 2; ; n++ {
+		if _, err := os.Stat(filepath.Join(dir, id+".tar.gz")); os.IsNotExist(err) {
+			break
+		}
+		id = fmt.Sprintf("%s-%d", base, n)
+	}
+	path := filepath.Join(dir, id+".tar.gz")
+
+	tmp, err := os.CreateTemp(dir, "."+id+".tmp-*")
+	if err != nil {
+		return backupInfo{}, 0, err
+	}
+	files, err := writeBackupArchive(tmp, workspace)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return backupInfo{}, 0, err
+	}
+	info, _ := os.Stat(path)
+	return backupInfo{ID: id, Path: path, Created: now, Size: info.Size()}, files, nil
+}
+
+// writeBackupArchive writes the backup's tar.gz to w and returns how many
+// files it holds.
+func writeBackupArchive(w io.Writer, workspace string) (int, error) {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	files := 0
+	add := func(path string, info fs.FileInfo) error {
+		rel, err := filepath.Rel(workspace, path)
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if info.IsDir() {
+			header.Name += "/"
+			return tw.WriteHeader(header)
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, f); err != nil {
+			return fmt.Errorf("%s: %w", rel, err)
+		}
+		files++
+		return nil
+	}
+
+	err := filepath.WalkDir(filepath.Join(workspace, ".projects"), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(workspace, path)
+		if !d.IsDir() && (!d.Type().IsRegular() || backupSkipped(rel)) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return add(path, info)
+	})
+	if err != nil {
+		return files, err
+	}
+	config := filepath.Join(workspace, ".orchestra.yaml")
+	if info, err := os.Stat(config); err == nil && info.Mode().IsRegular() {
+		if err := add(config, info); err != nil {
+			return files, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return files, err
+	}
+	return files, gz.Close()
+}
+
+// extractBackup extracts a backup into dir. Entries outside .projects/
+// other than .orchestra.yaml are refused, as are paths that climb out.
+func extractBackup(path, dir string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return 0, fmt.Errorf("gzip reader: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	files := 0
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return files, fmt.Errorf("tar read: %w", err)
+		}
+		name := filepath.FromSlash(strings.TrimSuffix(header.Name, "/"))
+		if !filepath.IsLocal(name) || (name != ".projects" && name != ".orchestra.yaml" && !strings.HasPrefix(name, ".projects"+string(filepath.Separator))) {
+			return files, fmt.Errorf("unexpected entry %q", header.Name)
+		}
+		target := filepath.Join(dir, name)
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return files, err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return files, err
+			}
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode).Perm())
+			if err != nil {
+				return files, err
+			}
+			if _, err := io.Copy(out, tr); err != nil {
+				out.Close()
+				return files, fmt.Errorf("write %s: %w", name, err)
+			}
+			if err := out.Close(); err != nil {
+				return files, err
+			}
+			files++
+		}
+	}
+}
+
+// pruneBackups removes all but the newest keep backups and returns them.
+func pruneBackups(workspace string, keep int, dryRun bool) ([]backupInfo, error) {
+	backups := listBackups(workspace)
+	if len(backups) <= keep {
+		return nil, nil
+	}
+	removed := backups[keep:]
+	if dryRun {
+		return removed, nil
+	}
+	for _, b := range removed {
+		if err := os.Remove(b.Path); err != nil {
+			return nil, err
+		}
+	}
+	return removed, nil
+}
+
+// --- commands ---
+
+// RunBackup handles `orchestra backup` -- backs up the workspace now,
+// optionally pruning old backups.
+func RunBackup(args []string) {
+	fs := newFlagSet("backup")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	keep := fs.Int("keep", 0, "Then remove all but the newest N backups (0 keeps all)")
+	parseFlags(fs, args)
+
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(absWorkspace, ".projects")); err != nil {
+		fatal("%s has no .projects/; nothing to back up", absWorkspace)
+	}
+	b, files, err := createBackup(absWorkspace, "")
+	if err != nil {
+		fatal("back up %s: %v", absWorkspace, err)
+	}
+	printStatus(tagOK, "backed up %d file(s) (%s) to %s", files, formatBytes(b.Size), b.Path)
+
+	if *keep > 0 {
+		removed, err := pruneBackups(absWorkspace, *keep, false)
+		if err != nil {
+			fatal("prune backups: %v", err)
+		}
+		if len(removed) > 0 {
+			printStatus(tagOK, "removed %d old backup(s), keeping %d", len(removed), *keep)
+		}
+	}
+}
+
+// runBackupList handles `orchestra backup list`.
+func runBackupList(args []string) {
+	fs := newFlagSet("backup list")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	porcelain := fs.Bool("porcelain", false, "Stable tab-separated output for scripts")
+	parseFlags(fs, args)
+
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	backups := listBackups(absWorkspace)
+
+	if jsonOutput() {
+		type backupJSON struct {
+			ID      string    `json:"id"`
+			Created time.Time `json:"created"`
+			Size    int64     `json:"size"` // bytes
+			Path    string    `json:"path"`
+		}
+		list := []backupJSON{}
+		for _, b := range backups {
+			list = append(list, backupJSON{b.ID, b.Created, b.Size, b.Path})
+		}
+		printJSON(list)
+		return
+	}
+
+	// Porcelain: id, created (RFC 3339), size in bytes, path.
+	if *porcelain {
+		for _, b := range backups {
+			fmt.Fprintf(os.Stdout, "%s\t%s\t%d\t%s\n", b.ID, b.Created.Format(time.RFC3339), b.Size, b.Path)
+		}
+		return
+	}
+	if meta := loadBackupMeta(absWorkspace); meta.Schedule != nil {
+		fmt.Fprintf(os.Stderr, "Schedule: %s, keeping %d (%s)\n\n", meta.Schedule.Interval, meta.Schedule.Keep, strings.Join(meta.Schedule.Files, ", "))
+	}
+	if len(backups) == 0 {
+		fmt.Fprintf(os.Stderr, "No backups of %s. Run 'orchestra backup' to take one.\n", absWorkspace)
+		return
+	}
+	tw := newTable(os.Stdout)
+	fmt.Fprintf(tw, "ID\tCREATED\tSIZE\n")
+	for _, b := range backups {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", b.ID, b.Created.Local().Format("2006-01-02 15:04"), formatBytes(b.Size))
+	}
+	tw.Flush()
+	fmt.Fprintf(os.Stderr, "\nIn %s\n", backupDir(absWorkspace))
+}
+
+// runBackupPrune handles `orchestra backup prune`.
+func runBackupPrune(args []string) {
+	fs := newFlagSet("backup prune")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	keep := fs.Int("keep", 7, "Number of newest backups to keep")
+	dryRun := fs.Bool("dry-run", false, "List the backups that would be removed")
+	parseFlags(fs, args)
+
+	if *keep < 1 {
+		fatal("--keep must be at least 1")
+	}
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	removed, err := pruneBackups(absWorkspace, *keep, *dryRun)
+	if err != nil {
+		fatal("prune backups: %v", err)
+	}
+	if len(removed) == 0 {
+		printStatus(tagSkip, "%d backup(s), nothing to prune", len(listBackups(absWorkspace)))
+		return
+	}
+	verb := "removed"
+	if *dryRun {
+		verb = "would remove"
+	}
+	for _, b := range removed {
+		printStatus(tagOK, "%s %s", verb, b.ID)
+	}
+}
+
+// runBackupRestore handles `orchestra backup restore` -- replaces the
+// workspace's .projects/ and .orchestra.yaml with a backup, after backing
+// up what it replaces.
+func runBackupRestore(args []string) {
+	fs := newFlagSet("backup restore")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	yes := fs.Bool("yes", false, "Do not ask for confirmation")
+	force := fs.Bool("force", false, "Restore even while serve runs for the workspace")
+	parseFlags(fs, args)
+
+	if fs.NArg() != 1 {
+		fatal("usage: orchestra backup restore <id|latest> [--yes]")
+	}
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	b, err := findBackup(absWorkspace, fs.Arg(0))
+	if err != nil {
+		fatal("%v", err)
+	}
+	if !*force {
+		for _, rec := range liveServeRecords() {
+			if rec.Workspace == absWorkspace {
+				fatal("serve %d runs for %s; stop it first, or pass --force", rec.PID, absWorkspace)
+			}
+		}
+	}
+	if !*yes {
+		if !isTerminal(os.Stdin) {
+			fatal("restoring replaces .projects/; pass --yes to confirm")
+		}
+		if !confirm(fmt.Sprintf("Replace .projects/ in %s with backup %s?", absWorkspace, b.ID)) {
+			fatal("aborted")
+		}
+	}
+
+	// Extract next to .projects/ first, so a bad archive changes nothing.
+	staging, err := os.MkdirTemp(absWorkspace, ".orchestra-restore-*")
+	if err != nil {
+		fatal("%v", err)
+	}
+	defer os.RemoveAll(staging)
+	files, err := extractBackup(b.Path, staging)
+	if err != nil {
+		fatal("read backup %s: %v", b.ID, err)
+	}
+	if _, err := os.Stat(filepath.Join(staging, ".projects")); err != nil {
+		fatal("backup %s has no .projects/", b.ID)
+	}
+
+	projects := filepath.Join(absWorkspace, ".projects")
+	if _, err := os.Stat(projects); err == nil {
+		pre, _, err := createBackup(absWorkspace, backupPreRestore)
+		if err != nil {
+			fatal("back up the current state first: %v", err)
+		}
+		printStatus(tagOK, "backed up the current state as %s", pre.ID)
+		if err := os.Rename(projects, filepath.Join(staging, ".projects-replaced")); err != nil {
+			fatal("move .projects/ aside: %v", err)
+		}
+	}
+	if err := os.Rename(filepath.Join(staging, ".projects"), projects); err != nil {
+		fatal("restore .projects/: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(staging, ".orchestra.yaml")); err == nil {
+		if err := moveFile(filepath.Join(staging, ".orchestra.yaml"), filepath.Join(absWorkspace, ".orchestra.yaml")); err != nil {
+			fatal("restore .orchestra.yaml: %v", err)
+		}
+	}
+	printStatus(tagOK, "restored %d file(s) from %s", files, b.ID)
+}
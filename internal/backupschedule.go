@@ -0,0 +1,293 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Backup schedules. `orchestra backup schedule` generates a launchd agent
+// on macOS, or a systemd user service and timer on Linux, that run
+// `orchestra backup --workspace=<dir> --keep=N`, and loads it. The files
+// are named after the workspace hash, so each workspace has its own
+// schedule and scheduling again replaces it.
+
+// backupJob is what a schedule runs.
+type backupJob struct {
+	workspace string
+	interval  string // hourly, daily, or weekly; systemd's OnCalendar takes them as is
+	keep      int
+	bin       string // the orchestra executable
+	logFile   string
+}
+
+func (j backupJob) args() []string {
+	return []string{j.bin, "backup", "--workspace=" + j.workspace, "--keep=" + strconv.Itoa(j.keep)}
+}
+
+// --- launchd ---
+
+func launchdLabel(workspace string) string {
+	return "dev.orchestra.backup." + workspaceHash(workspace)
+}
+
+func launchdPlistPath(workspace string) string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, "Library", "LaunchAgents", launchdLabel(workspace)+".plist")
+}
+
+// launchdPlist is the agent for j. Runs missed while the machine slept
+// happen when it wakes.
+func launchdPlist(j backupJob) []byte {
+	esc := func(s string) string {
+		var b bytes.Buffer
+		xml.EscapeText(&b, []byte(s))
+		return b.String()
+	}
+	var interval string
+	switch j.interval {
+	case "hourly":
+		interval = "<dict><key>Minute</key><integer>0</integer></dict>"
+	case "daily":
+		interval = "<dict><key>Hour</key><integer>3</integer><key>Minute</key><integer>0</integer></dict>"
+	case "weekly":
+		interval = "<dict><key>Weekday</key><integer>0</integer><key>Hour</key><integer>3</integer><key>Minute</key><integer>0</integer></dict>"
+	}
+	var args strings.Builder
+	for _, a := range j.args() {
+		args.WriteString("\n    <string>" + esc(a) + "</string>")
+	}
+	return []byte(fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+  <key>Label</key>
+  <string>%s</string>
+  <key>ProgramArguments</key>
+  <array>%s
+  </array>
+  <key>StartCalendarInterval</key>
+  %s
+  <key>StandardOutPath</key>
+  <string>%s</string>
+  <key>StandardErrorPath</key>
+  <string>%s</string>
+</dict>
+</plist>
+`, esc(launchdLabel(j.workspace)), args.String(), interval, esc(j.logFile), esc(j.logFile)))
+}
+
+// --- systemd ---
+
+func systemdUnitName(workspace string) string {
+	return "orchestra-backup-" + workspaceHash(workspace)
+}
+
+func systemdUserDir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "systemd", "user")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "systemd", "user")
+}
+
+// systemdQuote quotes an ExecStart argument.
+func systemdQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "%", "%%", "$", "$$").Replace(s) + `"`
+}
+
+// systemdUnits are the service and timer for j. Persistent makes a run
+// missed while the machine was off happen at the next boot.
+func systemdUnits(j backupJob) (service, timer []byte) {
+	var cmdline []string
+	for _, a := range j.args() {
+		cmdline = append(cmdline, systemdQuote(a))
+	}
+	service = []byte(fmt.Sprintf(`[Unit]
+Description=orchestra backup of %s
+
+[Service]
+Type=oneshot
+ExecStart=%s
+StandardOutput=append:%s
+StandardError=append:%s
+`, j.workspace, strings.Join(cmdline, " "), j.logFile, j.logFile))
+	timer = []byte(fmt.Sprintf(`[Unit]
+Description=%s orchestra backup of %s
+
+[Timer]
+OnCalendar=%s
+Persistent=true
+RandomizedDelaySec=5m
+
+[Install]
+WantedBy=timers.target
+`, strings.ToUpper(j.interval[:1])+j.interval[1:], j.workspace, j.interval))
+	return service, timer
+}
+
+// --- command ---
+
+// runBackupSchedule handles `orchestra backup schedule` -- installs, or
+// with --remove uninstalls, a recurring backup of the workspace.
+func runBackupSchedule(args []string) {
+	fs := newFlagSet("backup schedule")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	hourly := fs.Bool("hourly", false, "Back up every hour")
+	daily := fs.Bool("daily", false, "Back up every day")
+	weekly := fs.Bool("weekly", false, "Back up every week")
+	keep := fs.Int("keep", 7, "Number of newest backups to keep")
+	remove := fs.Bool("remove", false, "Remove the workspace's schedule")
+	dryRun := fs.Bool("dry-run", false, "Print the generated files without installing them")
+	parseFlags(fs, args)
+
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	if *remove {
+		removeBackupSchedule(absWorkspace)
+		return
+	}
+
+	var intervals []string
+	for name, set := range map[string]bool{"hourly": *hourly, "daily": *daily, "weekly": *weekly} {
+		if set {
+			intervals = append(intervals, name)
+		}
+	}
+	if len(intervals) != 1 {
+		fatal("pick one of --hourly, --daily, or --weekly")
+	}
+	if *keep < 1 {
+		fatal("--keep must be at least 1")
+	}
+	if _, err := os.Stat(filepath.Join(absWorkspace, ".projects")); err != nil {
+		fatal("%s has no .projects/; nothing to back up", absWorkspace)
+	}
+	bin, err := os.Executable()
+	if err != nil {
+		fatal("resolve self path: %v", err)
+	}
+	job := backupJob{
+		workspace: absWorkspace,
+		interval:  intervals[0],
+		keep:      *keep,
+		bin:       bin,
+		logFile:   filepath.Join(backupDir(absWorkspace), "backup.log"),
+	}
+
+	files := map[string][]byte{}
+	switch runtime.GOOS {
+	case "darwin":
+		files[launchdPlistPath(absWorkspace)] = launchdPlist(job)
+	case "linux":
+		service, timer := systemdUnits(job)
+		unit := filepath.Join(systemdUserDir(), systemdUnitName(absWorkspace))
+		files[unit+".service"] = service
+		files[unit+".timer"] = timer
+	default:
+		fatal("scheduling needs launchd (macOS) or systemd (Linux); on %s, have your scheduler run: %s", runtime.GOOS, strings.Join(job.args(), " "))
+	}
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sortNames(paths)
+
+	if *dryRun {
+		for _, path := range paths {
+			fmt.Fprintf(os.Stdout, "# %s\n%s\n", path, files[path])
+		}
+		return
+	}
+	for _, path := range paths {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			fatal("%v", err)
+		}
+		if err := writeFileAtomic(path, files[path], 0644); err != nil {
+			fatal("write %s: %v", path, err)
+		}
+		printStatus(tagOK, "wrote %s", path)
+	}
+
+	meta := loadBackupMeta(absWorkspace)
+	meta.Schedule = &backupSchedule{Interval: job.interval, Keep: job.keep, Files: paths, CreatedAt: time.Now().UTC().Format(time.RFC3339)}
+	if err := saveBackupMeta(absWorkspace, meta); err != nil {
+		fatal("save schedule: %v", err)
+	}
+
+	if err := loadBackupSchedule(absWorkspace); err != nil {
+		printStatus(tagWarn, "could not load the schedule: %v", err)
+		if runtime.GOOS == "darwin" {
+			fmt.Fprintf(os.Stderr, "Load it with: launchctl bootstrap gui/%d %s\n", os.Getuid(), launchdPlistPath(absWorkspace))
+		} else {
+			fmt.Fprintf(os.Stderr, "Load it with: systemctl --user daemon-reload && systemctl --user enable --now %s.timer\n", systemdUnitName(absWorkspace))
+		}
+		return
+	}
+	printStatus(tagOK, "backing up %s %s, keeping %d; output goes to %s", absWorkspace, job.interval, job.keep, job.logFile)
+}
+
+// loadBackupSchedule hands the installed files to launchd or systemd.
+func loadBackupSchedule(workspace string) error {
+	if runtime.GOOS == "darwin" {
+		domain := fmt.Sprintf("gui/%d", os.Getuid())
+		exec.Command("launchctl", "bootout", domain, launchdPlistPath(workspace)).Run() // replacing an older schedule
+		return runQuiet("launchctl", "bootstrap", domain, launchdPlistPath(workspace))
+	}
+	if err := runQuiet("systemctl", "--user", "daemon-reload"); err != nil {
+		return err
+	}
+	return runQuiet("systemctl", "--user", "enable", "--now", systemdUnitName(workspace)+".timer")
+}
+
+// removeBackupSchedule unloads and deletes the workspace's schedule. The
+// backups themselves are kept.
+func removeBackupSchedule(workspace string) {
+	meta := loadBackupMeta(workspace)
+	if meta.Schedule == nil {
+		printStatus(tagSkip, "no backup schedule for %s", workspace)
+		return
+	}
+	switch runtime.GOOS {
+	case "darwin":
+		exec.Command("launchctl", "bootout", fmt.Sprintf("gui/%d", os.Getuid()), launchdPlistPath(workspace)).Run()
+	case "linux":
+		exec.Command("systemctl", "--user", "disable", "--now", systemdUnitName(workspace)+".timer").Run()
+	}
+	for _, path := range meta.Schedule.Files {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			fatal("remove %s: %v", path, err)
+		}
+		printStatus(tagOK, "removed %s", path)
+	}
+	if runtime.GOOS == "linux" {
+		exec.Command("systemctl", "--user", "daemon-reload").Run()
+	}
+	meta.Schedule = nil
+	if err := saveBackupMeta(workspace, meta); err != nil {
+		fatal("save schedule: %v", err)
+	}
+	printStatus(tagOK, "removed the backup schedule for %s; existing backups are kept", workspace)
+}
+
+// runQuiet runs a command and returns its last line of output as the error
+// when it fails.
+func runQuiet(name string, args ...string) error {
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		if detail := lastLine(string(out)); detail != "" {
+			return fmt.Errorf("%s: %s", name, detail)
+		}
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
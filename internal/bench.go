@@ -0,0 +1,296 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// benchBaselineVersion is bumped when benchBaseline changes incompatibly.
+const benchBaselineVersion = 1
+
+// benchBaseline is a saved bench run, compared against by later runs.
+type benchBaseline struct {
+	Version    int                       `json:"version"`
+	Orchestra  string                    `json:"orchestra"` // orchestra version that recorded it
+	Platform   string                    `json:"platform"`  // GOOS/GOARCH
+	RecordedAt time.Time                 `json:"recorded_at"`
+	Results    map[string]benchBaselineP `json:"results"` // measurement -> percentiles
+}
+
+// benchBaselineP is one measurement's percentiles, in milliseconds.
+type benchBaselineP struct {
+	P50 float64 `json:"p50_ms"`
+	P90 float64 `json:"p90_ms"`
+	P99 float64 `json:"p99_ms"`
+}
+
+// benchResult is one measurement's samples.
+type benchResult struct {
+	Name    string
+	Samples []time.Duration
+}
+
+// percentile returns the nearest-rank percentile p (0-100) of the samples.
+func (r *benchResult) percentile(p float64) time.Duration {
+	if len(r.Samples) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), r.Samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func (r *benchResult) percentiles() benchBaselineP {
+	return benchBaselineP{P50: ms(r.percentile(50)), P90: ms(r.percentile(90)), P99: ms(r.percentile(99))}
+}
+
+func ms(d time.Duration) float64 {
+	return math.Round(float64(d)/float64(time.Millisecond)*10) / 10
+}
+
+func defaultBenchBaselinePath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".orchestra", "bench-baseline.json")
+}
+
+// RunBench handles `orchestra bench` -- measures how long serve's backend
+// takes to start and how fast it answers, and compares the percentiles
+// with a saved baseline.
+func RunBench(args []string) {
+	fs := newFlagSet("bench")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	certsDir := fs.String("certs-dir", defaultCertsDir(), "mTLS certificates directory")
+	n := fs.Int("n", 20, "Round-trips to time for tools/list and the tool call")
+	starts := fs.Int("starts", 3, "Cold starts to time")
+	tool := fs.String("tool", "list_projects", "Tool to time a tools/call round-trip for")
+	toolArgs := fs.String("tool-args", "{}", "JSON arguments for --tool")
+	baselinePath := fs.String("baseline", defaultBenchBaselinePath(), "Baseline file to compare against")
+	saveBaseline := fs.Bool("save-baseline", false, "Save this run as the baseline")
+	maxRegression := fs.Float64("max-regression", 0, "Exit 1 if any p50 is this many percent slower than the baseline (0 to never fail)")
+	porcelain := fs.Bool("porcelain", false, "Stable tab-separated output for scripts")
+	parseFlags(fs, args)
+
+	if *n < 1 || *starts < 1 {
+		fatal("-n and --starts must be at least 1")
+	}
+	var arguments map[string]any
+	if err := json.Unmarshal([]byte(*toolArgs), &arguments); err != nil {
+		fatal("--tool-args: %v", err)
+	}
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	checkWorkspaceSchema(absWorkspace, false)
+	bins, err := siblingBins()
+	if err != nil {
+		fatal("%v", err)
+	}
+
+	// The backends log to a file of their own, so a running serve's log
+	// is left alone. It is kept when something fails; errors from a
+	// backend that did not start name it.
+	lf, err := os.CreateTemp("", "orchestra-bench-*.log")
+	if err != nil {
+		fatal("create log: %v", err)
+	}
+	sess := &serveSession{
+		bins:      bins,
+		certsDir:  expandHome(*certsDir),
+		logFile:   lf.Name(),
+		log:       newServeLog(lf),
+		workspace: absWorkspace,
+	}
+	results, err := runBench(sess, *starts, *n, *tool, arguments)
+	lf.Close()
+	if err != nil {
+		fatal("%v", err)
+	}
+	os.Remove(lf.Name())
+
+	baselineFile := expandHome(*baselinePath)
+	base := loadBenchBaseline(baselineFile)
+	regressed := printBenchResults(results, base, *porcelain)
+
+	if *saveBaseline {
+		if err := saveBenchBaseline(baselineFile, results); err != nil {
+			fatal("save baseline: %v", err)
+		}
+		printStatus(tagOK, "saved baseline to %s", baselineFile)
+	} else if base == nil && !*porcelain {
+		printStatus(tagSkip, "no baseline at %s; save one with --save-baseline", baselineFile)
+	}
+	if *maxRegression > 0 && base != nil {
+		var slower []string
+		for _, r := range results {
+			if change, ok := regressed[r.Name]; ok && change > *maxRegression {
+				slower = append(slower, fmt.Sprintf("%s +%.0f%%", r.Name, change))
+			}
+		}
+		if len(slower) > 0 {
+			printStatus(tagFail, "p50 regressed by more than %g%%: %s", *maxRegression, strings.Join(slower, ", "))
+			os.Exit(1)
+		}
+	}
+}
+
+// runBench times starts cold starts of sess's backend, then n tools/list
+// and n tools/call round-trips through transport-stdio to the last one.
+func runBench(sess *serveSession, starts, n int, tool string, arguments map[string]any) ([]*benchResult, error) {
+	cold := &benchResult{Name: "cold-start"}
+	for i := 0; i < starts; i++ {
+		if sess.backend != nil {
+			sess.backend.stop()
+			sess.backend = nil
+		}
+		start := time.Now()
+		b, err := sess.startBackend(sess.workspace, "localhost:0")
+		if err != nil {
+			b.stop()
+			return nil, err
+		}
+		cold.Samples = append(cold.Samples, time.Since(start))
+		sess.backend = b
+	}
+	defer sess.backend.stop()
+
+	cmd := exec.Command(sess.bins["transport-stdio"], transportArgs(sess.backend.addr, sess.certsDir)...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stderr = sess.log.source(logSourceTransport)
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start transport-stdio: %w", err)
+	}
+	defer func() {
+		stdin.Close()
+		cmd.Process.Kill()
+		cmd.Wait()
+	}()
+
+	c := newMCPClient(stdin, stdout)
+	start := time.Now()
+	if err := c.initialize(); err != nil {
+		return nil, err
+	}
+	initialize := &benchResult{Name: "initialize", Samples: []time.Duration{time.Since(start)}}
+
+	list := &benchResult{Name: "tools/list"}
+	var tools []string
+	for i := 0; i < n; i++ {
+		start := time.Now()
+		if tools, err = c.listTools(); err != nil {
+			return nil, err
+		}
+		list.Samples = append(list.Samples, time.Since(start))
+	}
+	found := false
+	for _, t := range tools {
+		found = found || t == tool
+	}
+	if !found {
+		return nil, fmt.Errorf("no tool %q; the backend has: %s", tool, strings.Join(tools, ", "))
+	}
+
+	call := &benchResult{Name: "tools/call " + tool}
+	for i := 0; i < n; i++ {
+		start := time.Now()
+		if err := c.call("tools/call", map[string]any{"name": tool, "arguments": arguments}, nil); err != nil {
+			return nil, fmt.Errorf("tools/call %s: %w", tool, err)
+		}
+		call.Samples = append(call.Samples, time.Since(start))
+	}
+	return []*benchResult{cold, initialize, list, call}, nil
+}
+
+// printBenchResults prints each measurement's percentiles next to the
+// baseline's p50, and returns the p50 change in percent for every
+// measurement the baseline has.
+func printBenchResults(results []*benchResult, base *benchBaseline, porcelain bool) map[string]float64 {
+	changes := map[string]float64{}
+	tw := newTable(os.Stdout)
+	if !porcelain {
+		fmt.Fprintf(tw, "MEASUREMENT\tN\tP50\tP90\tP99\tMAX\tBASELINE P50\tCHANGE\n")
+	}
+	for _, r := range results {
+		p := r.percentiles()
+		baseP50, change := "-", "-"
+		if b, ok := base.result(r.Name); ok && b.P50 > 0 {
+			changes[r.Name] = (p.P50 - b.P50) / b.P50 * 100
+			baseP50 = fmt.Sprintf("%.1fms", b.P50)
+			change = fmt.Sprintf("%+.0f%%", changes[r.Name])
+		}
+		if porcelain {
+			// Porcelain: measurement, n, p50, p90, p99, max (ms), baseline p50 (ms or -), change (% or -).
+			fmt.Fprintf(os.Stdout, "%s\t%d\t%.1f\t%.1f\t%.1f\t%.1f\t%s\t%s\n", r.Name, len(r.Samples), p.P50, p.P90, p.P99, ms(r.percentile(100)),
+				strings.TrimSuffix(baseP50, "ms"), strings.TrimSuffix(change, "%"))
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%.1fms\t%.1fms\t%.1fms\t%.1fms\t%s\t%s\n", r.Name, len(r.Samples), p.P50, p.P90, p.P99, ms(r.percentile(100)), baseP50, change)
+	}
+	tw.Flush()
+	if base != nil && !porcelain {
+		fmt.Fprintf(os.Stderr, "\nBaseline: orchestra %s on %s, %s\n", base.Orchestra, base.Platform, base.RecordedAt.Local().Format("2006-01-02 15:04"))
+	}
+	return changes
+}
+
+func (b *benchBaseline) result(name string) (benchBaselineP, bool) {
+	if b == nil {
+		return benchBaselineP{}, false
+	}
+	p, ok := b.Results[name]
+	return p, ok
+}
+
+// loadBenchBaseline reads a baseline, or returns nil when there is none or
+// it cannot be used.
+func loadBenchBaseline(path string) *benchBaseline {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var b benchBaseline
+	if json.Unmarshal(data, &b) != nil || b.Version != benchBaselineVersion {
+		return nil
+	}
+	return &b
+}
+
+func saveBenchBaseline(path string, results []*benchResult) error {
+	b := benchBaseline{
+		Version:    benchBaselineVersion,
+		Orchestra:  Version,
+		Platform:   runtime.GOOS + "/" + runtime.GOARCH,
+		RecordedAt: time.Now().UTC(),
+		Results:    map[string]benchBaselineP{},
+	}
+	for _, r := range results {
+		b.Results[r.Name] = r.percentiles()
+	}
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(data, '\n'), 0644)
+}
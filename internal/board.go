@@ -0,0 +1,524 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// boardRefresh is how often the board re-reads the feature store.
+const boardRefresh = 2 * time.Second
+
+// boardColumn is one lifecycle state and the features in it.
+type boardColumn struct {
+	state string
+	cards []*feature
+}
+
+// boardPrompt is a one-line input shown at the bottom of the board.
+type boardPrompt struct {
+	label  string
+	input  []rune
+	submit func(text string)
+}
+
+// board is the state of `orchestra board`.
+type board struct {
+	workspace string
+	project   string
+	label     string
+	assignee  string
+	text      string
+
+	columns   []boardColumn
+	col, row  int
+	offsets   []int
+	rows      int
+	cols      int
+	signature string
+	message   string
+	prompt    *boardPrompt
+}
+
+// RunBoard handles `orchestra board` -- an interactive kanban view of the
+// feature lifecycle. Without a terminal it prints the board once.
+func RunBoard(args []string) {
+	fs := newFlagSet("board")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	project := fs.String("project", "", "Limit to one project")
+	label := fs.String("label", "", "Only show features with this label")
+	assignee := fs.String("assignee", "", "Only show features assigned to this person")
+	parseFlags(fs, args)
+
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	checkWorkspaceSchema(absWorkspace, false)
+
+	b := &board{workspace: absWorkspace, project: *project, label: *label, assignee: *assignee}
+	b.reload()
+
+	if globals.plain || !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+		b.printStatic()
+		return
+	}
+	restore, err := enterRawMode()
+	if err != nil {
+		b.printStatic()
+		return
+	}
+	fmt.Fprint(os.Stdout, "\033[?1049h\033[?25l") // alternate screen, hide cursor
+	defer func() {
+		fmt.Fprint(os.Stdout, "\033[?25h\033[?1049l")
+		restore()
+	}()
+	b.run()
+}
+
+// run is the interactive loop: redraw, then wait for a key or a refresh.
+func (b *board) run() {
+	keys := make(chan []byte)
+	go func() {
+		buf := make([]byte, 64)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				close(keys)
+				return
+			}
+			keys <- append([]byte(nil), buf[:n]...)
+		}
+	}()
+
+	ticker := time.NewTicker(boardRefresh)
+	defer ticker.Stop()
+	b.rows, b.cols = terminalSize()
+	dirty := true
+	for {
+		if dirty {
+			b.render()
+		}
+		select {
+		case chunk, ok := <-keys:
+			if !ok {
+				return
+			}
+			for _, k := range decodeKeys(chunk) {
+				if b.handleKey(k) {
+					return
+				}
+			}
+			dirty = true
+		case <-ticker.C:
+			rows, cols := terminalSize()
+			dirty = b.reload() || rows != b.rows || cols != b.cols
+			b.rows, b.cols = rows, cols
+		}
+	}
+}
+
+// reload re-reads the feature store, keeping the selected card when it
+// still exists. It reports whether anything visible changed.
+func (b *board) reload() bool {
+	var selected string
+	if f := b.selected(); f != nil {
+		selected = f.ID
+	}
+
+	byState := map[string][]*feature{}
+	var sig strings.Builder
+	for _, f := range scanFeatures(b.workspace, b.project, nil) { // stderr would corrupt the screen
+		if !b.matches(f) {
+			continue
+		}
+		byState[f.Status] = append(byState[f.Status], f)
+		fmt.Fprintf(&sig, "%s|%s|%s|%s\n", f.ID, f.Status, f.Title, f.UpdatedAt)
+	}
+	changed := sig.String() != b.signature
+	b.signature = sig.String()
+
+	b.columns = b.columns[:0]
+	for _, s := range lifecycleStates {
+		cards := byState[s.Name]
+		// needs-edits is a side state; only show it when something is there.
+		if s.Name == "needs-edits" && len(cards) == 0 {
+			continue
+		}
+		sortFeatures(cards, func(a, c *feature) bool { return a.ID < c.ID })
+		b.columns = append(b.columns, boardColumn{state: s.Name, cards: cards})
+	}
+	if len(b.offsets) != len(b.columns) {
+		b.offsets = make([]int, len(b.columns))
+	}
+	if b.col >= len(b.columns) {
+		b.col = len(b.columns) - 1
+	}
+
+	if selected != "" {
+		for ci, c := range b.columns {
+			for ri, f := range c.cards {
+				if f.ID == selected {
+					b.col, b.row = ci, ri
+				}
+			}
+		}
+	}
+	b.clampRow()
+	return changed
+}
+
+// matches applies the label, assignee, and text filters.
+func (b *board) matches(f *feature) bool {
+	if b.label != "" && !f.hasLabel(b.label) {
+		return false
+	}
+	if b.assignee != "" && !strings.EqualFold(f.Assignee, b.assignee) {
+		return false
+	}
+	if b.text != "" && !matchesAny(b.text, f.ID, f.Title) {
+		return false
+	}
+	return true
+}
+
+func (b *board) selected() *feature {
+	if b.col < 0 || b.col >= len(b.columns) {
+		return nil
+	}
+	cards := b.columns[b.col].cards
+	if b.row < 0 || b.row >= len(cards) {
+		return nil
+	}
+	return cards[b.row]
+}
+
+func (b *board) clampRow() {
+	if b.col < 0 || b.col >= len(b.columns) {
+		b.row = 0
+		return
+	}
+	if n := len(b.columns[b.col].cards); b.row >= n {
+		b.row = n - 1
+	}
+	if b.row < 0 {
+		b.row = 0
+	}
+}
+
+// handleKey applies one key press and reports whether to quit.
+func (b *board) handleKey(k string) bool {
+	if b.prompt != nil {
+		b.handlePromptKey(k)
+		return false
+	}
+	b.message = ""
+	switch k {
+	case "q", "ctrl-c":
+		return true
+	case "left", "h":
+		if b.col > 0 {
+			b.col--
+		}
+		b.clampRow()
+	case "right", "l":
+		if b.col < len(b.columns)-1 {
+			b.col++
+		}
+		b.clampRow()
+	case "up", "k":
+		if b.row > 0 {
+			b.row--
+		}
+	case "down", "j":
+		b.row++
+		b.clampRow()
+	case "a":
+		b.advance()
+	case "x":
+		b.reject()
+	case "/":
+		b.prompt = &boardPrompt{label: "Filter (label=X, assignee=Y, text; empty clears): ", submit: b.setFilter}
+	case "r":
+		b.reload()
+		b.message = "Refreshed"
+	case "?":
+		b.message = "←→/hl column  ↑↓/jk card  a advance  x reject (in-review)  / filter  r refresh  q quit"
+	}
+	return false
+}
+
+func (b *board) handlePromptKey(k string) {
+	p := b.prompt
+	switch k {
+	case "esc", "ctrl-c":
+		b.prompt = nil
+		b.message = "Cancelled"
+	case "enter":
+		b.prompt = nil
+		p.submit(strings.TrimSpace(string(p.input)))
+	case "backspace":
+		if len(p.input) > 0 {
+			p.input = p.input[:len(p.input)-1]
+		}
+	default:
+		if r, size := utf8.DecodeRuneInString(k); size == len(k) && r >= ' ' {
+			p.input = append(p.input, r)
+		}
+	}
+}
+
+func (b *board) setFilter(text string) {
+	b.label, b.assignee, b.text = "", "", ""
+	switch {
+	case strings.HasPrefix(text, "label="):
+		b.label = strings.TrimPrefix(text, "label=")
+	case strings.HasPrefix(text, "assignee="):
+		b.assignee = strings.TrimPrefix(text, "assignee=")
+	default:
+		b.text = text
+	}
+	b.reload()
+}
+
+// advance moves the selected card to its next state, prompting for
+// evidence when leaving a gated state.
+func (b *board) advance() {
+	f := b.selected()
+	if f == nil {
+		return
+	}
+	state := findState(f.Status)
+	if state == nil || len(state.Next) == 0 {
+		b.message = fmt.Sprintf("%s is %s; nothing to advance to", f.ID, f.Status)
+		return
+	}
+	next := state.Next[0]
+	if g := findGate(state.Gate); g != nil {
+		id, from := f.ID, f.Status
+		b.prompt = &boardPrompt{
+			label:  fmt.Sprintf("Gate %d evidence (%s): ", g.Number, g.Action),
+			submit: func(text string) { b.move(id, from, next, g.Number, text) },
+		}
+		return
+	}
+	b.move(f.ID, f.Status, next, 0, "")
+}
+
+// reject sends a card in review back to needs-edits with a reason.
+func (b *board) reject() {
+	f := b.selected()
+	if f == nil || f.Status != "in-review" {
+		b.message = "Only features in in-review can be rejected"
+		return
+	}
+	id := f.ID
+	b.prompt = &boardPrompt{
+		label:  "Reason for needs-edits: ",
+		submit: func(text string) { b.move(id, "in-review", "needs-edits", 4, text) },
+	}
+}
+
+// move re-reads the feature, checks it is still in from, and writes the new
+// state. Gate transitions require evidence; leaving in-review also records a
+// review so the board and submit_review leave the same trail.
+func (b *board) move(id, from, to string, gate int, evidence string) {
+	if gate > 0 && evidence == "" {
+		b.message = "Evidence is required to pass a gate"
+		return
+	}
+	f, err := findFeatureForUpdate(b.workspace, id)
+	if err != nil {
+		b.message = err.Error()
+		return
+	}
+	if f.Status != from {
+		b.message = fmt.Sprintf("%s moved to %s elsewhere; refreshed", f.ID, f.Status)
+		b.reload()
+		return
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	if gate > 0 {
+		f.Evidence = append(f.Evidence, featureEvidence{Gate: gate, From: from, To: to, Text: evidence, Time: now})
+	}
+	if from == "in-review" {
+		outcome := "approved"
+		if to == "needs-edits" {
+			outcome = "needs-edits"
+		}
+		f.Reviews = append(f.Reviews, featureReview{Reviewer: currentUser(), Status: outcome, Notes: evidence, Time: now})
+	}
+	f.Status = to
+	if err := commitFeature(b.workspace, f); err != nil {
+		b.message = fmt.Sprintf("save %s: %v", f.ID, err)
+		return
+	}
+	b.message = fmt.Sprintf("%s → %s", f.ID, to)
+	b.reload()
+}
+
+// render draws the whole screen in one write.
+func (b *board) render() {
+	var s strings.Builder
+	s.WriteString("\033[H\033[2J")
+
+	scope := "all projects"
+	if b.project != "" {
+		scope = b.project
+	}
+	title := "Orchestra board — " + scope
+	if f := b.filterSummary(); f != "" {
+		title += "  [" + f + "]"
+	}
+	s.WriteString(clip(title, b.cols) + "\r\n")
+
+	const minWidth = 18
+	width := b.cols / max(len(b.columns), 1)
+	if width < minWidth {
+		width = minWidth
+	}
+	visible := max(b.cols/width, 1)
+	first := 0
+	if b.col >= visible {
+		first = b.col - visible + 1
+	}
+	last := min(first+visible, len(b.columns))
+
+	for ci := first; ci < last; ci++ {
+		c := b.columns[ci]
+		head := pad(fmt.Sprintf("%s (%d)", c.state, len(c.cards)), width)
+		if ci == b.col {
+			head = colorize(ansiBold, head)
+		}
+		s.WriteString(head)
+	}
+	s.WriteString("\r\n" + strings.Repeat("─", min(b.cols, width*(last-first))) + "\r\n")
+
+	// Each card is two lines: ID (+ assignee), then the title.
+	slots := max((b.rows-4)/2, 1)
+	for ci := first; ci < last; ci++ {
+		if ci == b.col {
+			if b.row < b.offsets[ci] {
+				b.offsets[ci] = b.row
+			} else if b.row >= b.offsets[ci]+slots {
+				b.offsets[ci] = b.row - slots + 1
+			}
+		}
+	}
+	for line := 0; line < slots*2; line++ {
+		for ci := first; ci < last; ci++ {
+			c := b.columns[ci]
+			idx := b.offsets[ci] + line/2
+			cell := ""
+			if idx < len(c.cards) {
+				f := c.cards[idx]
+				if line%2 == 0 {
+					cell = f.ID
+					if f.Assignee != "" {
+						cell += " @" + f.Assignee
+					}
+				} else {
+					cell = "  " + f.Title
+				}
+			}
+			cell = pad(cell, width-1) + " "
+			if ci == b.col && idx == b.row && idx < len(c.cards) {
+				if useColor() {
+					cell = "\033[7m" + cell + ansiReset
+				} else {
+					cell = ">" + cell[1:]
+				}
+			}
+			s.WriteString(cell)
+		}
+		s.WriteString("\r\n")
+	}
+
+	switch {
+	case b.prompt != nil:
+		s.WriteString(clip(b.prompt.label+string(b.prompt.input), b.cols-1) + "█")
+	case b.message != "":
+		s.WriteString(clip(b.message, b.cols))
+	default:
+		s.WriteString(colorize(ansiDim, clip("? help  q quit", b.cols)))
+	}
+	fmt.Fprint(os.Stdout, s.String())
+}
+
+func (b *board) filterSummary() string {
+	var parts []string
+	if b.label != "" {
+		parts = append(parts, "label="+b.label)
+	}
+	if b.assignee != "" {
+		parts = append(parts, "assignee="+b.assignee)
+	}
+	if b.text != "" {
+		parts = append(parts, fmt.Sprintf("%q", b.text))
+	}
+	return strings.Join(parts, " ")
+}
+
+// printStatic prints the board as a list, for pipes and dumb terminals.
+func (b *board) printStatic() {
+	for _, c := range b.columns {
+		fmt.Fprintf(os.Stdout, "%s (%d)\n", c.state, len(c.cards))
+		tw := newTable(os.Stdout)
+		for _, f := range c.cards {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\n", f.ID, f.Title, f.Assignee)
+		}
+		tw.Flush()
+	}
+}
+
+// decodeKeys splits a chunk of terminal input into key names.
+func decodeKeys(chunk []byte) []string {
+	var keys []string
+	for len(chunk) > 0 {
+		switch {
+		case len(chunk) >= 3 && chunk[0] == 0x1b && chunk[1] == '[':
+			keys = append(keys, map[byte]string{'A': "up", 'B': "down", 'C': "right", 'D': "left"}[chunk[2]])
+			chunk = chunk[3:]
+			continue
+		case chunk[0] == 0x1b:
+			keys = append(keys, "esc")
+		case chunk[0] == '\r' || chunk[0] == '\n':
+			keys = append(keys, "enter")
+		case chunk[0] == 0x7f || chunk[0] == 0x08:
+			keys = append(keys, "backspace")
+		case chunk[0] == 0x03:
+			keys = append(keys, "ctrl-c")
+		default:
+			r, size := utf8.DecodeRune(chunk)
+			keys = append(keys, string(r))
+			chunk = chunk[size:]
+			continue
+		}
+		chunk = chunk[1:]
+	}
+	return keys
+}
+
+// clip truncates s to at most n runes.
+func clip(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	r := []rune(s)
+	if n == 1 {
+		return string(r[:1])
+	}
+	return string(r[:n-1]) + "…"
+}
+
+// pad clips s to n runes and right-pads it with spaces.
+func pad(s string, n int) string {
+	s = clip(s, n)
+	return s + strings.Repeat(" ", n-utf8.RuneCountInString(s))
+}
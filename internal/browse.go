@@ -0,0 +1,589 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/orchestra-mcp/cli/pkg/packs"
+)
+
+// browseItem is one pack or plugin in `orchestra browse`.
+type browseItem struct {
+	Kind        string // "pack" or "plugin"
+	Repo        string
+	Description string
+	Stacks      []string
+	Tags        []string
+	Installed   string // installed version, "" when not installed
+	Recommended bool   // suits a stack detected in the workspace
+}
+
+// browseDetail is the detail view of one item, rendered to lines.
+type browseDetail struct {
+	item   browseItem
+	lines  []string
+	offset int
+}
+
+// browser is the state of `orchestra browse`.
+type browser struct {
+	workspace string
+	items     []browseItem
+	shown     []browseItem
+	kind      string // "" for both, "pack", or "plugin"
+	text      string
+	row       int
+	offset    int
+	rows      int
+	cols      int
+	detail    *browseDetail
+	details   map[string][]string // rendered detail lines by repo, fetched once
+	message   string
+	prompt    *boardPrompt
+	restore   func()
+}
+
+// RunBrowse handles `orchestra browse` -- an interactive browser over the
+// pack index and the plugin catalog, with a detail view fetched on demand
+// and install actions. Without a terminal it prints the list once.
+func RunBrowse(args []string) {
+	fs := newFlagSet("browse")
+	workspace := fs.String("workspace", ".", "Project workspace directory packs are installed into")
+	kind := fs.String("kind", "", "Only show packs or plugins")
+	parseFlags(fs, args)
+
+	if *kind != "" && *kind != "pack" && *kind != "plugin" {
+		fatal("--kind must be pack or plugin")
+	}
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	checkWorkspaceSchema(absWorkspace, false)
+
+	b := &browser{workspace: absWorkspace, kind: *kind, text: strings.ToLower(strings.Join(fs.Args(), " ")), details: map[string][]string{}}
+	b.load(false)
+
+	if globals.plain || !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+		b.printStatic()
+		return
+	}
+	if !b.enterScreen() {
+		b.printStatic()
+		return
+	}
+	defer b.leaveScreen()
+	b.run()
+}
+
+func (b *browser) enterScreen() bool {
+	restore, err := enterRawMode()
+	if err != nil {
+		return false
+	}
+	b.restore = restore
+	fmt.Fprint(os.Stdout, "\033[?1049h\033[?25l") // alternate screen, hide cursor
+	return true
+}
+
+func (b *browser) leaveScreen() {
+	fmt.Fprint(os.Stdout, "\033[?25h\033[?1049l")
+	b.restore()
+}
+
+// run is the interactive loop. Unlike the board it reads keys on this
+// goroutine, so nothing competes for stdin while an install runs.
+func (b *browser) run() {
+	buf := make([]byte, 64)
+	for {
+		b.rows, b.cols = terminalSize()
+		b.render()
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return
+		}
+		for _, k := range decodeKeys(buf[:n]) {
+			if b.handleKey(k) {
+				return
+			}
+		}
+	}
+}
+
+// load collects the packs of the pack index and the plugins of the
+// catalog and the plugin registry, marking what is installed and which
+// packs suit the workspace. refresh fetches the pack index again.
+func (b *browser) load(refresh bool) {
+	var stacks []string
+	for _, s := range detectStacks(b.workspace) {
+		stacks = append(stacks, s.name)
+	}
+	index := loadPackIndex(refresh)
+	recommended := map[string]bool{}
+	for _, p := range packs.RecommendFrom(index, stacks) {
+		recommended[p.Repo] = true
+	}
+	installedPacks := map[string]string{}
+	for _, e := range loadPackRegistry(b.workspace).Packs {
+		installedPacks[e.Repo] = e.Version
+	}
+
+	b.items = b.items[:0]
+	for _, p := range index {
+		b.items = append(b.items, browseItem{Kind: "pack", Repo: p.Repo, Description: p.Description, Stacks: p.Stacks, Tags: p.Tags, Installed: installedPacks[p.Repo], Recommended: recommended[p.Repo]})
+	}
+	// Recommended packs first, otherwise in index order.
+	sort.SliceStable(b.items, func(i, j int) bool { return b.items[i].Recommended && !b.items[j].Recommended })
+
+	pluginReg, err := LoadRegistry()
+	if err != nil {
+		pluginReg = &PluginRegistry{Plugins: make(map[string]*PluginEntry)}
+	}
+	seen := map[string]bool{}
+	for _, p := range knownPlugins {
+		seen[p.Repo] = true
+		item := browseItem{Kind: "plugin", Repo: p.Repo, Description: p.Description, Tags: p.Tags}
+		if e, ok := pluginReg.Plugins[p.Repo]; ok {
+			item.Installed = e.Version
+		}
+		b.items = append(b.items, item)
+	}
+	repos := make([]string, 0, len(pluginReg.Plugins))
+	for repo := range pluginReg.Plugins {
+		if !seen[repo] {
+			repos = append(repos, repo)
+		}
+	}
+	sort.Strings(repos)
+	for _, repo := range repos {
+		e := pluginReg.Plugins[repo]
+		b.items = append(b.items, browseItem{Kind: "plugin", Repo: repo, Description: fmt.Sprintf("%s (%d tools)", e.ID, len(e.Tools())), Installed: e.Version})
+	}
+	b.filter()
+}
+
+// filter applies the kind and text filters, keeping the selection when it
+// is still shown.
+func (b *browser) filter() {
+	var selected string
+	if it := b.selected(); it != nil {
+		selected = it.Repo
+	}
+	b.shown = b.shown[:0]
+	for _, it := range b.items {
+		if b.kind != "" && it.Kind != b.kind {
+			continue
+		}
+		if b.text != "" && !matchesAny(b.text, append(append([]string{it.Repo, it.Description}, it.Tags...), it.Stacks...)...) {
+			continue
+		}
+		b.shown = append(b.shown, it)
+	}
+	b.row = 0
+	for i, it := range b.shown {
+		if it.Repo == selected {
+			b.row = i
+		}
+	}
+}
+
+func (b *browser) selected() *browseItem {
+	if b.row < 0 || b.row >= len(b.shown) {
+		return nil
+	}
+	return &b.shown[b.row]
+}
+
+// handleKey applies one key press and reports whether to quit.
+func (b *browser) handleKey(k string) bool {
+	if b.prompt != nil {
+		b.handlePromptKey(k)
+		return false
+	}
+	b.message = ""
+	if b.detail != nil {
+		return b.handleDetailKey(k)
+	}
+	switch k {
+	case "q", "ctrl-c":
+		return true
+	case "up", "k":
+		if b.row > 0 {
+			b.row--
+		}
+	case "down", "j":
+		if b.row < len(b.shown)-1 {
+			b.row++
+		}
+	case "enter", "right", "l":
+		if it := b.selected(); it != nil {
+			b.open(*it)
+		}
+	case "\t":
+		switch b.kind {
+		case "":
+			b.kind = "pack"
+		case "pack":
+			b.kind = "plugin"
+		default:
+			b.kind = ""
+		}
+		b.filter()
+	case "/":
+		b.prompt = &boardPrompt{label: "Search (empty clears): ", input: []rune(b.text), submit: func(text string) {
+			b.text = strings.ToLower(text)
+			b.filter()
+		}}
+	case "i":
+		b.confirmInstall()
+	case "r":
+		b.message = "Fetching the pack index…"
+		b.render()
+		b.load(true)
+		b.details = map[string][]string{}
+		b.message = "Refreshed"
+	case "?":
+		b.message = "↑↓/jk move  enter details  / search  tab packs/plugins  i install  r refresh index  q quit"
+	}
+	return false
+}
+
+func (b *browser) handleDetailKey(k string) bool {
+	d := b.detail
+	page := max(b.rows-3, 1)
+	switch k {
+	case "ctrl-c":
+		return true
+	case "q", "esc", "left", "h", "backspace":
+		b.detail = nil
+	case "up", "k":
+		d.offset--
+	case "down", "j":
+		d.offset++
+	case " ":
+		d.offset += page
+	case "b":
+		d.offset -= page
+	case "i":
+		b.confirmInstall()
+	}
+	d.offset = max(min(d.offset, len(d.lines)-page), 0)
+	return false
+}
+
+func (b *browser) handlePromptKey(k string) {
+	p := b.prompt
+	switch k {
+	case "esc", "ctrl-c":
+		b.prompt = nil
+		b.message = "Cancelled"
+	case "enter":
+		b.prompt = nil
+		p.submit(strings.TrimSpace(string(p.input)))
+	case "backspace":
+		if len(p.input) > 0 {
+			p.input = p.input[:len(p.input)-1]
+		}
+	default:
+		if r, size := utf8.DecodeRuneInString(k); size == len(k) && r >= ' ' {
+			p.input = append(p.input, r)
+		}
+	}
+}
+
+// --- details ---
+
+// open shows it in the detail view, fetching its pack.json and README on
+// first use.
+func (b *browser) open(it browseItem) {
+	lines, ok := b.details[it.Repo]
+	if !ok {
+		b.message = "Fetching " + it.Repo + "…"
+		b.render()
+		b.message = ""
+		lines = browseDetailLines(it)
+		b.details[it.Repo] = lines
+	}
+	b.detail = &browseDetail{item: it, lines: lines}
+}
+
+// browseDetailLines renders what is known about it: the index entry, the
+// pack's manifest, and the README, the latter two read from GitHub.
+func browseDetailLines(it browseItem) []string {
+	var s strings.Builder
+	fmt.Fprintf(&s, "%s\n\n", colorize(ansiBold, it.Repo))
+	fmt.Fprintf(&s, "Kind:        %s\n", it.Kind)
+	if it.Description != "" {
+		fmt.Fprintf(&s, "Description: %s\n", it.Description)
+	}
+	if len(it.Stacks) > 0 {
+		stacks := strings.Join(it.Stacks, ", ")
+		if it.Recommended {
+			stacks += " (suits this workspace)"
+		}
+		fmt.Fprintf(&s, "Stacks:      %s\n", stacks)
+	}
+	if len(it.Tags) > 0 {
+		fmt.Fprintf(&s, "Tags:        %s\n", strings.Join(it.Tags, ", "))
+	}
+	if it.Installed != "" {
+		fmt.Fprintf(&s, "Installed:   %s\n", it.Installed)
+	} else {
+		fmt.Fprintf(&s, "Installed:   no\n")
+	}
+
+	ownerRepo, err := githubOwnerRepo(it.Repo)
+	if err != nil {
+		fmt.Fprintf(&s, "\n%s\n", colorize(ansiDim, "No details: only github.com repos can be browsed."))
+		return strings.Split(strings.TrimRight(s.String(), "\n"), "\n")
+	}
+	if it.Kind == "pack" {
+		if data, err := fetchRepoFile(ownerRepo, "pack.json"); err == nil {
+			var m packs.Manifest
+			if json.Unmarshal(data, &m) == nil {
+				fmt.Fprintf(&s, "Version:     %s\n", orDash(m.Version))
+				fmt.Fprintf(&s, "Contents:    %d skill(s), %d agent(s), %d hook(s)\n", len(m.Contents.Skills), len(m.Contents.Agents), len(m.Contents.Hooks))
+				if len(m.Dependencies) > 0 {
+					fmt.Fprintf(&s, "Depends on:  %s\n", strings.Join(m.Dependencies, ", "))
+				}
+				if problems := packs.Problems(&m, Version); len(problems) > 0 {
+					fmt.Fprintf(&s, "Compatible:  no — %s\n", strings.Join(problems, "; "))
+				} else {
+					fmt.Fprintf(&s, "Compatible:  yes\n")
+				}
+			}
+		} else {
+			fmt.Fprintf(&s, "pack.json:   %v\n", err)
+		}
+	}
+	s.WriteString("\n")
+	if data, err := fetchRepoFile(ownerRepo, "README.md"); err == nil {
+		renderMarkdown(&s, string(data), "")
+	} else {
+		fmt.Fprintf(&s, "%s\n", colorize(ansiDim, "README: "+err.Error()))
+	}
+	return strings.Split(strings.TrimRight(s.String(), "\n"), "\n")
+}
+
+// fetchRepoFile reads file from the default branch of a GitHub repo.
+func fetchRepoFile(ownerRepo, file string) ([]byte, error) {
+	resp, err := githubGetCached("https://raw.githubusercontent.com/"+ownerRepo+"/HEAD/"+file, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, httpCacheMaxBody))
+}
+
+// --- install ---
+
+// confirmInstall asks before installing the selected item.
+func (b *browser) confirmInstall() {
+	it := b.selected()
+	if b.detail != nil {
+		it = &b.detail.item
+	}
+	if it == nil {
+		return
+	}
+	if it.Installed != "" {
+		b.message = fmt.Sprintf("%s is already installed (%s)", it.Repo, it.Installed)
+		return
+	}
+	question := fmt.Sprintf("Install %s? [y/N] ", it.Repo)
+	if it.Kind == "pack" {
+		question = fmt.Sprintf("Install %s into %s? [y/N] ", it.Repo, b.workspace)
+	}
+	repo, kind := it.Repo, it.Kind
+	b.prompt = &boardPrompt{label: question, submit: func(text string) {
+		if strings.EqualFold(text, "y") || strings.EqualFold(text, "yes") {
+			b.install(kind, repo)
+		} else {
+			b.message = "Cancelled"
+		}
+	}}
+}
+
+// install leaves the screen and runs `orchestra pack install` or
+// `orchestra install` as a child, so its prompts (post-install scripts,
+// signatures) and output work as on the command line.
+func (b *browser) install(kind, repo string) {
+	self, err := os.Executable()
+	if err != nil {
+		b.message = err.Error()
+		return
+	}
+	args := []string{"install", repo}
+	if kind == "pack" {
+		args = []string{"pack", "install", repo, "--workspace", b.workspace}
+	}
+
+	b.leaveScreen()
+	fmt.Fprintf(os.Stderr, "$ orchestra %s\n\n", strings.Join(args, " "))
+	cmd := exec.Command(self, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	err = cmd.Run()
+	fmt.Fprintf(os.Stderr, "\nPress Enter to return to browse.")
+	fmt.Fscanln(os.Stdin)
+	if !b.enterScreen() {
+		fatal("restore the terminal: cannot enter raw mode")
+	}
+
+	if err != nil {
+		b.message = fmt.Sprintf("Installing %s failed: %v", repo, err)
+	} else {
+		b.message = "Installed " + repo
+	}
+	b.load(false)
+	if b.detail != nil {
+		for _, it := range b.items {
+			if it.Repo == repo {
+				b.detail.item = it
+			}
+		}
+		delete(b.details, repo)
+		b.detail.lines = browseDetailLines(b.detail.item)
+		b.details[repo] = b.detail.lines
+	}
+}
+
+// --- rendering ---
+
+// render draws the whole screen in one write.
+func (b *browser) render() {
+	var s strings.Builder
+	s.WriteString("\033[H\033[2J")
+	if b.detail != nil {
+		b.renderDetail(&s)
+	} else {
+		b.renderList(&s)
+	}
+
+	switch {
+	case b.prompt != nil:
+		s.WriteString(clip(b.prompt.label+string(b.prompt.input), b.cols-1) + "█")
+	case b.message != "":
+		s.WriteString(clip(b.message, b.cols))
+	case b.detail != nil:
+		s.WriteString(colorize(ansiDim, clip("↑↓ scroll  space/b page  i install  esc back  ? help", b.cols)))
+	default:
+		s.WriteString(colorize(ansiDim, clip("? help  q quit", b.cols)))
+	}
+	fmt.Fprint(os.Stdout, s.String())
+}
+
+func (b *browser) renderList(s *strings.Builder) {
+	packCount, pluginCount := 0, 0
+	for _, it := range b.shown {
+		if it.Kind == "pack" {
+			packCount++
+		} else {
+			pluginCount++
+		}
+	}
+	title := fmt.Sprintf("Orchestra browse — %d pack(s), %d plugin(s)", packCount, pluginCount)
+	if b.text != "" {
+		title += fmt.Sprintf("  [%q]", b.text)
+	}
+	s.WriteString(clip(title, b.cols) + "\r\n")
+	s.WriteString(strings.Repeat("─", b.cols) + "\r\n")
+
+	slots := max(b.rows-3, 1)
+	if b.row < b.offset {
+		b.offset = b.row
+	} else if b.row >= b.offset+slots {
+		b.offset = b.row - slots + 1
+	}
+	nameWidth := min(max(b.cols/3, 20), 50)
+	for i := b.offset; i < b.offset+slots; i++ {
+		if i >= len(b.shown) {
+			s.WriteString("\r\n")
+			continue
+		}
+		it := b.shown[i]
+		mark := " "
+		switch {
+		case it.Installed != "":
+			mark = "✓"
+		case it.Recommended:
+			mark = "★"
+		}
+		line := fmt.Sprintf("%s %-6s %s %s", mark, it.Kind, pad(strings.TrimPrefix(it.Repo, "github.com/"), nameWidth), it.Description)
+		line = pad(line, b.cols)
+		if i == b.row {
+			if useColor() {
+				line = "\033[7m" + line + ansiReset
+			} else {
+				line = ">" + line[1:]
+			}
+		}
+		s.WriteString(line + "\r\n")
+	}
+}
+
+func (b *browser) renderDetail(s *strings.Builder) {
+	d := b.detail
+	slots := max(b.rows-1, 1)
+	for i := d.offset; i < d.offset+slots; i++ {
+		if i < len(d.lines) {
+			s.WriteString(clipVisible(d.lines[i], b.cols))
+		}
+		s.WriteString("\r\n")
+	}
+}
+
+// clipVisible is clip for text with ANSI color codes, counting only the
+// characters that are shown and never cutting a code in half.
+func clipVisible(s string, n int) string {
+	if !strings.Contains(s, "\033[") {
+		return clip(s, n)
+	}
+	var out strings.Builder
+	visible := 0
+	for i := 0; i < len(s); {
+		if strings.HasPrefix(s[i:], "\033[") {
+			end := strings.IndexFunc(s[i+2:], func(r rune) bool { return r >= '@' && r <= '~' })
+			if end < 0 {
+				break
+			}
+			out.WriteString(s[i : i+2+end+1])
+			i += 2 + end + 1
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if visible == n {
+			break
+		}
+		out.WriteRune(r)
+		visible++
+		i += size
+	}
+	return out.String() + ansiReset
+}
+
+// printStatic prints the list, for pipes and dumb terminals.
+func (b *browser) printStatic() {
+	tw := newTable(os.Stdout)
+	for _, it := range b.shown {
+		status := ""
+		switch {
+		case it.Installed != "":
+			status = "installed " + it.Installed
+		case it.Recommended:
+			status = "recommended"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", it.Kind, it.Repo, orDash(status), it.Description)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,211 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// A plugin bundle carries plugin binaries for one platform from the
+// machine that fetched or cross-compiled them (`orchestra install
+// --target`) to the machine that runs them (`orchestra provision`):
+//
+//	<bundle>/
+//	  bundle.json   platform and one entry per plugin
+//	  bin/<name>    the binaries
+//
+// Manifests are read on the target, where the binaries can run.
+
+// bundleFile is the bundle's index.
+const bundleFile = "bundle.json"
+
+type pluginBundle struct {
+	Platform string          `json:"platform"` // GOOS/GOARCH of every binary
+	Plugins  []bundledPlugin `json:"plugins"`
+}
+
+type bundledPlugin struct {
+	Repo      string `json:"repo"`
+	Version   string `json:"version"`
+	Name      string `json:"name"`
+	Binary    string `json:"binary"` // relative to the bundle
+	SHA256    string `json:"sha256"`
+	BundledAt string `json:"bundled_at"`
+
+	Provenance *provenanceResult `json:"provenance,omitempty"` // checked when bundled; nil for source builds
+	Checksums  *checksumResult   `json:"checksums,omitempty"`  // checked when bundled; nil for source builds
+}
+
+// splitPlatform parses "GOOS/GOARCH".
+func splitPlatform(platform string) (goos, goarch string, err error) {
+	goos, goarch, ok := strings.Cut(platform, "/")
+	if !ok || goos == "" || goarch == "" || strings.Contains(goarch, "/") {
+		return "", "", fmt.Errorf("invalid platform %q, want GOOS/GOARCH such as linux/arm64", platform)
+	}
+	return goos, goarch, nil
+}
+
+// bundleOutDir is --out, or orchestra-bundle-<os>-<arch> in the current
+// directory.
+func bundleOutDir(out, platform string) string {
+	if out != "" {
+		return out
+	}
+	return "orchestra-bundle-" + strings.ReplaceAll(platform, "/", "-")
+}
+
+// loadBundle reads dir/bundle.json. A missing file is an empty bundle.
+func loadBundle(dir string) (*pluginBundle, error) {
+	b := &pluginBundle{}
+	data, err := os.ReadFile(filepath.Join(dir, bundleFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return b, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, b); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", filepath.Join(dir, bundleFile), err)
+	}
+	return b, nil
+}
+
+func saveBundle(dir string, b *pluginBundle) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(dir, bundleFile), append(data, '\n'), 0644)
+}
+
+// runBundleInstall handles `orchestra install --target`: it puts repo's
+// binary for platform into the bundle at out and records it there,
+// replacing an earlier copy of the same repo.
+func runBundleInstall(repo, version, name, platform, out string, forceSource, forceBinary, noVerify bool) {
+	dir := bundleOutDir(out, platform)
+	b, err := loadBundle(dir)
+	if err != nil {
+		fatal("load bundle: %v", err)
+	}
+	if b.Platform != "" && b.Platform != platform {
+		fatal("bundle %s is for %s, not %s; use another --out", dir, b.Platform, platform)
+	}
+	b.Platform = platform
+
+	if err := os.MkdirAll(filepath.Join(dir, "bin"), 0755); err != nil {
+		fatal("create bundle dir: %v", err)
+	}
+	rel := "bin/" + name
+	binPath := filepath.Join(dir, filepath.FromSlash(rel))
+	prov, sums := fetchPlugin(repo, version, name, binPath, platform, forceSource, forceBinary, noVerify)
+
+	// Pin "latest" to the tag it resolved to, so provisioning from the
+	// bundle records what was actually fetched.
+	if version == "" {
+		version = latestRepoReleaseTag(repo)
+	}
+	if version == "" {
+		version = "latest"
+	}
+
+	sum, err := fileSHA256(binPath)
+	if err != nil {
+		fatal("hash binary: %v", err)
+	}
+	entry := bundledPlugin{
+		Repo:      repo,
+		Version:   version,
+		Name:      name,
+		Binary:    rel,
+		SHA256:    sum,
+		BundledAt: time.Now().UTC().Format(time.RFC3339),
+
+		Provenance: prov,
+		Checksums:  sums,
+	}
+	replaced := false
+	for i, p := range b.Plugins {
+		if p.Repo == repo {
+			b.Plugins[i], replaced = entry, true
+		}
+	}
+	if !replaced {
+		b.Plugins = append(b.Plugins, entry)
+	}
+	if err := saveBundle(dir, b); err != nil {
+		fatal("save bundle: %v", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "\nBundled %s (%s) for %s\n", repo, version, platform)
+	fmt.Fprintf(os.Stderr, "  Bundle: %s (%d plugin(s))\n", dir, len(b.Plugins))
+	fmt.Fprintf(os.Stderr, "  On the target machine: orchestra provision %s\n", dir)
+}
+
+// RunProvision handles `orchestra provision <bundle>` -- installs the
+// plugins in a bundle made by `orchestra install --target` on this machine.
+func RunProvision(args []string) {
+	fs := newFlagSet("provision")
+	noVerify := fs.Bool("no-verify", false, "Skip booting tools plugins to check the tools they expose")
+	vendor := fs.Bool("vendor", false, "Install into <workspace>/.orchestra/bin/ and the workspace's plugin registry")
+	workspace := fs.String("workspace", ".", "Project workspace directory (with --vendor)")
+	parseFlags(fs, args)
+
+	if fs.NArg() < 1 {
+		fatal("usage: orchestra provision <bundle-dir> [--vendor] [--no-verify]")
+	}
+	dir := fs.Arg(0)
+	b, err := loadBundle(dir)
+	if err != nil {
+		fatal("load bundle: %v", err)
+	}
+	if len(b.Plugins) == 0 {
+		fatal("%s is not a plugin bundle (no plugins in %s)", dir, bundleFile)
+	}
+	if b.Platform != currentPlatform() {
+		fatal("bundle %s is for %s; this machine is %s", dir, b.Platform, currentPlatform())
+	}
+
+	// Check every binary before installing any.
+	for _, p := range b.Plugins {
+		sum, err := fileSHA256(filepath.Join(dir, filepath.FromSlash(p.Binary)))
+		if err != nil {
+			fatal("%s: %v", p.Repo, err)
+		}
+		if sum != p.SHA256 {
+			fatal("%s: %s does not match its checksum in %s; rebuild the bundle", p.Repo, p.Binary, bundleFile)
+		}
+	}
+
+	store := vendorStore(*vendor, *workspace)
+	binDir := store.binDir()
+	if err := os.MkdirAll(binDir, 0755); err != nil {
+		fatal("create plugin bin dir: %v", err)
+	}
+	for _, p := range b.Plugins {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(p.Binary)))
+		if err != nil {
+			fatal("%s: %v", p.Repo, err)
+		}
+		binPath := filepath.Join(binDir, p.Name)
+		if err := writeFileAtomic(binPath, data, 0755); err != nil {
+			fatal("install %s: %v", binPath, err)
+		}
+		registerPlugin(store, p.Repo, p.Version, p.Name, binPath, p.Provenance, p.Checksums, *noVerify)
+	}
+	fmt.Fprintf(os.Stderr, "\nProvisioned %d plugin(s) from %s\n", len(b.Plugins), dir)
+}
+
+// fileSHA256 returns the hex sha256 of the file at path.
+func fileSHA256(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
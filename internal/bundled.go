@@ -1,7 +1,6 @@
 package internal
 
 import (
-	"fmt"
 	"os"
 	"path/filepath"
 )
@@ -17,9 +16,9 @@ func InstallBundledContent(workspace string) {
 	os.MkdirAll(skillDir, 0755)
 	skillPath := filepath.Join(skillDir, "SKILL.md")
 	if err := os.WriteFile(skillPath, []byte(projectManagerSkill), 0644); err != nil {
-		fmt.Fprintf(os.Stderr, "  [FAIL] project-manager skill: %v\n", err)
+		printStatus(tagFail, "project-manager skill: %v", err)
 	} else {
-		fmt.Fprintf(os.Stderr, "  [OK] .claude/skills/project-manager/\n")
+		printStatus(tagOK, ".claude/skills/project-manager/")
 	}
 
 	// --- orchestra agent ---
@@ -27,13 +26,14 @@ func InstallBundledContent(workspace string) {
 	os.MkdirAll(agentsDir, 0755)
 	agentPath := filepath.Join(agentsDir, "orchestra.md")
 	if err := os.WriteFile(agentPath, []byte(orchestraAgent), 0644); err != nil {
-		fmt.Fprintf(os.Stderr, "  [FAIL] orchestra agent: %v\n", err)
+		printStatus(tagFail, "orchestra agent: %v", err)
 	} else {
-		fmt.Fprintf(os.Stderr, "  [OK] .claude/agents/orchestra.md\n")
+		printStatus(tagOK, ".claude/agents/orchestra.md")
 	}
 }
 
-const projectManagerSkill = `---
+// projectManagerSkill renders the gates and tool catalog from knowledge.go.
+var projectManagerSkill = `---
 name: project-manager
 description: Project management with Orchestra MCP tools. Activates when planning features, tracking workflow, managing dependencies, or coordinating work.
 ---
@@ -60,13 +60,7 @@ backlog -> todo -> in-progress -> ready-for-testing -> in-testing ->
 
 ### Gated Transitions (evidence required)
 
-| Gate | From | Action Required | Evidence Example |
-|------|------|----------------|-----------------|
-| 1 | in-progress | Run tests, confirm pass | "go test ./... - all passed" |
-| 2 | in-testing | Verify coverage, edge cases | "Coverage 85%, edge cases covered" |
-| 3 | in-docs | Write/update documentation | "Added docs, updated README" |
-| 4 | in-review | Review code quality | "No issues, error handling OK" |
-
+` + gatesMarkdown() + `
 **NEVER batch-advance through gates.** Each gate requires real work done first.
 
 ## Starting a Session
@@ -87,47 +81,7 @@ assign_feature        -> Assign to a team member
 add_dependency        -> Create blocker relationships between features
 ` + "```" + `
 
-## Feature Tools (34 total)
-
-### Project (4)
-create_project, list_projects, delete_project, get_project_status
-
-### Feature (6)
-create_feature, get_feature, update_feature, list_features, delete_feature, search_features
-
-### Workflow (5)
-advance_feature, reject_feature, get_next_feature, set_current_feature, get_workflow_status
-
-### Review (3)
-request_review, submit_review, get_pending_reviews
-
-### Dependencies (4)
-add_dependency, remove_dependency, get_dependency_graph, get_blocked_features
-
-### WIP Limits (3)
-set_wip_limits, get_wip_limits, check_wip_limit
-
-### Reporting (3)
-get_progress, get_review_queue, get_blocked_features
-
-### Metadata (6)
-add_labels, remove_labels, assign_feature, unassign_feature, set_estimate, save_note, list_notes
-
-## Marketplace Tools (15 total)
-
-### Pack Management (6)
-install_pack, remove_pack, update_pack, list_packs, get_pack, search_packs
-
-### Recommendations (2)
-detect_stacks, recommend_packs
-
-### Content Queries (5)
-list_skills, list_agents, list_hooks, get_skill, get_agent
-
-### Configuration (2)
-set_project_stacks, get_project_stacks
-
-## Sub-Agent Rules
+` + toolCatalogMarkdown() + `## Sub-Agent Rules
 
 Sub-agents (Task tool) do **NOT** have MCP access. They cannot call advance_feature or any workflow tool.
 
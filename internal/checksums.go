@@ -0,0 +1,274 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Releases can publish a checksums.txt next to their archives, in the
+// `sha256sum` format GoReleaser writes, and sign it: with cosign keyless
+// signing from a GitHub Actions or GitLab CI workflow (checksums.txt.sig and
+// checksums.txt.pem), or with minisign (checksums.txt.minisig). install
+// checks the archive against checksums.txt and checksums.txt against its
+// signature before extracting anything. A mismatch or a bad signature stops
+// the install; a release that publishes neither is installed with a warning.
+//
+// The installed binary's own SHA-256 is recorded in the registry, so
+// `orchestra plugins` and `orchestra doctor` can tell when it has changed.
+
+// releaseChecksumsAsset is the checksums file a release publishes.
+const releaseChecksumsAsset = "checksums.txt"
+
+// Checksum statuses.
+const (
+	checksumVerified = "verified" // the archive matches checksums.txt
+	checksumNone     = "none"     // the release publishes no checksums.txt
+	checksumSkipped  = "skipped"  // --no-verify
+	checksumFailed   = "failed"   // mismatch, or a signature that does not verify
+)
+
+// Signature statuses, besides the signer ("cosign" or "minisign") once
+// verified.
+const (
+	signatureNone      = "none"      // checksums.txt is not signed
+	signatureUnchecked = "unchecked" // signed, but the tool or key to check it is missing
+)
+
+// signatureTimeout bounds cosign, which fetches Sigstore trust roots on
+// first use.
+const signatureTimeout = 60 * time.Second
+
+// checksumResult records how a release download was verified.
+type checksumResult struct {
+	Status    string `json:"status"`
+	Signature string `json:"signature,omitempty"`
+	Detail    string `json:"detail,omitempty"`
+	CheckedAt string `json:"checked_at"`
+}
+
+// String renders r for `plugins info`.
+func (r *checksumResult) String() string {
+	if r == nil {
+		return "-"
+	}
+	s := r.Status
+	switch r.Signature {
+	case "", signatureNone:
+		if r.Status == checksumVerified {
+			s += ", unsigned"
+		}
+	case signatureUnchecked:
+		s += ", signature unchecked"
+	default:
+		s += ", signed (" + r.Signature + ")"
+	}
+	if r.Detail != "" {
+		s += " (" + r.Detail + ")"
+	}
+	return s
+}
+
+// verifyReleaseChecksums checks the archive at path, downloaded as asset
+// from repo's release version, against the release's checksums.txt
+// and its signature. The result has status checksumFailed, along with an
+// error, when the archive must not be installed. Other errors mean the
+// checksums could not be fetched.
+func verifyReleaseChecksums(repo string, ref repoRef, version, asset, path string) (*checksumResult, error) {
+	r := &checksumResult{CheckedAt: time.Now().UTC().Format(time.RFC3339)}
+
+	sums, ok, err := fetchReleaseAsset(ref, version, releaseChecksumsAsset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", releaseChecksumsAsset, err)
+	}
+	if !ok {
+		r.Status, r.Signature, r.Detail = checksumNone, signatureNone, "the release publishes no "+releaseChecksumsAsset
+		return r, nil
+	}
+
+	want := checksumFor(sums, asset)
+	if want == "" {
+		r.Status = checksumFailed
+		return r, fmt.Errorf("%s does not list %s", releaseChecksumsAsset, asset)
+	}
+	got, err := fileSHA256(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(got, want) {
+		r.Status = checksumFailed
+		return r, fmt.Errorf("%s does not match %s: sha256 %s, want %s", asset, releaseChecksumsAsset, got, want)
+	}
+	r.Status = checksumVerified
+
+	signer, detail, err := verifyChecksumsSignature(repo, ref, version, sums)
+	if err != nil {
+		r.Status = checksumFailed
+		return r, fmt.Errorf("%s signature did not verify: %w", releaseChecksumsAsset, err)
+	}
+	r.Signature, r.Detail = signer, detail
+	return r, nil
+}
+
+// checksumFor returns the sha256 sums lists for asset, or "".
+func checksumFor(sums []byte, asset string) string {
+	sc := bufio.NewScanner(strings.NewReader(string(sums)))
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == asset {
+			return fields[0]
+		}
+	}
+	return ""
+}
+
+// verifyChecksumsSignature checks the signature published for checksums.txt
+// and returns the signer that verified it, or signatureNone or
+// signatureUnchecked with a detail. An error means the signature is bad.
+func verifyChecksumsSignature(repo string, ref repoRef, version string, sums []byte) (signer, detail string, err error) {
+	// cosign keyless: the certificate must name a workflow in the repo,
+	// issued to GitHub Actions or to the GitLab instance's CI.
+	sig, hasSig, err := fetchReleaseAsset(ref, version, releaseChecksumsAsset+".sig")
+	if err != nil {
+		return "", "", err
+	}
+	cert, hasCert, err := fetchReleaseAsset(ref, version, releaseChecksumsAsset+".pem")
+	if err != nil {
+		return "", "", err
+	}
+	if hasSig && hasCert {
+		if _, err := exec.LookPath("cosign"); err != nil {
+			return signatureUnchecked, "signed with cosign; install cosign to verify it", nil
+		}
+		err := runVerifier(map[string][]byte{"checksums.txt": sums, "checksums.txt.sig": sig, "checksums.txt.pem": cert},
+			"cosign", "verify-blob",
+			"--certificate", "{checksums.txt.pem}",
+			"--signature", "{checksums.txt.sig}",
+			"--certificate-identity-regexp", "^https://"+regexp.QuoteMeta(ref.String())+"/",
+			"--certificate-oidc-issuer", oidcIssuer(ref),
+			"{checksums.txt}")
+		if err != nil {
+			return "", "", err
+		}
+		return "cosign", "", nil
+	}
+
+	// minisign: the public key comes from minisign_keys in the global
+	// config, never from the release itself.
+	minisig, hasMinisig, err := fetchReleaseAsset(ref, version, releaseChecksumsAsset+".minisig")
+	if err != nil {
+		return "", "", err
+	}
+	if hasMinisig {
+		key := loadGlobalConfig().MinisignKeys[repo]
+		if key == "" {
+			return signatureUnchecked, fmt.Sprintf("signed with minisign; add its public key to minisign_keys in %s to verify it", globalConfigPath()), nil
+		}
+		if _, err := exec.LookPath("minisign"); err != nil {
+			return signatureUnchecked, "signed with minisign; install minisign to verify it", nil
+		}
+		err := runVerifier(map[string][]byte{"checksums.txt": sums, "checksums.txt.minisig": minisig},
+			"minisign", "-V", "-P", key, "-m", "{checksums.txt}", "-x", "{checksums.txt.minisig}")
+		if err != nil {
+			return "", "", err
+		}
+		return "minisign", "", nil
+	}
+	return signatureNone, "", nil
+}
+
+// runVerifier writes files to a temp directory and runs the verifier with
+// args, where "{name}" stands for the path of file name.
+func runVerifier(files map[string][]byte, name string, args ...string) error {
+	dir, err := os.MkdirTemp("", "orchestra-verify-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	for file, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, file), data, 0644); err != nil {
+			return err
+		}
+	}
+	for i, arg := range args {
+		if strings.HasPrefix(arg, "{") && strings.HasSuffix(arg, "}") {
+			args[i] = filepath.Join(dir, arg[1:len(arg)-1])
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), signatureTimeout)
+	defer cancel()
+	var stderr strings.Builder
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = io.Discard
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if detail := lastLine(stderr.String()); detail != "" {
+			return fmt.Errorf("%s: %s", name, detail)
+		}
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// oidcIssuer is the OIDC issuer of the CI that signs ref's releases.
+func oidcIssuer(ref repoRef) string {
+	if ref.isGitHub() {
+		return "https://token.actions.githubusercontent.com"
+	}
+	return "https://" + ref.Host
+}
+
+// fetchReleaseAsset downloads a small asset from ref's release version. ok
+// is false when the release does not publish it.
+func fetchReleaseAsset(ref repoRef, version, asset string) (data []byte, ok bool, err error) {
+	url, err := releaseAssetLocation(ref, version, asset)
+	if errors.Is(err, errNoReleaseAsset) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	resp, err := githubGet(url, 30*time.Second)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, false, nil
+	default:
+		return nil, false, fmt.Errorf("HTTP %d from %s", resp.StatusCode, redactURL(url))
+	}
+	data, err = io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+// pluginModified returns why p's binary no longer matches the checksum
+// recorded when it was installed, or "" when it does or none was recorded.
+func pluginModified(p *PluginEntry) string {
+	if p.SHA256 == "" {
+		return ""
+	}
+	sum, err := fileSHA256(p.Binary)
+	if err != nil {
+		return "" // a missing binary is reported as such
+	}
+	if sum != p.SHA256 {
+		return fmt.Sprintf("binary changed since install (sha256 %s, recorded %s)", sum[:12], p.SHA256[:min(12, len(p.SHA256))])
+	}
+	return ""
+}
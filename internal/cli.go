@@ -0,0 +1,448 @@
+package internal
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Command is a node in the orchestra command tree. Leaf commands set Run;
+// group commands (like `pack`) list Subcommands and may omit Run.
+type Command struct {
+	Name        string
+	Aliases     []string // silent synonyms
+	Deprecated  []string // old names that still work but print a warning
+	Summary     string   // one line, shown in command lists
+	Usage       string   // argument synopsis after the command path
+	Description string   // optional longer help, examples
+	Hidden      bool
+	JSON        bool // prints its result as JSON with --json; other commands reject the flag
+	Run         func(args []string)
+	Subcommands []*Command
+
+	parent *Command
+}
+
+// Path returns the space-separated command path without the root name,
+// e.g. "pack install".
+func (c *Command) Path() string {
+	if c.parent == nil {
+		return ""
+	}
+	if p := c.parent.Path(); p != "" {
+		return p + " " + c.Name
+	}
+	return c.Name
+}
+
+// find returns the direct subcommand matching name, one of its aliases, or
+// one of its deprecated names. deprecated is true for the latter.
+func (c *Command) find(name string) (sub *Command, deprecated bool) {
+	for _, sub := range c.Subcommands {
+		if sub.Name == name {
+			return sub, false
+		}
+		for _, alias := range sub.Aliases {
+			if alias == name {
+				return sub, false
+			}
+		}
+		for _, old := range sub.Deprecated {
+			if old == name {
+				return sub, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// link sets parent pointers throughout the tree.
+func (c *Command) link() {
+	for _, sub := range c.Subcommands {
+		sub.parent = c
+		sub.link()
+	}
+}
+
+// globalFlags are accepted before the command name and by every command.
+type globalFlags struct {
+	noColor   bool
+	noHooks   bool
+	debug     bool
+	debugFile string
+	json      bool
+	plain     bool
+}
+
+var globals globalFlags
+
+// registerGlobalFlags binds the global flags into fs.
+func registerGlobalFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&globals.noColor, "no-color", globals.noColor, "Disable colored output")
+	fs.BoolVar(&globals.noHooks, "no-hooks", globals.noHooks, "Run no lifecycle hooks from the config files")
+	fs.BoolVar(&globals.debug, "debug", globals.debug, "Log each step and how long it took to stderr")
+	fs.StringVar(&globals.debugFile, "debug-file", globals.debugFile, "Append --debug output to `FILE` instead of stderr")
+	fs.BoolVar(&globals.json, "json", globals.json, "Print the result as JSON on stdout, for commands that list or describe things")
+	fs.BoolVar(&globals.plain, "plain", globals.plain, "Screen-reader-friendly output: no color, box drawing, aligned columns, or spinners")
+}
+
+// Main dispatches os.Args[1:] through the command tree.
+func Main(args []string) {
+	// Started by the orchestrator in place of a plugin; see servesupervise.go.
+	if os.Getenv(pluginSuperviseEnv) != "" {
+		if id, binary, rest := superviseArgs(args); id != "" && binary != "" {
+			runPluginSupervisor(id, binary, rest)
+		}
+	}
+
+	root := rootCommand
+
+	// Leading global flags apply to whatever command follows.
+	for len(args) > 0 && isGlobalFlag(args[0]) {
+		args = args[applyGlobalFlag(args):]
+	}
+
+	applyProxyConfig()
+	if len(args) == 0 || args[0] != "org" {
+		refreshOrgInBackground()
+	}
+
+	if len(args) == 0 {
+		// No subcommand = default to serve (MCP clients call "command": "orchestra").
+		RunServe(nil)
+		return
+	}
+
+	switch args[0] {
+	case "--version", "-v":
+		RunVersion(nil)
+		return
+	case "--help", "-h":
+		printCommandHelp(root, nil)
+		return
+	}
+
+	// Bare flags are serve flags (e.g. "orchestra --workspace=.").
+	if strings.HasPrefix(args[0], "-") {
+		RunServe(args)
+		return
+	}
+
+	dispatch(root, args)
+}
+
+// dispatch walks down the tree consuming command names, then runs the
+// deepest matching command with the remaining args.
+func dispatch(cmd *Command, args []string) {
+	for len(args) > 0 {
+		sub, deprecated := cmd.find(args[0])
+		if sub == nil {
+			break
+		}
+		if deprecated {
+			warnDeprecated(cmd, args[0], sub)
+		}
+		cmd = sub
+		args = args[1:]
+	}
+
+	if cmd.Run != nil {
+		if !cmd.JSON && (globals.json || jsonFlagGiven(args)) {
+			fatal("orchestra %s has no JSON output; run it without --json", cmd.Path())
+		}
+		start := time.Now() // --debug is known only once Run parses its flags
+		cmd.Run(args)
+		debugf("orchestra %s: done (%s)", cmd.Path(), debugDuration(time.Since(start)))
+		return
+	}
+
+	// Group command: print its help, or reject an unknown subcommand.
+	if len(args) == 0 || args[0] == "--help" || args[0] == "-h" || args[0] == "help" {
+		printCommandHelp(cmd, nil)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "orchestra: unknown command %q\n", strings.TrimSpace(cmd.Path()+" "+args[0]))
+	fmt.Fprintf(os.Stderr, "Run '%s' for usage.\n", strings.TrimSpace("orchestra help "+cmd.Path()))
+	os.Exit(2)
+}
+
+// lookupCommand resolves a space-separated command path like "pack install".
+func lookupCommand(path string) *Command {
+	cmd := rootCommand
+	for _, name := range strings.Fields(path) {
+		sub, _ := cmd.find(name)
+		if sub == nil {
+			return nil
+		}
+		cmd = sub
+	}
+	return cmd
+}
+
+// warnDeprecated tells the user that old (under parent) has been renamed.
+func warnDeprecated(parent *Command, old string, cmd *Command) {
+	oldPath := strings.TrimSpace(parent.Path() + " " + old)
+	fmt.Fprintf(os.Stderr, "%s 'orchestra %s' is deprecated; use 'orchestra %s' instead.\n",
+		statusTag(tagWarn), oldPath, cmd.Path())
+}
+
+// jsonFlagGiven reports whether args turn on --json. ORCHESTRA_OUTPUT=json
+// is not a flag: it asks for JSON from the commands that have it and leaves
+// the others alone.
+func jsonFlagGiven(args []string) bool {
+	on := false
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "json" {
+			continue
+		}
+		on = !hasValue
+		if hasValue {
+			on, _ = strconv.ParseBool(value)
+		}
+	}
+	return on
+}
+
+func isGlobalFlag(arg string) bool {
+	switch arg {
+	case "--no-color", "-no-color", "--no-hooks", "-no-hooks",
+		"--debug", "-debug", "--debug-file", "-debug-file", "--json", "-json", "--plain", "-plain":
+		return true
+	}
+	return strings.HasPrefix(arg, "--debug-file=") || strings.HasPrefix(arg, "-debug-file=")
+}
+
+// applyGlobalFlag applies the global flag at args[0] and returns how many
+// args it took: 2 for "--debug-file FILE".
+func applyGlobalFlag(args []string) int {
+	name, value, hasValue := strings.Cut(strings.TrimLeft(args[0], "-"), "=")
+	switch name {
+	case "no-color":
+		globals.noColor = true
+	case "no-hooks":
+		globals.noHooks = true
+	case "debug":
+		globals.debug = true
+	case "json":
+		globals.json = true
+	case "plain":
+		globals.plain = true
+	case "debug-file":
+		if hasValue {
+			globals.debugFile = value
+			return 1
+		}
+		if len(args) < 2 {
+			fatal("flag needs an argument: --debug-file")
+		}
+		globals.debugFile = args[1]
+		return 2
+	}
+	return 1
+}
+
+// newFlagSet creates the flag set for the command at path. Help output is
+// generated from the command tree, and global flags are registered so every
+// command accepts them.
+func newFlagSet(path string) *flag.FlagSet {
+	fs := flag.NewFlagSet(path, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	registerGlobalFlags(fs)
+	fs.Usage = func() {
+		if cmd := lookupCommand(path); cmd != nil {
+			printCommandHelp(cmd, fs)
+		} else {
+			fs.PrintDefaults()
+		}
+	}
+	return fs
+}
+
+// parseFlags parses args into fs, allowing flags to appear after positional
+// arguments (`orchestra install <repo> --source`). A "--" ends flag parsing.
+// On -h/--help it prints help and exits 0; on a bad flag it exits 2. Flags
+// not given are then filled from ORCHESTRA_* and config (applyFlagDefaults).
+func parseFlags(fs *flag.FlagSet, args []string) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				os.Exit(0)
+			}
+			os.Exit(2)
+		}
+		rest := fs.Args()
+		if len(rest) == 0 {
+			break
+		}
+		// flag stops at a consumed "--" or at the first non-flag argument.
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			positional = append(positional, rest...)
+			break
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+
+	// Re-parse so fs.Args() reports the collected positional arguments.
+	fs.Parse(append([]string{"--"}, positional...))
+
+	applyFlagDefaults(fs)
+}
+
+// printCommandHelp writes generated help for cmd. fs, when non-nil, supplies
+// the command's flags.
+func printCommandHelp(cmd *Command, fs *flag.FlagSet) {
+	var w io.Writer = os.Stderr
+	path := strings.TrimSpace("orchestra " + cmd.Path())
+
+	if cmd.Summary != "" {
+		fmt.Fprintf(w, "%s\n\n", plainText(path+" — "+cmd.Summary))
+	}
+
+	fmt.Fprintf(w, "Usage:\n")
+	switch {
+	case cmd.Run != nil:
+		fmt.Fprintf(w, "  %s %s\n", path, cmd.Usage)
+	case len(cmd.Subcommands) > 0:
+		fmt.Fprintf(w, "  %s <command> [flags]\n", path)
+	}
+
+	if len(cmd.Subcommands) > 0 {
+		fmt.Fprintf(w, "\nCommands:\n")
+		tw := newTable(w)
+		for _, sub := range cmd.Subcommands {
+			if sub.Hidden {
+				continue
+			}
+			fmt.Fprintf(tw, "  %s\t%s\n", sub.Name, sub.Summary)
+		}
+		tw.Flush()
+	}
+
+	if len(cmd.Aliases) > 0 {
+		fmt.Fprintf(w, "\nAliases: %s\n", strings.Join(cmd.Aliases, ", "))
+	}
+	if len(cmd.Deprecated) > 0 {
+		fmt.Fprintf(w, "Deprecated names: %s\n", strings.Join(cmd.Deprecated, ", "))
+	}
+
+	if fs != nil {
+		var local, global []*flag.Flag
+		fs.VisitAll(func(f *flag.Flag) {
+			if isGlobalFlag("--" + f.Name) {
+				global = append(global, f)
+			} else {
+				local = append(local, f)
+			}
+		})
+		if len(local) > 0 {
+			fmt.Fprintf(w, "\nFlags:\n")
+			printFlags(w, local)
+		}
+		if len(global) > 0 {
+			fmt.Fprintf(w, "\nGlobal flags:\n")
+			printFlags(w, global)
+		}
+		fmt.Fprintf(w, "\nAny flag can also be set with ORCHESTRA_<FLAG> (e.g. ORCHESTRA_WORKSPACE) or under\ndefaults: in .orchestra.yaml or ~/.orchestra/config.yaml, e.g. with\n'orchestra config set defaults.<flag> VALUE'. See 'Configuration' in docs/COMMANDS.md.\n")
+	}
+
+	if cmd.Description != "" {
+		fmt.Fprintf(w, "\n%s\n", strings.TrimRight(cmd.Description, "\n"))
+	}
+
+	if len(cmd.Subcommands) > 0 {
+		fmt.Fprintf(w, "\nRun '%s' for details on a command.\n", strings.Join(strings.Fields("orchestra help "+cmd.Path()+" <command>"), " "))
+	}
+}
+
+// printFlags lists flags as "--name=VALUE  usage (default: x)".
+func printFlags(w io.Writer, flags []*flag.Flag) {
+	tw := newTable(w)
+	for _, f := range flags {
+		name, usage := flag.UnquoteUsage(f)
+		arg := "--" + f.Name
+		if name != "" {
+			arg += "=" + strings.ToUpper(name)
+		}
+		if f.DefValue != "" && f.DefValue != "false" {
+			usage += fmt.Sprintf(" (default: %s)", f.DefValue)
+		}
+		fmt.Fprintf(tw, "  %s\t%s\n", arg, usage)
+	}
+	tw.Flush()
+}
+
+// runHelp handles `orchestra help [command...]`.
+func runHelp(args []string) {
+	cmd := lookupCommand(strings.Join(args, " "))
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "orchestra: unknown command %q\n", strings.Join(args, " "))
+		os.Exit(2)
+	}
+	if cmd.Run != nil && cmd != lookupCommand("help") {
+		// Leaf commands know their own flags; let them print help.
+		cmd.Run([]string{"--help"})
+		return
+	}
+	printCommandHelp(cmd, nil)
+}
+
+// walkCommands calls fn for every command below root in tree order.
+func walkCommands(root *Command, fn func(*Command)) {
+	for _, sub := range root.Subcommands {
+		fn(sub)
+		walkCommands(sub, fn)
+	}
+}
+
+// runCommands handles `orchestra commands` -- lists every command path with
+// its aliases and deprecated names so wrappers and shell completions can stay
+// in sync with the CLI surface.
+func runCommands(args []string) {
+	fs := newFlagSet("commands")
+	porcelain := fs.Bool("porcelain", false, "Stable tab-separated output for scripts")
+	parseFlags(fs, args)
+
+	// Porcelain: name path, kind (command, alias, deprecated), canonical path.
+	if *porcelain {
+		walkCommands(rootCommand, func(c *Command) {
+			prefix := c.parent.Path()
+			fmt.Fprintf(os.Stdout, "%s\tcommand\t%s\n", c.Path(), c.Path())
+			for _, a := range c.Aliases {
+				fmt.Fprintf(os.Stdout, "%s\talias\t%s\n", strings.TrimSpace(prefix+" "+a), c.Path())
+			}
+			for _, d := range c.Deprecated {
+				fmt.Fprintf(os.Stdout, "%s\tdeprecated\t%s\n", strings.TrimSpace(prefix+" "+d), c.Path())
+			}
+		})
+		return
+	}
+
+	tw := newTable(os.Stdout)
+	walkCommands(rootCommand, func(c *Command) {
+		if c.Hidden {
+			return
+		}
+		var names []string
+		names = append(names, c.Aliases...)
+		for _, d := range c.Deprecated {
+			names = append(names, d+" (deprecated)")
+		}
+		aliases := ""
+		if len(names) > 0 {
+			aliases = "[" + strings.Join(names, ", ") + "]"
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\n", c.Path(), aliases, c.Summary)
+	})
+	tw.Flush()
+}
@@ -0,0 +1,536 @@
+package internal
+
+// rootCommand is the orchestra command tree. It is built in init because
+// command Run functions refer back to the tree for help output.
+var rootCommand *Command
+
+func init() {
+	rootCommand = &Command{
+		Name:    "orchestra",
+		Summary: "AI-agentic project management via MCP",
+		Subcommands: []*Command{
+			{
+				Name:    "serve",
+				Aliases: []string{"start"},
+				Summary: "Start the MCP stdio server (default)",
+				Usage:   "[flags]",
+				Run:     RunServe,
+				Subcommands: []*Command{
+					{
+						Name:    "switch",
+						Summary: "Point a running serve session at another workspace",
+						Usage:   "<workspace> [--pid=N | --from=DIR]",
+						Run:     RunServeSwitch,
+					},
+				},
+			},
+			{
+				Name:    "stop",
+				Summary: "Stop the serve daemon running for the workspace",
+				Usage:   "[flags]",
+				Run:     RunStop,
+			},
+			{
+				Name:    "logs",
+				Summary: "Show the serve log, filtered by time, level, and plugin",
+				Usage:   "[flags]",
+				Description: `Examples:
+  orchestra logs --follow
+  orchestra logs --since=10m --level=warn
+  orchestra logs --plugin='tools.*' --raw`,
+				Run: RunLogs,
+			},
+			{
+				Name:    "setup",
+				Summary: "First-run wizard: prerequisites, binaries, certs, global config",
+				Usage:   "[flags]",
+				Run:     RunSetup,
+			},
+			{
+				Name:    "config",
+				Summary: "Read and edit ~/.orchestra/config.yaml and .orchestra.yaml",
+				Description: `Keys:
+  defaults.<flag>              default for --<flag> in every command (certs-dir, ide, debug, channel, ...)
+  pack_indexes                 pack index URLs or files, comma-separated
+  mirror.download, mirror.api  GitHub download and API mirrors
+  proxy.http, proxy.https, proxy.no_proxy
+                               proxy for orchestra and git, unless set in the environment
+  auto_update                  notify (default) or off: whether init checks for a newer release
+  org.refresh                  how often org settings sync again (24h default, 0 never)
+  github_token, gitlab_token, gitlab_hosts
+  credentials.<host>.token, credentials.<host>.username
+  minisign_keys.<repo>
+  conventions.branch, conventions.commit, estimates.<size>, tool_timeouts.<tool>,
+  log_levels.<plugin>
+                               workspace settings (--local)
+
+Writes go to ~/.orchestra/config.yaml unless --local is given or the key
+is a workspace setting. Reads prefer the workspace, as commands do.
+
+Examples:
+  orchestra config set defaults.certs-dir /etc/orchestra/certs
+  orchestra config set defaults.ide cursor,claude --local
+  orchestra config set pack_indexes https://packs.example.com/index.json
+  orchestra config get defaults.ide --show-origin
+  orchestra config list`,
+				Subcommands: []*Command{
+					{Name: "list", Aliases: []string{"ls"}, Summary: "List the settings in both config files", Usage: "[--global|--local] [--show-secrets] [flags]", Run: runConfigList, JSON: true},
+					{Name: "get", Summary: "Print a setting's value", Usage: "<key> [--global|--local] [--show-origin] [flags]", Run: runConfigGet},
+					{Name: "set", Summary: "Set a setting", Usage: "<key> <value> [--local] [flags]", Run: runConfigSet},
+					{Name: "unset", Summary: "Remove a setting", Usage: "<key> [--local] [flags]", Run: runConfigUnset},
+				},
+			},
+			{
+				Name:    "org",
+				Summary: "Sync shared settings (allowed sources, default packs, policy, labels) from an org repo",
+				Description: `The settings repo has a settings.yaml at its root; see docs/COMMANDS.md.
+They are stored in ~/.orchestra/config.yaml and refreshed in the
+background once older than org.refresh.
+
+Examples:
+  orchestra org sync --from=github.com/mycorp/orchestra-settings
+  orchestra org sync --from=github.com/mycorp/orchestra-settings@v2 --refresh=7d
+  orchestra org status`,
+				Subcommands: []*Command{
+					{Name: "sync", Summary: "Fetch the org settings now", Usage: "[--from=REPO[@REF]|DIR] [--refresh=DURATION] [--quiet] [flags]", Run: runOrgSync},
+					{Name: "status", Summary: "Show the synced org settings and when they were fetched", Usage: "[flags]", Run: runOrgStatus},
+					{Name: "leave", Summary: "Remove the org settings", Usage: "[flags]", Run: runOrgLeave},
+				},
+			},
+			{
+				Name:    "doctor",
+				Summary: "Check the installation and workspace wiring; --fix repairs simple problems",
+				Usage:   "[flags]",
+				JSON:    true,
+				Run:     RunDoctor,
+			},
+			{
+				Name:    "bench",
+				Summary: "Time backend cold starts and MCP round-trips against a saved baseline",
+				Usage:   "[flags]",
+				Run:     RunBench,
+			},
+			{
+				Name:    "init",
+				Summary: "Initialize MCP configs for your IDE(s)",
+				Usage:   "[flags]",
+				Run:     RunInit,
+			},
+			{
+				Name:    "deinit",
+				Summary: "Remove orchestra from a workspace: IDE config entries, bundled content, packs, orchestra's files in .projects/",
+				Usage:   "[--keep-packs] [--purge-features] [--dry-run] [--yes] [flags]",
+				Run:     RunDeinit,
+			},
+			{
+				Name:    "install",
+				Summary: "Install a plugin from a git repo or a local directory",
+				Usage:   "<repo>[//dir][@version] | <path> [flags]",
+				Description: `Examples:
+  orchestra install github.com/someone/my-plugin
+  orchestra install github.com/someone/my-plugin@v1.2.0
+  orchestra install github.com/someone/my-plugin --source
+  orchestra install github.com/orchestra-mcp/sdk-go --dev
+  orchestra install github.com/someone/my-plugin --dry-run
+  orchestra install github.com/someone/my-plugin --vendor
+  orchestra install github.com/someone/my-plugin --target=linux/arm64
+  orchestra install github.com/someone/monorepo//plugins/my-plugin
+  orchestra install ./plugins/my-plugin`,
+				Run: RunInstall,
+			},
+			{
+				Name:    "provision",
+				Summary: "Install the plugins in a bundle made by install --target",
+				Usage:   "<bundle-dir> [flags]",
+				Run:     RunProvision,
+			},
+			{
+				Name:    "pack",
+				Summary: "Manage content packs (skills, agents, hooks)",
+				Description: `Examples:
+  orchestra pack install github.com/orchestra-mcp/pack-go-backend
+  orchestra pack install github.com/orchestra-mcp/pack-essentials@v0.1.0
+  orchestra pack remove orchestra-mcp/pack-go-backend
+  orchestra pack sync
+  orchestra pack search go
+  orchestra pack recommend`,
+				Subcommands: []*Command{
+					{Name: "install", Summary: "Install a pack from GitHub", Usage: "<repo>[@version] [flags]", Run: runPackInstall},
+					{Name: "remove", Deprecated: []string{"uninstall"}, Summary: "Remove an installed pack", Usage: "<name> [flags]", Run: runPackRemove},
+					{Name: "update", Summary: "Update one or all packs", Usage: "[name] [flags]", Run: runPackUpdate},
+					{Name: "sync", Summary: "Install exactly the packs packs.lock records", Usage: "[flags]", Run: runPackSync},
+					{Name: "list", Aliases: []string{"ls"}, Summary: "List installed packs", Usage: "[flags]", Run: runPackList, JSON: true},
+					{Name: "info", Summary: "Show a pack's metadata and compatibility", Usage: "<name|repo>[@version] [flags]", Run: runPackInfo, JSON: true},
+					{Name: "keygen", Summary: "Create a signing key for publishing packs", Usage: "<publisher>", Run: runPackKeygen},
+					{Name: "sign", Summary: "Write pack.sum and pack.sig for a pack directory", Usage: "[dir] --publisher=NAME [flags]", Run: runPackSign},
+					{Name: "verify", Summary: "Verify a pack directory's signature", Usage: "[dir]", Run: runPackVerify},
+					{Name: "init", Summary: "Scaffold a new pack repository", Usage: "<name> [flags]", Run: runPackInit},
+					{Name: "validate", Summary: "Check a pack directory before publishing", Usage: "[dir] [flags]", Run: runPackValidate},
+					{
+						Name:    "trust",
+						Summary: "Manage trusted pack publishers",
+						Subcommands: []*Command{
+							{Name: "add", Summary: "Trust a publisher's public key", Usage: "<publisher> <public-key>", Run: runPackTrustAdd},
+							{Name: "remove", Summary: "Stop trusting a publisher or one of its keys", Usage: "<publisher> [public-key]", Run: runPackTrustRemove},
+							{Name: "list", Aliases: []string{"ls"}, Summary: "List trusted publishers", Usage: "[flags]", Run: runPackTrustList, JSON: true},
+						},
+					},
+					{Name: "search", Summary: "Search available packs", Usage: "<query> [flags]", Run: runPackSearch, JSON: true},
+					{Name: "recommend", Summary: "Detect stacks & recommend packs", Usage: "[flags]", Run: runPackRecommend, JSON: true},
+					{
+						Name:    "index",
+						Summary: "Manage the pack indexes search and recommend read",
+						Subcommands: []*Command{
+							{Name: "refresh", Summary: "Fetch every pack index again", Run: runPackIndexRefresh},
+							{Name: "list", Aliases: []string{"ls"}, Summary: "List the pack index sources and when each was fetched", Usage: "[flags]", Run: runPackIndexList, JSON: true},
+						},
+					},
+				},
+			},
+			{
+				Name:    "outdated",
+				Summary: "Compare installed packs and plugins with their latest versions",
+				Usage:   "[flags]",
+				JSON:    true,
+				Run:     RunOutdated,
+			},
+			{
+				Name:    "drift",
+				Summary: "Compare .claude/ content with what installed packs shipped",
+				Usage:   "[pack...] [flags]",
+				Description: `Examples:
+  orchestra drift
+  orchestra drift pack-go-backend --adopt
+  orchestra drift --restore`,
+				Run: RunDrift,
+			},
+			{
+				Name:    "plugins",
+				Summary: "List installed plugins",
+				Usage:   "[flags]",
+				JSON:    true,
+				Run:     RunPlugins,
+				Subcommands: []*Command{
+					{Name: "info", Summary: "Show a plugin's tools, prompts, resources, and storage", Usage: "<plugin-id-or-repo>", Run: runPluginsInfo, JSON: true},
+					{Name: "enable", Summary: "Load a plugin in this workspace again, or with --global in every workspace", Usage: "<plugin-id-or-repo> [flags]", Run: runPluginsEnable},
+					{Name: "disable", Summary: "Stop loading a plugin in this workspace, or with --global in every workspace", Usage: "<plugin-id-or-repo> [flags]", Run: runPluginsDisable},
+					{Name: "trust", Summary: "Let serve run the plugins this workspace vendors, or with --revoke stop it", Usage: "[flags]", Run: runPluginsTrust},
+				},
+			},
+			{
+				Name:    "search",
+				Summary: "Search packs, plugins, and installed content",
+				Usage:   "<query> [flags]",
+				JSON:    true,
+				Run:     RunSearch,
+			},
+			{
+				Name:    "browse",
+				Summary: "Browse packs and plugins interactively and install them",
+				Usage:   "[query] [flags]",
+				Description: `Keys:
+  ↑↓ / j k   move
+  enter      details: pack.json and the README, fetched from GitHub
+  /          search; empty clears
+  tab        packs, plugins, or both
+  i          install the selected pack or plugin
+  r          fetch the pack index again
+  q          quit (esc leaves the details)
+
+✓ marks what is installed, ★ packs that suit the workspace's stacks.`,
+				Run: RunBrowse,
+			},
+			{
+				Name:       "uninstall",
+				Deprecated: []string{"remove"},
+				Summary:    "Remove an installed plugin",
+				Usage:      "<plugin-id-or-repo>",
+				Run:        RunUninstall,
+			},
+			{
+				Name:    "update",
+				Aliases: []string{"upgrade"},
+				Summary: "Update Orchestra or an installed plugin; --pr updates packs via a pull request",
+				Usage:   "[plugin-id-or-repo] | --pr [flags]",
+				Run:     RunUpdate,
+			},
+			{
+				Name:    "version",
+				Summary: "Print version info",
+				Usage:   "[flags]",
+				JSON:    true,
+				Run:     RunVersion,
+			},
+			{
+				Name:    "release",
+				Summary: "Tools for maintainers publishing orchestra releases",
+				Subcommands: []*Command{
+					{Name: "scaffold", Summary: "Generate a Homebrew formula, install.sh, and Scoop manifest for a release", Usage: "[--version=TAG] [--repo=OWNER/REPO] [flags]", Run: runReleaseScaffold},
+				},
+			},
+			{
+				Name:    "encrypt-workspace",
+				Summary: "Encrypt the .projects/ feature store at rest, or manage its key",
+				Usage:   "[--age-recipients=R,...] | --decrypt | --export-key | --import-key [flags]",
+				Run:     RunEncryptWorkspace,
+			},
+			{
+				Name:    "upgrade-workspace",
+				Summary: "Migrate .projects/ to the current schema version",
+				Usage:   "[flags]",
+				Run:     RunUpgradeWorkspace,
+			},
+			{
+				Name:    "docs",
+				Summary: "Regenerate CLAUDE.md, AGENTS.md, and PROGRESS.md",
+				Subcommands: []*Command{
+					{Name: "regen", Summary: "Regenerate the workspace docs from installed skills, agents, hooks, and packs", Usage: "[flags]", Run: RunDocsRegen},
+				},
+			},
+			{
+				Name:    "schema",
+				Summary: "Publish JSON Schemas for the files orchestra reads and writes",
+				Description: `Examples:
+  orchestra schema export pack > pack.schema.json
+  orchestra schema export --out=schemas`,
+				Subcommands: []*Command{
+					{Name: "export", Summary: "Print or write JSON Schemas", Usage: "[name...] [--out=DIR]", Run: runSchemaExport},
+					{Name: "list", Aliases: []string{"ls"}, Summary: "List the published schemas", Usage: "[flags]", Run: runSchemaList, JSON: true},
+				},
+			},
+			{
+				Name:    "hooks",
+				Summary: "List, trust, and try the lifecycle hooks in config",
+				Description: `Hooks are shell commands under hooks: in ~/.orchestra/config.yaml or
+.orchestra.yaml, run at pre_install, post_install, pre_pack_install,
+post_pack_install, pre_pack_remove, post_pack_remove, post_init,
+pre_serve, and post_serve. A workspace's hooks run once trusted.
+
+Examples:
+  orchestra hooks list
+  orchestra hooks trust
+  orchestra hooks run post_pack_install`,
+				Subcommands: []*Command{
+					{Name: "list", Aliases: []string{"ls"}, Summary: "List hooks and whether they are trusted", Usage: "[flags]", Run: runHooksList, JSON: true},
+					{Name: "trust", Summary: "Let the workspace's hooks run", Usage: "[--yes] [--revoke] [flags]", Run: runHooksTrust},
+					{Name: "run", Summary: "Run an event's hooks now", Usage: "<event> [flags]", Run: runHooksRun},
+				},
+			},
+			{
+				Name:    "convention",
+				Summary: "Generate branch names and commit messages for a feature",
+				Subcommands: []*Command{
+					{Name: "branch", Summary: "Print the branch name for a feature", Usage: "<feature-id> [flags]", Run: runConventionBranch},
+					{Name: "commit", Summary: "Print the commit message for a feature", Usage: "<feature-id> [flags]", Run: runConventionCommit},
+				},
+			},
+			{
+				Name:    "features",
+				Summary: "Bulk-create features, manage templates, and view epics",
+				Description: `Examples:
+  orchestra features create -f plan.yaml
+  orchestra features create -f backlog.csv --project=my-app --dry-run
+  orchestra features templates
+  orchestra features list --archived`,
+				Subcommands: []*Command{
+					{Name: "list", Aliases: []string{"ls"}, Summary: "List features, or archived ones with --archived", Usage: "[--project=NAME] [--status=S] [--label=L] [--archived] [flags]", Run: runFeaturesList, JSON: true},
+					{Name: "create", Summary: "Create features from a YAML or CSV file", Usage: "-f FILE [flags]", Run: runFeaturesCreate},
+					{Name: "templates", Summary: "List feature templates (bug, spike, epic, ...)", Usage: "[flags]", Run: runFeaturesTemplates},
+					{Name: "tree", Summary: "Show epics and their children with rolled-up completion", Usage: "[flags]", Run: runFeaturesTree},
+					{Name: "parent", Summary: "Set or clear a feature's parent epic", Usage: "<feature-id> <parent-id> | --clear [flags]", Run: runFeaturesParent},
+					{Name: "graph", Summary: "Print the dependency and epic graph (Mermaid or DOT)", Usage: "[flags]", Run: runFeaturesGraph},
+				},
+			},
+			{
+				Name:    "labels",
+				Summary: "Manage the workspace label taxonomy and rewrite feature labels",
+				Description: `Labels and assignees listed in .orchestra.yaml are the ones CLAUDE.md
+tells the agent to use; features created in bulk warn about any other.
+
+Examples:
+  orchestra labels add frontend --color=blue --description="Web UI"
+  orchestra labels rename front-end frontend --dry-run
+  orchestra labels merge ui web-ui frontend
+  orchestra labels check --fix`,
+				Subcommands: []*Command{
+					{Name: "list", Aliases: []string{"ls"}, Summary: "List the taxonomy and labels in use outside it", Usage: "[--porcelain] [flags]", Run: runLabelsList, JSON: true},
+					{Name: "add", Summary: "Add a label to the taxonomy, or update its color and description", Usage: "<label> [--color=C] [--description=TEXT] [--force] [flags]", Run: runLabelsAdd},
+					{Name: "rename", Summary: "Rename a label in the taxonomy and every feature", Usage: "<old> <new> [--dry-run] [flags]", Run: runLabelsRename},
+					{Name: "merge", Summary: "Fold labels into one in the taxonomy and every feature", Usage: "<label>... <into> [--dry-run] [flags]", Run: runLabelsMerge},
+					{Name: "check", Summary: "Report features whose labels or assignee are outside the taxonomy", Usage: "[--fix] [flags]", Run: runLabelsCheck},
+				},
+			},
+			{
+				Name:    "plan",
+				Summary: "Turn a markdown plan or PRD into features",
+				Description: `Top-level sections with items become epics, their list items and
+subsections features. #words in titles are labels, #P0-#P3 priorities,
+and "(depends on: A, B)" or a "Depends on:" line adds dependencies.
+
+Examples:
+  orchestra plan import PLAN.md --dry-run
+  orchestra plan import docs/prd.md --project=my-app --label=v2`,
+				Subcommands: []*Command{
+					{Name: "import", Summary: "Preview a plan as features, then create them", Usage: "<file.md> [--project=NAME] [--label=LIST] [--skip=LIST] [--dry-run] [--yes] [flags]", Run: runPlanImport},
+				},
+			},
+			{
+				Name:    "board",
+				Summary: "Interactive kanban board of the feature lifecycle",
+				Usage:   "[flags]",
+				Description: `Keys:
+  ←→ / h l   move between columns
+  ↑↓ / j k   move between cards
+  a          advance the card (prompts for evidence at gates)
+  x          reject a card in in-review (back to needs-edits)
+  /          filter: label=X, assignee=Y, or text; empty clears
+  r          refresh now (the board also refreshes every 2s)
+  q          quit`,
+				Run: RunBoard,
+			},
+			{
+				Name:    "review",
+				Summary: "List features in review and record approvals or rejections",
+				Description: `Examples:
+  orchestra review list
+  orchestra review approve FEAT-ABC --notes="Error handling OK, tests cover the retry path"
+  orchestra review reject FEAT-ABC --notes="Missing docs for the new flag"`,
+				Subcommands: []*Command{
+					{Name: "list", Aliases: []string{"ls"}, Summary: "List features waiting for review", Usage: "[flags]", Run: runReviewList, JSON: true},
+					{Name: "approve", Summary: "Approve a feature and move it to done", Usage: "<feature-id> --notes=... [flags]", Run: runReviewApprove},
+					{Name: "reject", Summary: "Send a feature back to needs-edits", Usage: "<feature-id> --notes=... [flags]", Run: runReviewReject},
+				},
+			},
+			{
+				Name:    "status",
+				Summary: "Show the workspace, its serve session, packs, plugins, disk usage, and feature store stats",
+				Usage:   "[flags]",
+				JSON:    true,
+				Run:     RunStatus,
+			},
+			{
+				Name:    "compact",
+				Summary: "Index the feature store for fast loading and compact its history",
+				Usage:   "[--no-index] [flags]",
+				Run:     RunCompact,
+			},
+			{
+				Name:    "archive",
+				Summary: "Move long-done features into compressed archives",
+				Usage:   "[--done-older-than=90d] [--project=NAME] [--dry-run] [--restore=IDS] [flags]",
+				Description: `Archived features leave the feature store but stay listed by
+'orchestra features list --archived', and still count as done for the
+features that depend on them. An epic is archived only with all of its
+children.
+
+Examples:
+  orchestra archive --dry-run
+  orchestra archive --done-older-than=30d --project=my-app
+  orchestra archive --restore=FEAT-ABC`,
+				Run: RunArchive,
+			},
+			{
+				Name:    "backup",
+				Summary: "Back up the workspace's feature store, on demand or on a schedule",
+				Usage:   "[--keep=N] [flags]",
+				Run:     RunBackup,
+				Subcommands: []*Command{
+					{Name: "list", Aliases: []string{"ls"}, Summary: "List the workspace's backups", Usage: "[flags]", Run: runBackupList, JSON: true},
+					{Name: "prune", Summary: "Remove all but the newest backups", Usage: "[--keep=N] [--dry-run]", Run: runBackupPrune},
+					{Name: "restore", Summary: "Replace .projects/ with a backup", Usage: "<id|latest> [--yes]", Run: runBackupRestore},
+					{Name: "schedule", Summary: "Back up on a launchd or systemd timer", Usage: "--hourly|--daily|--weekly [--keep=N] | --remove", Run: runBackupSchedule},
+				},
+			},
+			{
+				Name:    "activity",
+				Summary: "Show git, file, test, and session activity since the last serve session",
+				Usage:   "[--since=last-session|DURATION|DATE] [flags]",
+				Run:     RunActivity,
+				Subcommands: []*Command{
+					{Name: "record", Summary: "Record a test run from a script or git hook", Usage: "test --command=CMD [--exit-code=N]", Run: runActivityRecord},
+					{Name: "hook", Summary: "Record test runs from an agent's PostToolUse hook payload on stdin", Usage: "[flags]", Run: runActivityHook},
+				},
+			},
+			{
+				Name:    "digest",
+				Summary: "Write .projects/DIGEST.md: recent moves, stuck work, up next",
+				Usage:   "[flags]",
+				Run:     RunDigest,
+			},
+			{
+				Name:    "context-size",
+				Summary: "Estimate how many tokens CLAUDE.md, skills, and agents add to context",
+				Usage:   "[flags]",
+				Run:     RunContextSize,
+			},
+			{
+				Name:    "lint-content",
+				Summary: "Check installed skills and agents for common problems",
+				Usage:   "[flags]",
+				Run:     RunLintContent,
+			},
+			{
+				Name:    "time",
+				Summary: "Track time spent on features",
+				Subcommands: []*Command{
+					{Name: "start", Summary: "Start a timer for a feature", Usage: "--feature=ID [flags]", Run: runTimeStart},
+					{Name: "stop", Summary: "Stop a running timer", Usage: "[--feature=ID] [flags]", Run: runTimeStop},
+					{Name: "status", Summary: "Show running timers", Usage: "[flags]", Run: runTimeStatus},
+				},
+			},
+			{
+				Name:    "report",
+				Summary: "Compare feature estimates with tracked time",
+				Usage:   "[flags]",
+				Run:     RunReport,
+			},
+			{
+				Name:    "analytics",
+				Summary: "Cycle time, burndown, and throughput from feature history",
+				Description: `Examples:
+  orchestra analytics cycle-time --since=30d
+  orchestra analytics burndown --since=2w --csv > burndown.csv
+  orchestra analytics throughput --since=2026-01-01`,
+				Subcommands: []*Command{
+					{Name: "cycle-time", Summary: "Days from in-progress to done per feature", Usage: "[flags]", Run: runAnalyticsCycleTime},
+					{Name: "burndown", Summary: "Remaining features per day", Usage: "[flags]", Run: runAnalyticsBurndown},
+					{Name: "throughput", Summary: "Features done per week", Usage: "[flags]", Run: runAnalyticsThroughput},
+				},
+			},
+			{
+				Name:    "explain",
+				Summary: "Explain an MCP tool, lifecycle state, or gate",
+				Usage:   "[tool|state|gate]",
+				Description: `Examples:
+  orchestra explain advance_feature
+  orchestra explain in-review
+  orchestra explain gate 2
+  orchestra explain tools`,
+				Run: RunExplain,
+			},
+			{
+				Name:    "why",
+				Summary: "Show which pack or plugin provides a skill, agent, hook, or tool",
+				Usage:   "<name> [flags]",
+				Description: `Examples:
+  orchestra why deploy
+  orchestra why /review-pr
+  orchestra why create_feature`,
+				Run: RunWhy,
+			},
+			{
+				Name:    "commands",
+				Summary: "List all commands, aliases, and deprecated names",
+				Usage:   "[flags]",
+				Run:     runCommands,
+			},
+			{
+				Name:    "help",
+				Summary: "Show help for a command",
+				Usage:   "[command...]",
+				Run:     runHelp,
+			},
+		},
+	}
+	rootCommand.link()
+}
@@ -0,0 +1,379 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Large feature stores are slow to scan: every feature is a markdown file
+// whose frontmatter has to be parsed. `orchestra compact` writes a derived
+// index of every feature's frontmatter to .projects/.index.json, keyed by
+// file size and modification time. Readers that only need frontmatter
+// (the transition recorder, status, and storage plugins that support it)
+// stat each file and parse only the ones whose size or mtime changed,
+// updating the index as they go. The feature files stay the source of
+// truth; deleting the index is always safe.
+
+// featureIndexVersion is bumped when featureIndex changes incompatibly.
+const featureIndexVersion = 1
+
+// tempFileMaxAge is how old a leftover writeFileAtomic temp file must be
+// before compact removes it, so a write in flight is never disturbed.
+const tempFileMaxAge = time.Hour
+
+// featureIndex is stored in .projects/.index.json, encrypted like the
+// feature files in encrypted workspaces.
+type featureIndex struct {
+	Version  int                  `json:"version"`
+	Features []*featureIndexEntry `json:"features"`
+}
+
+// featureIndexEntry is one feature's frontmatter, and the size and mtime of
+// the file it was read from. File is relative to .projects/.
+type featureIndexEntry struct {
+	File    string    `json:"file"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+
+	Project   string   `json:"project"`
+	ID        string   `json:"id"`
+	Title     string   `json:"title"`
+	Status    string   `json:"status"`
+	Priority  string   `json:"priority,omitempty"`
+	Labels    []string `json:"labels,omitempty"`
+	Assignee  string   `json:"assignee,omitempty"`
+	Estimate  string   `json:"estimate,omitempty"`
+	DependsOn []string `json:"depends_on,omitempty"`
+	Parent    string   `json:"parent,omitempty"`
+	CreatedAt string   `json:"created_at,omitempty"`
+	UpdatedAt string   `json:"updated_at,omitempty"`
+}
+
+func featureIndexPath(workspace string) string {
+	return filepath.Join(workspace, ".projects", ".index.json")
+}
+
+// loadFeatureIndex reads the index, or returns nil when there is none or it
+// cannot be used.
+func loadFeatureIndex(workspace string) *featureIndex {
+	data, err := readProjectFile(featureIndexPath(workspace))
+	if err != nil {
+		return nil
+	}
+	var idx featureIndex
+	if json.Unmarshal(data, &idx) != nil || idx.Version != featureIndexVersion {
+		return nil
+	}
+	return &idx
+}
+
+func saveFeatureIndex(workspace string, idx *featureIndex) error {
+	sort.Slice(idx.Features, func(i, j int) bool { return idx.Features[i].File < idx.Features[j].File })
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	return writeProjectFile(featureIndexPath(workspace), append(data, '\n'))
+}
+
+func newFeatureIndexEntry(f *feature, file string, info os.FileInfo) *featureIndexEntry {
+	return &featureIndexEntry{
+		File:      file,
+		Size:      info.Size(),
+		ModTime:   info.ModTime().UTC(),
+		Project:   f.Project,
+		ID:        f.ID,
+		Title:     f.Title,
+		Status:    f.Status,
+		Priority:  f.Priority,
+		Labels:    f.Labels,
+		Assignee:  f.Assignee,
+		Estimate:  f.Estimate,
+		DependsOn: f.DependsOn,
+		Parent:    f.Parent,
+		CreatedAt: f.CreatedAt,
+		UpdatedAt: f.UpdatedAt,
+	}
+}
+
+// feature returns the entry as a feature without a body. Its path is set,
+// so readFeature(f.path) loads the full record.
+func (e *featureIndexEntry) feature(workspace string) *feature {
+	return &feature{
+		ID:        e.ID,
+		Title:     e.Title,
+		Status:    e.Status,
+		Priority:  e.Priority,
+		Labels:    e.Labels,
+		Assignee:  e.Assignee,
+		Estimate:  e.Estimate,
+		DependsOn: e.DependsOn,
+		Parent:    e.Parent,
+		CreatedAt: e.CreatedAt,
+		UpdatedAt: e.UpdatedAt,
+		Project:   e.Project,
+		path:      filepath.Join(workspace, ".projects", filepath.FromSlash(e.File)),
+	}
+}
+
+// fresh reports whether the entry still describes the file at info.
+func (e *featureIndexEntry) fresh(info os.FileInfo) bool {
+	return e.Size == info.Size() && e.ModTime.Equal(info.ModTime().UTC())
+}
+
+// indexStats counts how an index refresh went.
+type indexStats struct {
+	Cached  int // entries reused without reading the file
+	Parsed  int // files read because they were new or changed
+	Removed int // entries whose file is gone
+	Failed  int // files that could not be parsed
+}
+
+// refreshFeatureIndex brings idx up to date with the feature files,
+// parsing only new or changed ones, and returns the features (without
+// bodies) in the same order as scanFeatures. idx may be nil.
+func refreshFeatureIndex(workspace string, idx *featureIndex, onError func(name string, err error)) ([]*feature, *featureIndex, indexStats) {
+	var stats indexStats
+	cached := map[string]*featureIndexEntry{}
+	if idx != nil {
+		for _, e := range idx.Features {
+			cached[e.File] = e
+		}
+	}
+
+	next := &featureIndex{Version: featureIndexVersion}
+	var features []*feature
+	for _, p := range listFeatureProjects(workspace) {
+		entries, err := os.ReadDir(featuresDir(workspace, p))
+		if err != nil {
+			continue
+		}
+		for _, de := range entries {
+			if de.IsDir() || !strings.HasSuffix(de.Name(), ".md") {
+				continue
+			}
+			file := p + "/features/" + de.Name()
+			info, err := de.Info()
+			if err != nil {
+				continue
+			}
+			e := cached[file]
+			delete(cached, file)
+			if e != nil && e.fresh(info) {
+				stats.Cached++
+			} else {
+				f, err := readFeature(filepath.Join(featuresDir(workspace, p), de.Name()))
+				if err != nil {
+					stats.Failed++
+					if onError != nil {
+						onError(p+"/"+de.Name(), err)
+					}
+					continue
+				}
+				f.Project = p
+				e = newFeatureIndexEntry(f, file, info)
+				stats.Parsed++
+			}
+			next.Features = append(next.Features, e)
+			features = append(features, e.feature(workspace))
+		}
+	}
+	stats.Removed = len(cached)
+	return features, next, stats
+}
+
+// scanFeatureSummaries is scanFeatures for callers that only need
+// frontmatter. It reads through the index when the workspace has one and
+// saves it back when anything changed; otherwise it scans every file.
+func scanFeatureSummaries(workspace string) []*feature {
+	idx := loadFeatureIndex(workspace)
+	if idx == nil || !featureKeyAvailable(workspace) {
+		return scanFeatures(workspace, "", nil)
+	}
+	features, next, stats := refreshFeatureIndex(workspace, idx, nil)
+	if stats.Parsed > 0 || stats.Removed > 0 {
+		saveFeatureIndex(workspace, next)
+	}
+	return features
+}
+
+// RunCompact handles `orchestra compact` -- rebuilds the feature index and
+// compacts the transition history.
+func RunCompact(args []string) {
+	fs := newFlagSet("compact")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	noIndex := fs.Bool("no-index", false, "Remove the feature index instead of rebuilding it")
+	parseFlags(fs, args)
+
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(absWorkspace, ".projects")); err != nil {
+		fatal("no .projects/ in %s", absWorkspace)
+	}
+	checkWorkspaceSchema(absWorkspace, true)
+	if !featureKeyAvailable(absWorkspace) {
+		fatal("the workspace is encrypted and its key is not available")
+	}
+
+	if _, err := recordTransitions(absWorkspace); err != nil {
+		printStatus(tagWarn, "could not update history: %v", err)
+	}
+
+	if *noIndex {
+		if err := os.Remove(featureIndexPath(absWorkspace)); err != nil && !os.IsNotExist(err) {
+			fatal("remove index: %v", err)
+		}
+		printStatus(tagOK, "removed .projects/.index.json")
+	} else {
+		start := time.Now()
+		_, idx, stats := refreshFeatureIndex(absWorkspace, nil, func(name string, err error) {
+			printStatus(tagWarn, "skip %s: %v", name, err)
+		})
+		if err := saveFeatureIndex(absWorkspace, idx); err != nil {
+			fatal("write index: %v", err)
+		}
+		printStatus(tagOK, ".projects/.index.json: %d feature(s) in %s", len(idx.Features), time.Since(start).Round(time.Millisecond))
+		if stats.Failed > 0 {
+			printStatus(tagWarn, "%d unreadable feature file(s) left out of the index", stats.Failed)
+		}
+	}
+
+	dropped, err := compactTransitions(absWorkspace)
+	if err != nil {
+		fatal("compact history: %v", err)
+	}
+	pruned, err := pruneHistorySnapshot(absWorkspace)
+	if err != nil {
+		fatal("compact history: %v", err)
+	}
+	printStatus(tagOK, "history: %d duplicate or unreadable transition(s) dropped, %d deleted feature(s) forgotten", dropped, pruned)
+
+	if trimmed, err := trimActivity(absWorkspace, time.Now().Add(-activityMaxAge)); err != nil {
+		printStatus(tagWarn, "could not trim activity log: %v", err)
+	} else if trimmed > 0 {
+		printStatus(tagOK, "activity: %d event(s) older than %d days dropped", trimmed, int(activityMaxAge.Hours()/24))
+	}
+
+	removed := removeStaleTempFiles(filepath.Join(absWorkspace, ".projects"), time.Now().Add(-tempFileMaxAge))
+	if removed > 0 {
+		printStatus(tagOK, "removed %d leftover temp file(s)", removed)
+	}
+}
+
+// compactTransitions rewrites the transition log in time order without
+// duplicate or unparsable lines and returns how many lines it dropped.
+// Duplicates appear when serve and a command record the same change.
+func compactTransitions(workspace string) (int, error) {
+	path := filepath.Join(historyDir(workspace), "transitions.jsonl")
+	lf, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	lines := 0
+	sc := bufio.NewScanner(lf)
+	for sc.Scan() {
+		if len(strings.TrimSpace(sc.Text())) > 0 {
+			lines++
+		}
+	}
+	lf.Close()
+	if err := sc.Err(); err != nil {
+		return 0, err
+	}
+
+	seen := map[transition]bool{}
+	var b strings.Builder
+	kept := 0
+	for _, t := range loadTransitions(workspace) {
+		t.Time = t.Time.UTC()
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+		line, _ := json.Marshal(t)
+		b.Write(line)
+		b.WriteByte('\n')
+		kept++
+	}
+	if kept == lines {
+		return 0, nil
+	}
+	return lines - kept, writeFileAtomic(path, []byte(b.String()), 0644)
+}
+
+// pruneHistorySnapshot forgets features that no longer exist, so the
+// snapshot does not grow forever. Their transitions stay in the log.
+// Archived features are kept, so restoring one records no transition.
+func pruneHistorySnapshot(workspace string) (int, error) {
+	snapPath := filepath.Join(historyDir(workspace), "snapshot.json")
+	data, err := os.ReadFile(snapPath)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	var snap historySnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return 0, fmt.Errorf("parse %s: %w", snapPath, err)
+	}
+	exists := map[string]bool{}
+	for _, f := range scanFeatureSummaries(workspace) {
+		exists[featureKey(f.Project, f.ID)] = true
+	}
+	archived, err := loadArchivedFeatures(workspace, "")
+	if err != nil {
+		return 0, err
+	}
+	for _, f := range archived {
+		exists[featureKey(f.Project, f.ID)] = true
+	}
+	pruned := 0
+	for id := range snap.Features {
+		if !exists[id] {
+			delete(snap.Features, id)
+			pruned++
+		}
+	}
+	if pruned == 0 {
+		return 0, nil
+	}
+	data, err = json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	return pruned, writeFileAtomic(snapPath, append(data, '\n'), 0644)
+}
+
+// removeStaleTempFiles deletes writeFileAtomic temp files (".name.tmp-*")
+// under dir last modified before cutoff, left by interrupted writes.
+func removeStaleTempFiles(dir string, cutoff time.Time) int {
+	removed := 0
+	filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		name := d.Name()
+		if !strings.HasPrefix(name, ".") || !strings.Contains(name, ".tmp-") {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.ModTime().Before(cutoff) {
+			if os.Remove(path) == nil {
+				removed++
+			}
+		}
+		return nil
+	})
+	return removed
+}
@@ -0,0 +1,337 @@
+package internal
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// workspaceConfigFile is the per-workspace settings file, committed with the
+// project so the whole team shares it.
+const workspaceConfigFile = ".orchestra.yaml"
+
+// workspaceConfig is the parsed .orchestra.yaml.
+type workspaceConfig struct {
+	Defaults    map[string]string          `yaml:"defaults,omitempty"` // flag name -> value; see applyFlagDefaults
+	Conventions conventionConfig           `yaml:"conventions,omitempty"`
+	Estimates   map[string]string          `yaml:"estimates,omitempty"` // t-shirt size -> duration, e.g. M: 6h
+	Templates   map[string]featureTemplate `yaml:"templates,omitempty"`
+
+	// ToolTimeouts overrides serve's --tool-timeout per tool name or
+	// path.Match pattern, e.g. "github_*": 2m. "0" means no limit.
+	ToolTimeouts map[string]string `yaml:"tool_timeouts,omitempty"`
+
+	// LogLevels is the minimum level serve records per plugin ID or
+	// path.Match pattern, e.g. "tools.*": warn; see pluginlogs.go.
+	LogLevels map[string]string `yaml:"log_levels,omitempty"`
+
+	Hooks hookSet `yaml:"hooks,omitempty"` // run once trusted; see hooks.go
+
+	// Labels and Assignees are the taxonomy features draw from; see
+	// labels.go. Empty means anything goes.
+	Labels    map[string]labelDef `yaml:"labels,omitempty"`
+	Assignees []string            `yaml:"assignees,omitempty"`
+}
+
+// conventionConfig holds text/template strings for branch names and commit
+// messages. See `orchestra convention`.
+type conventionConfig struct {
+	Branch string `yaml:"branch,omitempty"`
+	Commit string `yaml:"commit,omitempty"`
+}
+
+// loadWorkspaceConfig reads <workspace>/.orchestra.yaml. A missing file
+// yields the zero config; a malformed one is fatal so typos don't silently
+// fall back to defaults. Synced org settings fill in beneath it.
+func loadWorkspaceConfig(workspace string) *workspaceConfig {
+	cfg := &workspaceConfig{}
+	path := filepath.Join(workspace, workspaceConfigFile)
+	if data, err := os.ReadFile(path); err == nil {
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			fatal("parse %s: %v", path, err)
+		}
+	}
+	if org := loadOrgSettings(); org != nil {
+		mergeOrgSettings(cfg, org)
+	}
+	return cfg
+}
+
+// globalConfig is the parsed ~/.orchestra/config.yaml, the user's settings
+// for every workspace.
+type globalConfig struct {
+	GitHubToken string            `yaml:"github_token,omitempty"` // see githubToken
+	Defaults    map[string]string `yaml:"defaults,omitempty"`     // flag name -> value; see applyFlagDefaults
+	Mirror      mirrorConfig      `yaml:"mirror,omitempty"`       // see downloadBase and apiBase
+
+	MinisignKeys map[string]string `yaml:"minisign_keys,omitempty"` // plugin repo -> minisign public key; see verifyChecksumsSignature
+
+	Hooks hookSet `yaml:"hooks,omitempty"` // lifecycle hooks for every workspace; see hooks.go
+
+	PackIndexes []string `yaml:"pack_indexes,omitempty"` // pack index URLs or files; see packIndexSources
+
+	GitLabToken string   `yaml:"gitlab_token,omitempty"` // see gitlabToken
+	GitLabHosts []string `yaml:"gitlab_hosts,omitempty"` // self-hosted GitLab instances; see isGitLabHost
+
+	Credentials map[string]gitCredential `yaml:"credentials,omitempty"` // host -> token for private repos; see hostCredential
+
+	Proxy      proxyConfig `yaml:"proxy,omitempty"`       // see applyProxyConfig
+	AutoUpdate string      `yaml:"auto_update,omitempty"` // notify (default) or off; see CheckAndPromptUpdate
+
+	Org orgConfig `yaml:"org,omitempty"` // shared settings synced by orchestra org sync; see org.go
+}
+
+func globalConfigPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".orchestra", "config.yaml")
+}
+
+// loadGlobalConfig reads ~/.orchestra/config.yaml. Like the workspace
+// config, a missing file is the zero config and a malformed one is fatal.
+func loadGlobalConfig() *globalConfig {
+	cfg := &globalConfig{}
+	data, err := os.ReadFile(globalConfigPath())
+	if err != nil {
+		return cfg
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		fatal("parse %s: %v", globalConfigPath(), err)
+	}
+	return cfg
+}
+
+// saveGlobalConfig writes ~/.orchestra/config.yaml. It is private to the
+// user (mode 0600) since it may hold a GitHub token.
+func saveGlobalConfig(cfg *globalConfig) error {
+	if err := os.MkdirAll(filepath.Dir(globalConfigPath()), 0755); err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(globalConfigPath(), data, 0600)
+}
+
+// --- flag defaults ---
+
+// workspaceOnlyFlags may not be defaulted from org settings: they approve
+// scripts or skip safety checks, which is for whoever runs orchestra to
+// decide, and the workspace flag decides which .orchestra.yaml is read in
+// the first place.
+var workspaceOnlyFlags = map[string]bool{
+	"workspace":          true,
+	"force":              true,
+	"allow-post-install": true,
+	"no-verify":          true,
+}
+
+// workspaceDefaultFlags are the only flags .orchestra.yaml may default. A
+// committed (or cloned) file must not be able to approve prompts, accept
+// breaking changes, or move where serve listens or which file its log
+// truncates for whoever runs orchestra in the repo, so it is limited to
+// choosing the IDE, project, channel, and output, and to thresholds of
+// reports and checks.
+var workspaceDefaultFlags = map[string]bool{
+	"ide":            true,
+	"project":        true,
+	"channel":        true,
+	"level":          true,
+	"lines":          true,
+	"format":         true,
+	"porcelain":      true,
+	"csv":            true,
+	"raw":            true,
+	"label":          true,
+	"status":         true,
+	"assignee":       true,
+	"since":          true,
+	"stuck-after":    true,
+	"budget":         true,
+	"section-budget": true,
+	"tool-timeout":   true,
+	"silent-startup": true,
+}
+
+// flagEnvVar is the environment variable for a flag: ORCHESTRA_ plus the
+// name upper-cased with dashes as underscores (--certs-dir ->
+// ORCHESTRA_CERTS_DIR).
+func flagEnvVar(name string) string {
+	return "ORCHESTRA_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// eventEnvVars are set by orchestra to tell hooks, pack post-install
+// scripts, and plugins what is happening. They are not flag defaults: an
+// orchestra command a post_init hook runs must not take ORCHESTRA_PROJECT
+// for --project. ORCHESTRA_WORKSPACE is left out on purpose, so such a
+// command acts on the hook's workspace.
+var eventEnvVars = map[string]bool{
+	"ORCHESTRA_HOOK":           true,
+	"ORCHESTRA_VERSION":        true,
+	"ORCHESTRA_OS":             true,
+	"ORCHESTRA_PROJECT":        true,
+	"ORCHESTRA_REPO":           true,
+	"ORCHESTRA_PLUGIN":         true,
+	"ORCHESTRA_PLUGIN_VERSION": true,
+	"ORCHESTRA_PLUGIN_BINARY":  true,
+	"ORCHESTRA_PACK":           true,
+	"ORCHESTRA_PACK_VERSION":   true,
+	"ORCHESTRA_PACKS":          true,
+	"ORCHESTRA_LOG":            true,
+	"ORCHESTRA_SERVE_PID":      true,
+}
+
+// flagEnvValue returns the environment's default for a flag, and the
+// variable it came from.
+func flagEnvValue(name string) (value, env string) {
+	env = flagEnvVar(name)
+	if eventEnvVars[env] {
+		return "", env
+	}
+	return os.Getenv(env), env
+}
+
+// applyFlagDefaults fills flags that were not given on the command line.
+// Precedence, highest first:
+//
+//	--flag                        command line
+//	ORCHESTRA_<FLAG>              environment
+//	defaults: in .orchestra.yaml  workspace config
+//	defaults: in config.yaml      global config (~/.orchestra/config.yaml)
+//	defaults: in settings.yaml    org settings (orchestra org sync)
+//
+// The workspace flag is resolved first (from the environment or global
+// config only), since it decides which .orchestra.yaml is read. When none
+// of those sets it, it is the workspace the current directory is in (see
+// findWorkspaceRoot), except for init, which creates one.
+func applyFlagDefaults(fs *flag.FlagSet) {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+		// A shorthand like -f sets its long flag too.
+		if long, ok := strings.CutPrefix(f.Usage, "Shorthand for --"); ok {
+			set[long] = true
+		}
+	})
+
+	gcfg := loadGlobalConfig()
+	global := flagLayer{globalConfigPath(), gcfg.Defaults}
+	org := flagLayer{"org settings (" + redactURL(gcfg.Org.Source) + ")", map[string]string{}}
+	for name, v := range gcfg.Org.Settings.Defaults {
+		if !workspaceOnlyFlags[name] {
+			org.values[name] = v
+		}
+	}
+	setDefault := func(name string, layers ...flagLayer) bool {
+		if set[name] || fs.Lookup(name) == nil {
+			return false
+		}
+		value, source, ok := flagDefault(name, layers)
+		if !ok {
+			return false
+		}
+		if err := fs.Set(name, value); err != nil {
+			fmt.Fprintf(os.Stderr, "orchestra: invalid value %q for --%s from %s: %v\n", value, name, source, err)
+			os.Exit(2)
+		}
+		return true
+	}
+
+	workspace := "."
+	if f := fs.Lookup("workspace"); f != nil {
+		if !setDefault("workspace", global) && !set["workspace"] && !noWorkspaceDiscovery[fs.Name()] {
+			if root := findWorkspaceRoot("."); root != "" && !samePath(root, ".") {
+				fs.Set("workspace", root)
+				debugf("workspace: %s (found above the current directory)", root)
+			}
+		}
+		workspace = f.Value.String()
+	}
+	local := flagLayer{workspaceConfigFile, loadWorkspaceConfig(workspace).Defaults}
+	for name := range local.values {
+		if !workspaceDefaultFlags[name] {
+			debugf("ignoring defaults.%s in %s: only the global config can set it", name, workspaceConfigFile)
+			delete(local.values, name)
+		}
+	}
+
+	fs.VisitAll(func(f *flag.Flag) {
+		if f.Name != "workspace" && !strings.HasPrefix(f.Usage, "Shorthand for --") {
+			setDefault(f.Name, local, global, org)
+		}
+	})
+}
+
+// flagGiven reports whether the flag name was set on the command line, in
+// the environment, or in a config file.
+func flagGiven(fs *flag.FlagSet, name string) bool {
+	given := false
+	fs.Visit(func(f *flag.Flag) {
+		given = given || f.Name == name
+	})
+	return given
+}
+
+// flagLayer is one config file's defaults: section.
+type flagLayer struct {
+	source string
+	values map[string]string
+}
+
+// flagDefault looks name up in the environment and then in each layer,
+// returning the value and where it came from.
+func flagDefault(name string, layers []flagLayer) (value, source string, ok bool) {
+	if v, env := flagEnvValue(name); v != "" {
+		return v, "$" + env, true
+	}
+	for _, layer := range layers {
+		if v, ok := layer.values[name]; ok {
+			return v, layer.source, true
+		}
+	}
+	return "", "", false
+}
+
+// --- workspace discovery ---
+
+// noWorkspaceDiscovery lists the commands whose --workspace defaults to the
+// current directory itself rather than the workspace it is in.
+var noWorkspaceDiscovery = map[string]bool{
+	"init": true,
+}
+
+// findWorkspaceRoot returns the nearest directory at or above dir that is a
+// workspace -- one with .projects/ or .orchestra.yaml -- or "" when there is
+// none, the way git finds the repository from a subdirectory. Your home
+// directory and the filesystem root are passed over, as guardWorkspace
+// refuses them anyway and a stray ~/.projects is not a workspace.
+func findWorkspaceRoot(dir string) string {
+	abs, err := resolveWorkspace(dir)
+	if err != nil {
+		return ""
+	}
+	home, _ := os.UserHomeDir()
+	for {
+		parent := filepath.Dir(abs)
+		if parent == abs {
+			return "" // the filesystem root
+		}
+		if (home == "" || !samePath(abs, home)) && isWorkspaceRoot(abs) {
+			return abs
+		}
+		abs = parent
+	}
+}
+
+// isWorkspaceRoot reports whether dir has .projects/ or .orchestra.yaml.
+func isWorkspaceRoot(dir string) bool {
+	if info, err := os.Stat(filepath.Join(dir, ".projects")); err == nil && info.IsDir() {
+		return true
+	}
+	_, err := os.Stat(filepath.Join(dir, workspaceConfigFile))
+	return err == nil
+}
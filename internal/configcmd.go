@@ -0,0 +1,517 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// `orchestra config` reads and edits the two config files in place:
+//
+//	~/.orchestra/config.yaml   global: the user's settings, secrets included
+//	.orchestra.yaml            local: the workspace's, committed with it
+//
+// Keys are dotted paths into the files (mirror.download,
+// defaults.certs-dir). Edits go through the YAML node tree, so comments
+// and the order of untouched keys survive, and the result is parsed like
+// every command parses it before it is written.
+
+// configKey describes a settable key.
+type configKey struct {
+	path   []string // path in the file
+	global bool     // may be set in ~/.orchestra/config.yaml
+	local  bool     // may be set in .orchestra.yaml
+	list   bool     // a YAML list, given comma-separated
+	secret bool     // masked by list unless --show-secrets
+	check  func(string) error
+}
+
+// configKeys are the keys with a fixed name. The maps (defaults.<flag>,
+// credentials.<host>.token, ...) are handled by parseConfigKey.
+var configKeys = map[string]configKey{
+	"github_token":       {global: true, secret: true},
+	"gitlab_token":       {global: true, secret: true},
+	"gitlab_hosts":       {global: true, list: true},
+	"pack_indexes":       {global: true, list: true},
+	"mirror.download":    {global: true},
+	"mirror.api":         {global: true},
+	"proxy.http":         {global: true},
+	"proxy.https":        {global: true},
+	"proxy.no_proxy":     {global: true},
+	"auto_update":        {global: true, check: checkAutoUpdate},
+	"org.refresh":        {global: true, check: checkOrgRefresh},
+	"conventions.branch": {local: true},
+	"conventions.commit": {local: true},
+}
+
+// parseConfigKey resolves key to where it lives.
+func parseConfigKey(key string) (configKey, error) {
+	if k, ok := configKeys[key]; ok {
+		k.path = strings.Split(key, ".")
+		return k, nil
+	}
+	prefix, rest, _ := strings.Cut(key, ".")
+	if rest == "" {
+		return configKey{}, fmt.Errorf("unknown key %q; run 'orchestra help config' for the keys", key)
+	}
+	switch prefix {
+	case "defaults":
+		k := configKey{path: []string{prefix, rest}, global: true, local: workspaceDefaultFlags[rest]}
+		return k, nil
+	case "minisign_keys":
+		return configKey{path: []string{prefix, rest}, global: true}, nil
+	case "estimates", "tool_timeouts", "log_levels":
+		return configKey{path: []string{prefix, rest}, local: true}, nil
+	case "credentials":
+		// Hosts have dots, so the field is the last element.
+		i := strings.LastIndex(rest, ".")
+		if i < 0 || rest[i+1:] != "token" && rest[i+1:] != "username" {
+			return configKey{}, fmt.Errorf("unknown key %q; want credentials.<host>.token or credentials.<host>.username", key)
+		}
+		return configKey{path: []string{prefix, rest[:i], rest[i+1:]}, global: true, secret: rest[i+1:] == "token"}, nil
+	}
+	return configKey{}, fmt.Errorf("unknown key %q; run 'orchestra help config' for the keys", key)
+}
+
+func checkAutoUpdate(v string) error {
+	switch v {
+	case autoUpdateNotify, autoUpdateOff:
+		return nil
+	}
+	return fmt.Errorf("auto_update must be %s or %s", autoUpdateNotify, autoUpdateOff)
+}
+
+// --- files ---
+
+// configFile is one config file loaded as a YAML node tree.
+type configFile struct {
+	path  string
+	scope string // "global" or "local"
+	doc   yaml.Node
+}
+
+func loadConfigFile(path, scope string) (*configFile, error) {
+	cf := &configFile{path: path, scope: scope}
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, &cf.doc); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if cf.doc.Kind == 0 {
+		cf.doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	if cf.root().Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s: not a mapping", path)
+	}
+	return cf, nil
+}
+
+func (cf *configFile) root() *yaml.Node {
+	return cf.doc.Content[0]
+}
+
+// lookup returns the node at path, or nil.
+func (cf *configFile) lookup(path []string) *yaml.Node {
+	n := cf.root()
+	for _, name := range path {
+		n = mappingValue(n, name)
+		if n == nil {
+			return nil
+		}
+	}
+	return n
+}
+
+// set puts value at path, creating mappings on the way.
+func (cf *configFile) set(path []string, value *yaml.Node) {
+	n := cf.root()
+	for i, name := range path {
+		child := mappingValue(n, name)
+		if i == len(path)-1 {
+			if child != nil {
+				value.HeadComment, value.LineComment = child.HeadComment, child.LineComment
+				*child = *value
+			} else {
+				n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: name}, value)
+			}
+			return
+		}
+		if child == nil || child.Kind != yaml.MappingNode {
+			if child == nil {
+				child = &yaml.Node{Kind: yaml.MappingNode}
+				n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: name}, child)
+			} else {
+				*child = yaml.Node{Kind: yaml.MappingNode}
+			}
+		}
+		n = child
+	}
+}
+
+// unset removes path, and any mapping it leaves empty. It reports whether
+// path was set.
+func (cf *configFile) unset(path []string) bool {
+	return unsetNode(cf.root(), path)
+}
+
+func unsetNode(n *yaml.Node, path []string) bool {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value != path[0] {
+			continue
+		}
+		if len(path) > 1 {
+			child := n.Content[i+1]
+			if child.Kind != yaml.MappingNode || !unsetNode(child, path[1:]) {
+				return false
+			}
+			if len(child.Content) > 0 {
+				return true
+			}
+		}
+		n.Content = append(n.Content[:i], n.Content[i+2:]...)
+		return true
+	}
+	return false
+}
+
+func mappingValue(n *yaml.Node, name string) *yaml.Node {
+	if n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == name {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// save checks that the edited file still parses as its config type, then
+// writes it. The global config stays private to the user, since it may
+// hold tokens.
+func (cf *configFile) save() error {
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if len(cf.root().Content) > 0 {
+		if err := enc.Encode(&cf.doc); err != nil {
+			return err
+		}
+	}
+	enc.Close()
+
+	var err error
+	if cf.scope == "global" {
+		err = yaml.Unmarshal(b.Bytes(), &globalConfig{})
+	} else {
+		err = yaml.Unmarshal(b.Bytes(), &workspaceConfig{})
+	}
+	if err != nil {
+		return fmt.Errorf("invalid value: %w", err)
+	}
+
+	perm := os.FileMode(0644)
+	if cf.scope == "global" {
+		perm = 0600
+		if err := os.MkdirAll(filepath.Dir(cf.path), 0755); err != nil {
+			return err
+		}
+	}
+	return writeFileAtomic(cf.path, b.Bytes(), perm)
+}
+
+// configValueNode returns value as the node to store for k.
+func configValueNode(k configKey, value string) *yaml.Node {
+	if !k.list {
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+	}
+	seq := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, v := range splitCSV(value) {
+		seq.Content = append(seq.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: v})
+	}
+	return seq
+}
+
+// configNodeString renders a value for get and list: scalars as they are,
+// lists of scalars comma-separated, anything else as inline YAML.
+func configNodeString(n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		return n.Value
+	case yaml.SequenceNode:
+		var vals []string
+		for _, c := range n.Content {
+			if c.Kind != yaml.ScalarNode {
+				data, _ := yaml.Marshal(n)
+				return strings.Join(strings.Fields(string(data)), " ")
+			}
+			vals = append(vals, c.Value)
+		}
+		return strings.Join(vals, ",")
+	}
+	data, _ := yaml.Marshal(n)
+	return strings.Join(strings.Fields(string(data)), " ")
+}
+
+// flattenConfig lists the leaves of a config file as dotted keys. Lists
+// and mappings the commands do not edit by key (hooks, templates) are one
+// leaf each.
+func flattenConfig(n *yaml.Node, prefix []string, emit func(key []string, value *yaml.Node)) {
+	if n.Kind != yaml.MappingNode || len(prefix) > 0 && !isKeyedConfigMap(prefix) {
+		emit(prefix, n)
+		return
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		flattenConfig(n.Content[i+1], append(append([]string(nil), prefix...), n.Content[i].Value), emit)
+	}
+}
+
+// isKeyedConfigMap reports whether the mapping at path holds settable keys.
+func isKeyedConfigMap(path []string) bool {
+	switch path[0] {
+	case "defaults", "mirror", "proxy", "conventions", "minisign_keys", "estimates", "tool_timeouts", "log_levels":
+		return len(path) == 1
+	case "credentials":
+		return len(path) <= 2
+	}
+	return false
+}
+
+// configScopeFiles returns the files a command acts on: the global one,
+// the workspace's, or both (local first, as it takes precedence).
+func configScopeFiles(workspace string, global, local bool) []*configFile {
+	var files []*configFile
+	if !global {
+		absWorkspace, err := resolveWorkspace(workspace)
+		if err != nil {
+			fatal("resolve workspace: %v", err)
+		}
+		cf, err := loadConfigFile(filepath.Join(absWorkspace, workspaceConfigFile), "local")
+		if err != nil {
+			fatal("%v", err)
+		}
+		files = append(files, cf)
+	}
+	if !local {
+		cf, err := loadConfigFile(globalConfigPath(), "global")
+		if err != nil {
+			fatal("%v", err)
+		}
+		files = append(files, cf)
+	}
+	return files
+}
+
+// --- commands ---
+
+func runConfigList(args []string) {
+	fs := newFlagSet("config list")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	global := fs.Bool("global", false, "Only list ~/.orchestra/config.yaml")
+	local := fs.Bool("local", false, "Only list the workspace's .orchestra.yaml")
+	showSecrets := fs.Bool("show-secrets", false, "Print tokens instead of masking them")
+	porcelain := fs.Bool("porcelain", false, "Stable tab-separated output for scripts")
+	parseFlags(fs, args)
+	if *global && *local {
+		fatal("--global and --local are mutually exclusive")
+	}
+
+	type settingJSON struct {
+		Scope string `json:"scope"` // local or global
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	}
+	list := []settingJSON{}
+	for _, cf := range configScopeFiles(*workspace, *global, *local) {
+		flattenConfig(cf.root(), nil, func(key []string, value *yaml.Node) {
+			name := strings.Join(key, ".")
+			v := configNodeString(value)
+			if k, err := parseConfigKey(name); err == nil && k.secret && !*showSecrets && v != "" {
+				v = "********"
+			}
+			list = append(list, settingJSON{cf.scope, name, v})
+		})
+	}
+	if jsonOutput() {
+		printJSON(list)
+		return
+	}
+
+	// Porcelain: scope (local or global), key, value.
+	if *porcelain {
+		for _, st := range list {
+			fmt.Fprintf(os.Stdout, "%s\t%s\t%s\n", st.Scope, st.Key, st.Value)
+		}
+		return
+	}
+	tw := newTable(os.Stdout)
+	for _, st := range list {
+		fmt.Fprintf(tw, "  %s\t%s\t%s\n", st.Key, st.Value, st.Scope)
+	}
+	tw.Flush()
+}
+
+func runConfigGet(args []string) {
+	fs := newFlagSet("config get")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	global := fs.Bool("global", false, "Only read ~/.orchestra/config.yaml")
+	local := fs.Bool("local", false, "Only read the workspace's .orchestra.yaml")
+	showOrigin := fs.Bool("show-origin", false, "Print where the value comes from before it")
+	parseFlags(fs, args)
+	if fs.NArg() != 1 {
+		fatal("usage: orchestra config get <key> [--global|--local]")
+	}
+	if *global && *local {
+		fatal("--global and --local are mutually exclusive")
+	}
+	k, err := parseConfigKey(fs.Arg(0))
+	if err != nil {
+		fatal("%v", err)
+	}
+
+	show := func(origin, value string) {
+		if *showOrigin {
+			fmt.Fprintf(os.Stdout, "%s\t%s\n", origin, value)
+		} else {
+			fmt.Fprintln(os.Stdout, value)
+		}
+	}
+	// A flag default is what commands would use: the environment first.
+	if k.path[0] == "defaults" && !*global && !*local {
+		if v, env := flagEnvValue(k.path[1]); v != "" {
+			show("$"+env, v)
+			return
+		}
+	}
+	for _, cf := range configScopeFiles(*workspace, *global || !k.local, *local || !k.global) {
+		if n := cf.lookup(k.path); n != nil {
+			show(cf.path, configNodeString(n))
+			return
+		}
+	}
+	// Then what the org settings default it to.
+	if k.path[0] == "defaults" && !*local && !workspaceOnlyFlags[k.path[1]] {
+		if org := loadGlobalConfig().Org; org.Source != "" {
+			if v, ok := org.Settings.Defaults[k.path[1]]; ok {
+				show("org settings ("+redactURL(org.Source)+")", v)
+				return
+			}
+		}
+	}
+	os.Exit(1) // unset, as with git config
+}
+
+func runConfigSet(args []string) {
+	fs := newFlagSet("config set")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	local := fs.Bool("local", false, "Write the workspace's .orchestra.yaml instead of ~/.orchestra/config.yaml")
+	parseFlags(fs, args)
+	if fs.NArg() != 2 {
+		fatal("usage: orchestra config set <key> <value> [--local]")
+	}
+	key, value := fs.Arg(0), fs.Arg(1)
+	cf := configFileFor(key, *workspace, *local)
+	k, _ := parseConfigKey(key)
+	if k.check != nil {
+		if err := k.check(value); err != nil {
+			fatal("%v", err)
+		}
+	}
+	cf.set(k.path, configValueNode(k, value))
+	if err := cf.save(); err != nil {
+		fatal("%s: %v", key, err)
+	}
+	printStatus(tagOK, "%s set in %s", key, cf.path)
+}
+
+func runConfigUnset(args []string) {
+	fs := newFlagSet("config unset")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	local := fs.Bool("local", false, "Edit the workspace's .orchestra.yaml instead of ~/.orchestra/config.yaml")
+	parseFlags(fs, args)
+	if fs.NArg() != 1 {
+		fatal("usage: orchestra config unset <key> [--local]")
+	}
+	key := fs.Arg(0)
+	cf := configFileFor(key, *workspace, *local)
+	k, _ := parseConfigKey(key)
+	if !cf.unset(k.path) {
+		printStatus(tagSkip, "%s is not set in %s", key, cf.path)
+		return
+	}
+	if err := cf.save(); err != nil {
+		fatal("%s: %v", key, err)
+	}
+	printStatus(tagOK, "%s removed from %s", key, cf.path)
+}
+
+// configFileFor loads the file set and unset edit for key: the global
+// config unless --local is given or the key is only a workspace setting.
+func configFileFor(key, workspace string, local bool) *configFile {
+	k, err := parseConfigKey(key)
+	if err != nil {
+		fatal("%v", err)
+	}
+	if !k.global {
+		local = true
+	}
+	if local && !k.local {
+		if k.path[0] == "defaults" {
+			fatal("%s cannot be set in %s: a committed file must not change it for everyone who clones the workspace", key, workspaceConfigFile)
+		}
+		fatal("%s is a global setting; drop --local", key)
+	}
+	var cf *configFile
+	if local {
+		absWorkspace, err := resolveWorkspace(workspace)
+		if err != nil {
+			fatal("resolve workspace: %v", err)
+		}
+		cf, err = loadConfigFile(filepath.Join(absWorkspace, workspaceConfigFile), "local")
+	} else {
+		cf, err = loadConfigFile(globalConfigPath(), "global")
+	}
+	if err != nil {
+		fatal("%v", err)
+	}
+	return cf
+}
+
+// --- settings applied at startup ---
+
+// proxyConfig sets the HTTP proxy for orchestra and the git commands it
+// runs, for machines where it is not in the environment.
+type proxyConfig struct {
+	HTTP    string `yaml:"http,omitempty"`
+	HTTPS   string `yaml:"https,omitempty"`
+	NoProxy string `yaml:"no_proxy,omitempty"`
+}
+
+// applyProxyConfig exports proxy: from the global config as HTTP_PROXY,
+// HTTPS_PROXY, and NO_PROXY, each unless the environment already sets it
+// (in either case). Net/http and git both read them from there.
+func applyProxyConfig() {
+	data, err := os.ReadFile(globalConfigPath())
+	if err != nil {
+		return
+	}
+	// Parsed on its own and leniently: a malformed file is reported by
+	// whichever command reads the whole config, not by every command.
+	var cfg struct {
+		Proxy proxyConfig `yaml:"proxy"`
+	}
+	if yaml.Unmarshal(data, &cfg) != nil {
+		return
+	}
+	for env, v := range map[string]string{"HTTP_PROXY": cfg.Proxy.HTTP, "HTTPS_PROXY": cfg.Proxy.HTTPS, "NO_PROXY": cfg.Proxy.NoProxy} {
+		if v != "" && os.Getenv(env) == "" && os.Getenv(strings.ToLower(env)) == "" {
+			os.Setenv(env, v)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"unicode/utf8"
+)
+
+// contextItem is one file that ends up in the agent's context.
+type contextItem struct {
+	Kind   string // claude-md, agents-md, skill, agent
+	Name   string
+	Path   string // relative to the workspace
+	Pack   string // owning pack, if any
+	Tokens int
+}
+
+// estimateTokens approximates a token count at four characters per token.
+// It is deliberately rough: good enough to spot a file that is ten times
+// larger than it should be, without shipping a tokenizer.
+func estimateTokens(data []byte) int {
+	return (utf8.RuneCount(data) + 3) / 4
+}
+
+// RunContextSize handles `orchestra context-size` -- estimates how many
+// tokens CLAUDE.md, AGENTS.md, and installed skills and agents add to the
+// agent's context, and flags the ones worth trimming.
+func RunContextSize(args []string) {
+	fs := newFlagSet("context-size")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	maxFile := fs.Int("max-file", 4000, "Flag any single file above this many tokens")
+	budget := fs.Int("budget", 25000, "Flag the total when it exceeds this many tokens")
+	check := fs.Bool("check", false, "Exit with status 1 when anything is over the limits")
+	porcelain := fs.Bool("porcelain", false, "Stable tab-separated output for scripts")
+	parseFlags(fs, args)
+
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+
+	items := contextItems(absWorkspace)
+	total := 0
+	over := 0
+	for _, it := range items {
+		total += it.Tokens
+		if it.Tokens > *maxFile {
+			over++
+		}
+	}
+
+	// Porcelain: kind, name, path, pack, tokens, over limit.
+	if *porcelain {
+		for _, it := range items {
+			fmt.Fprintf(os.Stdout, "%s\t%s\t%s\t%s\t%d\t%t\n", it.Kind, it.Name, it.Path, it.Pack, it.Tokens, it.Tokens > *maxFile)
+		}
+		if *check && (over > 0 || total > *budget) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(items) == 0 {
+		fmt.Fprintf(os.Stderr, "No CLAUDE.md, AGENTS.md, skills, or agents found. Run 'orchestra init' first.\n")
+		return
+	}
+
+	tw := newTable(os.Stdout)
+	fmt.Fprintf(tw, "  KIND\tNAME\tPACK\tTOKENS\t\n")
+	for _, it := range items {
+		flag := ""
+		if it.Tokens > *maxFile {
+			flag = "over"
+		}
+		pack := it.Pack
+		if pack == "" {
+			pack = "-"
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t~%d\t%s\n", it.Kind, it.Name, pack, it.Tokens, flag)
+	}
+	tw.Flush()
+	fmt.Fprintf(os.Stdout, "\nTotal: ~%d tokens across %d files (budget %d)\n", total, len(items), *budget)
+
+	if over == 0 && total <= *budget {
+		printStatus(tagOK, "context size within limits")
+		return
+	}
+
+	fmt.Fprintln(os.Stdout, "\nSuggestions:")
+	for _, it := range items {
+		if it.Tokens > *maxFile {
+			fmt.Fprintf(os.Stdout, "  - %s (~%d tokens): %s\n", it.Path, it.Tokens, contextAdvice(it))
+		}
+	}
+	if total > *budget {
+		for _, p := range packTokens(items) {
+			fmt.Fprintf(os.Stdout, "  - pack %s adds ~%d tokens; if unused here, run 'orchestra pack remove %s'\n", p.name, p.tokens, p.name)
+		}
+	}
+	if *check {
+		os.Exit(1)
+	}
+}
+
+// contextItems lists CLAUDE.md, AGENTS.md, skills, and agents in that
+// order, attributing skills and agents to the pack that installed them.
+func contextItems(workspace string) []contextItem {
+	owner := map[string]string{} // "skill:name" or "agent:name" -> pack
+	reg := loadPackRegistry(workspace)
+	for _, name := range reg.Names() {
+		for _, s := range reg.Packs[name].Skills {
+			owner["skill:"+s] = name
+		}
+		for _, a := range reg.Packs[name].Agents {
+			owner["agent:"+a] = name
+		}
+	}
+
+	var items []contextItem
+	add := func(kind, name, rel string) {
+		data, err := os.ReadFile(filepath.Join(workspace, rel))
+		if err != nil {
+			return
+		}
+		items = append(items, contextItem{
+			Kind:   kind,
+			Name:   name,
+			Path:   filepath.ToSlash(rel),
+			Pack:   owner[kind+":"+name],
+			Tokens: estimateTokens(data),
+		})
+	}
+
+	add("claude-md", "CLAUDE.md", "CLAUDE.md")
+	add("agents-md", "AGENTS.md", "AGENTS.md")
+	claudeDir := filepath.Join(workspace, ".claude")
+	for _, name := range scanSkills(claudeDir) {
+		add("skill", name, filepath.Join(".claude", "skills", name, "SKILL.md"))
+	}
+	for _, name := range scanAgents(claudeDir) {
+		add("agent", name, filepath.Join(".claude", "agents", name+".md"))
+	}
+	return items
+}
+
+// contextAdvice suggests how to shrink an oversized file.
+func contextAdvice(it contextItem) string {
+	switch it.Kind {
+	case "claude-md":
+		return "it is loaded every session; move project detail into skills or .projects/ docs"
+	case "agents-md":
+		return "keep it to an index of agents; put instructions in the agent files"
+	case "skill":
+		return "split reference material into separate files next to SKILL.md and link them so they load on demand"
+	default:
+		return "trim the instructions or split it into narrower agents"
+	}
+}
+
+type packSize struct {
+	name   string
+	tokens int
+}
+
+// packTokens sums tokens per pack, largest first.
+func packTokens(items []contextItem) []packSize {
+	sums := map[string]int{}
+	for _, it := range items {
+		if it.Pack != "" {
+			sums[it.Pack] += it.Tokens
+		}
+	}
+	var out []packSize
+	for name, n := range sums {
+		out = append(out, packSize{name, n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].tokens != out[j].tokens {
+			return out[i].tokens > out[j].tokens
+		}
+		return out[i].name < out[j].name
+	})
+	return out
+}
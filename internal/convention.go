@@ -0,0 +1,179 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+const (
+	defaultBranchTemplate = "{{.Type}}/{{.ID}}-{{.Slug}}"
+	defaultCommitTemplate = "{{.Type}}: {{.Title}} ({{.ID}})"
+)
+
+// conventionData is the value branch and commit templates are executed with.
+type conventionData struct {
+	ID       string
+	Title    string
+	Slug     string // title reduced to [a-z0-9-]
+	Type     string // feat, fix, docs, or chore, derived from labels
+	Project  string
+	Priority string
+	Assignee string
+}
+
+// maxSlugLength keeps generated branch names readable.
+const maxSlugLength = 40
+
+func runConventionBranch(args []string) {
+	runConvention("convention branch", args, func(c conventionConfig) string { return c.Branch }, defaultBranchTemplate, true)
+}
+
+func runConventionCommit(args []string) {
+	runConvention("convention commit", args, func(c conventionConfig) string { return c.Commit }, defaultCommitTemplate, false)
+}
+
+// runConvention renders the configured (or default) template for a feature
+// and prints the single-line result to stdout, so hooks can capture it.
+func runConvention(path string, args []string, pick func(conventionConfig) string, fallback string, branch bool) {
+	fs := newFlagSet(path)
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	var worktree *bool
+	var worktreeDir *string
+	if branch {
+		worktree = fs.Bool("worktree", false, "Check the branch out in a linked git worktree, creating both as needed, and print the worktree's path")
+		worktreeDir = fs.String("worktree-dir", "", "Where --worktree puts a new worktree (default: <repo>-<branch> next to the main checkout)")
+	}
+	parseFlags(fs, args)
+
+	if fs.NArg() < 1 {
+		fatal("usage: orchestra %s <feature-id>", path)
+	}
+
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+
+	f, err := findFeature(absWorkspace, fs.Arg(0))
+	if err != nil {
+		fatal("%v", err)
+	}
+
+	text := pick(loadWorkspaceConfig(absWorkspace).Conventions)
+	if text == "" {
+		text = fallback
+	}
+	tmpl, err := template.New(path).Option("missingkey=error").Parse(text)
+	if err != nil {
+		fatal("parse %s template: %v", path, err)
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, newConventionData(f)); err != nil {
+		fatal("render %s template: %v", path, err)
+	}
+
+	out := strings.TrimSpace(b.String())
+	if branch {
+		// Git refs cannot contain whitespace.
+		out = strings.Join(strings.Fields(out), "-")
+		if *worktree || *worktreeDir != "" {
+			out = featureWorktree(absWorkspace, out, expandHome(*worktreeDir))
+		}
+	}
+	fmt.Fprintln(os.Stdout, out)
+}
+
+// featureWorktree returns the worktree of workspace's repository that has
+// branch checked out, adding one at dir ("" for <repo>-<branch> next to
+// the main checkout) when none has, and the branch when it does not exist.
+func featureWorktree(workspace, branch, dir string) string {
+	w := detectWorktree(workspace)
+	if w == nil {
+		fatal("--worktree: %s is not in a git repository", workspace)
+	}
+	for _, e := range listWorktrees(workspace) {
+		if e.Branch == branch {
+			printStatus(tagSkip, "%s is already checked out in %s", branch, e.Root)
+			return e.Root
+		}
+	}
+
+	if dir == "" {
+		mainRoot := w.mainRoot()
+		if mainRoot == "" {
+			mainRoot = w.Root
+		}
+		dir = filepath.Join(filepath.Dir(mainRoot), filepath.Base(mainRoot)+"-"+strings.ReplaceAll(branch, "/", "-"))
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		fatal("--worktree-dir: %v", err)
+	}
+	gitArgs := []string{"-C", workspace, "worktree", "add", "--quiet", dir, branch}
+	if gitOutput(workspace, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch) == "" {
+		gitArgs = []string{"-C", workspace, "worktree", "add", "--quiet", "-b", branch, dir}
+	}
+	cmd := exec.Command("git", gitArgs...)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		fatal("git worktree add: %v", err)
+	}
+	printStatus(tagOK, "worktree %s on %s", dir, branch)
+	fmt.Fprintf(os.Stderr, "  Run 'orchestra init --workspace=%s' to set it up for your IDE.\n", dir)
+	return dir
+}
+
+func newConventionData(f *feature) conventionData {
+	return conventionData{
+		ID:       f.ID,
+		Title:    f.Title,
+		Slug:     slugify(f.Title, maxSlugLength),
+		Type:     conventionType(f),
+		Project:  f.Project,
+		Priority: f.Priority,
+		Assignee: f.Assignee,
+	}
+}
+
+// conventionType maps feature labels to a conventional-commit type.
+func conventionType(f *feature) string {
+	switch {
+	case f.hasLabel("bug") || f.hasLabel("fix"):
+		return "fix"
+	case f.hasLabel("docs") || f.hasLabel("documentation"):
+		return "docs"
+	case f.hasLabel("chore"):
+		return "chore"
+	default:
+		return "feat"
+	}
+}
+
+// slugify lower-cases s and collapses runs of other characters into single
+// hyphens, trimming at a word boundary to at most max bytes.
+func slugify(s string, max int) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			hyphen = false
+		} else if !hyphen && b.Len() > 0 {
+			b.WriteByte('-')
+			hyphen = true
+		}
+	}
+	slug := strings.TrimSuffix(b.String(), "-")
+	if len(slug) > max {
+		slug = slug[:max]
+		if i := strings.LastIndex(slug, "-"); i > 0 {
+			slug = slug[:i]
+		}
+	}
+	return slug
+}
@@ -0,0 +1,116 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Debug tracing. --debug (or ORCHESTRA_DEBUG=1) logs the internal steps of
+// a command with how long each took, so a slow install or init can be
+// pinned on the clone, the download, or the doc generation:
+//
+//	debug +3.412s git clone https://github.com/orchestra-mcp/pack-go-backend.git (3.2s)
+//
+// Lines go to stderr, or with --debug-file to the end of that file.
+
+// debugStart is when the process started, for the offsets on each line.
+var debugStart = time.Now()
+
+var debugOut struct {
+	sync.Mutex
+	w      io.Writer // nil until the first line
+	header bool
+}
+
+// debugEnabled reports whether --debug or --debug-file is in effect.
+func debugEnabled() bool {
+	return globals.debug || globals.debugFile != ""
+}
+
+// debugToStderr reports whether debug lines go to stderr, where they would
+// break up a spinner's animation.
+func debugToStderr() bool {
+	return globals.debug && globals.debugFile == ""
+}
+
+// debugf logs one line.
+func debugf(format string, args ...any) {
+	if !debugEnabled() {
+		return
+	}
+	debugOut.Lock()
+	defer debugOut.Unlock()
+	if debugOut.w == nil {
+		debugOut.w = os.Stderr
+		if globals.debugFile != "" {
+			f, err := os.OpenFile(expandHome(globals.debugFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+			if err != nil {
+				printStatus(tagWarn, "--debug-file: %v; writing to stderr", err)
+			} else {
+				debugOut.w = f // left open; lines are written unbuffered
+			}
+		}
+	}
+	if !debugOut.header {
+		debugOut.header = true
+		writeDebugLine(fmt.Sprintf("orchestra %s %s/%s, %s, pid %d: %s", Version, runtime.GOOS, runtime.GOARCH, runtime.Version(), os.Getpid(), strings.Join(os.Args[1:], " ")))
+	}
+	writeDebugLine(fmt.Sprintf(format, args...))
+}
+
+// writeDebugLine writes msg stamped with the time since start. The caller
+// holds debugOut.
+func writeDebugLine(msg string) {
+	prefix := fmt.Sprintf("debug +%.3fs", time.Since(debugStart).Seconds())
+	if debugOut.w == os.Stderr {
+		prefix = colorize(ansiDim, prefix)
+	} else {
+		prefix = time.Now().Format("2006-01-02T15:04:05.000") + " " + prefix
+	}
+	fmt.Fprintf(debugOut.w, "%s %s\n", prefix, msg)
+}
+
+// debugSpan times one step; end logs it with its duration. A nil span,
+// returned when debugging is off, does nothing.
+//
+//	defer debugStep("git clone %s", url).end()
+type debugSpan struct {
+	start time.Time
+	msg   string
+}
+
+func debugStep(format string, args ...any) *debugSpan {
+	if !debugEnabled() {
+		return nil
+	}
+	return &debugSpan{start: time.Now(), msg: fmt.Sprintf(format, args...)}
+}
+
+func (s *debugSpan) end() {
+	if s != nil {
+		debugf("%s (%s)", s.msg, debugDuration(time.Since(s.start)))
+	}
+}
+
+// endf logs the step with an outcome, e.g. an HTTP status.
+func (s *debugSpan) endf(format string, args ...any) {
+	if s != nil {
+		debugf("%s: %s (%s)", s.msg, fmt.Sprintf(format, args...), debugDuration(time.Since(s.start)))
+	}
+}
+
+// debugDuration rounds d for reading: 3.2s, 412ms, 0.8ms.
+func debugDuration(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return d.Round(100 * time.Millisecond).String()
+	case d >= time.Millisecond:
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(100 * time.Microsecond).String()
+}
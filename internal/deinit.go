@@ -0,0 +1,265 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/orchestra-mcp/cli/pkg/packs"
+)
+
+// deinit backs orchestra out of a workspace: the orchestra server in IDE
+// configs, the bundled skill and agent, pack content, orchestra's own files
+// in .projects/, serve's logs, and the generated CLAUDE.md and AGENTS.md.
+// Other servers in an IDE config, and files orchestra did not write, are
+// left alone.
+// Features are the user's work, so they stay unless --purge-features.
+
+// deinitChange is a file or directory deinit removes or rewrites.
+type deinitChange struct {
+	path   string
+	what   string
+	before []byte // an IDE config's contents
+	after  []byte // what is left of it; nil removes the file
+	remove bool   // path is removed, with what is in it
+}
+
+// planDeinit returns the changes deinit makes in workspace, the packs it
+// removes, and the files it leaves because orchestra did not write them.
+// Without purgeFeatures, only orchestra's own files in .projects/ go.
+func planDeinit(workspace string, keepPacks, purgeFeatures bool) (changes []deinitChange, packNames, kept []string) {
+	seen := map[string]bool{}
+	for _, name := range allIDENames() {
+		ide := ideRegistry[name]
+		path := ide.ConfigPath(workspace)
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+		before, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		after, err := ide.Remove(workspace, before)
+		if err != nil {
+			kept = append(kept, fmt.Sprintf("%s (%v)", displayPath(workspace, path), err))
+			continue
+		}
+		if after != nil && string(after) == string(before) {
+			continue
+		}
+		changes = append(changes, deinitChange{path: path, what: "orchestra server", before: before, after: after, remove: after == nil})
+	}
+
+	if !keepPacks {
+		reg := loadPackRegistry(workspace)
+		for name := range reg.Packs {
+			packNames = append(packNames, name)
+		}
+		sort.Strings(packNames)
+	}
+
+	claudeDir := filepath.Join(workspace, ".claude")
+	for _, c := range []deinitChange{
+		{path: filepath.Join(claudeDir, "skills", "project-manager"), what: "bundled skill"},
+		{path: filepath.Join(claudeDir, "agents", "orchestra.md"), what: "bundled agent"},
+	} {
+		if _, err := os.Lstat(c.path); err == nil {
+			c.remove = true
+			changes = append(changes, c)
+		}
+	}
+
+	// The pack registry stays with the packs it lists.
+	projectsDir := filepath.Join(workspace, ".projects")
+	packsDir := filepath.Join(projectsDir, ".packs")
+	switch {
+	case purgeFeatures && keepPacks:
+		entries, _ := os.ReadDir(projectsDir)
+		for _, e := range entries {
+			if e.Name() != ".packs" {
+				changes = append(changes, deinitChange{path: filepath.Join(projectsDir, e.Name()), what: "workspace metadata", remove: true})
+			}
+		}
+	case purgeFeatures:
+		if _, err := os.Stat(projectsDir); err == nil {
+			changes = append(changes, deinitChange{path: projectsDir, what: "workspace metadata and features", remove: true})
+		}
+	default:
+		own := []string{
+			historyDir(workspace),
+			schemaPath(workspace),
+			featureIndexPath(workspace),
+			activityPath(workspace),
+			timeLogPath(workspace),
+			pluginSelectionPath(workspace),
+			progressDocPath(workspace),
+			digestPath(workspace),
+		}
+		if !keepPacks {
+			own = append([]string{packsDir}, own...)
+		}
+		for _, path := range own {
+			if _, err := os.Lstat(path); err == nil {
+				changes = append(changes, deinitChange{path: path, what: "workspace metadata", remove: true})
+			}
+		}
+	}
+
+	// What serve leaves in the workspace. deinit does not run while serve
+	// does, so none of it is in use.
+	for _, path := range []string{workspaceRunDir(workspace), defaultServeLog(workspace), pluginRestartsPath(workspace), servePIDFile(workspace)} {
+		if _, err := os.Lstat(path); err == nil {
+			changes = append(changes, deinitChange{path: path, what: "serve files", remove: true})
+		}
+	}
+
+	for _, name := range []string{"CLAUDE.md", "AGENTS.md"} {
+		path := filepath.Join(workspace, name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if !generatedDoc(name, data) {
+			kept = append(kept, name+" (not written by orchestra)")
+			continue
+		}
+		changes = append(changes, deinitChange{path: path, what: "generated doc", remove: true})
+	}
+	return changes, packNames, kept
+}
+
+func RunDeinit(args []string) {
+	fs := newFlagSet("deinit")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	keepPacks := fs.Bool("keep-packs", false, "Leave installed packs and their registry in place")
+	dryRun := fs.Bool("dry-run", false, "Print what would be removed, and remove nothing")
+	yes := fs.Bool("yes", false, "Remove without asking")
+	purgeFeatures := fs.Bool("purge-features", false, "Also delete .projects/ with its features, instead of only orchestra's own files in it")
+	force := fs.Bool("force", false, "Allow running in a home directory or filesystem root")
+	parseFlags(fs, args)
+
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	guardWorkspace("deinit", absWorkspace, *force)
+	for _, rec := range liveServeRecords() {
+		if samePath(rec.Workspace, absWorkspace) {
+			fatal("orchestra serve %d is serving %s; close the IDE session or run 'orchestra stop' first", rec.PID, absWorkspace)
+		}
+	}
+
+	changes, packNames, kept := planDeinit(absWorkspace, *keepPacks, *purgeFeatures)
+	if len(changes) == 0 && len(packNames) == 0 {
+		printStatus(tagSkip, "nothing of orchestra's to remove in %s", absWorkspace)
+		return
+	}
+
+	reg := loadPackRegistry(absWorkspace)
+	fmt.Fprintf(os.Stderr, "deinit will remove from %s:\n", absWorkspace)
+	for _, name := range packNames {
+		e := reg.Packs[name]
+		fmt.Fprintf(os.Stderr, "  pack %s (%d skill(s), %d agent(s), %d hook(s))\n", name, len(e.Skills), len(e.Agents), len(e.Hooks))
+	}
+	for _, c := range changes {
+		rel := displayPath(absWorkspace, c.path)
+		if c.remove {
+			fmt.Fprintf(os.Stderr, "  %s (%s)\n", rel, c.what)
+		} else {
+			fmt.Fprintf(os.Stderr, "  the orchestra server in %s\n", rel)
+		}
+	}
+	for _, k := range kept {
+		printStatus(tagWarn, "leaving %s", k)
+	}
+	features := countFeatureFiles(absWorkspace)
+	if features > 0 && !*purgeFeatures {
+		printStatus(tagSkip, "keeping %d feature(s) and the rest of .projects/; --purge-features deletes them", features)
+	}
+	if *dryRun {
+		for _, c := range changes {
+			if !c.remove {
+				fmt.Fprint(os.Stdout, unifiedDiff(displayPath(absWorkspace, c.path), c.before, c.after))
+			}
+		}
+		fmt.Fprintf(os.Stderr, "Dry run: nothing was removed.\n")
+		return
+	}
+
+	if !*yes {
+		if !isTerminal(os.Stdin) {
+			fatal("rerun with --dry-run to see the changes, or --yes to make them")
+		}
+		question := fmt.Sprintf("Remove orchestra from %s, keeping its %d feature(s)?", absWorkspace, features)
+		if *purgeFeatures {
+			question = fmt.Sprintf("Remove orchestra from %s and delete its %d feature(s)?", absWorkspace, features)
+		}
+		if !confirm(question) {
+			fatal("nothing was removed")
+		}
+	}
+	fmt.Fprintf(os.Stderr, "\n")
+
+	// Packs first: their removal drops hook rows from .claude/settings.json
+	// and reads the registry .projects/ holds.
+	for _, name := range packNames {
+		removal, err := packs.Remove(absWorkspace, reg.Packs[name])
+		printRemoval(removal, err)
+	}
+	for _, c := range changes {
+		rel := displayPath(absWorkspace, c.path)
+		var err error
+		if c.remove {
+			err = os.RemoveAll(c.path)
+		} else {
+			err = writeFileAtomic(c.path, c.after, 0644)
+		}
+		if err != nil {
+			printStatus(tagFail, "%s: %v", rel, err)
+			continue
+		}
+		if c.remove {
+			printStatus(tagOK, "removed %s", rel)
+		} else {
+			printStatus(tagOK, "removed the orchestra server from %s", rel)
+		}
+		// Directories orchestra created, such as .cursor/ for its config,
+		// go too once they are empty.
+		pruneEmptyDirs(absWorkspace, filepath.Dir(c.path))
+	}
+
+	fmt.Fprintf(os.Stderr, "\nOrchestra is removed from %s. Run 'orchestra init' to set it up again.\n", absWorkspace)
+}
+
+// countFeatureFiles returns how many feature files the workspace's
+// projects hold.
+func countFeatureFiles(workspace string) int {
+	n := 0
+	for _, project := range listFeatureProjects(workspace) {
+		entries, _ := os.ReadDir(featuresDir(workspace, project))
+		for _, e := range entries {
+			if !e.IsDir() && strings.HasSuffix(e.Name(), ".md") {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// pruneEmptyDirs removes dir and its parents while they are empty, up to
+// but not including workspace. Directories outside workspace are left.
+func pruneEmptyDirs(workspace, dir string) {
+	for {
+		rel, err := filepath.Rel(workspace, dir)
+		if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			return
+		}
+		if os.Remove(dir) != nil { // not empty
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
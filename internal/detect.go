@@ -16,29 +16,9 @@ type stackInfo struct {
 
 // detectStacks detects technology stacks in the given workspace.
 func detectStacks(root string) []stackInfo {
+	defer debugStep("stack detection").end()
 	var stacks []stackInfo
-
-	type check struct {
-		name  string
-		check func(string) (bool, string)
-	}
-
-	checks := []check{
-		{"go", checkAnyFile("go.mod", "go.work")},
-		{"rust", checkFile("Cargo.toml")},
-		{"react", checkPkgJSONDep("react")},
-		{"typescript", checkFile("tsconfig.json")},
-		{"python", checkAnyFile("pyproject.toml", "requirements.txt", "setup.py")},
-		{"ruby", checkFile("Gemfile")},
-		{"java", checkAnyFile("pom.xml", "build.gradle")},
-		{"kotlin", checkFile("build.gradle.kts")},
-		{"swift", checkSwiftStack},
-		{"csharp", checkCSharpStack},
-		{"php", checkFile("composer.json")},
-		{"docker", checkAnyFile("Dockerfile", "docker-compose.yml", "docker-compose.yaml")},
-	}
-
-	for _, c := range checks {
+	for _, c := range stackChecks {
 		if ok, evidence := c.check(root); ok {
 			stacks = append(stacks, stackInfo{name: c.name, evidence: evidence})
 		}
@@ -47,6 +27,35 @@ func detectStacks(root string) []stackInfo {
 	return stacks
 }
 
+// knownStacks lists the stack names detectStacks can report, the values a
+// pack's stacks are matched against.
+func knownStacks() []string {
+	names := make([]string, len(stackChecks))
+	for i, c := range stackChecks {
+		names[i] = c.name
+	}
+	return names
+}
+
+// stackChecks are detectStacks' checks, in the order stacks are reported.
+var stackChecks = []struct {
+	name  string
+	check func(string) (bool, string)
+}{
+	{"go", checkAnyFile("go.mod", "go.work")},
+	{"rust", checkFile("Cargo.toml")},
+	{"react", checkPkgJSONDep("react")},
+	{"typescript", checkFile("tsconfig.json")},
+	{"python", checkAnyFile("pyproject.toml", "requirements.txt", "setup.py")},
+	{"ruby", checkFile("Gemfile")},
+	{"java", checkAnyFile("pom.xml", "build.gradle")},
+	{"kotlin", checkFile("build.gradle.kts")},
+	{"swift", checkSwiftStack},
+	{"csharp", checkCSharpStack},
+	{"php", checkFile("composer.json")},
+	{"docker", checkAnyFile("Dockerfile", "docker-compose.yml", "docker-compose.yaml")},
+}
+
 func checkFile(name string) func(string) (bool, string) {
 	return func(root string) (bool, string) {
 		if _, err := os.Stat(filepath.Join(root, name)); err == nil {
@@ -151,7 +160,11 @@ func detectProjectName(root string) string {
 		}
 	}
 
-	// 5. Fallback to directory name.
+	// 5. Fallback to directory name -- the main checkout's, in a linked
+	// worktree, whose directory is usually named for its branch.
+	if w := detectWorktree(root); w.linked() && w.mainRoot() != "" {
+		return filepath.Base(w.mainRoot())
+	}
 	return filepath.Base(root)
 }
 
@@ -0,0 +1,275 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Digest defaults, chosen to keep DIGEST.md small enough to sit in the
+// agent's context on every session.
+const (
+	digestWindow     = 7 * 24 * time.Hour
+	digestStuckAfter = 5 * 24 * time.Hour
+	digestMaxItems   = 10
+)
+
+// activeStates are states where a feature is expected to keep moving.
+var activeStates = map[string]bool{
+	"in-progress":       true,
+	"ready-for-testing": true,
+	"in-testing":        true,
+	"ready-for-docs":    true,
+	"in-docs":           true,
+	"documented":        true,
+	"in-review":         true,
+	"needs-edits":       true,
+}
+
+func digestPath(workspace string) string {
+	return filepath.Join(workspace, ".projects", "DIGEST.md")
+}
+
+// RunDigest handles `orchestra digest` -- writes .projects/DIGEST.md with
+// recent transitions, stuck features, and upcoming work.
+func RunDigest(args []string) {
+	fs := newFlagSet("digest")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	since := fs.String("since", "7d", "How far back to list transitions (e.g. 7d, 2w)")
+	stuckAfter := fs.String("stuck-after", "5d", "Flag active features unchanged for this long")
+	toStdout := fs.Bool("stdout", false, "Print the digest instead of writing DIGEST.md")
+	parseFlags(fs, args)
+
+	now := time.Now()
+	start, err := parseSince(*since, now)
+	if err != nil {
+		fatal("--since: %v", err)
+	}
+	stuck, err := parseSince(*stuckAfter, now)
+	if err != nil {
+		fatal("--stuck-after: %v", err)
+	}
+
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	checkWorkspaceSchema(absWorkspace, !*toStdout)
+
+	if _, err := recordTransitions(absWorkspace); err != nil {
+		printStatus(tagWarn, "could not update history: %v", err)
+	}
+	digest := buildDigest(absWorkspace, now, start, now.Sub(stuck))
+
+	if *toStdout {
+		fmt.Fprint(os.Stdout, digest)
+		return
+	}
+	if workspaceEncrypted(absWorkspace) {
+		fatal("the workspace is encrypted; DIGEST.md would list features in the clear (use --stdout)")
+	}
+	if err := writeFileAtomic(digestPath(absWorkspace), []byte(digest), 0644); err != nil {
+		fatal("write DIGEST.md: %v", err)
+	}
+	printStatus(tagOK, ".projects/DIGEST.md")
+	GenerateWorkspaceDocs(absWorkspace) // link it from CLAUDE.md
+}
+
+// writeDigest regenerates DIGEST.md with the default windows when the
+// workspace already has one and is not encrypted. serve calls it as
+// features move.
+func writeDigest(workspace string) error {
+	if _, err := os.Stat(digestPath(workspace)); err != nil || workspaceEncrypted(workspace) {
+		return nil
+	}
+	now := time.Now()
+	return writeFileAtomic(digestPath(workspace), []byte(buildDigest(workspace, now, now.Add(-digestWindow), digestStuckAfter)), 0644)
+}
+
+// buildDigest renders the digest. Each list is capped at digestMaxItems so
+// the file stays compact.
+func buildDigest(workspace string, now, since time.Time, stuckAfter time.Duration) string {
+	features := scanFeatures(workspace, "", nil)
+	log := loadTransitions(workspace)
+
+	// IDs are only unique within a project, so features are looked up by
+	// featureKey.
+	byKey := map[string]*feature{}
+	for _, f := range features {
+		byKey[featureKey(f.Project, f.ID)] = f
+	}
+	// Archived features are done: they block nothing, and recent moves
+	// may still name them.
+	archived, _ := loadArchivedFeatures(workspace, "")
+	for _, f := range archived {
+		if byKey[featureKey(f.Project, f.ID)] == nil {
+			byKey[featureKey(f.Project, f.ID)] = f
+		}
+	}
+	lastMove := map[string]time.Time{}
+	for _, t := range log {
+		lastMove[featureKey(t.Project, t.Feature)] = t.Time
+	}
+
+	var b strings.Builder
+	b.WriteString("# Digest\n\n")
+	fmt.Fprintf(&b, "Generated %s by `orchestra digest`. Do not edit.\n\n", now.UTC().Format("2006-01-02 15:04 MST"))
+
+	// Counts per state, in lifecycle order.
+	counts := map[string]int{}
+	for _, f := range features {
+		counts[f.Status]++
+	}
+	var parts []string
+	for _, s := range lifecycleStates {
+		if counts[s.Name] > 0 {
+			parts = append(parts, fmt.Sprintf("%s %d", s.Name, counts[s.Name]))
+		}
+	}
+	if len(parts) == 0 {
+		b.WriteString("No features yet.\n")
+		return b.String()
+	}
+	fmt.Fprintf(&b, "**Status:** %s\n\n", strings.Join(parts, " · "))
+
+	// Recent transitions, newest first.
+	var recent []transition
+	for i := len(log) - 1; i >= 0; i-- {
+		if log[i].Time.Before(since) || log[i].From == "" {
+			continue
+		}
+		recent = append(recent, log[i])
+	}
+	fmt.Fprintf(&b, "## Recent (since %s)\n\n", since.Format(time.DateOnly))
+	if len(recent) == 0 {
+		b.WriteString("No transitions.\n")
+	}
+	for i, t := range recent {
+		if i == digestMaxItems {
+			fmt.Fprintf(&b, "- …and %d more\n", len(recent)-i)
+			break
+		}
+		fmt.Fprintf(&b, "- %s %s: %s → %s%s\n", t.Time.Local().Format("01-02"), t.Feature, t.From, t.To, digestTitle(byKey[featureKey(t.Project, t.Feature)]))
+	}
+	b.WriteString("\n")
+
+	// Stuck: active features that have not moved for stuckAfter.
+	var stuck []*feature
+	for _, f := range features {
+		if !activeStates[f.Status] {
+			continue
+		}
+		moved, ok := lastMove[featureKey(f.Project, f.ID)]
+		if !ok {
+			moved = transitionTime(f, now)
+		}
+		if now.Sub(moved) >= stuckAfter {
+			stuck = append(stuck, f)
+		}
+	}
+	sortFeatures(stuck, func(a, c *feature) bool {
+		return lastMove[featureKey(a.Project, a.ID)].Before(lastMove[featureKey(c.Project, c.ID)])
+	})
+	fmt.Fprintf(&b, "## Stuck (no change in %s)\n\n", formatDays(stuckAfter))
+	if len(stuck) == 0 {
+		b.WriteString("Nothing stuck.\n")
+	}
+	for i, f := range stuck {
+		if i == digestMaxItems {
+			fmt.Fprintf(&b, "- …and %d more\n", len(stuck)-i)
+			break
+		}
+		fmt.Fprintf(&b, "- %s [%s]%s\n", f.ID, f.Status, digestTitle(f))
+	}
+	b.WriteString("\n")
+
+	// Upcoming: todo then backlog, by priority; blocked work is marked.
+	var upcoming []*feature
+	for _, f := range features {
+		if f.Status == "todo" || f.Status == "backlog" {
+			upcoming = append(upcoming, f)
+		}
+	}
+	sortFeatures(upcoming, func(a, c *feature) bool {
+		if (a.Status == "todo") != (c.Status == "todo") {
+			return a.Status == "todo"
+		}
+		if pa, pc := priorityRank(a.Priority), priorityRank(c.Priority); pa != pc {
+			return pa < pc
+		}
+		return a.ID < c.ID
+	})
+	b.WriteString("## Up next\n\n")
+	if len(upcoming) == 0 {
+		b.WriteString("Nothing queued.\n")
+	}
+	for i, f := range upcoming {
+		if i == digestMaxItems {
+			fmt.Fprintf(&b, "- …and %d more\n", len(upcoming)-i)
+			break
+		}
+		line := fmt.Sprintf("- %s [%s", f.ID, f.Status)
+		if f.Priority != "" {
+			line += " " + f.Priority
+		}
+		line += "]" + digestTitle(f)
+		if blockers := openBlockers(f, byKey); len(blockers) > 0 {
+			line += " (blocked by " + strings.Join(blockers, ", ") + ")"
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+// digestTitle is " — title", or empty for unknown features.
+func digestTitle(f *feature) string {
+	if f == nil || f.Title == "" {
+		return ""
+	}
+	return " — " + f.Title
+}
+
+// openBlockers lists dependencies of f, which are in its project, that are
+// not done. byKey holds features by featureKey.
+func openBlockers(f *feature, byKey map[string]*feature) []string {
+	var open []string
+	for _, dep := range f.DependsOn {
+		if d, ok := byKey[featureKey(f.Project, dep)]; !ok || d.Status != "done" {
+			open = append(open, dep)
+		}
+	}
+	return open
+}
+
+// priorityRank orders priorities: P0 < P1 < ..., critical < high < medium <
+// low, and unset last.
+func priorityRank(p string) int {
+	p = strings.ToLower(strings.TrimSpace(p))
+	switch p {
+	case "critical", "urgent":
+		return 0
+	case "high":
+		return 1
+	case "medium", "normal":
+		return 2
+	case "low":
+		return 3
+	}
+	if strings.HasPrefix(p, "p") {
+		if n, err := strconv.Atoi(p[1:]); err == nil {
+			return n
+		}
+	}
+	return 100
+}
+
+// formatDays renders a duration in whole days when it is one, else hours.
+func formatDays(d time.Duration) string {
+	if d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+	return fmt.Sprintf("%.0fh", d.Hours())
+}
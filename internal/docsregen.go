@@ -0,0 +1,204 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/orchestra-mcp/cli/pkg/packs"
+)
+
+// CLAUDE.md and AGENTS.md describe the skills, agents, hooks, and packs
+// installed in the workspace. Pack commands regenerate them, but an agent
+// that edits .claude/ or installs packs through the marketplace tools in
+// the middle of a session leaves them stale. So they can be regenerated
+// three ways: `orchestra docs regen`, the regenerate_docs MCP tool that
+// serve answers itself, and serve's docs watcher, which regenerates them
+// when installed content changes.
+
+// docsWatchInterval is how often serve checks installed content.
+const docsWatchInterval = 10 * time.Second
+
+// RunDocsRegen handles `orchestra docs regen`.
+func RunDocsRegen(args []string) {
+	fs := newFlagSet("docs regen")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	force := fs.Bool("force", false, "Allow running in a home directory, filesystem root, or very large tree")
+	parseFlags(fs, args)
+
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	guardWorkspace("docs regen", absWorkspace, *force)
+	GenerateWorkspaceDocs(absWorkspace)
+}
+
+// regenerateDocsReport regenerates workspace's docs and returns what
+// happened, one line per file, and whether anything failed.
+func regenerateDocsReport(workspace string) (string, bool) {
+	var lines []string
+	failed := false
+	writeWorkspaceDocs(workspace, func(tag, format string, args ...any) {
+		if tag == tagFail {
+			failed = true
+		}
+		lines = append(lines, fmt.Sprintf("[%s] %s", tag, fmt.Sprintf(format, args...)))
+	})
+	return strings.Join(lines, "\n"), failed
+}
+
+// --- MCP tools serve answers ---
+
+// serveToolNames are the tools in toolGroups that serve answers itself
+// rather than passing them to the backend.
+func serveToolNames() []string {
+	var names []string
+	for _, g := range toolGroups {
+		if g.Plugin == "serve" {
+			for _, t := range g.Tools {
+				names = append(names, t.Name)
+			}
+		}
+	}
+	return names
+}
+
+// isServeTool reports whether serve answers tools/call for name.
+func isServeTool(name string) bool {
+	for _, n := range serveToolNames() {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// addServeTools adds serve's tools to a tools/list response. Only the last
+// page, the one without a nextCursor, gets them; other lines are returned
+// as they are.
+func addServeTools(line []byte) []byte {
+	var msg map[string]json.RawMessage
+	if json.Unmarshal(line, &msg) != nil || msg["result"] == nil {
+		return line
+	}
+	var result map[string]json.RawMessage
+	if json.Unmarshal(msg["result"], &result) != nil {
+		return line
+	}
+	if c, ok := result["nextCursor"]; ok && string(c) != "null" && string(c) != `""` {
+		return line
+	}
+	var tools []json.RawMessage
+	if raw, ok := result["tools"]; ok && json.Unmarshal(raw, &tools) != nil {
+		return line
+	}
+	for _, g := range toolGroups {
+		if g.Plugin != "serve" {
+			continue
+		}
+		for _, t := range g.Tools {
+			description := t.Summary
+			if t.Notes != "" {
+				description += ". " + t.Notes
+			}
+			tool, _ := json.Marshal(map[string]any{
+				"name":        t.Name,
+				"description": description,
+				"inputSchema": map[string]any{"type": "object", "properties": map[string]any{}},
+			})
+			tools = append(tools, tool)
+		}
+	}
+	result["tools"], _ = json.Marshal(tools)
+	msg["result"], _ = json.Marshal(result)
+	out, err := json.Marshal(msg)
+	if err != nil {
+		return line
+	}
+	return append(out, '\n')
+}
+
+// callServeTool runs one of serve's tools for workspace and returns the
+// tools/call response for request id.
+func callServeTool(id, name, workspace string, log io.Writer) []byte {
+	var text string
+	isError := false
+	switch name {
+	case "regenerate_docs":
+		text, isError = regenerateDocsReport(workspace)
+		fmt.Fprintf(log, "orchestra: regenerate_docs: %s\n", strings.ReplaceAll(text, "\n", "; "))
+	default:
+		text, isError = "unknown tool "+name, true
+	}
+	result, _ := json.Marshal(map[string]any{
+		"content": []map[string]string{{"type": "text", "text": text}},
+		"isError": isError,
+	})
+	return []byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":%s}`+"\n", id, result))
+}
+
+// --- docs watcher ---
+
+// watchDocs regenerates workspace's docs when the installed skills, agents,
+// hooks, or packs change, until stop closes. Docs orchestra did not write
+// are left alone.
+func watchDocs(workspace string, interval time.Duration, stop <-chan struct{}, log io.Writer) {
+	last := docsInputs(workspace)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			current := docsInputs(workspace)
+			if current == last {
+				continue
+			}
+			last = current
+			if !docsRegenerable(workspace) {
+				continue
+			}
+			text, _ := regenerateDocsReport(workspace)
+			fmt.Fprintf(log, "orchestra: installed content changed; regenerated docs: %s\n", strings.ReplaceAll(text, "\n", "; "))
+			last = docsInputs(workspace) // regenerating may apply overrides
+		}
+	}
+}
+
+// docsInputs fingerprints what CLAUDE.md and AGENTS.md are rendered from:
+// the installed content's names, the pack registry, and the workspace
+// config.
+func docsInputs(workspace string) string {
+	claudeDir := filepath.Join(workspace, ".claude")
+	var b strings.Builder
+	for _, names := range [][]string{scanSkills(claudeDir), scanAgents(claudeDir), scanHooks(claudeDir)} {
+		b.WriteString(strings.Join(names, ","))
+		b.WriteByte('|')
+	}
+	for _, path := range []string{packs.RegistryPath(workspace), filepath.Join(workspace, ".orchestra.yaml")} {
+		if info, err := os.Stat(path); err == nil {
+			fmt.Fprintf(&b, "%d:%d|", info.Size(), info.ModTime().UnixNano())
+		} else {
+			b.WriteString("-|")
+		}
+	}
+	return b.String()
+}
+
+// docsRegenerable reports whether the watcher may rewrite the docs: each
+// is missing or was written by orchestra.
+func docsRegenerable(workspace string) bool {
+	for _, name := range []string{"CLAUDE.md", "AGENTS.md"} {
+		data, err := os.ReadFile(filepath.Join(workspace, name))
+		if err == nil && !generatedDoc(name, data) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,589 @@
+package internal
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/orchestra-mcp/cli/pkg/packs"
+	"gopkg.in/yaml.v3"
+)
+
+// Doctor check outcomes. A check that --fix repaired reports doctorFixed.
+const (
+	doctorPass  = "PASS"
+	doctorWarn  = "WARN"
+	doctorFail  = "FAIL"
+	doctorFixed = "FIXED"
+)
+
+// doctorResult is the outcome of one check. Critical failures make doctor
+// exit non-zero; other failures only warn.
+type doctorResult struct {
+	Check    string `json:"check"`
+	Status   string `json:"status"`
+	Critical bool   `json:"critical"`
+	Detail   string `json:"detail"`
+}
+
+// doctor collects results, running a failed check's fix under --fix.
+type doctor struct {
+	workspace string
+	certsDir  string
+	fix       bool
+	results   []doctorResult
+}
+
+func (d *doctor) pass(check, format string, args ...any) {
+	d.results = append(d.results, doctorResult{Check: check, Status: doctorPass, Detail: fmt.Sprintf(format, args...)})
+}
+
+func (d *doctor) warn(check, format string, args ...any) {
+	d.results = append(d.results, doctorResult{Check: check, Status: doctorWarn, Detail: fmt.Sprintf(format, args...)})
+}
+
+// fail records a failed check. fix, when not nil, repairs it and describes
+// what it did; it runs only under --fix.
+func (d *doctor) fail(check string, critical bool, detail string, fix func() (string, error)) {
+	r := doctorResult{Check: check, Status: doctorFail, Critical: critical, Detail: detail}
+	if fix != nil && d.fix {
+		if done, err := fix(); err != nil {
+			r.Detail += fmt.Sprintf(" (fix failed: %v)", err)
+		} else {
+			r.Status, r.Detail = doctorFixed, done
+		}
+	} else if fix != nil {
+		r.Detail += " (--fix repairs this)"
+	}
+	d.results = append(d.results, r)
+}
+
+// RunDoctor handles `orchestra doctor` -- checks the installation and the
+// workspace's wiring, and repairs the simple problems with --fix.
+func RunDoctor(args []string) {
+	fs := newFlagSet("doctor")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	certsDir := fs.String("certs-dir", defaultCertsDir(), "mTLS certificates directory")
+	fix := fs.Bool("fix", false, "Repair what can be repaired safely: IDE configs, stale PID files, missing certificates")
+	porcelain := fs.Bool("porcelain", false, "Stable tab-separated output for scripts")
+	// Not parseFlags: config defaults would make a malformed config fatal
+	// before doctor could report it.
+	if err := fs.Parse(args); err != nil {
+		os.Exit(2)
+	}
+	if !flagGiven(fs, "workspace") {
+		if root := findWorkspaceRoot("."); root != "" {
+			*workspace = root
+		}
+	}
+
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	d := &doctor{workspace: absWorkspace, certsDir: expandHome(*certsDir), fix: *fix}
+
+	d.checkBinaries()
+	d.checkCerts()
+	d.checkTool("git", true, "needed to install packs and build plugins from source")
+	d.checkTool("go", false, "only needed for 'orchestra install --source'")
+	d.checkConfigs()
+	d.checkRegistries()
+	d.checkIDEConfigs()
+	d.checkWorktree()
+	d.checkPIDFiles()
+	d.checkPorts()
+
+	failed, critical := 0, 0
+	for _, r := range d.results {
+		if r.Status == doctorFail {
+			failed++
+			if r.Critical {
+				critical++
+			}
+		}
+	}
+	if jsonOutput() {
+		printJSON(struct {
+			Checks   []doctorResult `json:"checks"`
+			Failed   int            `json:"failed"`
+			Critical int            `json:"critical"`
+		}{d.results, failed, critical})
+		if critical > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+	tw := newTable(os.Stdout)
+	for _, r := range d.results {
+		// Porcelain: check, status, critical, detail.
+		if *porcelain {
+			fmt.Fprintf(os.Stdout, "%s\t%s\t%t\t%s\n", r.Check, r.Status, r.Critical, r.Detail)
+		} else {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", r.Status, r.Check, r.Detail)
+		}
+	}
+	tw.Flush()
+	if !*porcelain {
+		switch {
+		case failed == 0:
+			fmt.Fprintf(os.Stderr, "\nAll %d checks passed.\n", len(d.results))
+		case critical == 0:
+			fmt.Fprintf(os.Stderr, "\n%d of %d checks failed; none is critical.\n", failed, len(d.results))
+		default:
+			fmt.Fprintf(os.Stderr, "\n%d of %d checks failed, %d critical.\n", failed, len(d.results), critical)
+		}
+	}
+	if critical > 0 {
+		os.Exit(1)
+	}
+}
+
+// --- installation ---
+
+// checkBinaries looks for the binaries serve runs next to this one.
+func (d *doctor) checkBinaries() {
+	self, err := resolveBinaryPath()
+	if err != nil {
+		d.fail("binaries", true, err.Error(), nil)
+		return
+	}
+	dir := filepath.Dir(self)
+	var missing []string
+	for _, name := range orchestraBinaries {
+		if name == "orchestra" {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(dir, name))
+		if err != nil || info.IsDir() || (info.Mode()&0111 == 0 && !isWindowsExe(name)) {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		d.fail("binaries", true, fmt.Sprintf("%s missing or not executable in %s; run 'orchestra setup' or 'orchestra update'", strings.Join(missing, ", "), dir), nil)
+		return
+	}
+	d.pass("binaries", "%d binaries in %s", len(orchestraBinaries), dir)
+}
+
+// isWindowsExe reports whether executability comes from the name, not the
+// mode bits.
+func isWindowsExe(name string) bool {
+	return filepath.Ext(name) == ".exe"
+}
+
+// checkCerts validates the certificates directory: at least one parseable
+// certificate, none expired, and private keys readable only by the user.
+func (d *doctor) checkCerts() {
+	generate := func() (string, error) {
+		if err := generateCA(d.certsDir); err != nil {
+			return "", err
+		}
+		return "generated a local CA in " + d.certsDir, nil
+	}
+	if !certsPresent(d.certsDir) {
+		d.fail("certs", true, "no certificates in "+d.certsDir, generate)
+		return
+	}
+
+	var certs []string
+	for _, pattern := range []string{"*.crt", "*.pem"} {
+		matches, _ := filepath.Glob(filepath.Join(d.certsDir, pattern))
+		certs = append(certs, matches...)
+	}
+	now := time.Now()
+	var problems []string
+	valid := 0
+	for _, path := range certs {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", filepath.Base(path), err))
+			continue
+		}
+		block, _ := pem.Decode(data)
+		if block == nil || block.Type != "CERTIFICATE" {
+			continue // a key stored as .pem
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", filepath.Base(path), err))
+			continue
+		}
+		if now.After(cert.NotAfter) {
+			problems = append(problems, fmt.Sprintf("%s expired %s", filepath.Base(path), cert.NotAfter.Format("2006-01-02")))
+			continue
+		}
+		valid++
+	}
+	if len(problems) > 0 || valid == 0 {
+		detail := strings.Join(problems, "; ")
+		if valid == 0 && detail == "" {
+			detail = "no certificate found in " + d.certsDir
+		}
+		d.fail("certs", true, detail, nil)
+		return
+	}
+
+	keys, _ := filepath.Glob(filepath.Join(d.certsDir, "*.key"))
+	var loose []string
+	for _, path := range keys {
+		if info, err := os.Stat(path); err == nil && info.Mode().Perm()&0077 != 0 {
+			loose = append(loose, path)
+		}
+	}
+	if len(loose) > 0 {
+		d.fail("cert-keys", false, fmt.Sprintf("readable by other users: %s", strings.Join(loose, ", ")), func() (string, error) {
+			for _, path := range loose {
+				if err := os.Chmod(path, 0600); err != nil {
+					return "", err
+				}
+			}
+			return fmt.Sprintf("made %d private key(s) mode 0600", len(loose)), nil
+		})
+	}
+	d.pass("certs", "%d certificate(s) in %s", valid, d.certsDir)
+}
+
+// checkTool looks tool up on PATH.
+func (d *doctor) checkTool(tool string, critical bool, why string) {
+	path, err := exec.LookPath(tool)
+	if err != nil {
+		if critical {
+			d.fail(tool, true, "not found on PATH ("+why+")", nil)
+		} else {
+			d.warn(tool, "not found on PATH (%s)", why)
+		}
+		return
+	}
+	d.pass(tool, "%s", path)
+}
+
+// --- configuration ---
+
+// checkConfigs parses the global and workspace config files, which every
+// command reads and which are fatal when malformed.
+func (d *doctor) checkConfigs() {
+	for _, c := range []struct {
+		check, path string
+		into        any
+	}{
+		{"global-config", globalConfigPath(), &globalConfig{}},
+		{"workspace-config", filepath.Join(d.workspace, workspaceConfigFile), &workspaceConfig{}},
+	} {
+		data, err := os.ReadFile(c.path)
+		if os.IsNotExist(err) {
+			d.pass(c.check, "none (%s)", c.path)
+			continue
+		}
+		if err == nil {
+			err = yaml.Unmarshal(data, c.into)
+		}
+		if err != nil {
+			d.fail(c.check, true, fmt.Sprintf("%s: %v", c.path, err), nil)
+			continue
+		}
+		d.pass(c.check, "%s", c.path)
+	}
+}
+
+// checkRegistries parses the plugin and pack registries and checks that
+// registered plugin binaries exist.
+func (d *doctor) checkRegistries() {
+	check := func(name string, reg *PluginRegistry, err error, path string) {
+		if err != nil {
+			d.fail(name, true, err.Error(), nil)
+			return
+		}
+		var missing, modified []string
+		for _, id := range sortedPluginIDs(reg) {
+			if _, err := os.Stat(reg.Plugins[id].Binary); err != nil {
+				missing = append(missing, id)
+			} else if pluginModified(reg.Plugins[id]) != "" {
+				modified = append(modified, id)
+			}
+		}
+		var problems []string
+		if len(missing) > 0 {
+			problems = append(problems, "binary missing for "+strings.Join(missing, ", "))
+		}
+		if len(modified) > 0 {
+			problems = append(problems, "binary changed since install for "+strings.Join(modified, ", "))
+		}
+		if len(problems) > 0 {
+			d.fail(name, true, strings.Join(problems, "; ")+"; reinstall with 'orchestra update'", nil)
+			return
+		}
+		d.pass(name, "%d plugin(s) in %s", len(reg.Plugins), path)
+	}
+	reg, err := LoadRegistry()
+	check("plugin-registry", reg, err, registryPath())
+	vreg, err := loadVendorRegistry(d.workspace)
+	if err == nil && len(vreg.Plugins) == 0 {
+		d.pass("vendored-plugins", "none")
+	} else {
+		check("vendored-plugins", vreg, err, vendorRegistryPath(d.workspace))
+	}
+	if err == nil && len(vreg.Plugins) > 0 {
+		var refused []string
+		for _, p := range sortedPlugins(vreg) {
+			if problem := vendoredBinaryProblem(d.workspace, p); problem != "" {
+				refused = append(refused, p.ID+": "+problem)
+			}
+		}
+		switch {
+		case !vendoredPluginsTrusted(d.workspace):
+			d.fail("vendored-plugins-trust", false, fmt.Sprintf("%s is not trusted; serve skips its plugins until 'orchestra plugins trust'", vendorRegistryPath(d.workspace)), nil)
+		case len(refused) > 0:
+			d.fail("vendored-plugins-trust", false, "serve refuses "+strings.Join(refused, "; "), nil)
+		default:
+			d.pass("vendored-plugins-trust", "trusted")
+		}
+	}
+
+	path := packs.RegistryPath(d.workspace)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		d.pass("pack-registry", "no packs installed")
+		return
+	}
+	var preg packs.Registry
+	if err == nil {
+		err = json.Unmarshal(data, &preg)
+	}
+	if err != nil {
+		// LoadRegistry treats it as empty, so installed packs are forgotten.
+		d.fail("pack-registry", true, fmt.Sprintf("%s: %v; reinstall packs with 'orchestra pack install'", path, err), nil)
+		return
+	}
+	d.pass("pack-registry", "%d pack(s) in %s", len(preg.Packs), path)
+}
+
+func sortedPluginIDs(reg *PluginRegistry) []string {
+	ids := make([]string, 0, len(reg.Plugins))
+	for id := range reg.Plugins {
+		ids = append(ids, id)
+	}
+	sortNames(ids)
+	return ids
+}
+
+// ideCommand matches the orchestra command in TOML (codex) and YAML
+// (continue) IDE configs.
+var ideCommand = regexp.MustCompile(`(?m)^command\s*[:=]\s*"?([^"\n]+?)"?\s*$`)
+
+// ideConfigCommand returns the command an IDE config runs for orchestra,
+// and whether the config has an orchestra entry at all.
+func ideConfigCommand(name string, data []byte) (string, bool) {
+	switch name {
+	case "codex":
+		if !strings.Contains(string(data), "[mcp_servers.orchestra]") {
+			return "", false
+		}
+		m := ideCommand.FindSubmatch(data[strings.Index(string(data), "[mcp_servers.orchestra]"):])
+		if m == nil {
+			return "", true
+		}
+		return string(m[1]), true
+	case "continue":
+		m := ideCommand.FindSubmatch(data)
+		if m == nil {
+			return "", false
+		}
+		return string(m[1]), true
+	}
+
+	var config map[string]any
+	if json.Unmarshal(data, &config) != nil {
+		return "", false
+	}
+	key := "mcpServers"
+	if name == "zed" {
+		key = "context_servers"
+	}
+	servers, _ := config[key].(map[string]any)
+	entry, ok := servers["orchestra"].(map[string]any)
+	if !ok {
+		return "", false
+	}
+	switch cmd := entry["command"].(type) {
+	case string:
+		return cmd, true
+	case map[string]any: // zed
+		path, _ := cmd["path"].(string)
+		return path, true
+	}
+	return "", true
+}
+
+// checkIDEConfigs checks that every IDE config with an orchestra entry runs
+// a binary that exists. --fix regenerates broken ones for this binary.
+func (d *doctor) checkIDEConfigs() {
+	self, _ := resolveBinaryPath()
+	seen := map[string]bool{}
+	found := 0
+	for _, name := range allIDENames() {
+		ide := ideRegistry[name]
+		path := ide.ConfigPath(d.workspace)
+		if seen[path] {
+			continue // cline shares vscode's file
+		}
+		seen[path] = true
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		cmd, ok := ideConfigCommand(name, data)
+		if !ok {
+			continue
+		}
+		found++
+		check := "ide-" + name
+		if cmd == "" {
+			d.fail(check, true, path+" has an orchestra entry without a command", d.regenerateIDEConfig(ide, self))
+			continue
+		}
+		if info, err := os.Stat(cmd); err != nil || info.IsDir() {
+			d.fail(check, true, fmt.Sprintf("%s runs %s, which does not exist", path, cmd), d.regenerateIDEConfig(ide, self))
+			continue
+		}
+		d.pass(check, "%s runs %s", path, cmd)
+	}
+	if found == 0 {
+		d.warn("ide-configs", "no IDE config runs orchestra in %s; run 'orchestra init'", d.workspace)
+	}
+}
+
+// regenerateIDEConfig returns a fix that rewrites ide's config to run bin,
+// as init does. It returns nil when this binary's path is unknown.
+func (d *doctor) regenerateIDEConfig(ide *IDEConfig, bin string) func() (string, error) {
+	if bin == "" {
+		return nil
+	}
+	return func() (string, error) {
+		content, err := ide.Generate(d.workspace, bin)
+		if err != nil {
+			return "", err
+		}
+		path := ide.ConfigPath(d.workspace)
+		if err := writeFileAtomic(path, content, 0644); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("rewrote %s to run %s", path, bin), nil
+	}
+}
+
+// checkWorktree describes the linked worktree the workspace is in, and
+// warns when the workspace shares .claude/ with another worktree.
+func (d *doctor) checkWorktree() {
+	w := detectWorktree(d.workspace)
+	if w == nil {
+		return
+	}
+	warnings := worktreeWarnings(d.workspace)
+	if len(warnings) == 0 && w.linked() {
+		d.pass("worktree", "%s", w.describe())
+	}
+	for _, warning := range warnings {
+		d.warn("worktree", "%s", warning)
+	}
+}
+
+// --- processes ---
+
+// checkPIDFiles finds the workspace PID file, the serve daemon's state
+// file, and serve records left behind by processes that are gone.
+func (d *doctor) checkPIDFiles() {
+	pidFile := servePIDFile(d.workspace)
+	if data, err := os.ReadFile(pidFile); err == nil {
+		pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+		if pid <= 0 || !processAlive(pid) {
+			d.fail("pid-file", false, fmt.Sprintf("%s names pid %d, which is not running", pidFile, pid), func() (string, error) {
+				return "removed stale " + pidFile, os.Remove(pidFile)
+			})
+		} else {
+			d.pass("pid-file", "orchestrator pid %d is running", pid)
+		}
+	} else {
+		d.pass("pid-file", "no serve running in this workspace")
+	}
+	statePath := daemonStatePath(d.workspace)
+	if data, err := os.ReadFile(statePath); err == nil {
+		var st daemonState
+		json.Unmarshal(data, &st)
+		if st.PID <= 0 || !processAlive(st.PID) {
+			d.fail("serve-daemon", false, fmt.Sprintf("%s names pid %d, which is not running", statePath, st.PID), func() (string, error) {
+				return "removed stale " + statePath, os.Remove(statePath)
+			})
+		} else {
+			d.pass("serve-daemon", "daemon pid %d is running on %s", st.PID, st.Addr)
+		}
+	}
+
+	paths, _ := filepath.Glob(filepath.Join(serveRunDir(), "serve-*.json"))
+	var stale []serveRecord
+	for _, path := range paths {
+		var rec serveRecord
+		data, err := os.ReadFile(path)
+		if err != nil || json.Unmarshal(data, &rec) != nil || rec.PID == 0 {
+			continue
+		}
+		if !processAlive(rec.PID) {
+			stale = append(stale, rec)
+		}
+	}
+	if len(stale) == 0 {
+		d.pass("serve-records", "%d in %s", len(paths), serveRunDir())
+		return
+	}
+	d.fail("serve-records", false, fmt.Sprintf("%d record(s) in %s for serve processes that are gone", len(stale), serveRunDir()), func() (string, error) {
+		for _, rec := range stale {
+			reapServeRecord(rec)
+		}
+		return fmt.Sprintf("removed %d stale serve record(s)", len(stale)), nil
+	})
+}
+
+// checkPorts checks that serve can listen on localhost and that every
+// running serve session's orchestrator answers on its own address.
+func (d *doctor) checkPorts() {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		d.fail("ports", true, fmt.Sprintf("cannot listen on localhost: %v", err), nil)
+		return
+	}
+	l.Close()
+
+	var problems []string
+	owner := map[string]int{}
+	recs := liveServeRecords()
+	for _, rec := range recs {
+		if rec.Addr == "" {
+			continue
+		}
+		if other, ok := owner[rec.Addr]; ok {
+			problems = append(problems, fmt.Sprintf("serve %d and %d both use %s", other, rec.PID, rec.Addr))
+			continue
+		}
+		owner[rec.Addr] = rec.PID
+		conn, err := net.DialTimeout("tcp", rec.Addr, 2*time.Second)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("serve %d: nothing listens on %s", rec.PID, rec.Addr))
+			continue
+		}
+		conn.Close()
+	}
+	if len(problems) > 0 {
+		d.fail("ports", false, strings.Join(problems, "; ")+"; restart the IDE's MCP server", nil)
+		return
+	}
+	d.pass("ports", "localhost is available; %d serve session(s) reachable", len(recs))
+}
@@ -0,0 +1,226 @@
+package internal
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/orchestra-mcp/cli/pkg/packs"
+)
+
+// RunDrift handles `orchestra drift [pack...]` -- compares the workspace's
+// .claude/ content with what each pack installed, and optionally restores
+// the pack's files or adopts the local changes into the overlay.
+func RunDrift(args []string) {
+	fs := newFlagSet("drift")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	porcelain := fs.Bool("porcelain", false, "Stable tab-separated output for scripts")
+	refetch := fs.Bool("refetch", false, "Compare against a fresh copy of each pack at its installed version instead of the recorded hashes")
+	restore := fs.Bool("restore", false, "Put back the pack's version of modified and missing files, dropping their overlay copies")
+	adopt := fs.Bool("adopt", false, "Copy modified and added files into "+packs.OverlayDir+"/ so pack updates keep them")
+	parseFlags(fs, args)
+
+	if *restore && *adopt {
+		fatal("--restore and --adopt are mutually exclusive")
+	}
+	absWorkspace, _ := resolveWorkspace(*workspace)
+	checkWorkspaceSchema(absWorkspace, *restore || *adopt)
+
+	reg := loadPackRegistry(absWorkspace)
+	names := reg.Names()
+	if fs.NArg() > 0 {
+		names = nil
+		for _, arg := range fs.Args() {
+			name, e := reg.Lookup(arg)
+			if e == nil {
+				fatal("pack %q is not installed", arg)
+			}
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		fmt.Fprintf(os.Stderr, "No packs installed.\n")
+		return
+	}
+
+	drifted := false
+	for _, name := range names {
+		e := reg.Packs[name]
+		want := e.Files
+		var upstream map[string][]byte
+		if len(want) == 0 || *refetch || *restore {
+			sp := startSpinner(fmt.Sprintf("Fetching %s@%s", name, e.Version))
+			var err error
+			upstream, err = fetchPackContent(e)
+			sp.Stop(err)
+			if err != nil {
+				printStatus(tagFail, "%s: cannot compare without its files: %v", name, err)
+				drifted = true
+				continue
+			}
+			want = packs.ContentSums(upstream)
+		}
+		drift := packs.Drift(absWorkspace, e, want)
+
+		// Porcelain: pack, status, path; one line per file that differs.
+		if *porcelain {
+			for _, d := range drift {
+				fmt.Fprintf(os.Stdout, "%s\t%s\t%s\n", name, d.Status, d.Path)
+			}
+		} else {
+			printPackDrift(name, e.Version, drift)
+		}
+		switch {
+		case *restore:
+			drift = restoreDrift(absWorkspace, drift, upstream)
+		case *adopt:
+			drift = adoptDrift(absWorkspace, drift)
+		}
+		for _, d := range drift {
+			if d.Status != packs.DriftOverlay {
+				drifted = true
+			}
+		}
+	}
+	if drifted {
+		os.Exit(1)
+	}
+}
+
+// printPackDrift summarizes one pack's drift on stdout.
+func printPackDrift(name, version string, drift []packs.FileDrift) {
+	changed := 0
+	for _, d := range drift {
+		if d.Status != packs.DriftOverlay {
+			changed++
+		}
+	}
+	switch {
+	case len(drift) == 0:
+		fmt.Fprintf(os.Stdout, "%s %s: clean\n", name, version)
+		return
+	case changed == 0:
+		fmt.Fprintf(os.Stdout, "%s %s: clean, %d file(s) overridden locally\n", name, version, len(drift))
+	default:
+		fmt.Fprintf(os.Stdout, "%s %s: %d file(s) drifted\n", name, version, changed)
+	}
+	tw := newTable(os.Stdout)
+	for _, d := range drift {
+		status := d.Status
+		switch d.Status {
+		case packs.DriftModified, packs.DriftMissing:
+			status = colorize(ansiYellow, status)
+		case packs.DriftOverlay:
+			status = colorize(ansiDim, status)
+		}
+		fmt.Fprintf(tw, "  %s\t%s\n", status, d.Path)
+	}
+	tw.Flush()
+}
+
+// restoreDrift writes the pack's copy of each modified, missing, or
+// overlaid file and removes its overlay copy. Added files are kept, since
+// the pack has nothing to restore them to. It returns what still drifts.
+func restoreDrift(workspace string, drift []packs.FileDrift, upstream map[string][]byte) []packs.FileDrift {
+	var left []packs.FileDrift
+	for _, d := range drift {
+		data, ok := upstream[d.Path]
+		if d.Status == packs.DriftAdded || !ok {
+			printStatus(tagSkip, "kept %s (not part of the pack)", d.Path)
+			left = append(left, d)
+			continue
+		}
+		dst := filepath.Join(workspace, filepath.FromSlash(d.Path))
+		perm := os.FileMode(0644)
+		if strings.HasPrefix(d.Path, ".claude/hooks/") {
+			perm = 0755
+		}
+		err := os.MkdirAll(filepath.Dir(dst), 0755)
+		if err == nil {
+			err = writeFileAtomic(dst, data, perm)
+		}
+		if err != nil {
+			printStatus(tagFail, "restore %s: %v", d.Path, err)
+			left = append(left, d)
+			continue
+		}
+		overlay := filepath.Join(workspace, filepath.FromSlash(packs.OverlayPath(d.Path)))
+		if err := os.Remove(overlay); err == nil {
+			printStatus(tagOK, "restored %s and removed %s", d.Path, packs.OverlayPath(d.Path))
+		} else {
+			printStatus(tagOK, "restored %s", d.Path)
+		}
+	}
+	return left
+}
+
+// adoptDrift copies each modified or added file into the overlay, where
+// pack install and update reapply it. A deleted file cannot be kept that
+// way and is left drifted. It returns what still drifts.
+func adoptDrift(workspace string, drift []packs.FileDrift) []packs.FileDrift {
+	var left []packs.FileDrift
+	for _, d := range drift {
+		switch d.Status {
+		case packs.DriftOverlay:
+			left = append(left, d)
+			continue
+		case packs.DriftMissing:
+			printStatus(tagSkip, "%s was deleted; the overlay can only keep changed files. Restore it with --restore", d.Path)
+			left = append(left, d)
+			continue
+		}
+		rel := packs.OverlayPath(d.Path)
+		data, err := os.ReadFile(filepath.Join(workspace, filepath.FromSlash(d.Path)))
+		dst := filepath.Join(workspace, filepath.FromSlash(rel))
+		if err == nil {
+			err = os.MkdirAll(filepath.Dir(dst), 0755)
+		}
+		if err == nil {
+			err = writeFileAtomic(dst, data, 0644)
+		}
+		if err != nil {
+			printStatus(tagFail, "adopt %s: %v", d.Path, err)
+			left = append(left, d)
+			continue
+		}
+		printStatus(tagOK, "kept %s in %s", d.Path, rel)
+		left = append(left, packs.FileDrift{Path: d.Path, Status: packs.DriftOverlay})
+	}
+	return left
+}
+
+// fetchPackContent returns the files e's pack installs at its recorded
+// version, from the copy embedded in orchestra or a fresh clone of the tag
+// (vX.Y.Z, then X.Y.Z).
+func fetchPackContent(e *packs.Entry) (map[string][]byte, error) {
+	var files map[string][]byte
+	read := func(src fs.FS) error {
+		m, err := packs.ReadManifest(src)
+		if err != nil {
+			return err
+		}
+		if m.Version != e.Version {
+			return fmt.Errorf("found version %s, but %s is installed", m.Version, e.Version)
+		}
+		m.OS = e.OS
+		files, err = packs.ContentFiles(src, m)
+		return err
+	}
+	if e.Source == packs.SourceEmbedded {
+		src, err := embeddedPackFS(e.Repo)
+		if err != nil {
+			return nil, err
+		}
+		err = read(src)
+		return files, err
+	}
+	var err error
+	for _, ref := range []string{"v" + strings.TrimPrefix(e.Version, "v"), strings.TrimPrefix(e.Version, "v")} {
+		if _, err = withPackClone(e.Repo, ref, read); err == nil {
+			return files, nil
+		}
+	}
+	return nil, err
+}
@@ -0,0 +1,159 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// What selfUpdate does when the install directory is not writable
+// (--if-unwritable).
+const (
+	unwritableAsk     = "ask"     // prompt on a terminal, else fail
+	unwritableElevate = "elevate" // sudo (or doas, pkexec) for the file swap only
+	unwritableUser    = "user"    // install into ~/.orchestra/bin instead
+	unwritableFail    = "fail"
+)
+
+// userBinDir is where updates go when the install directory is locked.
+func userBinDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".orchestra", "bin")
+}
+
+// dirWritable reports whether files can be created in dir.
+func dirWritable(dir string) bool {
+	f, err := os.CreateTemp(dir, ".orchestra-update-test-*")
+	if err != nil {
+		return false
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return true
+}
+
+// elevator returns the first privilege escalation tool on PATH, or "".
+// sudo and doas ask for a password on the terminal; pkexec asks through
+// polkit, which also works from desktop sessions without one.
+func elevator() string {
+	for _, tool := range []string{"sudo", "doas", "pkexec"} {
+		if _, err := exec.LookPath(tool); err == nil {
+			return tool
+		}
+	}
+	return ""
+}
+
+// chooseUnwritable resolves mode for an install directory that cannot be
+// written, asking on a terminal when mode is unwritableAsk.
+func chooseUnwritable(mode, installDir string) (string, error) {
+	hint := fmt.Errorf("cannot write to %s; rerun with --if-unwritable=elevate to use sudo for the file swap, or --if-unwritable=user to install into %s", installDir, userBinDir())
+	switch mode {
+	case unwritableElevate:
+		if elevator() == "" {
+			return "", fmt.Errorf("cannot write to %s and no sudo, doas, or pkexec found; try --if-unwritable=user", installDir)
+		}
+		return mode, nil
+	case unwritableUser:
+		return mode, nil
+	case unwritableFail:
+		return "", hint
+	case unwritableAsk, "":
+	default:
+		return "", fmt.Errorf("unknown --if-unwritable %q (use ask, elevate, user, or fail)", mode)
+	}
+	if !isTerminal(os.Stdin) {
+		return "", hint
+	}
+
+	fmt.Fprintf(os.Stderr, "\n  Cannot write to %s.\n", installDir)
+	choices := []string{}
+	if tool := elevator(); tool != "" {
+		choices = append(choices, unwritableElevate)
+		fmt.Fprintf(os.Stderr, "    %d) replace the files with %s (only the copy runs elevated)\n", len(choices), tool)
+	}
+	choices = append(choices, unwritableUser)
+	fmt.Fprintf(os.Stderr, "    %d) install into %s and update PATH\n", len(choices), userBinDir())
+	fmt.Fprintf(os.Stderr, "    %d) cancel\n", len(choices)+1)
+	answer := ask(fmt.Sprintf("Choose 1-%d:", len(choices)+1), fmt.Sprint(len(choices)+1))
+	for i, c := range choices {
+		if answer == fmt.Sprint(i+1) {
+			return c, nil
+		}
+	}
+	return "", fmt.Errorf("update cancelled")
+}
+
+// elevatedInstall copies each staged src to its dest with one elevated
+// shell, so the password is asked once. Each file is written next to its
+// destination and renamed over it, so a running binary is never seen half
+// written.
+func elevatedInstall(pairs [][2]string) error {
+	tool := elevator()
+	if tool == "" {
+		return fmt.Errorf("no sudo, doas, or pkexec found")
+	}
+	script := `set -e
+while [ $# -gt 0 ]; do
+  install -m 0755 "$1" "$2.orchestra-new"
+  mv -f "$2.orchestra-new" "$2"
+  shift 2
+done`
+	args := []string{"sh", "-c", script, "sh"}
+	for _, p := range pairs {
+		args = append(args, p[0], p[1])
+	}
+	fmt.Fprintf(os.Stderr, "  Running %s to replace %d file(s)...\n", tool, len(pairs))
+	cmd := exec.Command(tool, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w", tool, err)
+	}
+	for _, p := range pairs {
+		printStatus(tagOK, "%s", filepath.Base(p[1]))
+	}
+	return nil
+}
+
+// printPathGuidance tells the user how to put dir ahead of oldDir on PATH
+// when the shell would still find the old orchestra first.
+func printPathGuidance(dir, oldDir string) {
+	entries := filepath.SplitList(os.Getenv("PATH"))
+	pos := func(d string) int {
+		for i, e := range entries {
+			if samePath(e, d) {
+				return i
+			}
+		}
+		return -1
+	}
+	newPos, oldPos := pos(dir), pos(oldDir)
+	if newPos >= 0 && (oldPos < 0 || newPos < oldPos) {
+		return
+	}
+
+	printStatus(tagWarn, "%s is not ahead of %s on PATH, so the old orchestra still runs", dir, oldDir)
+	shell := filepath.Base(os.Getenv("SHELL"))
+	display := dir
+	if home, _ := os.UserHomeDir(); home != "" && strings.HasPrefix(dir, home) {
+		display = "$HOME" + strings.TrimPrefix(dir, home)
+	}
+	switch shell {
+	case "fish":
+		fmt.Fprintf(os.Stderr, "\n  Run once:\n    fish_add_path %s\n", display)
+	default:
+		rc := "~/.profile"
+		switch shell {
+		case "zsh":
+			rc = "~/.zshrc"
+		case "bash":
+			rc = "~/.bashrc"
+		}
+		fmt.Fprintf(os.Stderr, "\n  Add this line to %s, then open a new shell:\n    export PATH=\"%s:$PATH\"\n", rc, display)
+	}
+	fmt.Fprintf(os.Stderr, "  The old binaries in %s can then be removed.\n", oldDir)
+}
@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+
+	"github.com/orchestra-mcp/cli/pkg/packs"
+)
+
+// embeddedPacks holds packs shipped inside the binary so `orchestra init
+// --with-packs` works offline. Each directory mirrors its upstream repo.
+//
+//go:embed all:embedded
+var embeddedPacks embed.FS
+
+// embeddedPackDirs maps upstream repos to their directory in embeddedPacks.
+var embeddedPackDirs = map[string]string{
+	"github.com/orchestra-mcp/pack-essentials": "embedded/pack-essentials",
+}
+
+// hasEmbeddedPack reports whether repo has a copy embedded in the binary.
+func hasEmbeddedPack(repo string) bool {
+	_, ok := embeddedPackDirs[repo]
+	return ok
+}
+
+// installEmbeddedPack installs the embedded copy of repo into workspace.
+func installEmbeddedPack(workspace, repo string, opts packInstallOptions) (*packs.Manifest, error) {
+	sub, err := embeddedPackFS(repo)
+	if err != nil {
+		return nil, err
+	}
+	return installPackFromFS(workspace, sub, opts)
+}
+
+// embeddedPackFS returns the embedded copy of repo, rooted at its pack.json.
+func embeddedPackFS(repo string) (fs.FS, error) {
+	dir, ok := embeddedPackDirs[repo]
+	if !ok {
+		return nil, fmt.Errorf("no embedded copy of %s", repo)
+	}
+	return fs.Sub(embeddedPacks, dir)
+}
+
+// installDefaultPacks installs the embedded default packs into workspace and
+// records them in the pack registry with their upstream repo, so a later
+// `orchestra pack update` fetches from upstream transparently.
+func installDefaultPacks(workspace string) {
+	reg := loadPackRegistry(workspace)
+	for _, repo := range []string{"github.com/orchestra-mcp/pack-essentials"} {
+		if name, existing := reg.FindByRepo(repo); existing != nil && existing.Source != packs.SourceEmbedded {
+			// Never downgrade an upstream install to the embedded copy.
+			printStatus(tagSkip, "%s already installed from upstream (%s)", name, existing.Version)
+			continue
+		}
+		if err := checkAllowedSource(repo); err != nil {
+			printStatus(tagSkip, "%v", err)
+			continue
+		}
+		manifest, err := installEmbeddedPack(workspace, repo, packInstallOptions{Embedded: true})
+		if err != nil {
+			printStatus(tagFail, "%s: %v", repo, err)
+			continue
+		}
+		entry := packs.NewEntry(manifest, repo)
+		entry.Source = packs.SourceEmbedded
+		reg.Packs[manifest.Name] = entry
+		printStatus(tagOK, "%s@%s (embedded)", manifest.Name, manifest.Version)
+	}
+	savePackRegistry(workspace, reg)
+}
@@ -0,0 +1,615 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Encryption at rest for the feature store. An encrypted workspace has
+// .projects/.encryption.json naming its key; every feature file is
+// AES-256-GCM ciphertext behind a one-line header:
+//
+//	orchestra-encrypted v1 <key-id>
+//	<base64 of nonce || ciphertext>
+//
+// The 32-byte key never enters the workspace in the clear. It comes from
+// ORCHESTRA_WORKSPACE_KEY (base64), from the OS keychain or
+// ~/.orchestra/keys/workspaces/<key-id>.key, or from
+// .projects/.encryption.key.age, decrypted with the user's age identity.
+// serve hands it to the storage plugins as ORCHESTRA_WORKSPACE_KEY, and
+// only encrypts for storage plugins that declare capabilityEncryption.
+
+const (
+	encryptionHeader   = "orchestra-encrypted v1 "
+	workspaceKeyEnv    = "ORCHESTRA_WORKSPACE_KEY"
+	ageIdentityEnv     = "ORCHESTRA_AGE_IDENTITY"
+	keychainService    = "orchestra-workspace"
+	workspaceKeySize   = 32
+	encryptionCipher   = "aes-256-gcm"
+	keyStoreKeychain   = "keychain"
+	keyStoreAge        = "age"
+	keyStoreFile       = "file"
+	encryptionKeyAgeAt = ".encryption.key.age" // under .projects/
+)
+
+// encryptionConfig is .projects/.encryption.json.
+type encryptionConfig struct {
+	Cipher        string   `json:"cipher"`
+	KeyID         string   `json:"key_id"`
+	KeyStore      string   `json:"key_store"` // keychain, file, or age
+	AgeRecipients []string `json:"age_recipients,omitempty"`
+}
+
+func encryptionConfigPath(workspace string) string {
+	return filepath.Join(workspace, ".projects", ".encryption.json")
+}
+
+// loadEncryptionConfig returns the workspace's encryption config, or nil
+// when the workspace is not encrypted.
+func loadEncryptionConfig(workspace string) *encryptionConfig {
+	data, err := os.ReadFile(encryptionConfigPath(workspace))
+	if err != nil {
+		return nil
+	}
+	var c encryptionConfig
+	if json.Unmarshal(data, &c) != nil || c.KeyID == "" {
+		return nil
+	}
+	return &c
+}
+
+func saveEncryptionConfig(workspace string, c *encryptionConfig) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(encryptionConfigPath(workspace), append(data, '\n'), 0644)
+}
+
+// workspaceEncrypted reports whether workspace's feature store is encrypted.
+func workspaceEncrypted(workspace string) bool {
+	return loadEncryptionConfig(workspace) != nil
+}
+
+// keyID is the short fingerprint a key is stored and looked up under.
+func keyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// --- keys ---
+
+var (
+	workspaceKeysMu sync.Mutex
+	workspaceKeys   = map[string][]byte{} // by key ID
+)
+
+// workspaceKey returns the key for c, trying ORCHESTRA_WORKSPACE_KEY, then
+// where c.KeyStore says it is kept.
+func workspaceKey(workspace string, c *encryptionConfig) ([]byte, error) {
+	workspaceKeysMu.Lock()
+	defer workspaceKeysMu.Unlock()
+	if key, ok := workspaceKeys[c.KeyID]; ok {
+		return key, nil
+	}
+
+	var key []byte
+	var err error
+	if env := os.Getenv(workspaceKeyEnv); env != "" {
+		key, err = base64.StdEncoding.DecodeString(strings.TrimSpace(env))
+		if err != nil {
+			return nil, fmt.Errorf("$%s: %w", workspaceKeyEnv, err)
+		}
+	} else {
+		switch c.KeyStore {
+		case keyStoreAge:
+			key, err = ageDecryptKey(workspace)
+		default:
+			key, err = loadStoredKey(c.KeyID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("no key for encrypted workspace (key %s): %w; set $%s or run 'orchestra encrypt-workspace --import-key'", c.KeyID, err, workspaceKeyEnv)
+		}
+	}
+	if keyID(key) != c.KeyID {
+		return nil, fmt.Errorf("key %s does not match the workspace's key %s", keyID(key), c.KeyID)
+	}
+	workspaceKeys[c.KeyID] = key
+	return key, nil
+}
+
+// keyFilePath is the fallback store on systems without a keychain tool.
+func keyFilePath(id string) string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".orchestra", "keys", "workspaces", id+".key")
+}
+
+// keychainAvailable reports whether the OS keychain can be used: macOS
+// `security` or libsecret's `secret-tool`.
+func keychainAvailable() bool {
+	switch runtime.GOOS {
+	case "darwin":
+		_, err := exec.LookPath("security")
+		return err == nil
+	case "linux":
+		_, err := exec.LookPath("secret-tool")
+		return err == nil
+	}
+	return false
+}
+
+// storeKey keeps key in the OS keychain, or in a 0600 file under
+// ~/.orchestra/keys/workspaces/ when there is none, and returns which.
+func storeKey(key []byte) (string, error) {
+	id := keyID(key)
+	secret := base64.StdEncoding.EncodeToString(key)
+	if keychainAvailable() {
+		var cmd *exec.Cmd
+		if runtime.GOOS == "darwin" {
+			cmd = exec.Command("security", "add-generic-password", "-U", "-s", keychainService, "-a", id, "-w", secret)
+		} else {
+			cmd = exec.Command("secret-tool", "store", "--label=orchestra workspace key "+id, "service", keychainService, "key", id)
+			cmd.Stdin = strings.NewReader(secret)
+		}
+		if out, err := cmd.CombinedOutput(); err == nil {
+			return keyStoreKeychain, nil
+		} else if msg := lastLine(string(out)); msg != "" {
+			printStatus(tagWarn, "keychain: %s; keeping the key in a file instead", msg)
+		}
+	}
+	path := keyFilePath(id)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", err
+	}
+	if err := writeFileAtomic(path, []byte(secret+"\n"), 0600); err != nil {
+		return "", err
+	}
+	return keyStoreFile, nil
+}
+
+// loadStoredKey reads key id from the keychain or the key file.
+func loadStoredKey(id string) ([]byte, error) {
+	if data, err := os.ReadFile(keyFilePath(id)); err == nil {
+		return base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	}
+	if !keychainAvailable() {
+		return nil, fmt.Errorf("not in %s", keyFilePath(id))
+	}
+	var cmd *exec.Cmd
+	if runtime.GOOS == "darwin" {
+		cmd = exec.Command("security", "find-generic-password", "-s", keychainService, "-a", id, "-w")
+	} else {
+		cmd = exec.Command("secret-tool", "lookup", "service", keychainService, "key", id)
+	}
+	out, err := cmd.Output()
+	if err != nil || len(bytes.TrimSpace(out)) == 0 {
+		return nil, fmt.Errorf("not in the keychain or %s", keyFilePath(id))
+	}
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(string(out)))
+}
+
+// ageIdentity is the age identity file used to unwrap .encryption.key.age.
+func ageIdentity() string {
+	if p := os.Getenv(ageIdentityEnv); p != "" {
+		return p
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "age", "keys.txt")
+}
+
+// ageEncryptKey wraps key for recipients with the age CLI into
+// .projects/.encryption.key.age, which is committed with the workspace.
+func ageEncryptKey(workspace string, key []byte, recipients []string) error {
+	if _, err := exec.LookPath("age"); err != nil {
+		return fmt.Errorf("--age-recipients needs the age CLI (https://age-encryption.org)")
+	}
+	args := []string{"--encrypt", "--armor"}
+	for _, r := range recipients {
+		args = append(args, "--recipient", r)
+	}
+	cmd := exec.Command("age", args...)
+	cmd.Stdin = bytes.NewReader(key)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("age: %s", orDash(lastLine(stderr.String())))
+	}
+	return writeFileAtomic(filepath.Join(workspace, ".projects", encryptionKeyAgeAt), out, 0644)
+}
+
+// ageDecryptKey unwraps .projects/.encryption.key.age with ageIdentity.
+func ageDecryptKey(workspace string) ([]byte, error) {
+	if _, err := exec.LookPath("age"); err != nil {
+		return nil, fmt.Errorf("the key is age-encrypted and the age CLI is not installed")
+	}
+	cmd := exec.Command("age", "--decrypt", "--identity", ageIdentity(), filepath.Join(workspace, ".projects", encryptionKeyAgeAt))
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("age with identity %s: %s", ageIdentity(), orDash(lastLine(stderr.String())))
+	}
+	return out, nil
+}
+
+// --- file format ---
+
+// isEncrypted reports whether data is an encrypted feature file.
+func isEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(encryptionHeader))
+}
+
+func sealFile(key, plain []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	header := encryptionHeader + keyID(key)
+	sealed := gcm.Seal(nonce, nonce, plain, []byte(header))
+	return []byte(header + "\n" + base64.StdEncoding.EncodeToString(sealed) + "\n"), nil
+}
+
+func openFile(key, data []byte) ([]byte, error) {
+	header, body, _ := bytes.Cut(data, []byte("\n"))
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(body)))
+	if err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("truncated ciphertext")
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], header)
+	if err != nil {
+		return nil, errors.New("decryption failed: wrong key or tampered file")
+	}
+	return plain, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// projectsWorkspace returns the workspace a file under .projects/ belongs
+// to, or "".
+func projectsWorkspace(path string) string {
+	for dir := filepath.Dir(path); dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if filepath.Base(dir) == ".projects" {
+			return filepath.Dir(dir)
+		}
+	}
+	return ""
+}
+
+// readProjectFile reads a feature store file, decrypting it when needed.
+func readProjectFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil || !isEncrypted(data) {
+		return data, err
+	}
+	workspace := projectsWorkspace(path)
+	c := loadEncryptionConfig(workspace)
+	if c == nil {
+		return nil, fmt.Errorf("encrypted, but %s is missing", encryptionConfigPath(workspace))
+	}
+	key, err := workspaceKey(workspace, c)
+	if err != nil {
+		return nil, err
+	}
+	return openFile(key, data)
+}
+
+// writeProjectFile writes a feature store file, encrypting it when the
+// workspace is encrypted.
+func writeProjectFile(path string, data []byte) error {
+	workspace := projectsWorkspace(path)
+	if c := loadEncryptionConfig(workspace); c != nil {
+		key, err := workspaceKey(workspace, c)
+		if err != nil {
+			return err
+		}
+		if data, err = sealFile(key, data); err != nil {
+			return err
+		}
+	}
+	return writeFileAtomic(path, data, 0644)
+}
+
+var missingKeyWarned sync.Map // workspace -> true
+
+// featureKeyAvailable reports whether workspace's feature files can be
+// read: it is not encrypted, or its key is at hand. The first miss in a
+// process is reported, so commands do not silently show an empty store.
+func featureKeyAvailable(workspace string) bool {
+	c := loadEncryptionConfig(workspace)
+	if c == nil {
+		return true
+	}
+	if _, err := workspaceKey(workspace, c); err != nil {
+		if _, warned := missingKeyWarned.LoadOrStore(workspace, true); !warned {
+			printStatus(tagWarn, "%v", err)
+		}
+		return false
+	}
+	return true
+}
+
+// capabilityEncryption is the manifest capability of a storage plugin that
+// reads and writes encrypted feature files (see PLUGIN_DEVELOPMENT.md).
+// Without it, a storage plugin would write features in the clear or fail
+// on the ciphertext.
+const capabilityEncryption = "encrypted-workspace"
+
+// storageEncryptionProblem returns why plugins cannot serve an encrypted
+// workspace, or "": one of the storage plugins among them does not
+// declare capabilityEncryption in its manifest.
+func storageEncryptionProblem(plugins []pluginConfig) string {
+	for _, p := range plugins {
+		if len(p.ProvidesStorage) == 0 {
+			continue
+		}
+		manifest, err := queryManifest(p.Binary)
+		if err != nil {
+			return fmt.Sprintf("storage plugin %s: %v", p.ID, err)
+		}
+		if !slices.Contains(manifest.Capabilities, capabilityEncryption) {
+			return fmt.Sprintf("storage plugin %s does not support encrypted workspaces (no %q capability in its manifest)", p.ID, capabilityEncryption)
+		}
+	}
+	return ""
+}
+
+// withWorkspaceKey returns plugins with the key of an encrypted workspace
+// given to its storage plugins in ORCHESTRA_WORKSPACE_KEY, and to no other
+// plugin. It fails when the key is missing or a storage plugin does not
+// support encryption. plugins itself, which the warm-start cache saves, is
+// left without the key.
+func withWorkspaceKey(workspace string, plugins []pluginConfig) ([]pluginConfig, error) {
+	c := loadEncryptionConfig(workspace)
+	if c == nil {
+		return plugins, nil
+	}
+	key, err := workspaceKey(workspace, c)
+	if err != nil {
+		return nil, err
+	}
+	if problem := storageEncryptionProblem(plugins); problem != "" {
+		return nil, fmt.Errorf("%s is encrypted, but %s", workspace, problem)
+	}
+	out := make([]pluginConfig, len(plugins))
+	for i, p := range plugins {
+		if len(p.ProvidesStorage) > 0 {
+			p.Env = append(slices.Clip(p.Env), workspaceKeyEnv+"="+base64.StdEncoding.EncodeToString(key))
+		}
+		out[i] = p
+	}
+	return out, nil
+}
+
+// --- encrypt-workspace ---
+
+// RunEncryptWorkspace handles `orchestra encrypt-workspace` -- encrypts
+// the feature store of an existing workspace, decrypts it again, or
+// exports and imports the key for teammates.
+func RunEncryptWorkspace(args []string) {
+	fs := newFlagSet("encrypt-workspace")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	ageRecipients := fs.String("age-recipients", "", "Comma-separated age recipients to wrap the key for in .projects/"+encryptionKeyAgeAt+", instead of the keychain")
+	decrypt := fs.Bool("decrypt", false, "Decrypt the feature store and turn encryption off")
+	exportKey := fs.Bool("export-key", false, "Print the workspace key (base64) for a teammate or CI secret")
+	importKey := fs.Bool("import-key", false, "Read a workspace key (base64) from stdin and keep it in the keychain")
+	parseFlags(fs, args)
+
+	absWorkspace, _ := resolveWorkspace(*workspace)
+	c := loadEncryptionConfig(absWorkspace)
+
+	switch {
+	case *exportKey:
+		if c == nil {
+			fatal("%s is not encrypted", absWorkspace)
+		}
+		key, err := workspaceKey(absWorkspace, c)
+		if err != nil {
+			fatal("%v", err)
+		}
+		fmt.Fprintln(os.Stdout, base64.StdEncoding.EncodeToString(key))
+		return
+	case *importKey:
+		if c == nil {
+			fatal("%s is not encrypted", absWorkspace)
+		}
+		data, err := io.ReadAll(io.LimitReader(os.Stdin, 1024))
+		if err != nil {
+			fatal("read key: %v", err)
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || keyID(key) != c.KeyID {
+			fatal("that is not the key for %s (key %s)", absWorkspace, c.KeyID)
+		}
+		store, err := storeKey(key)
+		if err != nil {
+			fatal("store key: %v", err)
+		}
+		printStatus(tagOK, "key %s kept in the %s", c.KeyID, keyStoreLabel(store))
+		return
+	case *decrypt:
+		if c == nil {
+			fatal("%s is not encrypted", absWorkspace)
+		}
+		checkWorkspaceSchema(absWorkspace, true)
+		if _, err := workspaceKey(absWorkspace, c); err != nil {
+			fatal("%v", err)
+		}
+		decryptFile := func(path string) error {
+			data, err := readProjectFile(path)
+			if err != nil {
+				return err
+			}
+			return writeFileAtomic(path, data, 0644)
+		}
+		n := rewriteFeatureFiles(absWorkspace, decryptFile)
+		if err := decryptFile(featureIndexPath(absWorkspace)); err != nil && !os.IsNotExist(err) {
+			printStatus(tagWarn, "feature index: %v", err)
+		}
+		for _, path := range featureArchivePaths(absWorkspace, "") {
+			if err := decryptFile(path); err != nil {
+				printStatus(tagWarn, "%s: %v", filepath.Base(path), err)
+			}
+		}
+		os.Remove(encryptionConfigPath(absWorkspace))
+		os.Remove(filepath.Join(absWorkspace, ".projects", encryptionKeyAgeAt))
+		printStatus(tagOK, "decrypted %d feature file(s); encryption is off", n)
+		if err := writeProgressDoc(absWorkspace); err != nil {
+			printStatus(tagWarn, "PROGRESS.md: %v", err)
+		}
+		GenerateWorkspaceDocs(absWorkspace)
+		return
+	}
+
+	if c != nil {
+		fatal("%s is already encrypted (key %s)", absWorkspace, c.KeyID)
+	}
+	if _, err := os.Stat(filepath.Join(absWorkspace, ".projects")); err != nil {
+		fatal("%s has no .projects/; run 'orchestra init' first", absWorkspace)
+	}
+	checkWorkspaceSchema(absWorkspace, true)
+	bins, err := siblingBins()
+	if err != nil {
+		fatal("%v", err)
+	}
+	if problem := storageEncryptionProblem(serveConfig(bins, "", absWorkspace, "", nil, io.Discard).Plugins); problem != "" {
+		fatal("not encrypting %s: %s; serve could not use it", absWorkspace, problem)
+	}
+
+	key := make([]byte, workspaceKeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		fatal("generate key: %v", err)
+	}
+	c = &encryptionConfig{Cipher: encryptionCipher, KeyID: keyID(key)}
+	var recipients []string
+	for _, r := range strings.Split(*ageRecipients, ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			recipients = append(recipients, r)
+		}
+	}
+	if len(recipients) > 0 {
+		if err := ageEncryptKey(absWorkspace, key, recipients); err != nil {
+			fatal("%v", err)
+		}
+		c.KeyStore, c.AgeRecipients = keyStoreAge, recipients
+		printStatus(tagOK, "key wrapped for %d age recipient(s) in .projects/%s", len(recipients), encryptionKeyAgeAt)
+	} else {
+		store, err := storeKey(key)
+		if err != nil {
+			fatal("store key: %v", err)
+		}
+		c.KeyStore = store
+		printStatus(tagOK, "key %s kept in the %s", c.KeyID, keyStoreLabel(store))
+	}
+
+	// Encrypt the files before recording the config, so an interrupted run
+	// leaves a readable workspace: readProjectFile decrypts by header, and
+	// the key is cached for this process.
+	workspaceKeysMu.Lock()
+	workspaceKeys[c.KeyID] = key
+	workspaceKeysMu.Unlock()
+	encryptFile := func(path string) error {
+		data, err := os.ReadFile(path)
+		if err != nil || isEncrypted(data) {
+			return err
+		}
+		sealed, err := sealFile(key, data)
+		if err != nil {
+			return err
+		}
+		return writeFileAtomic(path, sealed, 0644)
+	}
+	n := rewriteFeatureFiles(absWorkspace, encryptFile)
+	// The feature index repeats every title.
+	if err := encryptFile(featureIndexPath(absWorkspace)); err != nil && !os.IsNotExist(err) {
+		fatal("encrypt feature index: %v", err)
+	}
+	for _, path := range featureArchivePaths(absWorkspace, "") {
+		if err := encryptFile(path); err != nil {
+			fatal("encrypt archive %s: %v", filepath.Base(path), err)
+		}
+	}
+	if err := saveEncryptionConfig(absWorkspace, c); err != nil {
+		fatal("write %s: %v", encryptionConfigPath(absWorkspace), err)
+	}
+	printStatus(tagOK, "encrypted %d feature file(s)", n)
+
+	// Generated rollups would repeat titles in the clear.
+	for _, p := range []string{progressDocPath(absWorkspace), digestPath(absWorkspace)} {
+		if err := os.Remove(p); err == nil {
+			rel, _ := filepath.Rel(absWorkspace, p)
+			printStatus(tagOK, "removed %s (not generated for encrypted workspaces)", filepath.ToSlash(rel))
+		}
+	}
+	GenerateWorkspaceDocs(absWorkspace)
+
+	if c.KeyStore != keyStoreAge {
+		fmt.Fprintf(os.Stderr, "\nShare the key with teammates and CI out of band:\n")
+		fmt.Fprintf(os.Stderr, "  orchestra encrypt-workspace --export-key      # here\n")
+		fmt.Fprintf(os.Stderr, "  orchestra encrypt-workspace --import-key      # on their machine, key on stdin\n")
+		fmt.Fprintf(os.Stderr, "  %s=<key>                    # for CI\n", workspaceKeyEnv)
+	}
+}
+
+// rewriteFeatureFiles applies rewrite to every feature file in the
+// workspace, reporting failures, and returns how many it rewrote.
+func rewriteFeatureFiles(workspace string, rewrite func(path string) error) int {
+	n := 0
+	for _, project := range listFeatureProjects(workspace) {
+		entries, err := os.ReadDir(featuresDir(workspace, project))
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if e.IsDir() || !strings.HasSuffix(e.Name(), ".md") {
+				continue
+			}
+			if err := rewrite(filepath.Join(featuresDir(workspace, project), e.Name())); err != nil {
+				printStatus(tagFail, "%s/%s: %v", project, e.Name(), err)
+				continue
+			}
+			n++
+		}
+	}
+	return n
+}
+
+func keyStoreLabel(store string) string {
+	if store == keyStoreKeychain {
+		return "OS keychain"
+	}
+	return "key file ~/.orchestra/keys/workspaces/"
+}
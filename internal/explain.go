@@ -0,0 +1,134 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// RunExplain handles `orchestra explain [topic]` -- prints focused
+// documentation for an MCP tool, lifecycle state, or gate.
+func RunExplain(args []string) {
+	fs := newFlagSet("explain")
+	parseFlags(fs, args)
+
+	topic := strings.ToLower(strings.Join(fs.Args(), " "))
+	switch topic {
+	case "":
+		fmt.Fprintf(os.Stdout, "Topics:\n")
+		fmt.Fprintf(os.Stdout, "  tools    the %d MCP tools, by group\n", toolCount(""))
+		fmt.Fprintf(os.Stdout, "  states   the feature lifecycle\n")
+		fmt.Fprintf(os.Stdout, "  gates    evidence-gated transitions\n")
+		fmt.Fprintf(os.Stdout, "\nRun 'orchestra explain <tool|state|gate>' for details, e.g.\n")
+		fmt.Fprintf(os.Stdout, "  orchestra explain advance_feature\n")
+		fmt.Fprintf(os.Stdout, "  orchestra explain in-review\n")
+		fmt.Fprintf(os.Stdout, "  orchestra explain gate 2\n")
+		return
+	case "tools", "tool":
+		explainTools()
+		return
+	case "states", "state", "lifecycle":
+		explainStates()
+		return
+	case "gates", "gate":
+		explainGates()
+		return
+	}
+
+	if t, g := findTool(topic); t != nil {
+		explainTool(t, g)
+		return
+	}
+	if s := findState(topic); s != nil {
+		explainState(s)
+		return
+	}
+	if g := findGate(parseGateNumber(topic)); g != nil {
+		explainGate(g)
+		return
+	}
+
+	fatal("nothing to explain for %q. Run: orchestra explain", topic)
+}
+
+// parseGateNumber accepts "2", "gate 2", "gate-2", and "gate2".
+func parseGateNumber(topic string) int {
+	topic = strings.TrimPrefix(topic, "gate")
+	topic = strings.TrimLeft(topic, " -")
+	n, err := strconv.Atoi(topic)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+func explainTools() {
+	for _, g := range toolGroups {
+		fmt.Fprintf(os.Stdout, "%s (%s)\n", g.Name, g.Plugin)
+		tw := newTable(os.Stdout)
+		for _, t := range g.Tools {
+			fmt.Fprintf(tw, "  %s\t%s\n", t.Name, t.Summary)
+		}
+		tw.Flush()
+		fmt.Fprintln(os.Stdout)
+	}
+}
+
+func explainStates() {
+	tw := newTable(os.Stdout)
+	for _, s := range lifecycleStates {
+		gate := ""
+		if s.Gate > 0 {
+			gate = fmt.Sprintf("gate %d", s.Gate)
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\n", s.Name, gate, s.Summary)
+	}
+	tw.Flush()
+}
+
+func explainGates() {
+	tw := newTable(os.Stdout)
+	for _, g := range workflowGates {
+		fmt.Fprintf(tw, "  gate %d\t%s\t%s\n", g.Number, g.From, g.Action)
+	}
+	tw.Flush()
+}
+
+func explainTool(t *mcpTool, g *toolGroup) {
+	fmt.Fprintf(os.Stdout, "%s — %s\n\n", t.Name, t.Summary)
+	fmt.Fprintf(os.Stdout, "Group:     %s (%s plugin)\n", g.Name, g.Plugin)
+	if t.Args != "" {
+		fmt.Fprintf(os.Stdout, "Arguments: %s\n", t.Args)
+	}
+	if t.Notes != "" {
+		fmt.Fprintf(os.Stdout, "\n%s\n", t.Notes)
+	}
+	if t.Name == "advance_feature" {
+		fmt.Fprintf(os.Stdout, "\nGates:\n")
+		explainGates()
+	}
+}
+
+func explainState(s *lifecycleState) {
+	fmt.Fprintf(os.Stdout, "%s — %s\n\n", s.Name, s.Summary)
+	if len(s.Next) > 0 {
+		fmt.Fprintf(os.Stdout, "Next:     %s\n", strings.Join(s.Next, ", "))
+	} else {
+		fmt.Fprintf(os.Stdout, "Next:     (terminal state)\n")
+	}
+	if g := findGate(s.Gate); g != nil {
+		fmt.Fprintf(os.Stdout, "Gate %d:   %s\n", g.Number, g.Action)
+		fmt.Fprintf(os.Stdout, "Evidence: %s\n", g.Evidence)
+	}
+}
+
+func explainGate(g *workflowGate) {
+	fmt.Fprintf(os.Stdout, "Gate %d — leaving %s\n\n", g.Number, g.From)
+	fmt.Fprintf(os.Stdout, "Action:   %s\n", g.Action)
+	fmt.Fprintf(os.Stdout, "Evidence: %s\n", g.Evidence)
+	if s := findState(g.From); s != nil && len(s.Next) > 0 {
+		fmt.Fprintf(os.Stdout, "Unlocks:  %s\n", strings.Join(s.Next, ", "))
+	}
+	fmt.Fprintf(os.Stdout, "\nPass the evidence to advance_feature. Never batch-advance through gates.\n")
+}
@@ -0,0 +1,301 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// feature is a feature record as written by the storage plugin:
+// .projects/<project>/features/<ID>.md with YAML frontmatter and a markdown
+// body holding the description.
+type feature struct {
+	ID        string   `yaml:"id"`
+	Title     string   `yaml:"title"`
+	Status    string   `yaml:"status"`
+	Priority  string   `yaml:"priority,omitempty"`
+	Labels    []string `yaml:"labels,omitempty"`
+	Assignee  string   `yaml:"assignee,omitempty"`
+	Estimate  string   `yaml:"estimate,omitempty"`
+	DependsOn []string `yaml:"depends_on,omitempty"`
+	Parent    string   `yaml:"parent,omitempty"`
+	CreatedAt string   `yaml:"created_at,omitempty"`
+	UpdatedAt string   `yaml:"updated_at,omitempty"`
+
+	Reviews  []featureReview   `yaml:"reviews,omitempty"`
+	Evidence []featureEvidence `yaml:"evidence,omitempty"`
+
+	// Extra keeps frontmatter keys this CLI does not know about, so saving a
+	// feature never drops data written by a newer storage plugin.
+	Extra map[string]any `yaml:",inline"`
+
+	Project string `yaml:"-"`
+	Body    string `yaml:"-"`
+	path    string
+}
+
+// featureReview is one review outcome, as recorded by submit_review or
+// `orchestra review`.
+type featureReview struct {
+	Reviewer string `yaml:"reviewer,omitempty"`
+	Status   string `yaml:"status"` // approved or needs-edits
+	Notes    string `yaml:"notes,omitempty"`
+	Time     string `yaml:"time"`
+}
+
+// featureEvidence is the proof of work given when passing a gate.
+type featureEvidence struct {
+	Gate int    `yaml:"gate"`
+	From string `yaml:"from"`
+	To   string `yaml:"to"`
+	Text string `yaml:"text"`
+	Time string `yaml:"time"`
+}
+
+// hasLabel reports whether the feature carries label (case-insensitive).
+func (f *feature) hasLabel(label string) bool {
+	for _, l := range f.Labels {
+		if strings.EqualFold(l, label) {
+			return true
+		}
+	}
+	return false
+}
+
+// featuresDir returns the directory holding a project's feature files.
+func featuresDir(workspace, project string) string {
+	return filepath.Join(workspace, ".projects", project, "features")
+}
+
+// featureKey identifies a feature in the workspace: IDs are only unique
+// within a project.
+func featureKey(project, id string) string {
+	return project + "/" + id
+}
+
+// listFeatureProjects returns the projects in the workspace that have a
+// features/ directory, sorted by name.
+func listFeatureProjects(workspace string) []string {
+	entries, err := os.ReadDir(filepath.Join(workspace, ".projects"))
+	if err != nil {
+		return nil
+	}
+	var projects []string
+	for _, e := range entries {
+		if !e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		if info, err := os.Stat(featuresDir(workspace, e.Name())); err == nil && info.IsDir() {
+			projects = append(projects, e.Name())
+		}
+	}
+	sortNames(projects)
+	return projects
+}
+
+// loadFeatures reads every feature in project, or in all projects when
+// project is empty. Unreadable files are skipped with a warning.
+func loadFeatures(workspace, project string) []*feature {
+	return scanFeatures(workspace, project, func(name string, err error) {
+		printStatus(tagWarn, "skip %s: %v", name, err)
+	})
+}
+
+// scanFeatures is loadFeatures with a caller-supplied handler for unreadable
+// files; a nil handler skips them silently.
+func scanFeatures(workspace, project string, onError func(name string, err error)) []*feature {
+	if !featureKeyAvailable(workspace) {
+		return nil
+	}
+	projects := []string{project}
+	if project == "" {
+		projects = listFeatureProjects(workspace)
+	}
+
+	var features []*feature
+	for _, p := range projects {
+		entries, err := os.ReadDir(featuresDir(workspace, p))
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if e.IsDir() || !strings.HasSuffix(e.Name(), ".md") {
+				continue
+			}
+			f, err := readFeature(filepath.Join(featuresDir(workspace, p), e.Name()))
+			if err != nil {
+				if onError != nil {
+					onError(p+"/"+e.Name(), err)
+				}
+				continue
+			}
+			f.Project = p
+			features = append(features, f)
+		}
+	}
+	return features
+}
+
+// findFeature locates a feature by ID across all projects.
+func findFeature(workspace, id string) (*feature, error) {
+	for _, f := range loadFeatures(workspace, "") {
+		if strings.EqualFold(f.ID, id) {
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("feature %q not found in %s", id, filepath.Join(workspace, ".projects"))
+}
+
+// readFeature parses a feature file, decrypting it in encrypted workspaces.
+func readFeature(path string) (*feature, error) {
+	data, err := readProjectFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseFeature(data, path)
+}
+
+// parseFeature parses the contents of a feature file read from path.
+func parseFeature(data []byte, path string) (*feature, error) {
+	front, body, err := splitFrontmatter(data)
+	if err != nil {
+		return nil, err
+	}
+	f := &feature{path: path}
+	if err := yaml.Unmarshal(front, f); err != nil {
+		return nil, fmt.Errorf("parse frontmatter: %w", err)
+	}
+	if f.ID == "" {
+		f.ID = strings.TrimSuffix(filepath.Base(path), ".md")
+	}
+	f.Body = string(body)
+	return f, nil
+}
+
+// saveFeature writes f back to its file, preserving the body. Encrypted
+// workspaces get it encrypted.
+func saveFeature(f *feature) error {
+	var b bytes.Buffer
+	b.WriteString("---\n")
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(f); err != nil {
+		return err
+	}
+	enc.Close()
+	b.WriteString("---\n")
+	b.WriteString(f.Body)
+	return writeProjectFile(f.path, []byte(normalizeNewlines(b.String())))
+}
+
+// sortFeatures sorts features in place by less, keeping equal elements in
+// their original order.
+func sortFeatures(features []*feature, less func(a, b *feature) bool) {
+	sort.SliceStable(features, func(i, j int) bool { return less(features[i], features[j]) })
+}
+
+// findFeatureForUpdate is findFeature for commands about to modify the
+// feature: it records pending transitions first so the history shows the
+// status the change started from.
+func findFeatureForUpdate(workspace, id string) (*feature, error) {
+	if _, err := recordTransitions(workspace); err != nil {
+		printStatus(tagWarn, "could not update history: %v", err)
+	}
+	return findFeature(workspace, id)
+}
+
+// createFeature writes a new feature file into project. f.ID must be set;
+// see newFeatureID. The caller records transitions once the batch is written.
+func createFeature(workspace, project string, f *feature) error {
+	dir := featuresDir(workspace, project)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	f.path = filepath.Join(dir, f.ID+".md")
+	if _, err := os.Stat(f.path); err == nil {
+		return fmt.Errorf("%s already exists", f.path)
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	if f.CreatedAt == "" {
+		f.CreatedAt = now
+	}
+	f.UpdatedAt = now
+	f.Project = project
+	return saveFeature(f)
+}
+
+// featureIDs returns the upper-cased IDs of every feature in the workspace,
+// archived ones included, so an ID is never given out twice.
+func featureIDs(workspace string) map[string]bool {
+	ids := map[string]bool{}
+	for _, f := range scanFeatures(workspace, "", nil) {
+		ids[strings.ToUpper(f.ID)] = true
+	}
+	archived, _ := loadArchivedFeatures(workspace, "")
+	for _, f := range archived {
+		ids[strings.ToUpper(f.ID)] = true
+	}
+	return ids
+}
+
+// newFeatureID returns an ID of the form FEAT-XXX not in taken, and adds it.
+func newFeatureID(taken map[string]bool) (string, error) {
+	const letters = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	buf := make([]byte, 3)
+	for attempt := 0; attempt < 1000; attempt++ {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		id := "FEAT-"
+		for _, c := range buf {
+			id += string(letters[int(c)%len(letters)])
+		}
+		if !taken[id] {
+			taken[id] = true
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("could not find a free feature ID")
+}
+
+// commitFeature stamps updated_at, saves f, records any status change in the
+// transition history, and refreshes PROGRESS.md. Commands that modify features go through here.
+// Only a failed save is an error: once f is saved, the history and
+// PROGRESS.md are derived, so failing to update them is a warning.
+func commitFeature(workspace string, f *feature) error {
+	f.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	if err := saveFeature(f); err != nil {
+		return err
+	}
+	if _, err := recordTransitions(workspace); err != nil {
+		printStatus(tagWarn, "status history: %v", err)
+	}
+	if err := writeProgressDoc(workspace); err != nil {
+		printStatus(tagWarn, "PROGRESS.md: %v", err)
+	}
+	return nil
+}
+
+// splitFrontmatter separates a leading "---" YAML block from the body.
+func splitFrontmatter(data []byte) (front, body []byte, err error) {
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	if !bytes.HasPrefix(data, []byte("---\n")) {
+		return nil, nil, fmt.Errorf("missing frontmatter")
+	}
+	rest := data[len("---\n"):]
+	end := bytes.Index(rest, []byte("\n---\n"))
+	if end < 0 {
+		if bytes.HasSuffix(rest, []byte("\n---")) {
+			return rest[:len(rest)-len("\n---")], nil, nil
+		}
+		return nil, nil, fmt.Errorf("unterminated frontmatter")
+	}
+	return rest[:end+1], rest[end+len("\n---\n"):], nil
+}
@@ -0,0 +1,472 @@
+package internal
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// featureTemplate supplies defaults for a kind of feature. Workspaces add or
+// override templates under `templates:` in .orchestra.yaml.
+type featureTemplate struct {
+	Labels      []string `yaml:"labels,omitempty"`
+	Priority    string   `yaml:"priority,omitempty"`
+	Estimate    string   `yaml:"estimate,omitempty"`
+	Description string   `yaml:"description,omitempty"`
+}
+
+// builtinTemplates are available in every workspace.
+var builtinTemplates = map[string]featureTemplate{
+	"bug": {
+		Labels:      []string{"bug"},
+		Priority:    "P1",
+		Description: "## Steps to reproduce\n\n## Expected\n\n## Actual\n",
+	},
+	"spike": {
+		Labels:      []string{"spike"},
+		Estimate:    "S",
+		Description: "## Question\n\n## Timebox\n\n## Findings\n",
+	},
+	"epic": {
+		Labels:      []string{"epic"},
+		Estimate:    "XL",
+		Description: "## Goal\n\n## Scope\n\n## Out of scope\n",
+	},
+}
+
+// featureSpec is one feature in a bulk-create file. Key is a local name that
+// depends_on entries in the same file can refer to.
+type featureSpec struct {
+	Key         string   `yaml:"key,omitempty"`
+	Title       string   `yaml:"title"`
+	Description string   `yaml:"description,omitempty"`
+	Template    string   `yaml:"template,omitempty"`
+	Priority    string   `yaml:"priority,omitempty"`
+	Labels      []string `yaml:"labels,omitempty"`
+	Estimate    string   `yaml:"estimate,omitempty"`
+	Assignee    string   `yaml:"assignee,omitempty"`
+	DependsOn   []string `yaml:"depends_on,omitempty"`
+	Parent      string   `yaml:"parent,omitempty"`
+}
+
+// featureFile is the YAML bulk-create format.
+type featureFile struct {
+	Project  string        `yaml:"project,omitempty"`
+	Features []featureSpec `yaml:"features"`
+}
+
+// workspaceTemplates merges builtin templates with the workspace's own.
+func workspaceTemplates(cfg *workspaceConfig) map[string]featureTemplate {
+	out := make(map[string]featureTemplate, len(builtinTemplates)+len(cfg.Templates))
+	for name, t := range builtinTemplates {
+		out[name] = t
+	}
+	for name, t := range cfg.Templates {
+		out[name] = t
+	}
+	return out
+}
+
+// --- create ---
+
+func runFeaturesCreate(args []string) {
+	fs := newFlagSet("features create")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	file := fs.String("file", "", "YAML or CSV file of features to create (- for stdin)")
+	fs.StringVar(file, "f", "", "Shorthand for --file")
+	project := fs.String("project", "", "Project to create features in (overrides the file)")
+	dryRun := fs.Bool("dry-run", false, "Validate and print the plan without writing")
+	parseFlags(fs, args)
+
+	if *file == "" {
+		fatal("usage: orchestra features create -f features.yaml")
+	}
+
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	checkWorkspaceSchema(absWorkspace, true)
+
+	spec, err := readFeatureFile(*file)
+	if err != nil {
+		fatal("%v", err)
+	}
+	if *project != "" {
+		spec.Project = *project
+	}
+	if spec.Project == "" {
+		projects := listFeatureProjects(absWorkspace)
+		if len(projects) != 1 {
+			fatal("no project given; pass --project or set project: in the file")
+		}
+		spec.Project = projects[0]
+	}
+	if len(spec.Features) == 0 {
+		fatal("%s has no features", *file)
+	}
+
+	features, err := planFeatures(absWorkspace, spec, workspaceTemplates(loadWorkspaceConfig(absWorkspace)))
+	if err != nil {
+		fatal("%v", err)
+	}
+
+	if *dryRun {
+		tw := newTable(os.Stdout)
+		for _, f := range features {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", f.ID, f.Title, strings.Join(f.Labels, ","), strings.Join(f.DependsOn, ","))
+		}
+		tw.Flush()
+		fmt.Fprintf(os.Stderr, "Dry run: %d features would be created in %s\n", len(features), spec.Project)
+		return
+	}
+
+	createPlannedFeatures(absWorkspace, spec.Project, features)
+}
+
+// createPlannedFeatures writes features planned by planFeatures, then
+// records them in the history and PROGRESS.md. Labels and assignees
+// outside the workspace taxonomy are warned about, not refused.
+func createPlannedFeatures(workspace, project string, features []*feature) {
+	warnTaxonomy(workspace, features)
+	for _, f := range features {
+		if err := createFeature(workspace, project, f); err != nil {
+			fatal("create %q: %v", f.Title, err)
+		}
+		printStatus(tagOK, "%s %s", f.ID, f.Title)
+	}
+	if _, err := recordTransitions(workspace); err != nil {
+		printStatus(tagWarn, "could not update history: %v", err)
+	}
+	if err := writeProgressDoc(workspace); err != nil {
+		printStatus(tagWarn, "PROGRESS.md: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "Created %d features in %s\n", len(features), project)
+}
+
+// planFeatures applies templates, assigns IDs, and resolves dependencies.
+// Nothing is written, so a bad entry aborts the whole batch.
+func planFeatures(workspace string, spec *featureFile, templates map[string]featureTemplate) ([]*feature, error) {
+	existing := map[string]string{} // upper-cased ID -> ID
+	for _, f := range scanFeatures(workspace, "", nil) {
+		existing[strings.ToUpper(f.ID)] = f.ID
+	}
+	taken := featureIDs(workspace)
+
+	local := map[string]string{} // key or lower-cased title -> new ID
+	features := make([]*feature, len(spec.Features))
+	for i, s := range spec.Features {
+		if strings.TrimSpace(s.Title) == "" {
+			return nil, fmt.Errorf("feature %d: title is required", i+1)
+		}
+		f := &feature{
+			Title:    s.Title,
+			Status:   "backlog",
+			Priority: s.Priority,
+			Labels:   s.Labels,
+			Estimate: s.Estimate,
+			Assignee: s.Assignee,
+			Body:     s.Description,
+		}
+		if s.Template != "" {
+			t, ok := templates[s.Template]
+			if !ok {
+				return nil, fmt.Errorf("feature %q: unknown template %q", s.Title, s.Template)
+			}
+			applyTemplate(f, t)
+		}
+		id, err := newFeatureID(taken)
+		if err != nil {
+			return nil, err
+		}
+		f.ID = id
+		if s.Key != "" {
+			local[s.Key] = id
+		}
+		local[strings.ToLower(s.Title)] = id
+		features[i] = f
+	}
+
+	// resolve maps a key, a title in this file, or an existing ID to an ID.
+	resolve := func(ref string) string {
+		switch {
+		case local[ref] != "":
+			return local[ref]
+		case local[strings.ToLower(ref)] != "":
+			return local[strings.ToLower(ref)]
+		default:
+			return existing[strings.ToUpper(ref)]
+		}
+	}
+	for i, s := range spec.Features {
+		for _, dep := range s.DependsOn {
+			id := resolve(dep)
+			if id == "" {
+				return nil, fmt.Errorf("feature %q: depends_on %q matches no key, title, or existing feature ID", s.Title, dep)
+			}
+			features[i].DependsOn = append(features[i].DependsOn, id)
+		}
+		if s.Parent != "" {
+			id := resolve(s.Parent)
+			if id == "" {
+				return nil, fmt.Errorf("feature %q: parent %q matches no key, title, or existing feature ID", s.Title, s.Parent)
+			}
+			if id == features[i].ID {
+				return nil, fmt.Errorf("feature %q: cannot be its own parent", s.Title)
+			}
+			features[i].Parent = id
+		}
+	}
+	return features, nil
+}
+
+// applyTemplate fills fields the spec left empty and merges labels.
+func applyTemplate(f *feature, t featureTemplate) {
+	if f.Priority == "" {
+		f.Priority = t.Priority
+	}
+	if f.Estimate == "" {
+		f.Estimate = t.Estimate
+	}
+	if f.Body == "" {
+		f.Body = t.Description
+	}
+	for _, l := range t.Labels {
+		if !f.hasLabel(l) {
+			f.Labels = append(f.Labels, l)
+		}
+	}
+}
+
+// readFeatureFile parses a YAML or CSV bulk-create file, picking the format
+// from the extension (stdin is YAML).
+func readFeatureFile(path string) (*featureFile, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		fh, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer fh.Close()
+		r = fh
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return readFeatureCSV(r)
+	}
+	spec := &featureFile{}
+	if err := yaml.NewDecoder(r).Decode(spec); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return spec, nil
+}
+
+// readFeatureCSV reads a CSV with a header row. Known columns: key, title,
+// description, template, priority, labels, estimate, assignee, depends_on,
+// parent.
+// List columns separate values with ";".
+func readFeatureCSV(r io.Reader) (*featureFile, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("parse CSV: %w", err)
+	}
+	if len(rows) == 0 {
+		return &featureFile{}, nil
+	}
+
+	col := map[string]int{}
+	for i, h := range rows[0] {
+		col[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	if _, ok := col["title"]; !ok {
+		return nil, fmt.Errorf("CSV header has no title column")
+	}
+	get := func(row []string, name string) string {
+		if i, ok := col[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+	list := func(row []string, name string) []string {
+		var out []string
+		for _, v := range strings.Split(get(row, name), ";") {
+			if v = strings.TrimSpace(v); v != "" {
+				out = append(out, v)
+			}
+		}
+		return out
+	}
+
+	spec := &featureFile{}
+	for _, row := range rows[1:] {
+		spec.Features = append(spec.Features, featureSpec{
+			Key:         get(row, "key"),
+			Title:       get(row, "title"),
+			Description: get(row, "description"),
+			Template:    get(row, "template"),
+			Priority:    get(row, "priority"),
+			Labels:      list(row, "labels"),
+			Estimate:    get(row, "estimate"),
+			Assignee:    get(row, "assignee"),
+			DependsOn:   list(row, "depends_on"),
+			Parent:      get(row, "parent"),
+		})
+	}
+	return spec, nil
+}
+
+// --- templates ---
+
+func runFeaturesTemplates(args []string) {
+	fs := newFlagSet("features templates")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	parseFlags(fs, args)
+
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	cfg := loadWorkspaceConfig(absWorkspace)
+	templates := workspaceTemplates(cfg)
+
+	var names []string
+	for name := range templates {
+		names = append(names, name)
+	}
+	sortNames(names)
+
+	tw := newTable(os.Stdout)
+	for _, name := range names {
+		t := templates[name]
+		source := "builtin"
+		if _, ok := cfg.Templates[name]; ok {
+			source = "workspace"
+		}
+		fmt.Fprintf(tw, "  %s\t%s\tlabels=%s\tpriority=%s\testimate=%s\n",
+			name, source, strings.Join(t.Labels, ","), t.Priority, t.Estimate)
+	}
+	tw.Flush()
+}
+
+// --- list ---
+
+// featureJSON is a feature's frontmatter as the --json output of the
+// commands that list features prints it.
+type featureJSON struct {
+	Project    string   `json:"project"`
+	ID         string   `json:"id"`
+	Title      string   `json:"title"`
+	Status     string   `json:"status"`
+	Priority   string   `json:"priority,omitempty"`
+	Labels     []string `json:"labels"`
+	Assignee   string   `json:"assignee,omitempty"`
+	Estimate   string   `json:"estimate,omitempty"`
+	DependsOn  []string `json:"depends_on"`
+	Parent     string   `json:"parent,omitempty"`
+	CreatedAt  string   `json:"created_at,omitempty"`
+	UpdatedAt  string   `json:"updated_at,omitempty"`
+	ArchivedAt string   `json:"archived_at,omitempty"`
+}
+
+func describeFeatureJSON(f *feature) featureJSON {
+	j := featureJSON{
+		Project:   f.Project,
+		ID:        f.ID,
+		Title:     f.Title,
+		Status:    f.Status,
+		Priority:  f.Priority,
+		Labels:    f.Labels,
+		Assignee:  f.Assignee,
+		Estimate:  f.Estimate,
+		DependsOn: f.DependsOn,
+		Parent:    f.Parent,
+		CreatedAt: f.CreatedAt,
+		UpdatedAt: f.UpdatedAt,
+	}
+	if j.Labels == nil {
+		j.Labels = []string{}
+	}
+	if j.DependsOn == nil {
+		j.DependsOn = []string{}
+	}
+	return j
+}
+
+func runFeaturesList(args []string) {
+	fs := newFlagSet("features list")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	project := fs.String("project", "", "Only list features of this project")
+	status := fs.String("status", "", "Only list features with this status")
+	label := fs.String("label", "", "Only list features with this label")
+	archived := fs.Bool("archived", false, "List archived features instead of the active ones")
+	porcelain := fs.Bool("porcelain", false, "Stable tab-separated output for scripts")
+	parseFlags(fs, args)
+
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+
+	var features []*feature
+	if *archived {
+		if features, err = loadArchivedFeatures(absWorkspace, *project); err != nil {
+			fatal("read archive: %v", err)
+		}
+	} else {
+		features = loadFeatures(absWorkspace, *project)
+	}
+
+	var shown []*feature
+	for _, f := range features {
+		if *status != "" && f.Status != *status || *label != "" && !containsFold(f.Labels, *label) {
+			continue
+		}
+		shown = append(shown, f)
+	}
+
+	if jsonOutput() {
+		list := []featureJSON{}
+		for _, f := range shown {
+			j := describeFeatureJSON(f)
+			if *archived {
+				j.ArchivedAt = archivedAt(f.path).Format(time.RFC3339)
+			}
+			list = append(list, j)
+		}
+		printJSON(list)
+		return
+	}
+
+	// Porcelain: project, ID, status, priority, title; with --archived,
+	// then the time the feature was archived (RFC 3339).
+	tw := newTable(os.Stdout)
+	for _, f := range shown {
+		if *porcelain {
+			line := fmt.Sprintf("%s\t%s\t%s\t%s\t%s", f.Project, f.ID, f.Status, f.Priority, f.Title)
+			if *archived {
+				line += "\t" + archivedAt(f.path).Format(time.RFC3339)
+			}
+			fmt.Fprintln(os.Stdout, line)
+			continue
+		}
+		extra := f.Project
+		if *archived {
+			extra += "\tarchived " + archivedAt(f.path).Format("2006-01-02")
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\n", f.ID, f.Status, orDash(f.Priority), f.Title, extra)
+	}
+	tw.Flush()
+	if len(shown) == 0 && !*porcelain {
+		if *archived {
+			fmt.Fprintln(os.Stderr, "No archived features.")
+		} else {
+			fmt.Fprintln(os.Stderr, "No features.")
+		}
+	}
+}
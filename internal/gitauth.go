@@ -0,0 +1,121 @@
+package internal
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+// Private repos need a token for release lookups, downloads, and clones.
+// Tokens are looked up per host, first match wins:
+//
+//	github.com    ORCHESTRA_GITHUB_TOKEN, GITHUB_TOKEN, ORCHESTRA_GIT_TOKEN,
+//	              github_token:, credentials: github.com
+//	GitLab hosts  ORCHESTRA_GITLAB_TOKEN, GITLAB_TOKEN, ORCHESTRA_GIT_TOKEN,
+//	              gitlab_token:, credentials: <host>
+//	other hosts   credentials: <host>
+//
+// ORCHESTRA_GIT_TOKEN is never sent to other hosts: a pack's dependencies
+// can name any host, and the token must not follow them there.
+//
+// Clones get the token as an http.<url>.extraHeader in git's environment
+// (GIT_CONFIG_COUNT), scoped to the repo's host. It is never written into
+// a clone URL, so it does not end up in a dev clone's .git/config, in
+// error messages, or in the process list.
+
+// gitCredential is one entry of credentials: in ~/.orchestra/config.yaml.
+type gitCredential struct {
+	Username string `yaml:"username,omitempty"` // HTTP basic auth user for clones; see hostCredential
+	Token    string `yaml:"token"`
+}
+
+// hostCredential returns the user and token to authenticate to host with,
+// or an empty token. The user is what the host expects alongside a token
+// for HTTPS clones, unless credentials: names one.
+func hostCredential(host string) (user, token string) {
+	c := loadGlobalConfig().Credentials[host]
+	switch {
+	case host == "github.com":
+		user, token = "x-access-token", githubToken()
+	case isGitLabHost(host):
+		user, token = "oauth2", gitlabToken(host)
+	case host == "bitbucket.org":
+		user, token = "x-token-auth", c.Token
+	default:
+		user, token = "x-access-token", c.Token
+	}
+	if c.Username != "" {
+		user = c.Username
+	}
+	return user, token
+}
+
+// requestToken returns the token to send with an HTTP request for rawURL:
+// the GitHub token for GitHub and its API and raw hosts, the host's token
+// otherwise.
+func requestToken(rawURL string) string {
+	if isGitHubURL(rawURL) {
+		return githubToken()
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	_, token := hostCredential(u.Hostname())
+	return token
+}
+
+// gitlabToken returns ORCHESTRA_GITLAB_TOKEN, GITLAB_TOKEN,
+// ORCHESTRA_GIT_TOKEN, gitlab_token from the global config, or host's
+// entry under credentials:, in that order.
+func gitlabToken(host string) string {
+	for _, env := range []string{"ORCHESTRA_GITLAB_TOKEN", "GITLAB_TOKEN", "ORCHESTRA_GIT_TOKEN"} {
+		if v := os.Getenv(env); v != "" {
+			return v
+		}
+	}
+	cfg := loadGlobalConfig()
+	if cfg.GitLabToken != "" {
+		return cfg.GitLabToken
+	}
+	return cfg.Credentials[host].Token
+}
+
+// gitCommand is exec.Command for a git command that talks to repo's
+// remote, authenticated with repo's host token when there is one.
+func gitCommand(repo string, args ...string) *exec.Cmd {
+	cmd := exec.Command("git", args...)
+	cmd.Env = gitAuthEnv(repo)
+	return cmd
+}
+
+// gitAuthEnv returns the environment for git commands against repo: this
+// process's, plus an Authorization header for repo's host when repo is
+// cloned over HTTPS and a token is set. nil means the environment as is.
+func gitAuthEnv(repo string) []string {
+	r, err := parseRepoRef(repo)
+	if err != nil {
+		return nil
+	}
+	u, err := url.Parse(r.clone)
+	if err != nil || u.Scheme != "https" || u.User != nil {
+		return nil // SSH uses the user's keys; a URL with a user brings its own
+	}
+	user, token := hostCredential(r.Host)
+	if token == "" {
+		return nil
+	}
+
+	// Append to any config already passed this way rather than replace it.
+	n, _ := strconv.Atoi(os.Getenv("GIT_CONFIG_COUNT"))
+	basic := base64.StdEncoding.EncodeToString([]byte(user + ":" + token))
+	return append(os.Environ(),
+		fmt.Sprintf("GIT_CONFIG_COUNT=%d", n+1),
+		fmt.Sprintf("GIT_CONFIG_KEY_%d=http.https://%s/.extraHeader", n, u.Host),
+		fmt.Sprintf("GIT_CONFIG_VALUE_%d=Authorization: Basic %s", n, basic),
+		"GIT_TERMINAL_PROMPT=0",
+	)
+}
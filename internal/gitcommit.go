@@ -0,0 +1,153 @@
+package internal
+
+import (
+	"flag"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// defaultGitMessage is the --git-message template. {action} is the
+// command (init, install, remove, update) and {target} what it acted on.
+const defaultGitMessage = "chore(orchestra): {action} {target}"
+
+// generatedPaths are the workspace files orchestra writes for every
+// command that takes --git-commit, relative to the workspace.
+var generatedPaths = []string{
+	"CLAUDE.md",
+	"AGENTS.md",
+	".claude",
+	".projects/.packs",
+	".projects/.schema.json",
+	".projects/PROGRESS.md",
+}
+
+// gitCommitFlags registers --git-commit and --git-message on fs.
+type gitCommitFlags struct {
+	commit  *bool
+	message *string
+}
+
+func addGitCommitFlags(fs *flag.FlagSet) gitCommitFlags {
+	return gitCommitFlags{
+		commit:  fs.Bool("git-commit", false, "Stage and commit the files this command generates or changes"),
+		message: fs.String("git-message", defaultGitMessage, "Commit message template for --git-commit; {action} and {target} are filled in"),
+	}
+}
+
+// gitCommit commits the files an orchestra command wrote, and nothing
+// else: other staged or modified files are left as they were.
+type gitCommit struct {
+	workspace string
+	paths     []string
+	message   string
+}
+
+// prepare checks, before the command changes anything, that workspace is
+// in a git repository and that none of paths (plus generatedPaths) has
+// uncommitted changes, so the commit holds only what orchestra wrote. It
+// returns nil when --git-commit was not given.
+func (f gitCommitFlags) prepare(workspace string, paths ...string) *gitCommit {
+	if !*f.commit {
+		return nil
+	}
+	return newGitCommit(workspace, *f.message, paths...)
+}
+
+// newGitCommit is prepare for callers that always commit.
+func newGitCommit(workspace, message string, paths ...string) *gitCommit {
+	if _, err := exec.LookPath("git"); err != nil {
+		fatal("--git-commit: git not found on PATH")
+	}
+	if err := exec.Command("git", "-C", workspace, "rev-parse", "--show-toplevel").Run(); err != nil {
+		fatal("--git-commit: %s is not in a git repository", workspace)
+	}
+	c := &gitCommit{workspace: workspace, message: message}
+	for _, p := range append(paths, generatedPaths...) {
+		if rel, err := filepath.Rel(workspace, filepath.Join(workspace, p)); err == nil && !strings.HasPrefix(rel, "..") {
+			c.paths = append(c.paths, filepath.ToSlash(rel))
+		}
+	}
+	if dirty := c.git(append([]string{"status", "--porcelain", "--"}, c.paths...)...); dirty != "" {
+		fatal("--git-commit: these files have uncommitted changes; commit or stash them first:\n  %s", strings.ReplaceAll(dirty, "\n", "\n  "))
+	}
+	return c
+}
+
+// commit stages the prepared paths and commits them with the message
+// template filled in, and reports whether it made a commit. Nothing
+// happens when c is nil or nothing changed.
+func (c *gitCommit) commit(action, target string) bool {
+	if c == nil {
+		return false
+	}
+	var add []string
+	for _, p := range c.paths {
+		if c.addable(p) {
+			add = append(add, p)
+		}
+	}
+	if len(add) == 0 {
+		printStatus(tagSkip, "git commit: nothing changed")
+		return false
+	}
+	if _, err := c.run(append([]string{"add", "-A", "--"}, add...)...); err != nil {
+		printStatus(tagFail, "git add: %v", err)
+		return false
+	}
+	staged := c.git(append([]string{"diff", "--cached", "--name-only", "--relative", "--"}, add...)...)
+	if staged == "" {
+		printStatus(tagSkip, "git commit: nothing changed")
+		return false
+	}
+	files := strings.Split(staged, "\n")
+
+	msg := strings.NewReplacer("{action}", action, "{target}", target).Replace(c.message)
+	if out, err := c.run(append([]string{"commit", "--quiet", "-m", msg, "--"}, files...)...); err != nil {
+		printStatus(tagFail, "git commit: %v\n%s", err, out)
+		return false
+	}
+	printStatus(tagOK, "committed %d file(s): %s", len(files), strings.TrimSpace(c.git("log", "-1", "--format=%h %s")))
+	return true
+}
+
+// addable reports whether p can be passed to git add: it exists and is
+// not ignored, or git tracks it (so its deletion is staged).
+func (c *gitCommit) addable(p string) bool {
+	if _, err := os.Lstat(filepath.Join(c.workspace, p)); err == nil {
+		_, err := c.run("check-ignore", "--quiet", "--", p)
+		return err != nil // exit 1: not ignored
+	}
+	_, err := c.run("ls-files", "--error-unmatch", "--", p)
+	return err == nil
+}
+
+func (c *gitCommit) run(args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", c.workspace}, args...)...)
+	out, err := cmd.CombinedOutput()
+	return strings.TrimRight(string(out), "\n"), err
+}
+
+// git runs a read-only git command and returns its output, or "" on error.
+func (c *gitCommit) git(args ...string) string {
+	out, err := c.run(args...)
+	if err != nil {
+		return ""
+	}
+	return out
+}
+
+// packRef formats a pack for a commit message: its short name and, when
+// known, version (pack-go-backend@v0.2.0).
+func packRef(name, version string) string {
+	ref := path.Base(name)
+	if version != "" {
+		if !strings.HasPrefix(version, "v") {
+			version = "v" + version
+		}
+		ref += "@" + version
+	}
+	return ref
+}
@@ -0,0 +1,307 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Repos are named as `orchestra install` and `orchestra pack install` take
+// them, and recorded in the registries the same way:
+//
+//	github.com/owner/repo             cloned over HTTPS; GitHub releases
+//	gitlab.com/group/sub/repo         cloned over HTTPS; GitLab releases
+//	bitbucket.org/owner/repo          cloned over HTTPS; built from source
+//	git.example.com/org/repo          cloned over HTTPS; built from source
+//	https://git.example.com/org/repo  cloned from the URL as given
+//	git@git.example.com:org/repo.git  cloned over SSH, as is ssh://...
+//
+// Any of them can name a directory within the repo after "//", as in
+// github.com/org/monorepo//plugins/my-plugin, for a plugin that lives in a
+// monorepo: it is built in that directory and named after it.
+//
+// Release downloads work for GitHub and for GitLab: gitlab.com and the
+// self-hosted instances listed under gitlab_hosts: in the global config.
+// Everywhere else a plugin is built from source.
+
+// repoRef is a parsed repo name.
+type repoRef struct {
+	Host   string // host name, without a port
+	Path   string // path on the host, without a leading / or a trailing .git
+	Subdir string // directory within the repo, slash-separated; "" for its root
+	clone  string // URL git clones
+}
+
+// parseRepoRef parses repo in any of the forms above.
+func parseRepoRef(repo string) (repoRef, error) {
+	repo, subdir := splitRepoSubdir(repo)
+	r, err := parseRepoBase(repo)
+	if err != nil || subdir == "" {
+		return r, err
+	}
+	if r.Subdir = path.Clean(subdir); r.Subdir == "." || r.Subdir == ".." || strings.HasPrefix(r.Subdir, "../") {
+		return repoRef{}, fmt.Errorf("invalid repo %q: the directory after // must be within the repo", redactURL(repo+"//"+subdir))
+	}
+	return r, nil
+}
+
+// splitRepoSubdir splits "host/org/repo//sub/dir" into the repo and the
+// directory within it. The // of a URL's scheme does not count.
+func splitRepoSubdir(repo string) (base, subdir string) {
+	start := 0
+	if i := strings.Index(repo, "://"); i >= 0 {
+		start = i + len("://")
+	}
+	i := strings.Index(repo[start:], "//")
+	if i < 0 {
+		return repo, ""
+	}
+	return repo[:start+i], strings.Trim(repo[start+i+2:], "/")
+}
+
+func parseRepoBase(repo string) (repoRef, error) {
+	var r repoRef
+	switch {
+	case strings.Contains(repo, "://"):
+		u, err := url.Parse(repo)
+		if err != nil {
+			return r, fmt.Errorf("invalid repo URL %s: %w", redactURL(repo), err)
+		}
+		switch u.Scheme {
+		case "https", "http", "ssh", "git":
+		default:
+			return r, fmt.Errorf("invalid repo URL %s: unsupported scheme %q", redactURL(repo), u.Scheme)
+		}
+		r = repoRef{Host: strings.ToLower(u.Hostname()), Path: u.Path, clone: repo}
+	case isSCPLike(repo):
+		// git@host:org/repo.git
+		userHost, p, _ := strings.Cut(repo, ":")
+		_, host, found := strings.Cut(userHost, "@")
+		if !found {
+			host = userHost
+		}
+		r = repoRef{Host: strings.ToLower(host), Path: p, clone: repo}
+	default:
+		host, p, _ := strings.Cut(repo, "/")
+		r = repoRef{Host: strings.ToLower(host), Path: p}
+	}
+	r.Path = strings.TrimSuffix(strings.Trim(r.Path, "/"), ".git")
+	if r.Host == "" || r.Path == "" || !strings.Contains(r.Host, ".") && r.Host != "localhost" {
+		return repoRef{}, fmt.Errorf("invalid repo %q: want host/owner/repo, an https:// URL, or git@host:owner/repo.git", redactURL(repo))
+	}
+	if r.clone == "" {
+		r.clone = "https://" + r.Host + "/" + r.Path + ".git"
+	}
+	return r, nil
+}
+
+// isSCPLike reports whether repo is git's scp-like SSH syntax,
+// [user@]host:path, which has a colon before any slash.
+func isSCPLike(repo string) bool {
+	colon := strings.Index(repo, ":")
+	slash := strings.Index(repo, "/")
+	return colon > 0 && (slash < 0 || colon < slash)
+}
+
+// String is the repo as host/path, for messages and allowed_sources; it
+// leaves out Subdir.
+func (r repoRef) String() string {
+	return r.Host + "/" + r.Path
+}
+
+// name is the last element of Subdir, or else of the repo's path, which
+// names a plugin's binary.
+func (r repoRef) name() string {
+	if r.Subdir != "" {
+		return path.Base(r.Subdir)
+	}
+	return path.Base(r.Path)
+}
+
+func (r repoRef) isGitHub() bool {
+	return r.Host == "github.com"
+}
+
+// isGitLab reports whether r is on gitlab.com or a configured self-hosted
+// GitLab.
+func (r repoRef) isGitLab() bool {
+	return isGitLabHost(r.Host)
+}
+
+// repoCloneURL returns the URL git clones repo from. A repo that does not
+// parse is cloned as https://<repo>.git and left for git to report.
+func repoCloneURL(repo string) string {
+	r, err := parseRepoRef(repo)
+	if err != nil {
+		return "https://" + repo + ".git"
+	}
+	return r.clone
+}
+
+// repoName returns the last path element of repo, without .git.
+func repoName(repo string) string {
+	if r, err := parseRepoRef(repo); err == nil {
+		return r.name()
+	}
+	return strings.TrimSuffix(path.Base(repo), ".git")
+}
+
+// --- release assets ---
+
+// errNoReleaseAsset is returned for a release that does not publish the
+// asset asked for.
+var errNoReleaseAsset = errors.New("not published")
+
+// releaseAssetLocation returns the download URL of asset in repo's release
+// version (the latest when empty). On GitHub it is derived from the names,
+// unless a token is set: private repos' assets are only served through the
+// API, so then it is looked up there, as it always is on GitLab. A lookup
+// returns errNoReleaseAsset when the release does not have the asset.
+func releaseAssetLocation(r repoRef, version, asset string) (string, error) {
+	switch {
+	case r.isGitHub() && (githubToken() == "" || downloadBase() != githubDownloadBase):
+		return releaseDownloadURL(r.Path, version, asset), nil
+	case r.isGitHub(), r.isGitLab():
+		rel, err := repoRelease(r, version)
+		if err != nil {
+			return "", err
+		}
+		if url, ok := rel.Assets[asset]; ok {
+			return url, nil
+		}
+		return "", errNoReleaseAsset
+	}
+	return "", fmt.Errorf("release downloads are supported on GitHub and GitLab, not %s", r.Host)
+}
+
+// latestRepoReleaseTag returns the tag of repo's latest GitHub or GitLab
+// release, or "" when there is none or it cannot be looked up.
+func latestRepoReleaseTag(repo string) string {
+	r, err := parseRepoRef(repo)
+	switch {
+	case err != nil:
+		return ""
+	case r.isGitHub():
+		return latestReleaseTag(r.Path)
+	case r.isGitLab():
+		if rel, err := repoRelease(r, ""); err == nil {
+			return rel.Tag
+		}
+	}
+	return ""
+}
+
+// releaseInfo is a release as install uses it: its tag, and its assets'
+// download URLs by name.
+type releaseInfo struct {
+	Tag    string
+	Assets map[string]string
+}
+
+var releaseInfos = struct {
+	sync.Mutex
+	m map[string]*releaseInfo
+}{m: map[string]*releaseInfo{}}
+
+// repoRelease reads release version of a GitHub repo or GitLab project, or
+// its latest release when version is empty, from the host's API. Results
+// are cached for the process.
+func repoRelease(r repoRef, version string) (*releaseInfo, error) {
+	key := r.String() + "@" + version
+	releaseInfos.Lock()
+	defer releaseInfos.Unlock()
+	if rel, ok := releaseInfos.m[key]; ok {
+		return rel, nil
+	}
+
+	var api string
+	if r.isGitHub() {
+		api = githubAPIURL("/repos/" + r.Path + "/releases/latest")
+		if version != "" {
+			api = githubAPIURL("/repos/" + r.Path + "/releases/tags/" + url.PathEscape(version))
+		}
+	} else {
+		api = fmt.Sprintf("https://%s/api/v4/projects/%s/releases/permalink/latest", r.Host, url.PathEscape(r.Path))
+		if version != "" {
+			api = fmt.Sprintf("https://%s/api/v4/projects/%s/releases/%s", r.Host, url.PathEscape(r.Path), url.PathEscape(version))
+		}
+	}
+	resp, err := githubGetCached(api, 15*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		if version == "" {
+			return nil, fmt.Errorf("%s has no releases, or is private and no token is set for %s", r, r.Host)
+		}
+		return nil, fmt.Errorf("%s has no release %s, or is private and no token is set for %s", r, version, r.Host)
+	default:
+		return nil, fmt.Errorf("HTTP %d from %s", resp.StatusCode, redactURL(api))
+	}
+
+	// GitHub lists assets, whose API url serves the file to a token;
+	// GitLab lists links.
+	var body struct {
+		TagName string          `json:"tag_name"`
+		Assets  json.RawMessage `json:"assets"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("parse release: %w", err)
+	}
+	rel := &releaseInfo{Tag: body.TagName, Assets: map[string]string{}}
+	if r.isGitHub() {
+		var assets []struct {
+			Name string `json:"name"`
+			URL  string `json:"url"`
+		}
+		if err := json.Unmarshal(body.Assets, &assets); err != nil {
+			return nil, fmt.Errorf("parse release: %w", err)
+		}
+		for _, a := range assets {
+			rel.Assets[a.Name] = a.URL
+		}
+	} else {
+		var assets struct {
+			Links []struct {
+				Name           string `json:"name"`
+				URL            string `json:"url"`
+				DirectAssetURL string `json:"direct_asset_url"`
+			} `json:"links"`
+		}
+		if err := json.Unmarshal(body.Assets, &assets); err != nil {
+			return nil, fmt.Errorf("parse release: %w", err)
+		}
+		for _, l := range assets.Links {
+			rel.Assets[l.Name] = l.URL
+			if l.DirectAssetURL != "" {
+				rel.Assets[l.Name] = l.DirectAssetURL
+			}
+		}
+	}
+	releaseInfos.m[key] = rel
+	return rel, nil
+}
+
+// isGitLabHost reports whether host is gitlab.com or listed under
+// gitlab_hosts: in the global config. Only these are asked for releases
+// and sent the GitLab token.
+func isGitLabHost(host string) bool {
+	if host == "gitlab.com" {
+		return true
+	}
+	for _, h := range loadGlobalConfig().GitLabHosts {
+		if strings.EqualFold(h, host) {
+			return true
+		}
+	}
+	return false
+}
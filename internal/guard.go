@@ -0,0 +1,116 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// maxWorkspaceEntries is the number of files and directories beyond which a
+// workspace is considered suspiciously large (e.g. a home or source root).
+const maxWorkspaceEntries = 20000
+
+var errTooManyEntries = errors.New("too many entries")
+
+// workspaceHazard returns a description of why workspace looks like the
+// wrong directory to initialize or serve, or "" if it looks fine: the
+// filesystem root, the home directory, or a tree too large to be one
+// project (see workspaceSizeHazard).
+func workspaceHazard(workspace string) string {
+	if hazard := workspaceLocationHazard(workspace); hazard != "" {
+		return hazard
+	}
+	return workspaceSizeHazard(workspace)
+}
+
+// workspaceLocationHazard reports a workspace that is the filesystem root
+// or the home directory, or "".
+func workspaceLocationHazard(workspace string) string {
+	resolved := resolvedWorkspace(workspace)
+
+	// Filesystem root ("/" or "C:\").
+	if resolved == filepath.VolumeName(resolved)+string(filepath.Separator) {
+		return "it is the filesystem root"
+	}
+
+	if home, err := os.UserHomeDir(); err == nil && samePath(resolved, home) {
+		return "it is your home directory"
+	}
+	return ""
+}
+
+// workspaceSizeHazard reports a workspace with more than
+// maxWorkspaceEntries files and directories, not counting VCS and
+// dependency directories, or "".
+func workspaceSizeHazard(workspace string) string {
+	if n := countEntries(resolvedWorkspace(workspace), maxWorkspaceEntries); n >= maxWorkspaceEntries {
+		return fmt.Sprintf("it contains more than %d files and directories", maxWorkspaceEntries)
+	}
+	return ""
+}
+
+func resolvedWorkspace(workspace string) string {
+	resolved, err := filepath.EvalSymlinks(workspace)
+	if err != nil {
+		resolved = workspace
+	}
+	return filepath.Clean(resolved)
+}
+
+// uncountedDirs are directories countEntries skips: version control data
+// and installed dependencies, which make an ordinary project large.
+var uncountedDirs = map[string]bool{
+	".git": true, ".hg": true, ".svn": true, ".jj": true,
+	"node_modules": true, "bower_components": true, "vendor": true,
+	".venv": true, "venv": true, "__pycache__": true, ".tox": true,
+	"target": true, ".gradle": true, "Pods": true,
+}
+
+// countEntries walks root and returns the number of entries seen, stopping
+// early once limit is reached. uncountedDirs are not entered.
+func countEntries(root string, limit int) int {
+	n := 0
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() && path != root && uncountedDirs[d.Name()] {
+			return fs.SkipDir
+		}
+		n++
+		if n >= limit {
+			return errTooManyEntries
+		}
+		return nil
+	})
+	return n
+}
+
+// guardWorkspace stops cmd when the workspace is the home directory or the
+// filesystem root unless force is set.
+func guardWorkspace(cmd, workspace string, force bool) {
+	guardHazard(cmd, workspace, workspaceLocationHazard(workspace), force)
+}
+
+// guardWorkspaceSize stops cmd when the workspace is an enormous tree
+// unless force is set. Only init checks this: a workspace that already
+// exists must keep serving as it grows.
+func guardWorkspaceSize(cmd, workspace string, force bool) {
+	guardHazard(cmd, workspace, workspaceSizeHazard(workspace), force)
+}
+
+func guardHazard(cmd, workspace, hazard string, force bool) {
+	if hazard == "" {
+		return
+	}
+	if force {
+		printStatus(tagWarn, "running %s in %s (%s)", cmd, workspace, hazard)
+		return
+	}
+	fatal("refusing to %s in %s: %s.\n  cd into your project directory, or pass --force if this is intended.", cmd, workspace, hazard)
+}
@@ -0,0 +1,333 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// featureNode is a feature with its children (features whose parent is it).
+type featureNode struct {
+	f        *feature
+	children []*featureNode
+}
+
+// rollup counts done and total features in the subtree below n. A leaf
+// counts itself, so a childless feature reports 0/1 or 1/1.
+func (n *featureNode) rollup() (done, total int) {
+	if len(n.children) == 0 {
+		if n.f.Status == "done" {
+			return 1, 1
+		}
+		return 0, 1
+	}
+	for _, c := range n.children {
+		d, t := c.rollup()
+		done += d
+		total += t
+	}
+	return done, total
+}
+
+// buildFeatureTree links features into parent/child trees and returns the
+// roots sorted by ID. Features whose parent is missing, or that sit on a
+// parent cycle, are treated as roots.
+func buildFeatureTree(features []*feature) []*featureNode {
+	nodes := make(map[string]*featureNode, len(features))
+	for _, f := range features {
+		nodes[strings.ToUpper(f.ID)] = &featureNode{f: f}
+	}
+
+	var roots []*featureNode
+	for _, f := range features {
+		n := nodes[strings.ToUpper(f.ID)]
+		parent := nodes[strings.ToUpper(f.Parent)]
+		if f.Parent == "" || parent == nil || parentCycle(nodes, f) {
+			roots = append(roots, n)
+			continue
+		}
+		parent.children = append(parent.children, n)
+	}
+
+	var sortTree func([]*featureNode)
+	sortTree = func(ns []*featureNode) {
+		sort.SliceStable(ns, func(i, j int) bool { return ns[i].f.ID < ns[j].f.ID })
+		for _, n := range ns {
+			sortTree(n.children)
+		}
+	}
+	sortTree(roots)
+	return roots
+}
+
+// parentCycle reports whether following parents from f leads back to f.
+func parentCycle(nodes map[string]*featureNode, f *feature) bool {
+	seen := map[string]bool{strings.ToUpper(f.ID): true}
+	for p := strings.ToUpper(f.Parent); p != ""; {
+		if seen[p] {
+			return true
+		}
+		seen[p] = true
+		n := nodes[p]
+		if n == nil {
+			return false
+		}
+		p = strings.ToUpper(n.f.Parent)
+	}
+	return false
+}
+
+// --- tree ---
+
+func runFeaturesTree(args []string) {
+	fs := newFlagSet("features tree")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	project := fs.String("project", "", "Limit to one project")
+	porcelain := fs.Bool("porcelain", false, "Stable tab-separated output for scripts")
+	parseFlags(fs, args)
+
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	checkWorkspaceSchema(absWorkspace, false)
+
+	roots := buildFeatureTree(loadFeatures(absWorkspace, *project))
+	if len(roots) == 0 {
+		fmt.Fprintf(os.Stderr, "No features found.\n")
+		return
+	}
+
+	// Porcelain: depth, id, parent, status, done, total, title.
+	if *porcelain {
+		walkFeatureTree(roots, 0, func(n *featureNode, depth int) {
+			done, total := n.rollup()
+			fmt.Fprintf(os.Stdout, "%d\t%s\t%s\t%s\t%d\t%d\t%s\n", depth, n.f.ID, n.f.Parent, n.f.Status, done, total, n.f.Title)
+		})
+		return
+	}
+
+	walkFeatureTree(roots, 0, func(n *featureNode, depth int) {
+		line := fmt.Sprintf("%s%s  %s  [%s]", strings.Repeat("  ", depth), n.f.ID, n.f.Title, n.f.Status)
+		if len(n.children) > 0 {
+			done, total := n.rollup()
+			line += fmt.Sprintf("  %d/%d done (%d%%)", done, total, percent(done, total))
+		}
+		fmt.Fprintln(os.Stdout, line)
+	})
+}
+
+// walkFeatureTree visits nodes depth-first in display order.
+func walkFeatureTree(nodes []*featureNode, depth int, fn func(*featureNode, int)) {
+	for _, n := range nodes {
+		fn(n, depth)
+		walkFeatureTree(n.children, depth+1, fn)
+	}
+}
+
+func percent(part, whole int) int {
+	if whole == 0 {
+		return 0
+	}
+	return part * 100 / whole
+}
+
+// --- parent ---
+
+func runFeaturesParent(args []string) {
+	fs := newFlagSet("features parent")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	clearParent := fs.Bool("clear", false, "Remove the feature's parent")
+	parseFlags(fs, args)
+
+	if fs.NArg() < 1 || (fs.NArg() < 2 && !*clearParent) {
+		fatal("usage: orchestra features parent <feature-id> <parent-id> | --clear")
+	}
+
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	checkWorkspaceSchema(absWorkspace, true)
+
+	f, err := findFeatureForUpdate(absWorkspace, fs.Arg(0))
+	if err != nil {
+		fatal("%v", err)
+	}
+
+	if *clearParent {
+		f.Parent = ""
+	} else {
+		parent, err := findFeature(absWorkspace, fs.Arg(1))
+		if err != nil {
+			fatal("%v", err)
+		}
+		f.Parent = parent.ID
+		nodes := map[string]*featureNode{}
+		for _, other := range scanFeatures(absWorkspace, "", nil) {
+			if strings.EqualFold(other.ID, f.ID) {
+				other = f
+			}
+			nodes[strings.ToUpper(other.ID)] = &featureNode{f: other}
+		}
+		if parentCycle(nodes, f) {
+			fatal("%s cannot be a child of %s: that would create a cycle", f.ID, parent.ID)
+		}
+	}
+
+	if err := commitFeature(absWorkspace, f); err != nil {
+		fatal("save %s: %v", f.ID, err)
+	}
+	if f.Parent == "" {
+		printStatus(tagOK, "%s has no parent", f.ID)
+	} else {
+		printStatus(tagOK, "%s is now a child of %s", f.ID, f.Parent)
+	}
+}
+
+// --- graph ---
+
+func runFeaturesGraph(args []string) {
+	fs := newFlagSet("features graph")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	project := fs.String("project", "", "Limit to one project")
+	format := fs.String("format", "mermaid", "Output format: mermaid or dot")
+	parseFlags(fs, args)
+
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	checkWorkspaceSchema(absWorkspace, false)
+
+	features := loadFeatures(absWorkspace, *project)
+	switch *format {
+	case "mermaid":
+		fmt.Fprint(os.Stdout, featureGraphMermaid(features))
+	case "dot":
+		fmt.Fprint(os.Stdout, featureGraphDot(features))
+	default:
+		fatal("unknown --format %q (want mermaid or dot)", *format)
+	}
+}
+
+// graphLabel is a node label; parents show their rolled-up completion.
+func graphLabel(n *featureNode) string {
+	label := fmt.Sprintf("%s: %s [%s]", n.f.ID, n.f.Title, n.f.Status)
+	if len(n.children) > 0 {
+		done, total := n.rollup()
+		label += fmt.Sprintf(" %d/%d", done, total)
+	}
+	return strings.ReplaceAll(label, `"`, "'")
+}
+
+// featureGraphMermaid renders dependencies (solid, blocker --> blocked) and
+// parent links (dotted, parent -.-> child) as a Mermaid flowchart.
+func featureGraphMermaid(features []*feature) string {
+	var b strings.Builder
+	b.WriteString("graph TD\n")
+	roots := buildFeatureTree(features)
+	walkFeatureTree(roots, 0, func(n *featureNode, _ int) {
+		fmt.Fprintf(&b, "  %s[\"%s\"]\n", graphID(n.f.ID), graphLabel(n))
+	})
+	walkFeatureTree(roots, 0, func(n *featureNode, _ int) {
+		for _, c := range n.children {
+			fmt.Fprintf(&b, "  %s -.-> %s\n", graphID(n.f.ID), graphID(c.f.ID))
+		}
+		for _, dep := range n.f.DependsOn {
+			fmt.Fprintf(&b, "  %s --> %s\n", graphID(dep), graphID(n.f.ID))
+		}
+	})
+	return b.String()
+}
+
+// featureGraphDot renders the same graph in Graphviz DOT.
+func featureGraphDot(features []*feature) string {
+	var b strings.Builder
+	b.WriteString("digraph features {\n  rankdir=TB;\n  node [shape=box];\n")
+	roots := buildFeatureTree(features)
+	walkFeatureTree(roots, 0, func(n *featureNode, _ int) {
+		fmt.Fprintf(&b, "  %q [label=%q];\n", n.f.ID, graphLabel(n))
+	})
+	walkFeatureTree(roots, 0, func(n *featureNode, _ int) {
+		for _, c := range n.children {
+			fmt.Fprintf(&b, "  %q -> %q [style=dotted];\n", n.f.ID, c.f.ID)
+		}
+		for _, dep := range n.f.DependsOn {
+			fmt.Fprintf(&b, "  %q -> %q;\n", dep, n.f.ID)
+		}
+	})
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// graphID makes a feature ID safe as a Mermaid node ID.
+func graphID(id string) string {
+	return strings.NewReplacer("-", "_", " ", "_").Replace(id)
+}
+
+// --- progress doc ---
+
+// progressDocPath is the generated rollup of epics and features.
+func progressDocPath(workspace string) string {
+	return filepath.Join(workspace, ".projects", "PROGRESS.md")
+}
+
+// writeProgressDoc regenerates .projects/PROGRESS.md from the feature store.
+// Workspaces without features get no file, and neither do encrypted ones,
+// since it would list their titles in the clear.
+func writeProgressDoc(workspace string) error {
+	if workspaceEncrypted(workspace) {
+		return nil
+	}
+	features := scanFeatures(workspace, "", nil)
+	if len(features) == 0 {
+		return nil
+	}
+	return writeFileAtomic(progressDocPath(workspace), []byte(normalizeNewlines(buildProgressDoc(features))), 0644)
+}
+
+// buildProgressDoc renders per-project completion and the epic tree with
+// child completion rolled up into each parent.
+func buildProgressDoc(features []*feature) string {
+	var b strings.Builder
+	b.WriteString("# Progress\n\n")
+	b.WriteString("Generated by `orchestra` from `.projects/`. Do not edit.\n\n")
+
+	byProject := map[string][]*feature{}
+	var projects []string
+	for _, f := range features {
+		if _, ok := byProject[f.Project]; !ok {
+			projects = append(projects, f.Project)
+		}
+		byProject[f.Project] = append(byProject[f.Project], f)
+	}
+	sortNames(projects)
+
+	for _, p := range projects {
+		fs := byProject[p]
+		done := 0
+		for _, f := range fs {
+			if f.Status == "done" {
+				done++
+			}
+		}
+		fmt.Fprintf(&b, "## %s — %d/%d done (%d%%)\n\n", p, done, len(fs), percent(done, len(fs)))
+		walkFeatureTree(buildFeatureTree(fs), 0, func(n *featureNode, depth int) {
+			check := " "
+			if n.f.Status == "done" {
+				check = "x"
+			}
+			fmt.Fprintf(&b, "%s- [%s] %s %s (%s)", strings.Repeat("  ", depth), check, n.f.ID, n.f.Title, n.f.Status)
+			if len(n.children) > 0 {
+				d, t := n.rollup()
+				fmt.Fprintf(&b, " — %d/%d done (%d%%)", d, t, percent(d, t))
+			}
+			b.WriteString("\n")
+		})
+		b.WriteString("\n")
+	}
+	return b.String()
+}
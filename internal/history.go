@@ -0,0 +1,182 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The tools plugin keeps only a feature's current status. To answer "when
+// did this move", the CLI diffs the feature store against the last snapshot
+// and appends every change to an append-only log:
+//
+//	.projects/.history/snapshot.json      project/feature ID -> last seen status
+//	.projects/.history/transitions.jsonl  one transition per line
+//
+// serve records continuously while the MCP session runs; commands that read
+// history record first so they never report stale data.
+
+// transition is one status change. From is empty the first time a feature
+// is seen.
+type transition struct {
+	Time    time.Time `json:"time"`
+	Project string    `json:"project"`
+	Feature string    `json:"feature"`
+	From    string    `json:"from,omitempty"`
+	To      string    `json:"to"`
+}
+
+// historySnapshot is the last recorded status of every feature.
+type historySnapshot struct {
+	Features map[string]string `json:"features"`
+}
+
+func historyDir(workspace string) string {
+	return filepath.Join(workspace, ".projects", ".history")
+}
+
+// recordTransitions appends a transition for every feature whose status
+// changed since the last snapshot and returns them.
+func recordTransitions(workspace string) ([]transition, error) {
+	if _, err := os.Stat(filepath.Join(workspace, ".projects")); err != nil {
+		return nil, nil
+	}
+	snapPath := filepath.Join(historyDir(workspace), "snapshot.json")
+	snap := historySnapshot{Features: map[string]string{}}
+	if data, err := os.ReadFile(snapPath); err == nil {
+		json.Unmarshal(data, &snap)
+		if snap.Features == nil {
+			snap.Features = map[string]string{}
+		}
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	var changes []transition
+	migrated := false
+	features := scanFeatureSummaries(workspace)
+	projects := map[string]int{} // feature ID -> projects using it
+	for _, f := range features {
+		projects[f.ID]++
+	}
+	for _, f := range features {
+		key := featureKey(f.Project, f.ID)
+		prev, seen := snap.Features[key]
+		if !seen {
+			// Snapshots from before features were keyed by project. An ID
+			// more than one project uses could have been either feature's,
+			// so its current status is taken as the last seen one.
+			if prev, seen = snap.Features[f.ID]; seen {
+				if projects[f.ID] > 1 {
+					prev = f.Status
+				}
+				snap.Features[key] = prev
+				migrated = true
+			}
+		}
+		if seen && prev == f.Status {
+			continue
+		}
+		changes = append(changes, transition{
+			Time:    transitionTime(f, now),
+			Project: f.Project,
+			Feature: f.ID,
+			From:    prev,
+			To:      f.Status,
+		})
+		snap.Features[key] = f.Status
+	}
+	if migrated {
+		for k := range snap.Features {
+			if !strings.Contains(k, "/") {
+				delete(snap.Features, k)
+			}
+		}
+	}
+	if len(changes) == 0 && !migrated {
+		return nil, nil
+	}
+
+	if err := os.MkdirAll(historyDir(workspace), 0755); err != nil {
+		return nil, err
+	}
+	if len(changes) > 0 {
+		if err := appendTransitions(workspace, changes); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return changes, writeFileAtomic(snapPath, append(data, '\n'), 0644)
+}
+
+// appendTransitions adds changes to the end of the transition log.
+func appendTransitions(workspace string, changes []transition) error {
+	lf, err := os.OpenFile(filepath.Join(historyDir(workspace), "transitions.jsonl"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(lf)
+	for _, t := range changes {
+		line, _ := json.Marshal(t)
+		w.Write(append(line, '\n'))
+	}
+	if err := w.Flush(); err != nil {
+		lf.Close()
+		return err
+	}
+	return lf.Close()
+}
+
+// transitionTime prefers the feature's own updated_at, which the storage
+// plugin sets on every write, over the time the change was noticed.
+func transitionTime(f *feature, now time.Time) time.Time {
+	if t, err := time.Parse(time.RFC3339, f.UpdatedAt); err == nil && !t.After(now) {
+		return t.UTC()
+	}
+	return now
+}
+
+// loadTransitions reads the transition log in time order.
+func loadTransitions(workspace string) []transition {
+	lf, err := os.Open(filepath.Join(historyDir(workspace), "transitions.jsonl"))
+	if err != nil {
+		return nil
+	}
+	defer lf.Close()
+
+	var out []transition
+	sc := bufio.NewScanner(lf)
+	for sc.Scan() {
+		var t transition
+		if json.Unmarshal(sc.Bytes(), &t) == nil && t.Feature != "" {
+			out = append(out, t)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Time.Before(out[j].Time) })
+	return out
+}
+
+// watchTransitions records transitions every interval until stop closes.
+func watchTransitions(workspace string, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			recordTransitions(workspace)
+			return
+		case <-ticker.C:
+			if changes, _ := recordTransitions(workspace); len(changes) > 0 {
+				writeProgressDoc(workspace)
+				writeDigest(workspace)
+			}
+		}
+	}
+}
@@ -0,0 +1,441 @@
+package internal
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Lifecycle hooks. hooks: in ~/.orchestra/config.yaml and .orchestra.yaml
+// names shell commands the CLI runs at fixed points, with ORCHESTRA_*
+// variables describing the event. Global hooks run first. A failing pre_
+// hook stops the command; a failing post_ hook only warns.
+//
+// A .orchestra.yaml arrives with a clone, so its hooks run only once the
+// user has trusted them; trust is recorded per workspace against a hash of
+// the hooks, so editing them asks again.
+
+// Hook events, in the order the docs list them.
+const (
+	hookPreInstall      = "pre_install"
+	hookPostInstall     = "post_install"
+	hookPrePackInstall  = "pre_pack_install"
+	hookPostPackInstall = "post_pack_install"
+	hookPrePackRemove   = "pre_pack_remove"
+	hookPostPackRemove  = "post_pack_remove"
+	hookPostInit        = "post_init"
+	hookPreServe        = "pre_serve"
+	hookPostServe       = "post_serve"
+)
+
+var hookEvents = []string{
+	hookPreInstall, hookPostInstall,
+	hookPrePackInstall, hookPostPackInstall,
+	hookPrePackRemove, hookPostPackRemove,
+	hookPostInit,
+	hookPreServe, hookPostServe,
+}
+
+// hookTimeout bounds each hook command.
+const hookTimeout = 2 * time.Minute
+
+// hookEnv is set for hook commands; orchestra run by a hook runs no hooks,
+// so a hook cannot trigger itself.
+const hookEnv = "ORCHESTRA_HOOK"
+
+// hookCommands are the commands for one event: a string or a list.
+type hookCommands []string
+
+func (c *hookCommands) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		*c = hookCommands{n.Value}
+		return nil
+	}
+	var list []string
+	if err := n.Decode(&list); err != nil {
+		return err
+	}
+	*c = list
+	return nil
+}
+
+// hookSet is hooks: in a config file, commands by event.
+type hookSet map[string]hookCommands
+
+// jsonSchema lists the events for `orchestra schema export`.
+func (hookSet) jsonSchema() map[string]any {
+	command := map[string]any{
+		"oneOf": []any{
+			map[string]any{"type": "string"},
+			map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		},
+	}
+	props := map[string]any{}
+	for _, e := range hookEvents {
+		props[e] = command
+	}
+	return map[string]any{"type": "object", "properties": props, "additionalProperties": false}
+}
+
+// hash identifies the hooks for trust.
+func (h hookSet) hash() string {
+	data, _ := json.Marshal(h) // map keys are sorted
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// --- trust ---
+
+// hookTrust is ~/.orchestra/trusted-hooks.json: the hash of the trusted
+// hooks by workspace.
+type hookTrust struct {
+	Workspaces map[string]string `json:"workspaces"`
+}
+
+func hookTrustPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".orchestra", "trusted-hooks.json")
+}
+
+func loadHookTrust() *hookTrust {
+	t := &hookTrust{}
+	if data, err := os.ReadFile(hookTrustPath()); err == nil {
+		json.Unmarshal(data, t)
+	}
+	if t.Workspaces == nil {
+		t.Workspaces = map[string]string{}
+	}
+	return t
+}
+
+func (t *hookTrust) save() error {
+	if err := os.MkdirAll(filepath.Dir(hookTrustPath()), 0755); err != nil {
+		return err
+	}
+	data, _ := json.MarshalIndent(t, "", "  ")
+	return writeFileAtomic(hookTrustPath(), append(data, '\n'), 0600)
+}
+
+// workspaceHooksTrusted reports whether the workspace's hooks, as they are
+// now, have been trusted.
+func workspaceHooksTrusted(workspace string, hooks hookSet) bool {
+	return loadHookTrust().Workspaces[workspace] == hooks.hash()
+}
+
+func trustWorkspaceHooks(workspace string, hooks hookSet) error {
+	t := loadHookTrust()
+	t.Workspaces[workspace] = hooks.hash()
+	return t.save()
+}
+
+// printHookSet shows hooks for review, in event order.
+func printHookSet(w io.Writer, hooks hookSet) {
+	for _, e := range hookEvents {
+		for _, c := range hooks[e] {
+			fmt.Fprintf(w, "    %-18s %s\n", e, c)
+		}
+	}
+}
+
+// --- running ---
+
+// hookRun is one lifecycle event.
+type hookRun struct {
+	event     string
+	workspace string
+	vars      []string // extra KEY=VALUE for the environment
+
+	// log receives the hooks' output and status; serve passes its log.
+	// Without one, output goes to stderr and untrusted workspace hooks
+	// are offered for trust on a terminal.
+	log io.Writer
+}
+
+// run runs the event's global, then workspace, hooks in order. It stops at
+// and returns the first failure.
+func (h hookRun) run() error {
+	if globals.noHooks || os.Getenv(hookEnv) != "" {
+		return nil
+	}
+	global := loadGlobalConfig().Hooks[h.event]
+	wsHooks := loadWorkspaceConfig(h.workspace).Hooks
+	local := wsHooks[h.event]
+	if len(global) == 0 && len(local) == 0 {
+		return nil
+	}
+	if len(local) > 0 && !workspaceHooksTrusted(h.workspace, wsHooks) {
+		if !h.offerTrust(wsHooks) {
+			local = nil
+		}
+	}
+
+	for _, c := range global {
+		if err := h.runCommand(c); err != nil {
+			return err
+		}
+	}
+	for _, c := range local {
+		if err := h.runCommand(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// offerTrust asks to trust the workspace's hooks when it can, and reports
+// whether they may run.
+func (h hookRun) offerTrust(hooks hookSet) bool {
+	if h.log != nil || !isTerminal(os.Stdin) {
+		h.status(tagSkip, "%s hooks in %s: not trusted; review them with 'orchestra hooks list' and run 'orchestra hooks trust'", h.event, workspaceConfigFile)
+		return false
+	}
+	printStatus(tagWarn, "%s defines hooks that run shell commands:", filepath.Join(h.workspace, workspaceConfigFile))
+	printHookSet(os.Stderr, hooks)
+	if !confirm("Trust these hooks for this workspace?") {
+		printStatus(tagSkip, "%s hooks (not trusted)", h.event)
+		return false
+	}
+	if err := trustWorkspaceHooks(h.workspace, hooks); err != nil {
+		printStatus(tagWarn, "could not record trust: %v", err)
+	}
+	return true
+}
+
+func (h hookRun) status(tag, format string, args ...any) {
+	if h.log != nil {
+		fmt.Fprintf(h.log, "orchestra: "+format+"\n", args...)
+		return
+	}
+	printStatus(tag, format, args...)
+}
+
+// runCommand runs one hook command in the workspace through the shell.
+func (h hookRun) runCommand(command string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Dir = h.workspace
+	cmd.Env = append(os.Environ(),
+		hookEnv+"="+h.event,
+		"ORCHESTRA_WORKSPACE="+h.workspace,
+		"ORCHESTRA_VERSION="+Version,
+		"ORCHESTRA_OS="+runtime.GOOS,
+	)
+	cmd.Env = append(cmd.Env, h.vars...)
+	out := h.log
+	if out == nil {
+		out = prefixWriter(os.Stderr, "  | ")
+	}
+	cmd.Stdout, cmd.Stderr = out, out
+
+	start := time.Now()
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out after %s", hookTimeout)
+	}
+	if err != nil {
+		return fmt.Errorf("%s hook %q failed: %v", h.event, command, err)
+	}
+	h.status(tagOK, "%s hook %q (%s)", h.event, command, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// runPreHook runs a pre_ event, ending the command if a hook fails.
+func runPreHook(h hookRun) {
+	if err := h.run(); err != nil {
+		fatal("%v; not continuing", err)
+	}
+}
+
+// runPostHook runs a post_ event, warning if a hook fails.
+func runPostHook(h hookRun) {
+	if err := h.run(); err != nil {
+		h.status(tagWarn, "%v", err)
+	}
+}
+
+// --- command ---
+
+// runHooksList handles `orchestra hooks list`.
+func runHooksList(args []string) {
+	fs := newFlagSet("hooks list")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	porcelain := fs.Bool("porcelain", false, "Stable tab-separated output for scripts")
+	parseFlags(fs, args)
+
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	wsHooks := loadWorkspaceConfig(absWorkspace).Hooks
+	trusted := len(wsHooks) == 0 || workspaceHooksTrusted(absWorkspace, wsHooks)
+	wsState := "yes"
+	if !trusted {
+		wsState = "no"
+	}
+	scopes := []struct {
+		name  string
+		hooks hookSet
+		state string
+	}{
+		{"global", loadGlobalConfig().Hooks, "yes"},
+		{"workspace", wsHooks, wsState},
+	}
+
+	known := map[string]bool{}
+	for _, e := range hookEvents {
+		known[e] = true
+	}
+	type hookJSON struct {
+		Event   string `json:"event"`
+		Scope   string `json:"scope"` // global or workspace
+		Trusted bool   `json:"trusted"`
+		Command string `json:"command"`
+	}
+	list := []hookJSON{}
+	for _, s := range scopes {
+		var events []string
+		for e := range s.hooks {
+			events = append(events, e)
+		}
+		sortNames(events)
+		for _, e := range events {
+			if !known[e] {
+				printStatus(tagWarn, "%s hooks: unknown event %q (known: %s)", s.name, e, strings.Join(hookEvents, ", "))
+				continue
+			}
+			for _, c := range s.hooks[e] {
+				list = append(list, hookJSON{e, s.name, s.state == "yes", c})
+			}
+		}
+	}
+	if jsonOutput() {
+		printJSON(list)
+		return
+	}
+
+	// Porcelain: event, scope, trusted (yes/no), command.
+	yesNo := map[bool]string{true: "yes", false: "no"}
+	if *porcelain {
+		for _, h := range list {
+			fmt.Fprintf(os.Stdout, "%s\t%s\t%s\t%s\n", h.Event, h.Scope, yesNo[h.Trusted], h.Command)
+		}
+		return
+	}
+	tw := newTable(os.Stdout)
+	fmt.Fprintf(tw, "EVENT\tSCOPE\tTRUSTED\tCOMMAND\n")
+	for _, h := range list {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", h.Event, h.Scope, yesNo[h.Trusted], h.Command)
+	}
+	if len(list) == 0 {
+		fmt.Fprintf(os.Stderr, "No hooks. Add them under hooks: in %s or %s.\n", globalConfigPath(), workspaceConfigFile)
+		return
+	}
+	tw.Flush()
+	if !trusted {
+		fmt.Fprintf(os.Stderr, "\nWorkspace hooks do not run until trusted: orchestra hooks trust\n")
+	}
+}
+
+// runHooksTrust handles `orchestra hooks trust` -- lets the workspace's
+// hooks run, or with --revoke stops them.
+func runHooksTrust(args []string) {
+	fs := newFlagSet("hooks trust")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	yes := fs.Bool("yes", false, "Trust without asking")
+	revoke := fs.Bool("revoke", false, "Stop trusting the workspace's hooks")
+	parseFlags(fs, args)
+
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	if *revoke {
+		t := loadHookTrust()
+		if _, ok := t.Workspaces[absWorkspace]; !ok {
+			printStatus(tagSkip, "hooks in %s were not trusted", absWorkspace)
+			return
+		}
+		delete(t.Workspaces, absWorkspace)
+		if err := t.save(); err != nil {
+			fatal("save %s: %v", hookTrustPath(), err)
+		}
+		printStatus(tagOK, "no longer trusting hooks in %s", absWorkspace)
+		return
+	}
+
+	hooks := loadWorkspaceConfig(absWorkspace).Hooks
+	if len(hooks) == 0 {
+		printStatus(tagSkip, "%s defines no hooks", filepath.Join(absWorkspace, workspaceConfigFile))
+		return
+	}
+	if workspaceHooksTrusted(absWorkspace, hooks) {
+		printStatus(tagSkip, "hooks in %s are already trusted", absWorkspace)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%s defines:\n", filepath.Join(absWorkspace, workspaceConfigFile))
+	printHookSet(os.Stderr, hooks)
+	if !*yes {
+		if !isTerminal(os.Stdin) {
+			fatal("no terminal to confirm; rerun with --yes")
+		}
+		if !confirm("Trust these hooks for this workspace?") {
+			fatal("not trusted")
+		}
+	}
+	if err := trustWorkspaceHooks(absWorkspace, hooks); err != nil {
+		fatal("save %s: %v", hookTrustPath(), err)
+	}
+	printStatus(tagOK, "trusted hooks in %s; editing them asks again", absWorkspace)
+}
+
+// runHooksRun handles `orchestra hooks run` -- runs an event's hooks by
+// hand, to try them out.
+func runHooksRun(args []string) {
+	fs := newFlagSet("hooks run")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	parseFlags(fs, args)
+
+	if fs.NArg() != 1 {
+		fatal("usage: orchestra hooks run <event>  (events: %s)", strings.Join(hookEvents, ", "))
+	}
+	event := fs.Arg(0)
+	known := false
+	for _, e := range hookEvents {
+		known = known || e == event
+	}
+	if !known {
+		fatal("unknown event %q (known: %s)", event, strings.Join(hookEvents, ", "))
+	}
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	if globals.noHooks || os.Getenv(hookEnv) != "" {
+		printStatus(tagSkip, "hooks are disabled (--no-hooks, or run from a hook)")
+		return
+	}
+	if len(loadGlobalConfig().Hooks[event]) == 0 && len(loadWorkspaceConfig(absWorkspace).Hooks[event]) == 0 {
+		printStatus(tagSkip, "no %s hooks", event)
+		return
+	}
+	if err := (hookRun{event: event, workspace: absWorkspace}).run(); err != nil {
+		fatal("%v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// httpCacheMaxBody bounds the responses kept in the HTTP cache; metadata
+// is small, and anything larger is passed through uncached.
+const httpCacheMaxBody = 4 << 20
+
+// orchestraCacheDir returns ~/.orchestra/cache/.
+func orchestraCacheDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".orchestra", "cache")
+}
+
+// httpCacheEntry is a cached 200 response with the validators GitHub sent
+// for it.
+type httpCacheEntry struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	ContentType  string    `json:"content_type,omitempty"`
+	Body         []byte    `json:"body"`
+	Stored       time.Time `json:"stored"`
+}
+
+// httpCachePath returns where the response for url is cached. The key
+// includes the token, so a response only a token could see is never
+// served to another one.
+func httpCachePath(url string) string {
+	sum := sha256.Sum256([]byte(url + "\x00" + requestToken(url)))
+	return filepath.Join(orchestraCacheDir(), "http", hex.EncodeToString(sum[:16])+".json")
+}
+
+// githubGetCached is githubGet for metadata (API calls, raw manifests). It
+// revalidates a cached response with If-None-Match and If-Modified-Since;
+// GitHub answers 304 Not Modified without counting it against the rate
+// limit or resending the body, and the cached body is returned as a 200.
+// Responses without validators, non-200 responses, and bodies over
+// httpCacheMaxBody are not cached.
+func githubGetCached(url string, timeout time.Duration) (*http.Response, error) {
+	req, err := newGitHubRequest(url)
+	if err != nil {
+		return nil, err
+	}
+	path := httpCachePath(url)
+	var cached *httpCacheEntry
+	if data, err := os.ReadFile(path); err == nil {
+		var e httpCacheEntry
+		if json.Unmarshal(data, &e) == nil && e.URL == url {
+			cached = &e
+			if e.ETag != "" {
+				req.Header.Set("If-None-Match", e.ETag)
+			}
+			if e.LastModified != "" {
+				req.Header.Set("If-Modified-Since", e.LastModified)
+			}
+		}
+	}
+
+	step := debugStep("GET %s", url)
+	resp, err := (&http.Client{Timeout: timeout}).Do(req)
+	if err != nil {
+		step.endf("%v", err)
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		step.endf("304 Not Modified, using the cached body")
+		resp.Body.Close()
+		resp.StatusCode, resp.Status = http.StatusOK, "200 OK (cached)"
+		resp.Header.Set("Content-Type", cached.ContentType)
+		resp.Body = io.NopCloser(bytes.NewReader(cached.Body))
+		resp.ContentLength = int64(len(cached.Body))
+		return resp, nil
+	}
+
+	step.endf("%s", resp.Status)
+
+	etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if resp.StatusCode != http.StatusOK || (etag == "" && lastModified == "") {
+		return resp, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, httpCacheMaxBody+1))
+	rest := resp.Body
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), rest), rest}
+	if err != nil || len(body) > httpCacheMaxBody {
+		return resp, nil
+	}
+
+	data, _ := json.Marshal(httpCacheEntry{
+		URL:          url,
+		ETag:         etag,
+		LastModified: lastModified,
+		ContentType:  resp.Header.Get("Content-Type"),
+		Body:         body,
+		Stored:       time.Now().UTC(),
+	})
+	if os.MkdirAll(filepath.Dir(path), 0700) == nil {
+		writeFileAtomic(path, data, 0600)
+	}
+	return resp, nil
+}
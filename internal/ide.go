@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 // IDEConfig defines how to generate MCP config for a specific IDE.
@@ -13,6 +14,10 @@ type IDEConfig struct {
 	Display    string
 	ConfigPath func(workspace string) string
 	Generate   func(workspace, binaryPath string) ([]byte, error)
+	// Remove returns the config data without the orchestra server for
+	// workspace: data itself when there is none, nil when nothing else
+	// is left in the file.
+	Remove func(workspace string, data []byte) ([]byte, error)
 }
 
 // ideRegistry maps IDE names to their config generators.
@@ -73,6 +78,52 @@ func mergeJSONMcpConfig(existingPath string, serverKey string, serverConfig map[
 	return result, nil
 }
 
+// removeJSONMcpServer drops serverKey from the serversKey map of a JSON
+// config, along with the map if it is left empty. In a config shared by
+// workspaces, only the entry serving workspace is dropped.
+func removeJSONMcpServer(data []byte, serversKey, serverKey, workspace string, shared bool) ([]byte, error) {
+	config := make(map[string]any)
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	servers, ok := config[serversKey].(map[string]any)
+	if !ok || servers[serverKey] == nil {
+		return data, nil
+	}
+	if shared && !samePath(serverWorkspace(servers[serverKey]), workspace) {
+		return data, nil
+	}
+	delete(servers, serverKey)
+	if len(servers) == 0 {
+		delete(config, serversKey)
+	}
+	if len(config) == 0 {
+		return nil, nil
+	}
+	result, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(result, '\n'), nil
+}
+
+// serverWorkspace returns the --workspace argument of a server entry, in
+// the mcpServers shape or Zed's.
+func serverWorkspace(entry any) string {
+	m, _ := entry.(map[string]any)
+	if cmd, ok := m["command"].(map[string]any); ok {
+		m = cmd
+	}
+	args, _ := m["args"].([]any)
+	for i := 0; i+1 < len(args); i++ {
+		if args[i] == "--workspace" {
+			ws, _ := args[i+1].(string)
+			return ws
+		}
+	}
+	return ""
+}
+
 // --- Claude Code ---
 
 func claudeConfig() *IDEConfig {
@@ -86,6 +137,9 @@ func claudeConfig() *IDEConfig {
 			path := filepath.Join(ws, ".mcp.json")
 			return mergeJSONMcpConfig(path, "orchestra", orchestraServer(bin, ws))
 		},
+		Remove: func(ws string, data []byte) ([]byte, error) {
+			return removeJSONMcpServer(data, "mcpServers", "orchestra", ws, false)
+		},
 	}
 }
 
@@ -102,6 +156,9 @@ func cursorConfig() *IDEConfig {
 			path := filepath.Join(ws, ".cursor", "mcp.json")
 			return mergeJSONMcpConfig(path, "orchestra", orchestraServer(bin, ws))
 		},
+		Remove: func(ws string, data []byte) ([]byte, error) {
+			return removeJSONMcpServer(data, "mcpServers", "orchestra", ws, false)
+		},
 	}
 }
 
@@ -118,6 +175,9 @@ func vscodeConfig() *IDEConfig {
 			path := filepath.Join(ws, ".vscode", "mcp.json")
 			return mergeJSONMcpConfig(path, "orchestra", orchestraServer(bin, ws))
 		},
+		Remove: func(ws string, data []byte) ([]byte, error) {
+			return removeJSONMcpServer(data, "mcpServers", "orchestra", ws, false)
+		},
 	}
 }
 
@@ -134,6 +194,9 @@ func clineConfig() *IDEConfig {
 			path := filepath.Join(ws, ".vscode", "mcp.json")
 			return mergeJSONMcpConfig(path, "orchestra", orchestraServer(bin, ws))
 		},
+		Remove: func(ws string, data []byte) ([]byte, error) {
+			return removeJSONMcpServer(data, "mcpServers", "orchestra", ws, false)
+		},
 	}
 }
 
@@ -152,6 +215,9 @@ func windsurfConfig() *IDEConfig {
 			path := filepath.Join(home, ".codeium", "windsurf", "mcp_config.json")
 			return mergeJSONMcpConfig(path, "orchestra", orchestraServer(bin, ws))
 		},
+		Remove: func(ws string, data []byte) ([]byte, error) {
+			return removeJSONMcpServer(data, "mcpServers", "orchestra", ws, true)
+		},
 	}
 }
 
@@ -172,6 +238,9 @@ args = ["serve", "--workspace", %q]
 `, bin, ws)
 			return []byte(toml), nil
 		},
+		Remove: func(ws string, data []byte) ([]byte, error) {
+			return removeTOMLTable(data, "mcp_servers.orchestra"), nil
+		},
 	}
 }
 
@@ -188,6 +257,9 @@ func geminiConfig() *IDEConfig {
 			path := filepath.Join(ws, ".gemini", "settings.json")
 			return mergeJSONMcpConfig(path, "orchestra", orchestraServer(bin, ws))
 		},
+		Remove: func(ws string, data []byte) ([]byte, error) {
+			return removeJSONMcpServer(data, "mcpServers", "orchestra", ws, false)
+		},
 	}
 }
 
@@ -229,6 +301,9 @@ func zedConfig() *IDEConfig {
 			result = append(result, '\n')
 			return result, nil
 		},
+		Remove: func(ws string, data []byte) ([]byte, error) {
+			return removeJSONMcpServer(data, "context_servers", "orchestra", ws, false)
+		},
 	}
 }
 
@@ -251,5 +326,33 @@ args:
 `, bin, ws)
 			return []byte(yaml), nil
 		},
+		Remove: func(ws string, data []byte) ([]byte, error) {
+			return nil, nil // the file holds only the orchestra server
+		},
+	}
+}
+
+// removeTOMLTable drops the [table] section, and its subtables, from a TOML
+// file; nil when nothing else is left.
+func removeTOMLTable(data []byte, table string) []byte {
+	var kept []string
+	found, skipping := false, false
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		if t := strings.TrimSpace(line); strings.HasPrefix(t, "[") {
+			name := strings.TrimSpace(strings.Trim(t, "[]"))
+			skipping = name == table || strings.HasPrefix(name, table+".")
+			found = found || skipping
+		}
+		if !skipping {
+			kept = append(kept, line)
+		}
+	}
+	if !found {
+		return data
+	}
+	rest := strings.TrimSpace(strings.Join(kept, ""))
+	if rest == "" {
+		return nil
 	}
+	return []byte(rest + "\n")
 }
@@ -1,7 +1,6 @@
 package internal
 
 import (
-	"flag"
 	"fmt"
 	"os"
 	"os/exec"
@@ -10,18 +9,38 @@ import (
 )
 
 func RunInit(args []string) {
-	fs := flag.NewFlagSet("init", flag.ExitOnError)
+	fs := newFlagSet("init")
 	workspace := fs.String("workspace", ".", "Project directory to initialize")
 	ide := fs.String("ide", "", "Target IDE: claude, cursor, vscode, windsurf, codex, gemini, zed, continue, cline")
 	all := fs.Bool("all", false, "Generate configs for all supported IDEs")
-	fs.Parse(args)
+	force := fs.Bool("force", false, "Allow initializing a home directory, filesystem root, or very large tree")
+	withPacks := fs.Bool("with-packs", false, "Install the default packs (pack-essentials) from the copy embedded in the binary")
+	dryRun := fs.Bool("dry-run", false, "Print a diff of every file init would write, and write nothing")
+	yes := fs.Bool("yes", false, "Modify existing files orchestra did not write without asking")
+	noWizard := fs.Bool("no-wizard", false, "On a terminal, act on the detected IDEs instead of asking which IDEs and packs to set up")
+	gitFlags := addGitCommitFlags(fs)
+	parseFlags(fs, args)
 
 	// Resolve absolute workspace path.
-	absWorkspace, err := filepath.Abs(*workspace)
+	absWorkspace, err := resolveWorkspace(*workspace)
 	if err != nil {
 		fatal("resolve workspace: %v", err)
 	}
 
+	guardWorkspace("init", absWorkspace, *force)
+	guardWorkspaceSize("init", absWorkspace, *force)
+
+	// Don't create a workspace inside another by accident, from a
+	// subdirectory; the one above is the one to (re)initialize.
+	if !flagGiven(fs, "workspace") && !isWorkspaceRoot(absWorkspace) {
+		if parent := findWorkspaceRoot(filepath.Dir(absWorkspace)); parent != "" {
+			fatal("%s is inside the workspace at %s.\n  Run 'orchestra init' there, or pass --workspace=. to create a nested workspace here.", absWorkspace, parent)
+		}
+	}
+
+	// Refuse to rewrite a workspace created by a newer CLI.
+	checkWorkspaceSchema(absWorkspace, true)
+
 	// Resolve the orchestra binary path.
 	binPath, err := resolveBinaryPath()
 	if err != nil {
@@ -49,58 +68,114 @@ func RunInit(args []string) {
 		targets = detectIDEs(absWorkspace)
 	}
 
+	// On a terminal with nothing chosen by flags, ask.
+	var wizardPacks []string
+	wizard := wantInitWizard(fs, *noWizard)
+	if wizard {
+		choices, ok := runInitWizard(absWorkspace, targets)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Cancelled; nothing was written.\n")
+			return
+		}
+		targets, wizardPacks = choices.ides, choices.packs
+		*withPacks = choices.withPacks
+	}
+
+	plan := planInit(absWorkspace, binPath, targets)
+	if *dryRun {
+		var packRepos []string
+		if *withPacks {
+			packRepos = append(packRepos, "github.com/orchestra-mcp/pack-essentials (embedded)")
+		}
+		if org := loadOrgSettings(); org != nil {
+			reg := loadPackRegistry(absWorkspace)
+			for _, repo := range org.DefaultPacks {
+				if _, existing := reg.FindByRepo(repo); existing == nil {
+					packRepos = append(packRepos, repo)
+				}
+			}
+		}
+		printInitPlan(absWorkspace, plan, packRepos)
+		return
+	}
+	confirmInitPlan(absWorkspace, plan, *yes)
+
+	var configPaths []string
+	for _, name := range targets {
+		if rel, err := filepath.Rel(absWorkspace, ideRegistry[name].ConfigPath(absWorkspace)); err == nil {
+			configPaths = append(configPaths, rel)
+		}
+	}
+	commit := gitFlags.prepare(absWorkspace, configPaths...)
+
 	// Generate IDE configs.
 	fmt.Fprintf(os.Stderr, "Initializing Orchestra MCP for project %q\n", projectName)
 	fmt.Fprintf(os.Stderr, "Workspace: %s\n", absWorkspace)
+	if w := detectWorktree(absWorkspace); w.linked() {
+		fmt.Fprintf(os.Stderr, "Worktree: %s\n", w.describe())
+	}
 	fmt.Fprintf(os.Stderr, "Binary: %s\n\n", binPath)
+	for _, warning := range worktreeWarnings(absWorkspace) {
+		printStatus(tagWarn, "%s", warning)
+	}
 
 	for _, name := range targets {
 		ide := ideRegistry[name]
 		configPath := ide.ConfigPath(absWorkspace)
 		content, err := ide.Generate(absWorkspace, binPath)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "  [SKIP] %s: %v\n", ide.Display, err)
+			printStatus(tagSkip, "%s: %v", ide.Display, err)
 			continue
 		}
 
 		// Create parent directory.
 		if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
-			fmt.Fprintf(os.Stderr, "  [SKIP] %s: mkdir: %v\n", ide.Display, err)
+			printStatus(tagSkip, "%s: mkdir: %v", ide.Display, err)
 			continue
 		}
 
-		if err := os.WriteFile(configPath, content, 0644); err != nil {
-			fmt.Fprintf(os.Stderr, "  [SKIP] %s: write: %v\n", ide.Display, err)
+		if err := writeFileAtomic(configPath, content, 0644); err != nil {
+			printStatus(tagSkip, "%s: write: %v", ide.Display, err)
 			continue
 		}
 
 		// Show relative path if inside workspace, else absolute.
-		displayPath := configPath
-		if rel, err := filepath.Rel(absWorkspace, configPath); err == nil && !strings.HasPrefix(rel, "..") {
-			displayPath = rel
-		}
-		fmt.Fprintf(os.Stderr, "  [OK] %s → %s\n", ide.Display, displayPath)
+		printStatus(tagOK, "%s → %s", ide.Display, displayPath(absWorkspace, configPath))
 	}
 
-	// Create .projects/ directory.
+	// Create .projects/ directory, stamping the schema version on first init.
 	projectsDir := filepath.Join(absWorkspace, ".projects")
+	_, statErr := os.Stat(projectsDir)
 	if err := os.MkdirAll(projectsDir, 0755); err != nil {
-		fmt.Fprintf(os.Stderr, "\n  [WARN] Could not create .projects/: %v\n", err)
+		fmt.Fprintf(os.Stderr, "\n")
+		printStatus(tagWarn, "Could not create .projects/: %v", err)
 	} else {
-		fmt.Fprintf(os.Stderr, "\n  [OK] .projects/ directory ready\n")
+		fmt.Fprintf(os.Stderr, "\n")
+		if os.IsNotExist(statErr) {
+			stampWorkspaceSchema(absWorkspace)
+		}
+		printStatus(tagOK, ".projects/ directory ready")
 	}
 
 	// Install bundled skill + agent (project-manager, orchestra).
 	fmt.Fprintf(os.Stderr, "\n")
 	InstallBundledContent(absWorkspace)
+	if *withPacks {
+		installDefaultPacks(absWorkspace)
+	}
+	installOrgPacks(absWorkspace)
+	installWizardPacks(absWorkspace, wizardPacks)
 
 	// Generate CLAUDE.md and AGENTS.md from installed content.
 	fmt.Fprintf(os.Stderr, "\n")
 	GenerateWorkspaceDocs(absWorkspace)
+	commit.commit("init", projectName)
+	runPostHook(hookRun{event: hookPostInit, workspace: absWorkspace, vars: []string{"ORCHESTRA_PROJECT=" + projectName}})
 
 	// Detect technology stacks and recommend packs.
+	// The wizard has already offered the recommendations.
 	stacks := detectStacks(absWorkspace)
-	if len(stacks) > 0 {
+	if len(stacks) > 0 && !wizard {
 		var stackNames []string
 		for _, s := range stacks {
 			stackNames = append(stackNames, s.name)
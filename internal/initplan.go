@@ -0,0 +1,150 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// init rewrites CLAUDE.md, AGENTS.md, and IDE configs. Before touching a
+// workspace it works out every file it would write, so --dry-run can show
+// the changes as a diff, and files orchestra did not write before are not
+// modified without a yes.
+
+// initFile is a file init would write.
+type initFile struct {
+	path      string
+	before    []byte // nil when the file does not exist
+	after     []byte
+	generated bool // before was written by orchestra; rewriting it is init's job
+}
+
+func (f initFile) changed() bool {
+	return f.before == nil || !bytes.Equal(f.before, f.after)
+}
+
+// planInit returns the files init would write in workspace for targets,
+// in the order it writes them. An IDE whose config cannot be generated is
+// left out; init reports it when it runs.
+func planInit(workspace, binPath string, targets []string) []initFile {
+	var files []initFile
+	add := func(path string, after []byte, generated func(before []byte) bool) {
+		before, err := os.ReadFile(path)
+		if err != nil {
+			before = nil
+		}
+		f := initFile{path: path, before: before, after: after}
+		f.generated = before != nil && generated(before)
+		for i := range files {
+			if files[i].path == path { // two IDEs sharing a config file
+				files[i].after = after
+				return
+			}
+		}
+		files = append(files, f)
+	}
+	always := func([]byte) bool { return true }
+
+	for _, name := range targets {
+		ide := ideRegistry[name]
+		content, err := ide.Generate(workspace, binPath)
+		if err != nil {
+			continue
+		}
+		add(ide.ConfigPath(workspace), content, func(before []byte) bool {
+			return bytes.Contains(before, []byte("orchestra")) // it already configures orchestra
+		})
+	}
+
+	if _, err := os.Stat(filepath.Join(workspace, ".projects")); os.IsNotExist(err) {
+		data, _ := json.MarshalIndent(workspaceSchema{SchemaVersion: workspaceSchemaVersion, WrittenBy: "orchestra " + Version}, "", "  ")
+		add(schemaPath(workspace), append(data, '\n'), always)
+	}
+
+	claudeDir := filepath.Join(workspace, ".claude")
+	add(filepath.Join(claudeDir, "skills", "project-manager", "SKILL.md"), []byte(projectManagerSkill), always)
+	add(filepath.Join(claudeDir, "agents", "orchestra.md"), []byte(orchestraAgent), always)
+
+	// The docs list the bundled content, which is installed by then.
+	skills := addName(scanSkills(claudeDir), "project-manager")
+	agents := addName(scanAgents(claudeDir), "orchestra")
+	claudeMD, agentsMD := renderWorkspaceDocs(workspace, loadPackRegistry(workspace), skills, agents, scanHooks(claudeDir))
+	add(filepath.Join(workspace, "CLAUDE.md"), []byte(claudeMD), func(before []byte) bool {
+		return generatedDoc("CLAUDE.md", before)
+	})
+	add(filepath.Join(workspace, "AGENTS.md"), []byte(agentsMD), func(before []byte) bool {
+		return generatedDoc("AGENTS.md", before)
+	})
+	return files
+}
+
+// addName returns sorted names with name added, once.
+func addName(names []string, name string) []string {
+	if containsString(names, name) {
+		return names
+	}
+	names = append(names, name)
+	sortNames(names)
+	return names
+}
+
+// displayPath is path relative to workspace when it is inside it.
+func displayPath(workspace, path string) string {
+	if rel, err := filepath.Rel(workspace, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+// printInitPlan prints a diff of every file in plan that would change,
+// and a summary on stderr.
+func printInitPlan(workspace string, plan []initFile, packRepos []string) {
+	created, modified, unchanged := 0, 0, 0
+	for _, f := range plan {
+		switch {
+		case f.before == nil:
+			created++
+		case f.changed():
+			modified++
+		default:
+			unchanged++
+			continue
+		}
+		fmt.Fprint(os.Stdout, unifiedDiff(displayPath(workspace, f.path), f.before, f.after))
+	}
+	for _, repo := range packRepos {
+		printStatus(tagSkip, "would install pack %s (not previewed)", repo)
+	}
+	fmt.Fprintf(os.Stderr, "Dry run: %d file(s) would be created, %d modified, %d unchanged. Nothing was written.\n", created, modified, unchanged)
+}
+
+// confirmInitPlan asks before init modifies files orchestra did not write,
+// such as an existing CLAUDE.md or a codex config. yes skips the
+// question; without a terminal to ask on, init stops.
+func confirmInitPlan(workspace string, plan []initFile, yes bool) {
+	var adopt []initFile
+	for _, f := range plan {
+		if f.before != nil && f.changed() && !f.generated {
+			adopt = append(adopt, f)
+		}
+	}
+	if len(adopt) == 0 || yes {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "init will modify %d existing file(s) that orchestra did not write:\n", len(adopt))
+	for _, f := range adopt {
+		added, removed := diffStat(f.before, f.after)
+		fmt.Fprintf(os.Stderr, "  %s (+%d -%d)\n", displayPath(workspace, f.path), added, removed)
+	}
+	if !isTerminal(os.Stdin) {
+		fatal("rerun with --dry-run to see the changes, or --yes to make them")
+	}
+	fmt.Fprintf(os.Stderr, "Run 'orchestra init --dry-run' to see the changes.\n")
+	if !confirm("Modify them?") {
+		fatal("nothing was written")
+	}
+	fmt.Fprintf(os.Stderr, "\n")
+}
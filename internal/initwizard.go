@@ -0,0 +1,414 @@
+package internal
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/orchestra-mcp/cli/pkg/packs"
+)
+
+// The init wizard. `orchestra init` on a terminal, without --ide, --all,
+// --with-packs, --yes, or --dry-run, asks before it writes anything: the
+// IDEs to configure (the detected ones checked), then the packs
+// recommended for the detected stacks (all checked), then a summary to
+// confirm. init then runs as it would with those flags and installs the
+// chosen packs. Under --plain the same questions are asked one line at a
+// time. --no-wizard, a flag set in a config file, or a stdin or stdout that
+// is not a terminal, as in CI, skips it.
+
+// defaultPackRepo is the pack --with-packs installs from the copy embedded
+// in the binary.
+const defaultPackRepo = "github.com/orchestra-mcp/pack-essentials"
+
+// initChoices is what the wizard settled on.
+type initChoices struct {
+	ides      []string
+	withPacks bool     // install defaultPackRepo from the embedded copy
+	packs     []string // other pack repos to install from git
+}
+
+// wizardItem is one row of a wizard checklist.
+type wizardItem struct {
+	key     string // IDE name or pack repo
+	label   string
+	detail  string
+	checked bool
+}
+
+// initWizard is the state of the full-screen wizard.
+type initWizard struct {
+	workspace string
+	stacks    []string
+	installed []string // recommended packs the workspace already has
+	steps     [2][]wizardItem
+	step      int // 0 IDEs, 1 packs, 2 summary
+	row       int
+	offset    int
+	rows      int
+	cols      int
+	restore   func()
+}
+
+// wantInitWizard reports whether init should ask instead of acting on its
+// flags.
+func wantInitWizard(fs *flag.FlagSet, noWizard bool) bool {
+	if noWizard || !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+		return false
+	}
+	for _, name := range []string{"ide", "all", "with-packs", "yes", "dry-run"} {
+		if flagGiven(fs, name) {
+			return false
+		}
+	}
+	return true
+}
+
+// runInitWizard asks which of the IDEs and recommended packs to set up,
+// with detected preselected. ok is false when the user cancelled.
+func runInitWizard(workspace string, detected []string) (choices initChoices, ok bool) {
+	w := &initWizard{workspace: workspace}
+	for _, s := range detectStacks(workspace) {
+		w.stacks = append(w.stacks, s.name)
+	}
+	w.steps[0] = initIDEItems(workspace, detected)
+	w.steps[1], w.installed = initPackItems(workspace, w.stacks)
+
+	if globals.plain || !w.enterScreen() {
+		ok = w.askLines()
+	} else {
+		ok = w.run()
+		w.leaveScreen()
+	}
+	if !ok {
+		return choices, false
+	}
+	for _, it := range w.steps[0] {
+		if it.checked {
+			choices.ides = append(choices.ides, it.key)
+		}
+	}
+	for _, it := range w.steps[1] {
+		switch {
+		case !it.checked:
+		case it.key == defaultPackRepo:
+			choices.withPacks = true
+		default:
+			choices.packs = append(choices.packs, it.key)
+		}
+	}
+	return choices, true
+}
+
+// initIDEItems lists every supported IDE, checking the detected ones.
+func initIDEItems(workspace string, detected []string) []wizardItem {
+	var items []wizardItem
+	for _, name := range allIDENames() {
+		ide := ideRegistry[name]
+		item := wizardItem{key: name, label: ide.Display, detail: displayPath(workspace, ide.ConfigPath(workspace))}
+		if containsString(detected, name) {
+			item.checked = true
+			item.detail += " (detected)"
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// initPackItems lists the default pack and the packs the pack index
+// recommends for stacks, all checked, leaving out and returning the ones
+// the workspace already has.
+func initPackItems(workspace string, stacks []string) (items []wizardItem, installed []string) {
+	reg := loadPackRegistry(workspace)
+	recommended := []packs.CatalogEntry{{Repo: defaultPackRepo, Description: "Orchestra's own skills and agents, installed from the copy in this binary"}}
+	for _, p := range packs.RecommendFrom(loadPackIndex(false), stacks) {
+		if p.Repo != defaultPackRepo {
+			recommended = append(recommended, p)
+		}
+	}
+	for _, p := range recommended {
+		if name, existing := reg.FindByRepo(p.Repo); existing != nil {
+			installed = append(installed, name)
+			continue
+		}
+		detail := p.Description
+		if len(p.Stacks) > 0 && !containsString(p.Stacks, "*") {
+			detail += " (" + strings.Join(p.Stacks, ", ") + ")"
+		}
+		items = append(items, wizardItem{key: p.Repo, label: p.Repo, detail: detail, checked: true})
+	}
+	return items, installed
+}
+
+// --- full screen ---
+
+func (w *initWizard) enterScreen() bool {
+	restore, err := enterRawMode()
+	if err != nil {
+		return false
+	}
+	w.restore = restore
+	fmt.Fprint(os.Stdout, "\033[?1049h\033[?25l") // alternate screen, hide cursor
+	return true
+}
+
+func (w *initWizard) leaveScreen() {
+	fmt.Fprint(os.Stdout, "\033[?25h\033[?1049l")
+	w.restore()
+}
+
+// run is the interactive loop; it reports whether the user confirmed.
+func (w *initWizard) run() bool {
+	buf := make([]byte, 64)
+	for {
+		w.rows, w.cols = terminalSize()
+		w.render()
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return false
+		}
+		for _, k := range decodeKeys(buf[:n]) {
+			if done, ok := w.handleKey(k); done {
+				return ok
+			}
+		}
+	}
+}
+
+// handleKey applies one key press. done is true when the wizard is over,
+// ok when it ended with the summary confirmed.
+func (w *initWizard) handleKey(k string) (done, ok bool) {
+	switch k {
+	case "q", "ctrl-c":
+		return true, false
+	case "esc", "left", "h", "backspace":
+		if w.step == 0 {
+			return true, false
+		}
+		w.setStep(w.step - 1)
+		return false, false
+	case "enter", "right", "l":
+		if w.step == 2 {
+			return true, true
+		}
+		w.setStep(w.step + 1)
+		return false, false
+	}
+	if w.step == 2 {
+		return false, false
+	}
+	items := w.steps[w.step]
+	switch k {
+	case "up", "k":
+		if w.row > 0 {
+			w.row--
+		}
+	case "down", "j":
+		if w.row < len(items)-1 {
+			w.row++
+		}
+	case " ", "x":
+		if w.row < len(items) {
+			items[w.row].checked = !items[w.row].checked
+		}
+	case "a":
+		all := true
+		for _, it := range items {
+			all = all && it.checked
+		}
+		for i := range items {
+			items[i].checked = !all
+		}
+	}
+	return false, false
+}
+
+func (w *initWizard) setStep(step int) {
+	w.step, w.row, w.offset = step, 0, 0
+}
+
+// render draws the current step in one write.
+func (w *initWizard) render() {
+	var s strings.Builder
+	s.WriteString("\033[H\033[2J")
+	line := func(text string) {
+		s.WriteString(clipVisible(text, w.cols) + "\r\n")
+	}
+	titles := []string{"IDEs to configure", "Packs to install", "Ready to initialize"}
+	line(colorize(ansiBold, fmt.Sprintf("Orchestra init — step %d of 3: %s", w.step+1, titles[w.step])))
+	line(strings.Repeat("─", w.cols))
+
+	var header []string
+	switch w.step {
+	case 0:
+		header = []string{"Workspace: " + w.workspace}
+	case 1:
+		header = []string{"Detected stacks: " + orDash(strings.Join(w.stacks, ", "))}
+		if len(w.installed) > 0 {
+			header = append(header, "Already installed: "+strings.Join(w.installed, ", "))
+		}
+		if len(w.steps[1]) == 0 {
+			header = append(header, "", "Nothing to recommend for this workspace.")
+		}
+	case 2:
+		header = w.summary()
+	}
+	for _, h := range header {
+		line(h)
+	}
+	used := 3 + len(header)
+
+	if w.step < 2 {
+		items := w.steps[w.step]
+		if len(header) > 0 {
+			line("")
+			used++
+		}
+		slots := max(w.rows-used, 1)
+		if w.row < w.offset {
+			w.offset = w.row
+		} else if w.row >= w.offset+slots {
+			w.offset = w.row - slots + 1
+		}
+		labelWidth := 0
+		for _, it := range items {
+			labelWidth = max(labelWidth, len([]rune(it.label)))
+		}
+		for i := w.offset; i < w.offset+slots && i < len(items); i++ {
+			it := items[i]
+			box := "[ ]"
+			if it.checked {
+				box = "[x]"
+			}
+			text := fmt.Sprintf("%s %s  %s", box, pad(it.label, labelWidth), colorize(ansiDim, it.detail))
+			if i == w.row {
+				line("› " + text)
+			} else {
+				line("  " + text)
+			}
+			used++
+		}
+	}
+	for ; used < w.rows; used++ {
+		s.WriteString("\r\n")
+	}
+
+	keys := "↑↓/jk move  space toggle  a all/none  enter next  esc back  q quit"
+	switch w.step {
+	case 0:
+		keys = "↑↓/jk move  space toggle  a all/none  enter next  q quit"
+	case 2:
+		keys = "enter initialize  esc back  q quit"
+	}
+	s.WriteString(colorize(ansiDim, clip(keys, w.cols)))
+	fmt.Fprint(os.Stdout, s.String())
+}
+
+// summary describes what init will do with the current choices.
+func (w *initWizard) summary() []string {
+	lines := []string{"Workspace: " + w.workspace, ""}
+	var ides, chosen []string
+	for _, it := range w.steps[0] {
+		if it.checked {
+			ides = append(ides, it.label)
+		}
+	}
+	for _, it := range w.steps[1] {
+		if it.checked {
+			chosen = append(chosen, it.key)
+		}
+	}
+	if len(ides) == 0 {
+		lines = append(lines, "IDEs:  none (only .projects/ and the bundled skills)")
+	} else {
+		lines = append(lines, "IDEs:  "+strings.Join(ides, ", "))
+	}
+	if len(chosen) == 0 {
+		lines = append(lines, "Packs: none")
+	} else {
+		lines = append(lines, "Packs:")
+		for _, repo := range chosen {
+			lines = append(lines, "  "+repo)
+		}
+	}
+	return lines
+}
+
+// --- line by line ---
+
+// askLines asks the wizard's questions as numbered lists answered on one
+// line each, for --plain and terminals without raw mode.
+func (w *initWizard) askLines() bool {
+	fmt.Fprintf(os.Stderr, "Setting up %s\n", w.workspace)
+	questions := []string{"IDEs to configure", "Packs to install"}
+	for step, question := range questions {
+		items := w.steps[step]
+		fmt.Fprintf(os.Stderr, "\n%s:\n", question)
+		if step == 1 {
+			fmt.Fprintf(os.Stderr, "  Detected stacks: %s\n", orDash(strings.Join(w.stacks, ", ")))
+			if len(w.installed) > 0 {
+				fmt.Fprintf(os.Stderr, "  Already installed: %s\n", strings.Join(w.installed, ", "))
+			}
+			if len(items) == 0 {
+				fmt.Fprintf(os.Stderr, "  Nothing to recommend for this workspace.\n")
+				continue
+			}
+		}
+		var def []string
+		for i, it := range items {
+			fmt.Fprintf(os.Stderr, "  %d. %s - %s\n", i+1, it.label, plainText(it.detail))
+			if it.checked {
+				def = append(def, strconv.Itoa(i+1))
+			}
+		}
+		for {
+			answer := ask("Numbers, comma-separated, or none:", orDash(strings.Join(def, ",")))
+			if picked, ok := parseWizardNumbers(answer, len(items)); ok {
+				for i := range items {
+					items[i].checked = picked[i]
+				}
+				break
+			}
+			fmt.Fprintf(os.Stderr, "  Answer with numbers from 1 to %d, or none.\n", len(items))
+		}
+	}
+	fmt.Fprintln(os.Stderr)
+	for _, line := range w.summary() {
+		fmt.Fprintln(os.Stderr, strings.TrimRight("  "+line, " "))
+	}
+	return confirm("Initialize?")
+}
+
+// parseWizardNumbers reads a comma-separated list of 1-based item numbers;
+// "none" and "-" pick nothing.
+func parseWizardNumbers(answer string, n int) (map[int]bool, bool) {
+	picked := map[int]bool{}
+	answer = strings.TrimSpace(answer)
+	if strings.EqualFold(answer, "none") || answer == "-" || answer == "" {
+		return picked, true
+	}
+	for _, field := range strings.Split(answer, ",") {
+		i, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || i < 1 || i > n {
+			return nil, false
+		}
+		picked[i-1] = true
+	}
+	return picked, true
+}
+
+// installWizardPacks installs the packs chosen in the wizard from git. A
+// failure is reported and the next pack tried.
+func installWizardPacks(workspace string, repos []string) {
+	for _, repo := range repos {
+		manifest, rev, err := installPackFromGit(workspace, repo, "", packInstallOptions{})
+		if err != nil {
+			printStatus(tagFail, "%s: %v", repo, err)
+			continue
+		}
+		recordInstalledPack(workspace, repo, manifest, "", rev, packInstallOptions{})
+		printStatus(tagOK, "%s@%s", manifest.Name, manifest.Version)
+	}
+}
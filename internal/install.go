@@ -4,71 +4,164 @@ import (
 	"archive/tar"
 	"compress/gzip"
 	"encoding/json"
-	"flag"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
-	"runtime"
 	"strings"
 	"time"
 )
 
 // pluginManifest is the JSON structure returned by `<binary> --manifest`.
 type pluginManifest struct {
-	ID              string   `json:"id"`
-	ProvidesTools   []string `json:"provides_tools"`
-	ProvidesStorage []string `json:"provides_storage"`
-	NeedsStorage    []string `json:"needs_storage"`
+	ID                string   `json:"id"`
+	ProvidesTools     []string `json:"provides_tools"`
+	ProvidesStorage   []string `json:"provides_storage"`
+	ProvidesPrompts   []string `json:"provides_prompts"`
+	ProvidesResources []string `json:"provides_resources"`
+	NeedsStorage      []string `json:"needs_storage"`
+	ProtocolVersion   string   `json:"protocol_version"` // orchestrator protocol, MAJOR.MINOR
+	MCPVersion        string   `json:"mcp_version"`      // newest MCP revision implemented
+	Capabilities      []string `json:"capabilities"`     // optional features, e.g. capabilityEncryption
 }
 
 // RunInstall handles `orchestra install <repo> [flags]`.
 func RunInstall(args []string) {
-	fs := flag.NewFlagSet("install", flag.ExitOnError)
+	fs := newFlagSet("install")
 	forceSource := fs.Bool("source", false, "Force build from source (skip binary download)")
 	forceBinary := fs.Bool("binary", false, "Force binary download (fail if unavailable)")
 	devMode := fs.Bool("dev", false, "Clone full repo into libs/ for development")
-	fs.Parse(args)
+	dryRun := fs.Bool("dry-run", false, "Show the install plan without downloading or writing anything")
+	noVerify := fs.Bool("no-verify", false, "Skip checking the download against the release's checksums.txt and signature, and booting a tools plugin to check the tools it exposes")
+	vendor := fs.Bool("vendor", false, "Install into <workspace>/.orchestra/bin/ and the workspace's plugin registry")
+	workspace := fs.String("workspace", ".", "Project workspace directory (with --vendor)")
+	target := fs.String("target", "", "Fetch or cross-compile for another platform (GOOS/GOARCH) into a bundle for 'orchestra provision'")
+	out := fs.String("out", "", "Bundle directory for --target (default: orchestra-bundle-<os>-<arch>)")
+	copyBinary := fs.Bool("copy", false, "For a local path, copy the built binary instead of linking to it")
+	parseFlags(fs, args)
 
 	if fs.NArg() < 1 {
-		fatal("usage: orchestra install <repo> [--source] [--binary] [--dev]\n  Example: orchestra install github.com/orchestra-mcp/sdk-go\n  Dev:     orchestra install github.com/orchestra-mcp/sdk-go --dev")
+		fatal("usage: orchestra install <repo|path> [--source] [--binary] [--dev] [--dry-run] [--no-verify] [--vendor] [--copy] [--target=OS/ARCH [--out=DIR]]\n  Example:   orchestra install github.com/orchestra-mcp/sdk-go\n  Monorepo:  orchestra install github.com/org/monorepo//plugins/my-plugin\n  Local:     orchestra install ./plugins/my-plugin\n  Dev:       orchestra install github.com/orchestra-mcp/sdk-go --dev")
 	}
 
-	// Parse repo and optional version tag.
+	// Local paths are built in place; see installlocal.go.
 	rawArg := fs.Arg(0)
+	if isLocalPluginPath(rawArg) {
+		if *devMode || *forceBinary || *target != "" {
+			fatal("a local path is built in place; it cannot be combined with --dev, --binary, or --target")
+		}
+		runLocalInstall(rawArg, vendorStore(*vendor, *workspace), *workspace, *copyBinary, *dryRun, *noVerify)
+		return
+	}
+
+	// Parse repo and optional version tag.
 	repo, version := parseRepoVersion(rawArg)
 
 	// Derive name from last path segment.
-	name := filepath.Base(repo)
-	if name == "" || name == "." {
-		fatal("invalid repo path: %s", repo)
+	ref, err := parseRepoRef(repo)
+	if err != nil {
+		fatal("%v", err)
+	}
+	if err := checkAllowedSource(repo); err != nil {
+		fatal("%v", err)
+	}
+	name := ref.name()
+
+	if *vendor && *devMode {
+		fatal("--vendor and --dev cannot be combined")
+	}
+	if *target != "" && (*vendor || *devMode) {
+		fatal("--target builds a bundle for 'orchestra provision'; it cannot be combined with --vendor or --dev")
+	}
+	store := vendorStore(*vendor, *workspace)
+	// Dev mode clones into libs/, named after the repo even when the
+	// plugin is in a directory within it.
+	devDir := ""
+	if *devMode {
+		devDir = path.Base(ref.Path)
+	}
+
+	platform := currentPlatform()
+	if *target != "" {
+		if _, _, err := splitPlatform(*target); err != nil {
+			fatal("--target: %v", err)
+		}
+		platform = *target
+	}
+
+	// Dry run: describe what would happen and stop before touching anything.
+	if *dryRun {
+		bundleDir := ""
+		if *target != "" {
+			bundleDir = bundleOutDir(*out, platform)
+		}
+		printInstallPlan(store, platform, bundleDir, repo, version, name, devDir, *forceSource, *forceBinary, *noVerify)
+		return
+	}
+
+	if *target != "" {
+		runBundleInstall(repo, version, name, platform, *out, *forceSource, *forceBinary, *noVerify)
+		return
 	}
 
 	// Dev mode: clone full repo into libs/ directory.
 	if *devMode {
-		runDevInstall(repo, version, name)
+		runDevInstall(repo, version, devDir)
 		return
 	}
 
-	binDir := pluginBinDir()
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	hookVars := []string{"ORCHESTRA_REPO=" + repo, "ORCHESTRA_PLUGIN_VERSION=" + version}
+	runPreHook(hookRun{event: hookPreInstall, workspace: absWorkspace, vars: hookVars})
+
+	binDir := store.binDir()
 	if err := os.MkdirAll(binDir, 0755); err != nil {
 		fatal("create plugin bin dir: %v", err)
 	}
 	binPath := filepath.Join(binDir, name)
 
+	prov, sums := fetchPlugin(repo, version, name, binPath, platform, *forceSource, *forceBinary, *noVerify)
+	entry := registerPlugin(store, repo, version, name, binPath, prov, sums, *noVerify)
+	runPostHook(hookRun{event: hookPostInstall, workspace: absWorkspace, vars: append(hookVars,
+		"ORCHESTRA_PLUGIN="+entry.ID,
+		"ORCHESTRA_PLUGIN_BINARY="+entry.Binary,
+	)})
+}
+
+// fetchPlugin puts repo's binary for platform at binPath: a release
+// download unless forceSource, falling back to a source build unless
+// forceBinary. It returns the provenance and checksum checks of a
+// downloaded release, or nils for a source build. noVerify skips the
+// checksum check.
+func fetchPlugin(repo, version, name, binPath, platform string, forceSource, forceBinary, noVerify bool) (*provenanceResult, *checksumResult) {
 	installed := false
+	var prov *provenanceResult
+	var sums *checksumResult
 
 	// Strategy 1: Pre-built binary download (unless --source).
-	if !*forceSource {
+	if !forceSource {
 		fmt.Fprintf(os.Stderr, "Attempting binary download for %s...\n", repo)
-		if err := downloadRelease(repo, version, name, binPath); err == nil {
+		var err error
+		if prov, sums, err = downloadRelease(repo, version, name, binPath, platform, noVerify); err == nil {
 			installed = true
 			fmt.Fprintf(os.Stderr, "  Downloaded pre-built binary.\n")
 		} else {
+			// A release whose attestation, checksum, or signature does not
+			// verify may have been tampered with; building the source
+			// instead would hide that.
+			if (prov != nil && prov.Status == provenanceFailed) || (sums != nil && sums.Status == checksumFailed) {
+				fatal("%v", err)
+			}
+			prov, sums = nil, nil
 			fmt.Fprintf(os.Stderr, "  Binary download failed: %v\n", err)
-			if *forceBinary {
+			if forceBinary {
 				fatal("binary download failed and --binary flag was set")
 			}
 		}
@@ -77,7 +170,7 @@ func RunInstall(args []string) {
 	// Strategy 2: Build from source.
 	if !installed {
 		fmt.Fprintf(os.Stderr, "Building from source...\n")
-		if err := buildFromSource(repo, version, name, binPath); err != nil {
+		if err := buildFromSource(repo, version, name, binPath, platform); err != nil {
 			fatal("source build failed: %v", err)
 		}
 		fmt.Fprintf(os.Stderr, "  Built from source.\n")
@@ -87,11 +180,19 @@ func RunInstall(args []string) {
 	if err := os.Chmod(binPath, 0755); err != nil {
 		fatal("chmod binary: %v", err)
 	}
+	return prov, sums
+}
 
+// registerPlugin records the plugin binary at binPath in store: it reads
+// the manifest, verifies a tools plugin's tools unless noVerify, saves the
+// registry entry with prov and sums (the download's provenance and
+// checksum checks, nil for a source build) and the binary's checksum,
+// prints a summary, and returns the entry.
+func registerPlugin(store pluginStore, repo, version, name, binPath string, prov *provenanceResult, sums *checksumResult, noVerify bool) *PluginEntry {
 	// Query plugin manifest.
 	manifest, err := queryManifest(binPath)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "  Warning: could not read manifest: %v\n", err)
+		printStatus(tagWarn, "could not read manifest: %v", err)
 		// Use defaults derived from the repo name.
 		manifest = &pluginManifest{ID: name}
 	}
@@ -103,35 +204,106 @@ func RunInstall(args []string) {
 	}
 
 	// Register in registry.
-	reg, err := LoadRegistry()
+	reg, err := store.load()
 	if err != nil {
 		fatal("load registry: %v", err)
 	}
 
-	reg.Plugins[repo] = &PluginEntry{
-		ID:              manifest.ID,
-		Version:         displayVersion,
-		Binary:          binPath,
-		Repo:            repo,
-		InstalledAt:     time.Now().UTC().Format(time.RFC3339),
-		ProvidesTools:   manifest.ProvidesTools,
-		ProvidesStorage: manifest.ProvidesStorage,
-		NeedsStorage:    manifest.NeedsStorage,
+	entry := &PluginEntry{
+		ID:                manifest.ID,
+		Version:           displayVersion,
+		Binary:            binPath,
+		Repo:              repo,
+		InstalledAt:       time.Now().UTC().Format(time.RFC3339),
+		ProvidesTools:     manifest.ProvidesTools,
+		ProvidesStorage:   manifest.ProvidesStorage,
+		ProvidesPrompts:   manifest.ProvidesPrompts,
+		ProvidesResources: manifest.ProvidesResources,
+		NeedsStorage:      manifest.NeedsStorage,
+		ProtocolVersion:   manifest.ProtocolVersion,
+		MCPVersion:        manifest.MCPVersion,
+		Platform:          currentPlatform(),
+		Provenance:        prov,
+		Checksums:         sums,
+		Vendored:          store.workspace != "",
+	}
+	if isLocalPluginPath(repo) {
+		entry.Source = pluginSourceLocal
+		if info, err := os.Lstat(binPath); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			entry.Linked = true
+		}
+	}
+	// A linked binary changes whenever it is rebuilt, so there is no
+	// checksum to hold it to.
+	if !entry.Linked {
+		if entry.SHA256, err = fileSHA256(binPath); err != nil {
+			fatal("hash binary: %v", err)
+		}
 	}
 
-	if err := SaveRegistry(reg); err != nil {
+	// Boot tools plugins once to record what they really expose.
+	var verified *toolsVerification
+	var verifyErr error
+	if !noVerify && isToolsPlugin(entry) && pluginIncompatibility(entry) == "" {
+		sp := startSpinner(fmt.Sprintf("Verifying %s's tools", entry.ID))
+		verified, verifyErr = verifyPluginTools(entry)
+		sp.Stop(verifyErr)
+		if verifyErr == nil {
+			entry.VerifiedTools = verified.Tools
+			entry.VerifiedAt = time.Now().UTC().Format(time.RFC3339)
+		}
+	}
+
+	// Reinstalling or updating keeps a plugin disabled.
+	if old, ok := reg.Plugins[repo]; ok {
+		entry.Disabled = old.Disabled
+	}
+	reg.Plugins[repo] = entry
+	if err := store.save(reg); err != nil {
 		fatal("save registry: %v", err)
 	}
 
 	// Print summary.
 	fmt.Fprintf(os.Stderr, "\nInstalled %s (%s)\n", manifest.ID, displayVersion)
 	fmt.Fprintf(os.Stderr, "  Binary: %s\n", binPath)
-	if len(manifest.ProvidesTools) > 0 {
-		fmt.Fprintf(os.Stderr, "  Tools:  %s\n", strings.Join(manifest.ProvidesTools, ", "))
+	if entry.Vendored {
+		fmt.Fprintf(os.Stderr, "  Vendored for %s in %s; 'orchestra serve' prefers it over a global install\n", entry.Platform, store.registryPath())
+	}
+	if prov != nil {
+		fmt.Fprintf(os.Stderr, "  Provenance: %s\n", prov)
+	}
+	if sums != nil {
+		fmt.Fprintf(os.Stderr, "  Checksums: %s\n", sums)
+	}
+	if tools := entry.Tools(); len(tools) > 0 {
+		fmt.Fprintf(os.Stderr, "  Tools:  %s\n", strings.Join(tools, ", "))
 	}
 	if len(manifest.ProvidesStorage) > 0 {
 		fmt.Fprintf(os.Stderr, "  Storage: %s\n", strings.Join(manifest.ProvidesStorage, ", "))
 	}
+	if len(manifest.ProvidesPrompts) > 0 {
+		fmt.Fprintf(os.Stderr, "  Prompts: %s\n", strings.Join(manifest.ProvidesPrompts, ", "))
+	}
+	if len(manifest.ProvidesResources) > 0 {
+		fmt.Fprintf(os.Stderr, "  Resources: %s\n", strings.Join(manifest.ProvidesResources, ", "))
+	}
+	if reason := pluginIncompatibility(entry); reason != "" {
+		printStatus(tagWarn, "'orchestra serve' will skip this plugin: %s", reason)
+	}
+	if verified != nil {
+		if len(verified.Missing) == 0 && len(verified.Extra) == 0 {
+			printStatus(tagOK, "verified: exposes the %d tool(s) its manifest claims", len(verified.Tools))
+		}
+		if len(verified.Missing) > 0 {
+			printStatus(tagWarn, "claimed but not exposed: %s", strings.Join(verified.Missing, ", "))
+		}
+		if len(verified.Extra) > 0 {
+			printStatus(tagWarn, "exposed but not claimed: %s", strings.Join(verified.Extra, ", "))
+		}
+	} else if verifyErr != nil {
+		printStatus(tagWarn, "tools not verified; recorded the manifest's list. Retry with: orchestra update %s", entry.ID)
+	}
+	return entry
 }
 
 // runDevInstall clones a full git repo into the libs/ directory for local
@@ -156,30 +328,29 @@ func runDevInstall(repo, version, name string) {
 	if _, err := os.Stat(destDir); err == nil {
 		fmt.Fprintf(os.Stderr, "  %s already exists at libs/%s\n", name, name)
 		fmt.Fprintf(os.Stderr, "  Pulling latest...\n")
-		pullCmd := exec.Command("git", "pull")
+		pullCmd := gitCommand(repo, "pull")
 		pullCmd.Dir = destDir
 		pullCmd.Stdout = os.Stderr
 		pullCmd.Stderr = os.Stderr
-		if err := pullCmd.Run(); err != nil {
-			fmt.Fprintf(os.Stderr, "  Warning: git pull failed: %v\n", err)
+		step := debugStep("git pull in libs/%s", name)
+		err := pullCmd.Run()
+		step.end()
+		if err != nil {
+			printStatus(tagWarn, "git pull failed: %v", err)
 		}
 		fmt.Fprintf(os.Stderr, "  Updated libs/%s\n", name)
 		return
 	}
 
 	// Clone the repo.
-	cloneURL := "https://" + repo + ".git"
-	cloneArgs := []string{"clone"}
-	if version != "" {
-		cloneArgs = append(cloneArgs, "--branch", version)
-	}
-	cloneArgs = append(cloneArgs, cloneURL, destDir)
-
 	fmt.Fprintf(os.Stderr, "Cloning %s into libs/%s...\n", repo, name)
-	gitCmd := exec.Command("git", cloneArgs...)
+	gitCmd := gitCommand(repo, cloneArgs(repo, version, destDir, false)...)
 	gitCmd.Stdout = os.Stderr
 	gitCmd.Stderr = os.Stderr
-	if err := gitCmd.Run(); err != nil {
+	step := debugStep("git clone %s", redactURL(repoCloneURL(repo)))
+	err = gitCmd.Run()
+	step.end()
+	if err != nil {
 		fatal("git clone: %v", err)
 	}
 
@@ -192,47 +363,132 @@ func runDevInstall(repo, version, name string) {
 }
 
 // parseRepoVersion splits "github.com/foo/bar@v1.0.0" into repo and version.
+// The @ of a user name, as in git@host:org/repo.git, is not a version: a
+// version follows the repo's path.
 func parseRepoVersion(s string) (repo, version string) {
-	if idx := strings.LastIndex(s, "@"); idx != -1 {
+	if idx := strings.LastIndex(s, "@"); idx != -1 && isVersionAt(s, idx) {
 		return s[:idx], s[idx+1:]
 	}
 	return s, ""
 }
 
-// downloadRelease tries to download a pre-built binary from GitHub releases.
-func downloadRelease(repo, version, name, destPath string) error {
-	// Extract owner/repo from full path (e.g. "github.com/owner/repo" -> "owner/repo").
-	parts := strings.SplitN(repo, "/", 3)
-	if len(parts) < 3 || parts[0] != "github.com" {
-		return fmt.Errorf("binary downloads only supported for github.com repos")
+// isVersionAt reports whether the @ at idx in s separates a version, that
+// is, whether a path comes before it.
+func isVersionAt(s string, idx int) bool {
+	before := s[:idx]
+	if _, rest, ok := strings.Cut(before, "://"); ok {
+		before = rest
 	}
-	ownerRepo := parts[1] + "/" + parts[2]
+	return strings.ContainsAny(before, "/:")
+}
 
-	osName := runtime.GOOS
-	archName := runtime.GOARCH
-	tarName := fmt.Sprintf("%s-%s-%s.tar.gz", name, osName, archName)
+// githubOwnerRepo extracts "owner/repo" from a github.com repo in any of
+// the forms parseRepoRef takes.
+func githubOwnerRepo(repo string) (string, error) {
+	ref, err := parseRepoRef(repo)
+	if err != nil || !ref.isGitHub() || !strings.Contains(ref.Path, "/") {
+		return "", fmt.Errorf("%s is not a github.com repo", repo)
+	}
+	return ref.Path, nil
+}
 
-	var url string
-	if version != "" {
-		url = fmt.Sprintf("https://github.com/%s/releases/download/%s/%s", ownerRepo, version, tarName)
-	} else {
-		url = fmt.Sprintf("https://github.com/%s/releases/latest/download/%s", ownerRepo, tarName)
+// pluginAssetName is the name of name's release tarball for platform
+// (GOOS/GOARCH).
+func pluginAssetName(name, platform string) (string, error) {
+	goos, goarch, err := splitPlatform(platform)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s-%s-%s.tar.gz", name, goos, goarch), nil
+}
+
+// releaseAssetURL returns the release tarball URL for platform (GOOS/GOARCH).
+// An empty version points at the latest release.
+func releaseAssetURL(repo, version, name, platform string) (string, error) {
+	ref, err := parseRepoRef(repo)
+	if err != nil {
+		return "", err
+	}
+	tarName, err := pluginAssetName(name, platform)
+	if err != nil {
+		return "", err
+	}
+	url, err := releaseAssetLocation(ref, version, tarName)
+	if errors.Is(err, errNoReleaseAsset) {
+		return "", fmt.Errorf("the release does not publish %s", tarName)
+	}
+	return url, err
+}
+
+// downloadRelease tries to download a pre-built binary from GitHub or
+// GitLab releases.
+// The archive's checksum, unless noVerify, and its provenance are checked
+// before anything is extracted; when either fails, the results are
+// returned with an error.
+func downloadRelease(repo, version, name, destPath, platform string, noVerify bool) (*provenanceResult, *checksumResult, error) {
+	url, err := releaseAssetURL(repo, version, name, platform)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	fmt.Fprintf(os.Stderr, "  GET %s\n", url)
+	fmt.Fprintf(os.Stderr, "  GET %s\n", redactURL(url))
 
-	resp, err := http.Get(url)
+	resp, err := githubGet(url, 0)
 	if err != nil {
-		return fmt.Errorf("http get: %w", err)
+		return nil, nil, fmt.Errorf("http get: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("HTTP %d from %s", resp.StatusCode, url)
+		return nil, nil, fmt.Errorf("HTTP %d from %s", resp.StatusCode, redactURL(url))
+	}
+
+	archive, err := os.CreateTemp("", "orchestra-release-*.tar.gz")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+	step := debugStep("download %s", redactURL(url))
+	n, err := io.Copy(archive, resp.Body)
+	step.endf("%d bytes", n)
+	if err != nil {
+		return nil, nil, fmt.Errorf("download: %w", err)
+	}
+
+	ref, _ := parseRepoRef(repo)
+	tarName, _ := pluginAssetName(name, platform)
+	sums := &checksumResult{Status: checksumSkipped, CheckedAt: time.Now().UTC().Format(time.RFC3339)}
+	if !noVerify {
+		if sums, err = verifyReleaseChecksums(repo, ref, version, tarName, archive.Name()); err != nil {
+			return nil, sums, err
+		}
+		if sums.Status == checksumNone {
+			printStatus(tagWarn, "%s publishes no %s; the download is not checksummed", repo, releaseChecksumsAsset)
+		} else if sums.Signature == signatureUnchecked {
+			printStatus(tagWarn, "%s", sums.Detail)
+		}
+	}
+
+	// Attestations are GitHub's; elsewhere there is nothing to check.
+	var prov *provenanceResult
+	if ref.isGitHub() {
+		step = debugStep("provenance check")
+		prov = checkProvenance(archive.Name(), ref.Path)
+		step.endf("%s", prov.Status)
+	} else {
+		digest, _ := fileSHA256(archive.Name())
+		prov = &provenanceResult{Status: provenanceNone, Digest: "sha256:" + digest, Detail: "attestations are only published on GitHub", CheckedAt: time.Now().UTC().Format(time.RFC3339)}
+	}
+	if prov.Status == provenanceFailed {
+		return prov, sums, fmt.Errorf("%s: provenance attestation did not verify: %s", tarName, prov.Detail)
 	}
 
 	// Extract binary from tar.gz.
-	return extractTarGz(resp.Body, name, destPath)
+	if _, err := archive.Seek(0, io.SeekStart); err != nil {
+		return nil, nil, err
+	}
+	return prov, sums, extractTarGz(archive, name, destPath)
 }
 
 // extractTarGz reads a tar.gz stream and extracts the named binary to destPath.
@@ -275,8 +531,10 @@ func extractTarGz(r io.Reader, binaryName, destPath string) error {
 	return fmt.Errorf("binary %q not found in archive", binaryName)
 }
 
-// buildFromSource clones the repo and builds using `go build`.
-func buildFromSource(repo, version, name, destPath string) error {
+// buildFromSource clones the repo and builds using `go build`, cross-compiling
+// (with cgo disabled) when platform is not this machine's. A repo naming a
+// directory within it is built there.
+func buildFromSource(repo, version, name, destPath, platform string) error {
 	// Check that git is available.
 	if _, err := exec.LookPath("git"); err != nil {
 		return fmt.Errorf("git not found in PATH: %w", err)
@@ -295,34 +553,63 @@ func buildFromSource(repo, version, name, destPath string) error {
 	defer os.RemoveAll(tmpDir)
 
 	// Clone the repo.
-	cloneURL := "https://" + repo + ".git"
-	cloneArgs := []string{"clone", "--depth", "1"}
-	if version != "" {
-		cloneArgs = append(cloneArgs, "--branch", version)
-	}
-	cloneArgs = append(cloneArgs, cloneURL, tmpDir)
-
-	fmt.Fprintf(os.Stderr, "  git clone %s\n", cloneURL)
-	gitCmd := exec.Command("git", cloneArgs...)
+	fmt.Fprintf(os.Stderr, "  git clone %s\n", redactURL(repoCloneURL(repo)))
+	gitCmd := gitCommand(repo, cloneArgs(repo, version, tmpDir, true)...)
 	gitCmd.Stderr = os.Stderr
-	if err := gitCmd.Run(); err != nil {
+	step := debugStep("git clone %s", redactURL(repoCloneURL(repo)))
+	err = gitCmd.Run()
+	step.end()
+	if err != nil {
 		return fmt.Errorf("git clone: %w", err)
 	}
 
+	buildDir := tmpDir
+	if ref, err := parseRepoRef(repo); err == nil && ref.Subdir != "" {
+		buildDir = filepath.Join(tmpDir, filepath.FromSlash(ref.Subdir))
+		if info, err := os.Stat(buildDir); err != nil || !info.IsDir() {
+			return fmt.Errorf("%s has no directory %s", ref, ref.Subdir)
+		}
+	}
+	return goBuild(buildDir, destPath, platform)
+}
+
+// goBuild builds the plugin in dir into destPath with `go build`,
+// cross-compiling (with cgo disabled) when platform is not this machine's.
+func goBuild(dir, destPath, platform string) error {
+	goos, goarch, err := splitPlatform(platform)
+	if err != nil {
+		return err
+	}
+	// go build runs in dir, so a relative -o would land there.
+	if destPath, err = filepath.Abs(destPath); err != nil {
+		return err
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		return fmt.Errorf("go not found in PATH: %w", err)
+	}
+
 	// Determine the build target: prefer cmd/main.go, then cmd/, then root.
 	buildTarget := "./"
-	if _, err := os.Stat(filepath.Join(tmpDir, "cmd", "main.go")); err == nil {
+	if _, err := os.Stat(filepath.Join(dir, "cmd", "main.go")); err == nil {
 		buildTarget = "./cmd/"
-	} else if info, err := os.Stat(filepath.Join(tmpDir, "cmd")); err == nil && info.IsDir() {
+	} else if info, err := os.Stat(filepath.Join(dir, "cmd")); err == nil && info.IsDir() {
 		buildTarget = "./cmd/"
 	}
 
 	// Build the binary.
-	fmt.Fprintf(os.Stderr, "  go build -o %s %s\n", destPath, buildTarget)
 	buildCmd := exec.Command("go", "build", "-o", destPath, buildTarget)
-	buildCmd.Dir = tmpDir
+	buildCmd.Dir = dir
+	if platform != currentPlatform() {
+		fmt.Fprintf(os.Stderr, "  GOOS=%s GOARCH=%s CGO_ENABLED=0 go build -o %s %s\n", goos, goarch, destPath, buildTarget)
+		buildCmd.Env = append(os.Environ(), "GOOS="+goos, "GOARCH="+goarch, "CGO_ENABLED=0")
+	} else {
+		fmt.Fprintf(os.Stderr, "  go build -o %s %s\n", destPath, buildTarget)
+	}
 	buildCmd.Stderr = os.Stderr
-	if err := buildCmd.Run(); err != nil {
+	step := debugStep("go build %s for %s", buildTarget, platform)
+	err = buildCmd.Run()
+	step.end()
+	if err != nil {
 		return fmt.Errorf("go build: %w", err)
 	}
 
@@ -331,6 +618,7 @@ func buildFromSource(repo, version, name, destPath string) error {
 
 // queryManifest runs the binary with --manifest and parses its JSON output.
 func queryManifest(binPath string) (*pluginManifest, error) {
+	defer debugStep("manifest query: %s --manifest", binPath).end()
 	cmd := exec.Command(binPath, "--manifest")
 	out, err := cmd.Output()
 	if err != nil {
@@ -343,3 +631,207 @@ func queryManifest(binPath string) (*pluginManifest, error) {
 	}
 	return &m, nil
 }
+
+// printInstallPlan describes on stdout what `orchestra install` would do
+// for repo without downloading, building, or writing anything. Only
+// lightweight metadata requests (latest tag lookup, asset HEAD) touch the
+// network.
+// With bundleDir set (--target), the binary is for platform and goes into
+// that bundle instead of store. With devDir set (--dev), the repo is cloned
+// into libs/devDir instead.
+func printInstallPlan(store pluginStore, platform, bundleDir, repo, version, name, devDir string, forceSource, forceBinary, noVerify bool) {
+	fmt.Fprintf(os.Stdout, "Install plan for %s (dry run)\n\n", repo)
+
+	// Resolve the version that would be installed.
+	resolved := version
+	if resolved == "" {
+		resolved = latestRepoReleaseTag(repo)
+	}
+	switch {
+	case version != "":
+		fmt.Fprintf(os.Stdout, "  Version:  %s (pinned)\n", version)
+	case resolved != "":
+		fmt.Fprintf(os.Stdout, "  Version:  latest → %s\n", resolved)
+	default:
+		fmt.Fprintf(os.Stdout, "  Version:  latest (could not resolve release tag)\n")
+	}
+
+	if devDir != "" {
+		destDir := filepath.Join("libs", devDir)
+		fmt.Fprintf(os.Stdout, "  Mode:     dev (full git clone)\n")
+		fmt.Fprintf(os.Stdout, "  Clone:    git %s\n", strings.Join(cloneArgs(repo, version, destDir, false), " "))
+		fmt.Fprintf(os.Stdout, "  Dest:     %s\n", destDir)
+		fmt.Fprintf(os.Stderr, "\nNothing was downloaded or written.\n")
+		return
+	}
+
+	binPath := filepath.Join(store.binDir(), name)
+	if bundleDir != "" {
+		binPath = filepath.Join(bundleDir, "bin", name)
+		fmt.Fprintf(os.Stdout, "  Target:   %s\n", platform)
+	}
+
+	// Strategy 1: release asset.
+	if !forceSource {
+		url, err := releaseAssetURL(repo, version, name, platform)
+		if err != nil {
+			fmt.Fprintf(os.Stdout, "  Binary:   unavailable (%v)\n", err)
+			if forceBinary {
+				fmt.Fprintf(os.Stdout, "            the install would fail: --binary does not fall back to a source build\n")
+			}
+		} else {
+			fmt.Fprintf(os.Stdout, "  Binary:   %s\n", redactURL(url))
+			fmt.Fprintf(os.Stdout, "            %s\n", probeURL(url, forceBinary))
+			if noVerify {
+				fmt.Fprintf(os.Stdout, "  Checksum: skipped (--no-verify)\n")
+			} else {
+				ref, _ := parseRepoRef(repo)
+				if sumsURL, err := releaseAssetLocation(ref, version, releaseChecksumsAsset); err == nil {
+					fmt.Fprintf(os.Stdout, "  Checksum: %s\n", redactURL(sumsURL))
+					fmt.Fprintf(os.Stdout, "            %s\n", probeChecksums(sumsURL))
+				} else {
+					fmt.Fprintf(os.Stdout, "  Checksum: %s %v; the download would not be checksummed\n", releaseChecksumsAsset, err)
+				}
+			}
+		}
+	}
+
+	// Strategy 2: source build.
+	if forceBinary {
+		fmt.Fprintf(os.Stdout, "  Source:   disabled (--binary)\n")
+	} else {
+		if forceSource {
+			fmt.Fprintf(os.Stdout, "  Source:   forced (--source)\n")
+		} else {
+			fmt.Fprintf(os.Stdout, "  Source:   fallback if the binary download fails\n")
+		}
+		fmt.Fprintf(os.Stdout, "            git %s\n", strings.Join(cloneArgs(repo, version, "<tmp>", true), " "))
+		goBuild := "go build"
+		if platform != currentPlatform() {
+			goos, goarch, _ := splitPlatform(platform)
+			goBuild = fmt.Sprintf("GOOS=%s GOARCH=%s CGO_ENABLED=0 go build", goos, goarch)
+		}
+		in := ""
+		if ref, err := parseRepoRef(repo); err == nil && ref.Subdir != "" {
+			in = " in " + ref.Subdir
+		}
+		fmt.Fprintf(os.Stdout, "            %s -o %s ./cmd/ (or ./ when cmd/ is absent)%s\n", goBuild, binPath, in)
+	}
+
+	fmt.Fprintf(os.Stdout, "  Dest:     %s\n", binPath)
+
+	displayVersion := version
+	if displayVersion == "" {
+		displayVersion = "latest"
+	}
+	if bundleDir != "" {
+		fmt.Fprintf(os.Stdout, "  Bundle:   add %s (%s) to %s\n", repo, displayVersion, filepath.Join(bundleDir, bundleFile))
+		fmt.Fprintf(os.Stdout, "            'orchestra provision' reads the manifest and registers it on the target\n")
+		fmt.Fprintf(os.Stderr, "\nNothing was downloaded or written.\n")
+		return
+	}
+	fmt.Fprintf(os.Stdout, "  Manifest: %s --manifest\n", binPath)
+
+	// Registry changes.
+	reg, err := store.load()
+	if err != nil {
+		fmt.Fprintf(os.Stdout, "  Registry: could not read %s: %v\n", store.registryPath(), err)
+	} else if existing, ok := reg.Plugins[repo]; ok {
+		fmt.Fprintf(os.Stdout, "  Registry: replace %s (%s → %s) in %s\n", existing.ID, existing.Version, displayVersion, store.registryPath())
+	} else {
+		fmt.Fprintf(os.Stdout, "  Registry: add %s (%s) to %s\n", repo, displayVersion, store.registryPath())
+	}
+
+	fmt.Fprintf(os.Stderr, "\nNothing was downloaded or written.\n")
+}
+
+// cloneArgs builds the git clone arguments used for source and dev installs.
+func cloneArgs(repo, version, dest string, shallow bool) []string {
+	args := []string{"clone"}
+	if shallow {
+		args = append(args, "--depth", "1")
+	}
+	if version != "" {
+		args = append(args, "--branch", version)
+	}
+	return append(args, repoCloneURL(repo), dest)
+}
+
+// latestReleaseTag asks the GitHub API for the latest release tag of
+// ownerRepo. Returns "" on any error.
+func latestReleaseTag(ownerRepo string) string {
+	resp, err := githubGetCached(githubAPIURL("/repos/"+ownerRepo+"/releases/latest"), 5*time.Second)
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return ""
+	}
+
+	var release struct {
+		TagName string `json:"tag_name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return ""
+	}
+	return release.TagName
+}
+
+// probeHead sends a HEAD for url with its host's token, without following
+// a redirect: asset URLs redirect to signed storage URLs, which are only
+// signed for GET. A redirect is reported as 200.
+func probeHead(url string) (*http.Response, error) {
+	req, err := newGitHubRequest(url)
+	if err != nil {
+		return nil, err
+	}
+	req.Method = http.MethodHead
+	client := &http.Client{Timeout: 5 * time.Second, CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		resp.StatusCode = http.StatusOK
+	}
+	return resp, nil
+}
+
+// probeChecksums describes whether a release's checksums.txt at url is
+// published.
+func probeChecksums(url string) string {
+	resp, err := probeHead(url)
+	if err != nil {
+		return fmt.Sprintf("(availability unknown: %v)", err)
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return "(published; the archive and its signature, if any, are checked before extracting)"
+	case http.StatusNotFound:
+		return "(not published; the download would not be checksummed)"
+	}
+	return fmt.Sprintf("(HTTP %d)", resp.StatusCode)
+}
+
+// probeURL issues a HEAD request to report whether a release asset exists
+// without downloading its body. forceBinary is install --binary, which has
+// no source build to fall back to.
+func probeURL(url string, forceBinary bool) string {
+	resp, err := probeHead(url)
+	if err != nil {
+		return fmt.Sprintf("(availability unknown: %v)", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return "(asset available)"
+	}
+	if forceBinary {
+		return fmt.Sprintf("(HTTP %d — the install would fail: --binary does not fall back to a source build)", resp.StatusCode)
+	}
+	return fmt.Sprintf("(HTTP %d — would fall back to source build)", resp.StatusCode)
+}
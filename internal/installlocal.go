@@ -0,0 +1,119 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Local installs. `orchestra install ./plugins/my-plugin` builds the plugin
+// in its directory, with go build as a source install does, and links the
+// installed binary to that build, so a plugin author rebuilds in place
+// (or runs `orchestra update my-plugin`) and the next serve picks it up.
+// --copy, and --vendor, which needs a binary it can commit, copy it
+// instead. The registry keys the entry by the directory's absolute path
+// with source "local", so update rebuilds from the directory instead of
+// looking for a release.
+
+// pluginSourceLocal is PluginEntry.Source for a local install.
+const pluginSourceLocal = "local"
+
+// isLocalPluginPath reports whether an install argument is a directory on
+// this machine rather than a repo: an absolute path, or one starting with
+// ".", "..", or "~".
+func isLocalPluginPath(arg string) bool {
+	if filepath.IsAbs(arg) || arg == "." || arg == ".." || arg == "~" {
+		return true
+	}
+	for _, prefix := range []string{"./", "../", "~/", `.\`, `..\`} {
+		if strings.HasPrefix(arg, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// runLocalInstall installs the plugin in dir into store.
+func runLocalInstall(dir string, store pluginStore, workspace string, copyBinary, dryRun, noVerify bool) {
+	absDir, err := filepath.Abs(expandHome(dir))
+	if err != nil {
+		fatal("resolve %s: %v", dir, err)
+	}
+	if info, err := os.Stat(absDir); err != nil || !info.IsDir() {
+		fatal("%s is not a directory", absDir)
+	}
+	name := filepath.Base(absDir)
+	built := filepath.Join(absDir, name)
+	binPath := filepath.Join(store.binDir(), name)
+	if store.workspace != "" {
+		copyBinary = true
+	}
+
+	if dryRun {
+		fmt.Fprintf(os.Stdout, "Install plan for %s (dry run)\n\n", absDir)
+		fmt.Fprintf(os.Stdout, "  Mode:     local (built in place)\n")
+		fmt.Fprintf(os.Stdout, "  Build:    go build -o %s ./cmd/ (or ./ when cmd/ is absent) in %s\n", built, absDir)
+		if copyBinary {
+			fmt.Fprintf(os.Stdout, "  Dest:     %s (copy)\n", binPath)
+		} else {
+			fmt.Fprintf(os.Stdout, "  Dest:     %s (symlink to the build)\n", binPath)
+		}
+		fmt.Fprintf(os.Stdout, "  Manifest: %s --manifest\n", binPath)
+		reg, err := store.load()
+		if err != nil {
+			fmt.Fprintf(os.Stdout, "  Registry: could not read %s: %v\n", store.registryPath(), err)
+		} else if existing, ok := reg.Plugins[absDir]; ok {
+			fmt.Fprintf(os.Stdout, "  Registry: replace %s (%s) in %s\n", existing.ID, existing.Version, store.registryPath())
+		} else {
+			fmt.Fprintf(os.Stdout, "  Registry: add %s (%s) to %s\n", absDir, pluginSourceLocal, store.registryPath())
+		}
+		fmt.Fprintf(os.Stderr, "\nNothing was built or written.\n")
+		return
+	}
+
+	absWorkspace, err := resolveWorkspace(workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	hookVars := []string{"ORCHESTRA_REPO=" + absDir, "ORCHESTRA_PLUGIN_VERSION=" + pluginSourceLocal}
+	runPreHook(hookRun{event: hookPreInstall, workspace: absWorkspace, vars: hookVars})
+
+	fmt.Fprintf(os.Stderr, "Building %s in place...\n", absDir)
+	if err := goBuild(absDir, built, currentPlatform()); err != nil {
+		fatal("build failed: %v", err)
+	}
+	if err := os.MkdirAll(store.binDir(), 0755); err != nil {
+		fatal("create plugin bin dir: %v", err)
+	}
+	if err := os.Remove(binPath); err != nil && !os.IsNotExist(err) {
+		fatal("replace %s: %v", binPath, err)
+	}
+	linked := false
+	if !copyBinary {
+		if err := os.Symlink(built, binPath); err == nil {
+			linked = true
+		} else {
+			// Creating symlinks needs extra rights on Windows.
+			printStatus(tagWarn, "could not link %s (%v); copying it instead", binPath, err)
+		}
+	}
+	if !linked {
+		data, err := os.ReadFile(built)
+		if err != nil {
+			fatal("read build: %v", err)
+		}
+		if err := writeFileAtomic(binPath, data, 0755); err != nil {
+			fatal("copy binary: %v", err)
+		}
+	}
+
+	entry := registerPlugin(store, absDir, pluginSourceLocal, name, binPath, nil, nil, noVerify)
+	if linked {
+		fmt.Fprintf(os.Stderr, "  Linked to %s; rebuild there, or run 'orchestra update %s', to pick up changes\n", built, entry.ID)
+	}
+	runPostHook(hookRun{event: hookPostInstall, workspace: absWorkspace, vars: append(hookVars,
+		"ORCHESTRA_PLUGIN="+entry.ID,
+		"ORCHESTRA_PLUGIN_BINARY="+entry.Binary,
+	)})
+}
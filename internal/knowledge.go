@@ -0,0 +1,225 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// This file is the single source of truth for the MCP tools, lifecycle
+// states, and gates that Orchestra documents. The bundled project-manager
+// skill, CLAUDE.md, and `orchestra explain` are all rendered from it.
+
+// toolGroup is a named group of MCP tools provided by one plugin.
+type toolGroup struct {
+	Name   string
+	Plugin string // "features", "marketplace", or "serve" (answered by serve itself)
+	Tools  []mcpTool
+}
+
+// mcpTool documents a single MCP tool.
+type mcpTool struct {
+	Name    string
+	Summary string
+	Args    string // required and notable arguments
+	Notes   string // optional longer guidance
+}
+
+// toolGroups lists every MCP tool in display order.
+var toolGroups = []toolGroup{
+	{Name: "Project", Plugin: "features", Tools: []mcpTool{
+		{Name: "create_project", Summary: "Create a project in the workspace", Args: "name, description"},
+		{Name: "list_projects", Summary: "List projects in the workspace"},
+		{Name: "delete_project", Summary: "Delete a project and its features", Args: "project"},
+		{Name: "get_project_status", Summary: "Feature counts per state and completion percentage", Args: "project"},
+	}},
+	{Name: "Feature", Plugin: "features", Tools: []mcpTool{
+		{Name: "create_feature", Summary: "Create a feature in the backlog", Args: "project, title, description, priority"},
+		{Name: "get_feature", Summary: "Show a feature with its metadata, notes, and reviews", Args: "feature_id"},
+		{Name: "update_feature", Summary: "Change a feature's title, description, priority, or labels", Args: "feature_id, fields to change"},
+		{Name: "list_features", Summary: "List features, optionally filtered by state, label, or assignee", Args: "project, filters"},
+		{Name: "delete_feature", Summary: "Delete a feature", Args: "feature_id"},
+		{Name: "search_features", Summary: "Full-text search over feature titles and descriptions", Args: "project, query"},
+	}},
+	{Name: "Workflow", Plugin: "features", Tools: []mcpTool{
+		{Name: "advance_feature", Summary: "Move a feature to the next lifecycle state", Args: "feature_id, evidence (required at gates)",
+			Notes: "Leaving a gated state requires evidence describing the work actually done (test results, docs written, review outcome). Advance one state at a time; never batch through gates."},
+		{Name: "reject_feature", Summary: "Send a feature in review back to needs-edits", Args: "feature_id, reason"},
+		{Name: "get_next_feature", Summary: "Pick the highest-priority actionable feature", Args: "project"},
+		{Name: "set_current_feature", Summary: "Mark a feature as the one being worked on (moves it to in-progress)", Args: "feature_id"},
+		{Name: "get_workflow_status", Summary: "What's blocked, in progress, and the completion percentage", Args: "project"},
+	}},
+	{Name: "Review", Plugin: "features", Tools: []mcpTool{
+		{Name: "request_review", Summary: "Move a documented feature into review", Args: "feature_id"},
+		{Name: "submit_review", Summary: "Record a review outcome (approved or needs-edits) with notes", Args: "feature_id, status, comment"},
+		{Name: "get_pending_reviews", Summary: "List features waiting for review", Args: "project"},
+	}},
+	{Name: "Dependencies", Plugin: "features", Tools: []mcpTool{
+		{Name: "add_dependency", Summary: "Record that a feature is blocked by another", Args: "feature_id, depends_on"},
+		{Name: "remove_dependency", Summary: "Remove a blocker relationship", Args: "feature_id, depends_on"},
+		{Name: "get_dependency_graph", Summary: "Show the dependency graph for a project", Args: "project"},
+		{Name: "get_blocked_features", Summary: "List features blocked by unfinished dependencies", Args: "project"},
+	}},
+	{Name: "WIP Limits", Plugin: "features", Tools: []mcpTool{
+		{Name: "set_wip_limits", Summary: "Set the maximum number of features per state", Args: "project, limits"},
+		{Name: "get_wip_limits", Summary: "Show the configured WIP limits", Args: "project"},
+		{Name: "check_wip_limit", Summary: "Check whether a state has room for another feature", Args: "project, state"},
+	}},
+	{Name: "Reporting", Plugin: "features", Tools: []mcpTool{
+		{Name: "get_progress", Summary: "Progress summary across the lifecycle", Args: "project"},
+		{Name: "get_review_queue", Summary: "Features in review, oldest first", Args: "project"},
+	}},
+	{Name: "Metadata", Plugin: "features", Tools: []mcpTool{
+		{Name: "add_labels", Summary: "Add labels to a feature", Args: "feature_id, labels"},
+		{Name: "remove_labels", Summary: "Remove labels from a feature", Args: "feature_id, labels"},
+		{Name: "assign_feature", Summary: "Assign a feature to a team member", Args: "feature_id, assignee"},
+		{Name: "unassign_feature", Summary: "Clear a feature's assignee", Args: "feature_id"},
+		{Name: "set_estimate", Summary: "Size a feature (S, M, L, XL)", Args: "feature_id, estimate"},
+		{Name: "save_note", Summary: "Attach a note (decision, context) to a feature", Args: "feature_id, note"},
+		{Name: "list_notes", Summary: "List a feature's notes", Args: "feature_id"},
+	}},
+	{Name: "Pack Management", Plugin: "marketplace", Tools: []mcpTool{
+		{Name: "install_pack", Summary: "Install a content pack into .claude/", Args: "repo, version"},
+		{Name: "remove_pack", Summary: "Remove an installed pack and its files", Args: "name"},
+		{Name: "update_pack", Summary: "Update one or all installed packs", Args: "name (optional)"},
+		{Name: "list_packs", Summary: "List installed packs"},
+		{Name: "get_pack", Summary: "Show an installed pack's contents and version", Args: "name"},
+		{Name: "search_packs", Summary: "Search the pack catalog", Args: "query"},
+	}},
+	{Name: "Recommendations", Plugin: "marketplace", Tools: []mcpTool{
+		{Name: "detect_stacks", Summary: "Detect technology stacks in the workspace"},
+		{Name: "recommend_packs", Summary: "Recommend packs for the detected stacks"},
+	}},
+	{Name: "Content Queries", Plugin: "marketplace", Tools: []mcpTool{
+		{Name: "list_skills", Summary: "List installed skills"},
+		{Name: "list_agents", Summary: "List installed agents"},
+		{Name: "list_hooks", Summary: "List installed hooks"},
+		{Name: "get_skill", Summary: "Show a skill's SKILL.md", Args: "name"},
+		{Name: "get_agent", Summary: "Show an agent's definition", Args: "name"},
+	}},
+	{Name: "Configuration", Plugin: "marketplace", Tools: []mcpTool{
+		{Name: "set_project_stacks", Summary: "Save the project's stacks", Args: "stacks"},
+		{Name: "get_project_stacks", Summary: "Show the project's saved stacks"},
+	}},
+	{Name: "Workspace Docs", Plugin: "serve", Tools: []mcpTool{
+		{Name: "regenerate_docs", Summary: "Regenerate CLAUDE.md, AGENTS.md, and PROGRESS.md from installed content",
+			Notes: "Call it after editing skills, agents, or hooks, or after installing or removing packs, so the docs match what is installed."},
+	}},
+}
+
+// lifecycleState documents one feature lifecycle state.
+type lifecycleState struct {
+	Name    string
+	Summary string
+	Next    []string
+	Gate    int // gate number required to leave this state, 0 if none
+}
+
+// lifecycleStates lists the feature lifecycle in order; needs-edits is the
+// rework state entered from review.
+var lifecycleStates = []lifecycleState{
+	{Name: "backlog", Summary: "Captured but not scheduled", Next: []string{"todo"}},
+	{Name: "todo", Summary: "Scheduled and ready to start", Next: []string{"in-progress"}},
+	{Name: "in-progress", Summary: "Being implemented", Next: []string{"ready-for-testing"}, Gate: 1},
+	{Name: "ready-for-testing", Summary: "Implementation done, waiting for verification", Next: []string{"in-testing"}},
+	{Name: "in-testing", Summary: "Coverage and edge cases being verified", Next: []string{"ready-for-docs"}, Gate: 2},
+	{Name: "ready-for-docs", Summary: "Verified, waiting for documentation", Next: []string{"in-docs"}},
+	{Name: "in-docs", Summary: "Documentation being written", Next: []string{"documented"}, Gate: 3},
+	{Name: "documented", Summary: "Documented, waiting for review", Next: []string{"in-review"}},
+	{Name: "in-review", Summary: "Code and docs under review", Next: []string{"done", "needs-edits"}, Gate: 4},
+	{Name: "done", Summary: "Complete"},
+	{Name: "needs-edits", Summary: "Review requested changes", Next: []string{"in-progress"}},
+}
+
+// workflowGate documents an evidence-gated transition.
+type workflowGate struct {
+	Number   int
+	From     string
+	Action   string
+	Evidence string
+}
+
+// workflowGates lists the gated transitions in lifecycle order.
+var workflowGates = []workflowGate{
+	{Number: 1, From: "in-progress", Action: "Run tests, confirm pass", Evidence: `"go test ./... - all passed"`},
+	{Number: 2, From: "in-testing", Action: "Verify coverage, edge cases", Evidence: `"Coverage 85%, edge cases covered"`},
+	{Number: 3, From: "in-docs", Action: "Write/update documentation", Evidence: `"Added docs, updated README"`},
+	{Number: 4, From: "in-review", Action: "Review code quality", Evidence: `"No issues, error handling OK"`},
+}
+
+// toolCount returns the number of tools provided by plugin ("" for all).
+func toolCount(plugin string) int {
+	n := 0
+	for _, g := range toolGroups {
+		if plugin == "" || g.Plugin == plugin {
+			n += len(g.Tools)
+		}
+	}
+	return n
+}
+
+// findTool returns the tool with the given name and its group.
+func findTool(name string) (*mcpTool, *toolGroup) {
+	for gi := range toolGroups {
+		g := &toolGroups[gi]
+		for ti := range g.Tools {
+			if g.Tools[ti].Name == name {
+				return &g.Tools[ti], g
+			}
+		}
+	}
+	return nil, nil
+}
+
+// findState returns the lifecycle state with the given name.
+func findState(name string) *lifecycleState {
+	for i := range lifecycleStates {
+		if lifecycleStates[i].Name == name {
+			return &lifecycleStates[i]
+		}
+	}
+	return nil
+}
+
+// findGate returns the gate with the given number.
+func findGate(number int) *workflowGate {
+	for i := range workflowGates {
+		if workflowGates[i].Number == number {
+			return &workflowGates[i]
+		}
+	}
+	return nil
+}
+
+// toolCatalogMarkdown renders the tool list used in the project-manager skill.
+func toolCatalogMarkdown() string {
+	var b strings.Builder
+	for _, plugin := range []struct{ id, title string }{
+		{"features", "Feature Tools"},
+		{"marketplace", "Marketplace Tools"},
+		{"serve", "Serve Tools"},
+	} {
+		fmt.Fprintf(&b, "## %s (%d total)\n\n", plugin.title, toolCount(plugin.id))
+		for _, g := range toolGroups {
+			if g.Plugin != plugin.id {
+				continue
+			}
+			names := make([]string, len(g.Tools))
+			for i, t := range g.Tools {
+				names[i] = t.Name
+			}
+			fmt.Fprintf(&b, "### %s (%d)\n%s\n\n", g.Name, len(g.Tools), strings.Join(names, ", "))
+		}
+	}
+	return b.String()
+}
+
+// gatesMarkdown renders the gated transitions table.
+func gatesMarkdown() string {
+	var b strings.Builder
+	b.WriteString("| Gate | From | Action Required | Evidence Example |\n")
+	b.WriteString("|------|------|----------------|-----------------|\n")
+	for _, g := range workflowGates {
+		fmt.Fprintf(&b, "| %d | %s | %s | %s |\n", g.Number, g.From, g.Action, g.Evidence)
+	}
+	return b.String()
+}
@@ -0,0 +1,516 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// A workspace can fix the labels its features use, so the agent does not
+// invent "frontend", "front-end", and "ui" for the same thing. The
+// taxonomy lives under labels: in .orchestra.yaml, and the people features
+// are assigned to under assignees:
+//
+//	labels:
+//	  frontend: {color: "#1f77b4", description: Web UI}
+//	  bug: {color: red}
+//	assignees: [alice, bob]
+//
+// CLAUDE.md lists both for the agent. `orchestra labels check` reports
+// features that stray from them, and bulk creation warns. Without a
+// taxonomy, any label goes.
+
+// labelDef is one label of the taxonomy.
+type labelDef struct {
+	Color       string `yaml:"color,omitempty"`
+	Description string `yaml:"description,omitempty"`
+}
+
+// labelColors are the color names a label may use besides #rgb and
+// #rrggbb.
+var labelColors = []string{"red", "orange", "yellow", "green", "teal", "blue", "purple", "pink", "brown", "gray"}
+
+var hexColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+func checkLabelColor(color string) error {
+	if color == "" || hexColor.MatchString(color) || containsFold(labelColors, color) {
+		return nil
+	}
+	return fmt.Errorf("invalid color %q: use #rgb, #rrggbb, or one of %s", color, strings.Join(labelColors, ", "))
+}
+
+func checkLabelName(name string) error {
+	if name == "" || strings.ContainsAny(name, ", \t\n") {
+		return fmt.Errorf("invalid label %q: labels are one word, without commas", name)
+	}
+	return nil
+}
+
+// labelKey folds the differences near-duplicate labels tend to have:
+// case, separators, and a plural s.
+func labelKey(label string) string {
+	key := strings.ToLower(label)
+	key = strings.NewReplacer("-", "", "_", "", " ", "", ".", "").Replace(key)
+	return strings.TrimSuffix(key, "s")
+}
+
+// closestName returns the name in names that value most likely means: one
+// equal to it but for case, separators, or a plural, or else one within a
+// small edit distance. It returns "" when none is close.
+func closestName(names []string, value string) string {
+	key := labelKey(value)
+	best, bestDist := "", 3
+	if len(key) < 5 {
+		bestDist = 2
+	}
+	for _, name := range names {
+		if name == value {
+			continue
+		}
+		k := labelKey(name)
+		if k == key {
+			return name
+		}
+		if d := editDistance(k, key); d < bestDist {
+			best, bestDist = name, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// labelNames returns the taxonomy's labels, sorted.
+func labelNames(cfg *workspaceConfig) []string {
+	names := make([]string, 0, len(cfg.Labels))
+	for name := range cfg.Labels {
+		names = append(names, name)
+	}
+	sortNames(names)
+	return names
+}
+
+// taxonomyProblems lists how f strays from the taxonomy and the assignee
+// list, one line each, with a suggestion where one is close.
+func taxonomyProblems(cfg *workspaceConfig, f *feature) []string {
+	var problems []string
+	if len(cfg.Labels) > 0 {
+		names := labelNames(cfg)
+		for _, l := range f.Labels {
+			if _, ok := cfg.Labels[l]; ok {
+				continue
+			}
+			p := fmt.Sprintf("label %q is not in the taxonomy", l)
+			if s := closestName(names, l); s != "" {
+				p += fmt.Sprintf(" (did you mean %q?)", s)
+			}
+			problems = append(problems, p)
+		}
+	}
+	if len(cfg.Assignees) > 0 && f.Assignee != "" && !containsString(cfg.Assignees, f.Assignee) {
+		p := fmt.Sprintf("assignee %q is not in the assignees list", f.Assignee)
+		if s := closestName(cfg.Assignees, f.Assignee); s != "" {
+			p += fmt.Sprintf(" (did you mean %q?)", s)
+		}
+		problems = append(problems, p)
+	}
+	return problems
+}
+
+func containsString(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// warnTaxonomy warns about features about to be created that stray from
+// the taxonomy.
+func warnTaxonomy(workspace string, features []*feature) {
+	cfg := loadWorkspaceConfig(workspace)
+	for _, f := range features {
+		for _, p := range taxonomyProblems(cfg, f) {
+			printStatus(tagWarn, "%s: %s", f.Title, p)
+		}
+	}
+}
+
+// relabelFeatures replaces labels in every feature of the store as rename
+// maps them, dropping duplicates, and returns the features it changed. A
+// relabel is not a change to the work, so updated_at is left alone.
+func relabelFeatures(workspace string, rename map[string]string, dryRun bool) []*feature {
+	var changed []*feature
+	for _, f := range loadFeatures(workspace, "") {
+		var labels []string
+		touched := false
+		for _, l := range f.Labels {
+			if to, ok := rename[l]; ok {
+				l, touched = to, true
+			}
+			if !containsString(labels, l) {
+				labels = append(labels, l)
+			}
+		}
+		if !touched {
+			continue
+		}
+		f.Labels = labels
+		if !dryRun {
+			if err := saveFeature(f); err != nil {
+				fatal("save %s: %v", f.ID, err)
+			}
+		}
+		changed = append(changed, f)
+	}
+	return changed
+}
+
+// labelConfigFile loads .orchestra.yaml for editing the taxonomy.
+func labelConfigFile(workspace string) *configFile {
+	cf, err := loadConfigFile(filepath.Join(workspace, workspaceConfigFile), "local")
+	if err != nil {
+		fatal("%v", err)
+	}
+	return cf
+}
+
+// --- commands ---
+
+func runLabelsList(args []string) {
+	fs := newFlagSet("labels list")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	porcelain := fs.Bool("porcelain", false, "Stable tab-separated output for scripts")
+	parseFlags(fs, args)
+
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	cfg := loadWorkspaceConfig(absWorkspace)
+	uses := map[string]int{}
+	for _, f := range loadFeatures(absWorkspace, "") {
+		for _, l := range f.Labels {
+			uses[l]++
+		}
+	}
+	names := labelNames(cfg)
+	var strays []string
+	for l := range uses {
+		if _, ok := cfg.Labels[l]; !ok {
+			strays = append(strays, l)
+		}
+	}
+	sortNames(strays)
+
+	if jsonOutput() {
+		type labelJSON struct {
+			Name        string `json:"name"`
+			Color       string `json:"color,omitempty"`
+			Description string `json:"description,omitempty"`
+			Features    int    `json:"features"` // how many features use it
+			InTaxonomy  bool   `json:"in_taxonomy"`
+		}
+		list := []labelJSON{}
+		for _, name := range names {
+			d := cfg.Labels[name]
+			list = append(list, labelJSON{name, d.Color, d.Description, uses[name], true})
+		}
+		for _, l := range strays {
+			list = append(list, labelJSON{Name: l, Features: uses[l]})
+		}
+		printJSON(list)
+		return
+	}
+
+	// Porcelain: label, color, features using it, in taxonomy
+	// (true/false), description.
+	tw := newTable(os.Stdout)
+	for _, name := range names {
+		d := cfg.Labels[name]
+		if *porcelain {
+			fmt.Fprintf(os.Stdout, "%s\t%s\t%d\ttrue\t%s\n", name, d.Color, uses[name], d.Description)
+		} else {
+			fmt.Fprintf(tw, "  %s\t%s\t%d feature(s)\t%s\n", name, orDash(d.Color), uses[name], d.Description)
+		}
+	}
+	for _, l := range strays {
+		if *porcelain {
+			fmt.Fprintf(os.Stdout, "%s\t\t%d\tfalse\t\n", l, uses[l])
+			continue
+		}
+		note := ""
+		if len(names) > 0 {
+			note = "not in the taxonomy"
+			if s := closestName(names, l); s != "" {
+				note += fmt.Sprintf("; did you mean %s?", s)
+			}
+		}
+		fmt.Fprintf(tw, "  %s\t-\t%d feature(s)\t%s\n", l, uses[l], note)
+	}
+	tw.Flush()
+	if !*porcelain && len(names) == 0 {
+		fmt.Fprintln(os.Stderr, "No label taxonomy yet; any label goes. Add labels with 'orchestra labels add'.")
+	}
+}
+
+func runLabelsAdd(args []string) {
+	fs := newFlagSet("labels add")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	color := fs.String("color", "", "Color: #rgb, #rrggbb, or a name like blue")
+	description := fs.String("description", "", "What the label is for")
+	force := fs.Bool("force", false, "Add the label even when it looks like one already in the taxonomy")
+	parseFlags(fs, args)
+	if fs.NArg() != 1 {
+		fatal("usage: orchestra labels add <label> [--color=C] [--description=TEXT]")
+	}
+	name := fs.Arg(0)
+	if err := checkLabelName(name); err != nil {
+		fatal("%v", err)
+	}
+	if err := checkLabelColor(*color); err != nil {
+		fatal("%v", err)
+	}
+
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	cfg := loadWorkspaceConfig(absWorkspace)
+	def, exists := cfg.Labels[name]
+	if !exists && !*force {
+		if s := closestName(labelNames(cfg), name); s != "" {
+			fatal("%q looks like %q, which is already in the taxonomy; use it, or pass --force to add %q anyway", name, s, name)
+		}
+	}
+	if flagGiven(fs, "color") {
+		def.Color = *color
+	}
+	if flagGiven(fs, "description") {
+		def.Description = *description
+	}
+
+	var node yaml.Node
+	if err := node.Encode(def); err != nil {
+		fatal("%v", err)
+	}
+	cf := labelConfigFile(absWorkspace)
+	cf.set([]string{"labels", name}, &node)
+	if err := cf.save(); err != nil {
+		fatal("%s: %v", workspaceConfigFile, err)
+	}
+	if exists {
+		printStatus(tagOK, "updated label %s", name)
+	} else {
+		printStatus(tagOK, "added label %s to %s", name, workspaceConfigFile)
+	}
+	GenerateWorkspaceDocs(absWorkspace)
+}
+
+func runLabelsRename(args []string) {
+	fs := newFlagSet("labels rename")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	dryRun := fs.Bool("dry-run", false, "List the features that would change without writing")
+	parseFlags(fs, args)
+	if fs.NArg() != 2 {
+		fatal("usage: orchestra labels rename <old> <new> [--dry-run]")
+	}
+	from, to := fs.Arg(0), fs.Arg(1)
+	if err := checkLabelName(to); err != nil {
+		fatal("%v", err)
+	}
+
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	checkWorkspaceSchema(absWorkspace, true)
+	cfg := loadWorkspaceConfig(absWorkspace)
+	if _, ok := cfg.Labels[to]; ok {
+		fatal("%s is already in the taxonomy; use 'orchestra labels merge %s %s' to fold %s into it", to, from, to, from)
+	}
+	relabelLabels(absWorkspace, cfg, []string{from}, to, *dryRun)
+}
+
+func runLabelsMerge(args []string) {
+	fs := newFlagSet("labels merge")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	dryRun := fs.Bool("dry-run", false, "List the features that would change without writing")
+	parseFlags(fs, args)
+	if fs.NArg() < 2 {
+		fatal("usage: orchestra labels merge <label>... <into> [--dry-run]")
+	}
+	from, into := fs.Args()[:fs.NArg()-1], fs.Arg(fs.NArg()-1)
+	if err := checkLabelName(into); err != nil {
+		fatal("%v", err)
+	}
+	if containsString(from, into) {
+		fatal("cannot merge %s into itself", into)
+	}
+
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	checkWorkspaceSchema(absWorkspace, true)
+	relabelLabels(absWorkspace, loadWorkspaceConfig(absWorkspace), from, into, *dryRun)
+}
+
+// relabelLabels implements rename and merge: it replaces the labels from
+// with to in every feature and in the taxonomy. A renamed label keeps its
+// color and description; labels merged into one already in the taxonomy
+// take its.
+func relabelLabels(workspace string, cfg *workspaceConfig, from []string, to string, dryRun bool) {
+	rename := map[string]string{}
+	for _, l := range from {
+		rename[l] = to
+	}
+	changed := relabelFeatures(workspace, rename, dryRun)
+	_, toKnown := cfg.Labels[to]
+	var inTaxonomy []string
+	for _, l := range from {
+		if _, ok := cfg.Labels[l]; ok {
+			inTaxonomy = append(inTaxonomy, l)
+		}
+	}
+	if len(changed) == 0 && len(inTaxonomy) == 0 {
+		fatal("no feature or taxonomy entry has %s", strings.Join(from, " or "))
+	}
+
+	sort.Slice(changed, func(i, j int) bool { return changed[i].ID < changed[j].ID })
+	for _, f := range changed {
+		if dryRun {
+			fmt.Fprintf(os.Stdout, "%s\t%s\t%s\n", f.ID, strings.Join(f.Labels, ","), f.Title)
+		}
+	}
+	if dryRun {
+		fmt.Fprintf(os.Stderr, "Dry run: %d feature(s) would be relabeled %s\n", len(changed), to)
+		return
+	}
+
+	if len(inTaxonomy) > 0 {
+		cf := labelConfigFile(workspace)
+		if !toKnown {
+			// Carry the first entry over, so a rename keeps its color.
+			node := cf.lookup([]string{"labels", inTaxonomy[0]})
+			if node == nil { // an org label
+				node = &yaml.Node{}
+				node.Encode(cfg.Labels[inTaxonomy[0]])
+			}
+			cf.set([]string{"labels", to}, node)
+		}
+		for _, l := range inTaxonomy {
+			cf.unset([]string{"labels", l})
+			if isOrgLabel(l) {
+				printStatus(tagWarn, "%s comes from the org settings and stays in the taxonomy until they drop it", l)
+			}
+		}
+		if err := cf.save(); err != nil {
+			fatal("%s: %v", workspaceConfigFile, err)
+		}
+	}
+	if err := writeProgressDoc(workspace); err != nil {
+		printStatus(tagWarn, "PROGRESS.md: %v", err)
+	}
+	printStatus(tagOK, "relabeled %d feature(s): %s -> %s", len(changed), strings.Join(from, ", "), to)
+	if len(inTaxonomy) > 0 {
+		GenerateWorkspaceDocs(workspace)
+	}
+}
+
+func runLabelsCheck(args []string) {
+	fs := newFlagSet("labels check")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	fix := fs.Bool("fix", false, "Replace labels and assignees with the close match suggested for them")
+	parseFlags(fs, args)
+
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	cfg := loadWorkspaceConfig(absWorkspace)
+	if len(cfg.Labels) == 0 && len(cfg.Assignees) == 0 {
+		fmt.Fprintln(os.Stderr, "No label taxonomy or assignees in .orchestra.yaml; nothing to check.")
+		return
+	}
+
+	names := labelNames(cfg)
+	bad, fixed := 0, 0
+	for _, f := range loadFeatures(absWorkspace, "") {
+		problems := taxonomyProblems(cfg, f)
+		if len(problems) == 0 {
+			continue
+		}
+		if *fix {
+			changed := false
+			for i, l := range f.Labels {
+				if _, ok := cfg.Labels[l]; !ok && len(cfg.Labels) > 0 {
+					if s := closestName(names, l); s != "" {
+						f.Labels[i], changed = s, true
+					}
+				}
+			}
+			if len(cfg.Assignees) > 0 && f.Assignee != "" && !containsString(cfg.Assignees, f.Assignee) {
+				if s := closestName(cfg.Assignees, f.Assignee); s != "" {
+					f.Assignee, changed = s, true
+				}
+			}
+			if changed {
+				var labels []string
+				for _, l := range f.Labels {
+					if !containsString(labels, l) {
+						labels = append(labels, l)
+					}
+				}
+				f.Labels = labels
+				if err := saveFeature(f); err != nil {
+					fatal("save %s: %v", f.ID, err)
+				}
+				fixed++
+				if problems = taxonomyProblems(cfg, f); len(problems) == 0 {
+					printStatus(tagOK, "%s: fixed", f.ID)
+					continue
+				}
+			}
+		}
+		bad++
+		for _, p := range problems {
+			printStatus(tagFail, "%s: %s", f.ID, p)
+		}
+	}
+	if fixed > 0 {
+		if err := writeProgressDoc(absWorkspace); err != nil {
+			printStatus(tagWarn, "PROGRESS.md: %v", err)
+		}
+	}
+	if bad > 0 {
+		fmt.Fprintf(os.Stderr, "%d feature(s) stray from the taxonomy. Fix them, 'orchestra labels add' the labels, or run with --fix.\n", bad)
+		os.Exit(1)
+	}
+	printStatus(tagOK, "every feature's labels and assignee are in the taxonomy")
+}
@@ -0,0 +1,203 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// lintIssue is one problem found by `orchestra lint-content`.
+type lintIssue struct {
+	Path     string // relative to the workspace
+	Line     int    // 0 when the issue is about the whole file
+	Severity string // error or warning
+	Check    string
+	Message  string
+}
+
+var (
+	// markdownLink matches [text](target) and [text](target "title").
+	markdownLink = regexp.MustCompile(`\[[^\]]*\]\(([^)\s]+)(?:\s+"[^"]*")?\)`)
+	// machinePath matches home directories that only exist on one machine.
+	machinePath = regexp.MustCompile(`(/Users/[^/\s]+/|/home/[^/\s]+/|[A-Za-z]:\\Users\\[^\\\s]+\\)`)
+	// markdownHeading matches an ATX heading.
+	markdownHeading = regexp.MustCompile(`^#{1,6}\s`)
+)
+
+// RunLintContent handles `orchestra lint-content` -- checks installed skills
+// and agents for problems that make them harder for an agent to use.
+func RunLintContent(args []string) {
+	fs := newFlagSet("lint-content")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	sectionBudget := fs.Int("section-budget", 1500, "Warn when a heading's section exceeds this many tokens")
+	strict := fs.Bool("strict", false, "Exit with status 1 on warnings as well as errors")
+	porcelain := fs.Bool("porcelain", false, "Stable tab-separated output for scripts")
+	parseFlags(fs, args)
+
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+
+	files := lintContentFiles(absWorkspace)
+	if len(files) == 0 {
+		fmt.Fprintf(os.Stderr, "No skills or agents installed.\n")
+		return
+	}
+
+	var issues []lintIssue
+	for _, rel := range files {
+		issues = append(issues, lintContentFile(absWorkspace, rel, *sectionBudget)...)
+	}
+
+	errors, warnings := 0, 0
+	for _, is := range issues {
+		if is.Severity == "error" {
+			errors++
+		} else {
+			warnings++
+		}
+		// Porcelain: path, line, severity, check, message.
+		if *porcelain {
+			fmt.Fprintf(os.Stdout, "%s\t%d\t%s\t%s\t%s\n", is.Path, is.Line, is.Severity, is.Check, is.Message)
+			continue
+		}
+		loc := is.Path
+		if is.Line > 0 {
+			loc = fmt.Sprintf("%s:%d", is.Path, is.Line)
+		}
+		fmt.Fprintf(os.Stdout, "%s: %s: %s (%s)\n", loc, is.Severity, is.Message, is.Check)
+	}
+
+	if !*porcelain {
+		if len(issues) == 0 {
+			printStatus(tagOK, "%d files, no problems", len(files))
+		} else {
+			fmt.Fprintf(os.Stderr, "\n%d files checked: %d errors, %d warnings\n", len(files), errors, warnings)
+		}
+	}
+	if errors > 0 || (*strict && warnings > 0) {
+		os.Exit(1)
+	}
+}
+
+// lintContentFiles lists every markdown file under installed skills and
+// agents, relative to the workspace.
+func lintContentFiles(workspace string) []string {
+	claudeDir := filepath.Join(workspace, ".claude")
+	var files []string
+	for _, name := range scanSkills(claudeDir) {
+		dir := filepath.Join(claudeDir, "skills", name)
+		filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !strings.EqualFold(filepath.Ext(path), ".md") {
+				return nil
+			}
+			if rel, err := filepath.Rel(workspace, path); err == nil {
+				files = append(files, filepath.ToSlash(rel))
+			}
+			return nil
+		})
+	}
+	for _, name := range scanAgents(claudeDir) {
+		files = append(files, ".claude/agents/"+name+".md")
+	}
+	return files
+}
+
+// lintContentFile runs every check on one file.
+func lintContentFile(workspace, rel string, sectionBudget int) []lintIssue {
+	path := filepath.Join(workspace, filepath.FromSlash(rel))
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []lintIssue{{Path: rel, Severity: "error", Check: "read", Message: err.Error()}}
+	}
+	if !utf8.Valid(data) {
+		return []lintIssue{{Path: rel, Severity: "error", Check: "utf8", Message: "file is not valid UTF-8"}}
+	}
+
+	var issues []lintIssue
+	add := func(line int, severity, check, format string, args ...any) {
+		issues = append(issues, lintIssue{Path: rel, Line: line, Severity: severity, Check: check, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// Skills and agents need a frontmatter description; the agent decides
+	// when to use them from it. Reference files inside a skill do not.
+	body := bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	offset := 0
+	needsFront := filepath.Base(rel) == "SKILL.md" || strings.HasPrefix(rel, ".claude/agents/")
+	front, rest, err := splitFrontmatter(data)
+	switch {
+	case err == nil:
+		offset = bytes.Count(front, []byte("\n")) + 2
+		body = rest
+		var meta struct {
+			Description string `yaml:"description"`
+		}
+		if err := yaml.Unmarshal(front, &meta); err != nil {
+			add(1, "error", "frontmatter", "invalid frontmatter: %v", err)
+		} else if needsFront && strings.TrimSpace(meta.Description) == "" {
+			add(1, "error", "frontmatter", "frontmatter has no description")
+		}
+	case needsFront:
+		add(1, "error", "frontmatter", "%v (add name and description)", err)
+	}
+
+	lines := strings.Split(string(body), "\n")
+	inFence := false
+	heading, headingLine, sectionTokens := "", 0, 0
+	flushSection := func() {
+		if heading != "" && sectionTokens > sectionBudget {
+			add(headingLine, "warning", "section-size", "section %q is ~%d tokens (budget %d); split it into a separate file", heading, sectionTokens, sectionBudget)
+		}
+	}
+	for i, line := range lines {
+		n := offset + i + 1
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+		}
+
+		if !inFence && markdownHeading.MatchString(line) {
+			flushSection()
+			heading, headingLine, sectionTokens = strings.TrimSpace(strings.TrimLeft(line, "#")), n, 0
+		}
+		sectionTokens += estimateTokens([]byte(line + "\n"))
+
+		if m := machinePath.FindString(line); m != "" {
+			add(n, "warning", "machine-path", "machine-specific path %q; use a path relative to the project", m)
+		}
+		if inFence {
+			continue
+		}
+		for _, m := range markdownLink.FindAllStringSubmatch(line, -1) {
+			if target := localLinkTarget(m[1]); target != "" {
+				if _, err := os.Stat(filepath.Join(filepath.Dir(path), filepath.FromSlash(target))); err != nil {
+					add(n, "error", "dead-link", "link target %q does not exist", m[1])
+				}
+			}
+		}
+	}
+	flushSection()
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].Line < issues[j].Line })
+	return issues
+}
+
+// localLinkTarget returns the file part of a relative link, or "" for
+// URLs, anchors, and absolute paths.
+func localLinkTarget(target string) string {
+	if strings.Contains(target, "://") || strings.HasPrefix(target, "mailto:") ||
+		strings.HasPrefix(target, "#") || strings.HasPrefix(target, "/") {
+		return ""
+	}
+	if i := strings.IndexAny(target, "#?"); i >= 0 {
+		target = target[:i]
+	}
+	return target
+}
@@ -0,0 +1,276 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// logFilter selects records for `orchestra logs`.
+type logFilter struct {
+	since  time.Time
+	level  int // minimum logLevelRank
+	plugin string
+	source string
+}
+
+func (f logFilter) match(rec logRecord) bool {
+	if logLevelRank(rec.Level) < f.level {
+		return false
+	}
+	if f.source != "" && rec.Source != f.source {
+		return false
+	}
+	if f.plugin != "" {
+		if ok, _ := path.Match(f.plugin, rec.Plugin); !ok {
+			return false
+		}
+	}
+	if !f.since.IsZero() {
+		t, err := time.Parse(time.RFC3339, rec.Time)
+		if err != nil || t.Before(f.since) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseLogSince accepts a duration back from now (10m, 2h) or a time
+// (RFC 3339, or a local "2006-01-02" or "2006-01-02 15:04").
+func parseLogSince(s string) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("--since=%s is neither a duration (10m, 2h) nor a time (2006-01-02, 2006-01-02 15:04, RFC 3339)", s)
+}
+
+// serveLogFile returns the log serve writes for workspace: the daemon's,
+// a running session's, or the default.
+func serveLogFile(workspace string) string {
+	if st := runningDaemon(workspace); st != nil && st.Log != "" {
+		return st.Log
+	}
+	for _, rec := range liveServeRecords() {
+		if rec.Workspace == workspace && rec.Log != "" {
+			return rec.Log
+		}
+	}
+	return defaultServeLog(workspace)
+}
+
+// RunLogs handles `orchestra logs` -- prints the serve log merged with the
+// plugin logs, filtered, and with --follow keeps printing what is logged.
+func RunLogs(args []string) {
+	fs := newFlagSet("logs")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	logPath := fs.String("log", "", "Read only this log file (default: the serve log serve writes for the workspace, and its plugin logs)")
+	follow := fs.Bool("follow", false, "Keep printing records as they are logged")
+	fs.BoolVar(follow, "f", false, "Shorthand for --follow")
+	since := fs.String("since", "", "Only records since a duration ago (10m, 2h) or a time (2006-01-02 15:04)")
+	level := fs.String("level", "info", "Minimum level: debug, info, warn, or error")
+	plugin := fs.String("plugin", "", "Only records about this plugin ID (path.Match pattern, e.g. tools.*)")
+	source := fs.String("source", "", "Only records from orchestra, orchestrator, or transport")
+	lines := fs.Int("lines", 0, "Print only the last N matching records before following (0 for all)")
+	raw := fs.Bool("raw", false, "Print records as the JSON lines stored in the log")
+	parseFlags(fs, args)
+
+	filter := logFilter{plugin: *plugin, source: *source}
+	if normalizeLogLevel(*level) == "" {
+		fatal("--level must be one of %s", strings.Join(logLevels, ", "))
+	}
+	filter.level = logLevelRank(normalizeLogLevel(*level))
+	switch *source {
+	case "", logSourceOrchestra, logSourceOrchestrator, logSourceTransport:
+	default:
+		fatal("--source must be one of %s, %s, or %s", logSourceOrchestra, logSourceOrchestrator, logSourceTransport)
+	}
+	if *since != "" {
+		t, err := parseLogSince(*since)
+		if err != nil {
+			fatal("%v", err)
+		}
+		filter.since = t
+	}
+
+	file := expandHome(*logPath)
+	pluginDir := ""
+	if file == "" {
+		absWorkspace, err := resolveWorkspace(*workspace)
+		if err != nil {
+			fatal("resolve workspace: %v", err)
+		}
+		file = serveLogFile(absWorkspace)
+		pluginDir = pluginLogDir(absWorkspace)
+	}
+	if _, err := os.Stat(file); err != nil && !(*follow && os.IsNotExist(err)) {
+		fatal("open log: %v", err)
+	}
+
+	p := &logPrinter{raw: *raw, color: useColor() && isTerminal(os.Stdout)}
+	tails := map[string]int64{} // file -> offset read up to
+	var batch []logLine
+	read := func(path string) {
+		f, err := os.Open(path)
+		if err != nil {
+			return
+		}
+		tails[path] = readLogLines(f, tails[path], func(line string) {
+			if rec := readLogRecord(line); filter.match(rec) {
+				batch = append(batch, logLine{rec.Time, line})
+			}
+		})
+		f.Close()
+	}
+	files := append([]string{file}, pluginLogFiles(pluginDir)...)
+	for _, path := range files {
+		read(path)
+	}
+	sortLogLines(batch)
+	if *lines > 0 && len(batch) > *lines {
+		batch = batch[len(batch)-*lines:]
+	}
+	for _, l := range batch {
+		p.print(l.text)
+	}
+	if !*follow {
+		return
+	}
+
+	for {
+		time.Sleep(250 * time.Millisecond)
+		batch = nil
+		for _, path := range append([]string{file}, pluginLogFiles(pluginDir)...) {
+			info, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			if info.Size() < tails[path] {
+				// serve truncates the log when it starts, and removes the
+				// plugin logs.
+				if path == file {
+					fmt.Fprintf(os.Stderr, "%s\n", colorize(ansiDim, "-- log truncated; serve restarted --"))
+					for other := range tails {
+						tails[other] = 0
+					}
+				}
+				tails[path] = 0
+			}
+			if info.Size() != tails[path] {
+				read(path)
+			}
+		}
+		for path := range tails {
+			if path != file {
+				if _, err := os.Stat(path); os.IsNotExist(err) {
+					delete(tails, path)
+				}
+			}
+		}
+		sortLogLines(batch)
+		for _, l := range batch {
+			p.print(l.text)
+		}
+	}
+}
+
+// logLine is a line of a log and the time of its record.
+type logLine struct {
+	time string
+	text string
+}
+
+// sortLogLines orders lines from several logs by time. Records share one
+// UTC time format, so the strings sort as times; lines without one, from
+// a plain-text log, come first in the order they were read.
+func sortLogLines(lines []logLine) {
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].time < lines[j].time })
+}
+
+// readLogLines calls fn for each complete line of f after offset and
+// returns the offset after the last one; a line still being written is
+// left for the next call.
+func readLogLines(f *os.File, offset int64, fn func(line string)) int64 {
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return offset
+	}
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return offset
+		}
+		offset += int64(len(line))
+		if line = strings.TrimRight(line, "\r\n"); line != "" {
+			fn(line)
+		}
+	}
+}
+
+// logPrinter renders records for a person, or as stored with raw.
+type logPrinter struct {
+	raw   bool
+	color bool
+}
+
+func (p *logPrinter) paint(code, s string) string {
+	if !p.color {
+		return s
+	}
+	return code + s + ansiReset
+}
+
+func (p *logPrinter) print(line string) {
+	if p.raw {
+		fmt.Fprintln(os.Stdout, line)
+		return
+	}
+	rec := readLogRecord(line)
+	if rec.Time == "" {
+		fmt.Fprintln(os.Stdout, rec.Msg) // a line of a plain-text log
+		return
+	}
+	stamp := rec.Time
+	if t, err := time.Parse(time.RFC3339, rec.Time); err == nil {
+		stamp = t.Local().Format("2006-01-02 15:04:05.000")
+	}
+	level := fmt.Sprintf("%-5s", strings.ToUpper(rec.Level))
+	switch rec.Level {
+	case "error":
+		level = p.paint(ansiRed, level)
+	case "warn":
+		level = p.paint(ansiYellow, level)
+	case "debug":
+		level = p.paint(ansiDim, level)
+	}
+	who := rec.Source
+	if rec.Plugin != "" {
+		who += "/" + rec.Plugin
+	}
+	msg := rec.Msg
+	if len(rec.Attrs) > 0 {
+		keys := make([]string, 0, len(rec.Attrs))
+		for k := range rec.Attrs {
+			keys = append(keys, k)
+		}
+		sortNames(keys)
+		var attrs []string
+		for _, k := range keys {
+			attrs = append(attrs, fmt.Sprintf("%s=%v", k, rec.Attrs[k]))
+		}
+		msg += " " + p.paint(ansiDim, strings.Join(attrs, " "))
+	}
+	fmt.Fprintf(os.Stdout, "%s %s %s %s\n", p.paint(ansiDim, stamp), level, p.paint(ansiCyan, who), msg)
+}
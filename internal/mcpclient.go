@@ -0,0 +1,127 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// rpcMessage is a JSON-RPC 2.0 request, response, or notification as MCP
+// exchanges them over stdio, one per line.
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int            `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  any             `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// mcpClient is a minimal MCP client for talking to a server over a stdio
+// pipe: enough to initialize and list what it exposes.
+type mcpClient struct {
+	w      io.Writer
+	r      *bufio.Reader
+	nextID int
+}
+
+func newMCPClient(w io.Writer, r io.Reader) *mcpClient {
+	return &mcpClient{w: w, r: bufio.NewReader(r)}
+}
+
+// initialize performs the MCP handshake, offering the newest revision this
+// build speaks.
+func (c *mcpClient) initialize() error {
+	params := map[string]any{
+		"protocolVersion": mcpRevisions[len(mcpRevisions)-1],
+		"capabilities":    map[string]any{},
+		"clientInfo":      map[string]any{"name": "orchestra", "version": Version},
+	}
+	if err := c.call("initialize", params, nil); err != nil {
+		return fmt.Errorf("initialize: %w", err)
+	}
+	return c.notify("notifications/initialized", nil)
+}
+
+// listTools returns the names of every tool the server exposes, following
+// pagination.
+func (c *mcpClient) listTools() ([]string, error) {
+	var names []string
+	cursor := ""
+	for {
+		var params any
+		if cursor != "" {
+			params = map[string]any{"cursor": cursor}
+		}
+		var page struct {
+			Tools []struct {
+				Name string `json:"name"`
+			} `json:"tools"`
+			NextCursor string `json:"nextCursor"`
+		}
+		if err := c.call("tools/list", params, &page); err != nil {
+			return nil, fmt.Errorf("tools/list: %w", err)
+		}
+		for _, t := range page.Tools {
+			names = append(names, t.Name)
+		}
+		if page.NextCursor == "" || page.NextCursor == cursor {
+			return names, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// call sends a request and decodes the matching response's result into
+// result (when non-nil). Notifications and other messages read while
+// waiting are skipped.
+func (c *mcpClient) call(method string, params, result any) error {
+	c.nextID++
+	id := c.nextID
+	if err := c.send(rpcMessage{JSONRPC: "2.0", ID: &id, Method: method, Params: params}); err != nil {
+		return err
+	}
+	for {
+		line, err := c.r.ReadBytes('\n')
+		if len(line) == 0 && err != nil {
+			if err == io.EOF {
+				return fmt.Errorf("server closed the connection")
+			}
+			return err
+		}
+		var msg rpcMessage
+		if json.Unmarshal(line, &msg) != nil || msg.ID == nil || *msg.ID != id || msg.Method != "" {
+			continue
+		}
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if result == nil {
+			return nil
+		}
+		return json.Unmarshal(msg.Result, result)
+	}
+}
+
+func (c *mcpClient) notify(method string, params any) error {
+	return c.send(rpcMessage{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+func (c *mcpClient) send(msg rpcMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = c.w.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Inline Markdown handled by renderMarkdown.
+var (
+	mdLink   = regexp.MustCompile(`\[([^\]]+)\]\((\S+?)\)`)
+	mdBold   = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	mdCode   = regexp.MustCompile("`([^`]+)`")
+	mdImage  = regexp.MustCompile(`!\[[^\]]*\]\([^)]*\)`)
+	mdHTML   = regexp.MustCompile(`<!--.*?-->|</?[a-zA-Z][^>]*>`)
+	mdBullet = regexp.MustCompile(`^(\s*)[-*+]\s+`)
+)
+
+// renderMarkdown writes md for a terminal, each line prefixed with indent:
+// headings in bold, bullets as •, code in cyan, links as "text (url)".
+// Images and HTML are dropped. Without color the text stays readable, just
+// without emphasis. Under --plain, rules are dropped, quotes lose their bar,
+// and bullets are dashes. It covers what release notes use, not all of
+// Markdown.
+func renderMarkdown(w io.Writer, md, indent string) {
+	rule, quote, bullet := strings.Repeat("─", 40), "│ ", "• "
+	if globals.plain {
+		rule, quote, bullet = "", "", "- "
+	}
+	inFence := false
+	blank := true
+	for _, line := range strings.Split(strings.ReplaceAll(md, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			fmt.Fprintf(w, "%s  %s\n", indent, colorize(ansiDim, line))
+			continue
+		}
+
+		line = mdHTML.ReplaceAllString(mdImage.ReplaceAllString(line, ""), "")
+		trimmed = strings.TrimSpace(line)
+		if trimmed == "" {
+			// Collapse runs of blank lines, including ones left by HTML.
+			if !blank {
+				fmt.Fprintln(w)
+			}
+			blank = true
+			continue
+		}
+		blank = false
+
+		switch {
+		case strings.HasPrefix(trimmed, "#"):
+			heading := strings.TrimSpace(strings.TrimLeft(trimmed, "#"))
+			fmt.Fprintf(w, "%s%s\n", indent, colorize(ansiBold, renderInline(heading)))
+		case trimmed == "---" || trimmed == "***":
+			if rule != "" {
+				fmt.Fprintf(w, "%s%s\n", indent, colorize(ansiDim, rule))
+			}
+		case strings.HasPrefix(trimmed, ">"):
+			fmt.Fprintf(w, "%s%s%s\n", indent, quote, renderInline(strings.TrimSpace(strings.TrimPrefix(trimmed, ">"))))
+		case mdBullet.MatchString(line):
+			m := mdBullet.FindStringSubmatch(line)
+			fmt.Fprintf(w, "%s%s%s%s\n", indent, m[1], bullet, renderInline(line[len(m[0]):]))
+		default:
+			fmt.Fprintf(w, "%s%s\n", indent, renderInline(trimmed))
+		}
+	}
+}
+
+// renderInline applies bold, code, and link formatting to one line.
+func renderInline(s string) string {
+	s = mdLink.ReplaceAllStringFunc(s, func(m string) string {
+		sub := mdLink.FindStringSubmatch(m)
+		if sub[1] == sub[2] {
+			return sub[2]
+		}
+		return sub[1] + " (" + sub[2] + ")"
+	})
+	s = mdCode.ReplaceAllStringFunc(s, func(m string) string {
+		return colorize(ansiCyan, strings.Trim(m, "`"))
+	})
+	return mdBold.ReplaceAllStringFunc(s, func(m string) string {
+		return colorize(ansiBold, strings.Trim(m, "*_"))
+	})
+}
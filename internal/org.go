@@ -0,0 +1,500 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// A platform team can manage defaults for every developer from one repo.
+// `orchestra org sync --from=<repo>` clones it, reads settings.yaml at its
+// root, and stores the result under org: in the global config:
+//
+//	allowed_sources: [github.com/mycorp/*, github.com/orchestra-mcp/*]
+//	default_packs: [github.com/mycorp/pack-go]
+//	policy:
+//	  require_signed: true      # pack installs act as --require-signed
+//	  post_install: never       # pack post-install scripts never run
+//	  conventions: {branch: "...", commit: "..."}
+//	labels: {frontend: {color: blue}}
+//	assignees: [alice, bob]
+//	defaults: {ide: cursor}
+//
+// The settings sit under the user's own: a workspace's labels and
+// assignees are added to the org's, its conventions win over the org's,
+// and defaults: in either config file win over the org's defaults. The
+// allowed sources and the policy are not overridable. Commands refresh
+// the settings in the background once they are older than org.refresh.
+
+const (
+	orgSettingsFile     = "settings.yaml"
+	defaultOrgRefresh   = "24h"
+	orgPostInstallNever = "never"
+)
+
+// errSourceNotAllowed is returned for a repo outside the org's allowed
+// sources.
+var errSourceNotAllowed = errors.New("not an allowed source")
+
+// orgConfig is the org: section of the global config.
+type orgConfig struct {
+	Source    string      `yaml:"source,omitempty"`     // settings repo[@ref], or a directory
+	Refresh   string      `yaml:"refresh,omitempty"`    // how often to sync again; 0 never
+	SyncedAt  string      `yaml:"synced_at,omitempty"`  // last successful sync
+	CheckedAt string      `yaml:"checked_at,omitempty"` // last attempt
+	Commit    string      `yaml:"commit,omitempty"`     // settings repo commit synced
+	Error     string      `yaml:"error,omitempty"`      // why the last attempt failed
+	Settings  orgSettings `yaml:"settings,omitempty"`
+}
+
+// orgSettings is the shared settings.yaml.
+type orgSettings struct {
+	AllowedSources []string            `yaml:"allowed_sources,omitempty"` // path.Match patterns; a trailing /** matches any depth
+	DefaultPacks   []string            `yaml:"default_packs,omitempty"`   // installed by orchestra init
+	Policy         orgPolicy           `yaml:"policy,omitempty"`
+	Labels         map[string]labelDef `yaml:"labels,omitempty"`
+	Assignees      []string            `yaml:"assignees,omitempty"`
+	Defaults       map[string]string   `yaml:"defaults,omitempty"` // flag name -> value, under both config files
+}
+
+// orgPolicy is the workflow policy every developer gets.
+type orgPolicy struct {
+	RequireSigned bool             `yaml:"require_signed,omitempty"`
+	PostInstall   string           `yaml:"post_install,omitempty"` // "never", or empty to ask
+	Conventions   conventionConfig `yaml:"conventions,omitempty"`  // for workspaces without their own
+}
+
+// loadOrgSettings returns the synced org settings, or nil when none are.
+func loadOrgSettings() *orgSettings {
+	org := loadGlobalConfig().Org
+	if org.Source == "" {
+		return nil
+	}
+	return &org.Settings
+}
+
+// checkOrgSettings validates settings before they are stored.
+func checkOrgSettings(s *orgSettings) error {
+	for _, p := range s.AllowedSources {
+		if _, err := path.Match(strings.TrimSuffix(p, "/**"), ""); err != nil {
+			return fmt.Errorf("allowed_sources: bad pattern %q", p)
+		}
+	}
+	for _, repo := range s.DefaultPacks {
+		if _, err := parseRepoRef(repo); err != nil {
+			return fmt.Errorf("default_packs: %v", err)
+		}
+		if !sourceAllowed(s.AllowedSources, repo) {
+			return fmt.Errorf("default_packs: %s is not in allowed_sources", repo)
+		}
+	}
+	switch s.Policy.PostInstall {
+	case "", orgPostInstallNever:
+	default:
+		return fmt.Errorf("policy.post_install must be %s or empty", orgPostInstallNever)
+	}
+	for name, d := range s.Labels {
+		if err := checkLabelName(name); err != nil {
+			return fmt.Errorf("labels: %v", err)
+		}
+		if err := checkLabelColor(d.Color); err != nil {
+			return fmt.Errorf("labels.%s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// sourceAllowed reports whether repo matches one of patterns, or whether
+// there are none.
+func sourceAllowed(patterns []string, repo string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	name := repo
+	if r, err := parseRepoRef(repo); err == nil {
+		name = r.String()
+	}
+	for _, p := range patterns {
+		if prefix, ok := strings.CutSuffix(p, "/**"); ok {
+			if ok, _ := path.Match(prefix, name); ok || strings.HasPrefix(name, prefix+"/") {
+				return true
+			}
+			continue
+		}
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// checkAllowedSource returns errSourceNotAllowed for a repo the org does
+// not allow installing from.
+func checkAllowedSource(repo string) error {
+	org := loadOrgSettings()
+	if org == nil || sourceAllowed(org.AllowedSources, repo) {
+		return nil
+	}
+	return fmt.Errorf("%s: %w in the org settings (allowed: %s)", repo, errSourceNotAllowed, strings.Join(org.AllowedSources, ", "))
+}
+
+// withOrgPolicy returns opts tightened by the org's policy.
+func withOrgPolicy(opts packInstallOptions) packInstallOptions {
+	org := loadOrgSettings()
+	if org == nil {
+		return opts
+	}
+	if org.Policy.RequireSigned && !opts.Embedded {
+		opts.RequireSigned = true // the embedded copy comes with the binary
+	}
+	if org.Policy.PostInstall == orgPostInstallNever {
+		opts.AllowPostInstall, opts.SkipPostInstall = false, true
+	}
+	return opts
+}
+
+// mergeOrgSettings layers org settings under the workspace's cfg.
+func mergeOrgSettings(cfg *workspaceConfig, org *orgSettings) {
+	for name, d := range org.Labels {
+		if _, ok := cfg.Labels[name]; !ok {
+			if cfg.Labels == nil {
+				cfg.Labels = map[string]labelDef{}
+			}
+			cfg.Labels[name] = d
+		}
+	}
+	for _, a := range org.Assignees {
+		if !containsString(cfg.Assignees, a) {
+			cfg.Assignees = append(cfg.Assignees, a)
+		}
+	}
+	if cfg.Conventions.Branch == "" {
+		cfg.Conventions.Branch = org.Policy.Conventions.Branch
+	}
+	if cfg.Conventions.Commit == "" {
+		cfg.Conventions.Commit = org.Policy.Conventions.Commit
+	}
+}
+
+// isOrgLabel reports whether label comes from the org settings rather than
+// the workspace's own taxonomy.
+func isOrgLabel(label string) bool {
+	org := loadOrgSettings()
+	if org == nil {
+		return false
+	}
+	_, ok := org.Labels[label]
+	return ok
+}
+
+// installOrgPacks installs the org's default packs that workspace does not
+// have yet. A failure is reported and the next pack tried.
+func installOrgPacks(workspace string) {
+	org := loadOrgSettings()
+	if org == nil || len(org.DefaultPacks) == 0 {
+		return
+	}
+	reg := loadPackRegistry(workspace)
+	for _, repo := range org.DefaultPacks {
+		if name, existing := reg.FindByRepo(repo); existing != nil {
+			printStatus(tagSkip, "%s already installed (%s)", name, existing.Version)
+			continue
+		}
+		manifest, rev, err := installPackFromGit(workspace, repo, "", packInstallOptions{})
+		if err != nil {
+			printStatus(tagFail, "%s: %v", repo, err)
+			continue
+		}
+		recordInstalledPack(workspace, repo, manifest, "", rev, packInstallOptions{})
+		printStatus(tagOK, "%s@%s (org default)", manifest.Name, manifest.Version)
+	}
+}
+
+// --- sync ---
+
+// fetchOrgSettings reads settings.yaml from source: a directory, or a repo
+// cloned at an optional @ref. It returns the settings and the commit read.
+func fetchOrgSettings(source string) (*orgSettings, string, error) {
+	var data []byte
+	var commit string
+	if info, err := os.Stat(source); err == nil && info.IsDir() {
+		var err error
+		if data, err = os.ReadFile(filepath.Join(source, orgSettingsFile)); err != nil {
+			return nil, "", err
+		}
+	} else {
+		repo, version := parsePackRepoVersion(source)
+		rev, err := withPackClone(repo, version, func(src fs.FS) error {
+			var err error
+			data, err = fs.ReadFile(src, orgSettingsFile)
+			return err
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		commit = rev.Commit
+	}
+
+	s := &orgSettings{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(s); err != nil && !errors.Is(err, io.EOF) {
+		return nil, "", fmt.Errorf("%s: %w", orgSettingsFile, err)
+	}
+	if err := checkOrgSettings(s); err != nil {
+		return nil, "", fmt.Errorf("%s: %w", orgSettingsFile, err)
+	}
+	return s, commit, nil
+}
+
+// saveOrgConfig writes org into the global config, leaving the rest of the
+// file as it is.
+func saveOrgConfig(org *orgConfig) error {
+	cf, err := loadConfigFile(globalConfigPath(), "global")
+	if err != nil {
+		return err
+	}
+	if org == nil {
+		cf.unset([]string{"org"})
+	} else {
+		var node yaml.Node
+		if err := node.Encode(org); err != nil {
+			return err
+		}
+		cf.set([]string{"org"}, &node)
+	}
+	if err := os.MkdirAll(filepath.Dir(globalConfigPath()), 0755); err != nil {
+		return err
+	}
+	return cf.save()
+}
+
+// syncOrg fetches org's settings and stores them, recording the attempt
+// either way.
+func syncOrg(org orgConfig, now time.Time) (orgConfig, error) {
+	org.CheckedAt = now.UTC().Format(time.RFC3339)
+	settings, commit, err := fetchOrgSettings(org.Source)
+	if err != nil {
+		org.Error = err.Error()
+	} else {
+		org.Settings, org.Commit, org.Error = *settings, commit, ""
+		org.SyncedAt = org.CheckedAt
+	}
+	if saveErr := saveOrgConfig(&org); saveErr != nil && err == nil {
+		err = saveErr
+	}
+	return org, err
+}
+
+func checkOrgRefresh(v string) error {
+	if v == "0" {
+		return nil
+	}
+	if _, err := parseSince(v, time.Now()); err != nil {
+		return fmt.Errorf("org.refresh: %v (e.g. 24h, 7d, or 0 for never)", err)
+	}
+	return nil
+}
+
+// orgRefreshDue reports whether org's settings are older than its refresh
+// interval, counting failed attempts so an offline machine does not retry
+// on every command.
+func orgRefreshDue(org orgConfig, now time.Time) bool {
+	refresh := org.Refresh
+	if refresh == "" {
+		refresh = defaultOrgRefresh
+	}
+	if org.Source == "" || refresh == "0" {
+		return false
+	}
+	cutoff, err := parseSince(refresh, now)
+	if err != nil {
+		return false
+	}
+	last, err := time.Parse(time.RFC3339, org.CheckedAt)
+	return err != nil || last.Before(cutoff)
+}
+
+// refreshOrgInBackground starts `orchestra org sync --quiet` when the org
+// settings are due for a refresh. The command that triggered it goes on
+// with the settings it has; the sync finishes on its own.
+func refreshOrgInBackground() {
+	data, err := os.ReadFile(globalConfigPath())
+	if err != nil {
+		return
+	}
+	// Parsed on its own and leniently, like the proxy settings: a malformed
+	// config is reported by the command that reads all of it (or by doctor),
+	// not by every command.
+	var cfg struct {
+		Org orgConfig `yaml:"org"`
+	}
+	if yaml.Unmarshal(data, &cfg) != nil {
+		return
+	}
+	org := cfg.Org
+	now := time.Now()
+	if !orgRefreshDue(org, now) {
+		return
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return
+	}
+	// Claim this refresh, so commands run meanwhile do not start another.
+	org.CheckedAt = now.UTC().Format(time.RFC3339)
+	if saveOrgConfig(&org) != nil {
+		return
+	}
+	cmd := exec.Command(self, "org", "sync", "--quiet")
+	if cmd.Start() == nil {
+		debugf("org settings: refreshing from %s in the background (pid %d)", org.Source, cmd.Process.Pid)
+		cmd.Process.Release()
+	}
+}
+
+// --- commands ---
+
+func runOrgSync(args []string) {
+	fs := newFlagSet("org sync")
+	from := fs.String("from", "", "Settings repo (host/owner/repo[@ref]) or directory; defaults to the one synced before")
+	refresh := fs.String("refresh", "", "Sync again in the background once the settings are older than this (default 24h; 0 never)")
+	quiet := fs.Bool("quiet", false, "Print nothing unless the sync fails")
+	parseFlags(fs, args)
+
+	org := loadGlobalConfig().Org
+	if *from != "" {
+		source := *from
+		if info, err := os.Stat(source); err == nil && info.IsDir() {
+			source, _ = filepath.Abs(source)
+		}
+		if source != org.Source {
+			org = orgConfig{Source: source, Refresh: org.Refresh}
+		}
+	}
+	if org.Source == "" {
+		fatal("no org settings yet; run 'orchestra org sync --from=<repo>'")
+	}
+	if *refresh != "" {
+		if err := checkOrgRefresh(*refresh); err != nil {
+			fatal("--refresh: %v", err)
+		}
+		org.Refresh = *refresh
+	}
+
+	var sp *spinner
+	if !*quiet {
+		sp = startSpinner("Syncing org settings from " + redactURL(org.Source))
+	}
+	org, err := syncOrg(org, time.Now())
+	if sp != nil {
+		sp.Stop(err)
+	}
+	if err != nil {
+		fatal("sync %s: %v", redactURL(org.Source), err)
+	}
+	if *quiet {
+		return
+	}
+	at := org.Commit
+	if len(at) > 12 {
+		at = at[:12]
+	}
+	printStatus(tagOK, "org settings from %s %s", redactURL(org.Source), orDash(at))
+	printOrgSettings(&org.Settings)
+}
+
+func runOrgStatus(args []string) {
+	fs := newFlagSet("org status")
+	parseFlags(fs, args)
+
+	org := loadGlobalConfig().Org
+	if org.Source == "" {
+		fmt.Fprintln(os.Stderr, "No org settings. Sync them with 'orchestra org sync --from=<repo>'.")
+		return
+	}
+	now := time.Now()
+	refresh := org.Refresh
+	if refresh == "" {
+		refresh = defaultOrgRefresh
+	}
+	fmt.Fprintf(os.Stdout, "Source:    %s\n", redactURL(org.Source))
+	fmt.Fprintf(os.Stdout, "Commit:    %s\n", orDash(org.Commit))
+	synced := "never"
+	if t, err := time.Parse(time.RFC3339, org.SyncedAt); err == nil {
+		synced = formatAge(now.Sub(t)) + " ago"
+	}
+	fmt.Fprintf(os.Stdout, "Synced:    %s\n", synced)
+	if refresh == "0" {
+		fmt.Fprintf(os.Stdout, "Refresh:   never (run 'orchestra org sync')\n")
+	} else {
+		fmt.Fprintf(os.Stdout, "Refresh:   every %s\n", refresh)
+	}
+	if org.Error != "" {
+		fmt.Fprintf(os.Stdout, "Last sync: failed: %s\n", org.Error)
+	}
+	printOrgSettings(&org.Settings)
+}
+
+func runOrgLeave(args []string) {
+	fs := newFlagSet("org leave")
+	parseFlags(fs, args)
+
+	org := loadGlobalConfig().Org
+	if org.Source == "" {
+		printStatus(tagSkip, "no org settings to remove")
+		return
+	}
+	if err := saveOrgConfig(nil); err != nil {
+		fatal("%v", err)
+	}
+	printStatus(tagOK, "removed the org settings from %s", redactURL(org.Source))
+}
+
+// printOrgSettings summarizes s for sync and status.
+func printOrgSettings(s *orgSettings) {
+	orAll := func(vals []string) string {
+		if len(vals) == 0 {
+			return "any"
+		}
+		return strings.Join(vals, ", ")
+	}
+	tw := newTable(os.Stdout)
+	fmt.Fprintf(tw, "  Allowed sources\t%s\n", orAll(s.AllowedSources))
+	fmt.Fprintf(tw, "  Default packs\t%s\n", orDash(strings.Join(s.DefaultPacks, ", ")))
+	var policy []string
+	if s.Policy.RequireSigned {
+		policy = append(policy, "signed packs only")
+	}
+	if s.Policy.PostInstall == orgPostInstallNever {
+		policy = append(policy, "no post-install scripts")
+	}
+	if s.Policy.Conventions.Branch != "" || s.Policy.Conventions.Commit != "" {
+		policy = append(policy, "branch/commit conventions")
+	}
+	fmt.Fprintf(tw, "  Policy\t%s\n", orDash(strings.Join(policy, ", ")))
+	labels := make([]string, 0, len(s.Labels))
+	for name := range s.Labels {
+		labels = append(labels, name)
+	}
+	sortNames(labels)
+	fmt.Fprintf(tw, "  Labels\t%s\n", orDash(strings.Join(labels, ", ")))
+	fmt.Fprintf(tw, "  Assignees\t%s\n", orDash(strings.Join(s.Assignees, ", ")))
+	defaults := make([]string, 0, len(s.Defaults))
+	for name, v := range s.Defaults {
+		defaults = append(defaults, name+"="+v)
+	}
+	sortNames(defaults)
+	fmt.Fprintf(tw, "  Defaults\t%s\n", orDash(strings.Join(defaults, ", ")))
+	tw.Flush()
+}
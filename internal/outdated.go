@@ -0,0 +1,286 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/orchestra-mcp/cli/pkg/packs"
+)
+
+// releaseCacheTTL is how long a looked-up latest version is reused before
+// GitHub is asked again.
+const releaseCacheTTL = 6 * time.Hour
+
+// outdatedRow is one installed pack or plugin compared with upstream.
+type outdatedRow struct {
+	Kind      string // "pack" or "plugin"
+	Name      string
+	Repo      string
+	Current   string
+	Latest    string    // "" when upstream could not be checked
+	Published time.Time // when Latest was released, if known
+}
+
+func (r outdatedRow) outdated() bool {
+	return r.Latest != "" && isNewerVersion(r.Current, r.Latest)
+}
+
+func (r outdatedRow) status() string {
+	switch {
+	case r.Latest == "":
+		return "unknown"
+	case r.outdated():
+		return "outdated"
+	}
+	return "ok"
+}
+
+// outdatedJSON is an outdatedRow as `outdated --json` prints it.
+type outdatedJSON struct {
+	Kind      string     `json:"kind"`
+	Name      string     `json:"name"`
+	Repo      string     `json:"repo"`
+	Current   string     `json:"current"`
+	Latest    string     `json:"latest"`    // "" when upstream could not be checked
+	Published *time.Time `json:"published"` // null when unknown
+	Status    string     `json:"status"`    // ok, outdated, or unknown
+}
+
+// RunOutdated handles `orchestra outdated` -- compares every installed pack
+// and plugin with the latest upstream version and exits 1 when anything
+// is behind.
+func RunOutdated(args []string) {
+	fs := newFlagSet("outdated")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	refresh := fs.Bool("refresh", false, "Ignore cached versions and ask GitHub again")
+	porcelain := fs.Bool("porcelain", false, "Stable tab-separated output for scripts")
+	parseFlags(fs, args)
+
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+
+	var rows []outdatedRow
+	reg := loadPackRegistry(absWorkspace)
+	for _, name := range reg.Names() {
+		e := reg.Packs[name]
+		rows = append(rows, outdatedRow{Kind: "pack", Name: name, Repo: e.Repo, Current: e.Version})
+	}
+	if preg, err := LoadRegistry(); err == nil {
+		for _, p := range sortedPlugins(preg) {
+			if p.Source == pluginSourceLocal {
+				continue // no upstream; update rebuilds it from its directory
+			}
+			rows = append(rows, outdatedRow{Kind: "plugin", Name: p.ID, Repo: p.Repo, Current: p.Version})
+		}
+	}
+	if len(rows) == 0 {
+		if jsonOutput() {
+			printJSON([]outdatedJSON{})
+		} else if !*porcelain {
+			fmt.Fprintf(os.Stderr, "No packs or plugins installed.\n")
+		}
+		return
+	}
+
+	sp := startSpinner("Checking upstream versions")
+	cache := loadReleaseCache()
+	var wg sync.WaitGroup
+	for i := range rows {
+		wg.Add(1)
+		go func(r *outdatedRow) {
+			defer wg.Done()
+			if rel, ok := cache.lookup(r.Repo, r.Kind, *refresh); ok {
+				r.Latest, r.Published = rel.Version, rel.Published
+			}
+		}(&rows[i])
+	}
+	wg.Wait()
+	cache.save()
+	sp.Stop(nil)
+
+	outdated, unknown := 0, 0
+	for _, r := range rows {
+		if r.outdated() {
+			outdated++
+		}
+		if r.Latest == "" {
+			unknown++
+		}
+	}
+
+	// Porcelain: kind, name, current, latest, status, latest release date.
+	if jsonOutput() {
+		list := []outdatedJSON{}
+		for _, r := range rows {
+			j := outdatedJSON{Kind: r.Kind, Name: r.Name, Repo: r.Repo, Current: r.Current, Latest: r.Latest, Status: r.status()}
+			if !r.Published.IsZero() {
+				j.Published = &r.Published
+			}
+			list = append(list, j)
+		}
+		printJSON(list)
+	} else if *porcelain {
+		for _, r := range rows {
+			published := ""
+			if !r.Published.IsZero() {
+				published = r.Published.UTC().Format(time.RFC3339)
+			}
+			fmt.Fprintf(os.Stdout, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Kind, r.Name, r.Current, r.Latest, r.status(), published)
+		}
+	} else {
+		tw := newTable(os.Stdout)
+		fmt.Fprintf(tw, "KIND\tNAME\tCURRENT\tLATEST\tBEHIND\tSTATUS\n")
+		for _, r := range rows {
+			behind := "-"
+			if r.outdated() && !r.Published.IsZero() {
+				behind = formatAge(time.Since(r.Published))
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Kind, r.Name, orDash(r.Current), orDash(r.Latest), behind, r.status())
+		}
+		tw.Flush()
+		fmt.Fprintf(os.Stderr, "\n")
+		if unknown > 0 {
+			printStatus(tagWarn, "%d could not be checked (offline, rate-limited, or not on GitHub)", unknown)
+		}
+		if outdated > 0 {
+			printStatus(tagFail, "%d outdated. Run 'orchestra pack update' and 'orchestra update <plugin>'", outdated)
+		} else {
+			printStatus(tagOK, "everything checked is up to date")
+		}
+	}
+
+	if outdated > 0 {
+		os.Exit(1)
+	}
+}
+
+// formatAge renders a duration in days, or hours when under a day.
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Hour:
+		return "<1h"
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
+
+// --- release cache ---
+
+// cachedRelease is the latest upstream version of a repo as of Checked.
+type cachedRelease struct {
+	Version   string    `json:"version"`
+	Published time.Time `json:"published,omitempty"`
+	Checked   time.Time `json:"checked"`
+}
+
+// releaseCache is ~/.orchestra/cache/releases.json: latest versions by
+// repo, shared by `orchestra outdated` runs so scheduled checks stay
+// within GitHub's rate limits.
+type releaseCache struct {
+	mu      sync.Mutex
+	Repos   map[string]cachedRelease `json:"repos"`
+	changed bool
+}
+
+func releaseCachePath() string {
+	return filepath.Join(orchestraCacheDir(), "releases.json")
+}
+
+// loadReleaseCache reads the cache; a missing or unreadable file is empty.
+func loadReleaseCache() *releaseCache {
+	c := &releaseCache{}
+	if data, err := os.ReadFile(releaseCachePath()); err == nil {
+		json.Unmarshal(data, c)
+	}
+	if c.Repos == nil {
+		c.Repos = map[string]cachedRelease{}
+	}
+	return c
+}
+
+func (c *releaseCache) save() {
+	if !c.changed {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(releaseCachePath()), 0755); err != nil {
+		return
+	}
+	data, _ := json.MarshalIndent(c, "", "  ")
+	writeFileAtomic(releaseCachePath(), append(data, '\n'), 0644)
+}
+
+// lookup returns the latest version of repo from the cache, or from GitHub
+// when the cached answer is missing, older than releaseCacheTTL, or
+// refresh is set. Failed lookups are not cached.
+func (c *releaseCache) lookup(repo, kind string, refresh bool) (cachedRelease, bool) {
+	c.mu.Lock()
+	rel, ok := c.Repos[repo]
+	c.mu.Unlock()
+	if ok && !refresh && time.Since(rel.Checked) < releaseCacheTTL {
+		return rel, true
+	}
+
+	ownerRepo, err := githubOwnerRepo(repo)
+	if err != nil {
+		return cachedRelease{}, false
+	}
+	rel, ok = latestRelease(ownerRepo)
+	if !ok && kind == "pack" {
+		rel, ok = latestPackVersion(ownerRepo)
+	}
+	if !ok {
+		return cachedRelease{}, false
+	}
+	rel.Checked = time.Now().UTC()
+	c.mu.Lock()
+	c.Repos[repo] = rel
+	c.changed = true
+	c.mu.Unlock()
+	return rel, true
+}
+
+// latestRelease asks the GitHub API for ownerRepo's latest release.
+func latestRelease(ownerRepo string) (cachedRelease, bool) {
+	resp, err := githubGetCached(githubAPIURL("/repos/"+ownerRepo+"/releases/latest"), 10*time.Second)
+	if err != nil {
+		return cachedRelease{}, false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return cachedRelease{}, false
+	}
+	var release struct {
+		TagName     string    `json:"tag_name"`
+		PublishedAt time.Time `json:"published_at"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil || release.TagName == "" {
+		return cachedRelease{}, false
+	}
+	return cachedRelease{Version: release.TagName, Published: release.PublishedAt}, true
+}
+
+// latestPackVersion reads the version in pack.json on the default branch,
+// for packs that publish without GitHub releases.
+func latestPackVersion(ownerRepo string) (cachedRelease, bool) {
+	resp, err := githubGetCached("https://raw.githubusercontent.com/"+ownerRepo+"/HEAD/pack.json", 10*time.Second)
+	if err != nil {
+		return cachedRelease{}, false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return cachedRelease{}, false
+	}
+	var m packs.Manifest
+	if err := json.NewDecoder(resp.Body).Decode(&m); err != nil || m.Version == "" {
+		return cachedRelease{}, false
+	}
+	return cachedRelease{Version: m.Version}, true
+}
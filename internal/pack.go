@@ -1,133 +1,248 @@
 package internal
 
 import (
-	"encoding/json"
-	"flag"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"os/exec"
-	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
-	"time"
-)
-
-// packManifest is the parsed pack.json from a pack repo.
-type packManifest struct {
-	Name        string   `json:"name"`
-	Description string   `json:"description"`
-	Version     string   `json:"version"`
-	Stacks      []string `json:"stacks"`
-	Contents    struct {
-		Skills []string `json:"skills"`
-		Agents []string `json:"agents"`
-		Hooks  []string `json:"hooks"`
-	} `json:"contents"`
-	Tags []string `json:"tags"`
-}
-
-// packEntry describes an installed pack in the local registry.
-type packEntry struct {
-	Version     string   `json:"version"`
-	Repo        string   `json:"repo"`
-	InstalledAt string   `json:"installed_at"`
-	Stacks      []string `json:"stacks"`
-	Skills      []string `json:"skills"`
-	Agents      []string `json:"agents"`
-	Hooks       []string `json:"hooks"`
-}
-
-// packRegistry holds the local pack registry.
-type packRegistry struct {
-	Packs map[string]*packEntry `json:"packs"`
-}
-
-// RunPack handles `orchestra pack <subcommand>`.
-func RunPack(args []string) {
-	if len(args) < 1 {
-		printPackUsage()
-		return
-	}
 
-	switch args[0] {
-	case "install":
-		runPackInstall(args[1:])
-	case "remove", "uninstall":
-		runPackRemove(args[1:])
-	case "update":
-		runPackUpdate(args[1:])
-	case "list", "ls":
-		runPackList(args[1:])
-	case "search":
-		runPackSearch(args[1:])
-	case "recommend":
-		runPackRecommend(args[1:])
-	case "help", "--help", "-h":
-		printPackUsage()
-	default:
-		fmt.Fprintf(os.Stderr, "unknown pack subcommand: %s\n\n", args[0])
-		printPackUsage()
-		os.Exit(1)
-	}
-}
+	"github.com/orchestra-mcp/cli/pkg/packs"
+)
 
-func printPackUsage() {
-	fmt.Fprintf(os.Stderr, `orchestra pack — manage content packs (skills, agents, hooks)
+// packInstallOptions controls the checks installPackFromFS applies and
+// whether the post-install script runs.
+type packInstallOptions struct {
+	Force         bool   // install incompatible or conflicting packs with a warning
+	RequireSigned bool   // refuse unsigned packs and untrusted publishers
+	Embedded      bool   // the copy shipped in the binary; skip signature checks
+	OS            string // GOOS to pick hook variants for; empty is the running OS
 
-Usage:
-  orchestra pack install <repo>[@version]   Install a pack from GitHub
-  orchestra pack remove <name>              Remove an installed pack
-  orchestra pack update [name]              Update one or all packs
-  orchestra pack list                       List installed packs
-  orchestra pack search <query>             Search available packs
-  orchestra pack recommend                  Detect stacks & recommend packs
+	AllowPostInstall bool // run post_install without asking
+	SkipPostInstall  bool // never run post_install
 
-Examples:
-  orchestra pack install github.com/orchestra-mcp/pack-go-backend
-  orchestra pack install github.com/orchestra-mcp/pack-essentials@v0.1.0
-  orchestra pack remove orchestra-mcp/pack-go-backend
-  orchestra pack search go
-  orchestra pack recommend
-`)
+	Constraint string // version range the pack was installed for; recorded for pack update
 }
 
 // --- install ---
 
 func runPackInstall(args []string) {
-	fs := flag.NewFlagSet("pack install", flag.ExitOnError)
+	fs := newFlagSet("pack install")
 	workspace := fs.String("workspace", ".", "Project workspace directory")
-	fs.Parse(args)
+	force := fs.Bool("force", false, "Install even if the pack is incompatible or conflicts with another pack's content")
+	requireSigned := fs.Bool("require-signed", false, "Refuse packs that are unsigned or signed by an untrusted publisher")
+	allowPostInstall := fs.Bool("allow-post-install", false, "Run the pack's post-install script without asking")
+	noPostInstall := fs.Bool("no-post-install", false, "Never run the pack's post-install script")
+	hookOS := fs.String("os", "", "Install hook variants for this OS (linux, darwin, windows) instead of the running one")
+	noDeps := fs.Bool("no-deps", false, "Install only this pack, not the packs it depends on")
+	frozen := fs.Bool("frozen", false, "Install the pack and its dependencies at the commits packs.lock records")
+	gitFlags := addGitCommitFlags(fs)
+	parseFlags(fs, args)
 
 	if fs.NArg() < 1 {
-		fatal("usage: orchestra pack install <repo>[@version]")
+		fatal("usage: orchestra pack install <repo>[@version|@range] [--no-deps] [--frozen]")
+	}
+	switch *hookOS {
+	case "", "linux", "darwin", "windows":
+	default:
+		fatal("--os must be linux, darwin, or windows")
 	}
 
 	rawArg := fs.Arg(0)
 	repo, version := parsePackRepoVersion(rawArg)
+	if err := checkAllowedSource(repo); err != nil {
+		fatal("%v", err)
+	}
+
+	absWorkspace, _ := resolveWorkspace(*workspace)
+	checkWorkspaceSchema(absWorkspace, true)
+	var lock *packs.Lock
+	var pin *packs.LockedPack
+	if *frozen {
+		if version != "" {
+			fatal("--frozen installs the version packs.lock records; drop @%s", version)
+		}
+		lock = loadPackLock(absWorkspace)
+		if _, pin = lock.FindByRepo(repo); pin == nil {
+			fatal("%s is not in %s; install it without --frozen to add it", repo, packs.LockPath(absWorkspace))
+		}
+		packLockFrozen = true
+	}
+	version, constraint := resolvePackVersion(repo, version)
+	commit := gitFlags.prepare(absWorkspace)
+	hookVars := []string{"ORCHESTRA_REPO=" + repo, "ORCHESTRA_PACK_VERSION=" + version}
+	runPreHook(hookRun{event: hookPrePackInstall, workspace: absWorkspace, vars: hookVars})
+
+	opts := packInstallOptions{Force: *force, RequireSigned: *requireSigned, OS: *hookOS,
+		AllowPostInstall: *allowPostInstall, SkipPostInstall: *noPostInstall}
+	var refs []string
+	if !*noDeps {
+		for _, dep := range resolvePackDependencies(absWorkspace, repo, version, lock) {
+			m := installAndRecordPack(absWorkspace, dep.Repo, "", dep.Pin, opts)
+			refs = append(refs, packRef(m.Name, m.Version))
+		}
+	}
+	opts.Constraint = constraint
+	manifest := installAndRecordPack(absWorkspace, repo, version, pin, opts)
+	refs = append(refs, packRef(manifest.Name, manifest.Version))
+
+	// Regenerate workspace docs to reflect new content.
+	GenerateWorkspaceDocs(absWorkspace)
+	commit.commit("install", strings.Join(refs, ", "))
+	runPostHook(hookRun{event: hookPostPackInstall, workspace: absWorkspace, vars: []string{
+		"ORCHESTRA_REPO=" + repo,
+		"ORCHESTRA_PACK=" + manifest.Name,
+		"ORCHESTRA_PACK_VERSION=" + manifest.Version,
+		"ORCHESTRA_PACKS=" + strings.Join(refs, " "),
+	}})
+}
+
+// lockedDependency is a dependency to install, at the revision packs.lock
+// pins for it when installing --frozen.
+type lockedDependency struct {
+	packs.Dependency
+	Pin *packs.LockedPack
+}
+
+// resolvePackDependencies returns the packs that repo at version depends
+// on, directly or through another dependency, that are not installed in
+// workspace, in install order. With a lock, every pack is read at its
+// locked revision, and one the lock does not list is an error.
+func resolvePackDependencies(workspace, repo, version string, lock *packs.Lock) []lockedDependency {
+	fetch := func(repo, version string) (*packs.Manifest, error) {
+		if lock == nil {
+			return fetchPackManifest(repo, version)
+		}
+		_, pin := lock.FindByRepo(repo)
+		if pin == nil {
+			return nil, fmt.Errorf("%s is not in packs.lock", repo)
+		}
+		return fetchLockedPackManifest(pin)
+	}
+	sp := startSpinner("Resolving dependencies of " + repo)
+	root, err := fetch(repo, version)
+	var deps []packs.Dependency
+	if err == nil {
+		deps, err = packs.Resolve(root, repo, loadPackRegistry(workspace), func(depRepo string) (*packs.Manifest, error) {
+			return fetch(depRepo, "")
+		})
+	}
+	sp.Stop(err)
+	if err != nil {
+		fatal("%v; rerun with --no-deps to install %s alone", err, repo)
+	}
+	if len(deps) > 0 {
+		fmt.Fprintf(os.Stderr, "  Installing dependencies first:\n")
+	}
+	locked := make([]lockedDependency, len(deps))
+	for i, d := range deps {
+		fmt.Fprintf(os.Stderr, "      %s (%s, needed by %s)\n", d.Manifest.Name, d.Repo, d.Of)
+		locked[i].Dependency = d
+		if lock != nil {
+			_, locked[i].Pin = lock.FindByRepo(d.Repo)
+		}
+	}
+	return locked
+}
 
-	absWorkspace, _ := filepath.Abs(*workspace)
+// loadPackLock reads the workspace's packs.lock, ending the command when
+// there is none or it cannot be read.
+func loadPackLock(workspace string) *packs.Lock {
+	lock, err := packs.LoadLock(workspace)
+	if os.IsNotExist(err) {
+		fatal("no %s; install packs to create it, or check out the one your team committed", packs.LockPath(workspace))
+	}
+	if err != nil {
+		fatal("%v", err)
+	}
+	return lock
+}
 
-	fmt.Fprintf(os.Stderr, "Installing pack from %s...\n", repo)
+// fetchLockedPackManifest reads pack.json at the revision pin locks.
+func fetchLockedPackManifest(pin *packs.LockedPack) (*packs.Manifest, error) {
+	if pin.Source == packs.SourceEmbedded {
+		src, err := embeddedPackFS(pin.Repo)
+		if err != nil {
+			return nil, err
+		}
+		return packs.ReadManifest(src)
+	}
+	if pin.Ref() == "" {
+		return nil, fmt.Errorf("packs.lock records no commit for %s; run 'orchestra pack update' to pin it", pin.Repo)
+	}
+	return fetchPackManifest(pin.Repo, pin.Ref())
+}
 
-	manifest, err := installPackFromGit(absWorkspace, repo, version)
+// installAndRecordPack installs repo at version, or at the revision pin
+// locks when pin is set, into workspace, falling back to the embedded copy
+// when the clone fails, records it in the pack registry, and runs its
+// post-install script. It ends the command if the pack cannot be
+// installed.
+func installAndRecordPack(absWorkspace, repo, version string, pin *packs.LockedPack, opts packInstallOptions) *packs.Manifest {
+	if pin != nil && pin.Source == packs.SourceEmbedded {
+		return installLockedEmbeddedPack(absWorkspace, repo, pin, opts)
+	}
+	if pin != nil {
+		if version = pin.Ref(); version == "" {
+			fatal("packs.lock records no commit for %s; run 'orchestra pack update' to pin it", repo)
+		}
+	}
+	sp := startSpinner("Installing pack from " + repo)
+	manifest, rev, err := installPackFromGit(absWorkspace, repo, version, opts)
+	sp.Stop(err)
+	source := ""
+	if err != nil && !errors.Is(err, packs.ErrIncompatible) && !errors.Is(err, packs.ErrConflict) && !errors.Is(err, packs.ErrVerify) && !errors.Is(err, errSourceNotAllowed) && version == "" && hasEmbeddedPack(repo) {
+		// Offline (or GitHub unavailable): fall back to the copy shipped
+		// in the binary. `pack update` later switches to upstream.
+		printStatus(tagWarn, "using the copy embedded in orchestra %s", Version)
+		opts.Embedded = true
+		manifest, err = installEmbeddedPack(absWorkspace, repo, opts)
+		source = packs.SourceEmbedded
+	}
 	if err != nil {
 		fatal("install failed: %v", err)
 	}
 
-	// Update local registry.
-	reg := loadPackRegistry(absWorkspace)
-	reg.Packs[manifest.Name] = &packEntry{
-		Version:     manifest.Version,
-		Repo:        repo,
-		InstalledAt: time.Now().UTC().Format(time.RFC3339),
-		Stacks:      manifest.Stacks,
-		Skills:      manifest.Contents.Skills,
-		Agents:      manifest.Contents.Agents,
-		Hooks:       manifest.Contents.Hooks,
+	if pin != nil && rev.Tag == "" {
+		rev.Tag = pin.Tag // a commit fetched on its own comes without tags
 	}
+	return recordInstalledPack(absWorkspace, repo, manifest, source, rev, opts)
+}
+
+// installLockedEmbeddedPack installs the embedded copy of repo that pin
+// locks. The copy is whatever this build embeds; a different version is
+// reported, since the locked one cannot be had.
+func installLockedEmbeddedPack(absWorkspace, repo string, pin *packs.LockedPack, opts packInstallOptions) *packs.Manifest {
+	opts.Embedded = true
+	manifest, err := installEmbeddedPack(absWorkspace, repo, opts)
+	if err != nil {
+		fatal("install failed: %v", err)
+	}
+	if manifest.Version != pin.Version {
+		printStatus(tagWarn, "packs.lock pins the embedded %s %s, but orchestra %s embeds %s", manifest.Name, pin.Version, Version, manifest.Version)
+	}
+	return recordInstalledPack(absWorkspace, repo, manifest, packs.SourceEmbedded, packRevision{}, opts)
+}
+
+// recordInstalledPack records a pack just installed from repo in the pack
+// registry, prints what it installed, and runs its post-install script.
+func recordInstalledPack(absWorkspace, repo string, manifest *packs.Manifest, source string, rev packRevision, opts packInstallOptions) *packs.Manifest {
+	reg := loadPackRegistry(absWorkspace)
+	entry := packs.NewEntry(manifest, repo)
+	entry.Source = source
+	entry.Tag, entry.Commit = rev.Tag, rev.Commit
+	entry.Constraint = opts.Constraint
+	reg.Packs[manifest.Name] = entry
 	savePackRegistry(absWorkspace, reg)
 
 	fmt.Fprintf(os.Stderr, "  Installed: %s@%s\n", manifest.Name, manifest.Version)
+	if entry.Constraint != "" {
+		fmt.Fprintf(os.Stderr, "  Constraint: %s (pack update stays within it)\n", entry.Constraint)
+	}
 	if len(manifest.Contents.Skills) > 0 {
 		fmt.Fprintf(os.Stderr, "  Skills: %s\n", strings.Join(manifest.Contents.Skills, ", "))
 	}
@@ -138,48 +253,113 @@ func runPackInstall(args []string) {
 		fmt.Fprintf(os.Stderr, "  Hooks: %s\n", strings.Join(manifest.Contents.Hooks, ", "))
 	}
 
-	// Regenerate workspace docs to reflect new content.
-	GenerateWorkspaceDocs(absWorkspace)
+	// The post-install script runs last, after the pack is recorded, so a
+	// declined or failed script leaves a normally installed pack.
+	if logPath := runPostInstall(absWorkspace, manifest, opts); logPath != "" {
+		entry.PostInstallLog = logPath
+		savePackRegistry(absWorkspace, reg)
+	}
+	return manifest
 }
 
 // --- remove ---
 
 func runPackRemove(args []string) {
-	fs := flag.NewFlagSet("pack remove", flag.ExitOnError)
+	fs := newFlagSet("pack remove")
 	workspace := fs.String("workspace", ".", "Project workspace directory")
-	fs.Parse(args)
+	cascade := fs.Bool("cascade", false, "Also remove the packs that depend on it")
+	force := fs.Bool("force", false, "Remove only this pack even though others depend on it")
+	gitFlags := addGitCommitFlags(fs)
+	parseFlags(fs, args)
 
 	if fs.NArg() < 1 {
-		fatal("usage: orchestra pack remove <name>")
+		fatal("usage: orchestra pack remove <name> [--cascade|--force]")
+	}
+	if *cascade && *force {
+		fatal("--cascade and --force cannot be combined")
 	}
 
 	name := fs.Arg(0)
-	absWorkspace, _ := filepath.Abs(*workspace)
+	absWorkspace, _ := resolveWorkspace(*workspace)
+	checkWorkspaceSchema(absWorkspace, true)
 
 	reg := loadPackRegistry(absWorkspace)
-	entry, ok := reg.Packs[name]
-	if !ok {
+	if _, ok := reg.Packs[name]; !ok {
 		fatal("pack %q is not installed", name)
 	}
-
-	removePackFiles(absWorkspace, entry.Skills, entry.Agents, entry.Hooks)
-	delete(reg.Packs, name)
-	savePackRegistry(absWorkspace, reg)
-
-	fmt.Fprintf(os.Stderr, "Removed pack: %s\n", name)
+	remove := reg.RemovalSet(name)
+	if len(remove) > 1 {
+		dependents := remove[:len(remove)-1]
+		printStatus(tagWarn, "%d pack(s) depend on %s:", len(dependents), name)
+		for _, d := range dependents {
+			e := reg.Packs[d]
+			fmt.Fprintf(os.Stderr, "      %s (%d skill(s), %d agent(s), %d hook(s))\n", d, len(e.Skills), len(e.Agents), len(e.Hooks))
+		}
+		switch {
+		case *force:
+			printStatus(tagWarn, "removing only %s; they stay installed without it", name)
+			remove = []string{name}
+		case !*cascade:
+			fatal("refusing to remove %s; remove its dependents first, rerun with --cascade to remove them too, or with --force to remove it anyway", name)
+		case isTerminal(os.Stdin) && !confirm(fmt.Sprintf("Remove %s and these %d pack(s)?", name, len(dependents))):
+			fatal("removal cancelled")
+		}
+	}
+	commit := gitFlags.prepare(absWorkspace)
+	// ORCHESTRA_PACKS also lists the dependents --cascade removes.
+	hookVars := []string{"ORCHESTRA_PACK=" + name, "ORCHESTRA_PACKS=" + strings.Join(remove, " ")}
+	runPreHook(hookRun{event: hookPrePackRemove, workspace: absWorkspace, vars: hookVars})
+
+	for _, n := range remove {
+		removal, err := packs.Remove(absWorkspace, reg.Packs[n])
+		printRemoval(removal, err)
+		delete(reg.Packs, n)
+		savePackRegistry(absWorkspace, reg)
+		fmt.Fprintf(os.Stderr, "Removed pack: %s\n\n", n)
+	}
 
 	// Regenerate workspace docs to reflect removed content.
 	GenerateWorkspaceDocs(absWorkspace)
+	refs := make([]string, len(remove))
+	for i, n := range remove {
+		refs[len(remove)-1-i] = packRef(n, "")
+	}
+	commit.commit("remove", strings.Join(refs, ", "))
+	runPostHook(hookRun{event: hookPostPackRemove, workspace: absWorkspace, vars: hookVars})
+}
+
+// printRemoval reports each file, directory, and settings row a pack
+// removal deleted, and any that could not be.
+func printRemoval(r *packs.Removal, err error) {
+	for _, p := range r.Files {
+		printStatus(tagOK, "removed %s", p)
+	}
+	for _, row := range r.Settings {
+		printStatus(tagOK, "removed hook %s", row)
+	}
+	for _, p := range r.Dirs {
+		printStatus(tagOK, "removed empty %s/", p)
+	}
+	if err != nil {
+		printStatus(tagFail, "%v", err)
+	}
 }
 
 // --- update ---
 
 func runPackUpdate(args []string) {
-	fs := flag.NewFlagSet("pack update", flag.ExitOnError)
+	fs := newFlagSet("pack update")
 	workspace := fs.String("workspace", ".", "Project workspace directory")
-	fs.Parse(args)
-
-	absWorkspace, _ := filepath.Abs(*workspace)
+	force := fs.Bool("force", false, "Update even if the new version is incompatible or conflicts with another pack's content")
+	requireSigned := fs.Bool("require-signed", false, "Refuse versions that are unsigned or signed by an untrusted publisher")
+	allowPostInstall := fs.Bool("allow-post-install", false, "Run post-install scripts without asking")
+	noPostInstall := fs.Bool("no-post-install", false, "Never run post-install scripts")
+	latest := fs.Bool("latest", false, "Update to the latest version even past the version range a pack was installed with, and drop the range")
+	gitFlags := addGitCommitFlags(fs)
+	parseFlags(fs, args)
+
+	absWorkspace, _ := resolveWorkspace(*workspace)
+	checkWorkspaceSchema(absWorkspace, true)
 	reg := loadPackRegistry(absWorkspace)
 
 	name := ""
@@ -187,13 +367,13 @@ func runPackUpdate(args []string) {
 		name = fs.Arg(0)
 	}
 
-	var toUpdate map[string]*packEntry
+	var toUpdate map[string]*packs.Entry
 	if name != "" {
 		entry, ok := reg.Packs[name]
 		if !ok {
 			fatal("pack %q is not installed", name)
 		}
-		toUpdate = map[string]*packEntry{name: entry}
+		toUpdate = map[string]*packs.Entry{name: entry}
 	} else {
 		toUpdate = reg.Packs
 	}
@@ -202,63 +382,421 @@ func runPackUpdate(args []string) {
 		fmt.Fprintf(os.Stderr, "No packs installed to update.\n")
 		return
 	}
+	commit := gitFlags.prepare(absWorkspace)
+
+	opts := packInstallOptions{Force: *force, RequireSigned: *requireSigned,
+		AllowPostInstall: *allowPostInstall, SkipPostInstall: *noPostInstall}
+	var refs []string
+	for _, b := range updatePacks(absWorkspace, reg, toUpdate, opts, *latest) {
+		refs = append(refs, packRef(b.Name, b.To))
+	}
+	commit.commit("update", strings.Join(refs, ", "))
+}
+
+// packBump records one pack updated by updatePacks.
+type packBump struct {
+	Name     string
+	From, To string // versions
+	Removal  *packs.Removal
+}
 
-	for packName, entry := range toUpdate {
+// updatePacks reinstalls each pack in toUpdate from its repo, at the
+// highest tag its version range allows or, for a pack without one or with
+// latest, at the default branch. It removes content the new version no
+// longer ships, saves the registry, and regenerates the docs once. It
+// returns the packs that updated, sorted by name; failures are reported
+// and skipped.
+func updatePacks(absWorkspace string, reg *packs.Registry, toUpdate map[string]*packs.Entry, opts packInstallOptions, latest bool) []packBump {
+	names := make([]string, 0, len(toUpdate))
+	for name := range toUpdate {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Each updated pack asks for new docs; they are written once, when the
+	// batch ends.
+	endDocs := beginDocsBatch(absWorkspace)
+	var bumps []packBump
+	for _, packName := range names {
+		entry := toUpdate[packName]
 		fmt.Fprintf(os.Stderr, "Updating %s...\n", packName)
-		removePackFiles(absWorkspace, entry.Skills, entry.Agents, entry.Hooks)
 
-		manifest, err := installPackFromGit(absWorkspace, entry.Repo, "")
+		// Install over the old files first, so a failed or incompatible
+		// update leaves the current version in place. Hooks stay in the
+		// variant the pack was installed for.
+		packOpts := opts
+		if packOpts.OS == "" {
+			packOpts.OS = entry.OS
+		}
+		version := ""
+		if entry.Constraint != "" && !latest {
+			c, err := packs.ParseConstraint(entry.Constraint)
+			if err == nil {
+				version, err = resolvePackConstraint(entry.Repo, c)
+			}
+			if err != nil {
+				printStatus(tagFail, "%s: %v", packName, err)
+				continue
+			}
+		}
+		manifest, rev, err := installPackFromGit(absWorkspace, entry.Repo, version, packOpts)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "  [FAIL] %s: %v\n", packName, err)
+			printStatus(tagFail, "%s: %v", packName, err)
 			continue
 		}
+		removal, err := packs.RemoveStale(absWorkspace, entry, manifest)
+		printRemoval(removal, err)
 
-		reg.Packs[packName] = &packEntry{
-			Version:     manifest.Version,
-			Repo:        entry.Repo,
-			InstalledAt: time.Now().UTC().Format(time.RFC3339),
-			Stacks:      manifest.Stacks,
-			Skills:      manifest.Contents.Skills,
-			Agents:      manifest.Contents.Agents,
-			Hooks:       manifest.Contents.Hooks,
+		updated := packs.NewEntry(manifest, entry.Repo)
+		updated.Tag, updated.Commit = rev.Tag, rev.Commit
+		if !latest {
+			updated.Constraint = entry.Constraint
 		}
-		fmt.Fprintf(os.Stderr, "  [OK] %s → %s\n", packName, manifest.Version)
+		updated.PostInstallLog = runPostInstall(absWorkspace, manifest, opts)
+		reg.Packs[packName] = updated
+		switch {
+		case updated.Constraint != "":
+			printStatus(tagOK, "%s → %s (within %s)", packName, manifest.Version, updated.Constraint)
+		case entry.Constraint != "":
+			printStatus(tagOK, "%s → %s (no longer held to %s)", packName, manifest.Version, entry.Constraint)
+		default:
+			printStatus(tagOK, "%s → %s", packName, manifest.Version)
+		}
+		bumps = append(bumps, packBump{Name: packName, From: entry.Version, To: manifest.Version, Removal: removal})
+		GenerateWorkspaceDocs(absWorkspace)
 	}
 
 	savePackRegistry(absWorkspace, reg)
+	endDocs()
+	return bumps
+}
 
-	// Regenerate workspace docs to reflect updated packs.
-	GenerateWorkspaceDocs(absWorkspace)
+// --- sync ---
+
+// runPackSync handles `orchestra pack sync` -- makes the installed packs
+// exactly what packs.lock records: packs missing or at another revision
+// are installed at the locked one, and packs the lock does not list are
+// removed. The lock itself is left as it is.
+func runPackSync(args []string) {
+	fs := newFlagSet("pack sync")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	force := fs.Bool("force", false, "Install locked packs even if they are incompatible or conflict with another pack's content")
+	requireSigned := fs.Bool("require-signed", false, "Refuse locked packs that are unsigned or signed by an untrusted publisher")
+	allowPostInstall := fs.Bool("allow-post-install", false, "Run post-install scripts without asking")
+	noPostInstall := fs.Bool("no-post-install", false, "Never run post-install scripts")
+	dryRun := fs.Bool("dry-run", false, "Show what would change without changing anything")
+	gitFlags := addGitCommitFlags(fs)
+	parseFlags(fs, args)
+
+	absWorkspace, _ := resolveWorkspace(*workspace)
+	checkWorkspaceSchema(absWorkspace, true)
+	lock := loadPackLock(absWorkspace)
+	reg := loadPackRegistry(absWorkspace)
+
+	lockedNames := make([]string, 0, len(lock.Packs))
+	for name := range lock.Packs {
+		lockedNames = append(lockedNames, name)
+	}
+	sortNames(lockedNames)
+	var install, extra []string
+	for _, name := range lockedNames {
+		pin := lock.Packs[name]
+		if pin.Satisfied(reg.Packs[name]) {
+			continue
+		}
+		if pin.Source != packs.SourceEmbedded && pin.Ref() == "" {
+			fatal("packs.lock records no commit for %s; run 'orchestra pack update %s' where it was installed to pin it", name, name)
+		}
+		install = append(install, name)
+	}
+	for _, name := range reg.Names() {
+		if lock.Packs[name] == nil {
+			extra = append(extra, name)
+		}
+	}
+	if len(install) == 0 && len(extra) == 0 {
+		printStatus(tagOK, "installed packs match %s", packs.LockPath(absWorkspace))
+		return
+	}
+
+	for _, name := range install {
+		pin := lock.Packs[name]
+		from := "not installed"
+		if e := reg.Packs[name]; e != nil {
+			from = e.Version
+		}
+		fmt.Fprintf(os.Stderr, "  install %s %s → %s\n", name, from, describePin(pin))
+	}
+	for _, name := range extra {
+		fmt.Fprintf(os.Stderr, "  remove  %s %s (not in packs.lock)\n", name, reg.Packs[name].Version)
+	}
+	if *dryRun {
+		fmt.Fprintf(os.Stderr, "\nNothing was changed.\n")
+		return
+	}
+
+	packLockFrozen = true
+	commit := gitFlags.prepare(absWorkspace)
+	endDocs := beginDocsBatch(absWorkspace)
+	var refs []string
+	// Extras go first, so their content never conflicts with a locked pack.
+	for _, name := range extra {
+		reg := loadPackRegistry(absWorkspace)
+		removal, err := packs.Remove(absWorkspace, reg.Packs[name])
+		printRemoval(removal, err)
+		delete(reg.Packs, name)
+		savePackRegistry(absWorkspace, reg)
+		refs = append(refs, name+" (removed)")
+		GenerateWorkspaceDocs(absWorkspace)
+	}
+	opts := packInstallOptions{Force: *force, RequireSigned: *requireSigned,
+		AllowPostInstall: *allowPostInstall, SkipPostInstall: *noPostInstall}
+	for _, name := range install {
+		pin := lock.Packs[name]
+		old := reg.Packs[name]
+		packOpts := opts
+		if old != nil {
+			packOpts.OS = old.OS
+		}
+		manifest := installAndRecordPack(absWorkspace, pin.Repo, "", pin, packOpts)
+		if old != nil {
+			removal, err := packs.RemoveStale(absWorkspace, old, manifest)
+			printRemoval(removal, err)
+		}
+		refs = append(refs, packRef(manifest.Name, manifest.Version))
+		GenerateWorkspaceDocs(absWorkspace)
+	}
+	endDocs()
+	commit.commit("sync", strings.Join(refs, ", "))
+}
+
+// describePin shows the version and revision a lock entry pins.
+func describePin(pin *packs.LockedPack) string {
+	var rev []string
+	if pin.Source != "" {
+		rev = append(rev, pin.Source)
+	}
+	if pin.Tag != "" {
+		rev = append(rev, pin.Tag)
+	}
+	if len(pin.Commit) >= 12 {
+		rev = append(rev, pin.Commit[:12])
+	}
+	if len(rev) == 0 {
+		return pin.Version
+	}
+	return fmt.Sprintf("%s (%s)", pin.Version, strings.Join(rev, ", "))
 }
 
 // --- list ---
 
 func runPackList(args []string) {
-	fs := flag.NewFlagSet("pack list", flag.ExitOnError)
+	fs := newFlagSet("pack list")
 	workspace := fs.String("workspace", ".", "Project workspace directory")
-	fs.Parse(args)
+	porcelain := fs.Bool("porcelain", false, "Stable tab-separated output for scripts")
+	parseFlags(fs, args)
 
-	absWorkspace, _ := filepath.Abs(*workspace)
+	absWorkspace, _ := resolveWorkspace(*workspace)
+	checkWorkspaceSchema(absWorkspace, false)
 	reg := loadPackRegistry(absWorkspace)
 
+	if jsonOutput() {
+		type packJSON struct {
+			Name string `json:"name"`
+			*packs.Entry
+		}
+		list := []packJSON{}
+		for _, name := range reg.Names() {
+			list = append(list, packJSON{name, reg.Packs[name]})
+		}
+		printJSON(list)
+		return
+	}
+
 	if len(reg.Packs) == 0 {
-		fmt.Fprintf(os.Stderr, "No packs installed. Run: orchestra pack install <repo>\n")
+		if !*porcelain {
+			fmt.Fprintf(os.Stderr, "No packs installed. Run: orchestra pack install <repo>\n")
+		}
 		return
 	}
 
-	fmt.Fprintf(os.Stderr, "Installed packs:\n\n")
-	for name, entry := range reg.Packs {
-		fmt.Fprintf(os.Stderr, "  %-40s %s  (%d skills, %d agents, %d hooks)\n",
-			name, entry.Version,
-			len(entry.Skills), len(entry.Agents), len(entry.Hooks))
+	// Porcelain: name, version, repo, skill count, agent count, hook count.
+	if *porcelain {
+		for _, name := range reg.Names() {
+			entry := reg.Packs[name]
+			fmt.Fprintf(os.Stdout, "%s\t%s\t%s\t%d\t%d\t%d\n",
+				name, entry.Version, entry.Repo,
+				len(entry.Skills), len(entry.Agents), len(entry.Hooks))
+		}
+		return
+	}
+
+	fmt.Fprintf(os.Stdout, "Installed packs:\n\n")
+	tw := newTable(os.Stdout)
+	for _, name := range reg.Names() {
+		entry := reg.Packs[name]
+		source := ""
+		if entry.Source != "" {
+			source = "  [" + entry.Source + "]"
+		}
+		version := entry.Version
+		if entry.Constraint != "" {
+			version += " (" + entry.Constraint + ")"
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t(%d skills, %d agents, %d hooks)%s\n",
+			name, version,
+			len(entry.Skills), len(entry.Agents), len(entry.Hooks), source)
+	}
+	tw.Flush()
+}
+
+// --- info ---
+
+func runPackInfo(args []string) {
+	fs := newFlagSet("pack info")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	parseFlags(fs, args)
+
+	if fs.NArg() < 1 {
+		fatal("usage: orchestra pack info <name|repo>[@version|@range]")
+	}
+
+	absWorkspace, _ := resolveWorkspace(*workspace)
+	reg := loadPackRegistry(absWorkspace)
+
+	// An installed pack, by registry name or repo.
+	repo, version := parsePackRepoVersion(fs.Arg(0))
+	name, entry := reg.Lookup(repo)
+	if entry != nil && version == "" {
+		if jsonOutput() {
+			printJSON(describePackInfoJSON(entry.Manifest(name), entry.Repo, entry))
+			return
+		}
+		printPackInfo(entry.Manifest(name), entry.Repo)
+		fmt.Fprintf(os.Stdout, "Installed:   %s", entry.InstalledAt)
+		if entry.Source != "" {
+			fmt.Fprintf(os.Stdout, " (%s)", entry.Source)
+		}
+		fmt.Fprintln(os.Stdout)
+		if entry.Constraint != "" {
+			fmt.Fprintf(os.Stdout, "Constraint:  %s\n", entry.Constraint)
+		}
+		return
+	}
+
+	// Otherwise read pack.json from the repo.
+	version, _ = resolvePackVersion(repo, version)
+	manifest, err := fetchPackManifest(repo, version)
+	if err != nil {
+		fatal("%v", err)
+	}
+	if jsonOutput() {
+		printJSON(describePackInfoJSON(manifest, repo, nil))
+		return
+	}
+	printPackInfo(manifest, repo)
+	fmt.Fprintf(os.Stdout, "Installed:   no\n")
+}
+
+// packInfoJSON is what `pack info --json` prints: the manifest, where it
+// came from, and what printPackInfo says about it.
+type packInfoJSON struct {
+	*packs.Manifest
+	Repo      string       `json:"repo"`
+	Publisher string       `json:"publisher,omitempty"` // set only when pack.sig verifies
+	Problems  []string     `json:"problems"`            // why it cannot be installed; empty when compatible
+	Installed *packs.Entry `json:"installed"`           // null when not installed
+}
+
+func describePackInfoJSON(m *packs.Manifest, repo string, entry *packs.Entry) packInfoJSON {
+	j := packInfoJSON{Manifest: m, Repo: repo, Publisher: m.Publisher, Problems: packs.Problems(m, Version), Installed: entry}
+	if j.Problems == nil {
+		j.Problems = []string{}
+	}
+	return j
+}
+
+// fetchPackManifest reads pack.json from repo without installing it,
+// falling back to the embedded copy when the clone fails.
+func fetchPackManifest(repo, version string) (*packs.Manifest, error) {
+	var manifest *packs.Manifest
+	_, err := withPackClone(repo, version, func(src fs.FS) error {
+		var err error
+		if manifest, err = packs.ReadManifest(src); err != nil {
+			return err
+		}
+		if ts, err := packs.LoadTrustStore(); err == nil {
+			if publisher, err := packs.Verify(src, manifest, ts); err == nil {
+				manifest.Publisher = publisher
+			}
+		}
+		return nil
+	})
+	if err != nil && version == "" && hasEmbeddedPack(repo) {
+		sub, subErr := embeddedPackFS(repo)
+		if subErr != nil {
+			return nil, subErr
+		}
+		return packs.ReadManifest(sub)
+	}
+	return manifest, err
+}
+
+// printPackInfo prints a manifest's metadata and whether this build of
+// orchestra can install it.
+func printPackInfo(m *packs.Manifest, repo string) {
+	orAny := func(vals []string) string {
+		if len(vals) == 0 {
+			return "any"
+		}
+		return strings.Join(vals, ", ")
+	}
+	orNone := func(vals []string) string {
+		if len(vals) == 0 {
+			return "-"
+		}
+		return strings.Join(vals, ", ")
+	}
+	fmt.Fprintf(os.Stdout, "Name:        %s\n", m.Name)
+	fmt.Fprintf(os.Stdout, "Version:     %s\n", m.Version)
+	fmt.Fprintf(os.Stdout, "Repo:        %s\n", repo)
+	if m.Description != "" {
+		fmt.Fprintf(os.Stdout, "Description: %s\n", m.Description)
+	}
+	fmt.Fprintf(os.Stdout, "Stacks:      %s\n", orAny(m.Stacks))
+	fmt.Fprintf(os.Stdout, "Skills:      %s\n", orNone(m.Contents.Skills))
+	fmt.Fprintf(os.Stdout, "Agents:      %s\n", orNone(m.Contents.Agents))
+	fmt.Fprintf(os.Stdout, "Hooks:       %s\n", orNone(m.Contents.Hooks))
+	if m.PostInstall != "" {
+		fmt.Fprintf(os.Stdout, "Post-install: %s (runs only with your approval)\n", m.PostInstall)
+	}
+	minVersion := m.MinOrchestraVersion
+	if minVersion == "" {
+		minVersion = "any"
+	}
+	fmt.Fprintf(os.Stdout, "Requires:    orchestra %s\n", minVersion)
+	if len(m.Dependencies) > 0 {
+		fmt.Fprintf(os.Stdout, "Depends on:  %s\n", strings.Join(m.Dependencies, ", "))
+	}
+	fmt.Fprintf(os.Stdout, "Platforms:   %s\n", orAny(m.Platforms))
+	if m.Publisher != "" {
+		fmt.Fprintf(os.Stdout, "Publisher:   %s (verified)\n", m.Publisher)
+	} else {
+		fmt.Fprintf(os.Stdout, "Publisher:   unverified\n")
+	}
+	if problems := packs.Problems(m, Version); len(problems) > 0 {
+		fmt.Fprintf(os.Stdout, "Compatible:  no — %s\n", strings.Join(problems, "; "))
+	} else {
+		fmt.Fprintf(os.Stdout, "Compatible:  yes (orchestra %s, %s/%s)\n", Version, runtime.GOOS, runtime.GOARCH)
 	}
 }
 
 // --- search ---
 
 func runPackSearch(args []string) {
-	fs := flag.NewFlagSet("pack search", flag.ExitOnError)
-	fs.Parse(args)
+	fs := newFlagSet("pack search")
+	porcelain := fs.Bool("porcelain", false, "Stable tab-separated output for scripts")
+	parseFlags(fs, args)
 
 	if fs.NArg() < 1 {
 		fatal("usage: orchestra pack search <query>")
@@ -266,58 +804,41 @@ func runPackSearch(args []string) {
 
 	query := strings.ToLower(fs.Arg(0))
 
-	type knownPack struct {
-		Repo        string
-		Stacks      []string
-		Description string
-		Tags        []string
-	}
-
-	// Same index as internal/packs/index.go — kept in sync.
-	known := []knownPack{
-		{Repo: "github.com/orchestra-mcp/pack-essentials", Stacks: []string{"*"}, Description: "Core project management skills and agents", Tags: []string{"core", "essential"}},
-		{Repo: "github.com/orchestra-mcp/pack-go-backend", Stacks: []string{"go"}, Description: "Go backend skills (Fiber, GORM, REST)", Tags: []string{"go", "backend", "fiber"}},
-		{Repo: "github.com/orchestra-mcp/pack-rust-engine", Stacks: []string{"rust"}, Description: "Rust engine skills", Tags: []string{"rust", "engine"}},
-		{Repo: "github.com/orchestra-mcp/pack-react-frontend", Stacks: []string{"react", "typescript"}, Description: "React frontend skills", Tags: []string{"react", "typescript"}},
-		{Repo: "github.com/orchestra-mcp/pack-database", Stacks: []string{"*"}, Description: "Database skills (PostgreSQL, SQLite, Redis)", Tags: []string{"database", "sql"}},
-		{Repo: "github.com/orchestra-mcp/pack-ai", Stacks: []string{"*"}, Description: "AI/LLM integration skills", Tags: []string{"ai", "llm", "rag"}},
-		{Repo: "github.com/orchestra-mcp/pack-mobile", Stacks: []string{"react-native"}, Description: "React Native mobile skills", Tags: []string{"mobile"}},
-		{Repo: "github.com/orchestra-mcp/pack-desktop", Stacks: []string{"go"}, Description: "Desktop app skills", Tags: []string{"desktop", "wails"}},
-		{Repo: "github.com/orchestra-mcp/pack-extensions", Stacks: []string{"*"}, Description: "Extension system skills", Tags: []string{"extensions"}},
-		{Repo: "github.com/orchestra-mcp/pack-chrome", Stacks: []string{"typescript"}, Description: "Chrome extension skills", Tags: []string{"chrome", "browser"}},
-		{Repo: "github.com/orchestra-mcp/pack-infra", Stacks: []string{"docker"}, Description: "Infrastructure and DevOps skills", Tags: []string{"docker", "devops"}},
-		{Repo: "github.com/orchestra-mcp/pack-proto", Stacks: []string{"go", "rust"}, Description: "Protobuf/gRPC skills", Tags: []string{"proto", "grpc"}},
-		{Repo: "github.com/orchestra-mcp/pack-native-swift", Stacks: []string{"swift"}, Description: "Swift/macOS/iOS plugin skills", Tags: []string{"swift", "macos"}},
-		{Repo: "github.com/orchestra-mcp/pack-native-kotlin", Stacks: []string{"kotlin", "java"}, Description: "Kotlin/Android plugin skills", Tags: []string{"kotlin", "android"}},
-		{Repo: "github.com/orchestra-mcp/pack-native-csharp", Stacks: []string{"csharp"}, Description: "C#/Windows plugin skills", Tags: []string{"csharp", "windows"}},
-		{Repo: "github.com/orchestra-mcp/pack-native-gtk", Stacks: []string{"c"}, Description: "GTK4/Linux desktop skills", Tags: []string{"gtk", "linux"}},
-		{Repo: "github.com/orchestra-mcp/pack-analytics", Stacks: []string{"*"}, Description: "ClickHouse analytics skills", Tags: []string{"analytics", "clickhouse"}},
-	}
-
-	var matches []knownPack
-	for _, p := range known {
-		if strings.Contains(strings.ToLower(p.Repo), query) ||
-			strings.Contains(strings.ToLower(p.Description), query) {
+	matches := []packs.CatalogEntry{}
+	for _, p := range loadPackIndex(false) {
+		if p.Matches(query) {
 			matches = append(matches, p)
-			continue
-		}
-		for _, tag := range p.Tags {
-			if strings.Contains(tag, query) {
-				matches = append(matches, p)
-				break
-			}
 		}
 	}
 
+	if jsonOutput() {
+		printJSON(matches)
+		return
+	}
+
 	if len(matches) == 0 {
-		fmt.Fprintf(os.Stderr, "No packs found for: %s\n", query)
+		if !*porcelain {
+			fmt.Fprintf(os.Stderr, "No packs found for: %s\n", query)
+		}
+		return
+	}
+
+	// Porcelain: repo, comma-separated stacks, description.
+	if *porcelain {
+		for _, p := range matches {
+			fmt.Fprintf(os.Stdout, "%s\t%s\t%s\n", p.Repo, strings.Join(p.Stacks, ","), p.Description)
+		}
 		return
 	}
 
-	fmt.Fprintf(os.Stderr, "Available packs matching %q:\n\n", query)
+	fmt.Fprintf(os.Stdout, "Available packs matching %q:\n\n", query)
 	for _, p := range matches {
-		fmt.Fprintf(os.Stderr, "  %-50s %s\n", p.Repo, p.Description)
-		fmt.Fprintf(os.Stderr, "  %s  stacks: %s\n\n",
+		if globals.plain {
+			fmt.Fprintf(os.Stdout, "  %s: %s. Stacks: %s\n", p.Repo, p.Description, strings.Join(p.Stacks, ", "))
+			continue
+		}
+		fmt.Fprintf(os.Stdout, "  %-50s %s\n", p.Repo, p.Description)
+		fmt.Fprintf(os.Stdout, "  %s  stacks: %s\n\n",
 			strings.Repeat(" ", 50), strings.Join(p.Stacks, ", "))
 	}
 	fmt.Fprintf(os.Stderr, "Install with: orchestra pack install <repo>\n")
@@ -326,14 +847,31 @@ func runPackSearch(args []string) {
 // --- recommend ---
 
 func runPackRecommend(args []string) {
-	fs := flag.NewFlagSet("pack recommend", flag.ExitOnError)
+	fs := newFlagSet("pack recommend")
 	workspace := fs.String("workspace", ".", "Project workspace directory")
-	fs.Parse(args)
+	parseFlags(fs, args)
 
-	absWorkspace, _ := filepath.Abs(*workspace)
+	absWorkspace, _ := resolveWorkspace(*workspace)
+	checkWorkspaceSchema(absWorkspace, false)
 
 	stacks := detectStacks(absWorkspace)
 
+	if jsonOutput() {
+		names := []string{}
+		for _, s := range stacks {
+			names = append(names, s.name)
+		}
+		recommended := []packs.CatalogEntry{}
+		if len(names) > 0 {
+			recommended = append(recommended, packs.RecommendFrom(loadPackIndex(false), names)...)
+		}
+		printJSON(struct {
+			Stacks []string             `json:"stacks"`
+			Packs  []packs.CatalogEntry `json:"packs"`
+		}{names, recommended})
+		return
+	}
+
 	if len(stacks) == 0 {
 		fmt.Fprintf(os.Stderr, "No technology stacks detected in %s\n", absWorkspace)
 		return
@@ -348,41 +886,12 @@ func runPackRecommend(args []string) {
 
 	fmt.Fprintf(os.Stderr, "Recommended packs:\n")
 
-	type knownPack struct {
-		Repo   string
-		Stacks []string
-		Desc   string
-	}
-	known := []knownPack{
-		{"github.com/orchestra-mcp/pack-essentials", []string{"*"}, "Core skills and agents"},
-		{"github.com/orchestra-mcp/pack-go-backend", []string{"go"}, "Go backend skills"},
-		{"github.com/orchestra-mcp/pack-rust-engine", []string{"rust"}, "Rust engine skills"},
-		{"github.com/orchestra-mcp/pack-react-frontend", []string{"react", "typescript"}, "React frontend skills"},
-		{"github.com/orchestra-mcp/pack-database", []string{"*"}, "Database skills"},
-		{"github.com/orchestra-mcp/pack-ai", []string{"*"}, "AI/LLM skills"},
-		{"github.com/orchestra-mcp/pack-mobile", []string{"react-native"}, "React Native skills"},
-		{"github.com/orchestra-mcp/pack-desktop", []string{"go"}, "Desktop app skills"},
-		{"github.com/orchestra-mcp/pack-infra", []string{"docker"}, "Infrastructure skills"},
-		{"github.com/orchestra-mcp/pack-proto", []string{"go", "rust"}, "Protobuf/gRPC skills"},
-		{"github.com/orchestra-mcp/pack-native-swift", []string{"swift"}, "Swift/iOS skills"},
-		{"github.com/orchestra-mcp/pack-native-kotlin", []string{"kotlin", "java"}, "Kotlin/Android skills"},
-		{"github.com/orchestra-mcp/pack-native-csharp", []string{"csharp"}, "C#/Windows skills"},
-		{"github.com/orchestra-mcp/pack-native-gtk", []string{"c"}, "GTK4/Linux skills"},
-		{"github.com/orchestra-mcp/pack-analytics", []string{"*"}, "ClickHouse analytics"},
-	}
-
-	stackSet := make(map[string]bool)
-	for _, s := range stacks {
-		stackSet[s.name] = true
-	}
-
-	for _, p := range known {
-		for _, ps := range p.Stacks {
-			if ps == "*" || stackSet[ps] {
-				fmt.Fprintf(os.Stderr, "  %-50s (%s)\n", p.Repo, strings.Join(p.Stacks, ", "))
-				break
-			}
+	for _, p := range packs.RecommendFrom(loadPackIndex(false), stackNames) {
+		if globals.plain {
+			fmt.Fprintf(os.Stderr, "  %s, for %s\n", p.Repo, strings.Join(p.Stacks, ", "))
+			continue
 		}
+		fmt.Fprintf(os.Stderr, "  %-50s (%s)\n", p.Repo, strings.Join(p.Stacks, ", "))
 	}
 
 	fmt.Fprintf(os.Stderr, "\nInstall with: orchestra pack install <repo>\n")
@@ -391,143 +900,215 @@ func runPackRecommend(args []string) {
 // --- helpers ---
 
 func parsePackRepoVersion(raw string) (string, string) {
-	if idx := strings.LastIndex(raw, "@"); idx > 0 {
+	if idx := strings.LastIndex(raw, "@"); idx > 0 && isVersionAt(raw, idx) {
 		return raw[:idx], raw[idx+1:]
 	}
 	return raw, ""
 }
 
-func installPackFromGit(workspace, repo, version string) (*packManifest, error) {
-	if _, err := exec.LookPath("git"); err != nil {
-		return nil, fmt.Errorf("git not found in PATH")
+// resolvePackVersion turns a version range, the @version of a pack
+// argument such as ^1.2, into the tag it selects, ending the command when
+// none does. It returns the tag and the range; any other version comes
+// back as it is, with no range.
+func resolvePackVersion(repo, version string) (string, string) {
+	if !packs.IsConstraint(version) {
+		return version, ""
 	}
-
-	tmpDir, err := os.MkdirTemp("", "orchestra-pack-*")
+	c, err := packs.ParseConstraint(version)
 	if err != nil {
-		return nil, fmt.Errorf("create temp dir: %w", err)
+		fatal("%v", err)
 	}
-	defer os.RemoveAll(tmpDir)
-
-	cloneURL := "https://" + repo + ".git"
-	cloneArgs := []string{"clone", "--depth", "1"}
-	if version != "" {
-		cloneArgs = append(cloneArgs, "--branch", version)
+	sp := startSpinner(fmt.Sprintf("Resolving %s@%s", repo, version))
+	tag, err := resolvePackConstraint(repo, c)
+	sp.Stop(err)
+	if err != nil {
+		fatal("%v", err)
 	}
-	cloneArgs = append(cloneArgs, cloneURL, tmpDir)
+	fmt.Fprintf(os.Stderr, "  %s selects %s\n", c, tag)
+	return tag, c.String()
+}
 
-	cmd := exec.Command("git", cloneArgs...)
-	cmd.Stderr = io.Discard
-	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("git clone %s: %w", cloneURL, err)
+// resolvePackConstraint returns the highest tag of repo that c allows.
+func resolvePackConstraint(repo string, c *packs.Constraint) (string, error) {
+	tags, err := listPackTags(repo)
+	if err != nil {
+		return "", err
+	}
+	tag := c.Best(tags)
+	if tag == "" {
+		return "", fmt.Errorf("no tag of %s matches %s (%d tag(s) listed)", repo, c, len(tags))
 	}
+	return tag, nil
+}
 
-	packJSON, err := os.ReadFile(filepath.Join(tmpDir, "pack.json"))
+// listPackTags lists repo's tags with git ls-remote, without cloning it.
+func listPackTags(repo string) ([]string, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("git not found in PATH")
+	}
+	cloneURL := repoCloneURL(repo)
+	defer debugStep("git ls-remote --tags %s", redactURL(cloneURL)).end()
+	cmd := gitCommand(repo, "ls-remote", "--tags", cloneURL)
+	cmd.Stderr = io.Discard
+	out, err := cmd.Output()
 	if err != nil {
-		return nil, fmt.Errorf("read pack.json: %w (is this a valid pack repo?)", err)
+		return nil, fmt.Errorf("git ls-remote %s: %w", redactURL(cloneURL), err)
+	}
+	var tags []string
+	for _, line := range strings.Split(string(out), "\n") {
+		_, ref, _ := strings.Cut(strings.TrimSpace(line), "\t")
+		// Annotated tags are listed twice; the ^{} line is the commit.
+		if tag, ok := strings.CutPrefix(ref, "refs/tags/"); ok && !strings.HasSuffix(tag, "^{}") {
+			tags = append(tags, tag)
+		}
 	}
+	return tags, nil
+}
 
-	var manifest packManifest
-	if err := json.Unmarshal(packJSON, &manifest); err != nil {
-		return nil, fmt.Errorf("parse pack.json: %w", err)
+func installPackFromGit(workspace, repo, version string, opts packInstallOptions) (*packs.Manifest, packRevision, error) {
+	if err := checkAllowedSource(repo); err != nil {
+		return nil, packRevision{}, err
 	}
+	var manifest *packs.Manifest
+	rev, err := withPackClone(repo, version, func(src fs.FS) error {
+		var err error
+		manifest, err = installPackFromFS(workspace, src, opts)
+		return err
+	})
+	return manifest, rev, err
+}
 
-	claudeDir := filepath.Join(workspace, ".claude")
+// packRevision is the commit a pack was cloned at, and its tag if it has
+// one.
+type packRevision struct {
+	Tag, Commit string
+}
 
-	for _, name := range manifest.Contents.Skills {
-		src := filepath.Join(tmpDir, "skills", name)
-		dst := filepath.Join(claudeDir, "skills", name)
-		if err := copyDirRecursive(src, dst); err != nil {
-			return nil, fmt.Errorf("copy skill %s: %w", name, err)
+// isCommitSHA reports whether version is a full commit hash rather than a
+// tag or branch.
+func isCommitSHA(version string) bool {
+	if len(version) != 40 {
+		return false
+	}
+	for _, c := range version {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
 		}
 	}
+	return true
+}
 
-	for _, name := range manifest.Contents.Agents {
-		src := filepath.Join(tmpDir, "agents", name+".md")
-		dst := filepath.Join(claudeDir, "agents", name+".md")
-		if err := copySingleFile(src, dst); err != nil {
-			return nil, fmt.Errorf("copy agent %s: %w", name, err)
-		}
+// withPackClone shallow-clones repo at version (a tag, branch, or full
+// commit hash) into a temp directory and calls fn with it. The clone is
+// removed when fn returns. It returns the revision it cloned.
+func withPackClone(repo, version string, fn func(src fs.FS) error) (packRevision, error) {
+	var rev packRevision
+	if _, err := exec.LookPath("git"); err != nil {
+		return rev, fmt.Errorf("git not found in PATH")
+	}
+
+	tmpDir, err := os.MkdirTemp("", "orchestra-pack-*")
+	if err != nil {
+		return rev, fmt.Errorf("create temp dir: %w", err)
 	}
+	defer os.RemoveAll(tmpDir)
 
-	for _, name := range manifest.Contents.Hooks {
-		src := filepath.Join(tmpDir, "hooks", name+".sh")
-		dst := filepath.Join(claudeDir, "hooks", name+".sh")
-		if err := copySingleFile(src, dst); err != nil {
-			return nil, fmt.Errorf("copy hook %s: %w", name, err)
+	cloneURL := repoCloneURL(repo)
+	git := func(args ...string) (string, error) {
+		cmd := gitCommand(repo, append([]string{"-C", tmpDir}, args...)...)
+		cmd.Stderr = io.Discard
+		out, err := cmd.Output()
+		return strings.TrimSpace(string(out)), err
+	}
+	step := debugStep("git clone %s", redactURL(cloneURL))
+	if isCommitSHA(version) {
+		// A commit cannot be cloned by name; fetch just that one.
+		for _, args := range [][]string{{"init", "-q"}, {"fetch", "-q", "--depth", "1", cloneURL, version}, {"checkout", "-q", "FETCH_HEAD"}} {
+			if _, err := git(args...); err != nil {
+				return rev, fmt.Errorf("git fetch %s %s: %w", redactURL(cloneURL), version, err)
+			}
+		}
+	} else {
+		cmd := gitCommand(repo, cloneArgs(repo, version, tmpDir, true)...)
+		cmd.Stderr = io.Discard
+		if err := cmd.Run(); err != nil {
+			return rev, fmt.Errorf("git clone %s: %w", redactURL(cloneURL), err)
 		}
-		os.Chmod(dst, 0755)
 	}
+	step.end()
+	rev.Commit, _ = git("rev-parse", "HEAD")
+	rev.Tag, _ = git("describe", "--tags", "--exact-match", "HEAD")
 
-	return &manifest, nil
+	return rev, fn(os.DirFS(tmpDir))
 }
 
-func removePackFiles(workspace string, skills, agents, hooks []string) {
-	claudeDir := filepath.Join(workspace, ".claude")
-	for _, name := range skills {
-		os.RemoveAll(filepath.Join(claudeDir, "skills", name))
-	}
-	for _, name := range agents {
-		os.Remove(filepath.Join(claudeDir, "agents", name+".md"))
-	}
-	for _, name := range hooks {
-		os.Remove(filepath.Join(claudeDir, "hooks", name+".sh"))
+// installPackFromFS installs the pack rooted at src through the shared
+// engine and reports its warnings. Incompatible packs and content owned by
+// another pack are refused unless opts.Force is set, and content that does
+// not match its signature is always refused. The org policy can require
+// signatures whatever opts say.
+func installPackFromFS(workspace string, src fs.FS, opts packInstallOptions) (*packs.Manifest, error) {
+	defer debugStep("install pack content").end()
+	opts = withOrgPolicy(opts)
+	res, err := packs.Install(workspace, src, loadPackRegistry(workspace), packs.Options{
+		Version:       Version,
+		Force:         opts.Force,
+		RequireSigned: opts.RequireSigned,
+		SkipVerify:    opts.Embedded,
+		OS:            opts.OS,
+	})
+	switch {
+	case errors.Is(err, packs.ErrIncompatible), errors.Is(err, packs.ErrConflict):
+		return nil, fmt.Errorf("%w (use --force to install anyway)", err)
+	case errors.Is(err, packs.ErrVerify) && opts.RequireSigned:
+		return nil, fmt.Errorf("%w (--require-signed or the org policy)", err)
+	case err != nil:
+		return nil, err
 	}
-}
 
-func loadPackRegistry(workspace string) *packRegistry {
-	path := filepath.Join(workspace, ".projects", ".packs", "registry.json")
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return &packRegistry{Packs: make(map[string]*packEntry)}
+	m := res.Manifest
+	for _, w := range res.Warnings {
+		switch {
+		case errors.Is(w, packs.ErrIncompatible), errors.Is(w, packs.ErrConflict):
+			printStatus(tagWarn, "%v; installing anyway (--force)", w)
+		case errors.Is(w, packs.ErrUnsigned):
+			printStatus(tagWarn, "%s is unsigned; its publisher cannot be verified", m.Name)
+		case errors.Is(w, packs.ErrUntrusted):
+			printStatus(tagWarn, "%v", w)
+			fmt.Fprintf(os.Stderr, "         Trust it with 'orchestra pack trust add %s <public-key>'.\n", res.Signer)
+		default:
+			printStatus(tagWarn, "%v", w)
+		}
 	}
-	var reg packRegistry
-	if err := json.Unmarshal(data, &reg); err != nil {
-		return &packRegistry{Packs: make(map[string]*packEntry)}
+	if m.Publisher != "" {
+		printStatus(tagOK, "verified publisher: %s", m.Publisher)
 	}
-	if reg.Packs == nil {
-		reg.Packs = make(map[string]*packEntry)
+	for _, p := range res.Overlaid {
+		printStatus(tagOK, "kept local %s (from %s)", p, packs.OverlayPath(p))
 	}
-	return &reg
+	return m, nil
 }
 
-func savePackRegistry(workspace string, reg *packRegistry) {
-	dir := filepath.Join(workspace, ".projects", ".packs")
-	os.MkdirAll(dir, 0755)
-	data, _ := json.MarshalIndent(reg, "", "  ")
-	os.WriteFile(filepath.Join(dir, "registry.json"), data, 0644)
+// loadPackRegistry reads the workspace's pack registry; a missing or
+// damaged file is an empty registry.
+func loadPackRegistry(workspace string) *packs.Registry {
+	return packs.LoadRegistry(workspace)
 }
 
-func copyDirRecursive(src, dst string) error {
-	if err := os.MkdirAll(dst, 0755); err != nil {
-		return err
+func savePackRegistry(workspace string, reg *packs.Registry) {
+	// A pack install may be what creates .projects/; stamp it as current.
+	if _, ok := readWorkspaceSchema(workspace); !ok {
+		stampWorkspaceSchema(workspace)
 	}
-	entries, err := os.ReadDir(src)
-	if err != nil {
-		return err
+	save := reg.Save
+	if packLockFrozen {
+		save = reg.SaveKeepLock
 	}
-	for _, entry := range entries {
-		srcPath := filepath.Join(src, entry.Name())
-		dstPath := filepath.Join(dst, entry.Name())
-		if entry.IsDir() {
-			if err := copyDirRecursive(srcPath, dstPath); err != nil {
-				return err
-			}
-		} else {
-			if err := copySingleFile(srcPath, dstPath); err != nil {
-				return err
-			}
-		}
+	if err := save(workspace); err != nil {
+		printStatus(tagWarn, "write pack registry: %v", err)
 	}
-	return nil
 }
 
-func copySingleFile(src, dst string) error {
-	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
-		return err
-	}
-	data, err := os.ReadFile(src)
-	if err != nil {
-		return err
-	}
-	return os.WriteFile(dst, data, 0644)
-}
+// packLockFrozen is set by the commands that install what packs.lock says,
+// which must leave it as it is even when they stop halfway.
+var packLockFrozen bool
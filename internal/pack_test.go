@@ -0,0 +1,62 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/orchestra-mcp/cli/pkg/packs"
+)
+
+// TestPackInstallRemove installs a pack through the CLI's wrappers around
+// the pack engine and removes it with `orchestra pack remove`.
+func TestPackInstallRemove(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	workspace := t.TempDir()
+	src := fstest.MapFS{
+		"pack.json":              {Data: []byte(`{"name":"pack-demo","version":"1.0.0","contents":{"skills":["review"],"agents":["planner"]}}`)},
+		"skills/review/SKILL.md": {Data: []byte("---\r\nname: review\r\n---\r\nReview the diff.\r\n")},
+		"agents/planner.md":      {Data: []byte("---\nname: planner\n---\nPlan the work.\n")},
+	}
+
+	manifest, err := installPackFromFS(workspace, src, packInstallOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordInstalledPack(workspace, "github.com/acme/pack-demo", manifest, "", packRevision{Tag: "v1.0.0", Commit: "0123abc"}, packInstallOptions{})
+
+	reg := packs.LoadRegistry(workspace)
+	e := reg.Packs["pack-demo"]
+	if e == nil || e.Repo != "github.com/acme/pack-demo" || e.Commit != "0123abc" {
+		t.Fatalf("registry entry = %+v", e)
+	}
+	lock, err := packs.LoadLock(workspace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pin := lock.Packs["pack-demo"]; pin == nil || !pin.Satisfied(e) {
+		t.Errorf("packs.lock entry %+v does not pin the installed pack", pin)
+	}
+	if _, ok := readWorkspaceSchema(workspace); !ok {
+		t.Error("installing into a new workspace did not stamp .schema.json")
+	}
+	skill := filepath.Join(workspace, ".claude", "skills", "review", "SKILL.md")
+	if data, err := os.ReadFile(skill); err != nil || string(data) != "---\nname: review\n---\nReview the diff.\n" {
+		t.Errorf("installed skill = %q, %v; want it with LF line endings", data, err)
+	}
+
+	runPackRemove([]string{"--workspace", workspace, "pack-demo"})
+	if _, err := os.Stat(skill); !os.IsNotExist(err) {
+		t.Errorf("skill still installed after pack remove: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(workspace, ".claude", "agents", "planner.md")); !os.IsNotExist(err) {
+		t.Errorf("agent still installed after pack remove: %v", err)
+	}
+	if reg := packs.LoadRegistry(workspace); len(reg.Packs) != 0 {
+		t.Errorf("registry after pack remove = %+v, want empty", reg.Packs)
+	}
+	if lock, err := packs.LoadLock(workspace); err != nil || len(lock.Packs) != 0 {
+		t.Errorf("packs.lock after pack remove = %+v, %v; want empty", lock, err)
+	}
+}
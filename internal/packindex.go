@@ -0,0 +1,234 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/orchestra-mcp/cli/pkg/packs"
+)
+
+// Pack indexes are the catalogs `pack search`, `pack recommend`, `browse`,
+// and `orchestra search` read: JSON documents (packs.Index) at URLs or local
+// paths, listed under pack_indexes: in ~/.orchestra/config.yaml or in
+// ORCHESTRA_PACK_INDEX. Fetched indexes are cached for packIndexTTL; when
+// none can be fetched or read from the cache, the built-in packs.Catalog
+// stands in.
+
+// defaultPackIndex is the index orchestra-mcp publishes its packs in.
+const defaultPackIndex = "https://raw.githubusercontent.com/orchestra-mcp/pack-index/main/index.json"
+
+// packIndexTTL is how long a fetched index is used before it is fetched
+// again.
+const packIndexTTL = 24 * time.Hour
+
+// packIndexSources returns the configured index sources, highest priority
+// first: ORCHESTRA_PACK_INDEX (comma-separated), then pack_indexes: in the
+// global config, then defaultPackIndex.
+func packIndexSources() []string {
+	if env := splitCSV(os.Getenv("ORCHESTRA_PACK_INDEX")); len(env) > 0 {
+		return env
+	}
+	if cfg := loadGlobalConfig().PackIndexes; len(cfg) > 0 {
+		return cfg
+	}
+	return []string{defaultPackIndex}
+}
+
+// isRemoteIndex reports whether source is a URL rather than a local file.
+func isRemoteIndex(source string) bool {
+	return strings.HasPrefix(source, "https://") || strings.HasPrefix(source, "http://")
+}
+
+// cachedPackIndex is a fetched index in ~/.orchestra/cache/pack-index/.
+type cachedPackIndex struct {
+	Source  string               `json:"source"`
+	Fetched time.Time            `json:"fetched"`
+	Packs   []packs.CatalogEntry `json:"packs"`
+}
+
+func packIndexCachePath(source string) string {
+	sum := sha256.Sum256([]byte(source))
+	return filepath.Join(orchestraCacheDir(), "pack-index", hex.EncodeToString(sum[:8])+".json")
+}
+
+// readCachedPackIndex returns the cached copy of source, or nil.
+func readCachedPackIndex(source string) *cachedPackIndex {
+	data, err := os.ReadFile(packIndexCachePath(source))
+	if err != nil {
+		return nil
+	}
+	var c cachedPackIndex
+	if json.Unmarshal(data, &c) != nil || c.Source != source {
+		return nil
+	}
+	return &c
+}
+
+// fetchPackIndex reads source: a URL over HTTP, caching the result, or a
+// local file as it is now.
+func fetchPackIndex(source string) ([]packs.CatalogEntry, error) {
+	if !isRemoteIndex(source) {
+		data, err := os.ReadFile(expandHome(strings.TrimPrefix(source, "file://")))
+		if err != nil {
+			return nil, err
+		}
+		return packs.ParseIndex(data)
+	}
+
+	resp, err := githubGetCached(source, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, httpCacheMaxBody))
+	if err != nil {
+		return nil, err
+	}
+	entries, err := packs.ParseIndex(data)
+	if err != nil {
+		return nil, err
+	}
+
+	c, _ := json.MarshalIndent(cachedPackIndex{Source: source, Fetched: time.Now().UTC(), Packs: entries}, "", "  ")
+	path := packIndexCachePath(source)
+	if os.MkdirAll(filepath.Dir(path), 0755) == nil {
+		writeFileAtomic(path, append(c, '\n'), 0644)
+	}
+	return entries, nil
+}
+
+// loadPackIndex returns the packs of every index source, merged: a repo
+// listed by more than one source takes the entry of the first. A remote
+// index is fetched when its cached copy is older than packIndexTTL, or
+// always with refresh; if that fails, the stale copy is used with a
+// warning. With no index available at all, it returns the built-in
+// catalog.
+func loadPackIndex(refresh bool) []packs.CatalogEntry {
+	var merged []packs.CatalogEntry
+	seen := map[string]bool{}
+	for _, source := range packIndexSources() {
+		entries, err := packIndexEntries(source, refresh)
+		if err != nil {
+			// Offline without a cached copy of the default index, the
+			// built-in catalog is what we want; say so only with --debug.
+			if source == defaultPackIndex {
+				debugf("pack index %s: %v", source, err)
+			} else {
+				printStatus(tagWarn, "pack index %s: %v", redactURL(source), err)
+			}
+			continue
+		}
+		for _, p := range entries {
+			if !seen[p.Repo] {
+				seen[p.Repo] = true
+				merged = append(merged, p)
+			}
+		}
+	}
+	if merged == nil {
+		debugf("pack index: none available, using the built-in catalog")
+		return packs.Catalog
+	}
+	return merged
+}
+
+// packIndexEntries returns one source's packs for loadPackIndex.
+func packIndexEntries(source string, refresh bool) ([]packs.CatalogEntry, error) {
+	if !isRemoteIndex(source) {
+		return fetchPackIndex(source)
+	}
+	cached := readCachedPackIndex(source)
+	if cached != nil && !refresh && time.Since(cached.Fetched) < packIndexTTL {
+		return cached.Packs, nil
+	}
+	entries, err := fetchPackIndex(source)
+	if err != nil && cached != nil {
+		printStatus(tagWarn, "pack index %s: %v; using the copy fetched %s ago", redactURL(source), err, formatAge(time.Since(cached.Fetched)))
+		return cached.Packs, nil
+	}
+	return entries, err
+}
+
+// --- commands ---
+
+func runPackIndexRefresh(args []string) {
+	fs := newFlagSet("pack index refresh")
+	parseFlags(fs, args)
+
+	failed := false
+	for _, source := range packIndexSources() {
+		entries, err := fetchPackIndex(source)
+		if err != nil {
+			printStatus(tagFail, "%s: %v", redactURL(source), err)
+			failed = true
+			continue
+		}
+		printStatus(tagOK, "%s: %d pack(s)", redactURL(source), len(entries))
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func runPackIndexList(args []string) {
+	fs := newFlagSet("pack index list")
+	porcelain := fs.Bool("porcelain", false, "Stable tab-separated output for scripts")
+	parseFlags(fs, args)
+
+	type indexJSON struct {
+		Source  string     `json:"source"`
+		Remote  bool       `json:"remote"`
+		Packs   *int       `json:"packs"`   // null when not fetched or unreadable
+		Fetched *time.Time `json:"fetched"` // null for local files and indexes never fetched
+		Error   string     `json:"error,omitempty"`
+	}
+	list := []indexJSON{}
+
+	// Porcelain: source, pack count, fetched (RFC 3339; empty for local
+	// files and indexes never fetched).
+	tw := newTable(os.Stdout)
+	for _, source := range packIndexSources() {
+		j := indexJSON{Source: redactURL(source), Remote: isRemoteIndex(source)}
+		count, fetched, age := "-", "", "not fetched"
+		if !isRemoteIndex(source) {
+			age = "local file"
+			if entries, err := fetchPackIndex(source); err == nil {
+				n := len(entries)
+				count, j.Packs = fmt.Sprint(n), &n
+			} else {
+				age = err.Error()
+				j.Error = age
+			}
+		} else if c := readCachedPackIndex(source); c != nil {
+			count, fetched = fmt.Sprint(len(c.Packs)), c.Fetched.Format(time.RFC3339)
+			age = "fetched " + formatAge(time.Since(c.Fetched)) + " ago"
+			n := len(c.Packs)
+			j.Packs, j.Fetched = &n, &c.Fetched
+		}
+		list = append(list, j)
+		if jsonOutput() {
+			continue
+		}
+		if *porcelain {
+			fmt.Fprintf(os.Stdout, "%s\t%s\t%s\n", redactURL(source), count, fetched)
+		} else {
+			fmt.Fprintf(tw, "  %s\t%s pack(s)\t%s\n", redactURL(source), count, age)
+		}
+	}
+	if jsonOutput() {
+		printJSON(list)
+		return
+	}
+	tw.Flush()
+}
@@ -0,0 +1,219 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/orchestra-mcp/cli/pkg/packs"
+)
+
+// Pack authoring commands: `pack init` scaffolds a pack repository and
+// `pack validate` checks one before it is published (packs.Validate).
+
+// --- init ---
+
+func runPackInit(args []string) {
+	fs := newFlagSet("pack init")
+	dirFlag := fs.String("dir", "", "Directory to create the pack in (default: ./<name without owner>)")
+	description := fs.String("description", "", "One-line description for pack.json and the README")
+	stacks := fs.String("stacks", "", "Comma-separated stacks the pack suits, or * for every project")
+	tags := fs.String("tags", "", "Comma-separated tags for pack search")
+	yes := fs.Bool("yes", false, "Ask nothing; use the flags and defaults")
+	force := fs.Bool("force", false, "Scaffold into a directory that is not empty, keeping the files already there")
+	parseFlags(fs, args)
+
+	if fs.NArg() < 1 {
+		fatal("usage: orchestra pack init <name> [flags]")
+	}
+	name := fs.Arg(0)
+	if !packs.ValidName(name) {
+		fatal("%q is not a pack name: use <name> or <owner>/<name> of letters, digits, '.', '_', and '-'", name)
+	}
+	dir := *dirFlag
+	if dir == "" {
+		dir = path.Base(name)
+	}
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 && !*force {
+		fatal("%s is not empty; pass --force to add the missing files", dir)
+	}
+
+	prompt := func(question, def string) string {
+		if *yes {
+			return def
+		}
+		return ask(question, def)
+	}
+	short := strings.TrimPrefix(path.Base(name), "pack-")
+	if short == "" {
+		short = path.Base(name)
+	}
+	if *description == "" {
+		*description = prompt("Description:", "")
+	}
+	if *stacks == "" {
+		if !*yes {
+			fmt.Fprintf(os.Stderr, "  Stacks orchestra detects: %s\n", strings.Join(knownStacks(), ", "))
+		}
+		*stacks = prompt("Stacks (comma-separated, * for every project):", "*")
+	}
+	if *tags == "" {
+		*tags = prompt("Tags (comma-separated):", "")
+	}
+
+	m := packs.Manifest{
+		Name:        name,
+		Description: *description,
+		Version:     "0.1.0",
+		Stacks:      splitCSV(*stacks),
+		Contents:    packs.Contents{Skills: []string{short}, Agents: []string{short}, Hooks: []string{}},
+		Tags:        splitCSV(*tags),
+	}
+	if m.Stacks == nil {
+		m.Stacks = []string{"*"}
+	}
+	if m.Tags == nil {
+		m.Tags = []string{}
+	}
+	manifest, _ := json.MarshalIndent(m, "", "  ")
+
+	files := []struct {
+		rel     string
+		content string
+	}{
+		{"pack.json", string(manifest) + "\n"},
+		{filepath.Join("skills", short, "SKILL.md"), packSkillStub(short)},
+		{filepath.Join("agents", short+".md"), packAgentStub(short)},
+		{filepath.Join("hooks", ".gitkeep"), ""},
+		{"README.md", packReadmeStub(m)},
+	}
+	for _, f := range files {
+		p := filepath.Join(dir, f.rel)
+		if _, err := os.Stat(p); err == nil {
+			printStatus(tagSkip, "%s (exists)", f.rel)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			fatal("%v", err)
+		}
+		if err := writeFileAtomic(p, []byte(f.content), 0644); err != nil {
+			fatal("write %s: %v", f.rel, err)
+		}
+		printStatus(tagOK, "%s", f.rel)
+	}
+
+	fmt.Fprintf(os.Stderr, "\nCreated pack %s in %s\n", name, dir)
+	fmt.Fprintf(os.Stderr, "  Edit skills/%s/SKILL.md and agents/%s.md, list new content in pack.json,\n", short, short)
+	fmt.Fprintf(os.Stderr, "  then run 'orchestra pack validate %s' before publishing.\n", dir)
+}
+
+func splitCSV(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func packSkillStub(name string) string {
+	return fmt.Sprintf(`---
+name: %s
+description: What this skill does and when an agent should use it. Agents read this line to pick the skill.
+---
+
+# %s
+
+Steps the agent follows, written as instructions.
+
+## Checklist
+
+- [ ] ...
+`, name, name)
+}
+
+func packAgentStub(name string) string {
+	return fmt.Sprintf(`---
+name: %s
+description: What this agent is for and when to hand work to it.
+---
+
+# %s Agent
+
+You are ...
+
+## Responsibilities
+
+- ...
+`, name, name)
+}
+
+func packReadmeStub(m packs.Manifest) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", m.Name)
+	if m.Description != "" {
+		fmt.Fprintf(&b, "%s\n\n", m.Description)
+	}
+	repo := m.Name
+	if !strings.Contains(repo, "/") {
+		repo = "<owner>/" + repo
+	}
+	fmt.Fprintf(&b, "## Install\n\n```bash\norchestra pack install github.com/%s\n```\n\n", repo)
+	fmt.Fprintf(&b, "## Contents\n\n")
+	for _, s := range m.Contents.Skills {
+		fmt.Fprintf(&b, "- Skill `%s`\n", s)
+	}
+	for _, a := range m.Contents.Agents {
+		fmt.Fprintf(&b, "- Agent `%s`\n", a)
+	}
+	fmt.Fprintf(&b, "\n## Development\n\n```bash\norchestra pack validate     # check pack.json and the files it lists\norchestra pack sign --publisher=NAME\n```\n\nTag releases with the version in pack.json (`v0.1.0`).\n")
+	return b.String()
+}
+
+// --- validate ---
+
+func runPackValidate(args []string) {
+	fs := newFlagSet("pack validate")
+	strict := fs.Bool("strict", false, "Fail on warnings too")
+	porcelain := fs.Bool("porcelain", false, "Stable tab-separated output for scripts")
+	parseFlags(fs, args)
+
+	dir := "."
+	if fs.NArg() > 0 {
+		dir = fs.Arg(0)
+	}
+	problems := packs.Validate(os.DirFS(dir), knownStacks())
+	errs, warnings := 0, 0
+	for _, p := range problems {
+		level := "error"
+		if p.Warning {
+			level = "warning"
+			warnings++
+		} else {
+			errs++
+		}
+		// Porcelain: level, file, message.
+		if *porcelain {
+			fmt.Fprintf(os.Stdout, "%s\t%s\t%s\n", level, p.File, p.Message)
+		} else if p.Warning {
+			printStatus(tagWarn, "%s", p)
+		} else {
+			printStatus(tagFail, "%s", p)
+		}
+	}
+	if !*porcelain {
+		switch {
+		case len(problems) == 0:
+			printStatus(tagOK, "%s is ready to publish", dir)
+		default:
+			fmt.Fprintf(os.Stderr, "\n%d error(s), %d warning(s)\n", errs, warnings)
+		}
+	}
+	if errs > 0 || (*strict && warnings > 0) {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,256 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/orchestra-mcp/cli/pkg/packs"
+)
+
+// Pack signing commands. The pack.sum/pack.sig format, the trust store,
+// and verification live in pkg/packs; these commands manage keys and
+// trust and sign pack directories.
+
+// signingKeyPath is where `pack keygen` stores a publisher's private key.
+func signingKeyPath(publisher string) string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".orchestra", "keys", publisher+".key")
+}
+
+// --- keygen ---
+
+func runPackKeygen(args []string) {
+	fs := newFlagSet("pack keygen")
+	parseFlags(fs, args)
+
+	if fs.NArg() < 1 {
+		fatal("usage: orchestra pack keygen <publisher>")
+	}
+	publisher := fs.Arg(0)
+	keyPath := signingKeyPath(publisher)
+	if _, err := os.Stat(keyPath); err == nil {
+		fatal("%s already exists; remove it first to make a new key", keyPath)
+	}
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		fatal("generate key: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(keyPath), 0700); err != nil {
+		fatal("%v", err)
+	}
+	if err := writeFileAtomic(keyPath, []byte(base64.StdEncoding.EncodeToString(priv.Seed())+"\n"), 0600); err != nil {
+		fatal("write key: %v", err)
+	}
+
+	pubKey := base64.StdEncoding.EncodeToString(pub)
+	printStatus(tagOK, "private key: %s (keep it secret)", keyPath)
+	fmt.Fprintf(os.Stdout, "%s\n", pubKey)
+	fmt.Fprintf(os.Stderr, "Users trust your packs with:\n  orchestra pack trust add %s %s\n", publisher, pubKey)
+}
+
+// --- sign ---
+
+func runPackSign(args []string) {
+	fs := newFlagSet("pack sign")
+	publisher := fs.String("publisher", "", "Publisher name to sign as")
+	keyFile := fs.String("key", "", "Private key file (default ~/.orchestra/keys/<publisher>.key)")
+	parseFlags(fs, args)
+
+	if *publisher == "" {
+		fatal("usage: orchestra pack sign [dir] --publisher=NAME")
+	}
+	dir := "."
+	if fs.NArg() > 0 {
+		dir = fs.Arg(0)
+	}
+	if *keyFile == "" {
+		*keyFile = signingKeyPath(*publisher)
+	}
+
+	seedData, err := os.ReadFile(*keyFile)
+	if err != nil {
+		fatal("read key: %v (create one with 'orchestra pack keygen %s')", err, *publisher)
+	}
+	seed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(seedData)))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		fatal("%s is not an orchestra signing key", *keyFile)
+	}
+	priv := ed25519.NewKeyFromSeed(seed)
+
+	src := os.DirFS(dir)
+	manifest, err := packs.ReadManifest(src)
+	if err != nil {
+		fatal("%v", err)
+	}
+	sums, sigData, err := packs.Sign(src, manifest, *publisher, priv)
+	if err != nil {
+		fatal("hash pack contents: %v", err)
+	}
+
+	if err := writeFileAtomic(filepath.Join(dir, packs.SumFile), sums, 0644); err != nil {
+		fatal("write %s: %v", packs.SumFile, err)
+	}
+	if err := writeFileAtomic(filepath.Join(dir, packs.SigFile), sigData, 0644); err != nil {
+		fatal("write %s: %v", packs.SigFile, err)
+	}
+	printStatus(tagOK, "signed %s@%s as %s (%d files)", manifest.Name, manifest.Version, *publisher, bytes.Count(sums, []byte("\n")))
+}
+
+// --- verify ---
+
+func runPackVerify(args []string) {
+	fs := newFlagSet("pack verify")
+	parseFlags(fs, args)
+
+	dir := "."
+	if fs.NArg() > 0 {
+		dir = fs.Arg(0)
+	}
+	src := os.DirFS(dir)
+	manifest, err := packs.ReadManifest(src)
+	if err != nil {
+		fatal("%v", err)
+	}
+	ts, err := packs.LoadTrustStore()
+	if err != nil {
+		fatal("%v", err)
+	}
+	publisher, err := packs.Verify(src, manifest, ts)
+	switch {
+	case err == nil:
+		printStatus(tagOK, "verified publisher: %s", publisher)
+	case errors.Is(err, packs.ErrUnsigned), errors.Is(err, packs.ErrUntrusted):
+		printStatus(tagWarn, "%v", err)
+		os.Exit(1)
+	default:
+		fatal("%v", err)
+	}
+}
+
+// --- trust ---
+
+func runPackTrustAdd(args []string) {
+	fs := newFlagSet("pack trust add")
+	parseFlags(fs, args)
+
+	if fs.NArg() < 2 {
+		fatal("usage: orchestra pack trust add <publisher> <public-key>")
+	}
+	publisher, key := fs.Arg(0), fs.Arg(1)
+	if raw, err := base64.StdEncoding.DecodeString(key); err != nil || len(raw) != ed25519.PublicKeySize {
+		fatal("%q is not an ed25519 public key (base64)", key)
+	}
+
+	ts, err := packs.LoadTrustStore()
+	if err != nil {
+		fatal("%v", err)
+	}
+	if ts.Trusts(publisher, key) {
+		printStatus(tagSkip, "%s key %s is already trusted", publisher, packs.KeyID(key))
+		return
+	}
+	ts.Publishers[publisher] = append(ts.Publishers[publisher], key)
+	if err := ts.Save(); err != nil {
+		fatal("write trust store: %v", err)
+	}
+	printStatus(tagOK, "trusted %s key %s", publisher, packs.KeyID(key))
+}
+
+func runPackTrustRemove(args []string) {
+	fs := newFlagSet("pack trust remove")
+	parseFlags(fs, args)
+
+	if fs.NArg() < 1 {
+		fatal("usage: orchestra pack trust remove <publisher> [public-key]")
+	}
+	publisher := fs.Arg(0)
+
+	ts, err := packs.LoadTrustStore()
+	if err != nil {
+		fatal("%v", err)
+	}
+	keys, ok := ts.Publishers[publisher]
+	if !ok {
+		fatal("publisher %q is not in the trust store", publisher)
+	}
+	if fs.NArg() < 2 {
+		delete(ts.Publishers, publisher)
+	} else {
+		var kept []string
+		for _, k := range keys {
+			if k != fs.Arg(1) {
+				kept = append(kept, k)
+			}
+		}
+		if len(kept) == len(keys) {
+			fatal("%s has no key %s", publisher, fs.Arg(1))
+		}
+		if len(kept) == 0 {
+			delete(ts.Publishers, publisher)
+		} else {
+			ts.Publishers[publisher] = kept
+		}
+	}
+	if err := ts.Save(); err != nil {
+		fatal("write trust store: %v", err)
+	}
+	printStatus(tagOK, "removed %s from the trust store", publisher)
+}
+
+func runPackTrustList(args []string) {
+	fs := newFlagSet("pack trust list")
+	porcelain := fs.Bool("porcelain", false, "Stable tab-separated output for scripts")
+	parseFlags(fs, args)
+
+	ts, err := packs.LoadTrustStore()
+	if err != nil {
+		fatal("%v", err)
+	}
+	var publishers []string
+	for p := range ts.Publishers {
+		publishers = append(publishers, p)
+	}
+	sortNames(publishers)
+
+	if jsonOutput() {
+		type keyJSON struct {
+			Publisher string `json:"publisher"`
+			KeyID     string `json:"key_id"`
+			PublicKey string `json:"public_key"`
+		}
+		list := []keyJSON{}
+		for _, p := range publishers {
+			for _, k := range ts.Publishers[p] {
+				list = append(list, keyJSON{p, packs.KeyID(k), k})
+			}
+		}
+		printJSON(list)
+		return
+	}
+
+	if len(publishers) == 0 && !*porcelain {
+		fmt.Fprintf(os.Stderr, "No trusted publishers. Add one with: orchestra pack trust add <publisher> <public-key>\n")
+		return
+	}
+
+	// Porcelain: publisher, key id, public key.
+	tw := newTable(os.Stdout)
+	for _, p := range publishers {
+		for _, k := range ts.Publishers[p] {
+			if *porcelain {
+				fmt.Fprintf(os.Stdout, "%s\t%s\t%s\n", p, packs.KeyID(k), k)
+			} else {
+				fmt.Fprintf(tw, "  %s\t%s\t%s\n", p, packs.KeyID(k), k)
+			}
+		}
+	}
+	tw.Flush()
+}
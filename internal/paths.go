@@ -0,0 +1,145 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/orchestra-mcp/cli/pkg/packs"
+)
+
+// resolveWorkspace turns a --workspace value into a canonical absolute path.
+// Symlinked project roots are resolved to their target so that PID files,
+// process matching, and IDE configs agree no matter which path the IDE used
+// to open the project. A path that does not exist yet is returned as-is.
+func resolveWorkspace(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		return resolved, nil
+	}
+	return abs, nil
+}
+
+// samePath reports whether a and b name the same file or directory. It
+// compares inodes when both exist, which handles case-insensitive
+// filesystems (macOS, Windows) and symlinks, and falls back to comparing
+// cleaned paths otherwise.
+func samePath(a, b string) bool {
+	ai, errA := os.Stat(a)
+	bi, errB := os.Stat(b)
+	if errA == nil && errB == nil {
+		return os.SameFile(ai, bi)
+	}
+	return filepath.Clean(a) == filepath.Clean(b)
+}
+
+// writeFileAtomic writes data to path atomically; see packs.WriteFileAtomic.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	return packs.WriteFileAtomic(path, data, perm)
+}
+
+// moveFile renames src to dst. When the rename fails because the two paths
+// are on different filesystems (EXDEV, common when the temp dir or the
+// install dir is a network mount), it copies src into a temp file beside dst
+// and renames that into place instead, preserving atomic replacement.
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("cross-device move: %w", err)
+	}
+	tmpName := tmp.Name()
+	if _, err := io.Copy(tmp, in); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return fmt.Errorf("cross-device move: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	tmp.Close()
+	if err := os.Chmod(tmpName, info.Mode().Perm()); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Rename(tmpName, dst); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	os.Remove(src)
+	return nil
+}
+
+// staleLockAge is how old a lock file must be before it is assumed to be
+// left behind by a process that crashed while holding it.
+const staleLockAge = 30 * time.Second
+
+// acquireLock takes an exclusive lock across processes by creating path
+// with O_EXCL, waiting up to timeout for another holder to release it. A
+// lock whose holder is no longer running, or that is older than
+// staleLockAge, is broken. The returned func releases the lock.
+func acquireLock(path string, timeout time.Duration) (func(), error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if lockIsStale(path) {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%s is held by another process", path)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func lockIsStale(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false // released meanwhile; retry the create
+	}
+	if time.Since(info.ModTime()) > staleLockAge {
+		return true
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return false // still being written
+	}
+	return !processAlive(pid)
+}
@@ -0,0 +1,416 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// `orchestra plan import` turns a markdown plan or PRD into features:
+//
+//	# Checkout v2                      the only H1 is the plan's title
+//	## Background                      a top-level section with prose only
+//	Why we are doing this.             is context, and is skipped
+//	## Cart #frontend                  a section with items is an epic
+//	- Add to cart #P1                  each top-level list item is a feature
+//	- Saved carts (depends on: Add to cart)
+//	  Nested lines and items are the   in its parent epic
+//	  feature's description.
+//	### Payments                       a subsection is a feature, or an
+//	Prose describes it.                epic of its own when it has items
+//	- [x] Already done                 checked items are skipped
+//
+// #words in a title are labels, and #P0 to #P3 set the priority. A
+// dependency is a "(depends on: A, B)" at the end of a title, or a
+// "Depends on: A, B" line in the description, naming other titles in the
+// plan or existing feature IDs. A "**Title**: text" item takes its title
+// from the bold part.
+
+// planItem is a heading or list item read from a plan.
+type planItem struct {
+	title    string
+	heading  bool
+	level    int // heading level; list items are 0
+	parent   int // index of the enclosing heading, or -1
+	children int
+	done     bool // a checked task list item
+	body     []string
+}
+
+var (
+	planHeadingRe = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	planItemRe    = regexp.MustCompile(`^(\s*)(?:[-*+]|\d+[.)])\s+(.*)$`)
+	planCheckRe   = regexp.MustCompile(`^\[([ xX])\]\s+`)
+	planTagRe     = regexp.MustCompile(`(^|\s)#([\w][\w-]*)`)
+	planDependsRe = regexp.MustCompile(`(?i)\s*\(depends on:?\s*([^)]*)\)\s*$`)
+	planDepLineRe = regexp.MustCompile(`(?i)^(?:[-*+]\s+)?depends on:?\s*(.+)$`)
+	planBoldRe    = regexp.MustCompile(`^(?:\*\*|__)(.+?)(?:\*\*|__)(.*)$`)
+	planPriorityT = regexp.MustCompile(`^[Pp][0-3]$`)
+)
+
+func runPlanImport(args []string) {
+	fs := newFlagSet("plan import")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	project := fs.String("project", "", "Project to create features in (default: the only project)")
+	labels := fs.String("label", "", "Comma-separated labels added to every imported feature")
+	skip := fs.String("skip", "", "Comma-separated section headings to leave out, with everything under them")
+	dryRun := fs.Bool("dry-run", false, "Print the backlog without creating anything")
+	yes := fs.Bool("yes", false, "Create the features without asking")
+	parseFlags(fs, args)
+
+	if fs.NArg() != 1 {
+		fatal("usage: orchestra plan import <file.md> [--project=NAME] [--dry-run] [--yes]")
+	}
+	file := fs.Arg(0)
+
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	checkWorkspaceSchema(absWorkspace, true)
+
+	var r io.Reader = os.Stdin
+	if file != "-" {
+		fh, err := os.Open(file)
+		if err != nil {
+			fatal("%v", err)
+		}
+		defer fh.Close()
+		r = fh
+	}
+	items, err := parsePlan(r)
+	if err != nil {
+		fatal("read %s: %v", file, err)
+	}
+	spec, skipped := planSpec(items, splitCSV(*skip), splitCSV(*labels))
+	if len(spec.Features) == 0 {
+		fatal("%s has no features: add list items or subsections under its headings", file)
+	}
+
+	if *project == "" {
+		projects := listFeatureProjects(absWorkspace)
+		if len(projects) != 1 {
+			fatal("no project given; pass --project")
+		}
+		*project = projects[0]
+	}
+	spec.Project = *project
+
+	features, err := planFeatures(absWorkspace, spec, workspaceTemplates(loadWorkspaceConfig(absWorkspace)))
+	if err != nil {
+		fatal("%v", err)
+	}
+
+	printPlanPreview(features)
+	epics := 0
+	for _, f := range features {
+		if f.hasLabel("epic") {
+			epics++
+		}
+	}
+	fmt.Fprintf(os.Stderr, "\n%d feature(s), %d of them epics, from %s into %s\n", len(features), epics, file, spec.Project)
+	for _, s := range skipped {
+		fmt.Fprintf(os.Stderr, "  skipped %s\n", s)
+	}
+
+	if *dryRun {
+		return
+	}
+	if !*yes {
+		if !isTerminal(os.Stdin) || file == "-" {
+			fatal("pass --yes to create the features without a prompt, or --dry-run to only preview them")
+		}
+		fmt.Fprintln(os.Stderr)
+		if !confirm(fmt.Sprintf("Create these %d feature(s)?", len(features))) {
+			printStatus(tagSkip, "nothing created")
+			return
+		}
+	}
+	createPlannedFeatures(absWorkspace, spec.Project, features)
+}
+
+// printPlanPreview prints the planned features as a tree: epics with their
+// children indented under them.
+func printPlanPreview(features []*feature) {
+	depth := map[string]int{}
+	for _, f := range features {
+		if f.Parent != "" {
+			depth[f.ID] = depth[f.Parent] + 1 // parents come before children
+		}
+	}
+	tw := newTable(os.Stdout)
+	for _, f := range features {
+		deps := ""
+		if len(f.DependsOn) > 0 {
+			deps = "after " + strings.Join(f.DependsOn, ",")
+		}
+		fmt.Fprintf(tw, "  %s\t%s%s\t%s\t%s\t%s\n", f.ID, strings.Repeat("  ", depth[f.ID]), f.Title, orDash(strings.Join(f.Labels, ",")), orDash(f.Priority), deps)
+	}
+	tw.Flush()
+}
+
+// --- parsing ---
+
+// parsePlan reads a markdown plan into headings and list items, in order.
+func parsePlan(r io.Reader) ([]*planItem, error) {
+	var lines []string
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for sc.Scan() {
+		lines = append(lines, strings.TrimRight(sc.Text(), " \t\r"))
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	// A lone H1 is the document's title rather than a section.
+	h1s := 0
+	inFence := false
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+		}
+		if m := planHeadingRe.FindStringSubmatch(line); m != nil && !inFence && len(m[1]) == 1 {
+			h1s++
+		}
+	}
+
+	var items []*planItem
+	var stack []int // open headings, outermost first
+	heading, item := -1, -1
+	target := func() *planItem {
+		switch {
+		case item >= 0:
+			return items[item]
+		case heading >= 0:
+			return items[heading]
+		}
+		return nil // text before the first heading
+	}
+	inFence = false
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+		}
+		if inFence || strings.HasPrefix(strings.TrimSpace(line), "```") {
+			if t := target(); t != nil {
+				t.body = append(t.body, dedent(line, item >= 0))
+			}
+			continue
+		}
+
+		if m := planHeadingRe.FindStringSubmatch(line); m != nil {
+			level := len(m[1])
+			item = -1
+			if level == 1 && h1s == 1 {
+				heading, stack = -1, nil
+				continue
+			}
+			for len(stack) > 0 && items[stack[len(stack)-1]].level >= level {
+				stack = stack[:len(stack)-1]
+			}
+			parent := -1
+			if len(stack) > 0 {
+				parent = stack[len(stack)-1]
+				items[parent].children++
+			}
+			items = append(items, &planItem{title: m[2], heading: true, level: level, parent: parent})
+			heading = len(items) - 1
+			stack = append(stack, heading)
+			continue
+		}
+
+		if m := planItemRe.FindStringSubmatch(line); m != nil && len(m[1]) < 2 {
+			text := m[2]
+			done := false
+			if c := planCheckRe.FindStringSubmatch(text); c != nil {
+				done = c[1] != " "
+				text = text[len(c[0]):]
+			}
+			if heading >= 0 {
+				items[heading].children++
+			}
+			items = append(items, &planItem{title: text, parent: heading, done: done})
+			item = len(items) - 1
+			continue
+		}
+
+		// Unindented text after a list belongs to the section again.
+		if item >= 0 && line != "" && !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") {
+			item = -1
+		}
+		if t := target(); t != nil {
+			t.body = append(t.body, dedent(line, item >= 0))
+		}
+	}
+	return items, nil
+}
+
+// dedent removes a list item's continuation indent from line.
+func dedent(line string, inItem bool) string {
+	if !inItem {
+		return line
+	}
+	if strings.HasPrefix(line, "\t") {
+		return line[1:]
+	}
+	for i := 0; i < 4 && strings.HasPrefix(line, " "); i++ {
+		line = line[1:]
+	}
+	return line
+}
+
+// planSpec converts parsed items to a featureSpec batch for planFeatures,
+// with extra labels on every feature. It returns what it left out.
+func planSpec(items []*planItem, skip, extraLabels []string) (*featureFile, []string) {
+	spec := &featureFile{}
+	var skipped []string
+	dropped := make([]bool, len(items))
+	doneCount := 0
+	for i, it := range items {
+		title, _, _, _ := parsePlanTitle(it.title)
+		switch {
+		case it.parent >= 0 && dropped[it.parent]:
+			dropped[i] = true
+		case it.heading && containsFold(skip, title):
+			dropped[i] = true
+			skipped = append(skipped, fmt.Sprintf("%q (--skip)", title))
+		case it.heading && it.parent < 0 && it.children == 0:
+			// A top-level section of prose: background, goals, and the like.
+			dropped[i] = true
+			skipped = append(skipped, fmt.Sprintf("%q (no items)", title))
+		case it.done:
+			dropped[i] = true
+			doneCount++
+		}
+	}
+	if doneCount > 0 {
+		skipped = append(skipped, fmt.Sprintf("%d checked item(s)", doneCount))
+	}
+
+	for i, it := range items {
+		if dropped[i] {
+			continue
+		}
+		title, tags, priority, deps := parsePlanTitle(it.title)
+		body, bodyDeps := planBody(it.body)
+		if lead, rest, ok := splitBoldLead(title); ok && !it.heading {
+			title = lead
+			body = strings.TrimSpace(rest + "\n\n" + body)
+		}
+		s := featureSpec{
+			Key:         planKey(i),
+			Title:       title,
+			Description: body,
+			Priority:    priority,
+			Labels:      appendUnique(tags, extraLabels...),
+			DependsOn:   append(deps, bodyDeps...),
+		}
+		if it.children > 0 && hasKeptChild(items, dropped, i) {
+			s.Template = "epic"
+		}
+		if it.parent >= 0 {
+			s.Parent = planKey(it.parent)
+		}
+		spec.Features = append(spec.Features, s)
+	}
+	return spec, skipped
+}
+
+// splitBoldLead splits "**Title**: text" (or "**Title:** text", or with a
+// dash) into its title and text.
+func splitBoldLead(s string) (title, rest string, ok bool) {
+	m := planBoldRe.FindStringSubmatch(s)
+	if m == nil {
+		return "", "", false
+	}
+	title, rest = strings.TrimSpace(m[1]), strings.TrimSpace(m[2])
+	if strings.HasSuffix(title, ":") {
+		return strings.TrimSpace(strings.TrimSuffix(title, ":")), rest, true
+	}
+	for _, sep := range []string{":", "—", "–", "- "} {
+		if strings.HasPrefix(rest, sep) {
+			return title, strings.TrimSpace(strings.TrimPrefix(rest, sep)), true
+		}
+	}
+	return title, "", rest == ""
+}
+
+// planKey is the featureSpec key of item i, which no title in a plan can
+// clash with.
+func planKey(i int) string {
+	return fmt.Sprintf("plan-item:%d", i)
+}
+
+func hasKeptChild(items []*planItem, dropped []bool, parent int) bool {
+	for i, it := range items {
+		if it.parent == parent && !dropped[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePlanTitle splits a heading or item into its title, #labels, #P0-#P3
+// priority, and "(depends on: ...)" dependencies.
+func parsePlanTitle(text string) (title string, labels []string, priority string, deps []string) {
+	if m := planDependsRe.FindStringSubmatchIndex(text); m != nil {
+		deps = splitPlanRefs(text[m[2]:m[3]])
+		text = text[:m[0]]
+	}
+	for _, m := range planTagRe.FindAllStringSubmatch(text, -1) {
+		if planPriorityT.MatchString(m[2]) {
+			priority = strings.ToUpper(m[2])
+		} else {
+			labels = appendUnique(labels, strings.ToLower(m[2]))
+		}
+	}
+	title = strings.Join(strings.Fields(planTagRe.ReplaceAllString(text, "$1")), " ")
+	return strings.TrimSuffix(title, ":"), labels, priority, deps
+}
+
+// planBody trims a description and takes out its "Depends on:" lines.
+func planBody(lines []string) (string, []string) {
+	var deps []string
+	var kept []string
+	for _, line := range lines {
+		if m := planDepLineRe.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+			deps = append(deps, splitPlanRefs(m[1])...)
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n")), deps
+}
+
+func splitPlanRefs(s string) []string {
+	var refs []string
+	for _, ref := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' }) {
+		if ref = strings.Trim(strings.TrimSpace(ref), "*_`\"."); ref != "" {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// appendUnique appends the values of add not already in list.
+func appendUnique(list []string, add ...string) []string {
+	for _, v := range add {
+		if !containsFold(list, v) {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+func containsFold(list []string, v string) bool {
+	for _, s := range list {
+		if strings.EqualFold(s, v) {
+			return true
+		}
+	}
+	return false
+}
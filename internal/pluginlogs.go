@@ -0,0 +1,171 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Per-plugin logs. Each plugin's own output, the lines it marks with a
+// "[plugin.id]" prefix or a JSON plugin field, goes to
+// <workspace>/.orchestra/run/logs/<plugin-id>.log instead of the serve
+// log. What the orchestrator and serve say about the plugin ("plugin x
+// registered and booted", which readiness counts) stays in the serve log.
+// `orchestra logs` merges the files back into one view. log_levels in
+// .orchestra.yaml sets the minimum level recorded for a plugin, in either
+// file.
+
+// workspaceRunDir returns <workspace>/.orchestra/run/, which holds this
+// machine's runtime files for the workspace.
+func workspaceRunDir(workspace string) string {
+	return filepath.Join(workspace, ".orchestra", "run")
+}
+
+// ignoreRunDir keeps a workspace run directory out of commits of a
+// vendored .orchestra/.
+func ignoreRunDir(dir string) {
+	ignore := filepath.Join(dir, ".gitignore")
+	if _, err := os.Stat(ignore); os.IsNotExist(err) {
+		os.WriteFile(ignore, []byte("*\n"), 0644)
+	}
+}
+
+// pluginLogDir returns <workspace>/.orchestra/run/logs/.
+func pluginLogDir(workspace string) string {
+	return filepath.Join(workspaceRunDir(workspace), "logs")
+}
+
+// pluginLogFiles returns the plugin logs in dir, sorted; none for "".
+func pluginLogFiles(dir string) []string {
+	if dir == "" {
+		return nil
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.log"))
+	sort.Strings(files)
+	return files
+}
+
+// resetPluginLogs removes the plugin logs a previous serve left in dir.
+func resetPluginLogs(dir string) {
+	for _, f := range pluginLogFiles(dir) {
+		os.Remove(f)
+	}
+}
+
+// pluginLogLevels is the minimum level recorded for each plugin: per-plugin
+// overrides from .orchestra.yaml, else everything.
+type pluginLogLevels struct {
+	exact    map[string]int // plugin ID -> logLevelRank
+	patterns []pluginLogLevelPattern
+}
+
+type pluginLogLevelPattern struct {
+	pattern string
+	rank    int
+}
+
+// loadPluginLogLevels reads log_levels from the workspace's .orchestra.yaml.
+func loadPluginLogLevels(workspace string) (pluginLogLevels, error) {
+	l := pluginLogLevels{exact: map[string]int{}}
+	for id, value := range loadWorkspaceConfig(workspace).LogLevels {
+		level := normalizeLogLevel(value)
+		if level == "" {
+			return l, fmt.Errorf("%s: log_levels: %s: level %q is not one of %s", workspaceConfigFile, id, value, strings.Join(logLevels, ", "))
+		}
+		if _, err := path.Match(id, ""); err != nil {
+			return l, fmt.Errorf("%s: log_levels: bad pattern %q: %v", workspaceConfigFile, id, err)
+		}
+		if isGlob(id) {
+			l.patterns = append(l.patterns, pluginLogLevelPattern{id, logLevelRank(level)})
+		} else {
+			l.exact[id] = logLevelRank(level)
+		}
+	}
+	sort.Slice(l.patterns, func(i, j int) bool {
+		if len(l.patterns[i].pattern) != len(l.patterns[j].pattern) {
+			return len(l.patterns[i].pattern) > len(l.patterns[j].pattern)
+		}
+		return l.patterns[i].pattern < l.patterns[j].pattern
+	})
+	return l, nil
+}
+
+// min returns the lowest logLevelRank recorded for plugin: an exact
+// override, then the longest matching pattern, then debug.
+func (l pluginLogLevels) min(plugin string) int {
+	if rank, ok := l.exact[plugin]; ok {
+		return rank
+	}
+	for _, p := range l.patterns {
+		if ok, _ := path.Match(p.pattern, plugin); ok {
+			return p.rank
+		}
+	}
+	return 0
+}
+
+// pluginLogs writes the session's plugins' own records to their files.
+// Records marked with an ID that is not a plugin of the session stay in
+// the serve log.
+type pluginLogs struct {
+	dir    string
+	levels pluginLogLevels
+
+	mu    sync.Mutex
+	known map[string]bool
+	files map[string]*os.File
+}
+
+func newPluginLogs(dir string, levels pluginLogLevels) *pluginLogs {
+	return &pluginLogs{dir: dir, levels: levels, known: map[string]bool{}, files: map[string]*os.File{}}
+}
+
+// setPlugins records the plugins the backend runs; it is called for every
+// backend start.
+func (p *pluginLogs) setPlugins(plugins []pluginConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.known = map[string]bool{}
+	for _, pc := range plugins {
+		p.known[pc.ID] = true
+	}
+}
+
+// write appends data, a marshaled record of plugin's, to the plugin's
+// file. It reports whether plugin is one of the session's plugins and the
+// file could be written.
+func (p *pluginLogs) write(plugin string, data []byte) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.known[plugin] {
+		return false
+	}
+	f := p.files[plugin]
+	if f == nil {
+		if err := os.MkdirAll(p.dir, 0755); err != nil {
+			return false
+		}
+		ignoreRunDir(filepath.Dir(p.dir))
+		var err error
+		f, err = os.OpenFile(filepath.Join(p.dir, plugin+".log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return false
+		}
+		p.files[plugin] = f
+	}
+	_, err := f.Write(append(data, '\n'))
+	return err == nil
+}
+
+func (p *pluginLogs) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for id, f := range p.files {
+		f.Close()
+		delete(p.files, id)
+	}
+}
@@ -2,120 +2,396 @@ package internal
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"sort"
+	"strings"
 )
 
-// RunPlugins handles `orchestra plugins` -- lists all installed third-party plugins.
+// RunPlugins handles `orchestra plugins` -- lists all installed third-party
+// plugins: the global ones and those the workspace vendors.
 func RunPlugins(args []string) {
+	fs := newFlagSet("plugins")
+	porcelain := fs.Bool("porcelain", false, "Stable tab-separated output for scripts")
+	workspace := fs.String("workspace", ".", "Project workspace directory whose vendored plugins are listed")
+	parseFlags(fs, args)
+
 	reg, err := LoadRegistry()
 	if err != nil {
 		fatal("load registry: %v", err)
 	}
+	store := vendorStore(true, *workspace)
+	vendored, err := store.load()
+	if err != nil {
+		fatal("load vendored plugins: %v", err)
+	}
+	sel := loadPluginSelection(store.workspace, io.Discard)
 
-	if len(reg.Plugins) == 0 {
-		fmt.Fprintf(os.Stderr, "No plugins installed. Run: orchestra install <github-repo>\n")
+	if len(reg.Plugins)+len(vendored.Plugins) == 0 {
+		if jsonOutput() {
+			printJSON([]pluginJSON{})
+		} else if !*porcelain {
+			fmt.Fprintf(os.Stderr, "No plugins installed. Run: orchestra install <github-repo>\n")
+		}
 		return
 	}
 
-	fmt.Fprintf(os.Stderr, "Installed plugins:\n")
-	for _, p := range reg.Plugins {
+	plugins := append(sortedPlugins(vendored), sortedPlugins(reg)...)
+
+	if jsonOutput() {
+		list := []pluginJSON{}
+		for _, p := range plugins {
+			list = append(list, describePluginJSON(p, sel))
+		}
+		printJSON(list)
+		return
+	}
+
+	// Porcelain: id, version, repo, binary, tool count, storage count,
+	// prompt count, resource count, scope (global or vendored), state in
+	// the workspace (enabled or disabled).
+	if *porcelain {
+		for _, p := range plugins {
+			scope := "global"
+			if p.Vendored {
+				scope = "vendored"
+			}
+			state := "enabled"
+			if sel.skipReason(p.ID) != "" {
+				state = "disabled"
+			}
+			fmt.Fprintf(os.Stdout, "%s\t%s\t%s\t%s\t%d\t%d\t%d\t%d\t%s\t%s\n",
+				p.ID, p.Version, p.Repo, p.Binary, len(p.Tools()), len(p.ProvidesStorage),
+				len(p.ProvidesPrompts), len(p.ProvidesResources), scope, state)
+		}
+		return
+	}
+
+	fmt.Fprintf(os.Stdout, "Installed plugins:\n")
+	tw := newTable(os.Stdout)
+	for _, p := range plugins {
 		// Build a capability summary.
 		var caps []string
-		if n := len(p.ProvidesTools); n > 0 {
+		if n := len(p.Tools()); n > 0 {
 			caps = append(caps, fmt.Sprintf("%d tools", n))
 		}
 		if n := len(p.ProvidesStorage); n > 0 {
 			caps = append(caps, fmt.Sprintf("%d storage", n))
 		}
+		if n := len(p.ProvidesPrompts); n > 0 {
+			caps = append(caps, fmt.Sprintf("%d prompts", n))
+		}
+		if n := len(p.ProvidesResources); n > 0 {
+			caps = append(caps, fmt.Sprintf("%d resources", n))
+		}
 		capStr := ""
 		if len(caps) > 0 {
-			capStr = "  ("
-			for i, c := range caps {
-				if i > 0 {
-					capStr += ", "
-				}
-				capStr += c
-			}
-			capStr += ")"
+			capStr = "  (" + strings.Join(caps, ", ") + ")"
+		}
+		if pluginIncompatibility(p) != "" {
+			capStr += "  [incompatible]"
+		}
+		if pluginModified(p) != "" {
+			capStr += "  [modified]"
+		}
+		if p.Vendored {
+			capStr += "  [vendored]"
+		}
+		if reason := sel.skipReason(p.ID); reason != "" {
+			capStr += "  [" + reason + "]"
 		}
 
-		fmt.Fprintf(os.Stderr, "  %-24s %-10s %s%s\n", p.ID, p.Version, p.Repo, capStr)
+		fmt.Fprintf(tw, "  %s\t%s\t%s%s\n", p.ID, p.Version, p.Repo, capStr)
 	}
+	tw.Flush()
 }
 
-// RunUninstall handles `orchestra uninstall <plugin-id-or-repo>`.
-func RunUninstall(args []string) {
-	if len(args) < 1 {
-		fatal("usage: orchestra uninstall <plugin-id-or-repo>")
+// runPluginsInfo handles `orchestra plugins info <plugin-id-or-repo>` --
+// prints everything the registry records about one plugin.
+func runPluginsInfo(args []string) {
+	fs := newFlagSet("plugins info")
+	workspace := fs.String("workspace", ".", "Project workspace directory whose vendored plugins are searched first")
+	parseFlags(fs, args)
+
+	if fs.NArg() < 1 {
+		fatal("usage: orchestra plugins info <plugin-id-or-repo>")
+	}
+	var p *PluginEntry
+	for _, store := range []pluginStore{vendorStore(true, *workspace), {}} {
+		reg, err := store.load()
+		if err != nil {
+			fatal("load registry: %v", err)
+		}
+		if _, p = findPlugin(reg, fs.Arg(0)); p != nil {
+			break
+		}
+	}
+	if p == nil {
+		fatal("plugin not found: %s", fs.Arg(0))
 	}
-	target := args[0]
 
-	reg, err := LoadRegistry()
-	if err != nil {
-		fatal("load registry: %v", err)
+	if jsonOutput() {
+		sel := loadPluginSelection(vendorStore(true, *workspace).workspace, io.Discard)
+		printJSON(struct {
+			pluginJSON
+			Compatibility []protocolCheck `json:"compatibility"`
+		}{describePluginJSON(p, sel), checkPluginProtocols(p)})
+		return
 	}
 
-	// Find by repo URL first, then by plugin ID.
-	repoKey := ""
-	var entry *PluginEntry
-	if p, ok := reg.Plugins[target]; ok {
-		repoKey = target
-		entry = p
+	orNone := func(vals []string) string {
+		if len(vals) == 0 {
+			return "-"
+		}
+		return strings.Join(vals, ", ")
+	}
+	fmt.Fprintf(os.Stdout, "ID:           %s\n", p.ID)
+	fmt.Fprintf(os.Stdout, "Version:      %s\n", p.Version)
+	if p.Source == pluginSourceLocal {
+		fmt.Fprintf(os.Stdout, "Source:       %s (local; 'orchestra update' rebuilds it there)\n", p.Repo)
 	} else {
-		for k, p := range reg.Plugins {
-			if p.ID == target {
-				repoKey = k
-				entry = p
-				break
-			}
+		fmt.Fprintf(os.Stdout, "Repo:         %s\n", p.Repo)
+	}
+	if p.Linked {
+		fmt.Fprintf(os.Stdout, "Binary:       %s (symlink to the build)\n", p.Binary)
+	} else {
+		fmt.Fprintf(os.Stdout, "Binary:       %s\n", p.Binary)
+	}
+	fmt.Fprintf(os.Stdout, "Platform:     %s\n", orDash(p.Platform))
+	if p.Vendored {
+		fmt.Fprintf(os.Stdout, "Vendored:     yes (%s)\n", vendorRegistryPath(*workspace))
+	}
+	fmt.Fprintf(os.Stdout, "Installed:    %s\n", orDash(p.InstalledAt))
+	if p.Provenance != nil {
+		fmt.Fprintf(os.Stdout, "Provenance:   %s\n", p.Provenance)
+	} else {
+		fmt.Fprintf(os.Stdout, "Provenance:   - (built from source or installed before provenance checks)\n")
+	}
+	if p.Checksums != nil {
+		fmt.Fprintf(os.Stdout, "Checksums:    %s\n", p.Checksums)
+	} else {
+		fmt.Fprintf(os.Stdout, "Checksums:    - (built from source or installed before checksum checks)\n")
+	}
+	fmt.Fprintf(os.Stdout, "SHA256:       %s\n", orDash(p.SHA256))
+	fmt.Fprintf(os.Stdout, "Tools:        %s\n", orNone(p.Tools()))
+	if p.VerifiedAt != "" {
+		fmt.Fprintf(os.Stdout, "Verified:     %s\n", p.VerifiedAt)
+		if v := compareTools(p.ProvidesTools, p.VerifiedTools); len(v.Missing)+len(v.Extra) > 0 {
+			fmt.Fprintf(os.Stdout, "Claimed:      %s\n", orNone(p.ProvidesTools))
 		}
+	} else {
+		fmt.Fprintf(os.Stdout, "Verified:     no (tools as claimed by the manifest)\n")
 	}
+	fmt.Fprintf(os.Stdout, "Prompts:      %s\n", orNone(p.ProvidesPrompts))
+	fmt.Fprintf(os.Stdout, "Resources:    %s\n", orNone(p.ProvidesResources))
+	fmt.Fprintf(os.Stdout, "Storage:      %s\n", orNone(p.ProvidesStorage))
+	fmt.Fprintf(os.Stdout, "Needs:        %s\n", orNone(p.NeedsStorage))
 
+	fmt.Fprintf(os.Stdout, "\nCompatibility:\n")
+	tw := newTable(os.Stdout)
+	fmt.Fprintf(tw, "  PROTOCOL\tPLUGIN\tORCHESTRA\tNEGOTIATED\tSTATUS\n")
+	for _, c := range checkPluginProtocols(p) {
+		status := c.Status
+		if c.Note != "" {
+			status += ": " + c.Note
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\n", c.Protocol, orDash(c.Plugin), c.Supported, orDash(c.Negotiated), status)
+	}
+	tw.Flush()
+
+	if p.Vendored {
+		if reason := vendoredUnusable(p); reason != "" {
+			printStatus(tagWarn, "'orchestra serve' cannot use the vendored copy (%s) and falls back to a global install. Re-vendor with: orchestra update --vendor %s", reason, p.ID)
+		}
+	} else if _, err := os.Stat(p.Binary); err != nil {
+		printStatus(tagWarn, "binary missing; 'orchestra serve' skips this plugin. Reinstall with: orchestra update %s", p.ID)
+	}
+	if reason := pluginIncompatibility(p); reason != "" {
+		printStatus(tagWarn, "'orchestra serve' skips this plugin: %s", reason)
+	}
+	if reason := pluginModified(p); reason != "" {
+		printStatus(tagWarn, "%s; it may have been tampered with. Reinstall with: orchestra update %s", reason, p.ID)
+	}
+}
+
+// pluginJSON is a plugin as `plugins --json` and `plugins info --json`
+// print it: its registry entry, plus the tools serve exposes and what would
+// keep serve from loading it.
+type pluginJSON struct {
+	*PluginEntry
+	Tools         []string `json:"tools"`
+	Scope         string   `json:"scope"` // global or vendored
+	State         string   `json:"state"` // enabled or disabled in the workspace
+	SkipReason    string   `json:"skip_reason,omitempty"`
+	BinaryMissing bool     `json:"binary_missing,omitempty"`
+	Incompatible  string   `json:"incompatible,omitempty"`
+	Modified      string   `json:"modified,omitempty"`
+}
+
+func describePluginJSON(p *PluginEntry, sel *pluginSelection) pluginJSON {
+	j := pluginJSON{PluginEntry: p, Tools: p.Tools(), Scope: "global", State: "enabled"}
+	if j.Tools == nil {
+		j.Tools = []string{}
+	}
+	if p.Vendored {
+		j.Scope = "vendored"
+	}
+	if j.SkipReason = sel.skipReason(p.ID); j.SkipReason != "" {
+		j.State = "disabled"
+	}
+	if _, err := os.Stat(p.Binary); err != nil {
+		j.BinaryMissing = true
+	}
+	j.Incompatible = pluginIncompatibility(p)
+	j.Modified = pluginModified(p)
+	return j
+}
+
+// findPlugin looks a plugin up by repo, then by plugin ID, and returns its
+// registry key and entry (nil when not installed).
+func findPlugin(reg *PluginRegistry, target string) (string, *PluginEntry) {
+	if p, ok := reg.Plugins[target]; ok {
+		return target, p
+	}
+	for k, p := range reg.Plugins {
+		if p.ID == target {
+			return k, p
+		}
+	}
+	return "", nil
+}
+
+// sortedPlugins returns registry entries ordered by plugin ID.
+func sortedPlugins(reg *PluginRegistry) []*PluginEntry {
+	plugins := make([]*PluginEntry, 0, len(reg.Plugins))
+	for _, p := range reg.Plugins {
+		plugins = append(plugins, p)
+	}
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].ID < plugins[j].ID })
+	return plugins
+}
+
+// RunUninstall handles `orchestra uninstall <plugin-id-or-repo>`.
+func RunUninstall(args []string) {
+	fs := newFlagSet("uninstall")
+	vendor := fs.Bool("vendor", false, "Remove a plugin vendored into the workspace")
+	workspace := fs.String("workspace", ".", "Project workspace directory: with --vendor, the one to remove from; otherwise, whose vendored plugins are checked for dependents")
+	cascade := fs.Bool("cascade", false, "Also uninstall the plugins that need storage only this plugin provides")
+	parseFlags(fs, args)
+
+	if fs.NArg() < 1 {
+		fatal("usage: orchestra uninstall <plugin-id-or-repo> [--cascade]")
+	}
+	target := fs.Arg(0)
+
+	// Dependents can sit in either registry: a vendored plugin may need
+	// storage from a global one, and the other way round.
+	stores := []pluginStore{{}, vendorStore(true, *workspace)}
+	regs := make([]*PluginRegistry, len(stores))
+	var installed []*PluginEntry
+	for i, store := range stores {
+		reg, err := store.load()
+		if err != nil {
+			fatal("load registry: %v", err)
+		}
+		regs[i] = reg
+		installed = append(installed, sortedPlugins(reg)...)
+	}
+
+	from := 0
+	if *vendor {
+		from = 1
+	}
+	_, entry := findPlugin(regs[from], target)
 	if entry == nil {
 		fatal("plugin not found: %s", target)
 	}
 
-	// Delete binary.
-	if err := os.Remove(entry.Binary); err != nil && !os.IsNotExist(err) {
-		fmt.Fprintf(os.Stderr, "  Warning: could not remove binary %s: %v\n", entry.Binary, err)
+	remove, dependents := storageRemovalSet(installed, entry)
+	if len(dependents) > 0 {
+		printStatus(tagWarn, "%d plugin(s) need storage that would be gone without %s:", len(dependents), entry.ID)
+		for _, d := range dependents {
+			scope := ""
+			if d.Plugin.Vendored {
+				scope = " [vendored]"
+			}
+			fmt.Fprintf(os.Stderr, "      %s%s (needs %s)\n", d.Plugin.ID, scope, strings.Join(d.Storage, ", "))
+		}
+		if !*cascade {
+			fatal("refusing to uninstall %s; uninstall its dependents first, or rerun with --cascade to remove them too", entry.ID)
+		}
+		if isTerminal(os.Stdin) && !confirm(fmt.Sprintf("Uninstall %s and these %d plugin(s)?", entry.ID, len(dependents))) {
+			fatal("uninstall cancelled")
+		}
 	}
 
-	// Remove from registry.
-	delete(reg.Plugins, repoKey)
-	if err := SaveRegistry(reg); err != nil {
-		fatal("save registry: %v", err)
+	// Dependents go first, so a failure never leaves one without its storage.
+	for j := len(remove) - 1; j >= 0; j-- {
+		p := remove[j]
+		i := 0
+		if p.Vendored {
+			i = 1
+		}
+		// Delete binary.
+		if err := os.Remove(p.Binary); err != nil && !os.IsNotExist(err) {
+			printStatus(tagWarn, "could not remove binary %s: %v", p.Binary, err)
+		}
+		// Remove from registry.
+		for k, e := range regs[i].Plugins {
+			if e == p {
+				delete(regs[i].Plugins, k)
+			}
+		}
+		if err := stores[i].save(regs[i]); err != nil {
+			fatal("save registry: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "Uninstalled %s (%s)\n", p.ID, p.Repo)
 	}
-
-	fmt.Fprintf(os.Stderr, "Uninstalled %s (%s)\n", entry.ID, entry.Repo)
 }
 
 // RunUpdate handles `orchestra update` (self-update) or `orchestra update <plugin>`.
 func RunUpdate(args []string) {
-	if len(args) < 1 {
+	fs := newFlagSet("update")
+	channel := fs.String("channel", "", "Release channel for Orchestra itself: stable or beta (default: beta for prerelease builds, else stable)")
+	pr := fs.Bool("pr", false, "Update the workspace's packs on a new branch and open a GitHub pull request")
+	workspace := fs.String("workspace", ".", "Project workspace directory (with --pr or --vendor)")
+	vendor := fs.Bool("vendor", false, "Update a plugin vendored into the workspace")
+	remote := fs.String("remote", "origin", "Git remote to push the update branch to (with --pr)")
+	base := fs.String("base", "", "Branch the pull request targets (with --pr; default: the current branch)")
+	draft := fs.Bool("draft", false, "Open the pull request as a draft (with --pr)")
+	quiet := fs.Bool("quiet", false, "Do not print the release notes of the new version")
+	acceptBreaking := fs.Bool("accept-breaking", false, "Apply a release whose notes flag breaking changes without asking")
+	noReexec := fs.Bool("no-reexec", false, "Install the new sibling binaries with this orchestra instead of the new one")
+	ifUnwritable := fs.String("if-unwritable", unwritableAsk, "When the install directory is not writable: ask, elevate (sudo for the file swap), user (install into ~/.orchestra/bin), or fail")
+	parseFlags(fs, args)
+
+	if dir := os.Getenv(finishUpdateEnv); dir != "" {
+		finishSelfUpdate(dir)
+		return
+	}
+
+	if *pr {
+		if fs.NArg() > 0 {
+			fatal("--pr updates every pack in the workspace; it takes no plugin argument")
+		}
+		runUpdatePR(*workspace, *remote, *base, *draft)
+		return
+	}
+
+	if fs.NArg() < 1 {
 		// No args = self-update Orchestra.
-		runSelfUpdate()
+		runSelfUpdate(releaseChannel(*channel), selfUpdateOptions{Reexec: !*noReexec, Quiet: *quiet, AcceptBreaking: *acceptBreaking, IfUnwritable: *ifUnwritable})
 		return
 	}
-	target := args[0]
+	target := fs.Arg(0)
 
-	reg, err := LoadRegistry()
+	store := vendorStore(*vendor, *workspace)
+	reg, err := store.load()
 	if err != nil {
 		fatal("load registry: %v", err)
 	}
 
-	// Find by repo URL first, then by plugin ID.
-	var entry *PluginEntry
-	if p, ok := reg.Plugins[target]; ok {
-		entry = p
-	} else {
-		for _, p := range reg.Plugins {
-			if p.ID == target {
-				entry = p
-				break
-			}
-		}
-	}
-
+	_, entry := findPlugin(reg, target)
 	if entry == nil {
 		fatal("plugin not found: %s", target)
 	}
@@ -124,6 +400,13 @@ func RunUpdate(args []string) {
 
 	// Re-run install with the same repo. This will overwrite the binary and
 	// update the registry entry. Pass the repo without a version tag so it
-	// fetches the latest.
-	RunInstall([]string{entry.Repo})
+	// fetches the latest; a local install is rebuilt from its directory,
+	// copied or linked as before.
+	installArgs := []string{entry.Repo}
+	if store.workspace != "" {
+		installArgs = append(installArgs, "--vendor", "--workspace", store.workspace)
+	} else if entry.Source == pluginSourceLocal && !entry.Linked {
+		installArgs = append(installArgs, "--copy")
+	}
+	RunInstall(installArgs)
 }
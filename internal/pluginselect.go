@@ -0,0 +1,280 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Every installed plugin loads for every workspace unless it is disabled.
+// `orchestra plugins disable` turns a plugin off for one workspace, in
+// .projects/plugins.yaml, or with --global for all of them, in the plugin
+// registry; a workspace's enabled: list overrides a global disable. serve
+// --plugins replaces both for one session. The built-in plugins always load.
+
+// pluginSelectionFile is .projects/plugins.yaml.
+type pluginSelectionFile struct {
+	Enabled  []string `yaml:"enabled,omitempty"`
+	Disabled []string `yaml:"disabled,omitempty"`
+}
+
+// pluginSelection decides which installed plugins serve loads.
+type pluginSelection struct {
+	file           pluginSelectionFile
+	globalDisabled map[string]bool // plugin IDs disabled in the global registry
+	only           []string        // serve --plugins; nil when not given
+}
+
+// builtinPluginIDs are the plugins serve always starts.
+var builtinPluginIDs = []string{"storage.markdown", "tools.features", "tools.marketplace"}
+
+func pluginSelectionPath(workspace string) string {
+	return filepath.Join(workspace, ".projects", "plugins.yaml")
+}
+
+func loadPluginSelectionFile(workspace string) (pluginSelectionFile, error) {
+	var f pluginSelectionFile
+	data, err := os.ReadFile(pluginSelectionPath(workspace))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return f, nil
+		}
+		return f, err
+	}
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return f, fmt.Errorf("parse %s: %w", pluginSelectionPath(workspace), err)
+	}
+	return f, nil
+}
+
+// savePluginSelectionFile writes f with sorted lists, or removes the file
+// when both are empty.
+func savePluginSelectionFile(workspace string, f pluginSelectionFile) error {
+	path := pluginSelectionPath(workspace)
+	if len(f.Enabled)+len(f.Disabled) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	sortNames(f.Enabled)
+	sortNames(f.Disabled)
+	var buf bytes.Buffer
+	buf.WriteString("# Plugins 'orchestra serve' loads for this workspace; edit with\n# 'orchestra plugins enable|disable <id>'.\n")
+	data, err := yaml.Marshal(f)
+	if err != nil {
+		return err
+	}
+	buf.Write(data)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, buf.Bytes(), 0644)
+}
+
+// loadPluginSelection reads workspace's selection and the global disables.
+// An unreadable .projects/plugins.yaml is logged and ignored.
+func loadPluginSelection(workspace string, log io.Writer) *pluginSelection {
+	sel := &pluginSelection{globalDisabled: map[string]bool{}}
+	f, err := loadPluginSelectionFile(workspace)
+	if err != nil {
+		fmt.Fprintf(log, "orchestra: ignoring plugin selection: %v\n", err)
+	}
+	sel.file = f
+	if reg, err := LoadRegistry(); err == nil {
+		for _, p := range reg.Plugins {
+			if p.Disabled {
+				sel.globalDisabled[p.ID] = true
+			}
+		}
+	}
+	return sel
+}
+
+// skipReason returns why serve does not load the plugin with id, or "".
+func (sel *pluginSelection) skipReason(id string) string {
+	if sel.only != nil {
+		if containsString(sel.only, id) {
+			return ""
+		}
+		return "not in --plugins"
+	}
+	switch {
+	case containsString(sel.file.Enabled, id):
+		return ""
+	case containsString(sel.file.Disabled, id):
+		return "disabled in .projects/plugins.yaml"
+	case sel.globalDisabled[id]:
+		return "disabled globally"
+	}
+	return ""
+}
+
+// unknown returns the plugin IDs the selection names that are not among
+// installed.
+func (sel *pluginSelection) unknown(installed []*PluginEntry) []string {
+	known := map[string]bool{}
+	for _, id := range builtinPluginIDs {
+		known[id] = true
+	}
+	for _, p := range installed {
+		known[p.ID] = true
+	}
+	var names []string
+	if sel.only != nil {
+		names = sel.only
+	} else {
+		names = append(append(names, sel.file.Enabled...), sel.file.Disabled...)
+	}
+	var out []string
+	for _, id := range names {
+		if !known[id] {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// parsePluginList splits a --plugins value; an empty value selects none.
+func parsePluginList(s string) []string {
+	ids := []string{}
+	for _, id := range strings.Split(s, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func removeString(list []string, s string) []string {
+	var out []string
+	for _, v := range list {
+		if v != s {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// --- orchestra plugins enable/disable ---
+
+func runPluginsEnable(args []string)  { setPluginEnabled("enable", args) }
+func runPluginsDisable(args []string) { setPluginEnabled("disable", args) }
+
+// setPluginEnabled handles `orchestra plugins enable|disable <id>`.
+func setPluginEnabled(action string, args []string) {
+	fs := newFlagSet("plugins " + action)
+	workspace := fs.String("workspace", ".", "Project workspace directory whose plugins.yaml is changed")
+	global := fs.Bool("global", false, "Change the plugin for every workspace, in the global registry")
+	parseFlags(fs, args)
+
+	if fs.NArg() != 1 {
+		fatal("usage: orchestra plugins %s <plugin-id-or-repo>", action)
+	}
+	target := fs.Arg(0)
+	enable := action == "enable"
+	if containsString(builtinPluginIDs, target) {
+		fatal("%s is built in and always loads", target)
+	}
+
+	if *global {
+		reg, err := LoadRegistry()
+		if err != nil {
+			fatal("load registry: %v", err)
+		}
+		_, p := findPlugin(reg, target)
+		if p == nil {
+			fatal("plugin not installed globally: %s", target)
+		}
+		if p.Disabled == !enable {
+			printStatus(tagSkip, "%s is already %sd globally", p.ID, action)
+			return
+		}
+		if !enable {
+			warnStorageDependents(sortedPlugins(reg), p)
+		}
+		p.Disabled = !enable
+		if err := SaveRegistry(reg); err != nil {
+			fatal("save registry: %v", err)
+		}
+		printStatus(tagOK, "%sd %s for every workspace", action, p.ID)
+		return
+	}
+
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	installed := servePlugins(absWorkspace, io.Discard)
+	var p *PluginEntry
+	if _, p = findPlugin(&PluginRegistry{Plugins: pluginsByRepo(installed)}, target); p == nil {
+		fatal("plugin not installed: %s", target)
+	}
+	f, err := loadPluginSelectionFile(absWorkspace)
+	if err != nil {
+		fatal("%v", err)
+	}
+	sel := loadPluginSelection(absWorkspace, io.Discard)
+	if already := sel.skipReason(p.ID) == ""; already == enable {
+		printStatus(tagSkip, "%s is already %sd in %s", p.ID, action, absWorkspace)
+		return
+	}
+
+	f.Enabled = removeString(f.Enabled, p.ID)
+	f.Disabled = removeString(f.Disabled, p.ID)
+	// Only a global disable needs overriding; otherwise an enabled plugin
+	// is simply not listed.
+	if enable && sel.globalDisabled[p.ID] {
+		f.Enabled = append(f.Enabled, p.ID)
+	}
+	if !enable {
+		f.Disabled = append(f.Disabled, p.ID)
+		var loaded []*PluginEntry
+		for _, q := range installed {
+			if sel.skipReason(q.ID) == "" {
+				loaded = append(loaded, q)
+			}
+		}
+		warnStorageDependents(loaded, p)
+	}
+	if err := savePluginSelectionFile(absWorkspace, f); err != nil {
+		fatal("save %s: %v", pluginSelectionPath(absWorkspace), err)
+	}
+	printStatus(tagOK, "%sd %s in %s", action, p.ID, displayPath(absWorkspace, pluginSelectionPath(absWorkspace)))
+	if liveServeFor(absWorkspace) {
+		fmt.Fprintf(os.Stderr, "Restart 'orchestra serve' (or your IDE's MCP session) to apply it.\n")
+	}
+}
+
+// warnStorageDependents warns about plugins among loaded that need storage
+// only target provides.
+func warnStorageDependents(loaded []*PluginEntry, target *PluginEntry) {
+	_, dependents := storageRemovalSet(loaded, target)
+	for _, d := range dependents {
+		printStatus(tagWarn, "%s needs %s storage, which nothing else loaded provides", d.Plugin.ID, strings.Join(d.Storage, ", "))
+	}
+}
+
+// pluginsByRepo keys plugins by repo, as registries do, for findPlugin.
+func pluginsByRepo(plugins []*PluginEntry) map[string]*PluginEntry {
+	m := make(map[string]*PluginEntry, len(plugins))
+	for _, p := range plugins {
+		m[p.Repo] = p
+	}
+	return m
+}
+
+// liveServeFor reports whether a serve session is running for workspace.
+func liveServeFor(workspace string) bool {
+	for _, rec := range liveServeRecords() {
+		if samePath(rec.Workspace, workspace) {
+			return true
+		}
+	}
+	return false
+}
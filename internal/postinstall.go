@@ -0,0 +1,226 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/orchestra-mcp/cli/pkg/packs"
+)
+
+// postInstallTimeout bounds how long a pack's post-install script may run.
+const postInstallTimeout = 5 * time.Minute
+
+// runPostInstall runs the pack's post-install script in the workspace once
+// the user approves it: interactively after seeing the script, or up front
+// with --allow-post-install. Output is logged under .projects/.packs/logs/
+// and files the script changed are listed. It returns the log path.
+func runPostInstall(workspace string, m *packs.Manifest, opts packInstallOptions) string {
+	if m.PostInstall == "" {
+		return ""
+	}
+	lines := bytes.Count(m.PostInstallScript, []byte("\n"))
+	printStatus(tagWarn, "%s wants to run a post-install script (%s, %d lines)", m.Name, m.PostInstall, lines)
+
+	if org := loadOrgSettings(); org != nil && org.Policy.PostInstall == orgPostInstallNever {
+		printStatus(tagSkip, "post-install script (the org policy allows none)")
+		return ""
+	}
+	switch {
+	case opts.SkipPostInstall:
+		printStatus(tagSkip, "post-install script (--no-post-install)")
+		return ""
+	case opts.AllowPostInstall:
+	case !isTerminal(os.Stdin):
+		printStatus(tagSkip, "post-install script: no terminal to ask for approval; rerun with --allow-post-install to run it")
+		return ""
+	default:
+		fmt.Fprintf(os.Stderr, "\n")
+		for i, line := range strings.Split(strings.TrimRight(string(m.PostInstallScript), "\n"), "\n") {
+			fmt.Fprintf(os.Stderr, "  %4d  %s\n", i+1, line)
+		}
+		fmt.Fprintf(os.Stderr, "\n")
+		if !confirm(fmt.Sprintf("Run this script in %s?", workspace)) {
+			printStatus(tagSkip, "post-install script (not approved)")
+			return ""
+		}
+	}
+
+	shell, err := exec.LookPath("sh")
+	if err != nil {
+		printStatus(tagFail, "post-install script needs sh, which is not in PATH")
+		return ""
+	}
+	tmp, err := os.CreateTemp("", "orchestra-post-install-*.sh")
+	if err != nil {
+		printStatus(tagFail, "post-install script: %v", err)
+		return ""
+	}
+	defer os.Remove(tmp.Name())
+	tmp.Write(m.PostInstallScript)
+	tmp.Close()
+
+	logDir := filepath.Join(workspace, ".projects", ".packs", "logs")
+	os.MkdirAll(logDir, 0755)
+	logPath := filepath.Join(logDir, fmt.Sprintf("%s-%s.log",
+		strings.ReplaceAll(m.Name, "/", "_"), time.Now().UTC().Format("20060102T150405Z")))
+	var log bytes.Buffer
+	fmt.Fprintf(&log, "# %s@%s post-install (%s)\n# workspace: %s\n# started: %s\n\n",
+		m.Name, m.Version, m.PostInstall, workspace, time.Now().UTC().Format(time.RFC3339))
+
+	before := snapshotTree(workspace, logDir)
+
+	ctx, cancel := context.WithTimeout(context.Background(), postInstallTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, shell, tmp.Name())
+	cmd.Dir = workspace
+	cmd.Env = postInstallEnv(workspace, m)
+	out := io.MultiWriter(&log, prefixWriter(os.Stderr, "  | "))
+	cmd.Stdout, cmd.Stderr = out, out
+	runErr := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		runErr = fmt.Errorf("timed out after %s", postInstallTimeout)
+	}
+
+	changes := diffTrees(before, snapshotTree(workspace, logDir))
+	fmt.Fprintf(&log, "\n# exit: %v\n# changes:\n", exitSummary(runErr))
+	for _, c := range changes {
+		fmt.Fprintf(&log, "%s\n", c)
+	}
+	if err := writeFileAtomic(logPath, log.Bytes(), 0644); err != nil {
+		printStatus(tagWarn, "could not write %s: %v", logPath, err)
+	}
+
+	if runErr != nil {
+		printStatus(tagFail, "post-install script: %s", exitSummary(runErr))
+	} else {
+		printStatus(tagOK, "post-install script")
+	}
+	if len(changes) == 0 {
+		fmt.Fprintf(os.Stderr, "  No files changed.\n")
+	} else {
+		fmt.Fprintf(os.Stderr, "  Changed files:\n")
+		for _, c := range changes {
+			fmt.Fprintf(os.Stderr, "    %s\n", c)
+		}
+	}
+	rel, _ := filepath.Rel(workspace, logPath)
+	fmt.Fprintf(os.Stderr, "  Log: %s\n", filepath.ToSlash(rel))
+	return filepath.ToSlash(rel)
+}
+
+// postInstallEnv is the script's environment: enough to find tools and the
+// workspace, without the caller's tokens and credentials.
+func postInstallEnv(workspace string, m *packs.Manifest) []string {
+	var env []string
+	for _, key := range []string{"PATH", "HOME", "USER", "TMPDIR", "LANG", "SYSTEMROOT"} {
+		if v, ok := os.LookupEnv(key); ok {
+			env = append(env, key+"="+v)
+		}
+	}
+	return append(env,
+		"ORCHESTRA_WORKSPACE="+workspace,
+		"ORCHESTRA_PACK="+m.Name,
+		"ORCHESTRA_PACK_VERSION="+m.Version,
+		"ORCHESTRA_OS="+runtime.GOOS,
+	)
+}
+
+func exitSummary(err error) string {
+	if err == nil {
+		return "ok"
+	}
+	return err.Error()
+}
+
+// fileStamp identifies a file's content cheaply for change detection.
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// snapshotTree records every file under root except .git and skip.
+func snapshotTree(root, skip string) map[string]fileStamp {
+	files := map[string]fileStamp{}
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" || path == skip {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, path)
+		files[filepath.ToSlash(rel)] = fileStamp{info.Size(), info.ModTime()}
+		return nil
+	})
+	return files
+}
+
+// diffTrees lists added (+), modified (~), and deleted (-) files, sorted
+// by path.
+func diffTrees(before, after map[string]fileStamp) []string {
+	var changes []string
+	for path, a := range after {
+		b, ok := before[path]
+		switch {
+		case !ok:
+			changes = append(changes, "+ "+path)
+		case a.size != b.size || !a.modTime.Equal(b.modTime):
+			changes = append(changes, "~ "+path)
+		}
+	}
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			changes = append(changes, "- "+path)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i][2:] < changes[j][2:] })
+	return changes
+}
+
+// prefixWriter prefixes each line written to w, so script output stands
+// apart from orchestra's own.
+func prefixWriter(w io.Writer, prefix string) io.Writer {
+	return &linePrefixer{w: w, prefix: prefix, atStart: true}
+}
+
+type linePrefixer struct {
+	w       io.Writer
+	prefix  string
+	atStart bool
+}
+
+func (p *linePrefixer) Write(b []byte) (int, error) {
+	n := len(b)
+	for len(b) > 0 {
+		if p.atStart {
+			io.WriteString(p.w, p.prefix)
+			p.atStart = false
+		}
+		i := bytes.IndexByte(b, '\n')
+		if i < 0 {
+			p.w.Write(b)
+			break
+		}
+		p.w.Write(b[:i+1])
+		b = b[i+1:]
+		p.atStart = true
+	}
+	return n, nil
+}
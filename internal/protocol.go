@@ -0,0 +1,154 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Protocol versions. Plugins report the orchestrator protocol they speak
+// (protocol_version, "MAJOR.MINOR") and the newest MCP revision they
+// implement (mcp_version, a date) in their manifest. Install records both,
+// and serve negotiates before starting a plugin:
+//
+//   - orchestrator: the major version must match. A plugin with a newer
+//     minor version runs without the newer features.
+//   - mcp: a supported revision is used as is. A newer one is negotiated
+//     down to the newest revision orchestra speaks. One older than every
+//     supported revision is incompatible.
+//
+// Incompatible plugins are skipped by serve instead of failing at runtime
+// with errors that do not name the cause.
+
+// orchestratorProtocol is the orchestrator protocol this build speaks.
+var orchestratorProtocol = protocolVersion{Major: 1, Minor: 0}
+
+// mcpRevisions are the MCP protocol revisions this build speaks, oldest
+// first.
+var mcpRevisions = []string{"2024-11-05", "2025-03-26", "2025-06-18"}
+
+// protocolVersion is an orchestrator protocol version.
+type protocolVersion struct {
+	Major, Minor int
+}
+
+func parseProtocolVersion(s string) (protocolVersion, error) {
+	major, minor, _ := strings.Cut(strings.TrimPrefix(s, "v"), ".")
+	var v protocolVersion
+	var err error
+	if v.Major, err = strconv.Atoi(major); err != nil {
+		return v, fmt.Errorf("invalid protocol version %q", s)
+	}
+	if minor != "" {
+		if v.Minor, err = strconv.Atoi(minor); err != nil {
+			return v, fmt.Errorf("invalid protocol version %q", s)
+		}
+	}
+	return v, nil
+}
+
+func (v protocolVersion) String() string {
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+// Outcomes of a protocol check.
+const (
+	compatOK           = "ok"
+	compatAdapted      = "adapted"
+	compatUnreported   = "unreported"
+	compatIncompatible = "incompatible"
+)
+
+// protocolCheck is the outcome of negotiating one protocol with a plugin.
+type protocolCheck struct {
+	Protocol   string `json:"protocol"`             // "orchestrator" or "mcp"
+	Plugin     string `json:"plugin,omitempty"`     // version the plugin reported; "" if none
+	Supported  string `json:"supported"`            // what this build speaks
+	Negotiated string `json:"negotiated,omitempty"` // version serve configures; "" to leave it to the orchestrator
+	Status     string `json:"status"`               // compatOK, compatAdapted, compatUnreported, compatIncompatible
+	Note       string `json:"note,omitempty"`
+}
+
+// checkPluginProtocols negotiates each protocol with p.
+func checkPluginProtocols(p *PluginEntry) []protocolCheck {
+	return []protocolCheck{
+		checkOrchestratorProtocol(p.ProtocolVersion),
+		checkMCPRevision(p.MCPVersion),
+	}
+}
+
+func checkOrchestratorProtocol(reported string) protocolCheck {
+	c := protocolCheck{Protocol: "orchestrator", Plugin: reported, Supported: orchestratorProtocol.String()}
+	if reported == "" {
+		// Plugins built before versions were reported speak 1.0.
+		c.Status, c.Negotiated = compatUnreported, "1.0"
+		c.Note = "not reported; assuming 1.0"
+		if orchestratorProtocol.Major != 1 {
+			c.Status, c.Negotiated = compatIncompatible, ""
+			c.Note = "not reported; pre-versioning plugins speak 1.0"
+		}
+		return c
+	}
+	v, err := parseProtocolVersion(reported)
+	switch {
+	case err != nil:
+		c.Status, c.Note = compatIncompatible, err.Error()
+	case v.Major != orchestratorProtocol.Major:
+		c.Status = compatIncompatible
+		c.Note = fmt.Sprintf("major version %d, this orchestra speaks %d; ", v.Major, orchestratorProtocol.Major)
+		if v.Major > orchestratorProtocol.Major {
+			c.Note += "run 'orchestra update'"
+		} else {
+			c.Note += "update the plugin"
+		}
+	case v.Minor > orchestratorProtocol.Minor:
+		c.Status, c.Negotiated = compatAdapted, orchestratorProtocol.String()
+		c.Note = "plugin is newer; its " + v.String() + " features are unused"
+	default:
+		c.Status, c.Negotiated = compatOK, v.String()
+	}
+	return c
+}
+
+func checkMCPRevision(reported string) protocolCheck {
+	oldest, newest := mcpRevisions[0], mcpRevisions[len(mcpRevisions)-1]
+	c := protocolCheck{Protocol: "mcp", Plugin: reported, Supported: oldest + ".." + newest}
+	// Revisions are ISO dates, so they order as strings.
+	switch {
+	case reported == "":
+		c.Status, c.Note = compatUnreported, "not reported; negotiated at runtime"
+	case reported < oldest:
+		c.Status = compatIncompatible
+		c.Note = "older than every revision this orchestra speaks; update the plugin"
+	case reported > newest:
+		c.Status, c.Negotiated = compatAdapted, newest
+		c.Note = "plugin is newer; using " + newest
+	default:
+		c.Status, c.Negotiated = compatOK, reported
+		for _, r := range mcpRevisions {
+			if r == reported {
+				return c
+			}
+		}
+		// Between two known revisions: use the newest one not after it.
+		for i := len(mcpRevisions) - 1; i >= 0; i-- {
+			if mcpRevisions[i] < reported {
+				c.Status, c.Negotiated = compatAdapted, mcpRevisions[i]
+				c.Note = "unknown revision; using " + mcpRevisions[i]
+				break
+			}
+		}
+	}
+	return c
+}
+
+// pluginIncompatibility returns why serve must not start p, or "".
+func pluginIncompatibility(p *PluginEntry) string {
+	var reasons []string
+	for _, c := range checkPluginProtocols(p) {
+		if c.Status == compatIncompatible {
+			reasons = append(reasons, fmt.Sprintf("%s protocol %s: %s", c.Protocol, orDash(c.Plugin), c.Note))
+		}
+	}
+	return strings.Join(reasons, "; ")
+}
@@ -0,0 +1,199 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Release archives can carry GitHub Artifact Attestations: SLSA build
+// provenance signed through Sigstore, saying which workflow in which repo
+// built them. Whether an archive has one is asked of the GitHub API; the
+// signature is checked with the GitHub CLI (`gh attestation verify`),
+// which carries the Sigstore trust roots.
+
+// Provenance statuses.
+const (
+	provenanceVerified  = "verified"  // attestation present and valid for the repo
+	provenanceFailed    = "failed"    // attestation present but not valid
+	provenanceNone      = "none"      // the release publishes no attestation
+	provenanceUnchecked = "unchecked" // could not be checked (no gh, API error)
+)
+
+// provenanceTimeout bounds `gh attestation verify`, which fetches trust
+// roots on first use.
+const provenanceTimeout = 60 * time.Second
+
+// provenanceResult records a provenance check of a downloaded archive.
+type provenanceResult struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest"`           // sha256 of the archive
+	Signer    string `json:"signer,omitempty"` // workflow that built it, when verified
+	Detail    string `json:"detail,omitempty"`
+	CheckedAt string `json:"checked_at"`
+}
+
+// String renders r for `plugins info` and `version`.
+func (r *provenanceResult) String() string {
+	if r == nil {
+		return "-"
+	}
+	s := r.Status
+	switch {
+	case r.Signer != "":
+		s += " (" + r.Signer + ")"
+	case r.Detail != "":
+		s += " (" + r.Detail + ")"
+	}
+	return s
+}
+
+// checkProvenance checks the attestations ownerRepo published for the
+// archive at path.
+func checkProvenance(path, ownerRepo string) *provenanceResult {
+	digest, err := fileSHA256(path)
+	if err != nil {
+		return &provenanceResult{Status: provenanceUnchecked, Detail: err.Error(), CheckedAt: time.Now().UTC().Format(time.RFC3339)}
+	}
+	r := &provenanceResult{Digest: "sha256:" + digest, CheckedAt: time.Now().UTC().Format(time.RFC3339)}
+
+	present, err := hasAttestation(ownerRepo, r.Digest)
+	switch {
+	case err != nil:
+		r.Status, r.Detail = provenanceUnchecked, err.Error()
+		return r
+	case !present:
+		r.Status, r.Detail = provenanceNone, "no attestation published"
+		return r
+	}
+
+	if _, err := exec.LookPath("gh"); err != nil {
+		r.Status, r.Detail = provenanceUnchecked, "attestation found; install the GitHub CLI (gh) to verify it"
+		return r
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), provenanceTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "gh", "attestation", "verify", path, "--repo", ownerRepo, "--format", "json")
+	cmd.Env = os.Environ()
+	if token := githubToken(); token != "" && os.Getenv("GH_TOKEN") == "" {
+		cmd.Env = append(cmd.Env, "GH_TOKEN="+token)
+	}
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		r.Status, r.Detail = provenanceFailed, lastLine(stderr.String())
+		if r.Detail == "" {
+			r.Detail = err.Error()
+		}
+		return r
+	}
+	r.Status = provenanceVerified
+	r.Signer = attestationSigner(out)
+	return r
+}
+
+// hasAttestation asks the GitHub API whether ownerRepo has an attestation
+// for digest ("sha256:<hex>").
+func hasAttestation(ownerRepo, digest string) (bool, error) {
+	resp, err := githubGet(githubAPIURL("/repos/"+ownerRepo+"/attestations/"+digest), 10*time.Second)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("attestations API: HTTP %d", resp.StatusCode)
+	}
+	var body struct {
+		Attestations []json.RawMessage `json:"attestations"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return false, fmt.Errorf("attestations API: %w", err)
+	}
+	return len(body.Attestations) > 0, nil
+}
+
+// attestationSigner returns the build workflow from `gh attestation verify
+// --format json` output, or "".
+func attestationSigner(out []byte) string {
+	var results []struct {
+		VerificationResult struct {
+			Signature struct {
+				Certificate struct {
+					BuildSignerURI string `json:"buildSignerURI"`
+				} `json:"certificate"`
+			} `json:"signature"`
+		} `json:"verificationResult"`
+	}
+	if json.Unmarshal(out, &results) != nil || len(results) == 0 {
+		return ""
+	}
+	return strings.TrimPrefix(results[0].VerificationResult.Signature.Certificate.BuildSignerURI, "https://github.com/")
+}
+
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// --- orchestra's own provenance ---
+
+// selfProvenance is what `orchestra update` found for the release it
+// installed, shown by `orchestra version`.
+type selfProvenance struct {
+	Version    string            `json:"version"`
+	Provenance *provenanceResult `json:"provenance"`
+}
+
+func selfProvenancePath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".orchestra", "provenance.json")
+}
+
+func saveSelfProvenance(version string, r *provenanceResult) error {
+	data, err := json.MarshalIndent(selfProvenance{Version: version, Provenance: r}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(selfProvenancePath()), 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(selfProvenancePath(), append(data, '\n'), 0644)
+}
+
+// loadSelfProvenance returns the provenance recorded for this build's
+// version, or nil when it was not installed by `orchestra update`.
+func loadSelfProvenance() *provenanceResult {
+	data, err := os.ReadFile(selfProvenancePath())
+	if err != nil {
+		return nil
+	}
+	var sp selfProvenance
+	if json.Unmarshal(data, &sp) != nil || strings.TrimPrefix(sp.Version, "v") != strings.TrimPrefix(Version, "v") {
+		return nil
+	}
+	return sp.Provenance
+}
+
+// provenanceTag is the status tag a check is printed with.
+func provenanceTag(r *provenanceResult) string {
+	switch r.Status {
+	case provenanceVerified:
+		return tagOK
+	case provenanceFailed:
+		return tagFail
+	case provenanceNone:
+		return tagSkip
+	}
+	return tagWarn
+}
@@ -2,8 +2,11 @@ package internal
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
 )
 
 // PluginEntry describes a single installed plugin.
@@ -16,6 +19,39 @@ type PluginEntry struct {
 	ProvidesTools   []string `json:"provides_tools"`
 	ProvidesStorage []string `json:"provides_storage"`
 	NeedsStorage    []string `json:"needs_storage"`
+
+	ProvidesPrompts   []string `json:"provides_prompts,omitempty"`
+	ProvidesResources []string `json:"provides_resources,omitempty"` // URIs or URI templates
+
+	ProtocolVersion string `json:"protocol_version,omitempty"` // orchestrator protocol the plugin reported
+	MCPVersion      string `json:"mcp_version,omitempty"`      // MCP revision the plugin reported
+
+	// VerifiedTools are the tools the plugin actually exposed when install
+	// booted it; set (possibly empty) once VerifiedAt is.
+	VerifiedTools []string `json:"verified_tools,omitempty"`
+	VerifiedAt    string   `json:"verified_at,omitempty"`
+
+	Platform   string            `json:"platform,omitempty"`   // GOOS/GOARCH the binary was built for
+	Provenance *provenanceResult `json:"provenance,omitempty"` // attestation check of the release download
+	Checksums  *checksumResult   `json:"checksums,omitempty"`  // checksums.txt check of the release download
+	SHA256     string            `json:"sha256,omitempty"`     // of Binary as installed; see pluginModified
+	Disabled   bool              `json:"disabled,omitempty"`   // not loaded unless a workspace enables it; see pluginselect.go
+	Vendored   bool              `json:"-"`                    // loaded from a workspace's vendored registry
+
+	// Source is "local" for a plugin built from a directory on this
+	// machine, whose path is Repo; Linked is set when Binary is a symlink
+	// to the build there. See installlocal.go.
+	Source string `json:"source,omitempty"`
+	Linked bool   `json:"linked,omitempty"`
+}
+
+// Tools returns the plugin's tools: the verified list when install could
+// boot the plugin, otherwise the manifest's claim.
+func (p *PluginEntry) Tools() []string {
+	if p.VerifiedAt != "" {
+		return p.VerifiedTools
+	}
+	return p.ProvidesTools
 }
 
 // PluginRegistry holds all installed third-party plugins, keyed by repo URL.
@@ -60,7 +96,8 @@ func LoadRegistry() (*PluginRegistry, error) {
 	return &reg, nil
 }
 
-// SaveRegistry writes the registry to disk, creating directories as needed.
+// SaveRegistry writes the registry to disk atomically, creating directories
+// as needed.
 func SaveRegistry(reg *PluginRegistry) error {
 	if err := os.MkdirAll(registryDir(), 0755); err != nil {
 		return err
@@ -71,5 +108,243 @@ func SaveRegistry(reg *PluginRegistry) error {
 		return err
 	}
 
-	return os.WriteFile(registryPath(), data, 0644)
+	return writeFileAtomic(registryPath(), append(data, '\n'), 0644)
+}
+
+// --- vendored plugins ---
+
+// Vendored plugins live in the workspace, so a repository can carry the
+// plugins it needs (for example for CI agents) instead of relying on each
+// machine's global install. Binary paths in the vendored registry are
+// relative to the workspace, so the directory can be committed and moved.
+
+// vendorBinDir returns <workspace>/.orchestra/bin/.
+func vendorBinDir(workspace string) string {
+	return filepath.Join(workspace, ".orchestra", "bin")
+}
+
+// vendorRegistryPath returns <workspace>/.orchestra/plugins.json.
+func vendorRegistryPath(workspace string) string {
+	return filepath.Join(workspace, ".orchestra", "plugins.json")
+}
+
+// loadVendorRegistry reads workspace's vendored registry with binary paths
+// made absolute. A missing file is an empty registry.
+func loadVendorRegistry(workspace string) (*PluginRegistry, error) {
+	reg := &PluginRegistry{Plugins: make(map[string]*PluginEntry)}
+	data, err := os.ReadFile(vendorRegistryPath(workspace))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return reg, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, reg); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", vendorRegistryPath(workspace), err)
+	}
+	if reg.Plugins == nil {
+		reg.Plugins = make(map[string]*PluginEntry)
+	}
+	for _, p := range reg.Plugins {
+		if !filepath.IsAbs(p.Binary) {
+			p.Binary = filepath.Join(workspace, filepath.FromSlash(p.Binary))
+		}
+		p.Vendored = true
+	}
+	return reg, nil
+}
+
+// saveVendorRegistry writes workspace's vendored registry with binary
+// paths relative to the workspace. A registry that was trusted, or did not
+// exist, stays trusted: the change is the user's own.
+func saveVendorRegistry(workspace string, reg *PluginRegistry) error {
+	trusted := vendoredPluginsTrusted(workspace)
+	out := &PluginRegistry{Plugins: make(map[string]*PluginEntry, len(reg.Plugins))}
+	for k, p := range reg.Plugins {
+		e := *p
+		if rel, err := filepath.Rel(workspace, e.Binary); err == nil {
+			e.Binary = filepath.ToSlash(rel)
+		}
+		out.Plugins[k] = &e
+	}
+	if err := os.MkdirAll(filepath.Dir(vendorRegistryPath(workspace)), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(vendorRegistryPath(workspace), append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	if trusted {
+		return trustVendoredPlugins(workspace)
+	}
+	return nil
+}
+
+// pluginStore is where a plugin is installed: the user's global registry,
+// or the vendored registry of workspace when it is set.
+type pluginStore struct {
+	workspace string
+}
+
+func (s pluginStore) binDir() string {
+	if s.workspace != "" {
+		return vendorBinDir(s.workspace)
+	}
+	return pluginBinDir()
+}
+
+func (s pluginStore) registryPath() string {
+	if s.workspace != "" {
+		return vendorRegistryPath(s.workspace)
+	}
+	return registryPath()
+}
+
+func (s pluginStore) load() (*PluginRegistry, error) {
+	if s.workspace != "" {
+		return loadVendorRegistry(s.workspace)
+	}
+	return LoadRegistry()
+}
+
+func (s pluginStore) save(reg *PluginRegistry) error {
+	if s.workspace != "" {
+		return saveVendorRegistry(s.workspace, reg)
+	}
+	return SaveRegistry(reg)
+}
+
+// vendorStore resolves --vendor and --workspace into a pluginStore.
+func vendorStore(vendor bool, workspace string) pluginStore {
+	if !vendor {
+		return pluginStore{}
+	}
+	abs, err := resolveWorkspace(workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	return pluginStore{workspace: abs}
+}
+
+// currentPlatform is the GOOS/GOARCH recorded for installed binaries.
+func currentPlatform() string {
+	return runtime.GOOS + "/" + runtime.GOARCH
+}
+
+// vendoredUnusable returns why serve cannot run vendored plugin p on this
+// machine, or "".
+func vendoredUnusable(p *PluginEntry) string {
+	if p.Platform != "" && p.Platform != currentPlatform() {
+		return fmt.Sprintf("built for %s, this machine is %s", p.Platform, currentPlatform())
+	}
+	if _, err := os.Stat(p.Binary); err != nil {
+		return "binary missing: " + p.Binary
+	}
+	return ""
+}
+
+// servePlugins returns the plugins serve starts for workspace, ordered by
+// ID: the global registry's, with each one the workspace vendors replaced
+// by the vendored copy. Vendored plugins are only used once trusted (see
+// vendortrust.go). A vendored plugin that cannot run here, or whose binary
+// is outside .orchestra/bin/ or does not match its sha256, falls back to
+// the global install, if any; every case is logged.
+func servePlugins(workspace string, log io.Writer) []*PluginEntry {
+	byID := make(map[string]*PluginEntry)
+	if reg, err := LoadRegistry(); err == nil {
+		for _, p := range reg.Plugins {
+			byID[p.ID] = p
+		}
+	}
+	vendored, err := loadVendorRegistry(workspace)
+	if err != nil {
+		fmt.Fprintf(log, "orchestra: ignoring vendored plugins: %v\n", err)
+		vendored = &PluginRegistry{}
+	}
+	if len(vendored.Plugins) > 0 && !vendoredPluginsTrusted(workspace) {
+		fmt.Fprintf(log, "orchestra: ignoring vendored plugins: %s is not trusted; review it and run 'orchestra plugins trust'\n", vendorRegistryPath(workspace))
+		vendored = &PluginRegistry{}
+	}
+	for _, p := range sortedPlugins(vendored) {
+		reason := vendoredUnusable(p)
+		if reason == "" {
+			reason = vendoredBinaryProblem(workspace, p)
+		}
+		if reason != "" {
+			fallback := "no global install to fall back to"
+			if _, ok := byID[p.ID]; ok {
+				fallback = "using the global install"
+			}
+			fmt.Fprintf(log, "orchestra: vendored plugin %s: %s; %s\n", p.ID, reason, fallback)
+			continue
+		}
+		fmt.Fprintf(log, "orchestra: using vendored plugin %s (%s)\n", p.ID, p.Binary)
+		byID[p.ID] = p
+	}
+	return sortedPlugins(&PluginRegistry{Plugins: byID})
+}
+
+// --- storage dependencies ---
+
+// builtinStorage is the storage serve always starts (storage.markdown), so
+// a plugin needing it never depends on an installed plugin.
+var builtinStorage = []string{"markdown"}
+
+// storageDependent is an installed plugin that would lose storage it needs.
+type storageDependent struct {
+	Plugin  *PluginEntry
+	Storage []string // needed storage nothing left installed provides
+}
+
+// storageRemovalSet returns target followed by every installed plugin that
+// would be left without storage it needs once target is gone, directly or
+// because a plugin it relies on is removed too. Storage counts as provided
+// while any remaining plugin, or serve itself, provides it; a plugin whose
+// storage was already missing is not blamed on target.
+func storageRemovalSet(installed []*PluginEntry, target *PluginEntry) ([]*PluginEntry, []storageDependent) {
+	removed := map[*PluginEntry]bool{}
+	providedWithout := func() map[string]bool {
+		provided := map[string]bool{}
+		for _, s := range builtinStorage {
+			provided[s] = true
+		}
+		for _, p := range installed {
+			if !removed[p] {
+				for _, s := range p.ProvidesStorage {
+					provided[s] = true
+				}
+			}
+		}
+		return provided
+	}
+	before := providedWithout()
+	removed[target] = true
+	remove := []*PluginEntry{target}
+	var dependents []storageDependent
+	for {
+		provided := providedWithout()
+		added := false
+		for _, p := range installed {
+			if removed[p] {
+				continue
+			}
+			var lost []string
+			for _, s := range p.NeedsStorage {
+				if before[s] && !provided[s] {
+					lost = append(lost, s)
+				}
+			}
+			if len(lost) > 0 {
+				removed[p], added = true, true
+				remove = append(remove, p)
+				dependents = append(dependents, storageDependent{Plugin: p, Storage: lost})
+			}
+		}
+		if !added {
+			return remove, dependents
+		}
+	}
 }
@@ -0,0 +1,308 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// releasePlatforms are the GOOS/GOARCH pairs a release publishes an
+// archive for (see releaseAssetName).
+var releasePlatforms = []string{
+	"darwin/amd64",
+	"darwin/arm64",
+	"linux/amd64",
+	"linux/arm64",
+	"windows/amd64",
+}
+
+// placeholderSHA256 marks a checksum --no-checksums left for the
+// maintainer to fill in.
+const placeholderSHA256 = "REPLACE_WITH_SHA256"
+
+// releaseScaffold is what the packaging files are generated from.
+type releaseScaffold struct {
+	Repo    string            // owner/repo the release is published in
+	Tag     string            // release tag, e.g. v0.5.0
+	Sums    map[string]string // sha256 by platform
+	License string
+}
+
+// version is the tag without its leading v, as package managers want it.
+func (r *releaseScaffold) version() string {
+	return strings.TrimPrefix(r.Tag, "v")
+}
+
+// url is the public download URL of platform's archive.
+func (r *releaseScaffold) url(platform string) string {
+	goos, goarch, _ := strings.Cut(platform, "/")
+	return fmt.Sprintf("%s/%s/releases/download/%s/%s", githubDownloadBase, r.Repo, r.Tag, releaseAssetName(goos, goarch))
+}
+
+// runReleaseScaffold handles `orchestra release scaffold` -- writes a
+// Homebrew formula, an install.sh, and a Scoop manifest for a release, so
+// forks and maintainers package the archives the way orchestra update
+// expects to find them.
+func runReleaseScaffold(args []string) {
+	fs := newFlagSet("release scaffold")
+	repo := fs.String("repo", githubRepo, "GitHub owner/repo the release is published in")
+	tag := fs.String("version", "", "Release tag to package (default: the latest release)")
+	out := fs.String("out", "dist/packaging", "Directory to write orchestra.rb, install.sh, and orchestra.json to")
+	noChecksums := fs.Bool("no-checksums", false, "Do not download the archives; leave "+placeholderSHA256+" in place of each sha256")
+	parseFlags(fs, args)
+
+	r := &releaseScaffold{Repo: *repo, Tag: *tag, Sums: map[string]string{}, License: "MIT"}
+	if r.Tag == "" {
+		if r.Tag = latestReleaseTag(r.Repo); r.Tag == "" {
+			fatal("cannot find the latest release of %s; pass --version", r.Repo)
+		}
+	}
+	for _, platform := range releasePlatforms {
+		if *noChecksums {
+			r.Sums[platform] = placeholderSHA256
+			continue
+		}
+		goos, goarch, _ := strings.Cut(platform, "/")
+		asset := releaseAssetName(goos, goarch)
+		sp := startSpinner("Hashing " + asset)
+		sum, err := releaseAssetSHA256(r.Repo, r.Tag, asset)
+		sp.Stop(err)
+		if err != nil {
+			fatal("%s %s: %v (use --no-checksums to fill them in later)", r.Repo, r.Tag, err)
+		}
+		r.Sums[platform] = sum
+	}
+
+	if err := os.MkdirAll(*out, 0755); err != nil {
+		fatal("create %s: %v", *out, err)
+	}
+	scoop, err := scoopManifest(r)
+	if err != nil {
+		fatal("scoop manifest: %v", err)
+	}
+	files := []struct {
+		name string
+		data string
+		perm os.FileMode
+	}{
+		{"orchestra.rb", brewFormula(r), 0644},
+		{"install.sh", installScript(r), 0755},
+		{"orchestra.json", scoop, 0644},
+	}
+	for _, f := range files {
+		path := filepath.Join(*out, f.name)
+		if err := writeFileAtomic(path, []byte(f.data), f.perm); err != nil {
+			fatal("write %s: %v", path, err)
+		}
+		printStatus(tagOK, "%s", path)
+	}
+	if *noChecksums {
+		printStatus(tagWarn, "replace %s in each file before publishing", placeholderSHA256)
+	}
+}
+
+// releaseAssetSHA256 downloads asset from repo's release tag and returns
+// its sha256.
+func releaseAssetSHA256(repo, tag, asset string) (string, error) {
+	url := releaseDownloadURL(repo, tag, asset)
+	resp, err := githubGet(url, 0)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("HTTP %d from %s", resp.StatusCode, redactURL(url))
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, resp.Body); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// --- Homebrew ---
+
+// brewFormula renders a formula that installs every binary in
+// orchestraBinaries from the macOS and Linux archives.
+func brewFormula(r *releaseScaffold) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Generated by `orchestra release scaffold` for %s %s.\n", r.Repo, r.Tag)
+	b.WriteString("class Orchestra < Formula\n")
+	b.WriteString("  desc \"AI-powered project management over MCP\"\n")
+	fmt.Fprintf(&b, "  homepage \"https://github.com/%s\"\n", r.Repo)
+	fmt.Fprintf(&b, "  version \"%s\"\n", r.version())
+	fmt.Fprintf(&b, "  license \"%s\"\n\n", r.License)
+	for _, goos := range []string{"darwin", "linux"} {
+		block := map[string]string{"darwin": "on_macos", "linux": "on_linux"}[goos]
+		fmt.Fprintf(&b, "  %s do\n", block)
+		for _, arch := range []struct{ goarch, block string }{{"arm64", "on_arm"}, {"amd64", "on_intel"}} {
+			platform := goos + "/" + arch.goarch
+			if _, ok := r.Sums[platform]; !ok {
+				continue
+			}
+			fmt.Fprintf(&b, "    %s do\n", arch.block)
+			fmt.Fprintf(&b, "      url \"%s\"\n", r.url(platform))
+			fmt.Fprintf(&b, "      sha256 \"%s\"\n", r.Sums[platform])
+			b.WriteString("    end\n")
+		}
+		b.WriteString("  end\n\n")
+	}
+	b.WriteString("  def install\n")
+	for _, name := range orchestraBinaries {
+		fmt.Fprintf(&b, "    bin.install \"%s\"\n", name)
+	}
+	b.WriteString("  end\n\n")
+	b.WriteString("  test do\n")
+	b.WriteString("    assert_match version.to_s, shell_output(\"#{bin}/orchestra version\")\n")
+	b.WriteString("  end\n")
+	b.WriteString("end\n")
+	return b.String()
+}
+
+// --- install.sh ---
+
+// installScript renders a POSIX sh installer for macOS and Linux. It
+// honors ORCHESTRA_DOWNLOAD_MIRROR like orchestra itself, and checks the
+// archive's sha256 when it installs the version it was generated for.
+func installScript(r *releaseScaffold) string {
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n")
+	fmt.Fprintf(&b, "# Generated by `orchestra release scaffold` for %s %s.\n", r.Repo, r.Tag)
+	b.WriteString("#\n")
+	b.WriteString("#   curl -fsSL <url>/install.sh | sh\n")
+	b.WriteString("#\n")
+	b.WriteString("# ORCHESTRA_VERSION      tag to install (default: the version above)\n")
+	b.WriteString("# ORCHESTRA_INSTALL_DIR  where the binaries go (default: /usr/local/bin, or ~/.orchestra/bin when that is not writable)\n")
+	b.WriteString("# ORCHESTRA_DOWNLOAD_MIRROR  replaces https://github.com, as for orchestra itself\n")
+	b.WriteString("set -eu\n\n")
+	fmt.Fprintf(&b, "repo=%q\n", r.Repo)
+	fmt.Fprintf(&b, "default_version=%q\n", r.Tag)
+	b.WriteString("version=\"${ORCHESTRA_VERSION:-$default_version}\"\n")
+	fmt.Fprintf(&b, "base=\"${ORCHESTRA_DOWNLOAD_MIRROR:-%s}\"\n", githubDownloadBase)
+	fmt.Fprintf(&b, "binaries=%q\n\n", strings.Join(orchestraBinaries, " "))
+
+	b.WriteString("case \"$(uname -s)\" in\n")
+	b.WriteString("  Darwin) os=darwin ;;\n")
+	b.WriteString("  Linux) os=linux ;;\n")
+	b.WriteString("  *) echo \"orchestra: unsupported OS $(uname -s); on Windows use Scoop\" >&2; exit 1 ;;\n")
+	b.WriteString("esac\n")
+	b.WriteString("case \"$(uname -m)\" in\n")
+	b.WriteString("  x86_64 | amd64) arch=amd64 ;;\n")
+	b.WriteString("  arm64 | aarch64) arch=arm64 ;;\n")
+	b.WriteString("  *) echo \"orchestra: unsupported architecture $(uname -m)\" >&2; exit 1 ;;\n")
+	b.WriteString("esac\n\n")
+
+	b.WriteString("expected=\"\"\n")
+	b.WriteString("if [ \"$version\" = \"$default_version\" ]; then\n")
+	b.WriteString("  case \"$os/$arch\" in\n")
+	var platforms []string
+	for platform := range r.Sums {
+		if !strings.HasPrefix(platform, "windows/") {
+			platforms = append(platforms, platform)
+		}
+	}
+	sort.Strings(platforms)
+	for _, platform := range platforms {
+		if r.Sums[platform] != placeholderSHA256 {
+			fmt.Fprintf(&b, "    %s) expected=%s ;;\n", platform, r.Sums[platform])
+		}
+	}
+	b.WriteString("  esac\n")
+	b.WriteString("fi\n\n")
+
+	b.WriteString("dir=\"${ORCHESTRA_INSTALL_DIR:-/usr/local/bin}\"\n")
+	b.WriteString("if [ -z \"${ORCHESTRA_INSTALL_DIR:-}\" ] && [ ! -w \"$dir\" ]; then\n")
+	b.WriteString("  dir=\"$HOME/.orchestra/bin\"\n")
+	b.WriteString("fi\n")
+	b.WriteString("mkdir -p \"$dir\"\n\n")
+
+	fmt.Fprintf(&b, "asset=%q\n", releaseAssetName("$os", "$arch"))
+	b.WriteString("url=\"$base/$repo/releases/download/$version/$asset\"\n")
+	b.WriteString("tmp=\"$(mktemp -d)\"\n")
+	b.WriteString("trap 'rm -rf \"$tmp\"' EXIT\n\n")
+	b.WriteString("echo \"Downloading $asset ($version)...\"\n")
+	b.WriteString("curl -fsSL \"$url\" -o \"$tmp/$asset\"\n")
+	b.WriteString("if [ -n \"$expected\" ]; then\n")
+	b.WriteString("  if command -v sha256sum >/dev/null 2>&1; then\n")
+	b.WriteString("    actual=\"$(sha256sum \"$tmp/$asset\" | cut -d' ' -f1)\"\n")
+	b.WriteString("  else\n")
+	b.WriteString("    actual=\"$(shasum -a 256 \"$tmp/$asset\" | cut -d' ' -f1)\"\n")
+	b.WriteString("  fi\n")
+	b.WriteString("  if [ \"$actual\" != \"$expected\" ]; then\n")
+	b.WriteString("    echo \"orchestra: $asset checksum mismatch (got $actual, want $expected)\" >&2\n")
+	b.WriteString("    exit 1\n")
+	b.WriteString("  fi\n")
+	b.WriteString("fi\n")
+	b.WriteString("tar -xzf \"$tmp/$asset\" -C \"$tmp\"\n")
+	b.WriteString("for bin in $binaries; do\n")
+	b.WriteString("  install -m 0755 \"$tmp/$bin\" \"$dir/$bin\"\n")
+	b.WriteString("done\n\n")
+	b.WriteString("echo \"Installed orchestra $version to $dir\"\n")
+	b.WriteString("case \":$PATH:\" in\n")
+	b.WriteString("  *\":$dir:\"*) ;;\n")
+	b.WriteString("  *) echo \"Add $dir to your PATH.\" ;;\n")
+	b.WriteString("esac\n")
+	return b.String()
+}
+
+// --- Scoop ---
+
+// scoopArch maps GOARCH to Scoop's architecture keys.
+var scoopArch = map[string]string{"amd64": "64bit", "arm64": "arm64"}
+
+// scoopManifest renders a Scoop manifest for the Windows archives, with
+// checkver and autoupdate so the bucket follows new releases.
+func scoopManifest(r *releaseScaffold) (string, error) {
+	type archEntry struct {
+		URL  string `json:"url"`
+		Hash string `json:"hash"`
+	}
+	type autoArch struct {
+		URL string `json:"url"`
+	}
+	manifest := struct {
+		Version      string                         `json:"version"`
+		Description  string                         `json:"description"`
+		Homepage     string                         `json:"homepage"`
+		License      string                         `json:"license"`
+		Architecture map[string]archEntry           `json:"architecture"`
+		Bin          []string                       `json:"bin"`
+		Checkver     map[string]string              `json:"checkver"`
+		Autoupdate   map[string]map[string]autoArch `json:"autoupdate"`
+	}{
+		Version:      r.version(),
+		Description:  "AI-powered project management over MCP",
+		Homepage:     "https://github.com/" + r.Repo,
+		License:      r.License,
+		Architecture: map[string]archEntry{},
+		Checkver:     map[string]string{"github": "https://github.com/" + r.Repo},
+		Autoupdate:   map[string]map[string]autoArch{"architecture": {}},
+	}
+	for platform, sum := range r.Sums {
+		goos, goarch, _ := strings.Cut(platform, "/")
+		if goos != "windows" {
+			continue
+		}
+		key := scoopArch[goarch]
+		manifest.Architecture[key] = archEntry{URL: r.url(platform), Hash: sum}
+		tagPrefix := strings.TrimSuffix(r.Tag, r.version()) // "v" or ""
+		manifest.Autoupdate["architecture"][key] = autoArch{
+			URL: fmt.Sprintf("%s/%s/releases/download/%s$version/%s", githubDownloadBase, r.Repo, tagPrefix, releaseAssetName(goos, goarch)),
+		}
+	}
+	for _, name := range orchestraBinaries {
+		manifest.Bin = append(manifest.Bin, name+".exe")
+	}
+	data, err := json.MarshalIndent(manifest, "", "    ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
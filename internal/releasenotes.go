@@ -0,0 +1,129 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// maxReleaseNotes caps how many releases' notes an update prints.
+const maxReleaseNotes = 5
+
+// releasesBetween returns the releases on channel after current up to and
+// including latest, newest first.
+func releasesBetween(channel, current, latest string) []githubRelease {
+	var notes []githubRelease
+	for _, r := range fetchReleases() {
+		if channel == channelStable && r.Prerelease {
+			continue
+		}
+		if isNewerVersion(current, r.TagName) && !isNewerVersion(latest, r.TagName) {
+			notes = append(notes, r)
+		}
+	}
+	return notes
+}
+
+// printReleaseNotes shows the notes of releases, so the user sees what
+// changes before it is installed.
+func printReleaseNotes(releases []githubRelease) {
+	for i, r := range releases {
+		if i == maxReleaseNotes {
+			fmt.Fprintf(os.Stderr, "  ... %d older release(s): https://github.com/%s/releases\n\n", len(releases)-i, githubRepo)
+			break
+		}
+		fmt.Fprintf(os.Stderr, "%s\n", colorize(ansiBold, "What's new in "+r.TagName))
+		if strings.TrimSpace(r.Body) == "" {
+			fmt.Fprintf(os.Stderr, "  (no release notes) %s\n\n", r.HTMLURL)
+			continue
+		}
+		renderMarkdown(os.Stderr, strings.TrimSpace(r.Body), "  ")
+		fmt.Fprintf(os.Stderr, "\n")
+	}
+}
+
+// --- breaking changes ---
+
+// Release notes flag breaking changes in a section of their own, which
+// starts with a "breaking:" line (optionally a heading) and lists one
+// change per bullet, each prefixed with the area it breaks:
+//
+//	## breaking:
+//	- config: defaults.channel is now defaults.release_channel
+//	- workspace: .projects schema 3; run `orchestra init` to migrate
+//
+// The section ends at the next heading or blank line after its bullets.
+var (
+	breakingHeader = regexp.MustCompile(`(?i)^(#+\s*)?breaking:\s*$`)
+	breakingItem   = regexp.MustCompile(`^\s*[-*+]\s+(?:([a-zA-Z][\w-]*):\s+)?(.+)$`)
+)
+
+// breakingChange is one bullet of a release's breaking: section.
+type breakingChange struct {
+	Release string
+	Area    string // config, workspace, ...; "" when not given
+	Text    string
+}
+
+// parseBreaking returns the breaking changes flagged in a release body.
+func parseBreaking(release, body string) []breakingChange {
+	var changes []breakingChange
+	in := false
+	for _, line := range strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if breakingHeader.MatchString(trimmed) {
+			in = true
+			continue
+		}
+		if !in {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "#") || (trimmed == "" && len(changes) > 0) {
+			in = false
+			continue
+		}
+		if m := breakingItem.FindStringSubmatch(line); m != nil {
+			changes = append(changes, breakingChange{
+				Release: release,
+				Area:    strings.ToLower(m[1]),
+				Text:    strings.TrimSpace(m[2]),
+			})
+		}
+	}
+	return changes
+}
+
+// checkBreaking lists the breaking changes flagged in releases and returns
+// an error unless they are accepted, by --accept-breaking or on a
+// terminal. Unattended updates therefore stop at a breaking release
+// instead of applying it.
+func checkBreaking(releases []githubRelease, accept bool) error {
+	var changes []breakingChange
+	for _, r := range releases {
+		changes = append(changes, parseBreaking(r.TagName, r.Body)...)
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	printStatus(tagWarn, "This update has %d breaking change(s):", len(changes))
+	for _, c := range changes {
+		area := ""
+		if c.Area != "" {
+			area = c.Area + ": "
+		}
+		fmt.Fprintf(os.Stderr, "      %s %s%s\n", colorize(ansiDim, c.Release), colorize(ansiYellow, area), renderInline(c.Text))
+	}
+	fmt.Fprintln(os.Stderr)
+	if accept {
+		return nil
+	}
+	if !isTerminal(os.Stdin) {
+		return fmt.Errorf("update has breaking changes; review them and rerun with --accept-breaking")
+	}
+	if !confirm("Apply the update anyway?") {
+		return fmt.Errorf("update cancelled")
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// defaultEstimateHours maps t-shirt estimates to hours. Workspaces override
+// them with `estimates:` in .orchestra.yaml.
+var defaultEstimateHours = map[string]string{
+	"S":  "2h",
+	"M":  "4h",
+	"L":  "8h",
+	"XL": "24h",
+}
+
+// estimateDuration converts a feature estimate ("M", "3h", "90m") to a
+// duration. ok is false for missing or unrecognized estimates.
+func estimateDuration(estimate string, overrides map[string]string) (time.Duration, bool) {
+	estimate = strings.TrimSpace(estimate)
+	if estimate == "" {
+		return 0, false
+	}
+	if v, ok := overrides[strings.ToUpper(estimate)]; ok {
+		estimate = v
+	} else if v, ok := defaultEstimateHours[strings.ToUpper(estimate)]; ok {
+		estimate = v
+	}
+	d, err := time.ParseDuration(estimate)
+	if err != nil {
+		return 0, false
+	}
+	return d, true
+}
+
+// reportRow is one feature in `orchestra report`.
+type reportRow struct {
+	f         *feature
+	estimate  time.Duration
+	estimated bool
+	actual    time.Duration
+}
+
+// RunReport handles `orchestra report` -- compares estimates with tracked
+// time per feature, with totals and an estimate accuracy ratio over done work.
+func RunReport(args []string) {
+	fs := newFlagSet("report")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	project := fs.String("project", "", "Limit to one project")
+	all := fs.Bool("all", false, "Include features with no estimate and no tracked time")
+	porcelain := fs.Bool("porcelain", false, "Stable tab-separated output for scripts")
+	parseFlags(fs, args)
+
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	checkWorkspaceSchema(absWorkspace, false)
+
+	cfg := loadWorkspaceConfig(absWorkspace)
+	now := time.Now()
+	tracked := loadTimeLog(absWorkspace).trackedTime(now)
+
+	var rows []reportRow
+	for _, f := range loadFeatures(absWorkspace, *project) {
+		est, ok := estimateDuration(f.Estimate, cfg.Estimates)
+		row := reportRow{f: f, estimate: est, estimated: ok, actual: trackedFor(tracked, f)}
+		if !*all && !row.estimated && row.actual == 0 {
+			continue
+		}
+		rows = append(rows, row)
+	}
+
+	// Porcelain: project, id, status, estimate (hours), actual (hours).
+	if *porcelain {
+		for _, r := range rows {
+			est := ""
+			if r.estimated {
+				est = fmt.Sprintf("%.2f", r.estimate.Hours())
+			}
+			fmt.Fprintf(os.Stdout, "%s\t%s\t%s\t%s\t%.2f\n", r.f.Project, r.f.ID, r.f.Status, est, r.actual.Hours())
+		}
+		return
+	}
+
+	if len(rows) == 0 {
+		fmt.Fprintf(os.Stderr, "No estimated or tracked features. Use set_estimate and 'orchestra time start --feature=ID'.\n")
+		return
+	}
+
+	var totalEst, totalActual, doneEst, doneActual time.Duration
+	tw := newTable(os.Stdout)
+	fmt.Fprintf(tw, "  ID\tSTATUS\tESTIMATE\tACTUAL\tVARIANCE\tTITLE\n")
+	for _, r := range rows {
+		est, variance := "-", "-"
+		if r.estimated {
+			est = formatHours(r.estimate)
+			totalEst += r.estimate
+			if r.actual > 0 && r.estimate > 0 {
+				variance = fmt.Sprintf("%+.0f%%", (r.actual.Hours()/r.estimate.Hours()-1)*100)
+			}
+			if r.f.Status == "done" {
+				doneEst += r.estimate
+				doneActual += r.actual
+			}
+		}
+		totalActual += r.actual
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\t%s\n", r.f.ID, r.f.Status, est, formatHours(r.actual), variance, r.f.Title)
+	}
+	tw.Flush()
+
+	fmt.Fprintf(os.Stdout, "\nTotal: %s estimated, %s tracked across %d features\n",
+		formatHours(totalEst), formatHours(totalActual), len(rows))
+	if doneEst > 0 && doneActual > 0 {
+		fmt.Fprintf(os.Stdout, "Estimate accuracy (done features): %.2fx actual/estimate\n", doneActual.Hours()/doneEst.Hours())
+	}
+}
@@ -0,0 +1,123 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// runReviewList handles `orchestra review list` -- features waiting in
+// in-review, oldest first, like the get_pending_reviews MCP tool.
+func runReviewList(args []string) {
+	fs := newFlagSet("review list")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	project := fs.String("project", "", "Limit to one project")
+	porcelain := fs.Bool("porcelain", false, "Stable tab-separated output for scripts")
+	parseFlags(fs, args)
+
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	checkWorkspaceSchema(absWorkspace, false)
+
+	var pending []*feature
+	for _, f := range loadFeatures(absWorkspace, *project) {
+		if f.Status == "in-review" {
+			pending = append(pending, f)
+		}
+	}
+	sortFeaturesByUpdated(pending)
+
+	if jsonOutput() {
+		list := []featureJSON{}
+		for _, f := range pending {
+			list = append(list, describeFeatureJSON(f))
+		}
+		printJSON(list)
+		return
+	}
+
+	// Porcelain: project, id, assignee, updated_at, title.
+	if *porcelain {
+		for _, f := range pending {
+			fmt.Fprintf(os.Stdout, "%s\t%s\t%s\t%s\t%s\n", f.Project, f.ID, f.Assignee, f.UpdatedAt, f.Title)
+		}
+		return
+	}
+
+	if len(pending) == 0 {
+		fmt.Fprintf(os.Stderr, "No features waiting for review.\n")
+		return
+	}
+	tw := newTable(os.Stdout)
+	for _, f := range pending {
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", f.ID, f.Title, f.Assignee, f.UpdatedAt)
+	}
+	tw.Flush()
+}
+
+// runReviewApprove handles `orchestra review approve <id>` -- passes gate 4
+// and moves the feature to done.
+func runReviewApprove(args []string) {
+	runReviewDecision("review approve", args, "approved", "done")
+}
+
+// runReviewReject handles `orchestra review reject <id>` -- sends the
+// feature back to needs-edits.
+func runReviewReject(args []string) {
+	runReviewDecision("review reject", args, "needs-edits", "needs-edits")
+}
+
+// runReviewDecision records a review on a feature in in-review and moves it
+// to next. Notes are the gate evidence and are required.
+func runReviewDecision(path string, args []string, outcome, next string) {
+	fs := newFlagSet(path)
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	notes := fs.String("notes", "", "Review notes; the evidence for the gate (required)")
+	reviewer := fs.String("reviewer", currentUser(), "Reviewer name")
+	parseFlags(fs, args)
+
+	if fs.NArg() < 1 {
+		fatal("usage: orchestra %s <feature-id> --notes=...", path)
+	}
+	if *notes == "" {
+		fatal("--notes is required: describe what was reviewed")
+	}
+
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	checkWorkspaceSchema(absWorkspace, true)
+
+	f, err := findFeatureForUpdate(absWorkspace, fs.Arg(0))
+	if err != nil {
+		fatal("%v", err)
+	}
+	if f.Status != "in-review" {
+		fatal("%s is %s, not in-review", f.ID, f.Status)
+	}
+
+	f.Reviews = append(f.Reviews, featureReview{
+		Reviewer: *reviewer,
+		Status:   outcome,
+		Notes:    *notes,
+		Time:     time.Now().UTC().Format(time.RFC3339),
+	})
+	f.Status = next
+	if err := commitFeature(absWorkspace, f); err != nil {
+		fatal("save %s: %v", f.ID, err)
+	}
+	printStatus(tagOK, "%s %s → %s", f.ID, outcome, next)
+}
+
+// sortFeaturesByUpdated orders features oldest update first, by ID on ties.
+func sortFeaturesByUpdated(features []*feature) {
+	sortFeatures(features, func(a, b *feature) bool {
+		if a.UpdatedAt != b.UpdatedAt {
+			return a.UpdatedAt < b.UpdatedAt
+		}
+		return a.ID < b.ID
+	})
+}
@@ -0,0 +1,136 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/orchestra-mcp/cli/pkg/packs"
+)
+
+// workspaceSchemaVersion is the .projects/ layout version this CLI reads and
+// writes. It is owned by pkg/packs, which stamps the pack registry; bump it
+// there and append to workspaceMigrations whenever the on-disk layout
+// changes.
+const workspaceSchemaVersion = packs.SchemaVersion
+
+// workspaceSchema is the stamp stored in .projects/.schema.json.
+type workspaceSchema struct {
+	SchemaVersion int    `json:"schema_version"`
+	WrittenBy     string `json:"written_by"`
+}
+
+// workspaceMigrations upgrade a workspace one version at a time:
+// workspaceMigrations[i] moves a workspace from version i to i+1.
+var workspaceMigrations = []func(workspace string) error{
+	migrateV0ToV1,
+}
+
+// schemaPath returns the path of the workspace schema stamp.
+func schemaPath(workspace string) string {
+	return filepath.Join(workspace, ".projects", ".schema.json")
+}
+
+// readWorkspaceSchema returns the workspace's schema version. Workspaces
+// created before versioning have a .projects/ directory but no stamp and
+// report version 0. ok is false when there is no .projects/ at all.
+func readWorkspaceSchema(workspace string) (version int, ok bool) {
+	if _, err := os.Stat(filepath.Join(workspace, ".projects")); err != nil {
+		return 0, false
+	}
+	data, err := os.ReadFile(schemaPath(workspace))
+	if err != nil {
+		return 0, true
+	}
+	var stamp workspaceSchema
+	if err := json.Unmarshal(data, &stamp); err != nil {
+		return 0, true
+	}
+	return stamp.SchemaVersion, true
+}
+
+// stampWorkspaceSchema records the current schema version in .projects/.
+func stampWorkspaceSchema(workspace string) error {
+	if err := os.MkdirAll(filepath.Join(workspace, ".projects"), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(workspaceSchema{
+		SchemaVersion: workspaceSchemaVersion,
+		WrittenBy:     "orchestra " + Version,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(schemaPath(workspace), append(data, '\n'), 0644)
+}
+
+// checkWorkspaceSchema warns when the workspace was written by an older or
+// newer CLI. When forWrite is set, a workspace from a newer CLI is refused,
+// since rewriting it could drop data this version does not understand.
+func checkWorkspaceSchema(workspace string, forWrite bool) {
+	version, ok := readWorkspaceSchema(workspace)
+	if !ok {
+		return
+	}
+
+	switch {
+	case version < workspaceSchemaVersion:
+		printStatus(tagWarn, "workspace schema v%d is older than this CLI (v%d)", version, workspaceSchemaVersion)
+		fmt.Fprintf(os.Stderr, "         Run 'orchestra upgrade-workspace' to migrate it.\n")
+	case version > workspaceSchemaVersion:
+		if forWrite {
+			fatal("workspace schema v%d was written by a newer orchestra (this CLI supports v%d).\n  Run 'orchestra update' before modifying this workspace.", version, workspaceSchemaVersion)
+		}
+		printStatus(tagWarn, "workspace schema v%d is newer than this CLI (v%d); run 'orchestra update'", version, workspaceSchemaVersion)
+	}
+}
+
+// RunUpgradeWorkspace handles `orchestra upgrade-workspace` -- migrates the
+// workspace's .projects/ layout to the current schema version.
+func RunUpgradeWorkspace(args []string) {
+	fs := newFlagSet("upgrade-workspace")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	parseFlags(fs, args)
+
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+
+	version, ok := readWorkspaceSchema(absWorkspace)
+	if !ok {
+		fatal("no .projects/ directory in %s. Run: orchestra init", absWorkspace)
+	}
+	if version > workspaceSchemaVersion {
+		fatal("workspace schema v%d is newer than this CLI (v%d). Run: orchestra update", version, workspaceSchemaVersion)
+	}
+	if version == workspaceSchemaVersion {
+		fmt.Fprintf(os.Stderr, "Workspace is up to date (schema v%d)\n", version)
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "Upgrading workspace schema v%d → v%d\n", version, workspaceSchemaVersion)
+	for v := version; v < workspaceSchemaVersion; v++ {
+		if err := workspaceMigrations[v](absWorkspace); err != nil {
+			fatal("migrate v%d → v%d: %v", v, v+1, err)
+		}
+		printStatus(tagOK, "v%d → v%d", v, v+1)
+	}
+
+	if err := stampWorkspaceSchema(absWorkspace); err != nil {
+		fatal("write schema stamp: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "Done.\n")
+}
+
+// migrateV0ToV1 adds the schema version to the pack registry. The stamp
+// itself is written by the caller.
+func migrateV0ToV1(workspace string) error {
+	if _, err := os.Stat(filepath.Join(workspace, ".projects", ".packs", "registry.json")); err != nil {
+		return nil // no packs installed, nothing to rewrite
+	}
+	reg := loadPackRegistry(workspace)
+	savePackRegistry(workspace, reg)
+	return nil
+}
@@ -0,0 +1,340 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/orchestra-mcp/cli/pkg/packs"
+)
+
+// Published schemas. `orchestra schema export` emits JSON Schemas for the
+// files the CLI reads and writes, built by reflecting over the very structs
+// it decodes them into, so the schemas cannot drift from the code. Field
+// descriptions come from schemaFieldDocs.
+
+// jsonSchemaDraft is the JSON Schema dialect the exported schemas use.
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// publishedSchema is one exported schema.
+type publishedSchema struct {
+	Name        string `json:"name"`
+	File        string `json:"file"` // the file it describes, for `schema list`
+	Description string `json:"description"`
+	typ         reflect.Type
+	tag         string // "json" or "yaml": which struct tags name the fields
+
+	// generated files are written by orchestra, which always writes
+	// fields without omitempty, so those are required at every level.
+	// Hand-written files require only the fields in required.
+	generated bool
+	required  []string
+
+	// open schemas allow keys the CLI does not read. Plugins print
+	// metadata like version and description that other tools use.
+	open bool
+}
+
+var publishedSchemas = []publishedSchema{
+	{
+		Name:        "pack",
+		File:        "pack.json",
+		Description: "Manifest at the root of a pack repository",
+		typ:         reflect.TypeOf(packs.Manifest{}),
+		tag:         "json",
+		required:    []string{"name", "version"},
+	},
+	{
+		Name:        "plugin-manifest",
+		File:        "<plugin binary> --manifest",
+		Description: "JSON a plugin prints when run with --manifest",
+		typ:         reflect.TypeOf(pluginManifest{}),
+		tag:         "json",
+		required:    []string{"id"},
+		open:        true,
+	},
+	{
+		Name:        "plugin-registry",
+		File:        "~/.orchestra/plugins/registry.json, .orchestra/plugins.json",
+		Description: "Installed plugins, global or vendored into a workspace",
+		typ:         reflect.TypeOf(PluginRegistry{}),
+		tag:         "json",
+		generated:   true,
+	},
+	{
+		Name:        "pack-registry",
+		File:        ".projects/.packs/registry.json",
+		Description: "Packs installed in a workspace",
+		typ:         reflect.TypeOf(packs.Registry{}),
+		tag:         "json",
+		generated:   true,
+	},
+	{
+		Name:        "pack-lock",
+		File:        ".projects/.packs/packs.lock",
+		Description: "Exact revision of each installed pack, for pack sync",
+		typ:         reflect.TypeOf(packs.Lock{}),
+		tag:         "json",
+		generated:   true,
+	},
+	{
+		Name:        "workspace-config",
+		File:        workspaceConfigFile,
+		Description: "Workspace settings",
+		typ:         reflect.TypeOf(workspaceConfig{}),
+		tag:         "yaml",
+	},
+	{
+		Name:        "global-config",
+		File:        "~/.orchestra/config.yaml",
+		Description: "The user's settings for every workspace",
+		typ:         reflect.TypeOf(globalConfig{}),
+		tag:         "yaml",
+	},
+	{
+		Name:        "orchestrator-config",
+		File:        "orchestrator --config",
+		Description: "Config serve generates for the orchestrator",
+		typ:         reflect.TypeOf(orchestratorConfig{}),
+		tag:         "yaml",
+		generated:   true,
+	},
+}
+
+// schemaFieldDocs describes fields, keyed by schema name and the field's
+// path, with "*" standing for array items and map values.
+var schemaFieldDocs = map[string]string{
+	"pack/name":                  "Pack name, usually the repository name (pack-go-backend)",
+	"pack/version":               "Pack version, semver without a leading v",
+	"pack/stacks":                "Detected stacks the pack suits, or \"*\" for every project",
+	"pack/contents":              "Content the pack installs into .claude/",
+	"pack/contents/skills":       "Directories under skills/",
+	"pack/contents/agents":       "Files under agents/, without the .md extension",
+	"pack/contents/hooks":        "Hooks under hooks/; each installs in the variant (.sh, .ps1, .cmd) for the OS",
+	"pack/min_orchestra_version": "Oldest orchestra that can install the pack, e.g. 0.4.0",
+	"pack/platforms":             "Supported GOOS or GOOS/GOARCH values; empty means any",
+	"pack/post_install":          "sh script, relative to the pack root, run in the workspace after install once approved",
+	"pack/dependencies":          "Packs (by name or repo) whose content this pack relies on; pack install installs missing ones first",
+
+	"plugin-manifest/id":                 "Plugin ID the orchestrator routes to",
+	"plugin-manifest/provides_tools":     "MCP tools the plugin exposes",
+	"plugin-manifest/provides_storage":   "Storage backends the plugin provides",
+	"plugin-manifest/provides_prompts":   "MCP prompts the plugin exposes",
+	"plugin-manifest/provides_resources": "MCP resource URIs or URI templates the plugin exposes",
+	"plugin-manifest/needs_storage":      "Storage backends the plugin needs another plugin to provide",
+	"plugin-manifest/protocol_version":   "Orchestrator protocol the plugin speaks, MAJOR.MINOR",
+	"plugin-manifest/mcp_version":        "Newest MCP revision the plugin implements",
+	"plugin-manifest/capabilities":       "Optional features the plugin supports, e.g. encrypted-workspace",
+
+	"plugin-registry/plugins": "Installed plugins by repo",
+
+	"pack-registry/schema_version": "Workspace schema version; see orchestra upgrade-workspace",
+	"pack-registry/packs":          "Installed packs by name",
+
+	"pack-lock/lock_version":    "Lockfile format version",
+	"pack-lock/packs":           "Locked packs by name",
+	"pack-lock/packs/*/version": "Version from the pack's pack.json",
+	"pack-lock/packs/*/tag":     "Tag the commit was installed from, if any",
+	"pack-lock/packs/*/commit":  "Commit pack sync and pack install --frozen check out",
+	"pack-lock/packs/*/source":  "\"embedded\" for the copy shipped in orchestra",
+
+	"workspace-config/defaults":      "Flag defaults for this workspace, flag name to value",
+	"workspace-config/conventions":   "text/template strings for branch names and commit messages",
+	"workspace-config/estimates":     "Duration of each t-shirt size, e.g. M: 6h",
+	"workspace-config/templates":     "Feature templates by kind, added to or overriding the built-in ones",
+	"workspace-config/tool_timeouts": "serve's --tool-timeout per tool name or path.Match pattern; \"0\" means no limit",
+	"workspace-config/log_levels":    "Minimum level serve records per plugin ID or path.Match pattern: debug, info, warn, or error",
+	"workspace-config/hooks":         "Commands run at lifecycle events, by event; run once trusted with orchestra hooks trust",
+
+	"global-config/github_token":    "Token for GitHub API requests",
+	"global-config/defaults":        "Flag defaults for every workspace, flag name to value",
+	"global-config/mirror":          "Mirrors for GitHub downloads and API requests",
+	"global-config/mirror/download": "Replaces https://github.com for release assets",
+	"global-config/mirror/api":      "Replaces https://api.github.com",
+	"global-config/minisign_keys":   "minisign public key by plugin repo, for checksums.txt signatures",
+	"global-config/hooks":           "Commands run at lifecycle events in every workspace, by event",
+
+	"orchestrator-config/listen_addr": "Address the orchestrator listens on",
+	"orchestrator-config/certs_dir":   "mTLS certificates directory",
+	"orchestrator-config/plugins":     "Plugins the orchestrator boots",
+}
+
+func findPublishedSchema(name string) (publishedSchema, bool) {
+	for _, s := range publishedSchemas {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return publishedSchema{}, false
+}
+
+// document returns s as a JSON Schema document.
+func (s publishedSchema) document() map[string]any {
+	doc := s.schemaFor(s.typ, s.Name)
+	if !s.generated && len(s.required) > 0 {
+		doc["required"] = s.required
+	}
+	out := map[string]any{
+		"$schema":     jsonSchemaDraft,
+		"title":       s.File,
+		"description": s.Description,
+	}
+	for k, v := range doc {
+		out[k] = v
+	}
+	return out
+}
+
+// schemaFor describes values of type t found at path.
+func (s publishedSchema) schemaFor(t reflect.Type, path string) map[string]any {
+	nullable := false
+	for t.Kind() == reflect.Pointer {
+		t, nullable = t.Elem(), true
+	}
+	out := map[string]any{}
+	if doc := schemaFieldDocs[path]; doc != "" {
+		out["description"] = doc
+	}
+	// Types the reflection cannot describe, like config values that accept
+	// a string or a list, describe themselves.
+	if custom, ok := reflect.Zero(t).Interface().(interface{ jsonSchema() map[string]any }); ok {
+		for k, v := range custom.jsonSchema() {
+			out[k] = v
+		}
+		return out
+	}
+	switch t.Kind() {
+	case reflect.String:
+		out["type"] = "string"
+	case reflect.Bool:
+		out["type"] = "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		out["type"] = "integer"
+	case reflect.Float32, reflect.Float64:
+		out["type"] = "number"
+	case reflect.Slice, reflect.Array:
+		out["type"] = "array"
+		out["items"] = s.schemaFor(t.Elem(), path+"/*")
+	case reflect.Map:
+		out["type"] = "object"
+		out["additionalProperties"] = s.schemaFor(t.Elem(), path+"/*")
+	case reflect.Struct:
+		props := map[string]any{}
+		var required []string
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name, opts, _ := strings.Cut(f.Tag.Get(s.tag), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+				if s.tag == "yaml" {
+					name = strings.ToLower(name)
+				}
+			}
+			props[name] = s.schemaFor(f.Type, path+"/"+name)
+			if s.generated && !strings.Contains(opts, "omitempty") {
+				required = append(required, name)
+			}
+		}
+		out["type"] = "object"
+		out["properties"] = props
+		// The CLI ignores unknown keys; rejecting them here lets editors
+		// flag the typo the CLI would silently skip.
+		if !s.open {
+			out["additionalProperties"] = false
+		}
+		if len(required) > 0 {
+			out["required"] = required
+		}
+	}
+	// encoding/json writes nil slices, maps, and pointers as null.
+	if s.tag == "json" && path != s.Name && (nullable || t.Kind() == reflect.Slice || t.Kind() == reflect.Map) {
+		out["type"] = []string{out["type"].(string), "null"}
+	}
+	return out
+}
+
+// runSchemaExport handles `orchestra schema export` -- prints the named
+// schemas, or writes them to --out as <name>.schema.json.
+func runSchemaExport(args []string) {
+	fs := newFlagSet("schema export")
+	out := fs.String("out", "", "Write each schema to DIR/<name>.schema.json instead of stdout")
+	parseFlags(fs, args)
+
+	selected := publishedSchemas
+	if fs.NArg() > 0 {
+		selected = nil
+		for _, name := range fs.Args() {
+			s, ok := findPublishedSchema(name)
+			if !ok {
+				fatal("unknown schema %q; 'orchestra schema list' shows them", name)
+			}
+			selected = append(selected, s)
+		}
+	}
+
+	if *out == "" {
+		// One schema prints as is; several print as one object keyed by
+		// name.
+		var v any
+		if len(selected) == 1 {
+			v = selected[0].document()
+		} else {
+			all := map[string]any{}
+			for _, s := range selected {
+				all[s.Name] = s.document()
+			}
+			v = all
+		}
+		data, _ := json.MarshalIndent(v, "", "  ")
+		fmt.Fprintf(os.Stdout, "%s\n", data)
+		return
+	}
+
+	if err := os.MkdirAll(*out, 0755); err != nil {
+		fatal("%v", err)
+	}
+	for _, s := range selected {
+		data, _ := json.MarshalIndent(s.document(), "", "  ")
+		path := filepath.Join(*out, s.Name+".schema.json")
+		if err := writeFileAtomic(path, append(data, '\n'), 0644); err != nil {
+			fatal("write %s: %v", path, err)
+		}
+		printStatus(tagOK, "wrote %s", path)
+	}
+}
+
+// runSchemaList handles `orchestra schema list`.
+func runSchemaList(args []string) {
+	fs := newFlagSet("schema list")
+	porcelain := fs.Bool("porcelain", false, "Stable tab-separated output for scripts")
+	parseFlags(fs, args)
+
+	if jsonOutput() {
+		printJSON(publishedSchemas)
+		return
+	}
+
+	// Porcelain: name, file, description.
+	if *porcelain {
+		for _, s := range publishedSchemas {
+			fmt.Fprintf(os.Stdout, "%s\t%s\t%s\n", s.Name, s.File, s.Description)
+		}
+		return
+	}
+	tw := newTable(os.Stdout)
+	fmt.Fprintf(tw, "NAME\tFILE\tDESCRIPTION\n")
+	for _, s := range publishedSchemas {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", s.Name, s.File, s.Description)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,183 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// knownPlugin is a plugin listed in the built-in plugin catalog.
+type knownPlugin struct {
+	Repo        string
+	Description string
+	Tags        []string
+}
+
+// knownPlugins is the built-in plugin catalog used by search.
+var knownPlugins = []knownPlugin{
+	{Repo: "github.com/orchestra-mcp/plugin-tools-features", Description: "Feature workflow tools (bundled with Orchestra)", Tags: []string{"features", "workflow", "tools"}},
+	{Repo: "github.com/orchestra-mcp/plugin-storage-markdown", Description: "Markdown file storage backend (bundled with Orchestra)", Tags: []string{"storage", "markdown"}},
+	{Repo: "github.com/orchestra-mcp/plugin-transport-stdio", Description: "MCP JSON-RPC stdio bridge (bundled with Orchestra)", Tags: []string{"transport", "stdio"}},
+	{Repo: "github.com/orchestra-mcp/sdk-go", Description: "Go SDK for building Orchestra plugins", Tags: []string{"sdk", "go", "plugin"}},
+}
+
+// searchResult is a single hit from `orchestra search`.
+type searchResult struct {
+	Kind        string `json:"kind"` // pack, plugin, skill, agent, hook
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Installed   bool   `json:"installed"`
+	Hint        string `json:"hint"` // suggested next command
+}
+
+// RunSearch handles `orchestra search <query>` -- searches the pack catalog,
+// the plugin catalog, and locally installed content in one pass.
+func RunSearch(args []string) {
+	fs := newFlagSet("search")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	porcelain := fs.Bool("porcelain", false, "Stable tab-separated output for scripts")
+	parseFlags(fs, args)
+
+	if fs.NArg() < 1 {
+		fatal("usage: orchestra search <query>")
+	}
+
+	query := strings.ToLower(fs.Arg(0))
+	absWorkspace, _ := resolveWorkspace(*workspace)
+
+	results := searchAll(absWorkspace, query)
+	if jsonOutput() {
+		if results == nil {
+			results = []searchResult{}
+		}
+		printJSON(results)
+		return
+	}
+	if len(results) == 0 {
+		if !*porcelain {
+			fmt.Fprintf(os.Stderr, "Nothing found for: %s\n", query)
+		}
+		return
+	}
+
+	// Porcelain: kind, name, installed (true/false), description.
+	if *porcelain {
+		for _, r := range results {
+			fmt.Fprintf(os.Stdout, "%s\t%s\t%t\t%s\n", r.Kind, r.Name, r.Installed, r.Description)
+		}
+		return
+	}
+
+	fmt.Fprintf(os.Stdout, "Results for %q:\n\n", query)
+	for _, r := range results {
+		status := ""
+		if r.Installed {
+			status = " (installed)"
+		}
+		if globals.plain {
+			fmt.Fprintf(os.Stdout, "  %s %s%s: %s. %s\n", r.Kind, r.Name, status, r.Description, r.Hint)
+			continue
+		}
+		fmt.Fprintf(os.Stdout, "  %-7s %-50s %s%s\n", r.Kind, r.Name, r.Description, status)
+		fmt.Fprintf(os.Stdout, "          %s\n", r.Hint)
+	}
+}
+
+// searchAll collects matches from every source, packs and plugins first,
+// then installed skills, agents, and hooks.
+func searchAll(workspace, query string) []searchResult {
+	var results []searchResult
+
+	// Packs: catalog entries, marked when already installed.
+	packReg := loadPackRegistry(workspace)
+	installedPacks := make(map[string]bool)
+	for _, entry := range packReg.Packs {
+		installedPacks[entry.Repo] = true
+	}
+	for _, p := range loadPackIndex(false) {
+		if !p.Matches(query) {
+			continue
+		}
+		results = append(results, searchResult{
+			Kind:        "pack",
+			Name:        p.Repo,
+			Description: p.Description,
+			Installed:   installedPacks[p.Repo],
+			Hint:        "orchestra pack install " + p.Repo,
+		})
+	}
+
+	// Plugins: catalog entries plus anything installed from elsewhere.
+	pluginReg, err := LoadRegistry()
+	if err != nil {
+		pluginReg = &PluginRegistry{Plugins: make(map[string]*PluginEntry)}
+	}
+	seenPlugins := make(map[string]bool)
+	for _, p := range knownPlugins {
+		if !matchesAny(query, append([]string{p.Repo, p.Description}, p.Tags...)...) {
+			continue
+		}
+		_, installed := pluginReg.Plugins[p.Repo]
+		seenPlugins[p.Repo] = true
+		results = append(results, searchResult{
+			Kind:        "plugin",
+			Name:        p.Repo,
+			Description: p.Description,
+			Installed:   installed,
+			Hint:        "orchestra install " + p.Repo,
+		})
+	}
+	repos := make([]string, 0, len(pluginReg.Plugins))
+	for repo := range pluginReg.Plugins {
+		repos = append(repos, repo)
+	}
+	sort.Strings(repos)
+	for _, repo := range repos {
+		p := pluginReg.Plugins[repo]
+		terms := append([]string{repo, p.ID}, p.Tools()...)
+		terms = append(append(terms, p.ProvidesPrompts...), p.ProvidesResources...)
+		if seenPlugins[repo] || !matchesAny(query, terms...) {
+			continue
+		}
+		results = append(results, searchResult{
+			Kind:        "plugin",
+			Name:        repo,
+			Description: fmt.Sprintf("%s (%d tools)", p.ID, len(p.Tools())),
+			Installed:   true,
+			Hint:        "orchestra update " + p.ID,
+		})
+	}
+
+	// Locally installed content.
+	claudeDir := filepath.Join(workspace, ".claude")
+	for _, name := range scanSkills(claudeDir) {
+		if matchesAny(query, name) {
+			results = append(results, searchResult{Kind: "skill", Name: name, Description: "slash command /" + name, Installed: true, Hint: "use /" + name + " in your IDE"})
+		}
+	}
+	for _, name := range scanAgents(claudeDir) {
+		if matchesAny(query, name) {
+			results = append(results, searchResult{Kind: "agent", Name: name, Description: ".claude/agents/" + name + ".md", Installed: true, Hint: "delegated automatically by your IDE"})
+		}
+	}
+	for _, file := range scanHooks(claudeDir) {
+		name := strings.TrimSuffix(file, filepath.Ext(file))
+		if matchesAny(query, name) {
+			results = append(results, searchResult{Kind: "hook", Name: name, Description: ".claude/hooks/" + file, Installed: true, Hint: "runs automatically on IDE events"})
+		}
+	}
+
+	return results
+}
+
+// matchesAny reports whether the lowercase query is contained in any field.
+func matchesAny(query string, fields ...string) bool {
+	for _, f := range fields {
+		if strings.Contains(strings.ToLower(f), query) {
+			return true
+		}
+	}
+	return false
+}
@@ -3,11 +3,13 @@ package internal
 import (
 	"archive/tar"
 	"compress/gzip"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strconv"
@@ -15,12 +17,10 @@ import (
 	"time"
 )
 
-const (
-	githubRepo  = "orchestra-mcp/framework"
-	releasesURL = "https://api.github.com/repos/" + githubRepo + "/releases"
-)
+const githubRepo = "orchestra-mcp/framework"
 
-// orchestraBinaries lists all binaries shipped in a release tarball.
+// orchestraBinaries lists all binaries shipped in a release tarball. The
+// packaging `orchestra release scaffold` writes installs the same list.
 var orchestraBinaries = []string{
 	"orchestra",
 	"orchestrator",
@@ -30,30 +30,131 @@ var orchestraBinaries = []string{
 	"tools-marketplace",
 }
 
-// checkLatestVersion queries the GitHub API for the latest release tag
-// (including prereleases). Returns the tag string or "" on error.
-func checkLatestVersion() string {
-	client := &http.Client{Timeout: 5 * time.Second}
-	resp, err := client.Get(releasesURL)
+// releaseAssetName is the name of the release archive holding
+// orchestraBinaries for goos/goarch. `orchestra release scaffold` packages
+// the same names.
+func releaseAssetName(goos, goarch string) string {
+	return fmt.Sprintf("orchestra-%s-%s.tar.gz", goos, goarch)
+}
+
+// Release channels for update checks. Stable ignores prereleases; beta
+// takes the newest release of any kind.
+const (
+	channelStable = "stable"
+	channelBeta   = "beta"
+)
+
+// releaseChannel returns channel if set, else the configured channel
+// (ORCHESTRA_CHANNEL, then defaults: in the global config), else beta for
+// prerelease and development builds and stable otherwise.
+func releaseChannel(channel string) string {
+	if channel == "" {
+		global := flagLayer{globalConfigPath(), loadGlobalConfig().Defaults}
+		channel, _, _ = flagDefault("channel", []flagLayer{global})
+	}
+	switch channel {
+	case channelStable, channelBeta:
+		return channel
+	case "":
+	default:
+		fatal("unknown release channel %q (use %s or %s)", channel, channelStable, channelBeta)
+	}
+	if _, pre := splitVersion(Version); pre != "" || Version == "dev" {
+		return channelBeta
+	}
+	return channelStable
+}
+
+// githubRelease is one entry of the GitHub releases API, newest first.
+type githubRelease struct {
+	TagName    string `json:"tag_name"`
+	Prerelease bool   `json:"prerelease"`
+	Body       string `json:"body"` // release notes, Markdown
+	HTMLURL    string `json:"html_url"`
+}
+
+// fetchReleases returns Orchestra's recent releases, newest first, or nil
+// on error.
+func fetchReleases() []githubRelease {
+	resp, err := githubGetCached(githubAPIURL("/repos/"+githubRepo+"/releases"), 5*time.Second)
 	if err != nil {
-		return ""
+		return nil
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return ""
+		return nil
 	}
 
-	var releases []struct {
-		TagName string `json:"tag_name"`
-	}
+	var releases []githubRelease
 	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
-		return ""
+		return nil
+	}
+	return releases
+}
+
+// checkLatestVersion queries the GitHub API for the newest release tag on
+// channel. Returns the tag string or "" on error.
+func checkLatestVersion(channel string) string {
+	for _, r := range fetchReleases() {
+		if channel == channelStable && r.Prerelease {
+			continue
+		}
+		return r.TagName
+	}
+	return ""
+}
+
+// githubGet fetches a GitHub URL, sending the configured token (see
+// githubToken) so requests are not rate-limited and private repos work.
+// timeout 0 means none, for large downloads.
+func githubGet(url string, timeout time.Duration) (*http.Response, error) {
+	req, err := newGitHubRequest(url)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Timeout: timeout}
+	step := debugStep("GET %s", url)
+	resp, err := client.Do(req)
+	if err != nil {
+		step.endf("%v", err)
+		return nil, err
+	}
+	step.endf("%s", resp.Status) // headers only; a download is timed by its caller
+	return resp, nil
+}
+
+// newGitHubRequest builds a GET for url with the configured token for its
+// host (see requestToken). A GitHub API asset URL is asked for the asset
+// itself rather than its metadata.
+func newGitHubRequest(url string) (*http.Request, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token := requestToken(url); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
 	}
-	if len(releases) == 0 {
-		return ""
+	if isGitHubURL(url) && strings.Contains(url, "/releases/assets/") {
+		req.Header.Set("Accept", "application/octet-stream")
 	}
-	return releases[0].TagName
+	return req, nil
+}
+
+// githubToken returns ORCHESTRA_GITHUB_TOKEN, GITHUB_TOKEN,
+// ORCHESTRA_GIT_TOKEN, github_token from the global config, or the
+// github.com entry under credentials:, in that order.
+func githubToken() string {
+	for _, env := range []string{"ORCHESTRA_GITHUB_TOKEN", "GITHUB_TOKEN", "ORCHESTRA_GIT_TOKEN"} {
+		if v := os.Getenv(env); v != "" {
+			return v
+		}
+	}
+	cfg := loadGlobalConfig()
+	if cfg.GitHubToken != "" {
+		return cfg.GitHubToken
+	}
+	return cfg.Credentials["github.com"].Token
 }
 
 // isNewerVersion returns true if latest is strictly newer than current.
@@ -109,11 +210,16 @@ func parseSemver(base string) [3]int {
 	return parts
 }
 
-// runSelfUpdate checks for a newer version and updates all Orchestra binaries.
-func runSelfUpdate() {
-	fmt.Fprintf(os.Stderr, "Checking for updates...\n")
+// finishUpdateEnv is set, to the staging directory, when selfUpdate
+// re-executes the new orchestra to install the sibling binaries.
+const finishUpdateEnv = "ORCHESTRA_FINISH_UPDATE"
+
+// runSelfUpdate checks channel for a newer version and updates all
+// Orchestra binaries.
+func runSelfUpdate(channel string, opts selfUpdateOptions) {
+	fmt.Fprintf(os.Stderr, "Checking for updates (%s)...\n", channel)
 
-	latest := checkLatestVersion()
+	latest := checkLatestVersion(channel)
 	if latest == "" {
 		fmt.Fprintf(os.Stderr, "Could not check for updates.\n")
 		fmt.Fprintf(os.Stderr, "Download manually: https://github.com/%s/releases\n", githubRepo)
@@ -125,17 +231,42 @@ func runSelfUpdate() {
 		return
 	}
 
+	notes := releasesBetween(channel, Version, latest)
+	if !opts.Quiet {
+		fmt.Fprintf(os.Stderr, "\n")
+		printReleaseNotes(notes)
+	}
+	if err := checkBreaking(notes, opts.AcceptBreaking); err != nil {
+		fatal("%v", err)
+	}
 	fmt.Fprintf(os.Stderr, "Updating orchestra %s → %s...\n\n", Version, latest)
 
-	if err := selfUpdate(latest); err != nil {
+	if err := selfUpdate(latest, opts); err != nil {
 		fatal("update failed: %v", err)
 	}
 
 	fmt.Fprintf(os.Stderr, "\nUpdated to %s! Run 'orchestra version' to verify.\n", latest)
 }
 
-// selfUpdate downloads the release tarball and replaces all binaries.
-func selfUpdate(targetVersion string) error {
+// selfUpdateOptions control how selfUpdate installs a release.
+type selfUpdateOptions struct {
+	Reexec         bool   // let the new orchestra install the siblings
+	Quiet          bool   // skip the release notes
+	AcceptBreaking bool   // apply releases whose notes flag breaking changes
+	IfUnwritable   string // unwritableAsk, unwritableElevate, unwritableUser, or unwritableFail
+}
+
+// selfUpdate downloads the release tarball and replaces all binaries. The
+// new orchestra must run `version` on this machine before anything is
+// replaced, so a corrupt or wrong-platform download leaves the install as
+// it was. orchestra is replaced first; with Reexec, the new binary then
+// installs the siblings, so a release that adds or renames one is handled
+// by the code that knows about it.
+//
+// When the install directory is not writable, IfUnwritable decides:
+// elevate copies the verified files in with sudo (the only elevated step),
+// and user installs into ~/.orchestra/bin with PATH guidance.
+func selfUpdate(targetVersion string, opts selfUpdateOptions) error {
 	// Find where the current binary lives.
 	self, err := os.Executable()
 	if err != nil {
@@ -143,67 +274,197 @@ func selfUpdate(targetVersion string) error {
 	}
 	self, _ = filepath.EvalSymlinks(self)
 	installDir := filepath.Dir(self)
+	oldDir := installDir
 
-	// Check if we can write to the install directory.
-	testFile := filepath.Join(installDir, ".orchestra-update-test")
-	if err := os.WriteFile(testFile, []byte("test"), 0644); err != nil {
-		os.Remove(testFile)
-		return fmt.Errorf("cannot write to %s (try: sudo orchestra update)", installDir)
+	mode := ""
+	stageIn := installDir
+	if !dirWritable(installDir) {
+		if mode, err = chooseUnwritable(opts.IfUnwritable, installDir); err != nil {
+			return err
+		}
+		switch mode {
+		case unwritableUser:
+			installDir = userBinDir()
+			if err := os.MkdirAll(installDir, 0755); err != nil {
+				return fmt.Errorf("create %s: %w", installDir, err)
+			}
+			stageIn = installDir
+		case unwritableElevate:
+			stageIn = "" // the system temp directory
+		}
 	}
-	os.Remove(testFile)
 
 	// Build download URL.
-	tarName := fmt.Sprintf("orchestra-%s-%s.tar.gz", runtime.GOOS, runtime.GOARCH)
-	url := fmt.Sprintf("https://github.com/%s/releases/download/%s/%s", githubRepo, targetVersion, tarName)
+	tarName := releaseAssetName(runtime.GOOS, runtime.GOARCH)
+	url := releaseDownloadURL(githubRepo, targetVersion, tarName)
 
 	fmt.Fprintf(os.Stderr, "  Downloading %s...\n", tarName)
 
-	resp, err := http.Get(url)
+	resp, err := githubGet(url, 0)
 	if err != nil {
 		return fmt.Errorf("download: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("download failed: HTTP %d from %s", resp.StatusCode, url)
+		return fmt.Errorf("download failed: HTTP %d from %s", resp.StatusCode, redactURL(url))
 	}
 
 	// Extract all binaries to a temp directory.
-	tmpDir, err := os.MkdirTemp(installDir, ".orchestra-update-*")
+	tmpDir, err := os.MkdirTemp(stageIn, ".orchestra-update-*")
 	if err != nil {
 		return fmt.Errorf("create temp dir: %w", err)
 	}
 	defer os.RemoveAll(tmpDir)
 
-	if err := extractTarGzAll(resp.Body, tmpDir); err != nil {
+	archivePath := filepath.Join(tmpDir, tarName)
+	archive, err := os.Create(archivePath)
+	if err != nil {
+		return fmt.Errorf("create archive: %w", err)
+	}
+	defer archive.Close()
+	if _, err := io.Copy(archive, resp.Body); err != nil {
+		return fmt.Errorf("download: %w", err)
+	}
+
+	prov := checkProvenance(archivePath, githubRepo)
+	if prov.Status == provenanceFailed {
+		return fmt.Errorf("provenance attestation for %s did not verify; nothing was replaced: %s", tarName, prov.Detail)
+	}
+	printStatus(provenanceTag(prov), "provenance: %s", prov)
+
+	if _, err := archive.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("read archive: %w", err)
+	}
+	if err := extractTarGzAll(archive, tmpDir); err != nil {
 		return fmt.Errorf("extract: %w", err)
 	}
+	os.Remove(archivePath)
+
+	newSelf := filepath.Join(tmpDir, "orchestra")
+	if err := verifyUpdateBinary(newSelf, targetVersion); err != nil {
+		return fmt.Errorf("new orchestra failed verification; nothing was replaced: %w", err)
+	}
+	printStatus(tagOK, "verified orchestra %s runs on %s", targetVersion, currentPlatform())
+
+	if mode == unwritableElevate {
+		// The elevated copy cannot re-exec as the user, so the running
+		// binary's list of siblings decides.
+		var pairs [][2]string
+		for _, name := range orchestraBinaries {
+			if _, err := os.Stat(filepath.Join(tmpDir, name)); err == nil {
+				pairs = append(pairs, [2]string{filepath.Join(tmpDir, name), filepath.Join(installDir, name)})
+			}
+		}
+		if err := elevatedInstall(pairs); err != nil {
+			return err
+		}
+		saveSelfProvenance(targetVersion, prov)
+		return nil
+	}
+	if mode == unwritableUser {
+		defer printPathGuidance(installDir, oldDir)
+	}
 
+	selfDest := filepath.Join(installDir, "orchestra")
+	if err := moveFile(newSelf, selfDest); err != nil {
+		return fmt.Errorf("replace orchestra: %w", err)
+	}
+	if err := os.Chmod(selfDest, 0755); err != nil {
+		return fmt.Errorf("chmod orchestra: %w", err)
+	}
+	printStatus(tagOK, "orchestra")
+	saveSelfProvenance(targetVersion, prov)
+
+	if opts.Reexec {
+		cmd := exec.Command(selfDest, "update")
+		cmd.Env = append(os.Environ(), finishUpdateEnv+"="+tmpDir)
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err == nil {
+			return nil
+		}
+		printStatus(tagWarn, "the new orchestra could not finish the update (%v); finishing with this one", err)
+	}
+	return installStagedBinaries(tmpDir, installDir)
+}
+
+// finishSelfUpdate runs in the new orchestra re-executed by selfUpdate: it
+// installs the sibling binaries staged in dir.
+func finishSelfUpdate(dir string) {
+	self, err := os.Executable()
+	if err != nil {
+		fatal("find executable: %v", err)
+	}
+	self, _ = filepath.EvalSymlinks(self)
+	if err := installStagedBinaries(dir, filepath.Dir(self)); err != nil {
+		fatal("update failed: %v", err)
+	}
+}
+
+// verifyUpdateBinary runs `<path> version --porcelain` and checks that it
+// reports want for this platform.
+func verifyUpdateBinary(path, want string) error {
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("release has no orchestra binary")
+	}
+	if err := os.Chmod(path, 0755); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, path, "version", "--porcelain").Output()
+	if err != nil {
+		return fmt.Errorf("run orchestra version: %w", err)
+	}
+	fields := strings.Split(strings.TrimSpace(string(out)), "\t")
+	if len(fields) < 4 {
+		return fmt.Errorf("unexpected orchestra version output %q", strings.TrimSpace(string(out)))
+	}
+	if fields[3] != currentPlatform() {
+		return fmt.Errorf("binary is for %s, this machine is %s", fields[3], currentPlatform())
+	}
+	if strings.TrimPrefix(fields[0], "v") != strings.TrimPrefix(want, "v") {
+		return fmt.Errorf("binary reports version %s, expected %s", fields[0], want)
+	}
+	return nil
+}
+
+// installStagedBinaries moves the binaries extracted into tmpDir, except
+// orchestra itself, into installDir.
+func installStagedBinaries(tmpDir, installDir string) error {
 	// Replace each binary atomically.
 	for _, name := range orchestraBinaries {
+		if name == "orchestra" {
+			continue
+		}
 		srcPath := filepath.Join(tmpDir, name)
 		if _, err := os.Stat(srcPath); os.IsNotExist(err) {
-			fmt.Fprintf(os.Stderr, "  [SKIP] %s (not in release)\n", name)
+			printStatus(tagSkip, "%s (not in release)", name)
 			continue
 		}
 
 		destPath := filepath.Join(installDir, name)
 
-		// Atomic replace: rename is atomic on same filesystem.
-		if err := os.Rename(srcPath, destPath); err != nil {
+		// Atomic replace: rename is atomic on the same filesystem; moveFile
+		// falls back to copy+rename when the install dir is a separate mount.
+		if err := moveFile(srcPath, destPath); err != nil {
 			return fmt.Errorf("replace %s: %w", name, err)
 		}
 		if err := os.Chmod(destPath, 0755); err != nil {
 			return fmt.Errorf("chmod %s: %w", name, err)
 		}
 
-		fmt.Fprintf(os.Stderr, "  [OK] %s\n", name)
+		printStatus(tagOK, "%s", name)
 	}
 
 	return nil
 }
 
-// extractTarGzAll extracts all regular files from a tar.gz stream into destDir.
+// extractTarGzAll extracts all regular files from a tar.gz stream into
+// destDir, flattened to their base names. Which ones are installed is up to
+// the orchestra that finishes the update, so a newer release's new
+// binaries are staged too.
 func extractTarGzAll(r io.Reader, destDir string) error {
 	gz, err := gzip.NewReader(r)
 	if err != nil {
@@ -211,12 +472,6 @@ func extractTarGzAll(r io.Reader, destDir string) error {
 	}
 	defer gz.Close()
 
-	// Build a set of known binaries for safety.
-	known := make(map[string]bool, len(orchestraBinaries))
-	for _, name := range orchestraBinaries {
-		known[name] = true
-	}
-
 	tr := tar.NewReader(gz)
 	for {
 		header, err := tr.Next()
@@ -232,7 +487,7 @@ func extractTarGzAll(r io.Reader, destDir string) error {
 		}
 
 		baseName := filepath.Base(header.Name)
-		if !known[baseName] {
+		if baseName == "." || baseName == ".." || baseName == "/" {
 			continue
 		}
 
@@ -251,10 +506,20 @@ func extractTarGzAll(r io.Reader, destDir string) error {
 	return nil
 }
 
+// auto_update: values in the global config.
+const (
+	autoUpdateNotify = "notify" // check for a newer release and say so
+	autoUpdateOff    = "off"    // never check
+)
+
 // CheckAndPromptUpdate checks for a newer version and prints an advisory.
-// Used by orchestra init to inform the user without blocking.
+// Used by orchestra init to inform the user without blocking. auto_update:
+// off in the global config turns it off.
 func CheckAndPromptUpdate() {
-	latest := checkLatestVersion()
+	if loadGlobalConfig().AutoUpdate == autoUpdateOff {
+		return
+	}
+	latest := checkLatestVersion(releaseChannel(""))
 	if latest == "" {
 		return
 	}
@@ -1,17 +1,20 @@
 package internal
 
 import (
-	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"os/signal"
 	"path/filepath"
 	"regexp"
+	"runtime"
+	"strconv"
 	"strings"
-	"syscall"
+	"sync"
 	"time"
 
+	"github.com/orchestra-mcp/cli/pkg/packs"
 	"gopkg.in/yaml.v3"
 )
 
@@ -22,76 +25,399 @@ type pluginConfig struct {
 	Enabled         bool     `yaml:"enabled"`
 	ProvidesStorage []string `yaml:"provides_storage,omitempty"`
 	Args            []string `yaml:"args,omitempty"`
+
+	ProvidesPrompts   []string `yaml:"provides_prompts,omitempty"`
+	ProvidesResources []string `yaml:"provides_resources,omitempty"`
+
+	// Env is added to this plugin's environment only, such as the key of
+	// an encrypted workspace for its storage plugins.
+	Env []string `yaml:"env,omitempty"`
+
+	// Negotiated protocol versions (see protocol.go); empty leaves the
+	// choice to the orchestrator.
+	ProtocolVersion string `yaml:"protocol_version,omitempty"`
+	MCPVersion      string `yaml:"mcp_version,omitempty"`
 }
 
 type orchestratorConfig struct {
 	ListenAddr string         `yaml:"listen_addr"`
-	CertsDir   string         `yaml:"certs_dir"`
+	CertsDir   string         `yaml:"certs_dir,omitempty"` // empty on a unix socket
 	Plugins    []pluginConfig `yaml:"plugins"`
 }
 
+// serveBins are the sibling binaries serve runs, by name.
+type serveBins map[string]string
+
+// serveSession is a running `orchestra serve`: the stdio transport stays up
+// for the whole session while the backend (orchestrator and plugins) can be
+// restarted against another workspace by `orchestra serve switch`.
+type serveSession struct {
+	mu        sync.Mutex
+	bins      serveBins
+	certsDir  string
+	logFile   string
+	log       *serveLog
+	force     bool
+	workspace string
+	backend   *serveBackend
+	stopWatch chan struct{}
+
+	toolTimeouts toolTimeouts
+	metrics      serveMetrics
+	profiling    *serveProfiling
+
+	restartPlugins bool     // run plugins under the supervisor; see servesupervise.go
+	onlyPlugins    []string // serve --plugins, or nil; see pluginselect.go
+	coldStart      bool     // ignore the warm-start cache; see servewarm.go
+
+	activity     bool          // record workspace activity; see activity.go
+	activityDone chan struct{} // closed once the activity watcher has stopped
+
+	daemonized bool         // this process is a `serve --daemon`; see servedaemon.go
+	daemon     *daemonState // the daemon this session is attached to, or nil
+}
+
+// serveBackend is an orchestrator process and its plugins for one workspace.
+type serveBackend struct {
+	cmd    *exec.Cmd
+	tree   *processTree  // the orchestrator and its plugins; see serveproc.go
+	config string        // temp plugins.yaml
+	addr   string        // orchestrator listen address
+	exited chan struct{} // closed once the orchestrator has exited
+}
+
 func RunServe(args []string) {
-	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	fs := newFlagSet("serve")
 	workspace := fs.String("workspace", ".", "Project workspace directory")
 	certsDir := fs.String("certs-dir", defaultCertsDir(), "mTLS certificates directory")
+	listen := fs.String("listen", defaultListenAddr, "Orchestrator listen address, or the MCP endpoint's with --transport=http: host:port, :port, or host:low-high for the first free port in a range")
+	transport := fs.String("transport", "stdio", "How MCP clients connect: stdio, or http for streamable HTTP and HTTP with SSE")
+	var httpOpts httpOptions
+	fs.StringVar(&httpOpts.token, "token", "", "Token HTTP clients must send (default: generated and saved in <certs-dir>/http.token)")
+	fs.BoolVar(&httpOpts.tls, "tls", false, "Serve HTTPS with a certificate from the local CA in --certs-dir")
+	fs.StringVar(&httpOpts.tlsCert, "tls-cert", "", "Serve HTTPS with this certificate file (with --tls-key)")
+	fs.StringVar(&httpOpts.tlsKey, "tls-key", "", "Private key file for --tls-cert")
 	logPath := fs.String("log", "", "Log file path (default: <workspace>/.orchestra-mcp.log)")
-	fs.Parse(args)
+	force := fs.Bool("force", false, "Allow serving a home directory, filesystem root, or very large tree")
+	toolTimeout := fs.Duration("tool-timeout", defaultToolTimeout, "Cancel tool calls that run longer than this (0 for no limit); see tool_timeouts in .orchestra.yaml")
+	activity := fs.Bool("activity", false, "Record git, file, and session activity in .projects/activity.jsonl for the agent")
+	daemon := fs.Bool("daemon", false, "Run the backend in the background for IDE sessions to share; stop it with 'orchestra stop'")
+	plugins := fs.String("plugins", "", "Comma-separated IDs of the installed plugins to load, overriding .projects/plugins.yaml and global disables; built-in plugins always load")
+	noPluginRestart := fs.Bool("no-plugin-restart", false, "Leave crashed plugins down instead of restarting them")
+	unixSocket := fs.Bool("unix-socket", false, "Connect the orchestrator and transport over a unix socket in ~/.orchestra/run/sockets/ instead of localhost TCP with mTLS")
+	coldStart := fs.Bool("cold-start", false, "Check every plugin and let the orchestrator pick an address, instead of reusing the last start's")
+	silentStartup := fs.Bool("silent-startup", false, "Write nothing to stderr; log warnings and startup errors to the serve log only, for MCP clients that fail on stderr output")
+	profiling := &serveProfiling{}
+	fs.StringVar(&profiling.pprofAddr, "pprof", "", "Serve net/http/pprof on this address, e.g. :6060 (localhost unless a host is given)")
+	fs.StringVar(&profiling.cpuProfile, "cpuprofile", "", "Write a CPU profile of the serve process to this file")
+	fs.StringVar(&profiling.memProfile, "memprofile", "", "Write a heap profile of the serve process to this file on exit")
+	fs.StringVar(&profiling.orchestratorPprof, "orchestrator-pprof", "", "Ask the orchestrator to serve pprof on this address (ORCHESTRATOR_PPROF)")
+	fs.StringVar(&profiling.orchestratorCPUProfile, "orchestrator-cpuprofile", "", "Ask the orchestrator to write a CPU profile to this file (ORCHESTRATOR_CPUPROFILE)")
+	fs.StringVar(&profiling.orchestratorMemProfile, "orchestrator-memprofile", "", "Ask the orchestrator to write a heap profile to this file on exit (ORCHESTRATOR_MEMPROFILE)")
+	parseFlags(fs, args)
+
+	// With --transport=http, --listen is the MCP endpoint's address and the
+	// orchestrator picks a port of its own.
+	orchestratorListen := *listen
+	switch *transport {
+	case "stdio":
+		if httpOpts != (httpOptions{}) {
+			fatal("--token, --tls, --tls-cert, and --tls-key need --transport=http")
+		}
+	case "http":
+		if *daemon {
+			fatal("--transport=http cannot be combined with --daemon; HTTP clients share one serve already")
+		}
+		orchestratorListen = defaultListenAddr
+		if !flagGiven(fs, "listen") {
+			*listen = defaultHTTPListen
+		}
+	default:
+		fatal("--transport must be stdio or http, not %q", *transport)
+	}
+
+	// A daemon is started by a user, not an MCP client; its startup errors
+	// are reported by the process that starts it.
+	if *silentStartup && !*daemon {
+		guess := *logPath
+		if guess == "" {
+			if abs, err := filepath.Abs(*workspace); err == nil {
+				guess = defaultServeLog(abs)
+			}
+		}
+		if err := captureStderr(guess); err != nil {
+			fatal("--silent-startup: %v", err)
+		}
+		defer releaseStderr()
+	}
+
+	if *unixSocket {
+		switch {
+		case !unixSocketsSupported():
+			printStatus(tagWarn, "--unix-socket is not supported on %s; using localhost TCP with mTLS", runtime.GOOS)
+			*unixSocket = false
+		case *daemon:
+			printStatus(tagWarn, "--unix-socket is ignored with --daemon; sessions attach to a daemon over TCP with mTLS")
+			*unixSocket = false
+		case flagGiven(fs, "listen") && *transport == "stdio":
+			fatal("--listen and --unix-socket both set the orchestrator's address; use one")
+		}
+	}
 
 	// Resolve absolute paths.
-	absWorkspace, err := filepath.Abs(*workspace)
+	absWorkspace, err := resolveWorkspace(*workspace)
 	if err != nil {
 		fatal("resolve workspace: %v", err)
 	}
 
-	absCertsDir := *certsDir
-	if strings.HasPrefix(absCertsDir, "~") {
-		home, _ := os.UserHomeDir()
-		absCertsDir = filepath.Join(home, absCertsDir[1:])
+	guardWorkspace("serve", absWorkspace, *force)
+	checkWorkspaceSchema(absWorkspace, false)
+	timeouts, err := loadToolTimeouts(absWorkspace, *toolTimeout)
+	if err != nil {
+		fatal("%v", err)
+	}
+	levels, err := loadPluginLogLevels(absWorkspace)
+	if err != nil {
+		fatal("%v", err)
+	}
+
+	// --daemon starts this command again in the background. Without it,
+	// a daemon already serving the workspace is attached to.
+	if *daemon && os.Getenv(serveDaemonEnv) == "" {
+		spawnServeDaemon(absWorkspace, args)
+		return
+	}
+	os.Unsetenv(serveDaemonEnv)
+	var attachTo *daemonState
+	if !*daemon {
+		attachTo = runningDaemon(absWorkspace)
+	}
+
+	absCertsDir := expandHome(*certsDir)
+	if attachTo != nil {
+		absCertsDir = attachTo.CertsDir
 	}
 
 	logFile := *logPath
 	if logFile == "" {
-		logFile = filepath.Join(absWorkspace, ".orchestra-mcp.log")
+		logFile = defaultServeLog(absWorkspace)
+	}
+	capturedStderr.setLogFile(logFile)
+
+	bins, err := siblingBins()
+	if err != nil {
+		fatal("%v", err)
+	}
+
+	// Clean up after killed sessions: liveServeRecords stops the backend
+	// of each one it finds gone. Only when no other session runs is
+	// anything else running the serve binaries killed too, for sessions
+	// that left no record. Then truncate the logs, unless they are the
+	// daemon's.
+	if attachTo == nil {
+		if !anyServeRunning() {
+			killStaleProcesses(bins)
+		}
+		os.WriteFile(logFile, nil, 0644)
+		resetPluginLogs(pluginLogDir(absWorkspace))
+		os.Remove(pluginRestartsPath(absWorkspace))
+	}
+
+	lf, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		fatal("open log: %v", err)
+	}
+	defer lf.Close()
+	log := newServeLog(lf)
+	if attachTo == nil {
+		log.plugins = newPluginLogs(pluginLogDir(absWorkspace), levels)
+		defer log.plugins.close()
+	}
+	capturedStderr.attach(log)
+	if w := detectWorktree(absWorkspace); w.linked() {
+		fmt.Fprintf(log, "orchestra: %s\n", w.describe())
+	}
+	for _, warning := range worktreeWarnings(absWorkspace) {
+		fmt.Fprintf(log, "orchestra: warning: %s\n", warning)
+	}
+	if warning := worktreeMismatch(absWorkspace); warning != "" {
+		fmt.Fprintf(log, "orchestra: warning: %s\n", warning)
+	}
+	// IDE configs start serve without --force, so a large tree is only
+	// worth a warning here.
+	if hazard := workspaceSizeHazard(absWorkspace); hazard != "" && !*force {
+		fmt.Fprintf(log, "orchestra: warning: serving %s: %s; is this the project directory?\n", absWorkspace, hazard)
+	}
+
+	if err := profiling.start(log); err != nil {
+		fatal("%v", err)
+	}
+
+	sess := &serveSession{
+		bins:      bins,
+		certsDir:  absCertsDir,
+		logFile:   logFile,
+		log:       log,
+		force:     *force,
+		workspace: absWorkspace,
+
+		toolTimeouts:   timeouts,
+		profiling:      profiling,
+		restartPlugins: !*noPluginRestart,
+		coldStart:      *coldStart,
+		activity:       *activity,
+		daemonized:     *daemon,
+		daemon:         attachTo,
+	}
+	if flagGiven(fs, "plugins") {
+		sess.onlyPlugins = parsePluginList(*plugins)
+	}
+
+	var endpoint *httpEndpoint
+	if *transport == "http" {
+		endpoint, err = listenHTTP(*listen, httpOpts, absCertsDir, []listenFlag{
+			{"pprof", profiling.pprofAddr},
+			{"orchestrator-pprof", profiling.orchestratorPprof},
+		})
+		if err != nil {
+			profiling.stop(log)
+			fatal("%v", err)
+		}
+	}
+
+	if attachTo != nil {
+		sess.backend = daemonBackend(attachTo)
+		fmt.Fprintf(log, "orchestra: attached to serve daemon %d on %s\n", attachTo.PID, attachTo.Addr)
+		if flagGiven(fs, "listen") && *transport == "stdio" {
+			fmt.Fprintf(log, "orchestra: warning: --listen=%s ignored; the daemon's orchestrator listens on %s\n", *listen, attachTo.Addr)
+		}
+		if flagGiven(fs, "plugins") {
+			fmt.Fprintf(log, "orchestra: warning: --plugins ignored; the daemon loads its own plugins\n")
+		}
+	} else {
+		var listenAddr string
+		if *unixSocket {
+			listenAddr, err = serveSocketAddr()
+		} else {
+			listenAddr, err = resolveListenAddr(orchestratorListen, []listenFlag{
+				{"pprof", profiling.pprofAddr},
+				{"orchestrator-pprof", profiling.orchestratorPprof},
+			})
+		}
+		if err != nil {
+			profiling.stop(log)
+			fatal("%v", err)
+		}
+		if !*coldStart {
+			listenAddr = warmListenAddr(absWorkspace, listenAddr)
+		}
+		if err := sess.hook(hookPreServe).run(); err != nil {
+			fmt.Fprintf(log, "orchestra: %v\n", err)
+			profiling.stop(log)
+			fatal("%v; not serving", err)
+		}
+		// Start orchestrator.
+		sess.backend, err = sess.startBackend(absWorkspace, listenAddr)
+		if err != nil {
+			sess.backend.stop()
+			profiling.stop(log)
+			fatal("%v", err)
+		}
+		sess.attach()
+	}
+	writeServeRecord(sess.record(""))
+	if sess.daemonized {
+		runServeDaemon(sess)
+		return
+	}
+
+	// Setup signal handling and cleanup. SIGHUP, where there is one, is the
+	// reload signal `orchestra serve switch` sends.
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, serveSignals()...)
+	go func() {
+		for sig := range sigCh {
+			if isReloadSignal(sig) {
+				sess.handleSwitchRequest()
+				continue
+			}
+			sess.shutdown()
+			releaseStderr()
+			os.Exit(0)
+		}
+	}()
+	defer sess.shutdown()
+
+	if endpoint != nil {
+		fmt.Fprintf(log, "orchestra: MCP over HTTP on %s (/mcp, /sse)\n", endpoint.url())
+		printStatus(tagOK, "MCP over HTTP on %s", endpoint.url())
+		fmt.Fprintf(os.Stderr, "      streamable HTTP: %s/mcp\n      HTTP with SSE:   %s/sse\n", endpoint.url(), endpoint.url())
+		if httpOpts.token == "" {
+			fmt.Fprintf(os.Stderr, "      token: %s (from %s)\n", endpoint.token, httpTokenPath(absCertsDir))
+		}
+		if err := endpoint.serve(sess); err != nil {
+			sess.shutdown()
+			fatal("%v", err)
+		}
+		return
+	}
+
+	// Bridge stdin/stdout to transport-stdio until the IDE closes stdin,
+	// reconnecting if transport-stdio or the orchestrator dies. Backends
+	// restart on the same address.
+	code, err := newStdioBridge(sess, os.Stdout).run(os.Stdin)
+	if err != nil {
+		sess.shutdown()
+		fatal("%v", err)
+	}
+	if code != 0 {
+		sess.shutdown()
+		releaseStderr()
+		os.Exit(code)
 	}
+}
 
-	// Resolve sibling binaries.
+// siblingBins resolves the binaries serve runs, which ship next to the
+// orchestra executable.
+func siblingBins() (serveBins, error) {
 	selfPath, err := os.Executable()
 	if err != nil {
-		fatal("resolve self path: %v", err)
+		return nil, fmt.Errorf("resolve self path: %w", err)
 	}
 	selfPath, _ = filepath.EvalSymlinks(selfPath)
 	binDir := filepath.Dir(selfPath)
 
-	bins := map[string]string{
-		"orchestrator":       filepath.Join(binDir, "orchestrator"),
-		"storage-markdown":   filepath.Join(binDir, "storage-markdown"),
-		"tools-features":     filepath.Join(binDir, "tools-features"),
-		"tools-marketplace":  filepath.Join(binDir, "tools-marketplace"),
-		"transport-stdio":    filepath.Join(binDir, "transport-stdio"),
+	bins := serveBins{
+		"orchestrator":      filepath.Join(binDir, "orchestrator"),
+		"storage-markdown":  filepath.Join(binDir, "storage-markdown"),
+		"tools-features":    filepath.Join(binDir, "tools-features"),
+		"tools-marketplace": filepath.Join(binDir, "tools-marketplace"),
+		"transport-stdio":   filepath.Join(binDir, "transport-stdio"),
 	}
 	for name, path := range bins {
 		if _, err := os.Stat(path); os.IsNotExist(err) {
-			fatal("missing binary %q at %s", name, path)
+			return nil, fmt.Errorf("missing binary %q at %s", name, path)
 		}
 	}
+	return bins, nil
+}
 
-	// Kill stale processes.
-	for _, bin := range bins {
-		exec.Command("pkill", "-9", "-f", bin).Run()
+// serveConfig builds the orchestrator config for workspace. only, when not
+// nil, is serve --plugins: the installed plugins to load.
+func serveConfig(bins serveBins, certsDir, workspace, listenAddr string, only []string, log io.Writer) orchestratorConfig {
+	if _, ok := unixSocketPath(listenAddr); ok {
+		certsDir = ""
 	}
-	time.Sleep(500 * time.Millisecond)
-
-	// Write temp config.
 	cfg := orchestratorConfig{
-		ListenAddr: "localhost:0",
-		CertsDir:   absCertsDir,
+		ListenAddr: listenAddr,
+		CertsDir:   certsDir,
 		Plugins: []pluginConfig{
 			{
 				ID:              "storage.markdown",
 				Binary:          bins["storage-markdown"],
 				Enabled:         true,
 				ProvidesStorage: []string{"markdown"},
-				Args:            []string{fmt.Sprintf("--workspace=%s", absWorkspace)},
+				Args:            []string{fmt.Sprintf("--workspace=%s", workspace)},
 			},
 			{
 				ID:      "tools.features",
@@ -102,132 +428,321 @@ func RunServe(args []string) {
 				ID:      "tools.marketplace",
 				Binary:  bins["tools-marketplace"],
 				Enabled: true,
-				Args:    []string{fmt.Sprintf("--workspace=%s", absWorkspace)},
+				Args:    []string{fmt.Sprintf("--workspace=%s", workspace)},
 			},
 		},
 	}
 
-	// Load third-party plugins from registry.
-	registry, err := LoadRegistry()
-	if err == nil && registry != nil {
-		for _, p := range registry.Plugins {
-			// Verify binary still exists.
-			if _, err := os.Stat(p.Binary); err != nil {
-				continue // skip missing binaries
-			}
-			cfg.Plugins = append(cfg.Plugins, pluginConfig{
-				ID:              p.ID,
-				Binary:          p.Binary,
-				Enabled:         true,
-				ProvidesStorage: p.ProvidesStorage,
-				Args:            []string{fmt.Sprintf("--workspace=%s", absWorkspace)},
-			})
+	// Add third-party plugins, preferring vendored copies, in a stable
+	// order, unless they are disabled or --plugins leaves them out.
+	installed := servePlugins(workspace, log)
+	sel := loadPluginSelection(workspace, log)
+	sel.only = only
+	for _, id := range sel.unknown(installed) {
+		fmt.Fprintf(log, "orchestra: warning: plugin %s is selected but not installed\n", id)
+	}
+	for _, p := range installed {
+		// Verify binary still exists.
+		if _, err := os.Stat(p.Binary); err != nil {
+			continue // skip missing binaries
+		}
+		if reason := sel.skipReason(p.ID); reason != "" {
+			fmt.Fprintf(log, "orchestra: skipping plugin %s: %s\n", p.ID, reason)
+			continue
 		}
+		if reason := pluginIncompatibility(p); reason != "" {
+			fmt.Fprintf(log, "orchestra: skipping plugin %s: %s\n", p.ID, reason)
+			continue
+		}
+		cfg.Plugins = append(cfg.Plugins, registeredPluginConfig(p, workspace))
+	}
+	return cfg
+}
+
+// registeredPluginConfig is the orchestrator config for an installed plugin.
+func registeredPluginConfig(p *PluginEntry, workspace string) pluginConfig {
+	return pluginConfig{
+		ID:              p.ID,
+		Binary:          p.Binary,
+		Enabled:         true,
+		ProvidesStorage: p.ProvidesStorage,
+		Args:            []string{fmt.Sprintf("--workspace=%s", workspace)},
+
+		ProvidesPrompts:   p.ProvidesPrompts,
+		ProvidesResources: p.ProvidesResources,
+		ProtocolVersion:   checkOrchestratorProtocol(p.ProtocolVersion).Negotiated,
+		MCPVersion:        checkMCPRevision(p.MCPVersion).Negotiated,
 	}
+}
 
+// startBackend starts the orchestrator for workspace and waits for its
+// plugins to boot. Local versions in .claude/overrides/ are copied over
+// pack content first, so the IDE and plugins see them, the key of an
+// encrypted workspace is given to its storage plugins, the orchestrator's
+// profiling settings are passed down in the environment, and a feature index built by compact is brought
+// up to date. On error the returned backend (if any) still needs stop.
+func (s *serveSession) startBackend(workspace, listenAddr string) (*serveBackend, error) {
+	applied, err := packs.ApplyOverlay(workspace)
+	for _, p := range applied {
+		fmt.Fprintf(s.log, "orchestra: kept local %s (from %s)\n", p, packs.OverlayPath(p))
+	}
+	if err != nil {
+		fmt.Fprintf(s.log, "orchestra: %v\n", err)
+	}
+	start := time.Now()
+	env := s.profiling.orchestratorEnv()
+	if loadFeatureIndex(workspace) != nil {
+		scanFeatureSummaries(workspace) // refreshes the index the storage plugin loads
+	}
+	removeStaleSocket(listenAddr)
+	cfg, warm := s.backendConfig(workspace, listenAddr)
+	cfg.Plugins, err = withWorkspaceKey(workspace, cfg.Plugins)
+	if err != nil {
+		return nil, err
+	}
+	if s.log.plugins != nil {
+		s.log.plugins.setPlugins(cfg.Plugins)
+	}
+	if s.restartPlugins {
+		plugins, superviseEnv, err := supervisePlugins(cfg.Plugins, pluginRestartsPath(workspace), s.logFile)
+		if err != nil {
+			fmt.Fprintf(s.log, "orchestra: %v; crashed plugins will not be restarted\n", err)
+		}
+		cfg.Plugins = plugins
+		env = append(env, superviseEnv...)
+	}
+	b, err := startOrchestrator(s.bins["orchestrator"], cfg, env, s.logFile, s.log, 3)
+	if err == nil {
+		fmt.Fprintf(s.log, "orchestra: backend ready in %s (%d plugins)\n", time.Since(start).Round(time.Millisecond), len(cfg.Plugins))
+		debugf("backend ready (%d plugins, %s)", len(cfg.Plugins), debugDuration(time.Since(start)))
+		s.saveBackendStart(workspace, warm, b.addr)
+	}
+	return b, err
+}
+
+// readyPollInterval is how often startOrchestrator reads the log for
+// booted plugins. Short, as a warm start is done in well under a second.
+const readyPollInterval = 20 * time.Millisecond
+
+// startOrchestrator writes cfg to a temp file, starts the orchestrator on
+// it with env added to its environment and output appended to logFile
+// (open as log), and waits until wantBooted plugins have booted. On error
+// the returned backend (if any) still needs stop.
+func startOrchestrator(bin string, cfg orchestratorConfig, env []string, logFile string, log *serveLog, wantBooted int) (*serveBackend, error) {
 	tmpFile, err := os.CreateTemp("", "orchestra-*.yaml")
 	if err != nil {
-		fatal("create temp config: %v", err)
+		return nil, fmt.Errorf("create temp config: %w", err)
 	}
-	tmpConfig := tmpFile.Name()
+	b := &serveBackend{config: tmpFile.Name()}
 
-	data, _ := yaml.Marshal(&cfg)
+	data, _ := yaml.Marshal(cfg)
 	tmpFile.Write(data)
 	tmpFile.Close()
 
-	// Truncate log.
-	os.WriteFile(logFile, nil, 0644)
-
-	// Setup signal handling and cleanup.
-	var orchCmd *exec.Cmd
-	cleanup := func() {
-		if orchCmd != nil && orchCmd.Process != nil {
-			// Kill children first, then orchestrator.
-			exec.Command("pkill", "-P", fmt.Sprintf("%d", orchCmd.Process.Pid)).Run()
-			orchCmd.Process.Signal(syscall.SIGTERM)
-			time.Sleep(300 * time.Millisecond)
-			exec.Command("pkill", "-9", "-P", fmt.Sprintf("%d", orchCmd.Process.Pid)).Run()
-			orchCmd.Process.Kill()
-		}
-		os.Remove(tmpConfig)
+	// Only log lines written by this orchestrator count towards readiness.
+	var offset int64
+	if info, err := os.Stat(logFile); err == nil {
+		offset = info.Size()
 	}
 
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-	go func() {
-		<-sigCh
-		cleanup()
-		os.Exit(0)
-	}()
-	defer cleanup()
-
-	// Start orchestrator.
-	lf, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	b.cmd = exec.Command(bin, "--config", b.config)
+	if len(env) > 0 {
+		b.cmd.Env = append(os.Environ(), env...)
+	}
+	out, err := log.pipe(logSourceOrchestrator)
 	if err != nil {
-		fatal("open log: %v", err)
+		return b, fmt.Errorf("start orchestrator: %w", err)
 	}
-	defer lf.Close()
-
-	orchCmd = exec.Command(bins["orchestrator"], "--config", tmpConfig)
-	orchCmd.Stdout = lf
-	orchCmd.Stderr = lf
-	if err := orchCmd.Start(); err != nil {
-		fatal("start orchestrator: %v", err)
+	b.cmd.Stdout = out
+	b.cmd.Stderr = out
+	b.tree = newProcessTree(b.cmd)
+	err = b.cmd.Start()
+	out.Close()
+	if err != nil {
+		return b, fmt.Errorf("start orchestrator: %w", err)
 	}
-
-	// Write PID file.
-	pidFile := filepath.Join(absWorkspace, ".orchestra-mcp.pid")
-	os.WriteFile(pidFile, []byte(fmt.Sprintf("%d", orchCmd.Process.Pid)), 0644)
-	defer os.Remove(pidFile)
+	if err := b.tree.started(b.cmd.Process.Pid); err != nil {
+		fmt.Fprintf(log, "orchestra: %v; plugins may outlive serve\n", err)
+	}
+	b.exited = make(chan struct{})
+	go func() {
+		b.cmd.Wait()
+		close(b.exited)
+	}()
 
 	// Wait for plugins to register.
 	addrRe := regexp.MustCompile(`listening on (\S+)`)
 	ready := false
-	for i := 0; i < 30; i++ {
-		time.Sleep(500 * time.Millisecond)
+	var logStr string
+	for deadline := time.Now().Add(15 * time.Second); time.Now().Before(deadline); {
+		time.Sleep(readyPollInterval)
 
-		logData, _ := os.ReadFile(logFile)
-		logStr := string(logData)
+		logStr = readLogFrom(logFile, offset)
 
 		booted := strings.Count(logStr, "registered and booted")
-		if booted >= 3 {
+		if booted >= wantBooted {
 			ready = true
 			break
 		}
 
 		// Check if orchestrator is still alive.
-		if orchCmd.ProcessState != nil {
-			fatal("orchestrator exited unexpectedly. Check %s", logFile)
+		if b.hasExited() {
+			return b, fmt.Errorf("orchestrator exited unexpectedly. Check %s", logFile)
 		}
 	}
 
 	if !ready {
-		fatal("orchestrator did not become ready in 15 seconds. Check %s", logFile)
+		return b, fmt.Errorf("orchestrator did not become ready in 15 seconds. Check %s", logFile)
+	}
+
+	// A socket is where it was configured.
+	if _, ok := unixSocketPath(cfg.ListenAddr); ok {
+		b.addr = cfg.ListenAddr
+		return b, nil
 	}
 
 	// Extract listen address.
-	logData, _ := os.ReadFile(logFile)
-	matches := addrRe.FindStringSubmatch(string(logData))
+	matches := addrRe.FindStringSubmatch(logStr)
 	if len(matches) < 2 {
-		fatal("could not determine orchestrator address. Check %s", logFile)
+		return b, fmt.Errorf("could not determine orchestrator address. Check %s", logFile)
+	}
+	b.addr = matches[1]
+	return b, nil
+}
+
+// stop asks the orchestrator and its plugins to exit, kills whatever is
+// left, and removes its config. It is safe on a nil or partly started
+// backend.
+func (b *serveBackend) stop() {
+	if b == nil {
+		return
+	}
+	if b.cmd != nil && b.cmd.Process != nil {
+		b.tree.terminate()
+		time.Sleep(300 * time.Millisecond)
+		b.tree.kill()
+		b.cmd.Process.Kill()
+		<-b.exited
+		removeStaleSocket(b.addr)
+	}
+	os.Remove(b.config)
+}
+
+// hasExited reports whether the orchestrator process has exited.
+func (b *serveBackend) hasExited() bool {
+	select {
+	case <-b.exited:
+		return true
+	default:
+		return false
+	}
+}
+
+// ensureBackend restarts the backend on its address if the orchestrator
+// has exited, as the stdio bridge does before reconnecting.
+func (s *serveSession) ensureBackend() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.backend == nil {
+		return errServeStopped
+	}
+	if !s.backend.hasExited() {
+		return nil
+	}
+	if s.daemon != nil {
+		return s.reattachDaemon() // the daemon restarts its own orchestrator
+	}
+	fmt.Fprintf(s.log, "orchestra: orchestrator exited; restarting it on %s\n", s.backend.addr)
+	s.detach()
+	s.backend.stop()
+	backend, err := s.startBackend(s.workspace, s.backend.addr)
+	if err != nil {
+		backend.stop() // s.backend stays the exited one, so a retry reuses its address
+		return err
+	}
+	s.backend = backend
+	s.attach()
+	writeServeRecord(s.record(""))
+	return nil
+}
+
+// attach writes the workspace's PID file and starts recording feature
+// transitions, watching installed content for doc regeneration, and
+// recording activity with --activity, for it. The caller holds s.mu
+// or has not shared s yet.
+func (s *serveSession) attach() {
+	os.WriteFile(s.pidFile(), []byte(fmt.Sprintf("%d", s.backend.cmd.Process.Pid)), 0644)
+
+	// Record feature state changes made through MCP tools for analytics.
+	s.stopWatch = make(chan struct{})
+	go watchTransitions(s.workspace, 10*time.Second, s.stopWatch)
+	go watchDocs(s.workspace, docsWatchInterval, s.stopWatch, s.log)
+	if s.activity {
+		s.activityDone = make(chan struct{})
+		go watchActivity(s.workspace, os.Getpid(), activityInterval, s.stopWatch, s.activityDone)
+	}
+}
+
+// detach undoes attach. It waits briefly for the activity watcher to
+// record the end of the session.
+func (s *serveSession) detach() {
+	if s.stopWatch != nil {
+		close(s.stopWatch)
+		s.stopWatch = nil
+	}
+	if s.activityDone != nil {
+		select {
+		case <-s.activityDone:
+		case <-time.After(5 * time.Second):
+		}
+		s.activityDone = nil
 	}
-	orchAddr := matches[1]
+	os.Remove(s.pidFile())
+}
+
+func (s *serveSession) pidFile() string {
+	return servePIDFile(s.workspace)
+}
+
+// servePIDFile is where serve records its orchestrator's pid. In a linked
+// worktree the name carries the worktree's (worktreeScoped), as do the
+// default log and the daemon state.
+func servePIDFile(workspace string) string {
+	return worktreeScoped(workspace, filepath.Join(workspace, ".orchestra-mcp.pid"))
+}
 
-	// Run transport-stdio (stdin/stdout passthrough).
-	transportCmd := exec.Command(bins["transport-stdio"],
-		fmt.Sprintf("--orchestrator-addr=%s", orchAddr),
-		fmt.Sprintf("--certs-dir=%s", absCertsDir),
-	)
-	transportCmd.Stdin = os.Stdin
-	transportCmd.Stdout = os.Stdout
-	transportCmd.Stderr = lf
+// defaultServeLog is the log serve writes without --log.
+func defaultServeLog(workspace string) string {
+	return worktreeScoped(workspace, filepath.Join(workspace, ".orchestra-mcp.log"))
+}
 
-	if err := transportCmd.Run(); err != nil {
-		// Transport exited — this is normal when stdin closes.
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			os.Exit(exitErr.ExitCode())
+// shutdown stops the backend and removes the session's files. Later calls
+// are no-ops. A daemon's backend, used by an attached session, is left
+// running.
+func (s *serveSession) shutdown() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.backend != nil {
+		if s.daemon == nil {
+			s.detach()
+		}
+		s.backend.stop()
+		s.backend = nil
+		if s.daemon == nil {
+			runPostHook(s.hook(hookPostServe))
 		}
 	}
+	s.profiling.stop(s.log)
+	removeServeRecord(os.Getpid())
+}
+
+// hook is a serve lifecycle event for the session's workspace. Hooks log to
+// the serve log: serve's stdout is the MCP stream.
+func (s *serveSession) hook(event string) hookRun {
+	return hookRun{event: event, workspace: s.workspace, log: s.log, vars: []string{
+		"ORCHESTRA_LOG=" + s.logFile,
+		"ORCHESTRA_SERVE_PID=" + strconv.Itoa(os.Getpid()),
+	}}
 }
 
 func defaultCertsDir() string {
@@ -236,6 +751,12 @@ func defaultCertsDir() string {
 }
 
 func fatal(format string, args ...any) {
-	fmt.Fprintf(os.Stderr, "orchestra: "+format+"\n", args...)
+	if c := capturedStderr; c != nil {
+		c.fail(fmt.Sprintf(format, args...))
+	} else {
+		fmt.Fprintf(os.Stderr, "orchestra: "+format+"\n", args...)
+	}
+	debugf("exit 1")
+	releaseStderr()
 	os.Exit(1)
 }
@@ -0,0 +1,521 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// The IDE's MCP session lives on serve's stdin and stdout for as long as
+// the IDE runs, but transport-stdio and the orchestrator behind it can die
+// underneath it: a crash, an OOM kill, a laptop waking from sleep. serve
+// therefore sits between the two and copies messages line by line,
+// remembering the client's initialize request. When transport-stdio or the
+// orchestrator exits, serve restarts whatever died on the same address,
+// replays initialize and notifications/initialized on the new connection,
+// answers the requests that were in flight with an error, and then forwards
+// what the IDE sent in the meantime. The IDE never sees the session close.
+// `orchestra serve switch` reconnects the same way.
+
+const (
+	// reconnectTimeout bounds how long serve keeps retrying before it gives
+	// up and ends the session.
+	reconnectTimeout = time.Minute
+	// replayTimeout bounds the wait for the replayed initialize response.
+	replayTimeout = 15 * time.Second
+	// rpcInternalError is the JSON-RPC code for requests lost to a restart.
+	rpcInternalError = -32603
+)
+
+// errServeStopped is returned while reconnecting if serve is shutting down.
+var errServeStopped = errors.New("serve is shutting down")
+
+// bridgeMessage is the part of a JSON-RPC message the bridge routes on. IDs
+// are kept raw: clients may use numbers or strings.
+type bridgeMessage struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method,omitempty"`
+}
+
+// parseBridgeMessage returns the message's ID (as a map key, "" for
+// notifications) and method ("" for responses). Lines that are not JSON
+// objects come back empty and are forwarded untouched.
+func parseBridgeMessage(line []byte) (id, method string) {
+	var m bridgeMessage
+	if json.Unmarshal(line, &m) != nil {
+		return "", ""
+	}
+	if len(m.ID) > 0 && string(m.ID) != "null" {
+		id = string(m.ID)
+	}
+	return id, m.Method
+}
+
+// toolCallName returns the tool a tools/call request invokes.
+func toolCallName(line []byte) string {
+	var m struct {
+		Params struct {
+			Name string `json:"name"`
+		} `json:"params"`
+	}
+	json.Unmarshal(line, &m)
+	return m.Params.Name
+}
+
+// cancelledRequestID returns the request a notifications/cancelled names,
+// as a map key.
+func cancelledRequestID(line []byte) string {
+	var m struct {
+		Params struct {
+			RequestID json.RawMessage `json:"requestId"`
+		} `json:"params"`
+	}
+	json.Unmarshal(line, &m)
+	return string(m.Params.RequestID)
+}
+
+// queuedMessage is a client message held while the backend reconnects.
+type queuedMessage struct {
+	line       []byte
+	id, method string
+}
+
+// stdioBridge copies MCP messages between the IDE and transport-stdio.
+type stdioBridge struct {
+	sess *serveSession
+
+	mu        sync.Mutex
+	stdin     io.WriteCloser // transport-stdio's stdin; nil while down
+	transport *exec.Cmd
+	down      bool // reconnecting: client messages are queued
+	closing   bool // the client closed stdin
+	queue     []queuedMessage
+	pending   map[string]string // request ID -> method, sent but not answered
+	calls     map[string]*toolCall
+	timedOut  map[string]bool // tools/call requests answered with a timeout
+
+	// The client's handshake, replayed on every new connection.
+	initLine        []byte
+	initID          string
+	initDone        bool // the server answered initialize
+	initializedLine []byte
+	replays         int
+	replayID        string
+	replayed        chan struct{}
+
+	outMu sync.Mutex
+	out   io.Writer
+}
+
+func newStdioBridge(sess *serveSession, out io.Writer) *stdioBridge {
+	return &stdioBridge{
+		sess:     sess,
+		out:      out,
+		pending:  map[string]string{},
+		calls:    map[string]*toolCall{},
+		timedOut: map[string]bool{},
+	}
+}
+
+// toolCall is a tools/call request waiting for its reply.
+type toolCall struct {
+	tool  string
+	timer *time.Timer // nil without a limit
+}
+
+// run bridges in to transport-stdio until the client closes in, and
+// returns transport-stdio's exit code at that point. It reconnects whenever
+// transport-stdio exits while the client is still connected.
+func (b *stdioBridge) run(in io.Reader) (int, error) {
+	exited, err := b.startTransport()
+	if err != nil {
+		return 1, err
+	}
+	stopMetrics := make(chan struct{})
+	defer close(stopMetrics)
+	go b.sess.flushMetrics(stopMetrics)
+
+	go b.readClient(in)
+	for {
+		err := <-exited
+		if b.isClosing() {
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				return exitErr.ExitCode(), nil
+			}
+			return 0, nil
+		}
+		fmt.Fprintf(b.sess.log, "orchestra: transport-stdio exited (%v); reconnecting\n", orDash(errString(err)))
+		exited, err = b.reconnect()
+		if errors.Is(err, errServeStopped) || (err == nil && exited == nil) {
+			return 0, nil
+		}
+		if err != nil {
+			return 1, err
+		}
+	}
+}
+
+func (b *stdioBridge) isClosing() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.closing
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// startTransport starts transport-stdio against the current backend. The
+// returned channel receives its exit status once its output is drained.
+// If the orchestrator exits first, transport-stdio is killed so the
+// bridge reconnects to a fresh one.
+func (b *stdioBridge) startTransport() (<-chan error, error) {
+	b.sess.mu.Lock()
+	backend := b.sess.backend
+	b.sess.mu.Unlock()
+	if backend == nil {
+		return nil, errServeStopped
+	}
+
+	cmd := exec.Command(b.sess.bins["transport-stdio"], transportArgs(backend.addr, b.sess.certsDir)...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stderr = b.sess.log.source(logSourceTransport)
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start transport-stdio: %w", err)
+	}
+
+	b.mu.Lock()
+	b.transport, b.stdin = cmd, stdin
+	b.mu.Unlock()
+
+	exited := make(chan error, 1)
+	stopped := make(chan struct{})
+	go func() {
+		b.readServer(stdout)
+		err := cmd.Wait()
+		close(stopped)
+		exited <- err
+	}()
+	go func() {
+		select {
+		case <-backend.exited:
+			cmd.Process.Kill()
+		case <-stopped:
+		}
+	}()
+	return exited, nil
+}
+
+// readClient forwards the client's messages, recording its handshake.
+func (b *stdioBridge) readClient(in io.Reader) {
+	r := bufio.NewReader(in)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			id, method := parseBridgeMessage(line)
+			if method == "tools/call" && id != "" && isServeTool(toolCallName(line)) {
+				go b.callServeTool(id, toolCallName(line))
+				continue
+			}
+			b.mu.Lock()
+			switch {
+			case method == "initialize" && id != "":
+				b.initLine, b.initID, b.initDone = line, id, false
+			case method == "notifications/initialized":
+				b.initializedLine = line
+			case method == "notifications/cancelled":
+				b.endCallLocked(cancelledRequestID(line))
+			}
+			b.sendLocked(queuedMessage{line: line, id: id, method: method})
+			b.mu.Unlock()
+		}
+		if err != nil {
+			b.mu.Lock()
+			b.closing = true
+			if b.stdin != nil {
+				b.stdin.Close()
+			}
+			b.mu.Unlock()
+			return
+		}
+	}
+}
+
+// sendLocked writes m to transport-stdio, or queues it while the bridge
+// is down. The caller holds b.mu.
+func (b *stdioBridge) sendLocked(m queuedMessage) {
+	if b.down || b.stdin == nil {
+		b.queue = append(b.queue, m)
+		return
+	}
+	if _, err := b.stdin.Write(m.line); err != nil {
+		b.queue = append(b.queue, m) // transport is dying; resent after reconnect
+		return
+	}
+	if m.id != "" && m.method != "" {
+		b.pending[m.id] = m.method
+	}
+	if m.method == "tools/call" && m.id != "" {
+		b.startCallLocked(m.id, toolCallName(m.line))
+	}
+}
+
+// startCallLocked times a tools/call request against its tool's limit.
+// The caller holds b.mu.
+func (b *stdioBridge) startCallLocked(id, tool string) {
+	b.sess.metrics.countCall()
+	call := &toolCall{tool: tool}
+	if limit := b.sess.toolTimeouts.forTool(tool); limit > 0 {
+		call.timer = time.AfterFunc(limit, func() { b.timeoutCall(id, call, limit) })
+	}
+	b.calls[id] = call
+}
+
+// endCallLocked stops timing request id, answered or cancelled by the
+// client. The caller holds b.mu.
+func (b *stdioBridge) endCallLocked(id string) {
+	if call, ok := b.calls[id]; ok {
+		if call.timer != nil {
+			call.timer.Stop()
+		}
+		delete(b.calls, id)
+	}
+}
+
+// timeoutCall gives up on a tool call: it cancels the request in the
+// backend, answers the client with a timeout error, and logs and counts it.
+func (b *stdioBridge) timeoutCall(id string, call *toolCall, limit time.Duration) {
+	b.mu.Lock()
+	if b.calls[id] != call {
+		b.mu.Unlock()
+		return // answered in the meantime
+	}
+	delete(b.calls, id)
+	delete(b.pending, id)
+	b.timedOut[id] = true
+	reason := fmt.Sprintf("timed out after %s", limit)
+	if b.stdin != nil && !b.down {
+		b.stdin.Write(rpcCancelledLine(id, reason))
+	}
+	b.mu.Unlock()
+
+	b.writeClient(rpcErrorLine(id, rpcRequestTimeout, fmt.Sprintf("tool %s %s; orchestra cancelled it", call.tool, reason)))
+	fmt.Fprintf(b.sess.log, "orchestra: tool call %s (request %s) %s; cancelled\n", call.tool, id, reason)
+	b.sess.metrics.countTimeout(call.tool)
+}
+
+// readServer forwards transport-stdio's messages to the client, except the
+// response to a replayed initialize.
+func (b *stdioBridge) readServer(r io.Reader) {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			if id, method := parseBridgeMessage(line); id != "" && method == "" {
+				b.mu.Lock()
+				if id == b.replayID {
+					b.replayID = ""
+					close(b.replayed)
+					b.mu.Unlock()
+					continue
+				}
+				if b.timedOut[id] {
+					delete(b.timedOut, id) // the client already got a timeout error
+					b.mu.Unlock()
+					continue
+				}
+				if id == b.initID {
+					b.initDone = true
+				}
+				if b.pending[id] == "tools/list" {
+					line = addServeTools(line)
+				}
+				delete(b.pending, id)
+				b.endCallLocked(id)
+				b.mu.Unlock()
+			}
+			b.writeClient(line)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// callServeTool answers a tools/call for one of serve's own tools, which
+// works while the backend is down too.
+func (b *stdioBridge) callServeTool(id, tool string) {
+	b.sess.metrics.countCall()
+	b.sess.mu.Lock()
+	workspace := b.sess.workspace
+	b.sess.mu.Unlock()
+	b.writeClient(callServeTool(id, tool, workspace, b.sess.log))
+}
+
+func (b *stdioBridge) writeClient(line []byte) {
+	b.outMu.Lock()
+	defer b.outMu.Unlock()
+	if !bytes.HasSuffix(line, []byte("\n")) {
+		line = append(line, '\n')
+	}
+	b.out.Write(line)
+}
+
+// reconnect restarts the backend if it died and transport-stdio, replays
+// the handshake, and flushes queued messages, retrying with backoff for up
+// to reconnectTimeout. It returns a nil channel if the client went away.
+func (b *stdioBridge) reconnect() (<-chan error, error) {
+	b.mu.Lock()
+	b.down, b.stdin = true, nil
+	var lost []string
+	for id, method := range b.pending {
+		if method != "initialize" { // resent by replayHandshake
+			lost = append(lost, id)
+		}
+	}
+	sortNames(lost)
+	for _, id := range lost {
+		delete(b.pending, id)
+		b.endCallLocked(id)
+		b.writeClient(rpcErrorLine(id, rpcInternalError, "the orchestra backend restarted before replying; retry the request"))
+	}
+	b.timedOut = map[string]bool{} // the new connection will not answer them
+	b.mu.Unlock()
+
+	start := time.Now()
+	delay := 500 * time.Millisecond
+	for {
+		if b.isClosing() {
+			return nil, nil
+		}
+		err := b.sess.ensureBackend()
+		if errors.Is(err, errServeStopped) {
+			return nil, err
+		}
+		if err == nil {
+			var exited <-chan error
+			if exited, err = b.startTransport(); err == nil {
+				if err = b.replayHandshake(); err == nil {
+					fmt.Fprintf(b.sess.log, "orchestra: reconnected after %s\n", time.Since(start).Round(time.Millisecond))
+					return exited, nil
+				}
+				b.mu.Lock()
+				b.transport.Process.Kill()
+				b.mu.Unlock()
+				<-exited
+			}
+		}
+		fmt.Fprintf(b.sess.log, "orchestra: reconnect failed: %v\n", err)
+		if time.Since(start) > reconnectTimeout {
+			return nil, fmt.Errorf("the orchestra backend did not come back within %s: %v", reconnectTimeout, err)
+		}
+		time.Sleep(delay)
+		if delay < 8*time.Second {
+			delay *= 2
+		}
+	}
+}
+
+// replayHandshake initializes the new connection the way the client did
+// and then lets queued messages through. An initialize the server never
+// answered is simply resent, so the client gets its response.
+func (b *stdioBridge) replayHandshake() error {
+	b.mu.Lock()
+	if b.initLine != nil && b.initDone {
+		b.replays++
+		b.replayID = fmt.Sprintf(`"orchestra-replay-%d"`, b.replays)
+		b.replayed = make(chan struct{})
+		line, err := replaceRPCID(b.initLine, b.replayID)
+		if err != nil {
+			b.mu.Unlock()
+			return err
+		}
+		if _, err := b.stdin.Write(line); err != nil {
+			b.mu.Unlock()
+			return err
+		}
+		replayed := b.replayed
+		b.mu.Unlock()
+
+		select {
+		case <-replayed:
+		case <-time.After(replayTimeout):
+			b.mu.Lock()
+			b.replayID = ""
+			b.mu.Unlock()
+			return fmt.Errorf("no response to the replayed initialize in %s", replayTimeout)
+		}
+
+		b.mu.Lock()
+		if b.initializedLine != nil {
+			if _, err := b.stdin.Write(b.initializedLine); err != nil {
+				b.mu.Unlock()
+				return err
+			}
+		}
+	} else if b.initLine != nil && b.pending[b.initID] == "initialize" {
+		delete(b.pending, b.initID)
+		b.queue = append([]queuedMessage{{line: b.initLine, id: b.initID, method: "initialize"}}, b.queue...)
+	}
+
+	b.down = false
+	queue := b.queue
+	b.queue = nil
+	for _, m := range queue {
+		b.sendLocked(m)
+	}
+	b.mu.Unlock()
+	return nil
+}
+
+// replaceRPCID returns the JSON-RPC message line with its id set to id (a
+// JSON value).
+func replaceRPCID(line []byte, id string) ([]byte, error) {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(line, &m); err != nil {
+		return nil, err
+	}
+	m["id"] = json.RawMessage(id)
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// rpcErrorLine is a JSON-RPC error response to request id (a JSON value).
+func rpcErrorLine(id string, code int, message string) []byte {
+	data, _ := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      json.RawMessage(id),
+		"error":   rpcError{Code: code, Message: message},
+	})
+	return append(data, '\n')
+}
+
+// rpcCancelledLine is the MCP notifications/cancelled for request id.
+func rpcCancelledLine(id, reason string) []byte {
+	data, _ := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"method":  "notifications/cancelled",
+		"params":  map[string]any{"requestId": json.RawMessage(id), "reason": reason},
+	})
+	return append(data, '\n')
+}
@@ -0,0 +1,278 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"time"
+)
+
+// Daemon mode. `orchestra serve --daemon` starts the backend (orchestrator
+// and plugins) in a background process that outlives the terminal, and
+// records it in .projects/.serve.json. A later `orchestra serve` in the same
+// workspace finds the file and attaches instead of starting a backend of its
+// own: it runs just transport-stdio against the daemon's address, so any
+// number of IDE sessions share one process tree. The daemon restarts the
+// orchestrator on the same address if it dies; attached sessions reconnect
+// to it the way a foreground serve reconnects to its own. `orchestra stop`
+// ends the daemon.
+
+// serveDaemonEnv marks the background process `serve --daemon` starts.
+const serveDaemonEnv = "ORCHESTRA_SERVE_DAEMON"
+
+// daemonStartTimeout bounds how long `serve --daemon` waits for the
+// background process to report its backend ready.
+const daemonStartTimeout = 30 * time.Second
+
+// daemonState is .projects/.serve.json, written by a running daemon.
+type daemonState struct {
+	PID             int    `json:"pid"`              // the daemon's serve process
+	OrchestratorPID int    `json:"orchestrator_pid"` // changes when the orchestrator is restarted
+	Addr            string `json:"addr"`
+	CertsDir        string `json:"certs_dir"`
+	Log             string `json:"log"`
+	Version         string `json:"version"` // orchestra version running the daemon
+	StartedAt       string `json:"started_at"`
+}
+
+func daemonStatePath(workspace string) string {
+	return worktreeScoped(workspace, filepath.Join(workspace, ".projects", ".serve.json"))
+}
+
+// runningDaemon returns the state of the daemon serving workspace, or nil
+// when none is running. A state file left by a daemon that died is removed.
+func runningDaemon(workspace string) *daemonState {
+	data, err := os.ReadFile(daemonStatePath(workspace))
+	if err != nil {
+		return nil
+	}
+	var st daemonState
+	if json.Unmarshal(data, &st) != nil || st.PID == 0 {
+		return nil
+	}
+	if !processAlive(st.PID) {
+		os.Remove(daemonStatePath(workspace))
+		return nil
+	}
+	return &st
+}
+
+// anyServeRunning reports whether a serve session, or a daemon, is running
+// for any workspace -- another worktree of the repository, say.
+func anyServeRunning() bool {
+	return len(liveServeRecords()) > 0
+}
+
+// writeDaemonState records the daemon in its workspace. The caller holds
+// s.mu or has not shared s yet.
+func (s *serveSession) writeDaemonState(startedAt string) error {
+	st := daemonState{
+		PID:       os.Getpid(),
+		Addr:      s.backend.addr,
+		CertsDir:  s.certsDir,
+		Log:       s.logFile,
+		Version:   Version,
+		StartedAt: startedAt,
+	}
+	if s.backend.cmd != nil && s.backend.cmd.Process != nil {
+		st.OrchestratorPID = s.backend.cmd.Process.Pid
+	}
+	if err := os.MkdirAll(filepath.Dir(daemonStatePath(s.workspace)), 0755); err != nil {
+		return err
+	}
+	data, _ := json.MarshalIndent(st, "", "  ")
+	return writeFileAtomic(daemonStatePath(s.workspace), append(data, '\n'), 0644)
+}
+
+// spawnServeDaemon starts `serve` with args again as a detached background
+// process and waits until it reports its backend ready.
+func spawnServeDaemon(workspace string, args []string) {
+	if st := runningDaemon(workspace); st != nil {
+		printStatus(tagSkip, "serve daemon %d already runs for %s on %s", st.PID, workspace, st.Addr)
+		return
+	}
+	self, err := os.Executable()
+	if err != nil {
+		fatal("resolve self path: %v", err)
+	}
+	// The daemon writes to its log once it opens it; anything it prints
+	// before that lands here, so a failed start can be explained.
+	errFile, err := os.CreateTemp("", "orchestra-daemon-*.log")
+	if err != nil {
+		fatal("create temp file: %v", err)
+	}
+	defer os.Remove(errFile.Name())
+	defer errFile.Close()
+
+	cmd := exec.Command(self, append([]string{"serve"}, args...)...)
+	cmd.Env = append(os.Environ(), serveDaemonEnv+"=1")
+	cmd.Stderr = errFile
+	cmd.SysProcAttr = daemonSysProcAttr()
+	if err := cmd.Start(); err != nil {
+		fatal("start daemon: %v", err)
+	}
+	exited := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(exited)
+	}()
+
+	sp := startSpinner("Starting serve daemon for " + workspace)
+	deadline := time.After(daemonStartTimeout)
+	for {
+		select {
+		case <-exited:
+			data, _ := os.ReadFile(errFile.Name())
+			sp.Stop(errors.New("exited"))
+			fatal("the daemon exited during startup: %s", orDash(lastLine(string(data))))
+		case <-deadline:
+			sp.Stop(errors.New("timed out"))
+			cmd.Process.Kill()
+			fatal("the daemon did not start in %s", daemonStartTimeout)
+		case <-time.After(200 * time.Millisecond):
+		}
+		if st := runningDaemon(workspace); st != nil && st.PID == cmd.Process.Pid {
+			sp.Stop(nil)
+			printStatus(tagOK, "serve daemon %d runs for %s on %s", st.PID, workspace, st.Addr)
+			fmt.Fprintf(os.Stderr, "IDE sessions started with 'orchestra serve' here attach to it. Stop it with 'orchestra stop'.\n")
+			return
+		}
+	}
+}
+
+// runServeDaemon is the background process of `serve --daemon`: it keeps
+// the backend running, restarting the orchestrator if it dies, until it is
+// signalled to stop.
+func runServeDaemon(sess *serveSession) {
+	startedAt := time.Now().UTC().Format(time.RFC3339)
+	sess.mu.Lock()
+	err := sess.writeDaemonState(startedAt)
+	sess.mu.Unlock()
+	if err != nil {
+		sess.shutdown()
+		fatal("write daemon state: %v", err)
+	}
+	fmt.Fprintf(sess.log, "orchestra: daemon %d serving %s on %s\n", os.Getpid(), sess.workspace, sess.backend.addr)
+
+	// SIGHUP asks for a workspace switch, which switchWorkspace refuses
+	// for a daemon; answering it reports why.
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, serveSignals()...)
+	go func() {
+		for sig := range sigCh {
+			if isReloadSignal(sig) {
+				sess.handleSwitchRequest()
+				continue
+			}
+			fmt.Fprintf(sess.log, "orchestra: daemon stopping (%s)\n", sig)
+			sess.shutdown()
+			os.Remove(daemonStatePath(sess.workspace))
+			os.Exit(0)
+		}
+	}()
+
+	for {
+		sess.mu.Lock()
+		backend := sess.backend
+		sess.mu.Unlock()
+		if backend == nil {
+			return
+		}
+		<-backend.exited
+		if err := sess.ensureBackend(); err != nil {
+			if errors.Is(err, errServeStopped) {
+				return
+			}
+			fmt.Fprintf(sess.log, "orchestra: restarting the orchestrator failed: %v\n", err)
+			time.Sleep(5 * time.Second)
+			continue
+		}
+		sess.mu.Lock()
+		sess.writeDaemonState(startedAt)
+		sess.mu.Unlock()
+	}
+}
+
+// daemonBackend stands for the daemon's orchestrator in an attached
+// session. Its exited channel closes when that orchestrator process goes
+// away, so the bridge reconnects; stop leaves the process alone.
+func daemonBackend(st *daemonState) *serveBackend {
+	b := &serveBackend{addr: st.Addr, exited: make(chan struct{})}
+	go func() {
+		for processAlive(st.OrchestratorPID) {
+			time.Sleep(time.Second)
+		}
+		close(b.exited)
+	}()
+	return b
+}
+
+// reattachDaemon points an attached session at the daemon's current
+// orchestrator, once the daemon has restarted it, or at a daemon started
+// again in the workspace. The caller holds s.mu.
+func (s *serveSession) reattachDaemon() error {
+	st := runningDaemon(s.workspace)
+	if st == nil {
+		return fmt.Errorf("the serve daemon for %s is not running; start it with 'orchestra serve --daemon'", s.workspace)
+	}
+	if st.OrchestratorPID == s.daemon.OrchestratorPID || !processAlive(st.OrchestratorPID) {
+		return fmt.Errorf("waiting for serve daemon %d to restart its orchestrator", st.PID)
+	}
+	s.daemon = st
+	s.backend = daemonBackend(st)
+	writeServeRecord(s.record(""))
+	fmt.Fprintf(s.log, "orchestra: attached to serve daemon %d on %s\n", st.PID, st.Addr)
+	return nil
+}
+
+// RunStop handles `orchestra stop` -- stops the serve daemon running for
+// the workspace.
+func RunStop(args []string) {
+	fs := newFlagSet("stop")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	timeout := fs.Duration("timeout", 10*time.Second, "How long to wait for the daemon to exit before killing it")
+	parseFlags(fs, args)
+
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	st := runningDaemon(absWorkspace)
+	if st == nil {
+		printStatus(tagSkip, "no serve daemon runs for %s", absWorkspace)
+		return
+	}
+	var attached int
+	for _, rec := range liveServeRecords() {
+		if rec.AttachedTo == st.PID {
+			attached++
+		}
+	}
+
+	p, _ := os.FindProcess(st.PID)
+	if err := signalStop(p); err != nil {
+		p.Kill()
+	}
+	deadline := time.Now().Add(*timeout)
+	for processAlive(st.PID) && time.Now().Before(deadline) {
+		time.Sleep(100 * time.Millisecond)
+	}
+	if processAlive(st.PID) {
+		p.Kill()
+		printStatus(tagWarn, "serve daemon %d did not exit in %s; killed it", st.PID, *timeout)
+	}
+	// A daemon that was killed could not clean up after itself.
+	if st.OrchestratorPID != 0 {
+		stopOrphanedBackend(st.OrchestratorPID)
+	}
+	os.Remove(daemonStatePath(absWorkspace))
+	removeServeRecord(st.PID)
+	printStatus(tagOK, "stopped serve daemon %d for %s", st.PID, absWorkspace)
+	if attached > 0 {
+		printStatus(tagWarn, "%d IDE session(s) were attached; they reconnect if a daemon starts here within %s", attached, reconnectTimeout)
+	}
+}
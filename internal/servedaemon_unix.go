@@ -0,0 +1,11 @@
+//go:build !windows
+
+package internal
+
+import "syscall"
+
+// daemonSysProcAttr detaches the daemon from the terminal: in a session of
+// its own, it gets no SIGHUP or SIGINT meant for the shell that started it.
+func daemonSysProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
@@ -0,0 +1,14 @@
+//go:build windows
+
+package internal
+
+import "syscall"
+
+// detachedProcess is DETACHED_PROCESS, which syscall does not define.
+const detachedProcess = 0x00000008
+
+// daemonSysProcAttr detaches the daemon from the console, so it outlives
+// the window that started it and gets none of its Ctrl+C events.
+func daemonSysProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{CreationFlags: detachedProcess | syscall.CREATE_NEW_PROCESS_GROUP}
+}
@@ -0,0 +1,643 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// serve --transport=http exposes the MCP server over HTTP for web-based
+// agents and remote IDEs, instead of on serve's stdin and stdout. Each
+// client session gets a stdioBridge of its own, and with it the same
+// reconnects and tool timeouts an IDE on stdio has. Two protocols are
+// served:
+//
+//	/mcp               streamable HTTP: POST messages, GET a stream, DELETE to end
+//	/sse, /messages    HTTP with SSE: GET /sse for the stream, POST to the
+//	                   endpoint it names
+//
+// Every request needs the token, as "Authorization: Bearer <token>" or, for
+// clients that cannot set headers, ?token=<token>.
+
+const (
+	// defaultHTTPListen is where the MCP endpoint listens without --listen.
+	defaultHTTPListen = "localhost:8765"
+	// httpSessionIdle ends streamable HTTP sessions nobody has used for
+	// this long; clients that go away without a DELETE would leak them.
+	httpSessionIdle = 30 * time.Minute
+	// maxHTTPMessage bounds a POSTed message body.
+	maxHTTPMessage = 16 << 20
+	// httpStreamBuffer is how many server messages a session holds for a
+	// stream that is not being read.
+	httpStreamBuffer = 256
+)
+
+// httpOptions configure serve --transport=http.
+type httpOptions struct {
+	token   string
+	tls     bool // a certificate from the local CA in the certs dir
+	tlsCert string
+	tlsKey  string
+}
+
+// httpTokenPath is where serve keeps the token it generates.
+func httpTokenPath(certsDir string) string {
+	return filepath.Join(certsDir, "http.token")
+}
+
+// loadHTTPToken returns the token saved in certsDir, generating and saving
+// one on first use.
+func loadHTTPToken(certsDir string) (string, error) {
+	path := httpTokenPath(certsDir)
+	if data, err := os.ReadFile(path); err == nil {
+		if token := strings.TrimSpace(string(data)); token != "" {
+			return token, nil
+		}
+	}
+	token := randomHex(32)
+	if err := os.MkdirAll(certsDir, 0700); err != nil {
+		return "", err
+	}
+	if err := writeFileAtomic(path, []byte(token+"\n"), 0600); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func randomHex(n int) string {
+	buf := make([]byte, n)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// httpTLSConfig returns the TLS config for opts, or nil for plain HTTP.
+// --tls issues a certificate for host from the local CA `orchestra setup`
+// keeps in certsDir, so clients that trust ca.crt trust the endpoint.
+func httpTLSConfig(opts httpOptions, certsDir, host string) (*tls.Config, error) {
+	var cert tls.Certificate
+	var err error
+	switch {
+	case opts.tlsCert != "" || opts.tlsKey != "":
+		if opts.tlsCert == "" || opts.tlsKey == "" {
+			return nil, fmt.Errorf("--tls-cert and --tls-key go together")
+		}
+		cert, err = tls.LoadX509KeyPair(expandHome(opts.tlsCert), expandHome(opts.tlsKey))
+	case opts.tls:
+		cert, err = issueHTTPCert(certsDir, host)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}, nil
+}
+
+// issueHTTPCert signs a certificate for host with certsDir's local CA.
+// Loopback and wildcard hosts also get localhost and the loopback IPs.
+func issueHTTPCert(certsDir, host string) (tls.Certificate, error) {
+	caCertPEM, err := os.ReadFile(filepath.Join(certsDir, "ca.crt"))
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("--tls needs the local CA in %s; run 'orchestra setup', or pass --tls-cert and --tls-key", certsDir)
+	}
+	caKeyPEM, err := os.ReadFile(filepath.Join(certsDir, "ca.key"))
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("--tls needs the local CA in %s; run 'orchestra setup', or pass --tls-cert and --tls-key", certsDir)
+	}
+	ca, err := tls.X509KeyPair(caCertPEM, caKeyPEM)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("local CA in %s: %w", certsDir, err)
+	}
+	caCert, err := x509.ParseCertificate(ca.Certificate[0])
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("local CA in %s: %w", certsDir, err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "orchestra serve"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.AddDate(0, 0, 30),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	hosts := []string{host}
+	if ip := net.ParseIP(host); host == "" || host == "localhost" || ip != nil && (ip.IsLoopback() || ip.IsUnspecified()) {
+		hosts = append(hosts, "localhost", "127.0.0.1", "::1")
+		if name, err := os.Hostname(); err == nil && host != "localhost" && (ip == nil || ip.IsUnspecified()) {
+			hosts = append(hosts, name)
+		}
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			if !ip.IsUnspecified() {
+				tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+			}
+		} else if h != "" {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, caCert, &key.PublicKey, ca.PrivateKey)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.X509KeyPair(
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+}
+
+// --- sessions ---
+
+// httpServer serves MCP over HTTP for a serve session.
+type httpServer struct {
+	sess  *serveSession
+	token string
+
+	mu       sync.Mutex
+	sessions map[string]*httpSession
+}
+
+// httpSession is one client's MCP session: a bridge to a transport-stdio
+// of its own, fed what the client POSTs.
+type httpSession struct {
+	id   string
+	in   *io.PipeWriter // read by the bridge
+	log  io.Writer
+	done chan struct{}
+	once sync.Once
+
+	// Server messages that do not answer a waiting POST: requests and
+	// notifications from the server, and responses whose POST went away.
+	stream chan []byte
+
+	mu       sync.Mutex
+	waiting  map[string]chan []byte // streamable HTTP: request ID -> its POST
+	lastUsed time.Time
+	streams  int // open GET streams
+}
+
+// open starts a session and its bridge. The session ends when the client
+// ends it or the bridge gives up.
+func (h *httpServer) open() *httpSession {
+	r, w := io.Pipe()
+	s := &httpSession{
+		id:       randomHex(16),
+		in:       w,
+		log:      h.sess.log,
+		done:     make(chan struct{}),
+		stream:   make(chan []byte, httpStreamBuffer),
+		waiting:  map[string]chan []byte{},
+		lastUsed: time.Now(),
+	}
+	h.mu.Lock()
+	h.sessions[s.id] = s
+	h.mu.Unlock()
+	fmt.Fprintf(h.sess.log, "orchestra: HTTP session %s opened\n", s.id)
+
+	go func() {
+		if _, err := newStdioBridge(h.sess, s).run(r); err != nil {
+			fmt.Fprintf(h.sess.log, "orchestra: HTTP session %s: %v\n", s.id, err)
+		}
+		s.close()
+		h.mu.Lock()
+		delete(h.sessions, s.id)
+		h.mu.Unlock()
+		fmt.Fprintf(h.sess.log, "orchestra: HTTP session %s closed\n", s.id)
+	}()
+	return s
+}
+
+func (h *httpServer) lookup(id string) *httpSession {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.sessions[id]
+}
+
+// expireIdle closes streamable HTTP sessions that are not streaming and
+// have not been used in httpSessionIdle.
+func (h *httpServer) expireIdle(stop <-chan struct{}) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		h.mu.Lock()
+		var idle []*httpSession
+		for _, s := range h.sessions {
+			s.mu.Lock()
+			if s.streams == 0 && time.Since(s.lastUsed) > httpSessionIdle {
+				idle = append(idle, s)
+			}
+			s.mu.Unlock()
+		}
+		h.mu.Unlock()
+		for _, s := range idle {
+			fmt.Fprintf(h.sess.log, "orchestra: HTTP session %s idle for %s; closing it\n", s.id, httpSessionIdle)
+			s.close()
+		}
+	}
+}
+
+// close ends the session: the bridge sees the client go away.
+func (s *httpSession) close() {
+	s.once.Do(func() {
+		close(s.done)
+		s.in.Close()
+	})
+}
+
+// send passes a client message to the bridge.
+func (s *httpSession) send(msg []byte) error {
+	s.mu.Lock()
+	s.lastUsed = time.Now()
+	s.mu.Unlock()
+	_, err := s.in.Write(append(msg, '\n'))
+	return err
+}
+
+// Write takes the bridge's output, one message per call, and routes it to
+// the POST waiting for it or to the stream.
+func (s *httpSession) Write(line []byte) (int, error) {
+	msg := bytes.TrimSpace(append([]byte(nil), line...))
+	if id, method := parseBridgeMessage(msg); id != "" && method == "" {
+		s.mu.Lock()
+		ch := s.waiting[id]
+		delete(s.waiting, id)
+		s.mu.Unlock()
+		if ch != nil {
+			ch <- msg
+			return len(line), nil
+		}
+	}
+	select {
+	case s.stream <- msg:
+	case <-s.done:
+	default:
+		fmt.Fprintf(s.log, "orchestra: warning: HTTP session %s: no client reads its stream; dropped a message\n", s.id)
+	}
+	return len(line), nil
+}
+
+// wait registers the POST that waits for the response to request id.
+func (s *httpSession) wait(id string) chan []byte {
+	ch := make(chan []byte, 1)
+	s.mu.Lock()
+	s.waiting[id] = ch
+	s.mu.Unlock()
+	return ch
+}
+
+// unwait hands request id's response to the stream, for a POST that went
+// away before it came.
+func (s *httpSession) unwait(id string) {
+	s.mu.Lock()
+	delete(s.waiting, id)
+	s.mu.Unlock()
+}
+
+// streamEvents writes the session's stream to w as server-sent events
+// until the client disconnects or the session ends. endpoint, if set, is
+// sent first, for the HTTP with SSE protocol.
+func (s *httpSession) streamEvents(w http.ResponseWriter, r *http.Request, endpoint string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	s.mu.Lock()
+	s.streams++
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.streams--
+		s.lastUsed = time.Now()
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Mcp-Session-Id", s.id)
+	w.WriteHeader(http.StatusOK)
+	if endpoint != "" {
+		fmt.Fprintf(w, "event: endpoint\ndata: %s\n\n", endpoint)
+	}
+	flusher.Flush()
+
+	keepalive := time.NewTicker(30 * time.Second)
+	defer keepalive.Stop()
+	for {
+		select {
+		case msg := <-s.stream:
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", msg)
+		case <-keepalive.C:
+			io.WriteString(w, ": keepalive\n\n")
+		case <-r.Context().Done():
+			return
+		case <-s.done:
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// --- handlers ---
+
+// handler routes the endpoints behind the token check.
+func (h *httpServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mcp", h.handleMCP)
+	mux.HandleFunc("/sse", h.handleSSE)
+	mux.HandleFunc("/messages", h.handleMessages)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !h.authorized(r) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="orchestra"`)
+			http.Error(w, "missing or wrong token", http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func (h *httpServer) authorized(r *http.Request) bool {
+	given := r.URL.Query().Get("token")
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		given = strings.TrimPrefix(auth, "Bearer ")
+	}
+	return given != "" && subtle.ConstantTimeCompare([]byte(given), []byte(h.token)) == 1
+}
+
+// handleMCP serves streamable HTTP.
+func (h *httpServer) handleMCP(w http.ResponseWriter, r *http.Request) {
+	id := r.Header.Get("Mcp-Session-Id")
+	switch r.Method {
+	case http.MethodPost:
+		h.postMCP(w, r, id)
+	case http.MethodGet:
+		s := h.lookup(id)
+		if s == nil {
+			http.Error(w, "unknown session", http.StatusNotFound)
+			return
+		}
+		s.streamEvents(w, r, "")
+	case http.MethodDelete:
+		s := h.lookup(id)
+		if s == nil {
+			http.Error(w, "unknown session", http.StatusNotFound)
+			return
+		}
+		s.close()
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// postMCP sends the POSTed messages and answers with the responses to the
+// requests among them, as one JSON object, or an array for a batch.
+func (h *httpServer) postMCP(w http.ResponseWriter, r *http.Request, id string) {
+	msgs, batch, err := readHTTPMessages(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var s *httpSession
+	switch {
+	case id != "":
+		if s = h.lookup(id); s == nil {
+			http.Error(w, "unknown session", http.StatusNotFound)
+			return
+		}
+	case hasInitialize(msgs):
+		s = h.open()
+	default:
+		http.Error(w, "missing Mcp-Session-Id; start with initialize", http.StatusBadRequest)
+		return
+	}
+
+	type request struct {
+		id string
+		ch chan []byte
+	}
+	var requests []request
+	for _, msg := range msgs {
+		if id, method := parseBridgeMessage(msg); id != "" && method != "" {
+			requests = append(requests, request{id, s.wait(id)})
+		}
+	}
+	for _, msg := range msgs {
+		if err := s.send(msg); err != nil {
+			http.Error(w, "session closed", http.StatusNotFound)
+			return
+		}
+	}
+	w.Header().Set("Mcp-Session-Id", s.id)
+	if len(requests) == 0 {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	var responses []json.RawMessage
+	for _, req := range requests {
+		select {
+		case resp := <-req.ch:
+			responses = append(responses, resp)
+		case <-r.Context().Done():
+			for _, req := range requests {
+				s.unwait(req.id)
+			}
+			return
+		case <-s.done:
+			http.Error(w, "session closed", http.StatusNotFound)
+			return
+		}
+	}
+	var body []byte
+	if batch {
+		body, _ = json.Marshal(responses)
+	} else {
+		body = responses[0]
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(body, '\n'))
+}
+
+// handleSSE opens a session for the HTTP with SSE protocol and streams it.
+func (h *httpServer) handleSSE(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s := h.open()
+	defer s.close()
+	endpoint := "/messages?sessionId=" + s.id
+	if token := r.URL.Query().Get("token"); token != "" {
+		endpoint += "&token=" + token
+	}
+	s.streamEvents(w, r, endpoint)
+}
+
+// handleMessages takes the messages of an HTTP with SSE session; the
+// responses go out on its stream.
+func (h *httpServer) handleMessages(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s := h.lookup(r.URL.Query().Get("sessionId"))
+	if s == nil {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return
+	}
+	msgs, _, err := readHTTPMessages(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, msg := range msgs {
+		if err := s.send(msg); err != nil {
+			http.Error(w, "session closed", http.StatusNotFound)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// readHTTPMessages reads a POSTed JSON-RPC message or batch, each message
+// compacted onto one line for the bridge.
+func readHTTPMessages(r *http.Request) (msgs [][]byte, batch bool, err error) {
+	body, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, maxHTTPMessage))
+	if err != nil {
+		return nil, false, err
+	}
+	body = bytes.TrimSpace(body)
+	var raw []json.RawMessage
+	if batch = bytes.HasPrefix(body, []byte("[")); batch {
+		if err := json.Unmarshal(body, &raw); err != nil {
+			return nil, false, fmt.Errorf("not a JSON-RPC batch: %v", err)
+		}
+	} else {
+		raw = []json.RawMessage{body}
+	}
+	for _, m := range raw {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, m); err != nil || !bytes.HasPrefix(buf.Bytes(), []byte("{")) {
+			return nil, false, errors.New("not a JSON-RPC message")
+		}
+		msgs = append(msgs, buf.Bytes())
+	}
+	if len(msgs) == 0 {
+		return nil, false, errors.New("empty batch")
+	}
+	return msgs, batch, nil
+}
+
+func hasInitialize(msgs [][]byte) bool {
+	for _, msg := range msgs {
+		if _, method := parseBridgeMessage(msg); method == "initialize" {
+			return true
+		}
+	}
+	return false
+}
+
+// --- endpoint ---
+
+// httpEndpoint is the listener serve --transport=http serves on.
+type httpEndpoint struct {
+	ln    net.Listener
+	tls   *tls.Config
+	token string
+}
+
+// listenHTTP checks the --listen address, the TLS options, and the token,
+// and listens, so mistakes stop serve before the backend starts.
+func listenHTTP(spec string, opts httpOptions, certsDir string, others []listenFlag) (*httpEndpoint, error) {
+	addr, err := resolveListenAddr(spec, others)
+	if err != nil {
+		return nil, err
+	}
+	host, _, _ := net.SplitHostPort(addr)
+	e := &httpEndpoint{token: opts.token}
+	if e.tls, err = httpTLSConfig(opts, certsDir, host); err != nil {
+		return nil, err
+	}
+	if e.token == "" {
+		if e.token, err = loadHTTPToken(certsDir); err != nil {
+			return nil, fmt.Errorf("token: %w", err)
+		}
+	}
+	if e.ln, err = net.Listen("tcp", addr); err != nil {
+		return nil, fmt.Errorf("--listen: %v", listenError(addr, err))
+	}
+	return e, nil
+}
+
+// url is the base URL clients connect to.
+func (e *httpEndpoint) url() string {
+	scheme := "http"
+	if e.tls != nil {
+		scheme = "https"
+	}
+	host, port, _ := net.SplitHostPort(e.ln.Addr().String())
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		host = "localhost"
+	}
+	return scheme + "://" + net.JoinHostPort(host, port)
+}
+
+// serve serves MCP over HTTP until serve shuts down.
+func (e *httpEndpoint) serve(sess *serveSession) error {
+	h := &httpServer{sess: sess, token: e.token, sessions: map[string]*httpSession{}}
+	stop := make(chan struct{})
+	defer close(stop)
+	go h.expireIdle(stop)
+
+	ln := e.ln
+	if e.tls != nil {
+		ln = tls.NewListener(ln, e.tls)
+	}
+	srv := &http.Server{Handler: h.handler(), ReadHeaderTimeout: 10 * time.Second}
+	err := srv.Serve(ln)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,115 @@
+package internal
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// defaultListenAddr lets the orchestrator pick a free port on localhost.
+const defaultListenAddr = "localhost:0"
+
+// listenFlag is an address the serve process itself listens on, checked
+// against --listen.
+type listenFlag struct {
+	name, addr string
+}
+
+// resolveListenAddr turns a --listen value into the address the
+// orchestrator listens on. The value is host:port, :port (on localhost),
+// or host:low-high for the first free port in a range. A fixed port must
+// be free and not taken by another of serve's flags; port 0 is left to
+// the orchestrator.
+func resolveListenAddr(spec string, others []listenFlag) (string, error) {
+	host, ports, err := net.SplitHostPort(pprofListenAddr(spec))
+	if err != nil {
+		return "", fmt.Errorf("--listen=%s: want host:port, :port, or host:low-high", spec)
+	}
+	low, high, err := parsePortRange(ports)
+	if err != nil {
+		return "", fmt.Errorf("--listen=%s: %v", spec, err)
+	}
+	if low == 0 {
+		return net.JoinHostPort(host, "0"), nil
+	}
+
+	var lastErr error
+	for port := low; port <= high; port++ {
+		addr := net.JoinHostPort(host, strconv.Itoa(port))
+		if err := listenConflict(addr, others); err != nil {
+			lastErr = err
+			continue
+		}
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			lastErr = fmt.Errorf("%s: %v", addr, listenError(addr, err))
+			continue
+		}
+		l.Close()
+		return addr, nil
+	}
+	if low == high {
+		return "", fmt.Errorf("--listen: %v", lastErr)
+	}
+	return "", fmt.Errorf("--listen: no free port in %s (last tried %v)", spec, lastErr)
+}
+
+// parsePortRange parses "7700" or "7700-7799".
+func parsePortRange(s string) (low, high int, err error) {
+	lowStr, highStr, isRange := strings.Cut(s, "-")
+	if low, err = strconv.Atoi(lowStr); err != nil || low < 0 || low > 65535 {
+		return 0, 0, fmt.Errorf("bad port %q", lowStr)
+	}
+	if !isRange {
+		return low, low, nil
+	}
+	if high, err = strconv.Atoi(highStr); err != nil || high < 1 || high > 65535 {
+		return 0, 0, fmt.Errorf("bad port %q", highStr)
+	}
+	if low == 0 || low > high {
+		return 0, 0, fmt.Errorf("bad port range %s", s)
+	}
+	return low, high, nil
+}
+
+// listenConflict reports another serve flag that listens on addr.
+func listenConflict(addr string, others []listenFlag) error {
+	for _, o := range others {
+		if o.addr != "" && samePort(addr, pprofListenAddr(o.addr)) {
+			return fmt.Errorf("%s is also given to --%s", addr, o.name)
+		}
+	}
+	return nil
+}
+
+// samePort reports whether two host:port addresses share a port on hosts
+// that can clash: the same host, both loopback, or either one bound to all
+// interfaces.
+func samePort(a, b string) bool {
+	ha, pa, errA := net.SplitHostPort(a)
+	hb, pb, errB := net.SplitHostPort(b)
+	if errA != nil || errB != nil || pa != pb || pa == "0" {
+		return false
+	}
+	wildcard := func(h string) bool { return h == "" || h == "0.0.0.0" || h == "::" }
+	loopback := func(h string) bool {
+		ip := net.ParseIP(h)
+		return h == "localhost" || ip != nil && ip.IsLoopback()
+	}
+	return ha == hb || wildcard(ha) || wildcard(hb) || loopback(ha) && loopback(hb)
+}
+
+// listenError explains a failed listen, naming the serve session that holds
+// addr when it is one.
+func listenError(addr string, err error) error {
+	for _, rec := range liveServeRecords() {
+		if samePort(addr, rec.Addr) {
+			return fmt.Errorf("in use by orchestra serve %d for %s", rec.PID, rec.Workspace)
+		}
+	}
+	if opErr, ok := err.(*net.OpError); ok && opErr.Err != nil {
+		return opErr.Err
+	}
+	return err
+}
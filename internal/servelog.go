@@ -0,0 +1,282 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Structured serve logs. serve writes its log as JSON lines, one logRecord
+// per line: its own messages, and every line the orchestrator, its
+// plugins, and transport-stdio print, stamped with the time and source. The
+// level and plugin of a line are taken from the line itself: a JSON line
+// keeps its own fields, and a plain one is classified by its wording. A
+// plugin's own lines go to its file instead; see pluginlogs.go.
+// `orchestra logs` reads the records back.
+
+// logRecord is one line of the serve log.
+type logRecord struct {
+	Time   string         `json:"time"` // RFC 3339, millisecond precision
+	Level  string         `json:"level"`
+	Source string         `json:"source"` // orchestra, orchestrator, or transport
+	Plugin string         `json:"plugin,omitempty"`
+	Msg    string         `json:"msg"`
+	Attrs  map[string]any `json:"attrs,omitempty"` // other fields of a JSON line
+
+	own bool // the plugin printed the line itself: a [plugin.id] prefix or a JSON plugin field
+}
+
+// Log sources.
+const (
+	logSourceOrchestra    = "orchestra"
+	logSourceOrchestrator = "orchestrator"
+	logSourceTransport    = "transport"
+)
+
+// Log levels, least severe first.
+var logLevels = []string{"debug", "info", "warn", "error"}
+
+const logTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// serveLog writes records to the serve log file, and with plugins, the
+// plugins' own records to their files. Writing to it directly logs serve's own
+// messages; source and pipe give child processes a writer of their own.
+type serveLog struct {
+	mu      sync.Mutex
+	f       *os.File
+	self    *logLineWriter
+	plugins *pluginLogs
+}
+
+func newServeLog(f *os.File) *serveLog {
+	l := &serveLog{f: f}
+	l.self = &logLineWriter{log: l, source: logSourceOrchestra}
+	return l
+}
+
+// Write logs serve's own messages, one record per line. The "orchestra: "
+// prefix the messages carry for plain-text logs is dropped.
+func (l *serveLog) Write(p []byte) (int, error) {
+	return l.self.Write(p)
+}
+
+// source returns a writer that logs each line written to it as coming from
+// source.
+func (l *serveLog) source(source string) io.Writer {
+	return &logLineWriter{log: l, source: source}
+}
+
+// pipe returns a file for a child process's output, logged as coming from
+// source. The caller closes it once the child has started. Unlike an
+// io.Writer, which exec.Cmd.Wait drains, a pipe does not keep Wait waiting
+// for grandchildren (the orchestrator's plugins) that hold it open.
+func (l *serveLog) pipe(source string) (*os.File, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		lw := &logLineWriter{log: l, source: source}
+		io.Copy(lw, r)
+		lw.flush()
+		r.Close()
+	}()
+	return w, nil
+}
+
+func (l *serveLog) write(rec logRecord) {
+	data, _ := json.Marshal(rec)
+	if l.plugins != nil && rec.Plugin != "" {
+		if logLevelRank(rec.Level) < l.plugins.levels.min(rec.Plugin) {
+			return
+		}
+		if rec.own && l.plugins.write(rec.Plugin, data) {
+			return
+		}
+	}
+	l.mu.Lock()
+	l.f.Write(append(data, '\n'))
+	l.mu.Unlock()
+}
+
+// logLineWriter turns the lines written to it into records.
+type logLineWriter struct {
+	log    *serveLog
+	source string
+	mu     sync.Mutex
+	buf    []byte
+}
+
+func (w *logLineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.emit(string(w.buf[:i]))
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// flush logs a final line that did not end in a newline.
+func (w *logLineWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) > 0 {
+		w.emit(string(w.buf))
+		w.buf = nil
+	}
+}
+
+func (w *logLineWriter) emit(line string) {
+	line = strings.TrimRight(line, "\r")
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	if w.source == logSourceOrchestra {
+		line = strings.TrimPrefix(line, "orchestra: ")
+	}
+	w.log.write(parseLogLine(line, w.source, time.Now()))
+}
+
+var (
+	// goLogPrefix is the timestamp the standard log package writes.
+	goLogPrefix = regexp.MustCompile(`^\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}(\.\d+)? `)
+	// logPluginID finds the plugin a plain line is about: a "[plugin.id]"
+	// prefix, or "plugin <id>" / plugin=<id> in the text.
+	logPluginID = regexp.MustCompile(`^\[([A-Za-z0-9_.-]+)\]\s*|\bplugin[ =:]+"?([A-Za-z0-9_.-]*[A-Za-z0-9_-])`)
+)
+
+// parseLogLine makes a record of one line of output from source.
+func parseLogLine(line, source string, now time.Time) logRecord {
+	rec := logRecord{Time: now.UTC().Format(logTimeFormat), Source: source}
+	if strings.HasPrefix(line, "{") {
+		var fields map[string]any
+		if json.Unmarshal([]byte(line), &fields) == nil {
+			rec.Msg = takeLogField(fields, "msg", "message")
+			rec.Level = normalizeLogLevel(takeLogField(fields, "level", "lvl", "severity"))
+			rec.Plugin = takeLogField(fields, "plugin", "plugin_id")
+			rec.own = rec.Plugin != ""
+			takeLogField(fields, "time", "ts")
+			if len(fields) > 0 {
+				rec.Attrs = fields
+			}
+			if rec.Level == "" {
+				rec.Level = inferLogLevel(rec.Msg)
+			}
+			return rec
+		}
+	}
+	line = goLogPrefix.ReplaceAllString(line, "")
+	if m := logPluginID.FindStringSubmatch(line); m != nil && normalizeLogLevel(m[1]) == "" {
+		rec.Plugin = m[1] + m[2]
+		if m[1] != "" {
+			line = line[len(m[0]):]
+			rec.own = true
+		}
+	}
+	rec.Msg = line
+	rec.Level = inferLogLevel(line)
+	return rec
+}
+
+// takeLogField removes the first of keys present in fields and returns it
+// as a string.
+func takeLogField(fields map[string]any, keys ...string) string {
+	for _, k := range keys {
+		if v, ok := fields[k]; ok {
+			delete(fields, k)
+			if s, ok := v.(string); ok {
+				return s
+			}
+			data, _ := json.Marshal(v)
+			return string(data)
+		}
+	}
+	return ""
+}
+
+// normalizeLogLevel maps level names loggers use onto logLevels; it
+// returns "" for anything else.
+func normalizeLogLevel(level string) string {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug", "trace":
+		return "debug"
+	case "info", "notice":
+		return "info"
+	case "warn", "warning":
+		return "warn"
+	case "error", "err", "fatal", "panic", "critical":
+		return "error"
+	}
+	return ""
+}
+
+// inferLogLevel classifies a plain line by its wording.
+func inferLogLevel(msg string) string {
+	lower := strings.ToLower(msg)
+	has := func(words ...string) bool {
+		for _, w := range words {
+			if strings.Contains(lower, w) {
+				return true
+			}
+		}
+		return false
+	}
+	switch {
+	case has("panic", "fatal", "error", "failed", "failure"):
+		return "error"
+	case has("warn", "skipping", "ignoring", "cancelled", "exited", "timed out", "not found"):
+		return "warn"
+	case strings.HasPrefix(lower, "debug") || has("level=debug", "[debug]"):
+		return "debug"
+	}
+	return "info"
+}
+
+// logLevelRank orders levels; unknown levels rank as info.
+func logLevelRank(level string) int {
+	for i, l := range logLevels {
+		if l == level {
+			return i
+		}
+	}
+	return 1
+}
+
+// readLogRecord parses one line of a serve log. Lines of logs written
+// before serve logged JSON become records without a source.
+func readLogRecord(line string) logRecord {
+	var rec logRecord
+	if strings.HasPrefix(line, "{") && json.Unmarshal([]byte(line), &rec) == nil && rec.Time != "" {
+		return rec
+	}
+	return logRecord{Level: inferLogLevel(line), Msg: line}
+}
+
+// readLogFrom returns the messages logged to path after offset, one per
+// line.
+func readLogFrom(path string, offset int64) string {
+	data, _ := os.ReadFile(path)
+	if offset > int64(len(data)) {
+		offset = 0
+	}
+	var b strings.Builder
+	sc := bufio.NewScanner(bytes.NewReader(data[offset:]))
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		b.WriteString(readLogRecord(sc.Text()).Msg)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
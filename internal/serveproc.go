@@ -0,0 +1,22 @@
+package internal
+
+import (
+	"os"
+	"syscall"
+)
+
+// Process management for serve. The orchestrator starts one process per
+// plugin, and all of them have to go when serve stops the backend. Each
+// platform keeps the orchestrator and its plugins together as a
+// processTree: a process group on Unix (serveproc_unix.go), a job object on
+// Windows (serveproc_windows.go). The same files decide which signals serve
+// handles: Windows has no SIGHUP, so `serve switch` is Unix-only there.
+
+// shutdownSignals end serve cleanly on every platform. On Windows, Go
+// delivers closing the console window as SIGTERM.
+var shutdownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// serveSignals are the signals serve handles.
+func serveSignals() []os.Signal {
+	return append(append([]os.Signal(nil), shutdownSignals...), reloadSignals...)
+}
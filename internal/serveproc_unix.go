@@ -0,0 +1,104 @@
+//go:build !windows
+
+package internal
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// processTree is a process and the processes it starts: a process group
+// that the process leads.
+type processTree struct {
+	pgid int
+}
+
+// newProcessTree makes cmd, which has not started yet, lead a process group
+// of its own. Its plugins inherit the group, so they are signalled with it.
+func newProcessTree(cmd *exec.Cmd) *processTree {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+	return &processTree{}
+}
+
+// started records the group once the process has started.
+func (t *processTree) started(pid int) error {
+	t.pgid = pid
+	return nil
+}
+
+// terminate asks every process in the tree to exit.
+func (t *processTree) terminate() {
+	if t != nil && t.pgid > 0 {
+		syscall.Kill(-t.pgid, syscall.SIGTERM)
+	}
+}
+
+// kill ends every process in the tree that is still running.
+func (t *processTree) kill() {
+	if t != nil && t.pgid > 0 {
+		syscall.Kill(-t.pgid, syscall.SIGKILL)
+	}
+}
+
+// killStaleProcesses kills processes still running any of bins, left by
+// a serve that was killed before it could stop its backend.
+func killStaleProcesses(bins serveBins) {
+	killed := false
+	for _, bin := range bins {
+		// pkill exits 0 only when it matched a process.
+		if exec.Command("pkill", "-9", "-f", bin).Run() == nil {
+			killed = true
+		}
+	}
+	if killed {
+		time.Sleep(500 * time.Millisecond) // let the killed processes release their ports
+	}
+}
+
+// stopOrphanedBackend kills the process group the orchestrator pid led,
+// left running by a serve that was killed. The group's ID cannot be reused
+// while any of its processes run, so once the orchestrator has exited the
+// group holds only its plugins; while it runs, it must still be an
+// orchestrator, not an unrelated process given the same pid.
+func stopOrphanedBackend(pid int) {
+	if processAlive(pid) {
+		out, err := exec.Command("ps", "-o", "command=", "-p", strconv.Itoa(pid)).Output()
+		if err != nil || !strings.Contains(string(out), "orchestrator") {
+			return
+		}
+	}
+	syscall.Kill(-pid, syscall.SIGKILL)
+}
+
+// processAlive reports whether pid is a running process we may signal.
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return p.Signal(syscall.Signal(0)) == nil
+}
+
+// reloadSignals ask serve to pick up a `serve switch` request.
+var reloadSignals = []os.Signal{syscall.SIGHUP}
+
+func isReloadSignal(sig os.Signal) bool {
+	return sig == syscall.SIGHUP
+}
+
+// signalReload asks the serve process p to pick up a switch request.
+func signalReload(p *os.Process) error {
+	return p.Signal(syscall.SIGHUP)
+}
+
+// signalStop asks p to shut down cleanly.
+func signalStop(p *os.Process) error {
+	return p.Signal(syscall.SIGTERM)
+}
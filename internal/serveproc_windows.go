@@ -0,0 +1,155 @@
+//go:build windows
+
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"unsafe"
+)
+
+// Job objects are not in the syscall package; these are the kernel32 calls
+// and structures serve needs.
+var (
+	kernel32                     = syscall.NewLazyDLL("kernel32.dll")
+	procCreateJobObjectW         = kernel32.NewProc("CreateJobObjectW")
+	procSetInformationJobObject  = kernel32.NewProc("SetInformationJobObject")
+	procAssignProcessToJobObject = kernel32.NewProc("AssignProcessToJobObject")
+	procTerminateJobObject       = kernel32.NewProc("TerminateJobObject")
+)
+
+const (
+	jobObjectExtendedLimitInformationClass = 9
+	jobObjectLimitKillOnJobClose           = 0x2000
+
+	processTerminate               = 0x0001
+	processSetQuota                = 0x0100
+	processQueryLimitedInformation = 0x1000
+
+	stillActive = 259 // GetExitCodeProcess for a running process
+)
+
+type jobObjectBasicLimitInformation struct {
+	PerProcessUserTimeLimit int64
+	PerJobUserTimeLimit     int64
+	LimitFlags              uint32
+	MinimumWorkingSetSize   uintptr
+	MaximumWorkingSetSize   uintptr
+	ActiveProcessLimit      uint32
+	Affinity                uintptr
+	PriorityClass           uint32
+	SchedulingClass         uint32
+}
+
+type ioCounters struct {
+	ReadOperationCount  uint64
+	WriteOperationCount uint64
+	OtherOperationCount uint64
+	ReadTransferCount   uint64
+	WriteTransferCount  uint64
+	OtherTransferCount  uint64
+}
+
+type jobObjectExtendedLimitInformation struct {
+	BasicLimitInformation jobObjectBasicLimitInformation
+	IoInfo                ioCounters
+	ProcessMemoryLimit    uintptr
+	JobMemoryLimit        uintptr
+	PeakProcessMemoryUsed uintptr
+	PeakJobMemoryUsed     uintptr
+}
+
+// processTree is a process and the processes it starts: a job object that
+// kills everything in it when its last handle closes, so the tree goes
+// even when serve itself is killed.
+type processTree struct {
+	job syscall.Handle
+}
+
+func newProcessTree(cmd *exec.Cmd) *processTree {
+	return &processTree{}
+}
+
+// started puts the process in a new job. Processes it starts from then on
+// join the job too; the orchestrator starts its plugins only after reading
+// its config, well after this runs.
+func (t *processTree) started(pid int) error {
+	job, _, err := procCreateJobObjectW.Call(0, 0)
+	if job == 0 {
+		return fmt.Errorf("CreateJobObject: %w", err)
+	}
+	var info jobObjectExtendedLimitInformation
+	info.BasicLimitInformation.LimitFlags = jobObjectLimitKillOnJobClose
+	if r, _, err := procSetInformationJobObject.Call(job, jobObjectExtendedLimitInformationClass, uintptr(unsafe.Pointer(&info)), unsafe.Sizeof(info)); r == 0 {
+		syscall.CloseHandle(syscall.Handle(job))
+		return fmt.Errorf("SetInformationJobObject: %w", err)
+	}
+	h, err := syscall.OpenProcess(processSetQuota|processTerminate, false, uint32(pid))
+	if err != nil {
+		syscall.CloseHandle(syscall.Handle(job))
+		return fmt.Errorf("OpenProcess: %w", err)
+	}
+	defer syscall.CloseHandle(h)
+	if r, _, err := procAssignProcessToJobObject.Call(job, uintptr(h)); r == 0 {
+		syscall.CloseHandle(syscall.Handle(job))
+		return fmt.Errorf("AssignProcessToJobObject: %w", err)
+	}
+	t.job = syscall.Handle(job)
+	return nil
+}
+
+// terminate ends the tree. Processes without a console cannot be asked to
+// exit on Windows, so it is the same as kill.
+func (t *processTree) terminate() {
+	t.kill()
+}
+
+// kill ends every process in the tree.
+func (t *processTree) kill() {
+	if t != nil && t.job != 0 {
+		procTerminateJobObject.Call(uintptr(t.job), 1)
+		syscall.CloseHandle(t.job)
+		t.job = 0
+	}
+}
+
+// killStaleProcesses does nothing on Windows: a backend's job dies with
+// the serve that created it, so none are left behind.
+func killStaleProcesses(bins serveBins) {}
+
+// stopOrphanedBackend does nothing on Windows: the job object ended the
+// orchestrator and its plugins with the serve that was killed.
+func stopOrphanedBackend(pid int) {}
+
+// processAlive reports whether pid is a running process.
+func processAlive(pid int) bool {
+	h, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer syscall.CloseHandle(h)
+	var code uint32
+	if syscall.GetExitCodeProcess(h, &code) != nil {
+		return false
+	}
+	return code == stillActive
+}
+
+// reloadSignals is empty: Windows has no SIGHUP.
+var reloadSignals []os.Signal
+
+func isReloadSignal(sig os.Signal) bool {
+	return false
+}
+
+func signalReload(p *os.Process) error {
+	return errors.New("Windows has no SIGHUP to tell serve to switch; restart the IDE's MCP server in the other workspace instead")
+}
+
+// signalStop ends p. Its backend's job closes with it.
+func signalStop(p *os.Process) error {
+	return p.Kill()
+}
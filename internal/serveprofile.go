@@ -0,0 +1,149 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"path/filepath"
+	"runtime"
+	runtimepprof "runtime/pprof"
+	"strings"
+)
+
+// serve can profile itself on a user's machine, so slow startups and slow
+// tool calls can be diagnosed where they happen: --pprof serves the live
+// net/http/pprof endpoints, --cpuprofile records the whole session, and
+// --memprofile writes a heap profile when it ends. The orchestrator is a
+// separate binary; its settings are passed down in the environment as
+// ORCHESTRATOR_PPROF, ORCHESTRATOR_CPUPROFILE, and ORCHESTRATOR_MEMPROFILE,
+// which orchestrator builds with profiling support honor.
+
+// serveProfiling is serve's profiling setup.
+type serveProfiling struct {
+	pprofAddr  string
+	cpuProfile string
+	memProfile string
+
+	orchestratorPprof      string
+	orchestratorCPUProfile string
+	orchestratorMemProfile string
+
+	cpuFile  *os.File
+	listener net.Listener
+}
+
+// pprofListenAddr binds an address given as just ":port" to localhost:
+// the endpoints expose the process's memory, and serve never needs them
+// reachable from other machines.
+func pprofListenAddr(addr string) string {
+	if strings.HasPrefix(addr, ":") {
+		return "localhost" + addr
+	}
+	return addr
+}
+
+// absProfilePath resolves a profile file against the working directory, so
+// the path logged is the one written.
+func absProfilePath(path string) string {
+	if path == "" {
+		return ""
+	}
+	abs, err := filepath.Abs(expandHome(path))
+	if err != nil {
+		return path
+	}
+	return abs
+}
+
+// start begins CPU profiling and starts the pprof server, as requested.
+func (p *serveProfiling) start(log io.Writer) error {
+	p.cpuProfile = absProfilePath(p.cpuProfile)
+	p.memProfile = absProfilePath(p.memProfile)
+	p.orchestratorCPUProfile = absProfilePath(p.orchestratorCPUProfile)
+	p.orchestratorMemProfile = absProfilePath(p.orchestratorMemProfile)
+
+	if p.cpuProfile != "" {
+		f, err := os.Create(p.cpuProfile)
+		if err != nil {
+			return fmt.Errorf("--cpuprofile: %w", err)
+		}
+		if err := runtimepprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return fmt.Errorf("--cpuprofile: %w", err)
+		}
+		p.cpuFile = f
+		fmt.Fprintf(log, "orchestra: writing CPU profile to %s\n", p.cpuProfile)
+	}
+
+	if p.pprofAddr != "" {
+		l, err := net.Listen("tcp", pprofListenAddr(p.pprofAddr))
+		if err != nil {
+			p.stop(log)
+			return fmt.Errorf("--pprof: %w", err)
+		}
+		p.listener = l
+		// A mux of its own: importing net/http/pprof registers on the
+		// default one, which nothing else here should serve.
+		mux := http.NewServeMux()
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+		go http.Serve(l, mux)
+		fmt.Fprintf(log, "orchestra: pprof on http://%s/debug/pprof/\n", l.Addr())
+	}
+	return nil
+}
+
+// stop ends CPU profiling, writes the heap profile, and closes the pprof
+// server. It is safe to call more than once.
+func (p *serveProfiling) stop(log io.Writer) {
+	if p == nil {
+		return
+	}
+	if p.cpuFile != nil {
+		runtimepprof.StopCPUProfile()
+		p.cpuFile.Close()
+		p.cpuFile = nil
+	}
+	if p.memProfile != "" {
+		runtime.GC() // up-to-date statistics
+		if f, err := os.Create(p.memProfile); err != nil {
+			fmt.Fprintf(log, "orchestra: --memprofile: %v\n", err)
+		} else {
+			if err := runtimepprof.WriteHeapProfile(f); err != nil {
+				fmt.Fprintf(log, "orchestra: --memprofile: %v\n", err)
+			}
+			f.Close()
+			fmt.Fprintf(log, "orchestra: wrote heap profile to %s\n", p.memProfile)
+		}
+		p.memProfile = ""
+	}
+	if p.listener != nil {
+		p.listener.Close()
+		p.listener = nil
+	}
+}
+
+// orchestratorEnv is the environment that passes the orchestrator's
+// profiling settings down.
+func (p *serveProfiling) orchestratorEnv() []string {
+	if p == nil {
+		return nil
+	}
+	var env []string
+	for _, v := range []struct{ name, value string }{
+		{"ORCHESTRATOR_PPROF", pprofListenAddr(p.orchestratorPprof)},
+		{"ORCHESTRATOR_CPUPROFILE", p.orchestratorCPUProfile},
+		{"ORCHESTRATOR_MEMPROFILE", p.orchestratorMemProfile},
+	} {
+		if v.value != "" {
+			env = append(env, v.name+"="+v.value)
+		}
+	}
+	return env
+}
@@ -0,0 +1,134 @@
+package internal
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// serve --silent-startup keeps stderr empty for MCP clients that treat
+// any output there during the handshake as a failure. Everything serve
+// would print on stderr -- warnings, hook prompts, the error it exits
+// with -- is logged to the serve log instead, and a failed start shows
+// only in the log and the exit code.
+
+// stderrCapture is stderr while it is redirected to the serve log.
+type stderrCapture struct {
+	stderr *os.File // the real stderr
+	w      *os.File // what os.Stderr is meanwhile
+	done   chan struct{}
+
+	mu      sync.Mutex
+	log     *serveLog
+	logFile string      // where held lines go if serve exits before it opens the log
+	held    []logRecord // lines written before the log was open
+}
+
+var capturedStderr *stderrCapture
+
+// captureStderr redirects os.Stderr until releaseStderr. Lines written
+// before the serve log is attached are held, and logged to logFile if
+// serve exits first.
+func captureStderr(logFile string) error {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	c := &stderrCapture{stderr: os.Stderr, w: w, done: make(chan struct{}), logFile: logFile}
+	go func() {
+		sc := bufio.NewScanner(r)
+		sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for sc.Scan() {
+			c.line(sc.Text())
+		}
+		r.Close()
+		close(c.done)
+	}()
+	os.Stderr = w
+	capturedStderr = c
+	return nil
+}
+
+func (c *stderrCapture) line(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return
+	}
+	c.record(parseLogLine(strings.TrimPrefix(line, "orchestra: "), logSourceOrchestra, time.Now()))
+}
+
+// fail logs the error serve exits with.
+func (c *stderrCapture) fail(msg string) {
+	rec := parseLogLine(msg, logSourceOrchestra, time.Now())
+	rec.Level = "error"
+	c.record(rec)
+}
+
+func (c *stderrCapture) record(rec logRecord) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.log == nil {
+		c.held = append(c.held, rec)
+		return
+	}
+	c.log.write(rec)
+}
+
+// setLogFile changes where held lines go, once the log path is known.
+func (c *stderrCapture) setLogFile(path string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.logFile = path
+	c.mu.Unlock()
+}
+
+// attach logs the held lines and everything written after them to log.
+func (c *stderrCapture) attach(log *serveLog) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.log = log
+	for _, rec := range c.held {
+		log.write(rec)
+	}
+	c.held = nil
+}
+
+// releaseStderr restores os.Stderr once everything written to the capture
+// is logged. Lines still held go to the log file, or to stderr if it
+// cannot be opened: an error with nowhere else to go is not dropped.
+func releaseStderr() {
+	c := capturedStderr
+	if c == nil {
+		return
+	}
+	capturedStderr = nil
+	os.Stderr = c.stderr
+	c.w.Close()
+	<-c.done
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.held) == 0 {
+		return
+	}
+	if c.logFile != "" {
+		if f, err := os.OpenFile(c.logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644); err == nil {
+			log := newServeLog(f)
+			for _, rec := range c.held {
+				log.write(rec)
+			}
+			f.Close()
+			return
+		}
+	}
+	for _, rec := range c.held {
+		os.Stderr.WriteString("orchestra: " + rec.Msg + "\n")
+	}
+}
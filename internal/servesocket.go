@@ -0,0 +1,71 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// serve --unix-socket connects transport-stdio to the orchestrator over a
+// unix socket in ~/.orchestra/run/sockets/ instead of localhost TCP. The
+// directory is private to the user, so the connection needs no mTLS and
+// serve no certificates. Daemons keep TCP and mTLS: sessions of other
+// IDEs, and other tools, attach to them by address.
+
+// unixAddrPrefix marks an orchestrator address that is a socket path.
+const unixAddrPrefix = "unix:"
+
+// unixSocketsSupported reports whether serve offers --unix-socket here.
+func unixSocketsSupported() bool {
+	return runtime.GOOS != "windows"
+}
+
+func serveSocketDir() string {
+	return filepath.Join(serveRunDir(), "sockets")
+}
+
+// serveSocketAddr returns the orchestrator address for this serve's socket,
+// creating the directory it goes in. The path is kept short: sockets are
+// limited to about 100 bytes on macOS.
+func serveSocketAddr() (string, error) {
+	dir := serveSocketDir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("--unix-socket: %v", err)
+	}
+	// MkdirAll leaves an existing directory's mode alone.
+	if err := os.Chmod(dir, 0700); err != nil {
+		return "", fmt.Errorf("--unix-socket: %v", err)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("orchestrator-%d.sock", os.Getpid()))
+	if len(path) > 100 {
+		return "", fmt.Errorf("--unix-socket: socket path %s is too long; use TCP instead", path)
+	}
+	return unixAddrPrefix + path, nil
+}
+
+// unixSocketPath returns the socket path of an orchestrator address, and
+// whether it is one.
+func unixSocketPath(addr string) (string, bool) {
+	path, ok := strings.CutPrefix(addr, unixAddrPrefix)
+	return path, ok
+}
+
+// removeStaleSocket removes the socket file of addr, left by an
+// orchestrator that has exited, so the next one can listen on it.
+func removeStaleSocket(addr string) {
+	if path, ok := unixSocketPath(addr); ok {
+		os.Remove(path)
+	}
+}
+
+// transportArgs are the flags transport-stdio needs to reach the
+// orchestrator at addr: its certificates, unless addr is a socket.
+func transportArgs(addr, certsDir string) []string {
+	args := []string{fmt.Sprintf("--orchestrator-addr=%s", addr)}
+	if _, ok := unixSocketPath(addr); !ok {
+		args = append(args, fmt.Sprintf("--certs-dir=%s", certsDir))
+	}
+	return args
+}
@@ -0,0 +1,274 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Plugins crash, and the orchestrator does not start them again: the tools
+// of a plugin that died stay gone until serve restarts. So serve runs each
+// plugin under a supervisor, which is orchestra itself started by the
+// orchestrator in place of the plugin binary:
+//
+//	orchestrator -> orchestra --supervise-plugin=<id> --supervise-binary=<bin> <args>
+//	                  -> <bin> <args>
+//
+// The supervisor passes the plugin's stdio through. When the plugin exits
+// with an error or a signal, it starts it again after a backoff that
+// doubles from pluginRestartMinDelay up to pluginRestartMaxDelay, on the
+// address the plugin first listened on (--listen-addr), so the plugin
+// registers with the orchestrator again where it was. After
+// pluginMaxRestarts restarts in a row without the plugin staying up for
+// pluginStableAfter, it gives up. Each restart is logged as a warning in
+// the serve log and recorded in the restart history `orchestra status`
+// reads. A plugin that exits cleanly, or that is stopped along with the
+// backend, is not restarted.
+
+const (
+	// pluginSuperviseEnv holds the restart history file; it is set only in
+	// the orchestrator's environment, so no other orchestra run supervises.
+	pluginSuperviseEnv = "ORCHESTRA_SUPERVISE"
+
+	superviseIDFlag     = "--supervise-plugin="
+	superviseBinaryFlag = "--supervise-binary="
+
+	pluginRestartMinDelay = time.Second
+	pluginRestartMaxDelay = time.Minute
+	pluginStableAfter     = time.Minute
+	pluginMaxRestarts     = 5
+)
+
+// pluginRestart is one line of the restart history.
+type pluginRestart struct {
+	Time    time.Time `json:"time"`
+	Plugin  string    `json:"plugin"`
+	Reason  string    `json:"reason"` // how the plugin exited, e.g. "exit status 2"
+	Uptime  string    `json:"uptime"` // how long it had run
+	Attempt int       `json:"attempt"`
+	Delay   string    `json:"delay,omitempty"`   // wait before the restart
+	GaveUp  bool      `json:"gave_up,omitempty"` // no restart: too many in a row
+}
+
+// pluginRestartsPath is the restart history of workspace's serve. Like the
+// log, it starts empty with each serve.
+func pluginRestartsPath(workspace string) string {
+	return worktreeScoped(workspace, filepath.Join(workspace, ".orchestra-mcp.restarts.jsonl"))
+}
+
+// supervisePlugins rewrites plugins to run under the supervisor, with
+// history as their restart history and logFile as the serve log.
+func supervisePlugins(plugins []pluginConfig, history, logFile string) ([]pluginConfig, []string, error) {
+	self, err := os.Executable()
+	if err != nil {
+		return plugins, nil, fmt.Errorf("resolve self path: %w", err)
+	}
+	out := make([]pluginConfig, len(plugins))
+	for i, p := range plugins {
+		p.Args = append([]string{superviseIDFlag + p.ID, superviseBinaryFlag + p.Binary}, p.Args...)
+		p.Binary = self
+		out[i] = p
+	}
+	env := []string{pluginSuperviseEnv + "=" + history, "ORCHESTRA_LOG=" + logFile}
+	return out, env, nil
+}
+
+// superviseArgs finds the supervisor flags in args, wherever the
+// orchestrator put its own flags, and returns the rest for the plugin.
+func superviseArgs(args []string) (id, binary string, rest []string) {
+	for _, a := range args {
+		switch {
+		case strings.HasPrefix(a, superviseIDFlag):
+			id = strings.TrimPrefix(a, superviseIDFlag)
+		case strings.HasPrefix(a, superviseBinaryFlag):
+			binary = strings.TrimPrefix(a, superviseBinaryFlag)
+		default:
+			rest = append(rest, a)
+		}
+	}
+	return id, binary, rest
+}
+
+// runPluginSupervisor runs and restarts one plugin; see the top of the
+// file. It never returns.
+func runPluginSupervisor(id, binary string, args []string) {
+	history := os.Getenv(pluginSuperviseEnv)
+	logFile := os.Getenv("ORCHESTRA_LOG")
+	os.Unsetenv(pluginSuperviseEnv) // the plugin may run orchestra itself
+
+	var (
+		mu       sync.Mutex
+		current  *os.Process
+		stopping bool
+	)
+	stop := make(chan struct{})
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, shutdownSignals...)
+	go func() {
+		sig := <-sigCh
+		mu.Lock()
+		stopping = true
+		if current != nil {
+			current.Signal(sig)
+		}
+		mu.Unlock()
+		close(stop)
+	}()
+
+	var addr string
+	delay := pluginRestartMinDelay
+	restarts := 0
+	for {
+		cmd := exec.Command(binary, withListenAddr(args, addr)...)
+		cmd.Stdin, cmd.Stdout = os.Stdin, os.Stdout
+		stderr, err := cmd.StderrPipe()
+		if err == nil {
+			err = cmd.Start()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "orchestra: start plugin %s: %v\n", id, err)
+			os.Exit(1)
+		}
+		started := time.Now()
+		mu.Lock()
+		current = cmd.Process
+		mu.Unlock()
+
+		// Pass stderr through, noting where the plugin listens.
+		ready := make(chan string, 1)
+		copied := make(chan struct{})
+		go func() {
+			defer close(copied)
+			r := bufio.NewReader(stderr)
+			for {
+				line, err := r.ReadString('\n')
+				if rest, ok := strings.CutPrefix(strings.TrimSpace(line), "READY "); ok {
+					select {
+					case ready <- strings.TrimSpace(rest):
+					default:
+					}
+				}
+				io.WriteString(os.Stderr, line)
+				if err != nil {
+					return
+				}
+			}
+		}()
+		err = cmd.Wait()
+		<-copied
+		if addr == "" {
+			select {
+			case addr = <-ready:
+			default:
+			}
+		}
+
+		mu.Lock()
+		current = nil
+		done := stopping
+		mu.Unlock()
+		code := exitCode(err)
+		if done || err == nil {
+			os.Exit(code)
+		}
+
+		uptime := time.Since(started)
+		if uptime >= pluginStableAfter {
+			restarts, delay = 0, pluginRestartMinDelay
+		}
+		restarts++
+		rec := pluginRestart{Time: time.Now().UTC(), Plugin: id, Reason: err.Error(), Uptime: uptime.Round(time.Millisecond).String(), Attempt: restarts}
+		if restarts > pluginMaxRestarts {
+			rec.GaveUp = true
+			recordPluginRestart(history, logFile, rec, fmt.Sprintf("plugin %s exited (%v) after %s; gave up after %d restarts in a row", id, err, rec.Uptime, pluginMaxRestarts), "error")
+			os.Exit(code)
+		}
+		rec.Delay = delay.String()
+		recordPluginRestart(history, logFile, rec, fmt.Sprintf("plugin %s exited (%v) after %s; restarting in %s (restart %d of %d)", id, err, rec.Uptime, delay, restarts, pluginMaxRestarts), "warn")
+
+		select {
+		case <-time.After(delay):
+		case <-stop:
+			os.Exit(code)
+		}
+		delay = min(2*delay, pluginRestartMaxDelay)
+	}
+}
+
+// withListenAddr returns args with --listen-addr set to addr, or args as
+// they are when addr is empty.
+func withListenAddr(args []string, addr string) []string {
+	if addr == "" {
+		return args
+	}
+	var out []string
+	for i := 0; i < len(args); i++ {
+		name, _, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		if strings.HasPrefix(args[i], "-") && name == "listen-addr" {
+			if !hasValue {
+				i++ // the value is the next argument
+			}
+			continue
+		}
+		out = append(out, args[i])
+	}
+	return append(out, "--listen-addr="+addr)
+}
+
+// exitCode is the status to exit with for a plugin that ended with err.
+func exitCode(err error) int {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+		return exitErr.ExitCode()
+	}
+	if err != nil {
+		return 1
+	}
+	return 0
+}
+
+// recordPluginRestart appends rec to the restart history and msg to the
+// serve log, at level, or to stderr without one. The serve log is shared
+// with serve and the other supervisors; each record is one small append.
+func recordPluginRestart(history, logFile string, rec pluginRestart, msg, level string) {
+	if history != "" {
+		if f, err := os.OpenFile(history, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644); err == nil {
+			data, _ := json.Marshal(rec)
+			f.Write(append(data, '\n'))
+			f.Close()
+		}
+	}
+	f, err := os.OpenFile(logFile, os.O_WRONLY|os.O_APPEND, 0644)
+	if logFile == "" || err != nil {
+		fmt.Fprintf(os.Stderr, "orchestra: %s\n", msg)
+		return
+	}
+	newServeLog(f).write(logRecord{Time: rec.Time.Format(logTimeFormat), Level: level, Source: logSourceOrchestra, Plugin: rec.Plugin, Msg: msg})
+	f.Close()
+}
+
+// loadPluginRestarts reads the restart history of workspace's serve,
+// oldest first.
+func loadPluginRestarts(workspace string) []pluginRestart {
+	data, err := os.ReadFile(pluginRestartsPath(workspace))
+	if err != nil {
+		return nil
+	}
+	var restarts []pluginRestart
+	for _, line := range strings.Split(string(data), "\n") {
+		var r pluginRestart
+		if json.Unmarshal([]byte(line), &r) == nil && r.Plugin != "" {
+			restarts = append(restarts, r)
+		}
+	}
+	return restarts
+}
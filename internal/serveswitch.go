@@ -0,0 +1,298 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Workspace switching. Each running `orchestra serve` keeps a record in
+// ~/.orchestra/run/serve-<pid>.json. `orchestra serve switch` writes the
+// new workspace to serve-<pid>.switch and sends the process SIGHUP; serve
+// restarts the orchestrator and plugins against the new directory on the
+// same address, so the IDE's stdio session is never closed.
+
+// switchTimeout bounds how long `serve switch` waits for the backend to
+// come back up (startBackend itself gives up after 15 seconds).
+const switchTimeout = 30 * time.Second
+
+// serveRecord describes a running serve session.
+type serveRecord struct {
+	PID         int    `json:"pid"`
+	Workspace   string `json:"workspace"`
+	Addr        string `json:"addr"`
+	StartedAt   string `json:"started_at"`
+	SwitchedAt  string `json:"switched_at,omitempty"`
+	SwitchError string `json:"switch_error,omitempty"` // why the last switch failed
+	Daemon      bool   `json:"daemon,omitempty"`       // a `serve --daemon`
+	AttachedTo  int    `json:"attached_to,omitempty"`  // pid of the daemon the session uses
+	Log         string `json:"log,omitempty"`
+	BackendPID  int    `json:"backend_pid,omitempty"` // the orchestrator, which leads its plugins' process group
+
+	Metrics *serveMetricsSnapshot `json:"metrics,omitempty"`
+}
+
+func serveRunDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".orchestra", "run")
+}
+
+func serveRecordPath(pid int) string {
+	return filepath.Join(serveRunDir(), fmt.Sprintf("serve-%d.json", pid))
+}
+
+func serveSwitchPath(pid int) string {
+	return filepath.Join(serveRunDir(), fmt.Sprintf("serve-%d.switch", pid))
+}
+
+func writeServeRecord(rec serveRecord) {
+	if err := os.MkdirAll(serveRunDir(), 0755); err != nil {
+		return
+	}
+	data, _ := json.MarshalIndent(rec, "", "  ")
+	writeFileAtomic(serveRecordPath(rec.PID), append(data, '\n'), 0644)
+}
+
+func removeServeRecord(pid int) {
+	os.Remove(serveRecordPath(pid))
+	os.Remove(serveSwitchPath(pid))
+}
+
+// reapServeRecord cleans up after a serve session that is gone: its
+// orchestrator and plugins, if it was killed before it could stop them,
+// and its record. Other sessions' processes are left alone.
+func reapServeRecord(rec serveRecord) {
+	if rec.BackendPID != 0 {
+		stopOrphanedBackend(rec.BackendPID)
+	}
+	removeServeRecord(rec.PID)
+}
+
+// liveServeRecords returns records of running serve sessions, oldest
+// first, and removes records left behind by sessions that died.
+func liveServeRecords() []serveRecord {
+	paths, _ := filepath.Glob(filepath.Join(serveRunDir(), "serve-*.json"))
+	var recs []serveRecord
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var rec serveRecord
+		if json.Unmarshal(data, &rec) != nil || rec.PID == 0 {
+			continue
+		}
+		if !processAlive(rec.PID) {
+			reapServeRecord(rec)
+			continue
+		}
+		recs = append(recs, rec)
+	}
+	sort.Slice(recs, func(i, j int) bool { return recs[i].StartedAt < recs[j].StartedAt })
+	return recs
+}
+
+// record describes the session for its run file. The caller holds s.mu.
+func (s *serveSession) record(switchErr string) serveRecord {
+	rec := serveRecord{PID: os.Getpid(), Workspace: s.workspace, SwitchError: switchErr, Daemon: s.daemonized, Log: s.logFile, Metrics: s.metrics.snapshot()}
+	if s.daemon != nil {
+		rec.AttachedTo = s.daemon.PID
+	}
+	if s.backend != nil {
+		rec.Addr = s.backend.addr
+		if s.backend.cmd != nil && s.backend.cmd.Process != nil {
+			rec.BackendPID = s.backend.cmd.Process.Pid
+		}
+	}
+	if old, err := os.ReadFile(serveRecordPath(rec.PID)); err == nil {
+		var prev serveRecord
+		if json.Unmarshal(old, &prev) == nil {
+			rec.StartedAt = prev.StartedAt
+			rec.SwitchedAt = time.Now().UTC().Format(time.RFC3339Nano)
+		}
+	}
+	if rec.StartedAt == "" {
+		rec.StartedAt = time.Now().UTC().Format(time.RFC3339)
+	}
+	return rec
+}
+
+// handleSwitchRequest runs on SIGHUP: it reads the pending switch request,
+// switches, and reports the outcome in the session's record.
+func (s *serveSession) handleSwitchRequest() {
+	path := serveSwitchPath(os.Getpid())
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return // a stray SIGHUP; nothing requested
+	}
+	os.Remove(path)
+	target := strings.TrimSpace(string(data))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.backend == nil {
+		return // shutting down
+	}
+	from := s.workspace
+	if err := s.switchWorkspace(target); err != nil {
+		fmt.Fprintf(s.log, "orchestra: switch to %s failed: %v\n", target, err)
+		writeServeRecord(s.record(err.Error()))
+		return
+	}
+	fmt.Fprintf(s.log, "orchestra: switched workspace %s -> %s\n", from, s.workspace)
+	writeServeRecord(s.record(""))
+}
+
+// switchWorkspace restarts the backend against target on the current
+// address. If the new backend fails to start, the old workspace is
+// restored. A daemon's backend is shared, so neither the daemon nor the
+// sessions attached to it switch. The caller holds s.mu.
+func (s *serveSession) switchWorkspace(target string) error {
+	if s.daemonized {
+		return errors.New("a serve daemon is shared by the sessions attached to it; stop it with 'orchestra stop' and start one in the other workspace")
+	}
+	if s.daemon != nil {
+		return fmt.Errorf("this session is attached to serve daemon %d, which other sessions share; restart the IDE in the other workspace instead", s.daemon.PID)
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", target)
+	}
+	if samePath(target, s.workspace) {
+		return nil
+	}
+	if hazard := workspaceLocationHazard(target); hazard != "" && !s.force {
+		return fmt.Errorf("refusing to serve %s: %s (start serve with --force to allow it)", target, hazard)
+	}
+
+	addr := s.backend.addr
+	s.detach()
+	s.backend.stop()
+
+	backend, err := s.startBackend(target, addr)
+	if err != nil {
+		backend.stop()
+		restored, rerr := s.startBackend(s.workspace, addr)
+		if rerr != nil {
+			restored.stop()
+			s.backend = nil
+			return fmt.Errorf("%v; restarting %s also failed: %v", err, s.workspace, rerr)
+		}
+		s.backend = restored
+		s.attach()
+		return err
+	}
+	s.backend = backend
+	s.workspace = target
+	s.attach()
+	return nil
+}
+
+// RunServeSwitch handles `orchestra serve switch` -- repoints a running
+// serve session at another workspace without restarting the MCP session.
+func RunServeSwitch(args []string) {
+	fs := newFlagSet("serve switch")
+	pid := fs.Int("pid", 0, "Serve process to switch (default: the only one running, or the one serving --from)")
+	from := fs.String("from", "", "Switch the session currently serving this workspace")
+	parseFlags(fs, args)
+
+	if fs.NArg() < 1 {
+		fatal("usage: orchestra serve switch <workspace> [--pid=N | --from=DIR]")
+	}
+	target, err := resolveWorkspace(fs.Arg(0))
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	if info, err := os.Stat(target); err != nil || !info.IsDir() {
+		fatal("%s is not a directory", target)
+	}
+
+	rec, err := pickServeSession(liveServeRecords(), *pid, *from)
+	if err != nil {
+		fatal("%v", err)
+	}
+	if samePath(rec.Workspace, target) {
+		printStatus(tagSkip, "serve %d already serves %s", rec.PID, target)
+		return
+	}
+
+	if err := writeFileAtomic(serveSwitchPath(rec.PID), []byte(target+"\n"), 0644); err != nil {
+		fatal("write switch request: %v", err)
+	}
+	p, _ := os.FindProcess(rec.PID)
+	if err := signalReload(p); err != nil {
+		os.Remove(serveSwitchPath(rec.PID))
+		fatal("signal serve %d: %v", rec.PID, err)
+	}
+
+	sp := startSpinner(fmt.Sprintf("Switching serve %d to %s", rec.PID, target))
+	deadline := time.Now().Add(switchTimeout)
+	for time.Now().Before(deadline) {
+		time.Sleep(250 * time.Millisecond)
+		if _, err := os.Stat(serveSwitchPath(rec.PID)); err == nil {
+			continue // not picked up yet
+		}
+		data, err := os.ReadFile(serveRecordPath(rec.PID))
+		if err != nil {
+			sp.Stop(errors.New("serve exited"))
+			fatal("serve %d exited during the switch", rec.PID)
+		}
+		var now serveRecord
+		if json.Unmarshal(data, &now) != nil || now.SwitchedAt == rec.SwitchedAt {
+			continue // still restarting
+		}
+		if now.SwitchError != "" {
+			sp.Stop(errors.New(now.SwitchError))
+			fatal("switch failed: %s (still serving %s)", now.SwitchError, now.Workspace)
+		}
+		sp.Stop(nil)
+		printStatus(tagOK, "serve %d now serves %s", rec.PID, now.Workspace)
+		return
+	}
+	sp.Stop(errors.New("timed out"))
+	fatal("serve %d did not finish switching in %s; check its log", rec.PID, switchTimeout)
+}
+
+// pickServeSession chooses the session to switch: by pid, by the workspace
+// it serves, or the only one running.
+func pickServeSession(recs []serveRecord, pid int, from string) (serveRecord, error) {
+	if from != "" {
+		abs, err := resolveWorkspace(from)
+		if err != nil {
+			return serveRecord{}, err
+		}
+		from = abs
+	}
+	var matches []serveRecord
+	for _, rec := range recs {
+		if (pid == 0 || rec.PID == pid) && (from == "" || samePath(rec.Workspace, from)) {
+			matches = append(matches, rec)
+		}
+	}
+	switch {
+	case len(matches) == 1:
+		return matches[0], nil
+	case len(recs) == 0:
+		return serveRecord{}, errors.New("no running 'orchestra serve' found")
+	case len(matches) == 0:
+		return serveRecord{}, errors.New("no running 'orchestra serve' matches" + describeServeSessions(recs))
+	}
+	return serveRecord{}, errors.New("several 'orchestra serve' sessions are running; pick one with --pid or --from" + describeServeSessions(recs))
+}
+
+func describeServeSessions(recs []serveRecord) string {
+	var b strings.Builder
+	for _, rec := range recs {
+		b.WriteString("\n  " + strconv.Itoa(rec.PID) + "\t" + rec.Workspace)
+	}
+	return b.String()
+}
@@ -0,0 +1,202 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Warm starts. An IDE starts serve for every window, and most of those
+// starts are for a workspace whose plugins have not changed since the
+// last one. serve checks every installed plugin before the orchestrator
+// starts (binary present, enabled in the workspace, protocols compatible)
+// and saves the checked plugin list, with the address the orchestrator
+// listened on, to <workspace>/.orchestra/run/warm-start.yaml. The next
+// start reuses both while the registries, the plugin selection, and the
+// plugin binaries are as they were; anything else changed is a cold start.
+// --cold-start ignores the file.
+
+// warmStart is what a start saves for the next one.
+type warmStart struct {
+	Key     string            `yaml:"key"`             // what the checks depend on besides Files; see warmStartKey
+	Files   map[string]string `yaml:"files"`           // path -> pathStamp when the checks ran
+	Plugins []pluginConfig    `yaml:"plugins"`         // serveConfig's plugin list
+	Notes   []string          `yaml:"notes,omitempty"` // what the checks logged, replayed on a warm start
+	Addr    string            `yaml:"addr,omitempty"`  // where the orchestrator last listened
+	Checked string            `yaml:"checked"`         // when the checks ran
+}
+
+func warmStartPath(workspace string) string {
+	return filepath.Join(workspaceRunDir(workspace), "warm-start.yaml")
+}
+
+// warmStartKey is what the plugin checks depend on other than files: this
+// build, the built-in plugins' binaries, the certs directory, and
+// --plugins.
+func (s *serveSession) warmStartKey() string {
+	names := make([]string, 0, len(s.bins))
+	for name := range s.bins {
+		names = append(names, name)
+	}
+	sortNames(names)
+	parts := []string{Version, Commit, s.certsDir, fmt.Sprintf("%q", s.onlyPlugins)}
+	for _, name := range names {
+		parts = append(parts, s.bins[name])
+	}
+	return strings.Join(parts, "|")
+}
+
+// warmStartFiles stamps the files the plugin checks read: the global and
+// vendored registries, the vendored plugin trust, the workspace's plugin
+// selection, and every registered plugin binary.
+func warmStartFiles(workspace string) map[string]string {
+	paths := []string{registryPath(), vendorRegistryPath(workspace), pluginTrustPath(), pluginSelectionPath(workspace)}
+	if reg, err := LoadRegistry(); err == nil {
+		for _, p := range reg.Plugins {
+			paths = append(paths, p.Binary)
+		}
+	}
+	if reg, err := loadVendorRegistry(workspace); err == nil {
+		for _, p := range reg.Plugins {
+			paths = append(paths, p.Binary)
+		}
+	}
+	files := make(map[string]string, len(paths))
+	for _, path := range paths {
+		files[path] = pathStamp(path)
+	}
+	return files
+}
+
+// pathStamp identifies a file's contents by size and modification time;
+// "-" when it does not exist.
+func pathStamp(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "-"
+	}
+	return fmt.Sprintf("%d:%d", info.Size(), info.ModTime().UnixNano())
+}
+
+// readWarmStart reads workspace's warm-start file; nil when there is none
+// or it cannot be parsed.
+func readWarmStart(workspace string) *warmStart {
+	data, err := os.ReadFile(warmStartPath(workspace))
+	if err != nil {
+		return nil
+	}
+	var w warmStart
+	if yaml.Unmarshal(data, &w) != nil {
+		return nil
+	}
+	return &w
+}
+
+// current reports whether w's plugin checks still hold for key.
+func (w *warmStart) current(key string) bool {
+	if w == nil || w.Key != key || len(w.Plugins) == 0 {
+		return false
+	}
+	for path, stamp := range w.Files {
+		if pathStamp(path) != stamp {
+			return false
+		}
+	}
+	return true
+}
+
+func saveWarmStart(workspace string, w *warmStart) error {
+	if err := os.MkdirAll(workspaceRunDir(workspace), 0755); err != nil {
+		return err
+	}
+	ignoreRunDir(workspaceRunDir(workspace))
+	data, err := yaml.Marshal(w)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(warmStartPath(workspace), data, 0644)
+}
+
+// backendConfig returns the orchestrator config for workspace: the last
+// start's checked plugins when they are current, else serveConfig's. The
+// returned warmStart is saved, with the address, once the backend is ready.
+func (s *serveSession) backendConfig(workspace, listenAddr string) (orchestratorConfig, *warmStart) {
+	key := s.warmStartKey()
+	if w := readWarmStart(workspace); !s.coldStart && w.current(key) {
+		for _, note := range w.Notes {
+			fmt.Fprintln(s.log, note)
+		}
+		fmt.Fprintf(s.log, "orchestra: warm start: reusing the checks from %s (--cold-start redoes them)\n", w.Checked)
+		cfg := orchestratorConfig{ListenAddr: listenAddr, CertsDir: s.certsDir, Plugins: w.Plugins}
+		if _, ok := unixSocketPath(listenAddr); ok {
+			cfg.CertsDir = ""
+		}
+		return cfg, w
+	}
+
+	// Stamp the files before reading them, so a change made during the
+	// checks makes the next start cold.
+	w := &warmStart{Key: key, Files: warmStartFiles(workspace), Checked: time.Now().UTC().Format(time.RFC3339)}
+	var notes bytes.Buffer
+	cfg := serveConfig(s.bins, s.certsDir, workspace, listenAddr, s.onlyPlugins, io.MultiWriter(s.log, &notes))
+	w.Plugins = cfg.Plugins
+	for _, line := range strings.Split(strings.TrimSpace(notes.String()), "\n") {
+		if line != "" {
+			w.Notes = append(w.Notes, line)
+		}
+	}
+	return cfg, w
+}
+
+// saveBackendStart records a ready backend's checked plugins and address
+// for the next start. Failing to write it only costs that start its warm
+// path.
+func (s *serveSession) saveBackendStart(workspace string, w *warmStart, addr string) {
+	w.Addr = addr
+	if err := saveWarmStart(workspace, w); err != nil {
+		fmt.Fprintf(s.log, "orchestra: warning: save warm-start cache: %v\n", err)
+	}
+}
+
+// warmListenAddr returns the address the orchestrator last listened on for
+// workspace when resolved leaves the port to the orchestrator, the host is
+// the same, and the port is free; otherwise resolved.
+func warmListenAddr(workspace, resolved string) string {
+	host, port, err := net.SplitHostPort(resolved)
+	if err != nil || port != "0" {
+		return resolved
+	}
+	w := readWarmStart(workspace)
+	if w == nil {
+		return resolved
+	}
+	lastHost, lastPort, err := net.SplitHostPort(w.Addr)
+	if err != nil || lastPort == "0" || !sameListenHost(host, lastHost) {
+		return resolved
+	}
+	l, err := net.Listen("tcp", w.Addr)
+	if err != nil {
+		return resolved
+	}
+	l.Close()
+	return w.Addr
+}
+
+// sameListenHost reports whether the orchestrator, told to listen on
+// configured, could have reported listening on reported.
+func sameListenHost(configured, reported string) bool {
+	if configured == reported {
+		return true
+	}
+	loopback := func(h string) bool {
+		return h == "localhost" || net.ParseIP(h).IsLoopback()
+	}
+	return loopback(configured) && loopback(reported)
+}
@@ -0,0 +1,286 @@
+package internal
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// RunSetup handles `orchestra setup` -- the first-run wizard. It checks
+// prerequisites, offers to download missing sibling binaries, generates
+// certificates, saves global config, and offers to initialize the current
+// directory. Every step can be re-run safely; finished steps are skipped.
+func RunSetup(args []string) {
+	fs := newFlagSet("setup")
+	workspace := fs.String("workspace", ".", "Directory to offer to initialize")
+	certsDir := fs.String("certs-dir", defaultCertsDir(), "mTLS certificates directory")
+	ide := fs.String("ide", "", "IDEs you use, comma-separated (saved as the default for init)")
+	channel := fs.String("channel", "", "Release channel for updates: stable or beta")
+	yes := fs.Bool("yes", false, "Accept the defaults and answer yes to every question")
+	noDownload := fs.Bool("no-download", false, "Never download missing binaries")
+	parseFlags(fs, args)
+
+	approve := func(question string) bool {
+		return *yes || confirm(question)
+	}
+	prompt := func(question, def string) string {
+		if *yes {
+			return def
+		}
+		return ask(question, def)
+	}
+
+	fmt.Fprintf(os.Stderr, "Orchestra setup (%s)\n\n", Version)
+
+	// 1. Prerequisites.
+	fmt.Fprintf(os.Stderr, "Prerequisites\n")
+	checkSetupTool("git", "needed to install packs and build plugins from source")
+	checkSetupTool("go", "only needed for 'orchestra install --source'")
+	missing := missingSiblingBinaries()
+	if len(missing) > 0 && !*noDownload {
+		if approve(fmt.Sprintf("Download the missing binaries (%s)?", strings.Join(missing, ", "))) {
+			downloadSiblingBinaries(releaseChannel(*channel))
+		} else {
+			printStatus(tagSkip, "download; 'orchestra serve' needs %s", strings.Join(missing, ", "))
+		}
+	}
+
+	// 2. Certificates.
+	fmt.Fprintf(os.Stderr, "\nCertificates\n")
+	dir := expandHome(*certsDir)
+	switch {
+	case certsPresent(dir):
+		printStatus(tagOK, "%s", dir)
+	case approve(fmt.Sprintf("Generate a local CA in %s?", dir)):
+		if err := generateCA(dir); err != nil {
+			printStatus(tagFail, "generate CA: %v", err)
+		} else {
+			printStatus(tagOK, "created %s and %s", filepath.Join(dir, "ca.crt"), filepath.Join(dir, "ca.key"))
+		}
+	default:
+		printStatus(tagSkip, "certificates")
+	}
+
+	// 3. Global config.
+	fmt.Fprintf(os.Stderr, "\nGlobal config (%s)\n", globalConfigPath())
+	cfg := loadGlobalConfig()
+	if cfg.Defaults == nil {
+		cfg.Defaults = map[string]string{}
+	}
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+
+	ides := *ide
+	if ides == "" {
+		def := cfg.Defaults["ide"]
+		if def == "" {
+			def = strings.Join(detectIDEs(absWorkspace), ",")
+		}
+		for {
+			ides = prompt(fmt.Sprintf("IDEs you use (%s):", strings.Join(allIDENames(), ", ")), def)
+			bad := unknownIDEs(ides)
+			if len(bad) == 0 {
+				break
+			}
+			if *yes || !isTerminal(os.Stdin) {
+				fatal("unknown IDE %s. Supported: %s", strings.Join(bad, ", "), strings.Join(allIDENames(), ", "))
+			}
+			printStatus(tagFail, "unknown IDE %s", strings.Join(bad, ", "))
+		}
+	} else if bad := unknownIDEs(ides); len(bad) > 0 {
+		fatal("unknown IDE %s. Supported: %s", strings.Join(bad, ", "), strings.Join(allIDENames(), ", "))
+	}
+
+	ch := *channel
+	if ch == "" {
+		ch = prompt("Release channel (stable or beta):", releaseChannel(""))
+	}
+	ch = releaseChannel(ch)
+
+	// The token is only asked for, never taken from a flag, so it stays
+	// out of shell history; ORCHESTRA_GITHUB_TOKEN works without saving it.
+	tok := ""
+	if githubToken() == "" {
+		tok = prompt("GitHub token (optional, Enter to skip):", "")
+	}
+
+	if ides != "" {
+		cfg.Defaults["ide"] = ides
+	}
+	cfg.Defaults["channel"] = ch
+	if tok != "" {
+		cfg.GitHubToken = tok
+	}
+	if err := saveGlobalConfig(cfg); err != nil {
+		printStatus(tagFail, "write %s: %v", globalConfigPath(), err)
+	} else {
+		printStatus(tagOK, "ide=%s channel=%s token=%s", orDash(ides), ch, tokenState(cfg.GitHubToken))
+	}
+
+	// 4. Workspace.
+	fmt.Fprintf(os.Stderr, "\nWorkspace\n")
+	if _, err := os.Stat(filepath.Join(absWorkspace, ".projects")); err == nil {
+		printStatus(tagOK, "%s is already initialized", absWorkspace)
+	} else if hazard := workspaceHazard(absWorkspace); hazard != "" {
+		printStatus(tagSkip, "not offering to initialize %s: %s", absWorkspace, hazard)
+	} else if approve(fmt.Sprintf("Initialize %s now?", absWorkspace)) {
+		fmt.Fprintf(os.Stderr, "\n")
+		RunInit([]string{"--workspace", absWorkspace})
+		return
+	} else {
+		printStatus(tagSkip, "run 'orchestra init' in your project when ready")
+	}
+
+	fmt.Fprintf(os.Stderr, "\nSetup complete.\n")
+}
+
+// checkSetupTool reports whether tool is on PATH and its version.
+func checkSetupTool(tool, why string) {
+	path, err := exec.LookPath(tool)
+	if err != nil {
+		printStatus(tagWarn, "%s not found (%s)", tool, why)
+		return
+	}
+	versionArg := "--version"
+	if tool == "go" {
+		versionArg = "version"
+	}
+	out, _ := exec.Command(path, versionArg).Output()
+	printStatus(tagOK, "%s  %s", strings.TrimSpace(string(out)), path)
+}
+
+// missingSiblingBinaries reports each binary serve needs next to this one
+// and returns the names that are missing.
+func missingSiblingBinaries() []string {
+	self, err := resolveBinaryPath()
+	if err != nil {
+		printStatus(tagFail, "resolve orchestra path: %v", err)
+		return nil
+	}
+	dir := filepath.Dir(self)
+	var missing []string
+	for _, name := range orchestraBinaries {
+		if name == "orchestra" {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err != nil {
+			printStatus(tagFail, "%s missing from %s", name, dir)
+			missing = append(missing, name)
+			continue
+		}
+		printStatus(tagOK, "%s", path)
+	}
+	return missing
+}
+
+// downloadSiblingBinaries installs the newest release on channel next to
+// this binary, the same way `orchestra update` does.
+func downloadSiblingBinaries(channel string) {
+	latest := checkLatestVersion(channel)
+	if latest == "" {
+		printStatus(tagFail, "could not find a %s release; download manually: https://github.com/%s/releases", channel, githubRepo)
+		return
+	}
+	if err := selfUpdate(latest, selfUpdateOptions{Reexec: true}); err != nil {
+		printStatus(tagFail, "download %s: %v", latest, err)
+	}
+}
+
+// certsPresent reports whether dir already holds certificates.
+func certsPresent(dir string) bool {
+	for _, pattern := range []string{"*.crt", "*.pem"} {
+		if matches, _ := filepath.Glob(filepath.Join(dir, pattern)); len(matches) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// generateCA writes a self-signed ECDSA P-256 CA valid for ten years to
+// dir/ca.crt and dir/ca.key (mode 0600).
+func generateCA(dir string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "Orchestra local CA"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.AddDate(10, 0, 0),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(dir, "ca.key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(dir, "ca.crt"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+}
+
+// unknownIDEs returns the names in a comma-separated IDE list that init
+// does not support.
+func unknownIDEs(list string) []string {
+	var bad []string
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		if _, ok := ideRegistry[name]; !ok {
+			bad = append(bad, name)
+		}
+	}
+	return bad
+}
+
+// expandHome replaces a leading ~ with the user's home directory.
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~") {
+		home, _ := os.UserHomeDir()
+		return filepath.Join(home, path[1:])
+	}
+	return path
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func tokenState(token string) string {
+	if token == "" {
+		return "unset"
+	}
+	return "saved"
+}
@@ -0,0 +1,552 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// RunStatus handles `orchestra status` -- a one-screen summary of the
+// workspace: its packs, plugins, and disk usage, and the size and scan cost
+// of its feature store.
+func RunStatus(args []string) {
+	fs := newFlagSet("status")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	restarts := fs.Bool("restarts", false, "List the plugin restarts of the current or last serve")
+	parseFlags(fs, args)
+
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	if *restarts {
+		printPluginRestarts(absWorkspace)
+		return
+	}
+
+	st := collectStatus(absWorkspace)
+	if jsonOutput() {
+		printJSON(st)
+		return
+	}
+
+	fmt.Fprintf(os.Stdout, "Workspace:   %s\n", st.Workspace)
+	if st.Schema > 0 {
+		fmt.Fprintf(os.Stdout, "Schema:      v%d\n", st.Schema)
+	} else {
+		fmt.Fprintf(os.Stdout, "Schema:      - (no .projects/; run 'orchestra init')\n")
+	}
+	fmt.Fprintf(os.Stdout, "Serve:       %s\n", serveStatus(st.Serve))
+	if r := describePluginRestarts(st.Restarts); r != "" {
+		fmt.Fprintf(os.Stdout, "Restarts:    %s\n", r)
+	}
+	if c := st.Encryption; c != nil {
+		fmt.Fprintf(os.Stdout, "Encryption:  on (key %s, %s)\n", c.KeyID, c.KeyStore)
+	} else {
+		fmt.Fprintf(os.Stdout, "Encryption:  off\n")
+	}
+
+	fmt.Fprintln(os.Stdout)
+	printPackStats(st.Packs)
+	printPluginStats(st.Plugins)
+	printDiskUsage(st.Disk)
+
+	if st.Store == nil {
+		return
+	}
+	fmt.Fprintln(os.Stdout)
+	printStoreStats(st.Store)
+}
+
+// workspaceStatus is what `orchestra status` reports; --json prints it as
+// it is.
+type workspaceStatus struct {
+	Workspace  string            `json:"workspace"`
+	Schema     int               `json:"schema"`     // 0 without .projects/
+	Serve      []serveRecord     `json:"serve"`      // the workspace's running sessions
+	Restarts   []pluginRestart   `json:"restarts"`   // of the current or last serve, oldest first
+	Encryption *statusEncryption `json:"encryption"` // nil when off
+	Packs      []statusPack      `json:"packs"`
+	Plugins    []statusPlugin    `json:"plugins"`
+	Disk       []statusDisk      `json:"disk"`
+	Store      *storeStats       `json:"store"` // nil without .projects/
+}
+
+type statusEncryption struct {
+	KeyID    string `json:"key_id"`
+	KeyStore string `json:"key_store"`
+}
+
+type statusPack struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Skills  int    `json:"skills"`
+	Agents  int    `json:"agents"`
+	Hooks   int    `json:"hooks"`
+}
+
+type statusPlugin struct {
+	ID              string `json:"id"`
+	Version         string `json:"version,omitempty"`
+	BuiltIn         bool   `json:"built_in,omitempty"`
+	Vendored        bool   `json:"vendored,omitempty"`
+	Tools           int    `json:"tools"`
+	ToolsUnverified bool   `json:"tools_unverified,omitempty"`
+	Loaded          bool   `json:"loaded"`
+	SkipReason      string `json:"skip_reason,omitempty"` // disabled, binary missing, or incompatible
+}
+
+type statusDisk struct {
+	Dir     string `json:"dir"`
+	Missing bool   `json:"missing,omitempty"`
+	Bytes   int64  `json:"bytes"`
+	Files   int    `json:"files"`
+	Error   string `json:"error,omitempty"`
+}
+
+// collectStatus gathers everything `orchestra status` reports about
+// workspace.
+func collectStatus(workspace string) *workspaceStatus {
+	st := &workspaceStatus{
+		Workspace: workspace,
+		Serve:     []serveRecord{},
+		Restarts:  loadPluginRestarts(workspace),
+		Packs:     collectPackStats(workspace),
+		Plugins:   collectPluginStats(workspace),
+		Disk:      collectDiskUsage(workspace),
+	}
+	if version, ok := readWorkspaceSchema(workspace); ok {
+		st.Schema = version
+	}
+	for _, rec := range liveServeRecords() {
+		if rec.Workspace == workspace {
+			st.Serve = append(st.Serve, rec)
+		}
+	}
+	if st.Restarts == nil {
+		st.Restarts = []pluginRestart{}
+	}
+	if c := loadEncryptionConfig(workspace); c != nil {
+		st.Encryption = &statusEncryption{KeyID: c.KeyID, KeyStore: c.KeyStore}
+	}
+	if _, err := os.Stat(filepath.Join(workspace, ".projects")); err == nil {
+		st.Store = collectStoreStats(workspace)
+	}
+	return st
+}
+
+// collectPackStats returns the installed packs with their skill, agent, and
+// hook counts, sorted by name.
+func collectPackStats(workspace string) []statusPack {
+	reg := loadPackRegistry(workspace)
+	var names []string
+	for name := range reg.Packs {
+		names = append(names, name)
+	}
+	sortNames(names)
+	stats := []statusPack{}
+	for _, name := range names {
+		e := reg.Packs[name]
+		stats = append(stats, statusPack{name, e.Version, len(e.Skills), len(e.Agents), len(e.Hooks)})
+	}
+	return stats
+}
+
+// printPackStats prints the installed packs and the totals.
+func printPackStats(stats []statusPack) {
+	if len(stats) == 0 {
+		fmt.Fprintf(os.Stdout, "Packs:       none installed\n")
+		return
+	}
+	var skills, agents, hooks int
+	for _, p := range stats {
+		skills += p.Skills
+		agents += p.Agents
+		hooks += p.Hooks
+	}
+	fmt.Fprintf(os.Stdout, "Packs:       %d installed (%d skill(s), %d agent(s), %d hook(s))\n", len(stats), skills, agents, hooks)
+	tw := newTable(os.Stdout)
+	for _, p := range stats {
+		fmt.Fprintf(tw, "  %s\t%s\t%d skill(s)\t%d agent(s)\t%d hook(s)\n", p.Name, orDash(p.Version), p.Skills, p.Agents, p.Hooks)
+	}
+	tw.Flush()
+}
+
+// collectPluginStats returns the plugins serve loads for workspace with
+// their tool counts, and the installed plugins it would skip, with the
+// reason.
+func collectPluginStats(workspace string) []statusPlugin {
+	stats := []statusPlugin{
+		{ID: "storage.markdown", BuiltIn: true, Loaded: true},
+		{ID: "tools.features", BuiltIn: true, Tools: toolCount("features"), Loaded: true},
+		{ID: "tools.marketplace", BuiltIn: true, Tools: toolCount("marketplace"), Loaded: true},
+	}
+	sel := loadPluginSelection(workspace, io.Discard)
+	for _, p := range servePlugins(workspace, io.Discard) {
+		st := statusPlugin{
+			ID:              p.ID,
+			Version:         p.Version,
+			Vendored:        p.Vendored,
+			Tools:           len(p.Tools()),
+			ToolsUnverified: p.VerifiedAt == "" && len(p.ProvidesTools) > 0,
+			Loaded:          true,
+		}
+		if _, err := os.Stat(p.Binary); err != nil {
+			st.Loaded, st.SkipReason = false, "binary missing"
+		} else if reason := sel.skipReason(p.ID); reason != "" {
+			st.Loaded, st.SkipReason = false, reason
+		} else if reason := pluginIncompatibility(p); reason != "" {
+			st.Loaded, st.SkipReason = false, reason
+		}
+		stats = append(stats, st)
+	}
+	return stats
+}
+
+// printPluginStats prints the plugins serve loads and skips.
+func printPluginStats(stats []statusPlugin) {
+	var loaded, tools int
+	for _, st := range stats {
+		if st.Loaded {
+			loaded++
+			tools += st.Tools
+		}
+	}
+	fmt.Fprintf(os.Stdout, "Plugins:     %d loaded (%d tool(s))", loaded, tools)
+	if skipped := len(stats) - loaded; skipped > 0 {
+		fmt.Fprintf(os.Stdout, ", %d skipped", skipped)
+	}
+	fmt.Fprintln(os.Stdout)
+	tw := newTable(os.Stdout)
+	for _, st := range stats {
+		if !st.Loaded {
+			fmt.Fprintf(tw, "  %s\tskipped\t%s\n", st.ID, st.SkipReason)
+			continue
+		}
+		note := "built in"
+		if !st.BuiltIn {
+			note = orDash(st.Version)
+			if st.Vendored {
+				note += ", vendored"
+			}
+			if st.ToolsUnverified {
+				note += ", tools unverified"
+			}
+		}
+		fmt.Fprintf(tw, "  %s\t%d tool(s)\t%s\n", st.ID, st.Tools, note)
+	}
+	tw.Flush()
+}
+
+// collectDiskUsage returns the size of .projects/ and .claude/.
+func collectDiskUsage(workspace string) []statusDisk {
+	var usage []statusDisk
+	for _, dir := range []string{".projects", ".claude"} {
+		d := statusDisk{Dir: dir}
+		size, files, err := dirUsage(filepath.Join(workspace, dir))
+		switch {
+		case os.IsNotExist(err):
+			d.Missing = true
+		case err != nil:
+			d.Error = err.Error()
+		default:
+			d.Bytes, d.Files = size, files
+		}
+		usage = append(usage, d)
+	}
+	return usage
+}
+
+// printDiskUsage prints the size of .projects/ and .claude/.
+func printDiskUsage(usage []statusDisk) {
+	var parts []string
+	for _, d := range usage {
+		switch {
+		case d.Missing:
+			parts = append(parts, d.Dir+"/ -")
+		case d.Error != "":
+			parts = append(parts, fmt.Sprintf("%s/ ? (%s)", d.Dir, d.Error))
+		default:
+			parts = append(parts, fmt.Sprintf("%s/ %s in %d file(s)", d.Dir, formatBytes(d.Bytes), d.Files))
+		}
+	}
+	fmt.Fprintf(os.Stdout, "Disk:        %s\n", strings.Join(parts, ", "))
+}
+
+// dirUsage totals the regular files under dir. Symlinks are not followed.
+func dirUsage(dir string) (size int64, files int, err error) {
+	if _, err := os.Lstat(dir); err != nil {
+		return 0, 0, err
+	}
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil // removed while walking
+		}
+		size += info.Size()
+		files++
+		return nil
+	})
+	return size, files, err
+}
+
+// serveStatus describes a workspace's serve sessions. Sessions attached to
+// a daemon are counted with it.
+func serveStatus(recs []serveRecord) string {
+	var sessions []string
+	for _, rec := range recs {
+		if rec.AttachedTo != 0 {
+			continue
+		}
+		if rec.Daemon {
+			var attached int
+			for _, other := range recs {
+				if other.AttachedTo == rec.PID {
+					attached++
+				}
+			}
+			sessions = append(sessions, fmt.Sprintf("daemon pid %d on %s, %d IDE session(s) attached", rec.PID, orDash(rec.Addr), attached))
+			continue
+		}
+		session := fmt.Sprintf("pid %d on %s", rec.PID, orDash(rec.Addr))
+		if m := describeMetrics(rec.Metrics); m != "" {
+			session += ", " + m
+		}
+		sessions = append(sessions, session)
+	}
+	if len(sessions) == 0 {
+		return "not running"
+	}
+	return "running (" + strings.Join(sessions, "; ") + ")"
+}
+
+// describePluginRestarts summarizes the restart history: how many restarts
+// per plugin, which plugins were given up on, and the latest. It is empty
+// when no plugin crashed.
+func describePluginRestarts(restarts []pluginRestart) string {
+	if len(restarts) == 0 {
+		return ""
+	}
+	counts := map[string]int{}
+	var plugins []string
+	gaveUp := map[string]bool{}
+	for _, r := range restarts {
+		if counts[r.Plugin] == 0 && !gaveUp[r.Plugin] {
+			plugins = append(plugins, r.Plugin)
+		}
+		if r.GaveUp {
+			gaveUp[r.Plugin] = true
+		} else {
+			counts[r.Plugin]++
+		}
+	}
+	sortNames(plugins)
+	var parts []string
+	for _, p := range plugins {
+		part := fmt.Sprintf("%s %d", p, counts[p])
+		if gaveUp[p] {
+			part += ", gave up"
+		}
+		parts = append(parts, part)
+	}
+	last := restarts[len(restarts)-1]
+	return fmt.Sprintf("%s; last %s ago (%s: %s); see 'orchestra status --restarts'",
+		strings.Join(parts, "; "), formatAge(time.Since(last.Time)), last.Plugin, last.Reason)
+}
+
+// printPluginRestarts lists the restart history, oldest first.
+func printPluginRestarts(workspace string) {
+	restarts := loadPluginRestarts(workspace)
+	if jsonOutput() {
+		if restarts == nil {
+			restarts = []pluginRestart{}
+		}
+		printJSON(restarts)
+		return
+	}
+	if len(restarts) == 0 {
+		fmt.Fprintln(os.Stderr, "No plugin has been restarted.")
+		return
+	}
+	tw := newTable(os.Stdout)
+	for _, r := range restarts {
+		outcome := "restarted after " + r.Delay
+		if r.GaveUp {
+			outcome = "gave up"
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\tup %s\t#%d\t%s\n",
+			r.Time.Local().Format("2006-01-02 15:04:05"), r.Plugin, r.Reason, r.Uptime, r.Attempt, outcome)
+	}
+	tw.Flush()
+}
+
+// storeStats is the feature store's size, the state of its index, and how
+// long reading it takes with and without the index.
+type storeStats struct {
+	KeyUnavailable bool             `json:"key_unavailable,omitempty"` // nothing else is set
+	Projects       int              `json:"projects"`
+	Features       int              `json:"features"`
+	ByStatus       map[string]int   `json:"by_status"`
+	Unreadable     int              `json:"unreadable"`
+	Bytes          int64            `json:"bytes"`
+	Files          int              `json:"files"`
+	Largest        string           `json:"largest,omitempty"` // project/file
+	LargestBytes   int64            `json:"largest_bytes,omitempty"`
+	ScanMS         int64            `json:"scan_ms"`
+	Index          *storeIndexStats `json:"index"` // nil without one
+	Transitions    int              `json:"transitions"`
+	HistoryBytes   int64            `json:"history_bytes"`
+
+	scanTime time.Duration
+}
+
+type storeIndexStats struct {
+	Entries int   `json:"entries"`
+	Stale   int   `json:"stale"` // refreshed on the next read
+	Bytes   int64 `json:"bytes"`
+	ReadMS  int64 `json:"read_ms"`
+
+	readTime time.Duration
+}
+
+// collectStoreStats measures workspace's feature store. It does not write
+// the index.
+func collectStoreStats(workspace string) *storeStats {
+	st := &storeStats{ByStatus: map[string]int{}}
+	if !featureKeyAvailable(workspace) {
+		st.KeyUnavailable = true
+		return st
+	}
+
+	projects := listFeatureProjects(workspace)
+	st.Projects = len(projects)
+	for _, p := range projects {
+		entries, _ := os.ReadDir(featuresDir(workspace, p))
+		for _, e := range entries {
+			if e.IsDir() || !strings.HasSuffix(e.Name(), ".md") {
+				continue
+			}
+			info, err := e.Info()
+			if err != nil {
+				continue
+			}
+			st.Files++
+			st.Bytes += info.Size()
+			if info.Size() > st.LargestBytes {
+				st.LargestBytes, st.Largest = info.Size(), p+"/"+e.Name()
+			}
+		}
+	}
+
+	start := time.Now()
+	features := scanFeatures(workspace, "", nil)
+	st.scanTime = time.Since(start)
+	st.ScanMS = st.scanTime.Milliseconds()
+	st.Features = len(features)
+	for _, f := range features {
+		st.ByStatus[f.Status]++
+	}
+	if unreadable := st.Files - len(features); unreadable > 0 {
+		st.Unreadable = unreadable
+	}
+
+	start = time.Now()
+	if idx := loadFeatureIndex(workspace); idx != nil {
+		_, _, stats := refreshFeatureIndex(workspace, idx, nil)
+		readTime := time.Since(start)
+		stale := stats.Parsed + stats.Failed + stats.Removed
+		st.Index = &storeIndexStats{Entries: stats.Cached + stale, Stale: stale, ReadMS: readTime.Milliseconds(), readTime: readTime}
+		if info, err := os.Stat(featureIndexPath(workspace)); err == nil {
+			st.Index.Bytes = info.Size()
+		}
+	}
+
+	st.Transitions = len(loadTransitions(workspace))
+	if info, err := os.Stat(filepath.Join(historyDir(workspace), "transitions.jsonl")); err == nil {
+		st.HistoryBytes = info.Size()
+	}
+	return st
+}
+
+// printStoreStats prints the feature store's stats.
+func printStoreStats(st *storeStats) {
+	if st.KeyUnavailable {
+		fmt.Fprintf(os.Stdout, "Features:    ? (the workspace key is not available)\n")
+		return
+	}
+
+	counts := map[string]int{}
+	for s, n := range st.ByStatus {
+		counts[s] = n
+	}
+	var parts []string
+	for _, s := range lifecycleStates {
+		if counts[s.Name] > 0 {
+			parts = append(parts, fmt.Sprintf("%s %d", s.Name, counts[s.Name]))
+			delete(counts, s.Name)
+		}
+	}
+	var other []string
+	for s := range counts {
+		other = append(other, s)
+	}
+	sortNames(other)
+	for _, s := range other {
+		parts = append(parts, fmt.Sprintf("%s %d", orDash(s), counts[s]))
+	}
+
+	fmt.Fprintf(os.Stdout, "Projects:    %d\n", st.Projects)
+	fmt.Fprintf(os.Stdout, "Features:    %d", st.Features)
+	if len(parts) > 0 {
+		fmt.Fprintf(os.Stdout, " (%s)", strings.Join(parts, ", "))
+	}
+	fmt.Fprintln(os.Stdout)
+	if st.Unreadable > 0 {
+		fmt.Fprintf(os.Stdout, "Unreadable:  %d file(s)\n", st.Unreadable)
+	}
+	fmt.Fprintf(os.Stdout, "Size:        %s in %d file(s)", formatBytes(st.Bytes), st.Files)
+	if st.Largest != "" {
+		fmt.Fprintf(os.Stdout, ", largest %s (%s)", st.Largest, formatBytes(st.LargestBytes))
+	}
+	fmt.Fprintln(os.Stdout)
+	fmt.Fprintf(os.Stdout, "Full scan:   %s\n", st.scanTime.Round(time.Millisecond))
+
+	if idx := st.Index; idx == nil {
+		fmt.Fprintf(os.Stdout, "Index:       none (run 'orchestra compact' to build one)\n")
+	} else {
+		state := "fresh"
+		if idx.Stale > 0 {
+			state = fmt.Sprintf("%d of %d entries stale, refreshed on next read", idx.Stale, idx.Entries)
+		}
+		fmt.Fprintf(os.Stdout, "Index:       %s, %s\n", state, formatBytes(idx.Bytes))
+		fmt.Fprintf(os.Stdout, "Index read:  %s\n", idx.readTime.Round(time.Millisecond))
+	}
+
+	fmt.Fprintf(os.Stdout, "History:     %d transition(s), %s\n", st.Transitions, formatBytes(st.HistoryBytes))
+}
+
+// formatBytes renders n as B, KB, MB, or GB (powers of 1024).
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value, suffix := float64(n)/unit, "KB"
+	for _, s := range []string{"MB", "GB"} {
+		if value < unit {
+			break
+		}
+		value, suffix = value/unit, s
+	}
+	return fmt.Sprintf("%.1f %s", value, suffix)
+}
@@ -0,0 +1,261 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Status tags printed in front of per-item progress lines.
+const (
+	tagOK   = "OK"
+	tagFail = "FAIL"
+	tagSkip = "SKIP"
+	tagWarn = "WARN"
+)
+
+// ANSI color codes used for terminal output.
+const (
+	ansiReset  = "\033[0m"
+	ansiBold   = "\033[1m"
+	ansiDim    = "\033[2m"
+	ansiRed    = "\033[31m"
+	ansiGreen  = "\033[32m"
+	ansiYellow = "\033[33m"
+	ansiCyan   = "\033[36m"
+)
+
+var (
+	colorOnce   sync.Once
+	stderrColor bool
+	stderrIsTTY bool
+)
+
+// isTerminal reports whether f is attached to a character device (a TTY).
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// detectTerminal decides once per process whether stderr gets color and
+// animations. NO_COLOR (https://no-color.org), --no-color, --plain, and
+// TERM=dumb disable color; piped output is always plain.
+func detectTerminal() {
+	colorOnce.Do(func() {
+		stderrIsTTY = isTerminal(os.Stderr) && os.Getenv("TERM") != "dumb"
+		_, noColor := os.LookupEnv("NO_COLOR")
+		stderrColor = stderrIsTTY && !noColor && !globals.noColor && !globals.plain
+	})
+}
+
+// plainGlyphs spells out the symbols orchestra prints, for --plain.
+var plainGlyphs = strings.NewReplacer("→", "to", "—", "-", "–", "-", "…", "...", "·", ",", "✓", "installed", "★", "recommended")
+
+// plainText returns s with its symbols spelled out under --plain, and s
+// otherwise.
+func plainText(s string) string {
+	if !globals.plain {
+		return s
+	}
+	return plainGlyphs.Replace(s)
+}
+
+// useColor reports whether diagnostics on stderr should be colored.
+func useColor() bool {
+	detectTerminal()
+	return stderrColor
+}
+
+// colorize wraps s in the given ANSI code when color is enabled.
+func colorize(code, s string) string {
+	if !useColor() {
+		return s
+	}
+	return code + s + ansiReset
+}
+
+// statusTag renders "[OK]", "[FAIL]", etc., colored by severity.
+func statusTag(tag string) string {
+	label := "[" + tag + "]"
+	switch tag {
+	case tagOK:
+		return colorize(ansiGreen, label)
+	case tagFail:
+		return colorize(ansiRed, label)
+	case tagSkip, tagWarn:
+		return colorize(ansiYellow, label)
+	}
+	return label
+}
+
+// printStatus writes an indented "[TAG] message" line to stderr.
+func printStatus(tag, format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "  %s %s\n", statusTag(tag), plainText(fmt.Sprintf(format, args...)))
+}
+
+// confirm asks a yes/no question on stderr and reads the answer from stdin.
+// Anything but y or yes is no, as is a closed or non-terminal stdin.
+func confirm(question string) bool {
+	if !isTerminal(os.Stdin) {
+		return false
+	}
+	fmt.Fprintf(os.Stderr, "  %s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// ask prompts on stderr and reads a line from stdin, returning def when
+// the answer is empty or stdin is not a terminal.
+func ask(question, def string) string {
+	if !isTerminal(os.Stdin) {
+		return def
+	}
+	if def != "" {
+		fmt.Fprintf(os.Stderr, "  %s [%s] ", question, def)
+	} else {
+		fmt.Fprintf(os.Stderr, "  %s ", question)
+	}
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if answer = strings.TrimSpace(answer); answer != "" {
+		return answer
+	}
+	return def
+}
+
+// table is what newTable returns: tab-separated cells in, rows out.
+type table interface {
+	io.Writer
+	Flush() error
+}
+
+// newTable returns a tabwriter for aligned, tab-separated columns, or under
+// --plain a plainTable. Callers must Flush it when done.
+func newTable(w io.Writer) table {
+	if globals.plain {
+		return &plainTable{w: w}
+	}
+	return tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+}
+
+// plainTable writes each row on its own line with its non-empty cells
+// separated by commas, so a screen reader reads a row as one sentence
+// rather than across padded columns.
+type plainTable struct {
+	w       io.Writer
+	pending []byte
+}
+
+func (t *plainTable) Write(p []byte) (int, error) {
+	t.pending = append(t.pending, p...)
+	for {
+		i := bytes.IndexByte(t.pending, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		t.writeRow(string(t.pending[:i]))
+		t.pending = t.pending[i+1:]
+	}
+}
+
+func (t *plainTable) writeRow(row string) {
+	trimmed := strings.TrimLeft(row, " ")
+	var cells []string
+	for _, cell := range strings.Split(trimmed, "\t") {
+		if cell = strings.TrimSpace(cell); cell != "" {
+			cells = append(cells, cell)
+		}
+	}
+	fmt.Fprintf(t.w, "%s%s\n", row[:len(row)-len(trimmed)], plainText(strings.Join(cells, ", ")))
+}
+
+func (t *plainTable) Flush() error {
+	if len(t.pending) > 0 {
+		t.writeRow(string(t.pending))
+		t.pending = nil
+	}
+	return nil
+}
+
+// jsonOutput reports whether results should be printed as JSON: --json, or
+// ORCHESTRA_OUTPUT=json.
+func jsonOutput() bool {
+	return globals.json || os.Getenv("ORCHESTRA_OUTPUT") == "json"
+}
+
+// printJSON prints v to stdout as indented JSON.
+func printJSON(v any) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		fatal("encode JSON: %v", err)
+	}
+	os.Stdout.Write(append(data, '\n'))
+}
+
+// spinner shows an animated progress indicator on stderr while a slow step
+// runs. When stderr is not a TTY, --debug is writing to it, or --plain is
+// set, it prints the message once instead.
+type spinner struct {
+	msg      string
+	animated bool
+	step     *debugSpan
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// startSpinner begins a spinner with the given message.
+func startSpinner(msg string) *spinner {
+	detectTerminal()
+	s := &spinner{msg: msg, animated: stderrIsTTY && !debugToStderr() && !globals.plain, step: debugStep("%s", msg), stop: make(chan struct{}), done: make(chan struct{})}
+	if !s.animated {
+		fmt.Fprintf(os.Stderr, "  %s...\n", msg)
+		close(s.done)
+		return s
+	}
+
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for i := 0; ; i++ {
+			fmt.Fprintf(os.Stderr, "\r  %s %s", colorize(ansiCyan, spinnerFrames[i%len(spinnerFrames)]), msg)
+			select {
+			case <-s.stop:
+				// Clear the spinner line.
+				fmt.Fprintf(os.Stderr, "\r%s\r", strings.Repeat(" ", len(msg)+6))
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return s
+}
+
+// Stop halts the spinner and prints the final status line for its step.
+func (s *spinner) Stop(err error) {
+	if s.animated {
+		close(s.stop)
+	}
+	<-s.done
+	if err != nil {
+		s.step.endf("failed")
+		printStatus(tagFail, "%s: %v", s.msg, err)
+		return
+	}
+	s.step.end()
+	if stderrIsTTY {
+		printStatus(tagOK, "%s", s.msg)
+	}
+}
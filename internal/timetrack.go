@@ -0,0 +1,231 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// timeInterval is one tracked work session on a feature. End is nil while
+// the timer is running.
+type timeInterval struct {
+	Project string     `json:"project,omitempty"` // empty in logs from before projects were recorded
+	Feature string     `json:"feature"`
+	User    string     `json:"user,omitempty"`
+	Start   time.Time  `json:"start"`
+	End     *time.Time `json:"end,omitempty"`
+}
+
+// running reports whether the interval has not been stopped.
+func (iv *timeInterval) running() bool {
+	return iv.End == nil
+}
+
+// duration returns the interval length, measured to now while running.
+func (iv *timeInterval) duration(now time.Time) time.Duration {
+	if iv.running() {
+		return now.Sub(iv.Start)
+	}
+	return iv.End.Sub(iv.Start)
+}
+
+// timeLog is stored in .projects/.timelog.json.
+type timeLog struct {
+	Intervals []*timeInterval `json:"intervals"`
+}
+
+func timeLogPath(workspace string) string {
+	return filepath.Join(workspace, ".projects", ".timelog.json")
+}
+
+func loadTimeLog(workspace string) *timeLog {
+	log := &timeLog{}
+	data, err := os.ReadFile(timeLogPath(workspace))
+	if err != nil {
+		return log
+	}
+	if err := json.Unmarshal(data, log); err != nil {
+		fatal("parse %s: %v", timeLogPath(workspace), err)
+	}
+	return log
+}
+
+func saveTimeLog(workspace string, log *timeLog) {
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		fatal("encode time log: %v", err)
+	}
+	if err := writeFileAtomic(timeLogPath(workspace), append(data, '\n'), 0644); err != nil {
+		fatal("write time log: %v", err)
+	}
+}
+
+// trackedTime sums the time spent per feature, keyed by featureKey.
+// Intervals without a project are keyed with an empty one.
+func (l *timeLog) trackedTime(now time.Time) map[string]time.Duration {
+	totals := make(map[string]time.Duration)
+	for _, iv := range l.Intervals {
+		totals[featureKey(iv.Project, iv.Feature)] += iv.duration(now)
+	}
+	return totals
+}
+
+// trackedFor returns the time spent on f, counting intervals recorded
+// before projects were, which name only its ID.
+func trackedFor(totals map[string]time.Duration, f *feature) time.Duration {
+	total := totals[featureKey("", f.ID)]
+	if f.Project != "" {
+		total += totals[featureKey(f.Project, f.ID)]
+	}
+	return total
+}
+
+// runningFor returns the open interval for f, if any.
+func (l *timeLog) runningFor(f *feature) *timeInterval {
+	for _, iv := range l.Intervals {
+		if iv.running() && iv.Feature == f.ID && (iv.Project == f.Project || iv.Project == "") {
+			return iv
+		}
+	}
+	return nil
+}
+
+// currentUser names who is tracking time, for shared workspaces.
+func currentUser() string {
+	for _, env := range []string{"ORCHESTRA_USER", "USER", "USERNAME"} {
+		if u := os.Getenv(env); u != "" {
+			return u
+		}
+	}
+	return ""
+}
+
+// --- start ---
+
+func runTimeStart(args []string) {
+	fs := newFlagSet("time start")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	featureID := fs.String("feature", "", "Feature ID to track (required)")
+	parseFlags(fs, args)
+
+	absWorkspace, f := resolveTimeFeature(*workspace, *featureID, "start")
+	checkWorkspaceSchema(absWorkspace, true)
+
+	log := loadTimeLog(absWorkspace)
+	if iv := log.runningFor(f); iv != nil {
+		fatal("timer for %s already running since %s", f.ID, iv.Start.Local().Format("15:04"))
+	}
+	log.Intervals = append(log.Intervals, &timeInterval{
+		Project: f.Project,
+		Feature: f.ID,
+		User:    currentUser(),
+		Start:   time.Now().UTC().Truncate(time.Second),
+	})
+	saveTimeLog(absWorkspace, log)
+	printStatus(tagOK, "Started timer for %s — %s", f.ID, f.Title)
+}
+
+// --- stop ---
+
+func runTimeStop(args []string) {
+	fs := newFlagSet("time stop")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	featureID := fs.String("feature", "", "Feature ID to stop (default: the only running timer)")
+	parseFlags(fs, args)
+
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	checkWorkspaceSchema(absWorkspace, true)
+
+	log := loadTimeLog(absWorkspace)
+	var iv *timeInterval
+	if *featureID != "" {
+		f, err := findFeature(absWorkspace, *featureID)
+		if err != nil {
+			fatal("%v", err)
+		}
+		if iv = log.runningFor(f); iv == nil {
+			fatal("no timer running for %s", f.ID)
+		}
+	} else {
+		var open []*timeInterval
+		for _, candidate := range log.Intervals {
+			if candidate.running() {
+				open = append(open, candidate)
+			}
+		}
+		switch len(open) {
+		case 0:
+			fatal("no timer running")
+		case 1:
+			iv = open[0]
+		default:
+			ids := make([]string, len(open))
+			for i, o := range open {
+				ids[i] = o.Feature
+			}
+			fatal("%d timers running (%s); pass --feature=ID", len(open), strings.Join(ids, ", "))
+		}
+	}
+
+	end := time.Now().UTC().Truncate(time.Second)
+	iv.End = &end
+	saveTimeLog(absWorkspace, log)
+	printStatus(tagOK, "Stopped timer for %s after %s (total %s)",
+		iv.Feature, formatHours(iv.duration(end)), formatHours(trackedFor(log.trackedTime(end), &feature{Project: iv.Project, ID: iv.Feature})))
+}
+
+// --- status ---
+
+func runTimeStatus(args []string) {
+	fs := newFlagSet("time status")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	parseFlags(fs, args)
+
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+
+	now := time.Now()
+	log := loadTimeLog(absWorkspace)
+	tw := newTable(os.Stdout)
+	found := false
+	for _, iv := range log.Intervals {
+		if !iv.running() {
+			continue
+		}
+		found = true
+		fmt.Fprintf(tw, "  %s\t%s\tsince %s\t%s\n", iv.Feature, iv.User, iv.Start.Local().Format("2006-01-02 15:04"), formatHours(iv.duration(now)))
+	}
+	tw.Flush()
+	if !found {
+		fmt.Fprintf(os.Stderr, "No timers running.\n")
+	}
+}
+
+// resolveTimeFeature resolves the workspace and the --feature flag for verb.
+func resolveTimeFeature(workspace, id, verb string) (string, *feature) {
+	if id == "" {
+		fatal("usage: orchestra time %s --feature=ID", verb)
+	}
+	absWorkspace, err := resolveWorkspace(workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	f, err := findFeature(absWorkspace, id)
+	if err != nil {
+		fatal("%v", err)
+	}
+	return absWorkspace, f
+}
+
+// formatHours renders a duration as decimal hours, e.g. "1.5h".
+func formatHours(d time.Duration) string {
+	return fmt.Sprintf("%.1fh", d.Hours())
+}
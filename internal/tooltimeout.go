@@ -0,0 +1,205 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A third-party tool that hangs would otherwise freeze the agent's session
+// until the IDE gives up. serve's stdio bridge therefore times every
+// tools/call: when one runs past its limit, it sends the backend
+// notifications/cancelled for the request (the orchestrator passes it to the
+// plugin), answers the IDE with a timeout error, and drops the late reply
+// if one still arrives. Timeouts are logged and counted in the session's
+// metrics, which `orchestra status` shows.
+
+// defaultToolTimeout is --tool-timeout's default.
+const defaultToolTimeout = 5 * time.Minute
+
+// rpcRequestTimeout is the JSON-RPC error code MCP SDKs use for a request
+// that timed out.
+const rpcRequestTimeout = -32001
+
+// metricsFlushInterval is how often changed metrics are written to the
+// serve record.
+const metricsFlushInterval = 5 * time.Second
+
+// toolTimeouts is the limit for each tool call: per-tool overrides from
+// .orchestra.yaml, else the default. A zero duration means no limit.
+type toolTimeouts struct {
+	def      time.Duration
+	exact    map[string]time.Duration
+	patterns []toolTimeoutPattern // longest first
+}
+
+type toolTimeoutPattern struct {
+	pattern string
+	limit   time.Duration
+}
+
+// loadToolTimeouts reads tool_timeouts from the workspace's .orchestra.yaml
+// on top of def.
+func loadToolTimeouts(workspace string, def time.Duration) (toolTimeouts, error) {
+	t := toolTimeouts{def: def, exact: map[string]time.Duration{}}
+	if def < 0 {
+		return t, fmt.Errorf("--tool-timeout must not be negative")
+	}
+	for name, value := range loadWorkspaceConfig(workspace).ToolTimeouts {
+		limit, err := time.ParseDuration(value)
+		if value == "0" {
+			limit, err = 0, nil
+		}
+		if err != nil || limit < 0 {
+			return t, fmt.Errorf("%s: tool_timeouts: %s: invalid duration %q", workspaceConfigFile, name, value)
+		}
+		if _, err := path.Match(name, ""); err != nil {
+			return t, fmt.Errorf("%s: tool_timeouts: bad pattern %q: %v", workspaceConfigFile, name, err)
+		}
+		if isGlob(name) {
+			t.patterns = append(t.patterns, toolTimeoutPattern{name, limit})
+		} else {
+			t.exact[name] = limit
+		}
+	}
+	sort.Slice(t.patterns, func(i, j int) bool {
+		if len(t.patterns[i].pattern) != len(t.patterns[j].pattern) {
+			return len(t.patterns[i].pattern) > len(t.patterns[j].pattern)
+		}
+		return t.patterns[i].pattern < t.patterns[j].pattern
+	})
+	return t, nil
+}
+
+func isGlob(name string) bool {
+	for _, c := range name {
+		if c == '*' || c == '?' || c == '[' {
+			return true
+		}
+	}
+	return false
+}
+
+// forTool returns the limit for a call to tool: an exact override, then
+// the longest matching pattern, then the default.
+func (t toolTimeouts) forTool(tool string) time.Duration {
+	if limit, ok := t.exact[tool]; ok {
+		return limit
+	}
+	for _, p := range t.patterns {
+		if ok, _ := path.Match(p.pattern, tool); ok {
+			return p.limit
+		}
+	}
+	return t.def
+}
+
+// --- metrics ---
+
+// serveMetrics counts a session's tool calls.
+type serveMetrics struct {
+	mu       sync.Mutex
+	calls    int
+	timeouts map[string]int // tool -> calls that timed out
+	dirty    bool
+}
+
+// serveMetricsSnapshot is serveMetrics as stored in the serve record.
+type serveMetricsSnapshot struct {
+	ToolCalls    int            `json:"tool_calls"`
+	ToolTimeouts int            `json:"tool_timeouts"`
+	TimedOut     map[string]int `json:"timed_out,omitempty"` // tool -> timeouts
+}
+
+func (m *serveMetrics) countCall() {
+	m.mu.Lock()
+	m.calls++
+	m.dirty = true
+	m.mu.Unlock()
+}
+
+func (m *serveMetrics) countTimeout(tool string) {
+	m.mu.Lock()
+	if m.timeouts == nil {
+		m.timeouts = map[string]int{}
+	}
+	m.timeouts[tool]++
+	m.dirty = true
+	m.mu.Unlock()
+}
+
+func (m *serveMetrics) snapshot() *serveMetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	snap := &serveMetricsSnapshot{ToolCalls: m.calls}
+	for tool, n := range m.timeouts {
+		if snap.TimedOut == nil {
+			snap.TimedOut = map[string]int{}
+		}
+		snap.TimedOut[tool] = n
+		snap.ToolTimeouts += n
+	}
+	return snap
+}
+
+// takeDirty reports whether the metrics changed since the last call.
+func (m *serveMetrics) takeDirty() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	dirty := m.dirty
+	m.dirty = false
+	return dirty
+}
+
+// flushMetrics writes changed metrics into the session's record every
+// metricsFlushInterval until stop closes. Only the metrics are replaced,
+// so a switch result in the record is left for `serve switch` to read.
+func (s *serveSession) flushMetrics(stop <-chan struct{}) {
+	ticker := time.NewTicker(metricsFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		if !s.metrics.takeDirty() {
+			continue
+		}
+		s.mu.Lock()
+		path := serveRecordPath(os.Getpid())
+		if data, err := os.ReadFile(path); err == nil {
+			var rec serveRecord
+			if json.Unmarshal(data, &rec) == nil {
+				rec.Metrics = s.metrics.snapshot()
+				writeServeRecord(rec)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// describeMetrics summarizes a session's metrics for status.
+func describeMetrics(m *serveMetricsSnapshot) string {
+	if m == nil {
+		return ""
+	}
+	text := fmt.Sprintf("%d tool call(s)", m.ToolCalls)
+	if m.ToolTimeouts == 0 {
+		return text
+	}
+	tools := make([]string, 0, len(m.TimedOut))
+	for tool := range m.TimedOut {
+		tools = append(tools, tool)
+	}
+	sortNames(tools)
+	for i, tool := range tools {
+		tools[i] = fmt.Sprintf("%s %d", tool, m.TimedOut[tool])
+	}
+	return fmt.Sprintf("%s, %d timed out: %s", text, m.ToolTimeouts, strings.Join(tools, ", "))
+}
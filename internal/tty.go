@@ -0,0 +1,43 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// enterRawMode switches the controlling terminal to unbuffered, no-echo
+// input for interactive screens. The returned function restores the
+// previous settings.
+func enterRawMode() (restore func(), err error) {
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, err
+	}
+	// -isig delivers Ctrl-C as a key so the screen can restore the terminal.
+	if _, err := stty("-icanon", "-echo", "-isig", "min", "1"); err != nil {
+		return nil, err
+	}
+	return func() { stty(strings.TrimSpace(saved)) }, nil
+}
+
+// terminalSize returns the terminal's rows and columns, or 24x80 when it
+// cannot be determined.
+func terminalSize() (rows, cols int) {
+	out, err := stty("size")
+	if err == nil {
+		if n, _ := fmt.Sscanf(out, "%d %d", &rows, &cols); n == 2 && rows > 0 && cols > 0 {
+			return rows, cols
+		}
+	}
+	return 24, 80
+}
+
+// stty runs stty against the terminal on stdin.
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
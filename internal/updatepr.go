@@ -0,0 +1,242 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// prDiffLines caps the content diff included in an update pull request;
+// GitHub shows the full diff anyway.
+const prDiffLines = 400
+
+// pluginBump is a registered plugin with a newer release.
+type pluginBump struct {
+	ID, Repo, From, To string
+}
+
+// runUpdatePR handles `orchestra update --pr`: it updates every pack on a
+// new branch, commits the result, pushes it, and opens a GitHub pull
+// request summarizing the version bumps and content changes. The working
+// tree is returned to the original branch afterwards.
+func runUpdatePR(workspace, remote, base string, draft bool) {
+	absWorkspace, err := resolveWorkspace(workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	checkWorkspaceSchema(absWorkspace, true)
+
+	token := githubToken()
+	if token == "" {
+		fatal("--pr needs a GitHub token: set ORCHESTRA_GITHUB_TOKEN or GITHUB_TOKEN, or run 'orchestra setup'")
+	}
+	commit := newGitCommit(absWorkspace, defaultGitMessage)
+	remoteURL := commit.git("remote", "get-url", remote)
+	ownerRepo, ok := githubRepoFromRemote(remoteURL)
+	if !ok {
+		fatal("--pr: remote %q (%s) is not a GitHub repository", remote, orDash(remoteURL))
+	}
+	current := commit.git("rev-parse", "--abbrev-ref", "HEAD")
+	if current == "" || current == "HEAD" {
+		fatal("--pr: check out a branch first (HEAD is detached)")
+	}
+	if base == "" {
+		base = current
+	}
+
+	reg := loadPackRegistry(absWorkspace)
+	if len(reg.Packs) == 0 {
+		fmt.Fprintf(os.Stderr, "No packs installed to update.\n")
+		return
+	}
+
+	branch := "orchestra/update-" + time.Now().Format("20060102-150405")
+	if out, err := commit.run("checkout", "--quiet", "-b", branch); err != nil {
+		fatal("create branch %s: %v\n%s", branch, err, out)
+	}
+	restore := func() {
+		commit.run("checkout", "--quiet", current)
+	}
+	fail := func(format string, args ...any) {
+		restore()
+		commit.run("branch", "-D", branch)
+		fatal(format, args...)
+	}
+
+	// Post-install scripts can change anything; they run when each
+	// teammate updates locally, not in the pull request.
+	bumps := updatePacks(absWorkspace, reg, reg.Packs, packInstallOptions{SkipPostInstall: true}, false)
+	var refs []string
+	for _, b := range bumps {
+		refs = append(refs, packRef(b.Name, b.To))
+	}
+	target := strings.Join(refs, ", ")
+	if !commit.commit("update", target) {
+		restore()
+		commit.run("branch", "-D", branch)
+		if failed := len(reg.Packs) - len(bumps); failed > 0 {
+			fatal("%d pack(s) could not be updated; no pull request opened", failed)
+		}
+		printStatus(tagOK, "packs are up to date; no pull request needed")
+		return
+	}
+
+	stat := commit.git("diff", "--stat", current+"..."+branch)
+	diff := commit.git("diff", current+"..."+branch, "--", ".claude")
+
+	sp := startSpinner(fmt.Sprintf("Pushing %s to %s", branch, remote))
+	out, err := commit.run("push", "--quiet", "--set-upstream", remote, branch)
+	sp.Stop(err)
+	if err != nil {
+		fail("push %s: %v\n%s", branch, err, out)
+	}
+	restore()
+
+	title := strings.NewReplacer("{action}", "update", "{target}", target).Replace(defaultGitMessage)
+	body := updatePRBody(bumps, pluginBumps(), stat, diff)
+	url, err := createPullRequest(ownerRepo, token, title, body, branch, base, draft)
+	if err != nil {
+		fatal("open pull request: %v (branch %s is pushed; open it by hand)", err, branch)
+	}
+	printStatus(tagOK, "opened %s", url)
+	fmt.Println(url)
+}
+
+// pluginBumps returns registered plugins whose repo has a newer release.
+func pluginBumps() []pluginBump {
+	reg, err := LoadRegistry()
+	if err != nil {
+		return nil
+	}
+	var out []pluginBump
+	for _, p := range reg.Plugins {
+		ownerRepo, err := githubOwnerRepo(p.Repo)
+		if err != nil {
+			continue
+		}
+		if latest := latestReleaseTag(ownerRepo); latest != "" && isNewerVersion(p.Version, latest) {
+			out = append(out, pluginBump{ID: p.ID, Repo: p.Repo, From: p.Version, To: latest})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// updatePRBody renders the pull request description: pack bumps, content
+// removed, plugin releases to install locally, and the content diff.
+func updatePRBody(bumps []packBump, plugins []pluginBump, stat, diff string) string {
+	var b strings.Builder
+	b.WriteString("Updates the Orchestra packs installed in this repository.\n\n")
+	b.WriteString("| Pack | From | To |\n|---|---|---|\n")
+	for _, p := range bumps {
+		fmt.Fprintf(&b, "| `%s` | %s | %s |\n", p.Name, orDash(p.From), p.To)
+	}
+
+	var removed []string
+	for _, p := range bumps {
+		if p.Removal == nil {
+			continue
+		}
+		removed = append(removed, p.Removal.Files...)
+	}
+	if len(removed) > 0 {
+		b.WriteString("\n**Removed** (no longer shipped by the new versions):\n\n")
+		for _, f := range removed {
+			fmt.Fprintf(&b, "- `%s`\n", f)
+		}
+	}
+
+	if len(plugins) > 0 {
+		b.WriteString("\n**Plugin releases.** Plugins are installed per machine, so they are not part of this pull request. Run `orchestra update <plugin>` to pick these up:\n\n")
+		b.WriteString("| Plugin | Installed | Latest |\n|---|---|---|\n")
+		for _, p := range plugins {
+			fmt.Fprintf(&b, "| `%s` | %s | %s |\n", p.ID, p.From, p.To)
+		}
+	}
+
+	b.WriteString("\nPost-install scripts were not run. After merging, run `orchestra pack update` locally if a pack needs its script.\n")
+
+	if stat != "" {
+		fmt.Fprintf(&b, "\n### Changes\n\n```\n%s\n```\n", stat)
+	}
+	if diff != "" {
+		lines := strings.Split(diff, "\n")
+		if len(lines) > prDiffLines {
+			lines = append(lines[:prDiffLines], fmt.Sprintf("... %d more lines; see the Files tab", len(lines)-prDiffLines))
+		}
+		fmt.Fprintf(&b, "\n<details><summary>Content diff (.claude/)</summary>\n\n```diff\n%s\n```\n\n</details>\n", strings.Join(lines, "\n"))
+	}
+	return b.String()
+}
+
+// createPullRequest opens a pull request on ownerRepo and returns its URL.
+func createPullRequest(ownerRepo, token, title, body, head, base string, draft bool) (string, error) {
+	payload, _ := json.Marshal(map[string]any{
+		"title": title,
+		"body":  body,
+		"head":  head,
+		"base":  base,
+		"draft": draft,
+	})
+	req, err := http.NewRequest(http.MethodPost, "https://api.github.com/repos/"+ownerRepo+"/pulls", bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := (&http.Client{Timeout: 30 * time.Second}).Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		HTMLURL string `json:"html_url"`
+		Message string `json:"message"`
+		Errors  []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	json.NewDecoder(resp.Body).Decode(&result)
+	if resp.StatusCode != http.StatusCreated {
+		msg := result.Message
+		for _, e := range result.Errors {
+			msg += "; " + e.Message
+		}
+		return "", fmt.Errorf("GitHub returned %s: %s", resp.Status, msg)
+	}
+	return result.HTMLURL, nil
+}
+
+// githubRepoFromRemote extracts owner/repo from a GitHub remote URL in
+// any of the forms git accepts (https, ssh, scp-like).
+func githubRepoFromRemote(url string) (string, bool) {
+	url = strings.TrimSuffix(strings.TrimSpace(url), "/")
+	url = strings.TrimSuffix(url, ".git")
+	for _, prefix := range []string{"git@github.com:", "ssh://git@github.com/", "https://", "http://"} {
+		if !strings.HasPrefix(url, prefix) {
+			continue
+		}
+		rest := strings.TrimPrefix(url, prefix)
+		if strings.HasPrefix(prefix, "http") {
+			if at := strings.Index(rest, "@"); at >= 0 && at < strings.Index(rest+"/", "/") {
+				rest = rest[at+1:] // credentials
+			}
+			if !strings.HasPrefix(rest, "github.com/") {
+				return "", false
+			}
+			rest = strings.TrimPrefix(rest, "github.com/")
+		}
+		if parts := strings.Split(rest, "/"); len(parts) == 2 && parts[0] != "" && parts[1] != "" {
+			return rest, true
+		}
+		return "", false
+	}
+	return "", false
+}
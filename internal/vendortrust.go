@@ -0,0 +1,169 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Vendored plugin trust. A workspace's .orchestra/plugins.json arrives
+// with a clone, and IDE configs start serve as soon as the workspace is
+// opened, so serve runs vendored plugins only once the user has trusted
+// them, as with .orchestra.yaml hooks. Trust is recorded per workspace
+// against a hash of plugins.json, which holds each binary's sha256, so
+// any change to it asks again. Even when trusted, serve refuses a
+// vendored binary outside .orchestra/bin/ or one that does not match its
+// recorded sha256.
+
+// pluginTrust is ~/.orchestra/trusted-plugins.json: the hash of the
+// trusted plugins.json by workspace.
+type pluginTrust struct {
+	Workspaces map[string]string `json:"workspaces"`
+}
+
+func pluginTrustPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".orchestra", "trusted-plugins.json")
+}
+
+func loadPluginTrust() *pluginTrust {
+	t := &pluginTrust{}
+	if data, err := os.ReadFile(pluginTrustPath()); err == nil {
+		json.Unmarshal(data, t)
+	}
+	if t.Workspaces == nil {
+		t.Workspaces = map[string]string{}
+	}
+	return t
+}
+
+func (t *pluginTrust) save() error {
+	if err := os.MkdirAll(filepath.Dir(pluginTrustPath()), 0755); err != nil {
+		return err
+	}
+	data, _ := json.MarshalIndent(t, "", "  ")
+	return writeFileAtomic(pluginTrustPath(), append(data, '\n'), 0600)
+}
+
+// vendorRegistryHash identifies workspace's plugins.json for trust; "" when
+// there is none.
+func vendorRegistryHash(workspace string) string {
+	data, err := os.ReadFile(vendorRegistryPath(workspace))
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// vendoredPluginsTrusted reports whether workspace's vendored plugins, as
+// plugins.json records them now, have been trusted. A workspace without
+// plugins.json has nothing to trust.
+func vendoredPluginsTrusted(workspace string) bool {
+	hash := vendorRegistryHash(workspace)
+	return hash == "" || loadPluginTrust().Workspaces[workspace] == hash
+}
+
+func trustVendoredPlugins(workspace string) error {
+	t := loadPluginTrust()
+	t.Workspaces[workspace] = vendorRegistryHash(workspace)
+	return t.save()
+}
+
+// vendoredBinaryProblem returns why serve must not run vendored plugin p
+// of workspace, or "": its binary is outside .orchestra/bin/, has no
+// recorded sha256, or does not match it.
+func vendoredBinaryProblem(workspace string, p *PluginEntry) string {
+	binDir := vendorBinDir(workspace)
+	inside := func(dir, path string) bool {
+		rel, err := filepath.Rel(dir, path)
+		return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+	}
+	if !inside(binDir, filepath.Clean(p.Binary)) {
+		return fmt.Sprintf("binary %s is outside %s", p.Binary, binDir)
+	}
+	// A symlink in .orchestra/bin/ could point anywhere.
+	realBin, err := filepath.EvalSymlinks(p.Binary)
+	if err != nil {
+		return "binary missing: " + p.Binary
+	}
+	if realDir, err := filepath.EvalSymlinks(binDir); err != nil || !inside(realDir, realBin) {
+		return fmt.Sprintf("binary %s links outside %s", p.Binary, binDir)
+	}
+	if p.SHA256 == "" {
+		return "no sha256 recorded for its binary; reinstall it with 'orchestra install --vendor'"
+	}
+	sum, err := fileSHA256(p.Binary)
+	if err != nil {
+		return fmt.Sprintf("hash binary: %v", err)
+	}
+	if sum != p.SHA256 {
+		return fmt.Sprintf("binary sha256 %s does not match the recorded %s", sum[:12], p.SHA256[:min(12, len(p.SHA256))])
+	}
+	return ""
+}
+
+// runPluginsTrust handles `orchestra plugins trust` -- lets serve run the
+// workspace's vendored plugins, or with --revoke stops it.
+func runPluginsTrust(args []string) {
+	fs := newFlagSet("plugins trust")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	yes := fs.Bool("yes", false, "Trust without asking")
+	revoke := fs.Bool("revoke", false, "Stop trusting the workspace's vendored plugins")
+	parseFlags(fs, args)
+
+	absWorkspace, err := resolveWorkspace(*workspace)
+	if err != nil {
+		fatal("resolve workspace: %v", err)
+	}
+	if *revoke {
+		t := loadPluginTrust()
+		if _, ok := t.Workspaces[absWorkspace]; !ok {
+			printStatus(tagSkip, "vendored plugins in %s were not trusted", absWorkspace)
+			return
+		}
+		delete(t.Workspaces, absWorkspace)
+		if err := t.save(); err != nil {
+			fatal("save %s: %v", pluginTrustPath(), err)
+		}
+		printStatus(tagOK, "no longer trusting vendored plugins in %s", absWorkspace)
+		return
+	}
+
+	reg, err := loadVendorRegistry(absWorkspace)
+	if err != nil {
+		fatal("%v", err)
+	}
+	if len(reg.Plugins) == 0 {
+		printStatus(tagSkip, "%s vendors no plugins", absWorkspace)
+		return
+	}
+	if vendoredPluginsTrusted(absWorkspace) {
+		printStatus(tagSkip, "vendored plugins in %s are already trusted", absWorkspace)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%s vendors:\n", vendorRegistryPath(absWorkspace))
+	for _, p := range sortedPlugins(reg) {
+		fmt.Fprintf(os.Stderr, "    %-24s %-10s %s\n", p.ID, orDash(p.Version), orDash(p.Repo))
+		fmt.Fprintf(os.Stderr, "    %-24s binary %s\n", "", displayPath(absWorkspace, p.Binary))
+		if problem := vendoredBinaryProblem(absWorkspace, p); problem != "" {
+			fmt.Fprintf(os.Stderr, "    %-24s %s; serve will refuse it\n", "", problem)
+		}
+	}
+	if !*yes {
+		if !isTerminal(os.Stdin) {
+			fatal("no terminal to confirm; rerun with --yes")
+		}
+		if !confirm("Let serve run these plugins for this workspace?") {
+			fatal("not trusted")
+		}
+	}
+	if err := trustVendoredPlugins(absWorkspace); err != nil {
+		fatal("save %s: %v", pluginTrustPath(), err)
+	}
+	printStatus(tagOK, "trusted vendored plugins in %s; any change to %s asks again", absWorkspace, filepath.Base(vendorRegistryPath(absWorkspace)))
+}
@@ -0,0 +1,152 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// verifyTimeout bounds how long install waits for tools/list once the
+// scratch orchestrator is up.
+const verifyTimeout = 15 * time.Second
+
+// toolsVerification compares the tools a plugin exposed with the ones its
+// manifest claims.
+type toolsVerification struct {
+	Tools   []string // exposed, sorted
+	Missing []string // claimed but not exposed
+	Extra   []string // exposed but not claimed
+}
+
+// isToolsPlugin reports whether install should verify p's tools.
+func isToolsPlugin(p *PluginEntry) bool {
+	return len(p.ProvidesTools) > 0 || strings.HasPrefix(p.ID, "tools.")
+}
+
+// verifyPluginTools boots p under a scratch orchestrator (with markdown
+// storage on an empty workspace, so no project is touched), asks it for
+// tools/list through transport-stdio, and compares the answer with the
+// manifest. The orchestrator log is kept in the temp directory when
+// verification fails.
+func verifyPluginTools(p *PluginEntry) (*toolsVerification, error) {
+	defer debugStep("tools/list verification of %s", p.ID).end()
+	bins, err := siblingBins()
+	if err != nil {
+		return nil, err
+	}
+	scratch, err := os.MkdirTemp("", "orchestra-verify-*")
+	if err != nil {
+		return nil, fmt.Errorf("create scratch workspace: %w", err)
+	}
+	defer os.RemoveAll(scratch)
+
+	lf, err := os.CreateTemp("", "orchestra-verify-*.log")
+	if err != nil {
+		return nil, fmt.Errorf("create log: %w", err)
+	}
+	defer lf.Close()
+	logFile := lf.Name()
+	fail := func(err error) (*toolsVerification, error) {
+		return nil, fmt.Errorf("%w (log: %s)", err, logFile)
+	}
+
+	certsDir := defaultCertsDir()
+	cfg := orchestratorConfig{
+		ListenAddr: "localhost:0",
+		CertsDir:   certsDir,
+		Plugins: []pluginConfig{
+			{
+				ID:              "storage.markdown",
+				Binary:          bins["storage-markdown"],
+				Enabled:         true,
+				ProvidesStorage: []string{"markdown"},
+				Args:            []string{fmt.Sprintf("--workspace=%s", scratch)},
+			},
+			registeredPluginConfig(p, scratch),
+		},
+	}
+	log := newServeLog(lf)
+	backend, err := startOrchestrator(bins["orchestrator"], cfg, nil, logFile, log, len(cfg.Plugins))
+	defer backend.stop()
+	if err != nil {
+		return fail(err)
+	}
+
+	tools, err := listToolsVia(bins["transport-stdio"], backend.addr, certsDir, log)
+	if err != nil {
+		return fail(err)
+	}
+	os.Remove(logFile)
+	return compareTools(p.ProvidesTools, tools), nil
+}
+
+// listToolsVia runs transport-stdio against the orchestrator at addr and
+// returns the tools it lists.
+func listToolsVia(transportBin, addr, certsDir string, log *serveLog) ([]string, error) {
+	cmd := exec.Command(transportBin, transportArgs(addr, certsDir)...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stderr = log.source(logSourceTransport)
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start %s: %w", filepath.Base(transportBin), err)
+	}
+	defer func() {
+		stdin.Close()
+		cmd.Process.Kill()
+		cmd.Wait()
+	}()
+
+	type answer struct {
+		tools []string
+		err   error
+	}
+	done := make(chan answer, 1)
+	go func() {
+		c := newMCPClient(stdin, stdout)
+		if err := c.initialize(); err != nil {
+			done <- answer{err: err}
+			return
+		}
+		tools, err := c.listTools()
+		done <- answer{tools, err}
+	}()
+	select {
+	case a := <-done:
+		return a.tools, a.err
+	case <-time.After(verifyTimeout):
+		return nil, fmt.Errorf("no tools/list answer within %s", verifyTimeout)
+	}
+}
+
+// compareTools diffs the claimed tool names against the exposed ones.
+func compareTools(claimed, exposed []string) *toolsVerification {
+	v := &toolsVerification{Tools: append([]string(nil), exposed...)}
+	sort.Strings(v.Tools)
+	have := make(map[string]bool, len(exposed))
+	for _, t := range exposed {
+		have[t] = true
+	}
+	want := make(map[string]bool, len(claimed))
+	for _, t := range claimed {
+		want[t] = true
+		if !have[t] {
+			v.Missing = append(v.Missing, t)
+		}
+	}
+	for _, t := range v.Tools {
+		if !want[t] {
+			v.Extra = append(v.Extra, t)
+		}
+	}
+	return v
+}
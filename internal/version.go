@@ -2,6 +2,7 @@ package internal
 
 import (
 	"fmt"
+	"os"
 	"runtime"
 )
 
@@ -11,6 +12,38 @@ var (
 	Date    = "unknown"
 )
 
-func RunVersion() {
-	fmt.Printf("orchestra %s (%s/%s, commit %s, built %s)\n", Version, runtime.GOOS, runtime.GOARCH, Commit, Date)
+// RunVersion handles `orchestra version`.
+func RunVersion(args []string) {
+	fs := newFlagSet("version")
+	porcelain := fs.Bool("porcelain", false, "Stable tab-separated output for scripts")
+	parseFlags(fs, args)
+
+	// Provenance is what `orchestra update` found for this release; builds
+	// installed any other way have none recorded.
+	prov := loadSelfProvenance()
+
+	if jsonOutput() {
+		printJSON(struct {
+			Version    string            `json:"version"`
+			Commit     string            `json:"commit"`
+			Date       string            `json:"date"`
+			OS         string            `json:"os"`
+			Arch       string            `json:"arch"`
+			Provenance *provenanceResult `json:"provenance"`
+		}{Version, Commit, Date, runtime.GOOS, runtime.GOARCH, prov})
+		return
+	}
+	// Porcelain: version, commit, date, os/arch, provenance status.
+	if *porcelain {
+		status := "-"
+		if prov != nil {
+			status = prov.Status
+		}
+		fmt.Fprintf(os.Stdout, "%s\t%s\t%s\t%s/%s\t%s\n", Version, Commit, Date, runtime.GOOS, runtime.GOARCH, status)
+		return
+	}
+	fmt.Fprintf(os.Stdout, "orchestra %s (%s/%s, commit %s, built %s)\n", Version, runtime.GOOS, runtime.GOARCH, Commit, Date)
+	if prov != nil {
+		fmt.Fprintf(os.Stdout, "provenance: %s\n", prov)
+	}
 }
@@ -0,0 +1,194 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/orchestra-mcp/cli/pkg/packs"
+)
+
+// whyMatch is one answer to `orchestra why`: something named like the
+// query and where it came from.
+type whyMatch struct {
+	Kind      string // skill, agent, hook, tool, prompt, resource, storage
+	Name      string
+	From      string // pack, plugin, built-in, or unmanaged
+	Source    string // pack name or plugin ID; "orchestra" for built-ins
+	Version   string
+	Repo      string
+	Installed string
+	Path      string // workspace-relative file, for pack content
+}
+
+// RunWhy handles `orchestra why <name>` -- reports which pack or plugin
+// provides a skill, agent, hook, tool, prompt, resource, or storage.
+func RunWhy(args []string) {
+	fs := newFlagSet("why")
+	workspace := fs.String("workspace", ".", "Project workspace directory")
+	porcelain := fs.Bool("porcelain", false, "Stable tab-separated output for scripts")
+	parseFlags(fs, args)
+
+	if fs.NArg() < 1 {
+		fatal("usage: orchestra why <skill|agent|hook|tool|prompt> [--workspace=DIR]")
+	}
+	absWorkspace, _ := resolveWorkspace(*workspace)
+	name := whyName(fs.Arg(0))
+	matches := findWhy(absWorkspace, name)
+
+	// Porcelain: kind, name, from, source, version, repo, installed, path.
+	if *porcelain {
+		for _, m := range matches {
+			fmt.Fprintf(os.Stdout, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				m.Kind, m.Name, m.From, m.Source, orDash(m.Version), orDash(m.Repo), orDash(m.Installed), orDash(m.Path))
+		}
+		if len(matches) == 0 {
+			os.Exit(1)
+		}
+		return
+	}
+	if len(matches) == 0 {
+		fatal("nothing named %q is installed in %s or as a plugin", name, absWorkspace)
+	}
+	for i, m := range matches {
+		if i > 0 {
+			fmt.Fprintln(os.Stdout)
+		}
+		switch m.From {
+		case "pack":
+			fmt.Fprintf(os.Stdout, "%s %s comes from pack %s\n", m.Kind, m.Name, m.Source)
+		case "plugin":
+			fmt.Fprintf(os.Stdout, "%s %s comes from plugin %s\n", m.Kind, m.Name, m.Source)
+		case "built-in":
+			fmt.Fprintf(os.Stdout, "%s %s is built into orchestra (%s)\n", m.Kind, m.Name, m.Source)
+		default:
+			fmt.Fprintf(os.Stdout, "%s %s is not managed by orchestra (added by hand or by another tool)\n", m.Kind, m.Name)
+		}
+		if m.Version != "" {
+			fmt.Fprintf(os.Stdout, "  Version:    %s\n", m.Version)
+		}
+		if m.Repo != "" {
+			fmt.Fprintf(os.Stdout, "  Repo:       %s\n", m.Repo)
+		}
+		if m.Installed != "" {
+			fmt.Fprintf(os.Stdout, "  Installed:  %s\n", m.Installed)
+		}
+		if m.Path != "" {
+			fmt.Fprintf(os.Stdout, "  Path:       %s\n", m.Path)
+		}
+	}
+}
+
+// whyName strips what IDEs add around a name: the slash of a slash
+// command, the mcp__<server>__ prefix of a tool, and a file extension.
+func whyName(s string) string {
+	s = strings.TrimPrefix(s, "/")
+	if rest, ok := strings.CutPrefix(s, "mcp__"); ok {
+		if _, tool, ok := strings.Cut(rest, "__"); ok {
+			s = tool
+		}
+	}
+	for _, ext := range append([]string{".md"}, packs.HookExts...) {
+		s = strings.TrimSuffix(s, ext)
+	}
+	return s
+}
+
+// findWhy collects every match for name: pack content in the workspace,
+// the content orchestra init adds, built-in tools, and what installed
+// plugins (vendored first) provide.
+func findWhy(workspace, name string) []whyMatch {
+	var matches []whyMatch
+	owned := map[string]bool{} // kind + " " + name, for the unmanaged check
+
+	reg := loadPackRegistry(workspace)
+	for _, packName := range reg.Names() {
+		e := reg.Packs[packName]
+		add := func(kind string, names []string) {
+			for _, n := range names {
+				if n != name {
+					continue
+				}
+				owned[kind+" "+n] = true
+				matches = append(matches, whyMatch{Kind: kind, Name: n, From: "pack", Source: packName,
+					Version: e.Version, Repo: packRepo(e), Installed: e.InstalledAt, Path: whyContentPath(workspace, kind, n)})
+			}
+		}
+		add("skill", e.Skills)
+		add("agent", e.Agents)
+		add("hook", e.Hooks)
+	}
+
+	// Content orchestra init writes itself.
+	for kind, n := range map[string]string{"skill": "project-manager", "agent": "orchestra"} {
+		if n == name {
+			owned[kind+" "+n] = true
+			matches = append(matches, whyMatch{Kind: kind, Name: n, From: "built-in", Source: "orchestra", Version: Version,
+				Path: whyContentPath(workspace, kind, n)})
+		}
+	}
+
+	// Files no registry accounts for.
+	for _, kind := range []string{"skill", "agent", "hook"} {
+		path := whyContentPath(workspace, kind, name)
+		if owned[kind+" "+name] {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(workspace, filepath.FromSlash(path))); err == nil {
+			matches = append(matches, whyMatch{Kind: kind, Name: name, From: "unmanaged", Source: "-", Path: path})
+		}
+	}
+
+	if t, g := findTool(name); t != nil {
+		matches = append(matches, whyMatch{Kind: "tool", Name: t.Name, From: "built-in", Source: "tools." + g.Plugin, Version: Version})
+	}
+	if name == "markdown" {
+		matches = append(matches, whyMatch{Kind: "storage", Name: name, From: "built-in", Source: "storage.markdown", Version: Version})
+	}
+
+	for _, p := range servePlugins(workspace, io.Discard) {
+		add := func(kind string, names []string) {
+			for _, n := range names {
+				if n == name {
+					source := p.ID
+					if p.Vendored {
+						source += " [vendored]"
+					}
+					matches = append(matches, whyMatch{Kind: kind, Name: n, From: "plugin", Source: source,
+						Version: p.Version, Repo: p.Repo, Installed: p.InstalledAt})
+				}
+			}
+		}
+		add("tool", p.Tools())
+		add("prompt", p.ProvidesPrompts)
+		add("resource", p.ProvidesResources)
+		add("storage", p.ProvidesStorage)
+	}
+	return matches
+}
+
+// whyContentPath is where kind name lives under the workspace. A hook is
+// whichever variant is installed, .sh when none is.
+func whyContentPath(workspace, kind, name string) string {
+	switch kind {
+	case "skill":
+		return ".claude/skills/" + name + "/"
+	case "agent":
+		return ".claude/agents/" + name + ".md"
+	}
+	if rel := packs.InstalledHook(workspace, name); rel != "" {
+		return rel
+	}
+	return ".claude/hooks/" + name + ".sh"
+}
+
+// packRepo is e's repo, noting when the installed copy is the one
+// embedded in orchestra.
+func packRepo(e *packs.Entry) string {
+	if e.Source == packs.SourceEmbedded {
+		return e.Repo + " (embedded in orchestra)"
+	}
+	return e.Repo
+}
@@ -6,6 +6,28 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/orchestra-mcp/cli/pkg/packs"
+)
+
+// docsLockTimeout bounds how long doc generation waits for another
+// process generating the same workspace's docs.
+const docsLockTimeout = 10 * time.Second
+
+// docsState coordinates doc generation for one workspace within this
+// process.
+type docsState struct {
+	running bool          // a generation is writing the docs
+	batch   int           // open beginDocsBatch calls
+	pending bool          // docs were requested during a batch or a run
+	rerun   chan struct{} // closed when the run after the current one ends
+}
+
+var (
+	docsMu     sync.Mutex
+	docsStates = map[string]*docsState{}
 )
 
 // GenerateWorkspaceDocs creates or overwrites CLAUDE.md and AGENTS.md at the
@@ -13,11 +35,121 @@ import (
 // for installed content and reads the pack registry to produce accurate
 // documentation files. Call this from orchestra init and after pack
 // install/remove/update.
+//
+// Requests are debounced: inside a beginDocsBatch they only mark the docs
+// stale, and requests made while a generation is running wait for a single
+// follow-up run that covers all of them. Generation also holds
+// .claude/.docs.lock, so separate orchestra processes never interleave.
 func GenerateWorkspaceDocs(workspace string) {
+	docsMu.Lock()
+	st := docsStateFor(workspace)
+	if st.batch > 0 {
+		st.pending = true
+		docsMu.Unlock()
+		return
+	}
+	if st.running {
+		st.pending = true
+		if st.rerun == nil {
+			st.rerun = make(chan struct{})
+		}
+		wait := st.rerun
+		docsMu.Unlock()
+		<-wait
+		return
+	}
+
+	st.running = true
+	var done chan struct{}
+	for {
+		docsMu.Unlock()
+		writeWorkspaceDocs(workspace, printStatus)
+		docsMu.Lock()
+		if done != nil {
+			close(done)
+		}
+		if !st.pending {
+			break
+		}
+		if st.batch > 0 {
+			// The batch's end generates for the pending requests; their
+			// callers need not wait for it.
+			if st.rerun != nil {
+				close(st.rerun)
+				st.rerun = nil
+			}
+			break
+		}
+		st.pending = false
+		done, st.rerun = st.rerun, nil
+	}
+	st.running = false
+	docsMu.Unlock()
+}
+
+// beginDocsBatch defers GenerateWorkspaceDocs for workspace until the
+// returned func is called, then generates once if anything asked for it.
+// Batches nest.
+func beginDocsBatch(workspace string) func() {
+	docsMu.Lock()
+	docsStateFor(workspace).batch++
+	docsMu.Unlock()
+	return func() {
+		docsMu.Lock()
+		st := docsStateFor(workspace)
+		st.batch--
+		flush := st.batch == 0 && st.pending
+		if flush && !st.running {
+			st.pending = false
+		}
+		docsMu.Unlock()
+		if flush {
+			GenerateWorkspaceDocs(workspace)
+		}
+	}
+}
+
+// docsStateFor returns the state for workspace. The caller holds docsMu.
+func docsStateFor(workspace string) *docsState {
+	key := filepath.Clean(workspace)
+	st, ok := docsStates[key]
+	if !ok {
+		st = &docsState{}
+		docsStates[key] = st
+	}
+	return st
+}
+
+// statusFunc reports a step's outcome; printStatus, or a collector where
+// stderr is not the place, as in serve.
+type statusFunc func(tag, format string, args ...any)
+
+// writeWorkspaceDocs generates the docs once, under the workspace's docs
+// lock, reporting each file to report.
+func writeWorkspaceDocs(workspace string, report statusFunc) {
+	defer debugStep("doc generation").end()
 	// Ensure .claude/ directory exists.
 	claudeDir := filepath.Join(workspace, ".claude")
 	os.MkdirAll(claudeDir, 0755)
 
+	release, err := acquireLock(worktreeScoped(workspace, filepath.Join(claudeDir, ".docs.lock")), docsLockTimeout)
+	if err != nil {
+		// Each file is still replaced atomically; at worst the other
+		// process's version wins.
+		report(tagWarn, "docs lock: %v; generating anyway", err)
+	} else {
+		defer release()
+	}
+
+	// Local versions in .claude/overrides/ win over what packs installed.
+	applied, err := packs.ApplyOverlay(workspace)
+	for _, p := range applied {
+		report(tagOK, "kept local %s (from %s)", p, packs.OverlayPath(p))
+	}
+	if err != nil {
+		report(tagFail, "%v", err)
+	}
+
 	// Scan installed content from the filesystem.
 	skills := scanSkills(claudeDir)
 	agents := scanAgents(claudeDir)
@@ -26,25 +158,51 @@ func GenerateWorkspaceDocs(workspace string) {
 	// Load pack registry for the installed packs section.
 	reg := loadPackRegistry(workspace)
 
+	// Regenerate the feature progress rollup, if there are features.
+	if err := writeProgressDoc(workspace); err != nil {
+		report(tagFail, ".projects/PROGRESS.md: %v", err)
+	}
+
 	// Generate and write CLAUDE.md.
-	claudeMD := buildClaudeMD(reg, skills, agents, hooks)
+	claudeMD, agentsMD := renderWorkspaceDocs(workspace, reg, skills, agents, hooks)
 	claudeMDPath := filepath.Join(workspace, "CLAUDE.md")
-	if err := os.WriteFile(claudeMDPath, []byte(claudeMD), 0644); err != nil {
-		fmt.Fprintf(os.Stderr, "  [FAIL] CLAUDE.md: %v\n", err)
+	if err := writeFileAtomic(claudeMDPath, []byte(claudeMD), 0644); err != nil {
+		report(tagFail, "CLAUDE.md: %v", err)
 	} else {
-		fmt.Fprintf(os.Stderr, "  [OK] CLAUDE.md\n")
+		report(tagOK, "CLAUDE.md")
 	}
 
 	// Generate and write AGENTS.md.
-	agentsMD := buildAgentsMD(agents)
 	agentsMDPath := filepath.Join(workspace, "AGENTS.md")
-	if err := os.WriteFile(agentsMDPath, []byte(agentsMD), 0644); err != nil {
-		fmt.Fprintf(os.Stderr, "  [FAIL] AGENTS.md: %v\n", err)
+	if err := writeFileAtomic(agentsMDPath, []byte(agentsMD), 0644); err != nil {
+		report(tagFail, "AGENTS.md: %v", err)
 	} else {
-		fmt.Fprintf(os.Stderr, "  [OK] AGENTS.md\n")
+		report(tagOK, "AGENTS.md")
 	}
 }
 
+// renderWorkspaceDocs returns CLAUDE.md and AGENTS.md for workspace with
+// the given content installed.
+func renderWorkspaceDocs(workspace string, reg *packs.Registry, skills, agents, hooks []string) (claudeMD, agentsMD string) {
+	overrides := contentOverrides(workspace, skills, agents, hooks)
+	claudeMD = normalizeNewlines(buildClaudeMD(reg, skills, agents, hooks, projectDocs(workspace), overrides, loadWorkspaceConfig(workspace)))
+	agentsMD = normalizeNewlines(buildAgentsMD(agents, overrides))
+	return claudeMD, agentsMD
+}
+
+// generatedDocHeaders open the docs GenerateWorkspaceDocs writes.
+var generatedDocHeaders = map[string]string{
+	"CLAUDE.md": "# CLAUDE.md\n\nThis project uses [Orchestra MCP]",
+	"AGENTS.md": "# AGENTS.md\n\nSpecialized agents installed via Orchestra packs",
+}
+
+// generatedDoc reports whether data, the contents of the workspace doc
+// name, was written by GenerateWorkspaceDocs rather than by hand.
+func generatedDoc(name string, data []byte) bool {
+	header, ok := generatedDocHeaders[name]
+	return ok && strings.HasPrefix(normalizeNewlines(string(data)), header)
+}
+
 // scanSkills returns sorted skill directory names found in .claude/skills/.
 // Each skill is a directory containing at least a SKILL.md file.
 func scanSkills(claudeDir string) []string {
@@ -65,7 +223,7 @@ func scanSkills(claudeDir string) []string {
 			skills = append(skills, entry.Name())
 		}
 	}
-	sort.Strings(skills)
+	sortNames(skills)
 	return skills
 }
 
@@ -88,12 +246,12 @@ func scanAgents(claudeDir string) []string {
 			agents = append(agents, strings.TrimSuffix(name, ".md"))
 		}
 	}
-	sort.Strings(agents)
+	sortNames(agents)
 	return agents
 }
 
-// scanHooks returns sorted hook names (without .sh extension) found in
-// .claude/hooks/.
+// scanHooks returns sorted hook file names found in .claude/hooks/, with
+// their extension: one of packs.HookExts, e.g. "guard.sh" or "guard.ps1".
 func scanHooks(claudeDir string) []string {
 	hooksDir := filepath.Join(claudeDir, "hooks")
 	entries, err := os.ReadDir(hooksDir)
@@ -107,16 +265,55 @@ func scanHooks(claudeDir string) []string {
 			continue
 		}
 		name := entry.Name()
-		if strings.HasSuffix(name, ".sh") {
-			hooks = append(hooks, strings.TrimSuffix(name, ".sh"))
+		for _, ext := range packs.HookExts {
+			if strings.HasSuffix(name, ext) {
+				hooks = append(hooks, name)
+			}
 		}
 	}
-	sort.Strings(hooks)
+	sortNames(hooks)
 	return hooks
 }
 
-// buildClaudeMD generates the full CLAUDE.md content.
-func buildClaudeMD(reg *packRegistry, skills, agents, hooks []string) string {
+// contentOverrides returns the .claude/ paths (as listed in CLAUDE.md,
+// e.g. ".claude/agents/reviewer.md") that have a local version in
+// .claude/overrides/.
+func contentOverrides(workspace string, skills, agents, hooks []string) map[string]bool {
+	overrides := map[string]bool{}
+	check := func(rel string) {
+		if packs.Overridden(workspace, strings.TrimSuffix(rel, "/")) {
+			overrides[rel] = true
+		}
+	}
+	for _, name := range skills {
+		check(".claude/skills/" + name + "/")
+	}
+	for _, name := range agents {
+		check(".claude/agents/" + name + ".md")
+	}
+	for _, file := range hooks {
+		check(".claude/hooks/" + file)
+	}
+	return overrides
+}
+
+// contentSource is the file CLAUDE.md points to for rel: the local
+// version when there is one.
+func contentSource(rel string, overrides map[string]bool) string {
+	if overrides[rel] {
+		dir := ""
+		if strings.HasSuffix(rel, "/") {
+			dir = "/"
+		}
+		return packs.OverlayPath(rel) + dir + " (local override)"
+	}
+	return rel
+}
+
+// buildClaudeMD generates the full CLAUDE.md content. Content with a local
+// version in .claude/overrides/ points there. cfg supplies the label
+// taxonomy and assignees, if the workspace has them.
+func buildClaudeMD(reg *packs.Registry, skills, agents, hooks, docs []string, overrides map[string]bool, cfg *workspaceConfig) string {
 	var b strings.Builder
 
 	b.WriteString("# CLAUDE.md\n\n")
@@ -124,15 +321,47 @@ func buildClaudeMD(reg *packRegistry, skills, agents, hooks []string) string {
 
 	// Available Tools section.
 	b.WriteString("## Available Tools\n\n")
-	b.WriteString("Orchestra provides **49 tools** via MCP (34 feature workflow + 15 marketplace) and **5 prompts**.\n\n")
+	b.WriteString(fmt.Sprintf("Orchestra provides **%d tools** via MCP (%d feature workflow + %d marketplace + %d from serve) and **5 prompts**.\n\n",
+		toolCount(""), toolCount("features"), toolCount("marketplace"), toolCount("serve")))
+	b.WriteString("Run `orchestra explain <tool>` for details on any tool, lifecycle state, or gate.\n\n")
 	b.WriteString("Run `orchestra serve` to start the MCP server. IDE config is in `.mcp.json`.\n\n")
 
+	// Project Status section, linking generated docs under .projects/.
+	if len(docs) > 0 {
+		b.WriteString("## Project Status\n\n")
+		for _, doc := range docs {
+			b.WriteString(fmt.Sprintf("- [%s](%s)\n", doc, doc))
+		}
+		b.WriteString("\n")
+	}
+
+	// Labels section, so features use the taxonomy instead of new labels.
+	if len(cfg.Labels) > 0 || len(cfg.Assignees) > 0 {
+		b.WriteString("## Labels\n\n")
+		if len(cfg.Labels) > 0 {
+			b.WriteString("Label features only with these. Ask before adding one (`orchestra labels add`).\n\n")
+			for _, name := range labelNames(cfg) {
+				if d := cfg.Labels[name].Description; d != "" {
+					b.WriteString(fmt.Sprintf("- `%s` — %s\n", name, d))
+				} else {
+					b.WriteString(fmt.Sprintf("- `%s`\n", name))
+				}
+			}
+			b.WriteString("\n")
+		}
+		if len(cfg.Assignees) > 0 {
+			assignees := append([]string(nil), cfg.Assignees...)
+			sortNames(assignees)
+			b.WriteString(fmt.Sprintf("Assign features only to: `%s`.\n\n", strings.Join(assignees, "`, `")))
+		}
+	}
+
 	// Installed Packs section.
 	b.WriteString("## Installed Packs\n\n")
 	if len(reg.Packs) == 0 {
 		b.WriteString("No packs installed. Run `orchestra pack recommend` to get suggestions.\n\n")
 	} else {
-		packNames := sortedPackNames(reg)
+		packNames := reg.Names()
 		for _, name := range packNames {
 			entry := reg.Packs[name]
 			b.WriteString(fmt.Sprintf("- **%s** (v%s) — %d skills, %d agents, %d hooks\n",
@@ -150,7 +379,7 @@ func buildClaudeMD(reg *packRegistry, skills, agents, hooks []string) string {
 		b.WriteString("| Command | Source |\n")
 		b.WriteString("|---------|--------|\n")
 		for _, name := range skills {
-			b.WriteString(fmt.Sprintf("| `/%s` | .claude/skills/%s/ |\n", name, name))
+			b.WriteString(fmt.Sprintf("| `/%s` | %s |\n", name, contentSource(".claude/skills/"+name+"/", overrides)))
 		}
 		b.WriteString("\n")
 	}
@@ -164,7 +393,7 @@ func buildClaudeMD(reg *packRegistry, skills, agents, hooks []string) string {
 		b.WriteString("| Agent | File |\n")
 		b.WriteString("|-------|------|\n")
 		for _, name := range agents {
-			b.WriteString(fmt.Sprintf("| `%s` | .claude/agents/%s.md |\n", name, name))
+			b.WriteString(fmt.Sprintf("| `%s` | %s |\n", name, contentSource(".claude/agents/"+name+".md", overrides)))
 		}
 		b.WriteString("\n")
 	}
@@ -174,19 +403,31 @@ func buildClaudeMD(reg *packRegistry, skills, agents, hooks []string) string {
 	if len(hooks) == 0 {
 		b.WriteString("No hooks installed.\n")
 	} else {
-		b.WriteString("| Hook | File |\n")
-		b.WriteString("|------|------|\n")
-		for _, name := range hooks {
-			b.WriteString(fmt.Sprintf("| `%s` | .claude/hooks/%s.sh |\n", name, name))
+		b.WriteString("| Hook | File | Runs with |\n")
+		b.WriteString("|------|------|-----------|\n")
+		for _, file := range hooks {
+			name := strings.TrimSuffix(file, filepath.Ext(file))
+			b.WriteString(fmt.Sprintf("| `%s` | %s | %s |\n", name, contentSource(".claude/hooks/"+file, overrides), packs.HookRunner(file)))
 		}
-		b.WriteString("")
 	}
 
 	return b.String()
 }
 
+// projectDocs returns the generated status docs present under .projects/,
+// relative to the workspace, for linking from CLAUDE.md.
+func projectDocs(workspace string) []string {
+	var docs []string
+	for _, name := range []string{"PROGRESS.md", "DIGEST.md"} {
+		if _, err := os.Stat(filepath.Join(workspace, ".projects", name)); err == nil {
+			docs = append(docs, ".projects/"+name)
+		}
+	}
+	return docs
+}
+
 // buildAgentsMD generates the full AGENTS.md content.
-func buildAgentsMD(agents []string) string {
+func buildAgentsMD(agents []string, overrides map[string]bool) string {
 	var b strings.Builder
 
 	b.WriteString("# AGENTS.md\n\n")
@@ -200,19 +441,36 @@ func buildAgentsMD(agents []string) string {
 	} else {
 		for _, name := range agents {
 			b.WriteString(fmt.Sprintf("## %s\n\n", name))
-			b.WriteString(fmt.Sprintf("See [.claude/agents/%s.md](.claude/agents/%s.md)\n\n", name, name))
+			rel := ".claude/agents/" + name + ".md"
+			if overrides[rel] {
+				rel = packs.OverlayPath(rel)
+				b.WriteString("Customized locally; edit the override, not the pack's copy.\n\n")
+			}
+			b.WriteString(fmt.Sprintf("See [%s](%s)\n\n", rel, rel))
 		}
 	}
 
 	return b.String()
 }
 
-// sortedPackNames returns pack names from the registry in alphabetical order.
-func sortedPackNames(reg *packRegistry) []string {
-	names := make([]string, 0, len(reg.Packs))
-	for name := range reg.Packs {
-		names = append(names, name)
-	}
-	sort.Strings(names)
-	return names
+// sortNames sorts names case-insensitively, breaking ties bytewise. The
+// order depends only on the names themselves -- never on the OS locale or
+// map iteration -- so generated files are identical on every machine.
+func sortNames(names []string) {
+	sort.Slice(names, func(i, j int) bool {
+		a, b := strings.ToLower(names[i]), strings.ToLower(names[j])
+		if a != b {
+			return a < b
+		}
+		return names[i] < names[j]
+	})
+}
+
+// normalizeNewlines converts CRLF and lone CR line endings to LF and
+// guarantees exactly one trailing newline, so generated files do not
+// produce diffs between Windows and Unix checkouts.
+func normalizeNewlines(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+	return strings.TrimRight(s, "\n") + "\n"
 }
@@ -0,0 +1,192 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// A linked worktree (`git worktree add`) is a second checkout of a
+// repository with its own HEAD; its .git is a file pointing at
+// .git/worktrees/<name> in the main checkout. Each worktree is a workspace
+// of its own, but worktrees often share .projects/ or .claude/ through a
+// symlink, so the runtime files serve keeps there carry the worktree's name
+// (worktreeScoped), and init, serve, and doctor say when generated content
+// is shared.
+
+// gitWorktree is the git checkout a workspace is in.
+type gitWorktree struct {
+	Root      string // top of the checkout
+	GitDir    string // its own git dir: <main>/.git or <main>/.git/worktrees/<name>
+	CommonDir string // the git dir all of the repository's worktrees share
+}
+
+var worktreeCache = struct {
+	sync.Mutex
+	m map[string]*gitWorktree
+}{m: map[string]*gitWorktree{}}
+
+// detectWorktree returns the checkout dir is in, or nil when dir is not in
+// a git repository (or git is not installed). Results are cached per dir.
+func detectWorktree(dir string) *gitWorktree {
+	worktreeCache.Lock()
+	defer worktreeCache.Unlock()
+	if w, ok := worktreeCache.m[dir]; ok {
+		return w
+	}
+	var w *gitWorktree
+	lines := strings.Split(gitOutput(dir, "rev-parse", "--show-toplevel", "--absolute-git-dir", "--git-common-dir"), "\n")
+	if len(lines) == 3 {
+		common := filepath.FromSlash(lines[2])
+		if !filepath.IsAbs(common) {
+			common = filepath.Join(dir, common) // relative to where git ran
+		}
+		w = &gitWorktree{Root: filepath.FromSlash(lines[0]), GitDir: filepath.FromSlash(lines[1]), CommonDir: filepath.Clean(common)}
+	}
+	worktreeCache.m[dir] = w
+	return w
+}
+
+// linked reports whether w is a worktree added with `git worktree add`
+// rather than the repository's main checkout.
+func (w *gitWorktree) linked() bool {
+	return w != nil && !samePath(w.GitDir, w.CommonDir)
+}
+
+// name is the linked worktree's name, the last element of its git dir, or
+// "" for the main checkout.
+func (w *gitWorktree) name() string {
+	if !w.linked() {
+		return ""
+	}
+	return filepath.Base(w.GitDir)
+}
+
+// mainRoot returns the main checkout of w's repository, or "" when the
+// repository is bare.
+func (w *gitWorktree) mainRoot() string {
+	if filepath.Base(w.CommonDir) != ".git" {
+		return ""
+	}
+	return filepath.Dir(w.CommonDir)
+}
+
+// sameRepo reports whether w and o are checkouts of one repository.
+func (w *gitWorktree) sameRepo(o *gitWorktree) bool {
+	return w != nil && o != nil && samePath(w.CommonDir, o.CommonDir)
+}
+
+// describe says where w is and what it has checked out, e.g. "linked
+// worktree feat-x of /src/app, on branch feat/x".
+func (w *gitWorktree) describe() string {
+	branch, head := gitHead(w.Root)
+	at := "on branch " + branch
+	switch {
+	case head == "":
+		at = "with no commits"
+	case branch == "":
+		at = "detached at " + shortCommit(head)
+	}
+	if !w.linked() {
+		return "main checkout, " + at
+	}
+	return fmt.Sprintf("linked worktree %s of %s, %s", w.name(), orDash(w.mainRoot()), at)
+}
+
+// gitHead returns the branch checked out in dir ("" when HEAD is detached)
+// and the commit HEAD points at ("" before the first commit).
+func gitHead(dir string) (branch, head string) {
+	return gitOutput(dir, "symbolic-ref", "--short", "-q", "HEAD"), gitOutput(dir, "rev-parse", "-q", "--verify", "HEAD")
+}
+
+// worktreeScoped returns path, a runtime file of workspace, with the name
+// of the linked worktree workspace is in put before its extension
+// (.orchestra-mcp.pid becomes .orchestra-mcp.feat-x.pid), so worktrees
+// sharing the directory path is in do not share the file. In the main
+// checkout, or outside git, path is returned unchanged.
+func worktreeScoped(workspace, path string) string {
+	name := detectWorktree(workspace).name()
+	if name == "" {
+		return path
+	}
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + name + ext
+}
+
+// worktreeEntry is a checkout listed by `git worktree list`.
+type worktreeEntry struct {
+	Root   string
+	Branch string // "" when detached
+}
+
+// listWorktrees lists the checkouts of dir's repository, main first.
+func listWorktrees(dir string) []worktreeEntry {
+	var list []worktreeEntry
+	for _, line := range strings.Split(gitOutput(dir, "worktree", "list", "--porcelain"), "\n") {
+		if root, ok := strings.CutPrefix(line, "worktree "); ok {
+			list = append(list, worktreeEntry{Root: filepath.FromSlash(root)})
+		} else if ref, ok := strings.CutPrefix(line, "branch "); ok && len(list) > 0 {
+			list[len(list)-1].Branch = strings.TrimPrefix(ref, "refs/heads/")
+		}
+	}
+	return list
+}
+
+// sharedWorktreeDirs returns, for each of rel (".claude", ".projects")
+// that workspace shares with another worktree of its repository, the
+// other worktree's root.
+func sharedWorktreeDirs(workspace string, rel ...string) map[string]string {
+	w := detectWorktree(workspace)
+	if w == nil {
+		return nil
+	}
+	sub, err := filepath.Rel(w.Root, workspace)
+	if err != nil {
+		sub = "."
+	}
+	shared := map[string]string{}
+	for _, e := range listWorktrees(workspace) {
+		root := e.Root
+		if samePath(root, w.Root) {
+			continue
+		}
+		for _, r := range rel {
+			if _, ok := shared[r]; ok {
+				continue
+			}
+			mine, theirs := filepath.Join(workspace, r), filepath.Join(root, sub, r)
+			if _, err := os.Stat(mine); err == nil && samePath(mine, theirs) {
+				shared[r] = root
+			}
+		}
+	}
+	return shared
+}
+
+// worktreeWarnings returns what is odd about the worktree workspace is
+// in: .claude/ shared with another worktree, so each overwrites the docs
+// and skills generated for the other.
+func worktreeWarnings(workspace string) []string {
+	var warnings []string
+	if other, ok := sharedWorktreeDirs(workspace, ".claude")[".claude"]; ok {
+		warnings = append(warnings, fmt.Sprintf(".claude/ is shared with the worktree at %s; docs and skills generated for one overwrite the other's", other))
+	}
+	return warnings
+}
+
+// worktreeMismatch describes serve being started in one worktree for
+// another of the same repository -- usually an IDE config copied or
+// committed with the other worktree's path -- or returns "".
+func worktreeMismatch(workspace string) string {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	here, there := detectWorktree(cwd), detectWorktree(workspace)
+	if !here.sameRepo(there) || samePath(here.Root, there.Root) {
+		return ""
+	}
+	return fmt.Sprintf("started in the worktree at %s but serving the one at %s; run 'orchestra init' in %s so its IDE config names it", here.Root, there.Root, here.Root)
+}
@@ -0,0 +1,100 @@
+package packs
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// CatalogEntry is a pack listed in a pack index.
+type CatalogEntry struct {
+	Repo        string   `json:"repo"`
+	Stacks      []string `json:"stacks"` // detected stacks it suits; "*" for every project
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+// Index is a pack index document, the JSON a pack index URL serves:
+//
+//	{"packs": [{"repo": "github.com/acme/pack-x", "stacks": ["go"], "description": "...", "tags": ["x"]}]}
+type Index struct {
+	Packs []CatalogEntry `json:"packs"`
+}
+
+// ParseIndex decodes a pack index document. Every entry needs a repo.
+func ParseIndex(data []byte) ([]CatalogEntry, error) {
+	var idx Index
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, err
+	}
+	for i, p := range idx.Packs {
+		if p.Repo == "" {
+			return nil, fmt.Errorf("pack %d has no repo", i+1)
+		}
+	}
+	return idx.Packs, nil
+}
+
+// Catalog is the built-in pack index, used by `orchestra pack search` and
+// `orchestra pack recommend` when no pack index can be fetched or is
+// cached, and by the marketplace search and recommend tools.
+var Catalog = []CatalogEntry{
+	{Repo: "github.com/orchestra-mcp/pack-essentials", Stacks: []string{"*"}, Description: "Core project management skills and agents", Tags: []string{"core", "essential"}},
+	{Repo: "github.com/orchestra-mcp/pack-go-backend", Stacks: []string{"go"}, Description: "Go backend skills (Fiber, GORM, REST)", Tags: []string{"go", "backend", "fiber"}},
+	{Repo: "github.com/orchestra-mcp/pack-rust-engine", Stacks: []string{"rust"}, Description: "Rust engine skills", Tags: []string{"rust", "engine"}},
+	{Repo: "github.com/orchestra-mcp/pack-react-frontend", Stacks: []string{"react", "typescript"}, Description: "React frontend skills", Tags: []string{"react", "typescript"}},
+	{Repo: "github.com/orchestra-mcp/pack-database", Stacks: []string{"*"}, Description: "Database skills (PostgreSQL, SQLite, Redis)", Tags: []string{"database", "sql"}},
+	{Repo: "github.com/orchestra-mcp/pack-ai", Stacks: []string{"*"}, Description: "AI/LLM integration skills", Tags: []string{"ai", "llm", "rag"}},
+	{Repo: "github.com/orchestra-mcp/pack-mobile", Stacks: []string{"react-native"}, Description: "React Native mobile skills", Tags: []string{"mobile"}},
+	{Repo: "github.com/orchestra-mcp/pack-desktop", Stacks: []string{"go"}, Description: "Desktop app skills", Tags: []string{"desktop", "wails"}},
+	{Repo: "github.com/orchestra-mcp/pack-extensions", Stacks: []string{"*"}, Description: "Extension system skills", Tags: []string{"extensions"}},
+	{Repo: "github.com/orchestra-mcp/pack-chrome", Stacks: []string{"typescript"}, Description: "Chrome extension skills", Tags: []string{"chrome", "browser"}},
+	{Repo: "github.com/orchestra-mcp/pack-infra", Stacks: []string{"docker"}, Description: "Infrastructure and DevOps skills", Tags: []string{"docker", "devops"}},
+	{Repo: "github.com/orchestra-mcp/pack-proto", Stacks: []string{"go", "rust"}, Description: "Protobuf/gRPC skills", Tags: []string{"proto", "grpc"}},
+	{Repo: "github.com/orchestra-mcp/pack-native-swift", Stacks: []string{"swift"}, Description: "Swift/macOS/iOS plugin skills", Tags: []string{"swift", "macos"}},
+	{Repo: "github.com/orchestra-mcp/pack-native-kotlin", Stacks: []string{"kotlin", "java"}, Description: "Kotlin/Android plugin skills", Tags: []string{"kotlin", "android"}},
+	{Repo: "github.com/orchestra-mcp/pack-native-csharp", Stacks: []string{"csharp"}, Description: "C#/Windows plugin skills", Tags: []string{"csharp", "windows"}},
+	{Repo: "github.com/orchestra-mcp/pack-native-gtk", Stacks: []string{"c"}, Description: "GTK4/Linux desktop skills", Tags: []string{"gtk", "linux"}},
+	{Repo: "github.com/orchestra-mcp/pack-analytics", Stacks: []string{"*"}, Description: "ClickHouse analytics skills", Tags: []string{"analytics", "clickhouse"}},
+}
+
+// Matches reports whether the lowercase query appears in the pack's repo,
+// description, or tags.
+func (p CatalogEntry) Matches(query string) bool {
+	if strings.Contains(strings.ToLower(p.Repo), query) ||
+		strings.Contains(strings.ToLower(p.Description), query) {
+		return true
+	}
+	for _, tag := range p.Tags {
+		if strings.Contains(tag, query) {
+			return true
+		}
+	}
+	return false
+}
+
+// Recommend returns the built-in catalog's packs that suit any of the
+// detected stacks; see RecommendFrom.
+func Recommend(stacks []string) []CatalogEntry {
+	return RecommendFrom(Catalog, stacks)
+}
+
+// RecommendFrom returns the packs in catalog that suit any of the detected
+// stacks, in catalog order. Packs for every project ("*") are always
+// included.
+func RecommendFrom(catalog []CatalogEntry, stacks []string) []CatalogEntry {
+	have := make(map[string]bool, len(stacks))
+	for _, s := range stacks {
+		have[s] = true
+	}
+	var out []CatalogEntry
+	for _, p := range catalog {
+		for _, s := range p.Stacks {
+			if s == "*" || have[s] {
+				out = append(out, p)
+				break
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,176 @@
+package packs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Constraint is a semver range a pack is held to, such as ^1.2 (1.2.0 up
+// to but not including 2.0.0) or ~0.3 (0.3.0 up to 0.4.0). It is one or
+// more space-separated terms, all of which a version must satisfy:
+//
+//	^1.2.3  >=1.2.3 <2.0.0 (^0.3.1 is >=0.3.1 <0.4.0, ^0.0.3 is >=0.0.3 <0.0.4)
+//	~1.2.3  >=1.2.3 <1.3.0 (~1 is >=1.0.0 <2.0.0)
+//	>=1.2, >1.2, <=1.2, <2, =1.2.3
+//
+// A partial version stands for every version it leaves open, as in npm:
+// 1.2 is >=1.2.0 <1.3.0, so <=1.2 allows 1.2.5 and >1.2 starts at 1.3.0,
+// while >=1.2 and <1.2 bound at 1.2.0. Prereleases only match when a term
+// names a prerelease itself.
+type Constraint struct {
+	raw   string
+	terms []versionBound
+	pre   bool
+}
+
+// versionBound is one comparison: op is >=, >, <=, <, or =.
+type versionBound struct {
+	op      string
+	version string
+}
+
+// IsConstraint reports whether version, the part of repo@version after the
+// @, is a range rather than a tag, branch, or commit.
+func IsConstraint(version string) bool {
+	return version != "" && strings.ContainsAny(version[:1], "^~<>=")
+}
+
+// ParseConstraint parses a range in the forms Constraint describes.
+func ParseConstraint(s string) (*Constraint, error) {
+	c := &Constraint{raw: strings.TrimSpace(s)}
+	for _, term := range strings.Fields(c.raw) {
+		op := term[:len(term)-len(strings.TrimLeft(term, "^~<>="))]
+		base, pre, parts, ok := parseConstraintVersion(term[len(op):])
+		if !ok {
+			return nil, fmt.Errorf("version range %q: %q is not a version", s, term[len(op):])
+		}
+		if pre != "" {
+			c.pre = true
+		}
+		lower := joinVersion(base, pre)
+		switch op {
+		case "^":
+			// The leftmost nonzero number given stays fixed.
+			upper := [3]int{0, 0, base[2] + 1}
+			switch {
+			case base[0] > 0 || parts == 1:
+				upper = [3]int{base[0] + 1, 0, 0}
+			case base[1] > 0 || parts == 2:
+				upper = [3]int{0, base[1] + 1, 0}
+			}
+			c.terms = append(c.terms, versionBound{">=", lower}, versionBound{"<", joinVersion(upper, "")})
+		case "~":
+			upper := [3]int{base[0], base[1] + 1, 0}
+			if parts == 1 {
+				upper = [3]int{base[0] + 1, 0, 0}
+			}
+			c.terms = append(c.terms, versionBound{">=", lower}, versionBound{"<", joinVersion(upper, "")})
+		case ">=", "<":
+			c.terms = append(c.terms, versionBound{op, lower})
+		case ">", "<=", "=", "":
+			if op == "" {
+				op = "="
+			}
+			if parts == 3 || pre != "" {
+				c.terms = append(c.terms, versionBound{op, lower})
+				break
+			}
+			// The first version past the ones a partial version covers.
+			next := joinVersion([3]int{base[0] + 1, 0, 0}, "")
+			if parts == 2 {
+				next = joinVersion([3]int{base[0], base[1] + 1, 0}, "")
+			}
+			switch op {
+			case ">":
+				c.terms = append(c.terms, versionBound{">=", next})
+			case "<=":
+				c.terms = append(c.terms, versionBound{"<", next})
+			default:
+				c.terms = append(c.terms, versionBound{">=", lower}, versionBound{"<", next})
+			}
+		default:
+			return nil, fmt.Errorf("version range %q: unknown operator %q", s, op)
+		}
+	}
+	if len(c.terms) == 0 {
+		return nil, fmt.Errorf("empty version range")
+	}
+	return c, nil
+}
+
+// String returns the range as written.
+func (c *Constraint) String() string {
+	return c.raw
+}
+
+// Allows reports whether version, with or without a leading v, is in the
+// range.
+func (c *Constraint) Allows(version string) bool {
+	base, pre, parts, ok := parseConstraintVersion(version)
+	if !ok || parts < 3 || (pre != "" && !c.pre) {
+		return false
+	}
+	v := joinVersion(base, pre)
+	for _, t := range c.terms {
+		var ok bool
+		switch t.op {
+		case ">=":
+			ok = !versionLess(v, t.version)
+		case ">":
+			ok = versionLess(t.version, v)
+		case "<=":
+			ok = !versionLess(t.version, v)
+		case "<":
+			ok = versionLess(v, t.version)
+		case "=":
+			ok = !versionLess(v, t.version) && !versionLess(t.version, v)
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Best returns the highest of tags, such as v1.2.3 or 1.2.3, that the
+// range allows, or "" when none does. Tags that are not versions are
+// ignored.
+func (c *Constraint) Best(tags []string) string {
+	best := ""
+	for _, tag := range tags {
+		if c.Allows(tag) && (best == "" || versionLess(best, tag)) {
+			best = tag
+		}
+	}
+	return best
+}
+
+// parseConstraintVersion splits a full or partial version (1, 1.2, 1.2.3,
+// 1.2.3-beta, each with an optional leading v) into its numbers and
+// prerelease, and reports how many numbers it gave.
+func parseConstraintVersion(s string) (base [3]int, pre string, parts int, ok bool) {
+	s = strings.TrimPrefix(s, "v")
+	s, _, _ = strings.Cut(s, "+")
+	s, pre, _ = strings.Cut(s, "-")
+	fields := strings.Split(s, ".")
+	if s == "" || len(fields) > 3 {
+		return base, "", 0, false
+	}
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 0 || strings.Trim(f, "0123456789") != "" {
+			return base, "", 0, false
+		}
+		base[i] = n
+	}
+	return base, pre, len(fields), true
+}
+
+func joinVersion(base [3]int, pre string) string {
+	v := fmt.Sprintf("%d.%d.%d", base[0], base[1], base[2])
+	if pre != "" {
+		v += "-" + pre
+	}
+	return v
+}
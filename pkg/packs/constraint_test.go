@@ -0,0 +1,119 @@
+package packs
+
+import "testing"
+
+func TestVersionLess(t *testing.T) {
+	// Each version is older than the next, as in the SemVer spec's example.
+	ordered := []string{
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0-rc.2",
+		"1.0.0-rc.10",
+		"1.0.0",
+		"1.0.1",
+		"1.2.0",
+		"1.10.0",
+		"2.0.0",
+	}
+	for i := range ordered {
+		for j := range ordered {
+			if got, want := versionLess(ordered[i], ordered[j]), i < j; got != want {
+				t.Errorf("versionLess(%q, %q) = %v, want %v", ordered[i], ordered[j], got, want)
+			}
+		}
+	}
+
+	equal := [][2]string{
+		{"v1.2.3", "1.2.3"},
+		{"1.2.3+build.1", "1.2.3"},
+		{"1.2.10+build.1", "v1.2.10+build.2"},
+		{"1.0.0-rc.1+build.5", "1.0.0-rc.1"},
+	}
+	for _, p := range equal {
+		if versionLess(p[0], p[1]) || versionLess(p[1], p[0]) {
+			t.Errorf("%q and %q should have the same precedence", p[0], p[1])
+		}
+	}
+	if !versionLess("1.2.9", "1.2.10+build.1") {
+		t.Error("build metadata broke the patch number")
+	}
+}
+
+func TestConstraintAllows(t *testing.T) {
+	tests := []struct {
+		constraint string
+		allows     []string
+		rejects    []string
+	}{
+		{"^1.2", []string{"1.2.0", "1.9.3", "v1.2.1"}, []string{"1.1.9", "2.0.0", "1.3.0-beta"}},
+		{"^0.3.1", []string{"0.3.1", "0.3.9"}, []string{"0.3.0", "0.4.0"}},
+		{"^0.0.3", []string{"0.0.3"}, []string{"0.0.4"}},
+		{"~1.2.3", []string{"1.2.3", "1.2.9"}, []string{"1.3.0"}},
+		{"~1", []string{"1.0.0", "1.9.0"}, []string{"2.0.0"}},
+		{">=1.2", []string{"1.2.0", "3.0.0"}, []string{"1.1.9"}},
+		{"<1.2", []string{"1.1.9"}, []string{"1.2.0", "1.2.1"}},
+		{">1.2", []string{"1.3.0", "2.0.0"}, []string{"1.2.0", "1.2.1", "1.2.9"}},
+		{"<=1.2", []string{"1.2.0", "1.2.5", "1.0.0"}, []string{"1.3.0"}},
+		{">1", []string{"2.0.0"}, []string{"1.9.9"}},
+		{"<=1", []string{"1.9.9"}, []string{"2.0.0"}},
+		{">1.2.3", []string{"1.2.4"}, []string{"1.2.3"}},
+		{"<=1.2.3", []string{"1.2.3"}, []string{"1.2.4"}},
+		{"1.2", []string{"1.2.0", "1.2.7"}, []string{"1.3.0", "1.1.0"}},
+		{"=1.2.3", []string{"1.2.3", "1.2.3+build.1"}, []string{"1.2.4"}},
+		{">=1.0.0-rc.2 <1.1", []string{"1.0.0-rc.2", "1.0.0-rc.10", "1.0.0"}, []string{"1.0.0-rc.1", "1.1.0"}},
+		{">=1.2 <2", []string{"1.5.0"}, []string{"2.0.0", "1.2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			c, err := ParseConstraint(tt.constraint)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, v := range tt.allows {
+				if !c.Allows(v) {
+					t.Errorf("%s does not allow %s", tt.constraint, v)
+				}
+			}
+			for _, v := range tt.rejects {
+				if c.Allows(v) {
+					t.Errorf("%s allows %s", tt.constraint, v)
+				}
+			}
+		})
+	}
+}
+
+func TestParseConstraintErrors(t *testing.T) {
+	for _, s := range []string{"", "^", ">=1.x", "1.2.3.4", "!1.2"} {
+		if _, err := ParseConstraint(s); err == nil {
+			t.Errorf("ParseConstraint(%q): want an error", s)
+		}
+	}
+}
+
+func TestConstraintBest(t *testing.T) {
+	tags := []string{"v1.0.0-rc.2", "v1.0.0-rc.10", "v0.9.0", "latest", "v1.2.0+build.7", "v1.10.0", "v2.0.0-beta"}
+	tests := []struct {
+		constraint, want string
+	}{
+		{"^1", "v1.10.0"},
+		{"<=1.2", "v1.2.0+build.7"},
+		{">=1.0.0-rc.1 <1.0.0", "v1.0.0-rc.10"},
+		{"^0.9", "v0.9.0"},
+		{">=2", ""},
+	}
+	for _, tt := range tests {
+		c, err := ParseConstraint(tt.constraint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := c.Best(tags); got != tt.want {
+			t.Errorf("%s: Best = %q, want %q", tt.constraint, got, tt.want)
+		}
+	}
+}
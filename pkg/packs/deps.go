@@ -0,0 +1,114 @@
+package packs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrDependencyCycle is returned by Resolve when packs depend on each other
+// in a loop.
+var ErrDependencyCycle = errors.New("dependency cycle")
+
+// DependencyRepo returns the repo a dependency of the pack from
+// dependentRepo refers to. A dependency is a repo
+// (github.com/orchestra-mcp/pack-essentials, or a git URL such as
+// https://git.example.com/org/pack-x or git@git.example.com:org/pack-x.git),
+// a pack name (orchestra-mcp/pack-essentials, on GitHub), or a bare repo
+// name (pack-essentials), which lives next to the dependent's repo.
+func DependencyRepo(ref, dependentRepo string) string {
+	first, _, hasSlash := strings.Cut(ref, "/")
+	switch {
+	case strings.Contains(ref, "://") || strings.Contains(first, ":"):
+		return ref
+	case hasSlash && strings.Contains(first, "."):
+		return ref
+	case hasSlash:
+		return "github.com/" + ref
+	case dependentRepo != "":
+		// Not path.Dir, which would fold the // of a URL.
+		if i := strings.LastIndexAny(dependentRepo, "/:"); i >= 0 {
+			return dependentRepo[:i+1] + ref
+		}
+	}
+	return ref
+}
+
+// Dependency is a pack Resolve found missing.
+type Dependency struct {
+	Repo     string
+	Manifest *Manifest
+	Of       string // name of the pack that depends on it
+}
+
+// Resolve walks the dependencies of root, installed from rootRepo, and
+// returns the packs in none of reg's entries, in the order to install them:
+// each after the packs it depends on. fetch reads a repo's pack.json. A
+// pack is satisfied by an installed pack of the same name or repo, at any
+// version, whose recorded dependencies are walked in turn.
+func Resolve(root *Manifest, rootRepo string, reg *Registry, fetch func(repo string) (*Manifest, error)) ([]Dependency, error) {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := map[string]int{} // by repo and by pack name
+	var stack []string        // names of the packs being visited
+	var order []Dependency
+
+	var visit func(m *Manifest, repo string) error
+	visit = func(m *Manifest, repo string) error {
+		state[repo], state[m.Name] = visiting, visiting
+		stack = append(stack, m.Name)
+		cycle := func(to string) error {
+			return fmt.Errorf("%w: %s -> %s", ErrDependencyCycle, strings.Join(stack, " -> "), to)
+		}
+		for _, ref := range m.Dependencies {
+			depRepo := DependencyRepo(ref, repo)
+			if state[depRepo] == visiting || state[ref] == visiting {
+				return cycle(ref)
+			}
+			if state[depRepo] == done || state[ref] == done {
+				continue
+			}
+			name, e := reg.Lookup(ref)
+			if e == nil {
+				name, e = reg.FindByRepo(depRepo)
+			}
+			if e != nil {
+				// Installed, but its own dependencies may not be.
+				if err := visit(e.Manifest(name), orName(e.Repo, depRepo)); err != nil {
+					return err
+				}
+				continue
+			}
+			dep, err := fetch(depRepo)
+			if err != nil {
+				return fmt.Errorf("%s depends on %s: %w", m.Name, ref, err)
+			}
+			if state[dep.Name] == visiting {
+				return cycle(dep.Name)
+			}
+			if state[dep.Name] == done {
+				continue // the same pack under another reference
+			}
+			if err := visit(dep, depRepo); err != nil {
+				return err
+			}
+			order = append(order, Dependency{Repo: depRepo, Manifest: dep, Of: m.Name})
+		}
+		stack = stack[:len(stack)-1]
+		state[repo], state[m.Name] = done, done
+		return nil
+	}
+	if err := visit(root, rootRepo); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+func orName(s, fallback string) string {
+	if s != "" {
+		return s
+	}
+	return fallback
+}
@@ -0,0 +1,17 @@
+// Package packs is the pack engine shared by the orchestra CLI and the
+// marketplace MCP tools (install_pack, remove_pack, ...). Both sides must
+// read and write the same registry, copy the same files, and apply the same
+// conflict, compatibility, and signature rules, so all of that lives here.
+//
+// The package never prints or prompts. Install reports non-fatal problems
+// as warnings on its Result; callers decide how to show them.
+//
+// Layout of a workspace, relative to its root:
+//
+//	.claude/skills/<name>/             skill directories
+//	.claude/agents/<name>.md           agents
+//	.claude/hooks/<name>.sh            hooks
+//	.claude/overrides/...              local versions shadowing pack content (OverlayDir)
+//	.projects/.packs/registry.json     installed packs (Registry)
+//	.projects/.packs/packs.lock         exact revision of each installed pack (Lock)
+package packs
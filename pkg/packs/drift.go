@@ -0,0 +1,235 @@
+package packs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// OverlayDir is where local versions of pack content are kept, relative to
+// the workspace. It mirrors .claude/ (overrides/skills/<name>/..., and so
+// on) and shadows what packs install there: Install copies a pack's
+// overridden files over what the pack ships, and ApplyOverlay refreshes
+// them for content already installed. Nothing in the package writes to
+// it, so pack updates and removals never touch local versions.
+const OverlayDir = ".claude/overrides"
+
+// Drift statuses of a pack file in the workspace.
+const (
+	DriftModified = "modified" // differs from what the pack installed
+	DriftMissing  = "missing"  // installed by the pack, since deleted
+	DriftAdded    = "added"    // in one of the pack's skill directories, not from the pack
+	DriftOverlay  = "overlay"  // differs from the pack but matches the overlay
+)
+
+// FileDrift is one pack file whose workspace copy is not what the pack
+// installed. Path is workspace-relative with forward slashes.
+type FileDrift struct {
+	Path   string
+	Status string
+}
+
+// ContentFiles returns the files Install writes for m, as installed (text
+// normalized, hooks in the variant for m.OS), keyed by workspace-relative path such as
+// ".claude/skills/deploy/SKILL.md".
+func ContentFiles(src fs.FS, m *Manifest) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	read := func(name string) error {
+		data, err := fs.ReadFile(src, name)
+		if err != nil {
+			return err
+		}
+		if IsTextContent(name) {
+			data = []byte(normalizeNewlines(string(data)))
+		}
+		files[".claude/"+name] = data
+		return nil
+	}
+	for _, name := range m.Contents.Skills {
+		err := fs.WalkDir(src, path.Join("skills", name), func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			return read(p)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("read skill %s: %w", name, err)
+		}
+	}
+	for _, name := range m.Contents.Agents {
+		if err := read(path.Join("agents", name+".md")); err != nil {
+			return nil, fmt.Errorf("read agent %s: %w", name, err)
+		}
+	}
+	for _, name := range m.Contents.Hooks {
+		file, err := HookFile(src, name, m.OS)
+		if err == nil {
+			err = read(file)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read hook %s: %w", name, err)
+		}
+	}
+	return files, nil
+}
+
+// ContentSums returns the sha256 of each file ContentFiles lists.
+func ContentSums(files map[string][]byte) map[string]string {
+	sums := make(map[string]string, len(files))
+	for p, data := range files {
+		sums[p] = HashContent(data)
+	}
+	return sums
+}
+
+// HashContent is the hex sha256 recorded for a file in Entry.Files.
+func HashContent(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// OverlayPath returns where the overlay copy of a workspace-relative
+// .claude/ path lives.
+func OverlayPath(rel string) string {
+	return path.Join(OverlayDir, strings.TrimPrefix(rel, ".claude/"))
+}
+
+// Drift compares what e installed in workspace with want, the sha256 of
+// each file by path (Entry.Files, or ContentSums of the pack). Files that
+// match are left out; the rest are sorted by path.
+func Drift(workspace string, e *Entry, want map[string]string) []FileDrift {
+	var drift []FileDrift
+	for rel, sum := range want {
+		data, err := os.ReadFile(filepath.Join(workspace, filepath.FromSlash(rel)))
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			drift = append(drift, FileDrift{rel, DriftMissing})
+		case err != nil || HashContent(data) != sum:
+			status := DriftModified
+			if overlay, err := os.ReadFile(filepath.Join(workspace, filepath.FromSlash(OverlayPath(rel)))); err == nil && string(overlay) == string(data) {
+				status = DriftOverlay
+			}
+			drift = append(drift, FileDrift{rel, status})
+		}
+	}
+	for _, name := range e.Skills {
+		dir := filepath.Join(workspace, ".claude", "skills", name)
+		filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			rel, _ := filepath.Rel(workspace, p)
+			rel = filepath.ToSlash(rel)
+			if _, ok := want[rel]; !ok {
+				status := DriftAdded
+				if _, err := os.Stat(filepath.Join(workspace, filepath.FromSlash(OverlayPath(rel)))); err == nil {
+					status = DriftOverlay
+				}
+				drift = append(drift, FileDrift{rel, status})
+			}
+			return nil
+		})
+	}
+	sort.Slice(drift, func(i, j int) bool { return drift[i].Path < drift[j].Path })
+	return drift
+}
+
+// applyOverlay copies the overlay files that belong to m's content over
+// the workspace copies Install just wrote, returning their paths.
+func applyOverlay(workspace string, m *Manifest) ([]string, error) {
+	var roots []string
+	for _, name := range m.Contents.Skills {
+		roots = append(roots, ".claude/skills/"+name)
+	}
+	for _, name := range m.Contents.Agents {
+		roots = append(roots, ".claude/agents/"+name+".md")
+	}
+	for _, name := range m.Contents.Hooks {
+		for _, ext := range HookExts {
+			roots = append(roots, ".claude/hooks/"+name+ext)
+		}
+	}
+	return copyOverlay(workspace, roots, false)
+}
+
+// ApplyOverlay copies every overlay file over the workspace copy it
+// shadows, returning the paths it changed. Only installed content is
+// shadowed: an override whose skill directory, agent, or hook is not in
+// .claude/ is left alone, so removing a pack does not bring it back.
+func ApplyOverlay(workspace string) ([]string, error) {
+	var roots []string
+	for _, kind := range []string{"skills", "agents", "hooks"} {
+		entries, err := os.ReadDir(filepath.Join(workspace, filepath.FromSlash(OverlayDir), kind))
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			root := ".claude/" + kind + "/" + entry.Name()
+			if _, err := os.Stat(filepath.Join(workspace, filepath.FromSlash(root))); err == nil {
+				roots = append(roots, root)
+			}
+		}
+	}
+	return copyOverlay(workspace, roots, true)
+}
+
+// Overridden reports whether rel, a workspace-relative .claude/ path such
+// as ".claude/agents/reviewer.md" or ".claude/skills/deploy", has a copy
+// in the overlay.
+func Overridden(workspace, rel string) bool {
+	_, err := os.Stat(filepath.Join(workspace, filepath.FromSlash(OverlayPath(rel))))
+	return err == nil
+}
+
+// copyOverlay copies the overlay files under each root (a .claude/ path)
+// into the workspace. With onlyChanged, files that already match are
+// skipped and not returned.
+func copyOverlay(workspace string, roots []string, onlyChanged bool) ([]string, error) {
+	var applied []string
+	for _, root := range roots {
+		src := filepath.Join(workspace, filepath.FromSlash(OverlayPath(root)))
+		err := filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			if err != nil || d.IsDir() {
+				return err
+			}
+			sub, _ := filepath.Rel(src, p)
+			rel := path.Join(root, filepath.ToSlash(sub))
+			data, err := os.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			dst := filepath.Join(workspace, filepath.FromSlash(rel))
+			if onlyChanged {
+				if cur, err := os.ReadFile(dst); err == nil && string(cur) == string(data) {
+					return nil
+				}
+			}
+			if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+				return err
+			}
+			perm := os.FileMode(0644)
+			if strings.HasPrefix(rel, ".claude/hooks/") {
+				perm = 0755
+			}
+			if err := os.WriteFile(dst, data, perm); err != nil {
+				return err
+			}
+			applied = append(applied, rel)
+			return nil
+		})
+		if err != nil {
+			return applied, fmt.Errorf("apply overlay %s: %w", OverlayPath(root), err)
+		}
+	}
+	return applied, nil
+}
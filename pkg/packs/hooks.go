@@ -0,0 +1,87 @@
+package packs
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// HookExts are the hook script variants a pack can ship for one hook name:
+// hooks/<name>.sh, hooks/<name>.ps1 (PowerShell), and hooks/<name>.cmd.
+// Install copies the one variant that suits the target OS.
+var HookExts = []string{".sh", ".ps1", ".cmd"}
+
+// hookPreference returns the variants that run on goos, best first. Windows
+// prefers PowerShell, then cmd, then sh (Git Bash); elsewhere only sh runs.
+// An empty goos is an entry recorded before variants existed, which always
+// installed the sh script.
+func hookPreference(goos string) []string {
+	if goos == "windows" {
+		return []string{".ps1", ".cmd", ".sh"}
+	}
+	return []string{".sh"}
+}
+
+// targetOS is the OS Install picks hook variants for.
+func targetOS(opts Options) string {
+	if opts.OS != "" {
+		return opts.OS
+	}
+	return runtime.GOOS
+}
+
+// HookVariants lists the variant files src ships for hook name, in
+// HookExts order.
+func HookVariants(src fs.FS, name string) []string {
+	var files []string
+	for _, ext := range HookExts {
+		p := path.Join("hooks", name+ext)
+		if _, err := fs.Stat(src, p); err == nil {
+			files = append(files, p)
+		}
+	}
+	return files
+}
+
+// HookFile returns the variant of hook name src ships for goos, such as
+// "hooks/guard.ps1".
+func HookFile(src fs.FS, name, goos string) (string, error) {
+	for _, ext := range hookPreference(goos) {
+		p := path.Join("hooks", name+ext)
+		if _, err := fs.Stat(src, p); err == nil {
+			return p, nil
+		}
+	}
+	shipped := HookVariants(src, name)
+	if len(shipped) == 0 {
+		return "", fmt.Errorf("hook %s: no hooks/%s.sh, .ps1, or .cmd", name, name)
+	}
+	return "", fmt.Errorf("%w: hook %s has no variant for %s (ships %s)", ErrIncompatible, name, goos, strings.Join(shipped, ", "))
+}
+
+// InstalledHook returns the workspace-relative path of hook name in
+// workspace's .claude/hooks/, whichever variant is there, or "".
+func InstalledHook(workspace, name string) string {
+	for _, ext := range HookExts {
+		rel := ".claude/hooks/" + name + ext
+		if _, err := os.Stat(filepath.Join(workspace, filepath.FromSlash(rel))); err == nil {
+			return rel
+		}
+	}
+	return ""
+}
+
+// HookRunner names what runs a hook file, for docs: sh, PowerShell, or cmd.
+func HookRunner(file string) string {
+	switch strings.ToLower(path.Ext(file)) {
+	case ".ps1":
+		return "PowerShell"
+	case ".cmd":
+		return "cmd"
+	}
+	return "sh"
+}
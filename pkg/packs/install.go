@@ -0,0 +1,214 @@
+package packs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ErrConflict is returned when a pack would overwrite content owned by
+// another installed pack.
+var ErrConflict = errors.New("pack conflicts with installed content")
+
+// Options controls the checks Install applies.
+type Options struct {
+	// Version is the orchestra version installing the pack, checked against
+	// min_orchestra_version. "dev" or empty skips the check.
+	Version string
+	// Force installs incompatible or conflicting packs, reporting the
+	// problem as a warning instead of failing.
+	Force bool
+	// RequireSigned refuses unsigned packs and untrusted publishers.
+	RequireSigned bool
+	// SkipVerify skips signature checks, for the copy embedded in the
+	// orchestra binary.
+	SkipVerify bool
+	// OS picks hook variants (.sh, .ps1, .cmd) for this GOOS instead of
+	// the running one.
+	OS string
+}
+
+// Result describes a completed install.
+type Result struct {
+	Manifest *Manifest
+	// Signer is the publisher named in pack.sig, whether or not it is
+	// trusted. Manifest.Publisher is set only for trusted publishers.
+	Signer string
+	// Warnings are problems that did not stop the install: an incompatible
+	// or conflicting pack under Force, an unsigned pack, an untrusted
+	// publisher. Test them with errors.Is against ErrIncompatible,
+	// ErrConflict, ErrUnsigned, and ErrUntrusted.
+	Warnings []error
+	// Overlaid are the workspace paths replaced by their copy in
+	// OverlayDir after the pack's own files were written.
+	Overlaid []string
+}
+
+// Install checks the pack rooted at src and copies its skills, agents, and
+// hooks into the workspace's .claude/. Each hook is installed in the one
+// variant that runs on Options.OS (see HookExts); a hook with none makes
+// the pack incompatible, or is skipped under Force. reg is consulted for conflicts but
+// not modified; callers record the result with NewEntry and Registry.Save.
+//
+// Files in OverlayDir that belong to the pack's content are then copied
+// over it. Nothing is copied unless every check passes. Content that does not match
+// its signature is always refused, even with Force.
+func Install(workspace string, src fs.FS, reg *Registry, opts Options) (*Result, error) {
+	m, err := ReadManifest(src)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{Manifest: m}
+
+	if err := Compatibility(m, opts.Version); err != nil {
+		if !opts.Force {
+			return nil, err
+		}
+		res.Warnings = append(res.Warnings, err)
+	}
+	if conflicts := reg.Conflicts(m); len(conflicts) > 0 {
+		err := fmt.Errorf("%w: %s", ErrConflict, strings.Join(conflicts, ", "))
+		if !opts.Force {
+			return nil, err
+		}
+		res.Warnings = append(res.Warnings, err)
+	}
+	if !opts.SkipVerify {
+		ts, err := LoadTrustStore()
+		if err != nil {
+			return nil, err
+		}
+		publisher, err := Verify(src, m, ts)
+		res.Signer = publisher
+		switch {
+		case err == nil:
+			m.Publisher = publisher
+		case errors.Is(err, ErrUnsigned), errors.Is(err, ErrUntrusted):
+			if opts.RequireSigned {
+				return nil, fmt.Errorf("%w: %v", ErrVerify, err)
+			}
+			res.Warnings = append(res.Warnings, err)
+		default:
+			return nil, fmt.Errorf("%w: %v", ErrVerify, err)
+		}
+	}
+	if err := readPostInstall(src, m); err != nil {
+		return nil, err
+	}
+	m.OS = targetOS(opts)
+	hookFiles := map[string]string{}
+	var hooks []string
+	for _, name := range m.Contents.Hooks {
+		file, err := HookFile(src, name, m.OS)
+		switch {
+		case errors.Is(err, ErrIncompatible) && opts.Force:
+			res.Warnings = append(res.Warnings, fmt.Errorf("%w; skipping it", err))
+			continue
+		case err != nil:
+			return nil, err
+		}
+		hookFiles[name] = file
+		hooks = append(hooks, name)
+	}
+	m.Contents.Hooks = hooks
+
+	claudeDir := filepath.Join(workspace, ".claude")
+	for _, name := range m.Contents.Skills {
+		if err := copyDir(src, path.Join("skills", name), filepath.Join(claudeDir, "skills", name)); err != nil {
+			return nil, fmt.Errorf("copy skill %s: %w", name, err)
+		}
+	}
+	for _, name := range m.Contents.Agents {
+		if err := copyFile(src, path.Join("agents", name+".md"), filepath.Join(claudeDir, "agents", name+".md")); err != nil {
+			return nil, fmt.Errorf("copy agent %s: %w", name, err)
+		}
+	}
+	for _, name := range m.Contents.Hooks {
+		file := hookFiles[name]
+		dst := filepath.Join(claudeDir, "hooks", path.Base(file))
+		if err := copyFile(src, file, dst); err != nil {
+			return nil, fmt.Errorf("copy hook %s: %w", name, err)
+		}
+		os.Chmod(dst, 0755)
+		// Drop the variant an install for another OS left behind.
+		for _, ext := range HookExts {
+			if other := filepath.Join(claudeDir, "hooks", name+ext); other != dst {
+				os.Remove(other)
+			}
+		}
+	}
+
+	files, err := ContentFiles(src, m)
+	if err != nil {
+		return nil, err
+	}
+	m.Files = ContentSums(files)
+	if res.Overlaid, err = applyOverlay(workspace, m); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// copyDir copies the directory dir from src into dst.
+func copyDir(src fs.FS, dir, dst string) error {
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+	entries, err := fs.ReadDir(src, dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		srcPath := path.Join(dir, entry.Name())
+		dstPath := filepath.Join(dst, entry.Name())
+		if entry.IsDir() {
+			err = copyDir(src, srcPath, dstPath)
+		} else {
+			err = copyFile(src, srcPath, dstPath)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyFile copies the file name from src to dst.
+func copyFile(src fs.FS, name, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	data, err := fs.ReadFile(src, name)
+	if err != nil {
+		return err
+	}
+	// Pack text content is committed alongside the project; normalize line
+	// endings so a pack authored on Windows doesn't churn diffs (or break
+	// hook shebangs).
+	if IsTextContent(name) {
+		data = []byte(normalizeNewlines(string(data)))
+	}
+	return os.WriteFile(dst, data, 0644)
+}
+
+// IsTextContent reports whether a pack file has its line endings normalized
+// when installed and hashed.
+func IsTextContent(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".md", ".sh", ".ps1", ".json", ".yaml", ".yml", ".txt", ".toml":
+		return true
+	}
+	return false
+}
+
+// normalizeNewlines converts CRLF and lone CR to LF and guarantees exactly
+// one trailing newline.
+func normalizeNewlines(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+	return strings.TrimRight(s, "\n") + "\n"
+}
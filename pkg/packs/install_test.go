@@ -0,0 +1,139 @@
+package packs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+// testPack is a pack with one skill, one agent, and one hook.
+func testPack(name string) fstest.MapFS {
+	return fstest.MapFS{
+		"pack.json":                   {Data: []byte(`{"name":"` + name + `","version":"1.0.0","stacks":["go"],"contents":{"skills":["review"],"agents":["planner"],"hooks":["fmt"]}}`)},
+		"skills/review/SKILL.md":      {Data: []byte("---\nname: review\n---\nReview the diff.\n")},
+		"skills/review/checklist.txt": {Data: []byte("tests\ndocs\n")},
+		"agents/planner.md":           {Data: []byte("---\nname: planner\n---\nPlan the work.\n")},
+		"hooks/fmt.sh":                {Data: []byte("#!/bin/sh\ngofmt -l .\n")},
+		"hooks/fmt.ps1":               {Data: []byte("gofmt -l .\n")},
+	}
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInstallAndRemove(t *testing.T) {
+	t.Setenv("HOME", t.TempDir()) // empty trust store
+	workspace := t.TempDir()
+	reg := LoadRegistry(workspace)
+
+	res, err := Install(workspace, testPack("pack-demo"), reg, Options{Version: "dev", OS: "linux"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Warnings) != 1 || !errors.Is(res.Warnings[0], ErrUnsigned) {
+		t.Errorf("warnings = %v, want only ErrUnsigned", res.Warnings)
+	}
+	want := []string{
+		".claude/agents/planner.md",
+		".claude/hooks/fmt.sh",
+		".claude/skills/review/SKILL.md",
+		".claude/skills/review/checklist.txt",
+	}
+	var recorded []string
+	for rel, sum := range res.Manifest.Files {
+		recorded = append(recorded, rel)
+		data, err := os.ReadFile(filepath.Join(workspace, filepath.FromSlash(rel)))
+		if err != nil {
+			t.Errorf("%s not installed: %v", rel, err)
+		} else if HashContent(data) != sum {
+			t.Errorf("%s: recorded sum does not match the installed file", rel)
+		}
+	}
+	slices.Sort(recorded)
+	if !slices.Equal(recorded, want) {
+		t.Errorf("installed files = %q, want %q", recorded, want)
+	}
+	if _, err := os.Stat(filepath.Join(workspace, ".claude", "hooks", "fmt.ps1")); err == nil {
+		t.Error("the PowerShell variant was installed for linux")
+	}
+
+	entry := NewEntry(res.Manifest, "github.com/acme/pack-demo")
+	reg.Packs[res.Manifest.Name] = entry
+	if err := reg.Save(workspace); err != nil {
+		t.Fatal(err)
+	}
+	if drift := Drift(workspace, entry, entry.Files); len(drift) != 0 {
+		t.Errorf("drift right after install: %+v", drift)
+	}
+
+	// A second pack shipping the same skill conflicts unless forced.
+	other := testPack("pack-other")
+	if _, err := Install(workspace, other, reg, Options{OS: "linux"}); !errors.Is(err, ErrConflict) {
+		t.Errorf("conflicting install: err = %v, want ErrConflict", err)
+	}
+	// Reinstalling the owner does not.
+	if _, err := Install(workspace, testPack("pack-demo"), reg, Options{OS: "linux"}); err != nil {
+		t.Errorf("reinstall: %v", err)
+	}
+
+	removal, err := Remove(workspace, reg.Packs["pack-demo"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantRemoved := []string{".claude/skills/review", ".claude/agents/planner.md", ".claude/hooks/fmt.sh"}
+	if !slices.Equal(removal.Files, wantRemoved) {
+		t.Errorf("removed %q, want %q", removal.Files, wantRemoved)
+	}
+	if !slices.Equal(removal.Dirs, []string{".claude/skills", ".claude/agents", ".claude/hooks"}) {
+		t.Errorf("pruned %q, want the three content directories", removal.Dirs)
+	}
+	if again, err := Remove(workspace, reg.Packs["pack-demo"]); err != nil || !again.Empty() {
+		t.Errorf("second removal = %+v, %v; want nothing to do", again, err)
+	}
+}
+
+func TestInstallHookVariants(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	workspace := t.TempDir()
+	res, err := Install(workspace, testPack("pack-demo"), LoadRegistry(workspace), Options{OS: "windows"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := InstalledHook(workspace, "fmt"); got != ".claude/hooks/fmt.ps1" {
+		t.Errorf("installed hook = %q, want the PowerShell variant", got)
+	}
+	if res.Manifest.OS != "windows" {
+		t.Errorf("OS = %q, want windows", res.Manifest.OS)
+	}
+
+	// Only PowerShell: nothing runs on linux.
+	src := testPack("pack-demo")
+	delete(src, "hooks/fmt.sh")
+	if _, err := Install(t.TempDir(), src, &Registry{Packs: map[string]*Entry{}}, Options{OS: "linux"}); !errors.Is(err, ErrIncompatible) {
+		t.Errorf("install without a linux hook: err = %v, want ErrIncompatible", err)
+	}
+}
+
+func TestInstallNormalizesCRLF(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	workspace := t.TempDir()
+	src := testPack("pack-demo")
+	src["agents/planner.md"] = &fstest.MapFile{Data: []byte("---\r\nname: planner\r\n---\r\nPlan the work.\r\n")}
+	if _, err := Install(workspace, src, LoadRegistry(workspace), Options{OS: "linux"}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(filepath.Join(workspace, ".claude", "agents", "planner.md"))
+	if string(data) != "---\nname: planner\n---\nPlan the work.\n" {
+		t.Errorf("installed agent = %q, want LF line endings", data)
+	}
+}
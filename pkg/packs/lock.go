@@ -0,0 +1,102 @@
+package packs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LockVersion is the format version written into packs.lock.
+const LockVersion = 1
+
+// Lock is .projects/.packs/packs.lock: the exact revision of every
+// installed pack. Registry.Save rewrites it on every change. Unlike the
+// registry, it holds nothing machine-specific, so teams commit it and
+// reinstall the same content from it (`orchestra pack sync`).
+type Lock struct {
+	LockVersion int                    `json:"lock_version"`
+	Packs       map[string]*LockedPack `json:"packs"`
+}
+
+// LockedPack pins one pack.
+type LockedPack struct {
+	Repo    string `json:"repo"`
+	Version string `json:"version"`          // from pack.json
+	Tag     string `json:"tag,omitempty"`    // tag the commit was installed from, if any
+	Commit  string `json:"commit,omitempty"` // empty for embedded copies and older installs
+	Source  string `json:"source,omitempty"` // SourceEmbedded for the copy in the binary
+}
+
+// LockPath returns the lockfile for workspace.
+func LockPath(workspace string) string {
+	return filepath.Join(workspace, ".projects", ".packs", "packs.lock")
+}
+
+// LoadLock reads the workspace's lockfile. Unlike the registry, a damaged
+// lockfile is an error: reinstalling from a guess would defeat it.
+func LoadLock(workspace string) (*Lock, error) {
+	data, err := os.ReadFile(LockPath(workspace))
+	if err != nil {
+		return nil, err
+	}
+	var l Lock
+	if err := json.Unmarshal(data, &l); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", LockPath(workspace), err)
+	}
+	if l.LockVersion > LockVersion {
+		return nil, fmt.Errorf("%s has lock_version %d; this orchestra reads up to %d", LockPath(workspace), l.LockVersion, LockVersion)
+	}
+	if l.Packs == nil {
+		l.Packs = make(map[string]*LockedPack)
+	}
+	return &l, nil
+}
+
+// Lock returns the lockfile for the installed packs.
+func (r *Registry) Lock() *Lock {
+	l := &Lock{LockVersion: LockVersion, Packs: make(map[string]*LockedPack, len(r.Packs))}
+	for name, e := range r.Packs {
+		l.Packs[name] = &LockedPack{Repo: e.Repo, Version: e.Version, Tag: e.Tag, Commit: e.Commit, Source: e.Source}
+	}
+	return l
+}
+
+// Save writes the lockfile atomically.
+func (l *Lock) Save(workspace string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(LockPath(workspace), append(data, '\n'), 0644)
+}
+
+// Satisfied reports whether e is the pack p pins.
+func (p *LockedPack) Satisfied(e *Entry) bool {
+	if e == nil || e.Repo != p.Repo || e.Source != p.Source {
+		return false
+	}
+	if p.Commit != "" {
+		return e.Commit == p.Commit
+	}
+	return e.Version == p.Version
+}
+
+// Ref returns what to check out for p: its commit, or its tag for packs
+// locked before commits were recorded. It is empty when p pins neither.
+func (p *LockedPack) Ref() string {
+	if p.Commit != "" {
+		return p.Commit
+	}
+	return p.Tag
+}
+
+// FindByRepo returns the locked pack cloned from repo.
+func (l *Lock) FindByRepo(repo string) (string, *LockedPack) {
+	for name, p := range l.Packs {
+		if p.Repo == repo {
+			return name, p
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,192 @@
+package packs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Manifest is the parsed pack.json from the root of a pack.
+type Manifest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Version     string   `json:"version"`
+	Stacks      []string `json:"stacks"`
+	Contents    Contents `json:"contents"`
+	Tags        []string `json:"tags"`
+
+	// MinOrchestraVersion is the oldest orchestra that can wire up the
+	// pack's content (e.g. "0.4.0"). Platforms lists supported GOOS or
+	// GOOS/GOARCH values; empty means any.
+	MinOrchestraVersion string   `json:"min_orchestra_version,omitempty"`
+	Platforms           []string `json:"platforms,omitempty"`
+
+	// PostInstall is an optional sh script, relative to the pack root, that
+	// the CLI runs in the workspace after install once the user approves it.
+	PostInstall string `json:"post_install,omitempty"`
+
+	// Dependencies names the packs (by name or repo; see DependencyRepo)
+	// whose content this pack relies on. Installing the pack installs the
+	// missing ones first (see Resolve); removing one while this pack is
+	// installed is refused unless cascaded or forced.
+	Dependencies []string `json:"dependencies,omitempty"`
+
+	// Publisher is set by Install when pack.sig verifies against the trust
+	// store. PostInstallScript holds the script named by PostInstall. Files
+	// and OS are set by Install. None of them is read from pack.json.
+	Publisher         string            `json:"-"`
+	PostInstallScript []byte            `json:"-"`
+	Files             map[string]string `json:"-"` // sha256 of each installed file; see Entry.Files
+	OS                string            `json:"-"` // GOOS the hook variants were picked for
+}
+
+// Contents lists what a pack installs, by name.
+type Contents struct {
+	Skills []string `json:"skills"`
+	Agents []string `json:"agents"`
+	Hooks  []string `json:"hooks"`
+}
+
+// ErrIncompatible wraps the reasons a pack cannot be installed by a given
+// orchestra build.
+var ErrIncompatible = errors.New("pack is incompatible")
+
+// ReadManifest parses pack.json from the root of src.
+func ReadManifest(src fs.FS) (*Manifest, error) {
+	data, err := fs.ReadFile(src, "pack.json")
+	if err != nil {
+		return nil, fmt.Errorf("read pack.json: %w (is this a valid pack repo?)", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parse pack.json: %w", err)
+	}
+	return &m, nil
+}
+
+// readPostInstall loads the script named by m.PostInstall into m.
+func readPostInstall(src fs.FS, m *Manifest) error {
+	if m.PostInstall == "" {
+		return nil
+	}
+	if !fs.ValidPath(m.PostInstall) {
+		return fmt.Errorf("post_install %q must be a relative path inside the pack", m.PostInstall)
+	}
+	script, err := fs.ReadFile(src, m.PostInstall)
+	if err != nil {
+		return fmt.Errorf("read post_install script: %w", err)
+	}
+	m.PostInstallScript = bytes.ReplaceAll(script, []byte("\r\n"), []byte("\n"))
+	return nil
+}
+
+// Problems lists why m cannot be installed by orchestra version on this
+// platform. Development builds ("dev" or empty) skip the version check.
+func Problems(m *Manifest, version string) []string {
+	var problems []string
+	if m.MinOrchestraVersion != "" && version != "" && version != "dev" && versionLess(version, m.MinOrchestraVersion) {
+		problems = append(problems, fmt.Sprintf("requires orchestra %s or newer (this is %s)", m.MinOrchestraVersion, version))
+	}
+	if len(m.Platforms) > 0 && !PlatformSupported(m.Platforms) {
+		problems = append(problems, fmt.Sprintf("supports %s, not %s/%s", strings.Join(m.Platforms, ", "), runtime.GOOS, runtime.GOARCH))
+	}
+	return problems
+}
+
+// Compatibility returns an ErrIncompatible error describing Problems, or nil.
+func Compatibility(m *Manifest, version string) error {
+	problems := Problems(m, version)
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s %s", ErrIncompatible, m.Name, strings.Join(problems, "; "))
+}
+
+// PlatformSupported reports whether the running OS, or OS/arch, is listed.
+func PlatformSupported(platforms []string) bool {
+	for _, p := range platforms {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p == runtime.GOOS || p == runtime.GOOS+"/"+runtime.GOARCH {
+			return true
+		}
+	}
+	return false
+}
+
+// versionLess reports whether semver a is older than b, by SemVer
+// precedence: a prerelease sorts before its release ("0.4.0-beta" <
+// "0.4.0"), and build metadata ("0.4.0+build.1") is ignored.
+func versionLess(a, b string) bool {
+	aBase, aPre := splitVersion(a)
+	bBase, bPre := splitVersion(b)
+	an, bn := parseSemver(aBase), parseSemver(bBase)
+	for i := 0; i < 3; i++ {
+		if an[i] != bn[i] {
+			return an[i] < bn[i]
+		}
+	}
+	switch {
+	case aPre == bPre:
+		return false
+	case aPre == "":
+		return false
+	case bPre == "":
+		return true
+	}
+	return prereleaseLess(aPre, bPre)
+}
+
+// prereleaseLess compares prereleases identifier by identifier: numbers
+// numerically ("rc.2" < "rc.10"), numbers before other identifiers, the
+// rest in ASCII order. When one runs out first, it is the lower.
+func prereleaseLess(a, b string) bool {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		x, y := as[i], bs[i]
+		if x == y {
+			continue
+		}
+		xNum, yNum := isNumericIdentifier(x), isNumericIdentifier(y)
+		switch {
+		case xNum && yNum:
+			x, y = strings.TrimLeft(x, "0"), strings.TrimLeft(y, "0")
+			if len(x) != len(y) {
+				return len(x) < len(y)
+			}
+			if x != y {
+				return x < y
+			}
+			continue
+		case xNum:
+			return true
+		case yNum:
+			return false
+		}
+		return x < y
+	}
+	return len(as) < len(bs)
+}
+
+func isNumericIdentifier(s string) bool {
+	return s != "" && strings.Trim(s, "0123456789") == ""
+}
+
+func splitVersion(v string) (base, pre string) {
+	v = strings.TrimPrefix(v, "v")
+	v, _, _ = strings.Cut(v, "+")
+	base, pre, _ = strings.Cut(v, "-")
+	return base, pre
+}
+
+func parseSemver(base string) [3]int {
+	var parts [3]int
+	for i, s := range strings.SplitN(base, ".", 3) {
+		parts[i], _ = strconv.Atoi(s)
+	}
+	return parts
+}
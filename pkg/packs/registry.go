@@ -0,0 +1,285 @@
+package packs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SchemaVersion is the .projects/ layout version written into the registry.
+// The CLI stamps the same number in .projects/.schema.json; bump both
+// together.
+const SchemaVersion = 1
+
+// SourceEmbedded marks entries installed from the copy shipped inside the
+// orchestra binary rather than cloned from their repo.
+const SourceEmbedded = "embedded"
+
+// Entry describes an installed pack in the registry.
+type Entry struct {
+	Version     string   `json:"version"`
+	Repo        string   `json:"repo"`
+	InstalledAt string   `json:"installed_at"`
+	Stacks      []string `json:"stacks"`
+	Skills      []string `json:"skills"`
+	Agents      []string `json:"agents"`
+	Hooks       []string `json:"hooks"`
+	Source      string   `json:"source,omitempty"`     // SourceEmbedded for the copy in the binary
+	Tag         string   `json:"tag,omitempty"`        // git tag the pack was installed from, if any
+	Commit      string   `json:"commit,omitempty"`     // git commit the pack was installed from; see Lock
+	Constraint  string   `json:"constraint,omitempty"` // semver range updates stay within; see Constraint
+
+	MinOrchestraVersion string   `json:"min_orchestra_version,omitempty"`
+	Platforms           []string `json:"platforms,omitempty"`
+	Publisher           string   `json:"publisher,omitempty"` // verified publisher, empty for unsigned packs
+	PostInstall         string   `json:"post_install,omitempty"`
+	PostInstallLog      string   `json:"post_install_log,omitempty"` // workspace-relative log of the last run
+	Dependencies        []string `json:"dependencies,omitempty"`
+
+	// Files holds the sha256 of each file the pack installed, before any
+	// overlay, by workspace-relative path; see Drift.
+	Files map[string]string `json:"files,omitempty"`
+	// OS is the GOOS hook variants were installed for; empty for entries
+	// from before variants, which always installed .sh hooks.
+	OS string `json:"os,omitempty"`
+}
+
+// Registry is .projects/.packs/registry.json: installed packs by name.
+type Registry struct {
+	SchemaVersion int               `json:"schema_version,omitempty"`
+	Packs         map[string]*Entry `json:"packs"`
+}
+
+// RegistryPath returns the registry file for workspace.
+func RegistryPath(workspace string) string {
+	return filepath.Join(workspace, ".projects", ".packs", "registry.json")
+}
+
+// LoadRegistry reads the workspace's registry. A missing or unreadable file
+// is an empty registry, so a damaged file never blocks reinstalling packs.
+func LoadRegistry(workspace string) *Registry {
+	reg := &Registry{}
+	if data, err := os.ReadFile(RegistryPath(workspace)); err == nil {
+		if err := json.Unmarshal(data, reg); err != nil {
+			reg = &Registry{}
+		}
+	}
+	if reg.Packs == nil {
+		reg.Packs = make(map[string]*Entry)
+	}
+	return reg
+}
+
+// Save writes the registry atomically, stamped with SchemaVersion, and the
+// lockfile derived from it.
+func (r *Registry) Save(workspace string) error {
+	if err := r.SaveKeepLock(workspace); err != nil {
+		return err
+	}
+	return r.Lock().Save(workspace)
+}
+
+// SaveKeepLock writes the registry but leaves packs.lock as it is, for
+// installs that follow the lockfile rather than update it.
+func (r *Registry) SaveKeepLock(workspace string) error {
+	path := RegistryPath(workspace)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	r.SchemaVersion = SchemaVersion
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(path, append(data, '\n'), 0644)
+}
+
+// Names returns installed pack names sorted case-insensitively.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.Packs))
+	for name := range r.Packs {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := strings.ToLower(names[i]), strings.ToLower(names[j])
+		if a != b {
+			return a < b
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// FindByRepo returns the name and entry of the pack installed from repo.
+func (r *Registry) FindByRepo(repo string) (string, *Entry) {
+	for _, name := range r.Names() {
+		if r.Packs[name].Repo == repo {
+			return name, r.Packs[name]
+		}
+	}
+	return "", nil
+}
+
+// Lookup finds an installed pack by registry name or repo.
+func (r *Registry) Lookup(nameOrRepo string) (string, *Entry) {
+	if e, ok := r.Packs[nameOrRepo]; ok {
+		return nameOrRepo, e
+	}
+	return r.FindByRepo(nameOrRepo)
+}
+
+// Conflicts lists content in m that another installed pack already owns,
+// as "skill foo (owned by org/pack)". Reinstalling or updating the pack
+// that owns the content is not a conflict.
+func (r *Registry) Conflicts(m *Manifest) []string {
+	owners := map[string]string{}
+	for _, name := range r.Names() {
+		if name == m.Name {
+			continue
+		}
+		e := r.Packs[name]
+		for _, s := range e.Skills {
+			owners["skill "+s] = name
+		}
+		for _, a := range e.Agents {
+			owners["agent "+a] = name
+		}
+		for _, h := range e.Hooks {
+			owners["hook "+h] = name
+		}
+	}
+	var conflicts []string
+	check := func(kind string, names []string) {
+		for _, n := range names {
+			if owner, ok := owners[kind+" "+n]; ok {
+				conflicts = append(conflicts, fmt.Sprintf("%s %s (owned by %s)", kind, n, owner))
+			}
+		}
+	}
+	check("skill", m.Contents.Skills)
+	check("agent", m.Contents.Agents)
+	check("hook", m.Contents.Hooks)
+	return conflicts
+}
+
+// Dependents returns the installed packs that list the pack name in their
+// dependencies, by name or by its repo, sorted.
+func (r *Registry) Dependents(name string) []string {
+	refs := map[string]bool{name: true}
+	if e, ok := r.Packs[name]; ok && e.Repo != "" {
+		refs[e.Repo] = true
+	}
+	var dependents []string
+	for _, other := range r.Names() {
+		if other == name {
+			continue
+		}
+		for _, dep := range r.Packs[other].Dependencies {
+			if refs[dep] {
+				dependents = append(dependents, other)
+				break
+			}
+		}
+	}
+	return dependents
+}
+
+// RemovalSet returns name followed by every installed pack that depends
+// on it, directly or through another dependent, in the order they can be
+// removed: each pack before the packs it depends on.
+func (r *Registry) RemovalSet(name string) []string {
+	seen := map[string]bool{name: true}
+	order := []string{name}
+	for i := 0; i < len(order); i++ {
+		for _, d := range r.Dependents(order[i]) {
+			if !seen[d] {
+				seen[d] = true
+				order = append(order, d)
+			}
+		}
+	}
+	// Dependents were found after what they depend on; remove them first.
+	for i, j := 0, len(order)-1; i < j; i, j = i+1, j-1 {
+		order[i], order[j] = order[j], order[i]
+	}
+	return order
+}
+
+// NewEntry builds the registry entry for a freshly installed pack.
+func NewEntry(m *Manifest, repo string) *Entry {
+	return &Entry{
+		Version:     m.Version,
+		Repo:        repo,
+		InstalledAt: time.Now().UTC().Format(time.RFC3339),
+		Stacks:      m.Stacks,
+		Skills:      m.Contents.Skills,
+		Agents:      m.Contents.Agents,
+		Hooks:       m.Contents.Hooks,
+
+		MinOrchestraVersion: m.MinOrchestraVersion,
+		Platforms:           m.Platforms,
+		Publisher:           m.Publisher,
+		PostInstall:         m.PostInstall,
+		Dependencies:        m.Dependencies,
+		Files:               m.Files,
+		OS:                  m.OS,
+	}
+}
+
+// Manifest rebuilds the manifest fields recorded in an installed entry.
+func (e *Entry) Manifest(name string) *Manifest {
+	return &Manifest{
+		Name:                name,
+		Version:             e.Version,
+		Stacks:              e.Stacks,
+		Contents:            Contents{Skills: e.Skills, Agents: e.Agents, Hooks: e.Hooks},
+		MinOrchestraVersion: e.MinOrchestraVersion,
+		Platforms:           e.Platforms,
+		Publisher:           e.Publisher,
+		PostInstall:         e.PostInstall,
+		Dependencies:        e.Dependencies,
+		Files:               e.Files,
+		OS:                  e.OS,
+	}
+}
+
+// WriteFileAtomic writes data to a temp file next to path and renames it
+// into place, so readers never observe a half-written file. The temp file
+// lives in the destination directory, which keeps the rename on one
+// filesystem even on NFS/SMB mounts.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	// Flush to stable storage before the rename; network filesystems may
+	// otherwise expose the renamed file before its contents arrive.
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Chmod(tmpName, perm); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	return nil
+}
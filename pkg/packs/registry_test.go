@@ -0,0 +1,185 @@
+package packs
+
+import (
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRegistryRoundTrip(t *testing.T) {
+	workspace := t.TempDir()
+	reg := LoadRegistry(workspace)
+	reg.Packs["pack-go"] = &Entry{
+		Version:     "1.2.0",
+		Repo:        "github.com/acme/pack-go",
+		InstalledAt: "2026-01-02T03:04:05Z",
+		Stacks:      []string{"go"},
+		Skills:      []string{"go-review"},
+		Agents:      []string{"gopher"},
+		Hooks:       []string{"gofmt"},
+		Tag:         "v1.2.0",
+		Commit:      "0123456789abcdef",
+		Constraint:  "^1.2",
+		Files:       map[string]string{".claude/agents/gopher.md": "abc"},
+		OS:          "linux",
+	}
+	reg.Packs["pack-embedded"] = &Entry{Version: "0.1.0", Repo: "github.com/acme/pack-embedded", Source: SourceEmbedded}
+	if err := reg.Save(workspace); err != nil {
+		t.Fatal(err)
+	}
+
+	got := LoadRegistry(workspace)
+	if got.SchemaVersion != SchemaVersion {
+		t.Errorf("schema_version = %d, want %d", got.SchemaVersion, SchemaVersion)
+	}
+	if !reflect.DeepEqual(got.Packs, reg.Packs) {
+		t.Errorf("registry round trip:\n got %+v\nwant %+v", got.Packs, reg.Packs)
+	}
+
+	lock, err := LoadLock(workspace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(lock, reg.Lock()) {
+		t.Errorf("lock round trip:\n got %+v\nwant %+v", lock, reg.Lock())
+	}
+	for name, pin := range lock.Packs {
+		if !pin.Satisfied(reg.Packs[name]) {
+			t.Errorf("lock entry %s is not satisfied by the registry it came from", name)
+		}
+	}
+	if ref := lock.Packs["pack-go"].Ref(); ref != "0123456789abcdef" {
+		t.Errorf("Ref() = %q, want the commit", ref)
+	}
+}
+
+func TestLoadLegacyRegistry(t *testing.T) {
+	workspace := t.TempDir()
+	legacy := `{"packs":{"pack-go":{"version":"1.0.0","repo":"github.com/acme/pack-go","installed_at":"2025-01-01T00:00:00Z","stacks":["go"],"skills":["go-review"],"agents":null,"hooks":null}}}`
+	writeTestFile(t, RegistryPath(workspace), legacy)
+
+	reg := LoadRegistry(workspace)
+	if reg.SchemaVersion != 0 {
+		t.Errorf("schema_version = %d, want 0 for a registry without one", reg.SchemaVersion)
+	}
+	e := reg.Packs["pack-go"]
+	if e == nil || e.Version != "1.0.0" || !slices.Equal(e.Skills, []string{"go-review"}) {
+		t.Fatalf("pack-go = %+v", e)
+	}
+	if e.OS != "" {
+		t.Errorf("OS = %q, want empty for an entry from before hook variants", e.OS)
+	}
+
+	if err := reg.Save(workspace); err != nil {
+		t.Fatal(err)
+	}
+	if got := LoadRegistry(workspace); got.SchemaVersion != SchemaVersion || !reflect.DeepEqual(got.Packs, reg.Packs) {
+		t.Errorf("saved legacy registry = %+v, want %+v stamped with version %d", got.Packs, reg.Packs, SchemaVersion)
+	}
+}
+
+func TestLoadRegistryDamaged(t *testing.T) {
+	workspace := t.TempDir()
+	writeTestFile(t, RegistryPath(workspace), "{not json")
+	if reg := LoadRegistry(workspace); reg.Packs == nil || len(reg.Packs) != 0 {
+		t.Errorf("damaged registry = %+v, want empty", reg.Packs)
+	}
+}
+
+func TestLoadLock(t *testing.T) {
+	workspace := t.TempDir()
+	if _, err := LoadLock(workspace); !os.IsNotExist(err) {
+		t.Errorf("missing lock: err = %v, want not exist", err)
+	}
+
+	// Locks from before commits were recorded pin a tag.
+	writeTestFile(t, LockPath(workspace), `{"lock_version":1,"packs":{"pack-go":{"repo":"github.com/acme/pack-go","version":"1.0.0","tag":"v1.0.0"}}}`)
+	lock, err := LoadLock(workspace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pin := lock.Packs["pack-go"]
+	if pin.Ref() != "v1.0.0" {
+		t.Errorf("Ref() = %q, want the tag", pin.Ref())
+	}
+	if !pin.Satisfied(&Entry{Repo: "github.com/acme/pack-go", Version: "1.0.0"}) {
+		t.Error("a tag-only pin should be satisfied by the same version")
+	}
+	if pin.Satisfied(&Entry{Repo: "github.com/acme/pack-go", Version: "1.1.0"}) {
+		t.Error("a tag-only pin should not be satisfied by another version")
+	}
+	if name, p := lock.FindByRepo("github.com/acme/pack-go"); name != "pack-go" || p != pin {
+		t.Errorf("FindByRepo = %q, %v", name, p)
+	}
+
+	writeTestFile(t, LockPath(workspace), `{"lock_version":99,"packs":{}}`)
+	if _, err := LoadLock(workspace); err == nil || !strings.Contains(err.Error(), "lock_version 99") {
+		t.Errorf("newer lock: err = %v, want a lock_version error", err)
+	}
+	writeTestFile(t, LockPath(workspace), "{not json")
+	if _, err := LoadLock(workspace); err == nil {
+		t.Error("damaged lock: want an error")
+	}
+}
+
+func TestConflicts(t *testing.T) {
+	reg := &Registry{Packs: map[string]*Entry{
+		"pack-a": {Skills: []string{"review"}, Agents: []string{"planner"}, Hooks: []string{"fmt"}},
+		"pack-b": {Skills: []string{"deploy"}},
+	}}
+	tests := []struct {
+		name     string
+		manifest *Manifest
+		want     []string
+	}{
+		{
+			name:     "no overlap",
+			manifest: &Manifest{Name: "pack-c", Contents: Contents{Skills: []string{"lint"}, Agents: []string{"writer"}, Hooks: []string{"vet"}}},
+		},
+		{
+			name:     "reinstalling the owner",
+			manifest: &Manifest{Name: "pack-a", Contents: Contents{Skills: []string{"review"}, Agents: []string{"planner"}, Hooks: []string{"fmt"}}},
+		},
+		{
+			name:     "same name, different kind",
+			manifest: &Manifest{Name: "pack-c", Contents: Contents{Agents: []string{"review"}, Hooks: []string{"planner"}, Skills: []string{"fmt"}}},
+		},
+		{
+			name:     "every kind",
+			manifest: &Manifest{Name: "pack-c", Contents: Contents{Skills: []string{"lint", "review", "deploy"}, Agents: []string{"planner"}, Hooks: []string{"fmt"}}},
+			want: []string{
+				"skill review (owned by pack-a)",
+				"skill deploy (owned by pack-b)",
+				"agent planner (owned by pack-a)",
+				"hook fmt (owned by pack-a)",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := reg.Conflicts(tt.manifest); !slices.Equal(got, tt.want) {
+				t.Errorf("Conflicts = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := dir + "/file.json"
+	if err := WriteFileAtomic(path, []byte("one"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFileAtomic(path, []byte("two"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "two" {
+		t.Errorf("content = %q, want two", data)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("%d files left in the directory, want only the target", len(entries))
+	}
+}
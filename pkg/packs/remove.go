@@ -0,0 +1,233 @@
+package packs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// settingsFiles are the IDE settings that may hold rows pointing at pack
+// hooks, usually copied there by hand from a pack's README.
+var settingsFiles = []string{
+	filepath.Join(".claude", "settings.json"),
+	filepath.Join(".claude", "settings.local.json"),
+}
+
+// Removal reports what a removal deleted. Paths are relative to the
+// workspace and use forward slashes.
+type Removal struct {
+	// Files are the skill directories, agents, and hooks deleted.
+	Files []string
+	// Dirs are content directories pruned because they became empty.
+	Dirs []string
+	// Settings are hook rows dropped from IDE settings, as
+	// "<file>: <event> <command>".
+	Settings []string
+}
+
+// Empty reports whether nothing was removed.
+func (r *Removal) Empty() bool {
+	return len(r.Files) == 0 && len(r.Dirs) == 0 && len(r.Settings) == 0
+}
+
+// Remove deletes the content listed in e from the workspace's .claude/.
+// The registry entry itself is left to the caller.
+func Remove(workspace string, e *Entry) (*Removal, error) {
+	return RemoveFiles(workspace, e.Skills, e.Agents, e.Hooks)
+}
+
+// RemoveFiles deletes the named skills, agents, and hooks, drops settings
+// rows that run the deleted hooks, and prunes content directories left
+// empty. Content that is already gone is skipped, so the Removal lists
+// only what this call deleted. Failures do not stop the removal; they are
+// joined into the returned error.
+func RemoveFiles(workspace string, skills, agents, hooks []string) (*Removal, error) {
+	r := &Removal{}
+	var errs []error
+	remove := func(rel string, all bool) {
+		p := filepath.Join(workspace, rel)
+		if _, err := os.Lstat(p); err != nil {
+			return
+		}
+		var err error
+		if all {
+			err = os.RemoveAll(p)
+		} else {
+			err = os.Remove(p)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+		r.Files = append(r.Files, filepath.ToSlash(rel))
+	}
+	for _, name := range skills {
+		remove(filepath.Join(".claude", "skills", name), true)
+	}
+	for _, name := range agents {
+		remove(filepath.Join(".claude", "agents", name+".md"), false)
+	}
+	for _, name := range hooks {
+		for _, ext := range HookExts {
+			remove(filepath.Join(".claude", "hooks", name+ext), false)
+		}
+	}
+
+	if len(hooks) > 0 {
+		for _, rel := range settingsFiles {
+			rows, err := pruneHookSettings(filepath.Join(workspace, rel), hooks)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", filepath.ToSlash(rel), err))
+			}
+			for _, row := range rows {
+				r.Settings = append(r.Settings, filepath.ToSlash(rel)+": "+row)
+			}
+		}
+	}
+
+	for _, kind := range []string{"skills", "agents", "hooks"} {
+		rel := filepath.Join(".claude", kind)
+		entries, err := os.ReadDir(filepath.Join(workspace, rel))
+		if err != nil || len(entries) > 0 {
+			continue
+		}
+		if err := os.Remove(filepath.Join(workspace, rel)); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		r.Dirs = append(r.Dirs, filepath.ToSlash(rel))
+	}
+	return r, errors.Join(errs...)
+}
+
+// RemoveStale deletes content the old entry installed that the new manifest
+// no longer ships. Updates install over the old files first and then call
+// this, so a failed update leaves the old version in place.
+func RemoveStale(workspace string, old *Entry, m *Manifest) (*Removal, error) {
+	return RemoveFiles(workspace,
+		staleNames(old.Skills, m.Contents.Skills),
+		staleNames(old.Agents, m.Contents.Agents),
+		staleNames(old.Hooks, m.Contents.Hooks))
+}
+
+// staleNames returns the names in old that are not in current.
+func staleNames(old, current []string) []string {
+	keep := make(map[string]bool, len(current))
+	for _, name := range current {
+		keep[name] = true
+	}
+	var stale []string
+	for _, name := range old {
+		if !keep[name] {
+			stale = append(stale, name)
+		}
+	}
+	return stale
+}
+
+// pruneHookSettings drops hook rows that run one of the named hooks from
+// the settings file at path, along with matcher groups and events left
+// empty, and returns the dropped rows as "<event> <command>". A missing
+// file is not an error. The file is rewritten only if something changed.
+//
+// Settings use the shape
+//
+//	{"hooks": {"<event>": [{"matcher": "...", "hooks": [{"type": "command", "command": "..."}]}]}}
+func pruneHookSettings(path string, hooks []string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var settings map[string]any
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("parse: %w", err)
+	}
+	events, ok := settings["hooks"].(map[string]any)
+	if !ok {
+		return nil, nil
+	}
+
+	runsRemovedHook := func(command string) bool {
+		command = filepath.ToSlash(command)
+		for _, name := range hooks {
+			for _, ext := range HookExts {
+				if strings.Contains(command, ".claude/hooks/"+name+ext) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	var dropped []string
+	eventNames := make([]string, 0, len(events))
+	for event := range events {
+		eventNames = append(eventNames, event)
+	}
+	sort.Strings(eventNames)
+	for _, event := range eventNames {
+		groups, ok := events[event].([]any)
+		if !ok {
+			continue
+		}
+		var keptGroups []any
+		for _, g := range groups {
+			group, ok := g.(map[string]any)
+			if !ok {
+				keptGroups = append(keptGroups, g)
+				continue
+			}
+			rows, ok := group["hooks"].([]any)
+			if !ok {
+				keptGroups = append(keptGroups, g)
+				continue
+			}
+			var keptRows []any
+			for _, row := range rows {
+				h, _ := row.(map[string]any)
+				command, _ := h["command"].(string)
+				if command != "" && runsRemovedHook(command) {
+					dropped = append(dropped, event+" "+command)
+					continue
+				}
+				keptRows = append(keptRows, row)
+			}
+			if len(keptRows) == 0 {
+				continue
+			}
+			group["hooks"] = keptRows
+			keptGroups = append(keptGroups, group)
+		}
+		if len(keptGroups) == 0 {
+			delete(events, event)
+		} else {
+			events[event] = keptGroups
+		}
+	}
+	if len(dropped) == 0 {
+		return nil, nil
+	}
+	if len(events) == 0 {
+		delete(settings, "hooks")
+	}
+
+	out, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := WriteFileAtomic(path, append(out, '\n'), info.Mode().Perm()); err != nil {
+		return nil, err
+	}
+	return dropped, nil
+}
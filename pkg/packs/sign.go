@@ -0,0 +1,216 @@
+package packs
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+)
+
+// A signed pack ships two files next to pack.json:
+//
+//	pack.sum  sha256 of pack.json, every file the pack installs, and its
+//	          post_install script, one "<hex>  <path>" line per file,
+//	          sorted by path
+//	pack.sig  JSON {publisher, key, signature}: an ed25519 signature over
+//	          the bytes of pack.sum
+//
+// Verify recomputes the hashes, checks the signature, and accepts the
+// publisher only if the signing key is in the user's trust store.
+const (
+	SumFile = "pack.sum"
+	SigFile = "pack.sig"
+)
+
+// Signature is the parsed pack.sig.
+type Signature struct {
+	Publisher string `json:"publisher"`
+	Key       string `json:"key"`       // base64 ed25519 public key
+	Signature string `json:"signature"` // base64 signature over pack.sum
+}
+
+// Verification outcomes. ErrUnsigned and ErrUntrusted allow an install
+// with a warning; ErrVerify (content or signature mismatch) never does.
+var (
+	ErrUnsigned  = errors.New("pack is unsigned")
+	ErrUntrusted = errors.New("pack publisher is not trusted")
+	ErrVerify    = errors.New("pack verification failed")
+)
+
+// TrustStore maps publisher names to their trusted public keys
+// (~/.orchestra/trust.json).
+type TrustStore struct {
+	Publishers map[string][]string `json:"publishers"`
+}
+
+// TrustStorePath returns the user's trust store file.
+func TrustStorePath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".orchestra", "trust.json")
+}
+
+// LoadTrustStore reads the trust store; a missing file is empty.
+func LoadTrustStore() (*TrustStore, error) {
+	ts := &TrustStore{}
+	data, err := os.ReadFile(TrustStorePath())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, ts); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", TrustStorePath(), err)
+		}
+	}
+	if ts.Publishers == nil {
+		ts.Publishers = map[string][]string{}
+	}
+	return ts, nil
+}
+
+// Save writes the trust store atomically.
+func (ts *TrustStore) Save() error {
+	if err := os.MkdirAll(filepath.Dir(TrustStorePath()), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(ts, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(TrustStorePath(), append(data, '\n'), 0644)
+}
+
+// Trusts reports whether key is trusted for publisher.
+func (ts *TrustStore) Trusts(publisher, key string) bool {
+	for _, k := range ts.Publishers[publisher] {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// KeyID is a short fingerprint of a base64 public key for display.
+func KeyID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Sums hashes pack.json, every file m installs, and the post_install
+// script, and renders them in pack.sum format.
+func Sums(src fs.FS, m *Manifest) ([]byte, error) {
+	files := []string{"pack.json"}
+	for _, name := range m.Contents.Skills {
+		err := fs.WalkDir(src, path.Join("skills", name), func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range m.Contents.Agents {
+		files = append(files, path.Join("agents", name+".md"))
+	}
+	for _, name := range m.Contents.Hooks {
+		files = append(files, HookVariants(src, name)...)
+	}
+	if m.PostInstall != "" {
+		files = append(files, m.PostInstall)
+	}
+	sort.Strings(files)
+
+	var b bytes.Buffer
+	for _, f := range files {
+		data, err := fs.ReadFile(src, f)
+		if err != nil {
+			return nil, err
+		}
+		// Hash text as LF so a checkout with CRLF line endings still
+		// verifies; install normalizes these files the same way.
+		if IsTextContent(f) {
+			data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+		}
+		sum := sha256.Sum256(data)
+		fmt.Fprintf(&b, "%s  %s\n", hex.EncodeToString(sum[:]), f)
+	}
+	return b.Bytes(), nil
+}
+
+// Sign returns pack.sum and pack.sig contents for the pack rooted at src.
+func Sign(src fs.FS, m *Manifest, publisher string, key ed25519.PrivateKey) (sums, sig []byte, err error) {
+	sums, err = Sums(src, m)
+	if err != nil {
+		return nil, nil, err
+	}
+	sig, err = json.MarshalIndent(Signature{
+		Publisher: publisher,
+		Key:       base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, sums)),
+	}, "", "  ")
+	if err != nil {
+		return nil, nil, err
+	}
+	return sums, append(sig, '\n'), nil
+}
+
+// Verify checks pack.sum and pack.sig in src. It returns the publisher
+// when the signature is valid and its key is in ts; ErrUnsigned or a
+// wrapped ErrUntrusted (with the publisher) when the pack may still be
+// installed with a warning; and any other error when the content does not
+// match its signature.
+func Verify(src fs.FS, m *Manifest, ts *TrustStore) (string, error) {
+	sigData, err := fs.ReadFile(src, SigFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", ErrUnsigned
+	}
+	if err != nil {
+		return "", err
+	}
+	var sig Signature
+	if err := json.Unmarshal(sigData, &sig); err != nil {
+		return "", fmt.Errorf("parse %s: %w", SigFile, err)
+	}
+	key, err := base64.StdEncoding.DecodeString(sig.Key)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return "", fmt.Errorf("%s: invalid public key", SigFile)
+	}
+	signature, err := base64.StdEncoding.DecodeString(sig.Signature)
+	if err != nil {
+		return "", fmt.Errorf("%s: invalid signature encoding", SigFile)
+	}
+
+	sums, err := fs.ReadFile(src, SumFile)
+	if err != nil {
+		return "", fmt.Errorf("signed pack has no %s: %w", SumFile, err)
+	}
+	sums = bytes.ReplaceAll(sums, []byte("\r\n"), []byte("\n"))
+	if !ed25519.Verify(ed25519.PublicKey(key), sums, signature) {
+		return "", fmt.Errorf("%s does not match its signature from %s", SumFile, sig.Publisher)
+	}
+	actual, err := Sums(src, m)
+	if err != nil {
+		return "", fmt.Errorf("hash pack contents: %w", err)
+	}
+	if !bytes.Equal(sums, actual) {
+		return "", fmt.Errorf("pack contents differ from %s signed by %s", SumFile, sig.Publisher)
+	}
+
+	if !ts.Trusts(sig.Publisher, sig.Key) {
+		return sig.Publisher, fmt.Errorf("%w: signed by %q with key %s, which is not in %s", ErrUntrusted, sig.Publisher, KeyID(sig.Key), TrustStorePath())
+	}
+	return sig.Publisher, nil
+}
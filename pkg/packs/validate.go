@@ -0,0 +1,245 @@
+package packs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Problem is something Validate found wrong with a pack. Warnings do not
+// stop the pack from installing; errors do, or make it install broken.
+type Problem struct {
+	File    string // pack-relative path, such as "pack.json" or "hooks/guard.sh"
+	Message string
+	Warning bool
+}
+
+func (p Problem) String() string {
+	return p.File + ": " + p.Message
+}
+
+var (
+	packNamePattern    = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*(/[A-Za-z0-9][A-Za-z0-9._-]*)?$`)
+	contentNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+	semverPattern      = regexp.MustCompile(`^\d+\.\d+\.\d+(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+)
+
+// ValidName reports whether name can name a pack: <name> or <owner>/<name>.
+func ValidName(name string) bool {
+	return packNamePattern.MatchString(name)
+}
+
+// knownGOOS and knownGOARCH are the platforms a manifest may list.
+var (
+	knownGOOS   = []string{"aix", "android", "darwin", "dragonfly", "freebsd", "illumos", "ios", "linux", "netbsd", "openbsd", "plan9", "solaris", "windows"}
+	knownGOARCH = []string{"386", "amd64", "arm", "arm64", "loong64", "mips", "mips64", "mips64le", "mipsle", "ppc64", "ppc64le", "riscv64", "s390x"}
+)
+
+// Validate checks the pack rooted at src before it is published: pack.json
+// against the manifest's schema, the files its contents name, hook and
+// post_install shebangs, and pack.sum when the pack is signed. Stacks not
+// in knownStacks (when given) are warned about, since no project would
+// have them recommended. Problems are sorted by file.
+func Validate(src fs.FS, knownStacks []string) []Problem {
+	var problems []Problem
+	add := func(warning bool, file, format string, args ...any) {
+		problems = append(problems, Problem{File: file, Message: fmt.Sprintf(format, args...), Warning: warning})
+	}
+
+	data, err := fs.ReadFile(src, "pack.json")
+	if err != nil {
+		add(false, "pack.json", "%v", err)
+		return problems
+	}
+	var m Manifest
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&m); err != nil {
+		add(false, "pack.json", "%s", strings.TrimPrefix(err.Error(), "json: "))
+		return problems
+	}
+
+	// Manifest fields.
+	switch {
+	case m.Name == "":
+		add(false, "pack.json", "name is required")
+	case !ValidName(m.Name):
+		add(false, "pack.json", "name %q must be <name> or <owner>/<name> of letters, digits, '.', '_', and '-'", m.Name)
+	}
+	switch {
+	case m.Version == "":
+		add(false, "pack.json", "version is required")
+	case strings.HasPrefix(m.Version, "v"):
+		add(false, "pack.json", "version %q must be semver without a leading v", m.Version)
+	case !semverPattern.MatchString(m.Version):
+		add(false, "pack.json", "version %q is not semver (MAJOR.MINOR.PATCH)", m.Version)
+	}
+	if m.Description == "" {
+		add(true, "pack.json", "description is empty; pack search and pack info show it")
+	}
+	if len(m.Stacks) == 0 {
+		add(true, "pack.json", "stacks is empty, so pack recommend never suggests the pack; use [\"*\"] for every project")
+	}
+	for _, s := range m.Stacks {
+		if s != "*" && len(knownStacks) > 0 && !contains(knownStacks, s) {
+			add(true, "pack.json", "stack %q is not one orchestra detects (%s)", s, strings.Join(knownStacks, ", "))
+		}
+	}
+	if v := m.MinOrchestraVersion; v != "" && !semverPattern.MatchString(strings.TrimPrefix(v, "v")) {
+		add(false, "pack.json", "min_orchestra_version %q is not semver", v)
+	}
+	for _, p := range m.Platforms {
+		goos, goarch, hasArch := strings.Cut(strings.ToLower(strings.TrimSpace(p)), "/")
+		if !contains(knownGOOS, goos) || (hasArch && !contains(knownGOARCH, goarch)) {
+			add(false, "pack.json", "platform %q is not a GOOS or GOOS/GOARCH (linux, darwin/arm64, ...)", p)
+		}
+	}
+	for _, dep := range m.Dependencies {
+		switch {
+		case strings.TrimSpace(dep) == "":
+			add(false, "pack.json", "dependencies has an empty entry")
+		case dep == m.Name || path.Base(dep) == path.Base(m.Name):
+			add(false, "pack.json", "the pack depends on itself (%s)", dep)
+		}
+	}
+
+	// Contents: every name has its files, and every file is listed.
+	listed := map[string]bool{}
+	checkNames := func(kind string, names []string) {
+		seen := map[string]bool{}
+		for _, name := range names {
+			if seen[name] {
+				add(false, "pack.json", "contents.%s lists %q twice", kind, name)
+			}
+			seen[name] = true
+			if !contentNamePattern.MatchString(name) {
+				add(false, "pack.json", "contents.%s: %q is not a plain file name", kind, name)
+			}
+		}
+	}
+	checkNames("skills", m.Contents.Skills)
+	checkNames("agents", m.Contents.Agents)
+	checkNames("hooks", m.Contents.Hooks)
+
+	for _, name := range m.Contents.Skills {
+		dir := path.Join("skills", name)
+		listed[dir] = true
+		file := path.Join(dir, "SKILL.md")
+		data, err := fs.ReadFile(src, file)
+		if err != nil {
+			add(false, file, "contents.skills lists %s, but %s is missing", name, file)
+			continue
+		}
+		checkFrontmatter(data, name, file, add)
+	}
+	for _, name := range m.Contents.Agents {
+		file := path.Join("agents", name+".md")
+		listed[file] = true
+		data, err := fs.ReadFile(src, file)
+		if err != nil {
+			add(false, file, "contents.agents lists %s, but %s is missing", name, file)
+			continue
+		}
+		checkFrontmatter(data, name, file, add)
+	}
+	for _, name := range m.Contents.Hooks {
+		variants := HookVariants(src, name)
+		if len(variants) == 0 {
+			add(false, "hooks/"+name, "contents.hooks lists %s, but there is no hooks/%s.sh, .ps1, or .cmd", name, name)
+		}
+		if !contains(variants, path.Join("hooks", name+".sh")) && len(variants) > 0 {
+			add(true, variants[0], "hook %s has no .sh variant, so it installs only on Windows", name)
+		}
+		for _, file := range variants {
+			listed[file] = true
+			if path.Ext(file) == ".sh" {
+				checkShebang(src, file, "hook", add)
+			}
+		}
+	}
+	for _, dir := range []string{"skills", "agents", "hooks"} {
+		entries, _ := fs.ReadDir(src, dir)
+		for _, e := range entries {
+			file := path.Join(dir, e.Name())
+			if !listed[file] && !strings.HasPrefix(e.Name(), ".") {
+				add(true, file, "not listed in pack.json contents, so it is not installed")
+			}
+		}
+	}
+
+	if m.PostInstall != "" {
+		if !fs.ValidPath(m.PostInstall) {
+			add(false, "pack.json", "post_install %q must be a relative path inside the pack", m.PostInstall)
+		} else if _, err := fs.Stat(src, m.PostInstall); err != nil {
+			add(false, m.PostInstall, "post_install names %s, which is missing", m.PostInstall)
+		} else {
+			checkShebang(src, m.PostInstall, "post_install script", add)
+		}
+	}
+
+	// A signed pack must still match what was signed.
+	if _, err := fs.Stat(src, SigFile); err == nil {
+		if _, err := Verify(src, &m, &TrustStore{}); err != nil && !errors.Is(err, ErrUntrusted) {
+			add(false, SumFile, "%v; sign the pack again", err)
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].File < problems[j].File })
+	return problems
+}
+
+// checkFrontmatter checks that a skill or agent file starts with YAML
+// frontmatter naming it and saying when to use it, which is what an agent
+// reads to pick it.
+func checkFrontmatter(data []byte, name, file string, add func(bool, string, string, ...any)) {
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	rest, ok := bytes.CutPrefix(data, []byte("---\n"))
+	end := bytes.Index(rest, []byte("\n---"))
+	if !ok || end < 0 {
+		add(true, file, "has no --- frontmatter with name and description")
+		return
+	}
+	fields := map[string]string{}
+	for _, line := range strings.Split(string(rest[:end]), "\n") {
+		if k, v, ok := strings.Cut(line, ":"); ok && !strings.HasPrefix(line, " ") {
+			fields[strings.TrimSpace(k)] = strings.Trim(strings.TrimSpace(v), `"'`)
+		}
+	}
+	switch {
+	case fields["name"] == "":
+		add(true, file, "frontmatter has no name")
+	case fields["name"] != name:
+		add(true, file, "frontmatter name %q differs from %q in pack.json", fields["name"], name)
+	}
+	if fields["description"] == "" {
+		add(true, file, "frontmatter has no description; agents use it to decide when to use %s", name)
+	}
+}
+
+// checkShebang checks that an sh script starts with #!, without which it
+// cannot be run directly.
+func checkShebang(src fs.FS, file, what string, add func(bool, string, string, ...any)) {
+	data, err := fs.ReadFile(src, file)
+	if err != nil {
+		add(false, file, "%v", err)
+		return
+	}
+	if !bytes.HasPrefix(data, []byte("#!")) {
+		add(false, file, "%s has no #! line; start it with #!/bin/sh", what)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}